@@ -31,6 +31,8 @@ import (
 	"taskai/internal/collab"
 	"taskai/internal/config"
 	"taskai/internal/db"
+	"taskai/internal/secrets"
+	"taskai/internal/sso"
 	"taskai/internal/version"
 	"taskai/internal/yjs"
 )
@@ -79,6 +81,9 @@ func main() {
 		zap.String("port", cfg.Port),
 	)
 
+	// Identity providers on local addresses are only for development
+	sso.AllowPrivateIssuers = cfg.Env == "development"
+
 	// Initialize APM (OpenTelemetry → Datadog via otel-collector).
 	// When APM_ENABLED != "true" this is a zero-cost noop.
 	apmCfg := apm.ConfigFromEnv(version.Version)
@@ -129,6 +134,22 @@ func main() {
 	}
 	defer database.Close()
 
+	// Encrypt stored third-party secrets, including any saved before the key was set
+	if cfg.SecretsEncryptionKey != "" {
+		keyring, err := secrets.ParseKeyring(cfg.SecretsEncryptionKey, cfg.SecretsPreviousKeys)
+		if err != nil {
+			logger.Fatal("Invalid SECRETS_ENCRYPTION_KEY", zap.Error(err))
+		}
+		database.SetSecretKeyring(keyring)
+		n, err := database.EncryptStoredSecrets(context.Background(), false)
+		if err != nil {
+			logger.Fatal("Failed to encrypt stored secrets", zap.Error(err))
+		}
+		logger.Info("Secrets encryption enabled", zap.String("key_id", keyring.KeyID()), zap.Int("encrypted", n))
+	} else {
+		logger.Warn("SECRETS_ENCRYPTION_KEY not set; third-party secrets are stored unencrypted and left out of backups")
+	}
+
 	// Create background context with cancel for graceful shutdown
 	bgCtx, bgCancel := context.WithCancel(context.Background())
 	defer bgCancel()
@@ -151,12 +172,26 @@ func main() {
 	server.SetCollabManager(collabManager)
 	server.SetYjsClient(yjsClient)
 
+	// Rate limiting — per API key or user on protected routes, per client IP
+	// elsewhere. The postgres store shares limits between replicas.
+	trustedProxies, err := api.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
+	var rateLimitStore api.RateLimitStore = api.NewMemoryRateLimitStore(cfg.RateLimitMaxKeys)
+	if cfg.RateLimitStore == "postgres" {
+		rateLimitStore = api.NewDBRateLimitStore(database, logger)
+	}
+	limiter := api.NewRateLimiter(rateLimitStore, trustedProxies, logger)
+	logger.Info("Rate limiting configured", zap.String("store", cfg.RateLimitStore))
+
 	// Setup router
 	r := chi.NewRouter()
 
 	// Middleware stack
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	// Client IP from X-Forwarded-For / X-Real-IP, only when sent by a trusted proxy
+	r.Use(trustedProxies.RealIP)
 	// APM tracing — instruments every HTTP request with an OpenTelemetry span.
 	// Zero-overhead noop when APM_ENABLED != "true".
 	r.Use(otelhttp.NewMiddleware("taskai.http"))
@@ -209,7 +244,7 @@ func main() {
 		AllowedOrigins:   cfg.CORSAllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-Next-Cursor", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -382,12 +417,28 @@ func main() {
 
 		// Auth routes (public) with rate limiting
 		r.Route("/auth", func(r chi.Router) {
-			// Apply stricter rate limiting to auth endpoints (20 req/min)
-			r.Use(api.RateLimitMiddleware(20))
+			// Apply stricter rate limiting to auth endpoints (20 req/min),
+			// tighter still for password guessing and reset mail
+			r.Use(limiter.Limit(api.AuthRateLimit))
 			r.Post("/signup", server.HandleSignup)
-			r.Post("/login", server.HandleLogin)
-			r.Post("/forgot-password", server.HandleForgotPassword)
+			r.With(limiter.Limit(api.LoginRateLimit)).Post("/login", server.HandleLogin)
+			r.Post("/refresh", server.HandleRefreshToken)
+			r.Post("/logout", server.HandleLogout)
+			r.With(limiter.Limit(api.PasswordResetRateLimit)).Post("/forgot-password", server.HandleForgotPassword)
 			r.Post("/reset-password", server.HandleResetPassword)
+			r.Post("/unlock", server.HandleUnlockAccount)
+			// TOTP codes can be guessed like passwords
+			r.With(limiter.Limit(api.LoginRateLimit)).Post("/2fa/verify", server.HandleVerifySecondFactor)
+			r.Post("/webauthn/login/begin", server.HandleWebAuthnLoginBegin)
+			r.Post("/webauthn/login/finish", server.HandleWebAuthnLoginFinish)
+
+			// Team single sign-on
+			r.Get("/sso/discover", server.HandleSSODiscover)
+			r.Get("/sso/{teamId}/login", server.HandleSSOLogin)
+			r.Get("/sso/{teamId}/link", server.HandleSSOLinkConfirm)
+			r.Get("/sso/{teamId}/oidc/callback", server.HandleSSOOIDCCallback)
+			r.Post("/sso/{teamId}/saml/acs", server.HandleSSOSAMLACS)
+			r.Get("/sso/{teamId}/saml/metadata", server.HandleSSOSAMLMetadata)
 
 			// GitHub callback — shared between repo-sync and login flows.
 			// The state JWT secret differs between the two; we dispatch accordingly.
@@ -408,19 +459,46 @@ func main() {
 
 		// Invite validation (public, rate limited)
 		r.Group(func(r chi.Router) {
-			r.Use(api.RateLimitMiddleware(30))
+			r.Use(limiter.Limit(api.PublicRateLimit))
 			r.Get("/invites/validate", server.HandleValidateInvite)
 			r.Get("/team/invitations/by-token", server.HandleGetInvitationByToken)
 		})
 
+		// SCIM 2.0 provisioning for identity providers, authenticated by the
+		// team's SCIM token and rate limited per team
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(server.SCIMAuth)
+			r.Use(limiter.Limit(api.SCIMRateLimit))
+			r.Use(server.AuditLog)
+			r.Get("/ServiceProviderConfig", server.HandleSCIMServiceProviderConfig)
+			r.Get("/ResourceTypes", server.HandleSCIMResourceTypes)
+			r.Get("/Users", server.HandleSCIMListUsers)
+			r.Post("/Users", server.HandleSCIMCreateUser)
+			r.Get("/Users/{id}", server.HandleSCIMGetUser)
+			r.Put("/Users/{id}", server.HandleSCIMReplaceUser)
+			r.Patch("/Users/{id}", server.HandleSCIMPatchUser)
+			r.Delete("/Users/{id}", server.HandleSCIMDeleteUser)
+			r.Get("/Groups", server.HandleSCIMListGroups)
+			r.Post("/Groups", server.HandleSCIMCreateGroup)
+			r.Get("/Groups/{id}", server.HandleSCIMGetGroup)
+			r.Put("/Groups/{id}", server.HandleSCIMReplaceGroup)
+			r.Patch("/Groups/{id}", server.HandleSCIMPatchGroup)
+			r.Delete("/Groups/{id}", server.HandleSCIMDeleteGroup)
+		})
+
+		// GitHub webhook receiver (public, authenticated by X-Hub-Signature-256)
+		r.Post("/github/webhook", server.HandleGitHubWebhook)
+
 		// User notification WebSocket — auth via ?token= query param
 		r.Get("/ws/user", server.HandleUserWebSocket)
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
 			r.Use(server.JWTAuth)
-			// Apply general rate limiting (100 req/min)
-			r.Use(api.RateLimitMiddleware(cfg.RateLimitRequests))
+			// Apply general rate limiting (100 req/min per user or API key)
+			r.Use(limiter.Limit(api.RateLimitPolicy{Name: "api", Requests: cfg.RateLimitRequests, Window: time.Minute}))
+			// Record every successful change in the audit log
+			r.Use(server.AuditLog)
 
 			r.Get("/me", server.HandleMe)
 			r.Patch("/me", server.HandleUpdateProfile)
@@ -428,14 +506,17 @@ func main() {
 			// Project routes
 			r.Get("/projects", server.HandleListProjects)
 			r.Post("/projects", server.HandleCreateProject)
+			r.Post("/projects/import", server.HandleImportProject)
 			r.Get("/projects/{id}", server.HandleGetProject)
 			r.Patch("/projects/{id}", server.HandleUpdateProject)
 			r.Delete("/projects/{id}", server.HandleDeleteProject)
+			r.Get("/projects/{id}/export", server.HandleExportProject)
 
 			// Task routes
 			r.Get("/projects/{projectId}/tasks", server.HandleListTasks)
 			r.Post("/projects/{projectId}/tasks", server.HandleCreateTask)
 			r.Get("/projects/{projectId}/tasks/{taskNumber}", server.HandleGetTaskByNumber)
+			r.Post("/projects/{projectId}/tasks/{taskNumber}/time-entries", server.HandleCreateTimeEntryByNumber)
 			r.Patch("/tasks/{id}", server.HandleUpdateTask)
 			r.Delete("/tasks/{id}", server.HandleDeleteTask)
 
@@ -444,13 +525,23 @@ func main() {
 			r.Post("/projects/{projectId}/swim-lanes", server.HandleCreateSwimLane)
 			r.Patch("/swim-lanes/{id}", server.HandleUpdateSwimLane)
 			r.Delete("/swim-lanes/{id}", server.HandleDeleteSwimLane)
+			r.Get("/projects/{projectId}/flow-metrics", server.HandleGetFlowMetrics)
+
+			// Custom field routes
+			r.Get("/projects/{projectId}/custom-fields", server.HandleListCustomFields)
+			r.Post("/projects/{projectId}/custom-fields", server.HandleCreateCustomField)
+			r.Patch("/custom-fields/{id}", server.HandleUpdateCustomField)
+			r.Delete("/custom-fields/{id}", server.HandleDeleteCustomField)
 
 			// Wiki routes
 			r.Get("/projects/{projectId}/wiki/pages", server.HandleListWikiPages)
 			r.Post("/projects/{projectId}/wiki/pages", server.HandleCreateWikiPage)
+			r.Get("/projects/{projectId}/wiki/tree", server.HandleGetWikiTree)
+			r.Get("/projects/{projectId}/wiki/by-path", server.HandleGetWikiPageByPath)
 			r.Get("/wiki/pages/{pageId}", server.HandleGetWikiPage)
 			r.Patch("/wiki/pages/{pageId}", server.HandleUpdateWikiPage)
 			r.Delete("/wiki/pages/{pageId}", server.HandleDeleteWikiPage)
+			r.Post("/wiki/pages/{pageId}/move", server.HandleMoveWikiPage)
 			r.Get("/wiki/pages/{pageId}/content", server.HandleGetWikiPageContent)
 			r.Put("/wiki/pages/{pageId}/content", server.HandleUpdateWikiPageContent)
 			r.Get("/wiki/pages/{pageId}/versions", server.HandleListWikiPageVersions)
@@ -467,7 +558,7 @@ func main() {
 			r.Delete("/wiki/attachments/{attachmentId}", server.HandleDeleteWikiPageAttachment)
 
 			// Wiki search routes
-			r.Post("/wiki/search", server.HandleSearchWiki)
+			r.With(limiter.Limit(api.SearchRateLimit)).Post("/wiki/search", server.HandleSearchWiki)
 			r.Get("/wiki/autocomplete", server.HandleAutocompletePages)
 
 			// Wiki annotation routes
@@ -483,11 +574,28 @@ func main() {
 			r.Get("/projects/{id}/graph", server.HandleGetProjectGraph)
 
 			// Global search
-			r.Post("/search", server.HandleGlobalSearch)
+			r.With(limiter.Limit(api.SearchRateLimit)).Post("/search", server.HandleGlobalSearch)
 
 			// Task comment routes
 			r.Get("/tasks/{taskId}/comments", server.HandleListTaskComments)
 			r.Post("/tasks/{taskId}/comments", server.HandleCreateTaskComment)
+			r.Get("/tasks/{taskId}/history", server.HandleGetTaskHistory)
+			r.Get("/tasks/{taskId}/time-entries", server.HandleListTimeEntries)
+			r.Post("/tasks/{taskId}/time-entries", server.HandleCreateTimeEntry)
+			r.Post("/tasks/{taskId}/timer/start", server.HandleStartTimer)
+			r.Patch("/time-entries/{id}", server.HandleUpdateTimeEntry)
+			r.Delete("/time-entries/{id}", server.HandleDeleteTimeEntry)
+			r.Get("/me/timer", server.HandleGetTimer)
+			r.Post("/me/timer/stop", server.HandleStopTimer)
+			r.Get("/timesheets", server.HandleGetTimesheet)
+			r.Get("/tasks/{taskId}/relations", server.HandleListTaskRelations)
+			r.Post("/tasks/{taskId}/relations", server.HandleCreateTaskRelation)
+			r.Delete("/tasks/{taskId}/relations/{relationId}", server.HandleDeleteTaskRelation)
+			r.Get("/tasks/{taskId}/recurrence", server.HandleGetTaskRecurrence)
+			r.Post("/tasks/{taskId}/recurrence", server.HandleCreateTaskRecurrence)
+			r.Get("/recurrences/{id}", server.HandleGetRecurrence)
+			r.Patch("/recurrences/{id}", server.HandleUpdateRecurrence)
+			r.Delete("/recurrences/{id}", server.HandleDeleteRecurrence)
 
 			// Task reactions (bidirectional)
 			r.Post("/tasks/{taskId}/reactions", server.HandleToggleReaction)
@@ -498,8 +606,11 @@ func main() {
 			// Sprint routes (project-scoped)
 			r.Get("/projects/{id}/sprints", server.HandleListSprints)
 			r.Post("/projects/{id}/sprints", server.HandleCreateSprint)
+			r.Get("/projects/{id}/sprints/velocity", server.HandleGetSprintVelocity)
 			r.Patch("/sprints/{id}", server.HandleUpdateSprint)
 			r.Delete("/sprints/{id}", server.HandleDeleteSprint)
+			r.Get("/sprints/{id}/analytics", server.HandleGetSprintAnalytics)
+			r.Post("/sprints/{id}/complete", server.HandleCompleteSprint)
 			r.Post("/sprints/{id}/share", server.HandleShareSprint)
 			r.Delete("/sprints/{id}/share/{projectId}", server.HandleUnshareSprint)
 
@@ -516,8 +627,10 @@ func main() {
 			r.Post("/projects/{id}/members", server.HandleAddProjectMember)
 			r.Patch("/projects/{id}/members/{memberId}", server.HandleUpdateProjectMember)
 			r.Delete("/projects/{id}/members/{memberId}", server.HandleRemoveProjectMember)
+			r.Get("/projects/{id}/permissions", server.HandleGetProjectPermissions)
 			r.Get("/projects/{id}/github", server.HandleGetProjectGitHubSettings)
 			r.Patch("/projects/{id}/github", server.HandleUpdateProjectGitHubSettings)
+			r.Post("/projects/{id}/github/webhook-secret", server.HandleRotateGitHubWebhookSecret)
 			r.Post("/projects/{id}/github/sync", server.HandleGitHubSync)
 			r.Post("/projects/{id}/github/discover-mappings", server.HandleGitHubDiscoverMappings)
 			r.Post("/projects/{id}/github/oauth-init", server.HandleGitHubOAuthInit)
@@ -528,6 +641,14 @@ func main() {
 			r.Put("/projects/{id}/github/mappings", server.HandleSaveGitHubMappings)
 			r.Get("/projects/{id}/github/sync-logs", server.HandleGetGitHubSyncLogs)
 
+			// Outgoing webhook routes
+			r.Get("/projects/{id}/webhooks", server.HandleListWebhooks)
+			r.Post("/projects/{id}/webhooks", server.HandleCreateWebhook)
+			r.Patch("/projects/{id}/webhooks/{webhookId}", server.HandleUpdateWebhook)
+			r.Delete("/projects/{id}/webhooks/{webhookId}", server.HandleDeleteWebhook)
+			r.Get("/projects/{id}/webhooks/{webhookId}/deliveries", server.HandleListWebhookDeliveries)
+			r.Post("/projects/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", server.HandleRedeliverWebhook)
+
 			// Project invitation routes
 			r.Post("/projects/{id}/invitations", server.HandleInviteProjectMember)
 			r.Get("/projects/{id}/invitations", server.HandleGetProjectInvitations)
@@ -544,6 +665,14 @@ func main() {
 			r.Post("/settings/2fa/setup", server.Handle2FASetup)
 			r.Post("/settings/2fa/enable", server.Handle2FAEnable)
 			r.Post("/settings/2fa/disable", server.Handle2FADisable)
+			r.Get("/settings/sessions", server.HandleListSessions)
+			r.Delete("/settings/sessions", server.HandleRevokeOtherSessions)
+			r.Delete("/settings/sessions/{id}", server.HandleRevokeSession)
+			r.Post("/settings/webauthn/register/begin", server.HandleWebAuthnRegisterBegin)
+			r.Post("/settings/webauthn/register/finish", server.HandleWebAuthnRegisterFinish)
+			r.Get("/settings/webauthn/credentials", server.HandleListWebAuthnCredentials)
+			r.Patch("/settings/webauthn/credentials/{id}", server.HandleRenameWebAuthnCredential)
+			r.Delete("/settings/webauthn/credentials/{id}", server.HandleDeleteWebAuthnCredential)
 
 			// API key routes
 			r.Get("/api-keys", server.HandleListAPIKeys)
@@ -557,11 +686,23 @@ func main() {
 			r.Get("/team", server.HandleGetMyTeam)
 			r.Patch("/team", server.HandleUpdateTeam)
 			r.Get("/team/memberships", server.HandleGetMyTeamMemberships)
+			r.Get("/team/sso", server.HandleGetTeamSSO)
+			r.Put("/team/sso", server.HandleUpdateTeamSSO)
+			r.Delete("/team/sso", server.HandleDeleteTeamSSO)
+			r.Post("/team/sso/domains/{domain}/verify", server.HandleVerifyTeamSSODomain)
+			r.Get("/team/scim", server.HandleGetTeamSCIM)
+			r.Post("/team/scim/token", server.HandleCreateTeamSCIMToken)
+			r.Delete("/team/scim/token", server.HandleDeleteTeamSCIMToken)
+			r.Put("/team/scim/groups/{groupId}", server.HandleUpdateSCIMGroupMapping)
+			r.Get("/team/roles", server.HandleListTeamRoles)
+			r.Post("/team/roles", server.HandleCreateTeamRole)
+			r.Put("/team/roles/{roleId}", server.HandleUpdateTeamRole)
+			r.Delete("/team/roles/{roleId}", server.HandleDeleteTeamRole)
 			r.Get("/team/members", server.HandleGetTeamMembers)
 			r.Post("/team/members", server.HandleAddTeamMember)
 			r.Post("/team/invite", server.HandleInviteTeamMember)
 			r.Delete("/team/members/{memberId}", server.HandleRemoveTeamMember)
-			r.Get("/team/users/search", server.HandleSearchUsers)
+			r.With(limiter.Limit(api.SearchRateLimit)).Get("/team/users/search", server.HandleSearchUsers)
 
 			// Team invitations
 			r.Get("/team/invitations", server.HandleGetMyInvitations)
@@ -618,8 +759,13 @@ func main() {
 			r.Patch("/admin/users/{id}/invites", server.HandleAdminBoostInvites)
 			r.Patch("/admin/users/{id}/profile", server.HandleUpdateUserProfile)
 			r.Post("/admin/users/{id}/reset-password", server.HandleAdminResetPassword)
+			r.Get("/admin/users/locked", server.HandleGetLockedAccounts)
+			r.Post("/admin/users/{id}/unlock", server.HandleAdminUnlockUser)
 			r.Delete("/admin/users/{id}", server.HandleDeleteUser)
 
+			// Audit log (admins, and project owners for their projects)
+			r.Get("/audit-log", server.HandleListAuditLog)
+
 			// Admin email provider routes
 			r.Get("/admin/settings/email", server.HandleGetEmailProvider)
 			r.Post("/admin/settings/email", server.HandleSaveEmailProvider)
@@ -658,6 +804,9 @@ func main() {
 	go server.StartSnapshotWorker(bgCtx)
 	go server.StartIndexingWorker(bgCtx)
 	go server.StartGitHubSyncWorker(bgCtx)
+	go server.StartWebhookDeliveryWorker(bgCtx)
+	go server.StartRecurrenceWorker(bgCtx)
+	go server.StartSprintSnapshotWorker(bgCtx)
 
 	// Create HTTP server
 	addr := fmt.Sprintf(":%s", cfg.Port)
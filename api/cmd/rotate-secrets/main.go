@@ -0,0 +1,61 @@
+// Command rotate-secrets re-encrypts every stored third-party secret with the
+// current master key.
+//
+// To rotate the master key, set SECRETS_ENCRYPTION_KEY to the new key and
+// SECRETS_PREVIOUS_KEYS to the old one, run this command, then drop the old
+// key from SECRETS_PREVIOUS_KEYS. Secrets stored before encryption was
+// enabled are encrypted too.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/config"
+	"taskai/internal/db"
+	"taskai/internal/secrets"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Println("Usage: SECRETS_ENCRYPTION_KEY=<new> SECRETS_PREVIOUS_KEYS=<old> rotate-secrets")
+		fmt.Println("\nDatabase settings are read from the same environment as the API server.")
+	}
+	flag.Parse()
+
+	cfg := config.Load()
+	if cfg.SecretsEncryptionKey == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	logger, _ := zap.NewProduction()
+	defer logger.Sync()
+
+	keyring, err := secrets.ParseKeyring(cfg.SecretsEncryptionKey, cfg.SecretsPreviousKeys)
+	if err != nil {
+		logger.Fatal("Invalid encryption keys", zap.Error(err))
+	}
+
+	database, err := db.New(db.Config{
+		Driver:         cfg.DBDriver,
+		DBPath:         cfg.DBPath,
+		DSN:            cfg.DBDSN,
+		MigrationsPath: cfg.MigrationsPath,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize database", zap.Error(err))
+	}
+	defer database.Close()
+	database.SetSecretKeyring(keyring)
+
+	n, err := database.EncryptStoredSecrets(context.Background(), true)
+	if err != nil {
+		logger.Fatal("Secret rotation failed", zap.Error(err), zap.Int("rotated", n))
+	}
+	logger.Info("Secret rotation completed", zap.String("key_id", keyring.KeyID()), zap.Int("rotated", n))
+}
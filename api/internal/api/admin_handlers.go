@@ -33,13 +33,16 @@ type UserWithStats struct {
 	FailedAttempts  int       `json:"failed_attempts"`
 	InviteCount     int       `json:"invite_count"`
 	LinkedProviders []string  `json:"linked_providers"`
+	// Second factors: TOTP and WebAuthn credentials (passkeys, security keys)
+	TOTPEnabled         bool `json:"totp_enabled"`
+	WebAuthnCredentials int  `json:"webauthn_credentials"`
 }
 
 // UserActivity represents a user activity log entry
 type UserActivity struct {
 	ID           int64   `json:"id"`
 	UserID       int64   `json:"user_id"`
-	ActivityType string  `json:"activity_type"` // 'login', 'logout', 'failed_login'
+	ActivityType string  `json:"activity_type"` // 'login', 'logout', 'failed_login', 'login_alert', 'account_locked', 'account_unlocked'
 	IPAddress    *string `json:"ip_address"`
 	UserAgent    *string `json:"user_agent"`
 	CreatedAt    string  `json:"created_at"`
@@ -83,6 +86,13 @@ func (s *Server) HandleGetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	strongAuth, err := s.db.ListStrongAuth(ctx)
+	if err != nil {
+		s.logger.Error("Failed to query second factors", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to get users", "internal_error")
+		return
+	}
+
 	users := make([]UserWithStats, 0, len(entUsers))
 	for _, u := range entUsers {
 		if deletedIDs[u.ID] {
@@ -148,6 +158,8 @@ func (s *Server) HandleGetUsers(w http.ResponseWriter, r *http.Request) {
 			s.db.Rebind(`SELECT auth_provider FROM users WHERE id = ? LIMIT 1`), u.ID,
 		).Scan(&authProvider)
 		userStats.LinkedProviders = s.getUserLinkedProviders(ctx, u.ID, authProvider == "password")
+		userStats.TOTPEnabled = strongAuth[u.ID].TOTPEnabled
+		userStats.WebAuthnCredentials = strongAuth[u.ID].WebAuthnCredentials
 
 		users = append(users, userStats)
 	}
@@ -249,7 +261,18 @@ func (s *Server) HandleUpdateUserAdmin(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	err := s.db.Client.User.UpdateOneID(targetUserID).
+	target, err := s.db.Client.User.Get(ctx, targetUserID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "user not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update user", "internal_error")
+		return
+	}
+
+	err = s.db.Client.User.UpdateOneID(targetUserID).
 		SetIsAdmin(req.IsAdmin).
 		Exec(ctx)
 	if err != nil {
@@ -262,6 +285,11 @@ func (s *Server) HandleUpdateUserAdmin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "user.admin_changed", ResourceType: "user", ResourceID: targetUserID,
+		Before: map[string]bool{"is_admin": target.IsAdmin},
+		After:  map[string]bool{"is_admin": req.IsAdmin},
+	})
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"id":       targetUserID,
 		"is_admin": req.IsAdmin,
@@ -337,7 +365,20 @@ func (s *Server) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Log the user out everywhere
+	if _, err := s.db.RevokeUserSessions(ctx, targetUserID, 0); err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.Error(err), zap.Int64("target_user_id", targetUserID))
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webauthn_credentials WHERE user_id = $1`, targetUserID); err != nil {
+		s.logger.Error("Failed to delete webauthn credentials", zap.Error(err), zap.Int64("target_user_id", targetUserID))
+	}
+
 	s.logger.Info("Admin soft-deleted user", zap.Int64("admin_id", userID), zap.Int64("deleted_user_id", targetUserID))
+	auditChange(r, auditEvent{
+		Action: "user.deleted", ResourceType: "user", ResourceID: targetUserID,
+		Before: map[string]interface{}{"email": existingEmail, "deleted_at": nil},
+		After:  map[string]interface{}{"email": anonymizedEmail, "deleted_at": now},
+	})
 	respondJSON(w, http.StatusOK, map[string]interface{}{"id": targetUserID, "deleted": true})
 }
 
@@ -461,6 +502,17 @@ func (s *Server) HandleUpdateUserProfile(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	before, err := s.db.Client.User.Get(ctx, targetUserID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "user not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update profile", "internal_error")
+		return
+	}
+
 	update := s.db.Client.User.UpdateOneID(targetUserID).
 		SetFirstName(req.FirstName).
 		SetLastName(req.LastName)
@@ -481,6 +533,10 @@ func (s *Server) HandleUpdateUserProfile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "user.profile_updated", ResourceType: "user", ResourceID: targetUserID,
+		Before: entUserToAPI(before), After: entUserToAPI(entUser),
+	})
 	respondJSON(w, http.StatusOK, entUserToAPI(entUser))
 }
 
@@ -562,6 +618,9 @@ func (s *Server) HandleAdminResetPassword(w http.ResponseWriter, r *http.Request
 			return
 		}
 		s.logger.Info("Admin sent password reset email", zap.Int64("admin_id", adminID), zap.Int64("target_user_id", targetUserID))
+		auditChange(r, auditEvent{
+			Action: "user.password_reset_sent", ResourceType: "user", ResourceID: targetUserID,
+		})
 		respondJSON(w, http.StatusOK, map[string]string{"message": "Reset email sent to " + entUser.Email})
 		return
 	}
@@ -586,7 +645,13 @@ func (s *Server) HandleAdminResetPassword(w http.ResponseWriter, r *http.Request
 		respondError(w, http.StatusInternalServerError, "failed to set password", "internal_error")
 		return
 	}
+	if _, err := s.db.RevokeUserSessions(ctx, targetUserID, 0); err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.Error(err), zap.Int64("target_user_id", targetUserID))
+	}
 	s.logger.Info("Admin set password for user", zap.Int64("admin_id", adminID), zap.Int64("target_user_id", targetUserID))
+	auditChange(r, auditEvent{
+		Action: "user.password_set", ResourceType: "user", ResourceID: targetUserID,
+	})
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Password updated successfully"})
 }
 
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -11,18 +12,22 @@ import (
 
 // CreateAPIKeyRequest represents the request to create an API key
 type CreateAPIKeyRequest struct {
-	Name      string `json:"name"`
-	ExpiresIn *int   `json:"expires_in,omitempty"` // Days until expiration, null for no expiration
+	Name       string   `json:"name"`
+	ExpiresIn  *int     `json:"expires_in,omitempty"`  // Days until expiration, null for no expiration
+	Scopes     []string `json:"scopes,omitempty"`      // Defaults to everything but admin
+	ProjectIDs []int64  `json:"project_ids,omitempty"` // Restricts the key to these projects
 }
 
 // CreateAPIKeyResponse represents the response when creating an API key
 type CreateAPIKeyResponse struct {
-	ID        int64      `json:"id"`
-	Name      string     `json:"name"`
-	Key       string     `json:"key"`
-	KeyPrefix string     `json:"key_prefix"`
-	CreatedAt time.Time  `json:"created_at"`
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key"`
+	KeyPrefix  string     `json:"key_prefix"`
+	Scopes     []string   `json:"scopes"`
+	ProjectIDs []int64    `json:"project_ids,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
 
 // APIKeyResponse represents an API key in responses (without the full key)
@@ -30,7 +35,10 @@ type APIKeyResponse struct {
 	ID         int64      `json:"id"`
 	Name       string     `json:"name"`
 	KeyPrefix  string     `json:"key_prefix"`
+	Scopes     []string   `json:"scopes"`
+	ProjectIDs []int64    `json:"project_ids,omitempty"`
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
@@ -76,8 +84,38 @@ func (s *Server) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		expiresAt = &exp
 	}
 
+	// Validate scopes
+	scopes := defaultAPIKeyScopes
+	if len(req.Scopes) > 0 {
+		scopes = make([]string, 0, len(req.Scopes))
+		seen := make(map[string]bool, len(req.Scopes))
+		for _, scope := range req.Scopes {
+			if !apiKeyScopes[scope] {
+				respondError(w, http.StatusBadRequest, "unknown scope "+strconv.Quote(scope), "validation_error")
+				return
+			}
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	// Keys can only be restricted to projects the user can access
+	for _, projectID := range req.ProjectIDs {
+		hasAccess, err := s.authorizeProject(r.Context(), userID, projectID, PermProjectView)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+			return
+		}
+		if !hasAccess {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("project %d not found", projectID), "validation_error")
+			return
+		}
+	}
+
 	// Create API key
-	apiKey, err := s.db.CreateAPIKey(r.Context(), userID, req.Name, expiresAt)
+	apiKey, err := s.db.CreateScopedAPIKey(r.Context(), userID, req.Name, expiresAt, scopes, req.ProjectIDs)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to create API key", "internal_error")
 		return
@@ -85,12 +123,14 @@ func (s *Server) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 
 	// Return response
 	response := CreateAPIKeyResponse{
-		ID:        apiKey.ID,
-		Name:      apiKey.Name,
-		Key:       apiKey.Key,
-		KeyPrefix: apiKey.KeyPrefix,
-		CreatedAt: apiKey.CreatedAt,
-		ExpiresAt: apiKey.ExpiresAt,
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Key:        apiKey.Key,
+		KeyPrefix:  apiKey.KeyPrefix,
+		Scopes:     apiKey.Scopes,
+		ProjectIDs: apiKey.ProjectIDs,
+		CreatedAt:  apiKey.CreatedAt,
+		ExpiresAt:  apiKey.ExpiresAt,
 	}
 
 	respondJSON(w, http.StatusCreated, response)
@@ -118,7 +158,10 @@ func (s *Server) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
 			ID:         key.ID,
 			Name:       key.Name,
 			KeyPrefix:  key.KeyPrefix,
+			Scopes:     key.Scopes,
+			ProjectIDs: key.ProjectIDs,
 			LastUsedAt: key.LastUsedAt,
+			LastUsedIP: key.LastUsedIP,
 			CreatedAt:  key.CreatedAt,
 			ExpiresAt:  key.ExpiresAt,
 		}
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"taskai/internal/db"
+)
+
+// API key scopes. A write scope includes the matching read scope and admin
+// includes everything, including routes no other scope covers.
+const (
+	ScopeTasksRead     = "tasks:read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeWikiRead      = "wiki:read"
+	ScopeWikiWrite     = "wiki:write"
+	ScopeCommentsWrite = "comments:write"
+	ScopeAdmin         = "admin"
+)
+
+// apiKeyScopes lists the valid scopes
+var apiKeyScopes = map[string]bool{
+	ScopeTasksRead:     true,
+	ScopeTasksWrite:    true,
+	ScopeWikiRead:      true,
+	ScopeWikiWrite:     true,
+	ScopeCommentsWrite: true,
+	ScopeAdmin:         true,
+}
+
+// defaultAPIKeyScopes are granted to new keys that don't ask for scopes:
+// everything an agent needs to work on tasks and docs, but no admin access.
+var defaultAPIKeyScopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeWikiRead, ScopeWikiWrite, ScopeCommentsWrite}
+
+// apiKeyScopeRules map routes (relative to /api) to the scope needed to read
+// (GET) and to write (anything else). The first matching rule wins; "*"
+// matches one path segment and a trailing "**" the rest of the path. Routes
+// without a rule need the admin scope.
+var apiKeyScopeRules = []struct {
+	pattern     string
+	read, write string
+}{
+	// Discussion
+	{"/tasks/*/comments/**", ScopeTasksRead, ScopeCommentsWrite},
+	{"/tasks/*/reactions/**", ScopeTasksRead, ScopeCommentsWrite},
+	{"/wiki/pages/*/annotations/**", ScopeWikiRead, ScopeCommentsWrite},
+	{"/wiki/annotations/**", ScopeWikiRead, ScopeCommentsWrite},
+	{"/wiki/annotation-comments/**", ScopeWikiRead, ScopeCommentsWrite},
+
+	// Wiki; search and preview are POSTs that only read
+	{"/wiki/search", ScopeWikiRead, ScopeWikiRead},
+	{"/wiki/preview", ScopeWikiRead, ScopeWikiRead},
+	{"/wiki/**", ScopeWikiRead, ScopeWikiWrite},
+	{"/projects/*/wiki/**", ScopeWikiRead, ScopeWikiWrite},
+
+	// Tasks; pushing to GitHub is an integration and stays admin-only
+	{"/tasks/*/github/**", ScopeAdmin, ScopeAdmin},
+	{"/tasks/**", ScopeTasksRead, ScopeTasksWrite},
+	{"/projects/*/tasks/**", ScopeTasksRead, ScopeTasksWrite},
+	{"/recurrences/**", ScopeTasksRead, ScopeTasksWrite},
+	{"/time-entries/**", ScopeTasksRead, ScopeTasksWrite},
+	{"/timesheets", ScopeTasksRead, ScopeTasksRead},
+	{"/sprints/*/analytics", ScopeTasksRead, ScopeTasksRead},
+	{"/sprints/*/complete", ScopeTasksWrite, ScopeTasksWrite},
+	{"/search", ScopeTasksRead, ScopeTasksRead},
+
+	// Project structure can be read with tasks:read but only changed by admin keys
+	{"/projects", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/swim-lanes", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/custom-fields", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/flow-metrics", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/sprints", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/sprints/velocity", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/tags", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/members", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/permissions", ScopeTasksRead, ScopeAdmin},
+	{"/projects/*/graph", ScopeTasksRead, ScopeAdmin},
+	{"/me/timer/**", ScopeTasksRead, ScopeTasksWrite},
+	{"/me", ScopeTasksRead, ScopeAdmin},
+	{"/users/*/profile", ScopeTasksRead, ScopeAdmin},
+	{"/notifications/**", ScopeTasksRead, ScopeTasksRead},
+	{"/openapi.yaml", ScopeTasksRead, ScopeAdmin},
+}
+
+// projectPathPattern extracts the project ID from /api/projects/{id}/... paths
+var projectPathPattern = regexp.MustCompile(`^/api/projects/(\d+)(?:/|$)`)
+
+// requiredAPIKeyScope returns the scope an API key needs for a request.
+func requiredAPIKeyScope(method, path string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api"), "/"), "/")
+	read := method == http.MethodGet || method == http.MethodHead
+	for _, rule := range apiKeyScopeRules {
+		if matchScopePattern(rule.pattern, segments) {
+			if read {
+				return rule.read
+			}
+			return rule.write
+		}
+	}
+	return ScopeAdmin
+}
+
+func matchScopePattern(pattern string, segments []string) bool {
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, part := range parts {
+		if part == "**" {
+			return true
+		}
+		if i >= len(segments) || (part != "*" && part != segments[i]) {
+			return false
+		}
+	}
+	return len(segments) == len(parts)
+}
+
+// apiKeyHasScope reports whether a key's scopes cover the required scope.
+func apiKeyHasScope(scopes []string, required string) bool {
+	for _, scope := range scopes {
+		if scope == required || scope == ScopeAdmin {
+			return true
+		}
+		// tasks:write covers tasks:read, wiki:write covers wiki:read
+		if strings.HasSuffix(scope, ":write") && required == strings.TrimSuffix(scope, ":write")+":read" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyFromContext returns the API key a request was authenticated with, or
+// nil for JWT sessions.
+func apiKeyFromContext(ctx context.Context) *db.APIKey {
+	key, _ := ctx.Value(APIKeyKey).(*db.APIKey)
+	return key
+}
+
+// apiKeyAllowsProject reports whether the request's API key, if any, may
+// access a project.
+func apiKeyAllowsProject(ctx context.Context, projectID int64) bool {
+	return keyAllowsProject(apiKeyFromContext(ctx), projectID)
+}
+
+func keyAllowsProject(key *db.APIKey, projectID int64) bool {
+	if key == nil || len(key.ProjectIDs) == 0 {
+		return true
+	}
+	for _, id := range key.ProjectIDs {
+		if id == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeAPIKey checks a key's scopes and project allow-list against the
+// request route. It returns an error message, or "" when the key may proceed.
+func authorizeAPIKey(key *db.APIKey, r *http.Request) string {
+	if scope := requiredAPIKeyScope(r.Method, r.URL.Path); !apiKeyHasScope(key.Scopes, scope) {
+		return "API key is missing the " + scope + " scope"
+	}
+	if m := projectPathPattern.FindStringSubmatch(r.URL.Path); m != nil {
+		projectID, _ := strconv.ParseInt(m[1], 10, 64)
+		if !keyAllowsProject(key, projectID) {
+			return "API key is not allowed to access this project"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRequiredAPIKeyScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/projects/1/tasks", ScopeTasksRead},
+		{http.MethodPost, "/api/projects/1/tasks", ScopeTasksWrite},
+		{http.MethodPatch, "/api/tasks/5", ScopeTasksWrite},
+		{http.MethodPost, "/api/tasks/5/comments", ScopeCommentsWrite},
+		{http.MethodGet, "/api/tasks/5/comments", ScopeTasksRead},
+		{http.MethodPost, "/api/tasks/5/github/push", ScopeAdmin},
+		{http.MethodPost, "/api/projects/1/tasks/3/time-entries", ScopeTasksWrite},
+		{http.MethodPost, "/api/me/timer/stop", ScopeTasksWrite},
+		{http.MethodGet, "/api/timesheets", ScopeTasksRead},
+		{http.MethodPatch, "/api/me", ScopeAdmin},
+		{http.MethodGet, "/api/projects/1/custom-fields", ScopeTasksRead},
+		{http.MethodPost, "/api/projects/1/custom-fields", ScopeAdmin},
+		{http.MethodDelete, "/api/custom-fields/4", ScopeAdmin},
+		{http.MethodPost, "/api/wiki/search", ScopeWikiRead},
+		{http.MethodPut, "/api/wiki/pages/3/content", ScopeWikiWrite},
+		{http.MethodPost, "/api/wiki/pages/3/annotations", ScopeCommentsWrite},
+		{http.MethodGet, "/api/projects/1", ScopeTasksRead},
+		{http.MethodDelete, "/api/projects/1", ScopeAdmin},
+		{http.MethodGet, "/api/api-keys", ScopeAdmin},
+		{http.MethodGet, "/api/admin/users", ScopeAdmin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := requiredAPIKeyScope(tt.method, tt.path); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHasScope(t *testing.T) {
+	tests := []struct {
+		scopes   []string
+		required string
+		want     bool
+	}{
+		{[]string{ScopeTasksRead}, ScopeTasksRead, true},
+		{[]string{ScopeTasksRead}, ScopeTasksWrite, false},
+		{[]string{ScopeTasksWrite}, ScopeTasksRead, true},
+		{[]string{ScopeWikiWrite}, ScopeTasksRead, false},
+		{[]string{ScopeCommentsWrite}, ScopeTasksRead, false},
+		{[]string{ScopeAdmin}, ScopeWikiWrite, true},
+		{nil, ScopeTasksRead, false},
+	}
+	for _, tt := range tests {
+		if got := apiKeyHasScope(tt.scopes, tt.required); got != tt.want {
+			t.Errorf("apiKeyHasScope(%v, %q) = %v, want %v", tt.scopes, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestJWTAuthWithScopedAPIKey(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "agent@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Allowed")
+	otherID := ts.CreateTestProject(t, userID, "Other")
+
+	key, err := ts.DB.CreateScopedAPIKey(context.Background(), userID, "Agent", nil,
+		[]string{ScopeTasksRead}, []int64{projectID})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	var allowed bool
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed = apiKeyAllowsProject(r.Context(), otherID)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{"read tasks in allowed project", http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks", projectID), http.StatusOK},
+		{"write without scope", http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID), http.StatusForbidden},
+		{"admin route", http.MethodDelete, fmt.Sprintf("/api/projects/%d", projectID), http.StatusForbidden},
+		{"other project", http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks", otherID), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{
+				"Authorization":   "ApiKey " + key.Key,
+				"X-Forwarded-For": "203.0.113.7",
+			}
+			rec, req := MakeRequest(t, tt.method, tt.path, nil, headers)
+			ts.JWTAuth(handler).ServeHTTP(rec, req)
+
+			if tt.wantStatus == http.StatusForbidden {
+				AssertError(t, rec, http.StatusForbidden, "API key", "insufficient_scope")
+				return
+			}
+			AssertStatusCode(t, rec.Code, tt.wantStatus)
+			if allowed {
+				t.Error("Expected handlers to see the key's project restriction")
+			}
+		})
+	}
+
+	keys, err := ts.DB.GetAPIKeysByUserID(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("Failed to list API keys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].LastUsedIP == nil || *keys[0].LastUsedIP != "203.0.113.7" {
+		t.Errorf("Expected last used IP to be recorded, got %+v", keys)
+	}
+}
+
+func TestHandleCreateScopedAPIKey(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "apikey@example.com", "password123")
+	strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Mine")
+	foreignID := ts.CreateTestProject(t, strangerID, "Theirs")
+
+	t.Run("defaults to non-admin scopes", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/api-keys", CreateAPIKeyRequest{Name: "Default"}, userID, nil)
+		ts.HandleCreateAPIKey(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+		var resp CreateAPIKeyResponse
+		DecodeJSON(t, rec, &resp)
+		if len(resp.Scopes) != len(defaultAPIKeyScopes) {
+			t.Errorf("Expected default scopes %v, got %v", defaultAPIKeyScopes, resp.Scopes)
+		}
+		for _, scope := range resp.Scopes {
+			if scope == ScopeAdmin {
+				t.Error("Expected new keys not to get the admin scope by default")
+			}
+		}
+	})
+
+	t.Run("scoped to a project", func(t *testing.T) {
+		body := CreateAPIKeyRequest{Name: "Reader", Scopes: []string{ScopeTasksRead, ScopeTasksRead}, ProjectIDs: []int64{projectID}}
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/api-keys", body, userID, nil)
+		ts.HandleCreateAPIKey(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+		var resp CreateAPIKeyResponse
+		DecodeJSON(t, rec, &resp)
+		if len(resp.Scopes) != 1 || resp.Scopes[0] != ScopeTasksRead {
+			t.Errorf("Expected [tasks:read], got %v", resp.Scopes)
+		}
+		if len(resp.ProjectIDs) != 1 || resp.ProjectIDs[0] != projectID {
+			t.Errorf("Expected project_ids [%d], got %v", projectID, resp.ProjectIDs)
+		}
+	})
+
+	t.Run("unknown scope", func(t *testing.T) {
+		body := CreateAPIKeyRequest{Name: "Bad", Scopes: []string{"tasks:delete"}}
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/api-keys", body, userID, nil)
+		ts.HandleCreateAPIKey(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "unknown scope", "validation_error")
+	})
+
+	t.Run("project the user cannot access", func(t *testing.T) {
+		body := CreateAPIKeyRequest{Name: "Sneaky", ProjectIDs: []int64{foreignID}}
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/api-keys", body, userID, nil)
+		ts.HandleCreateAPIKey(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "not found", "validation_error")
+	})
+}
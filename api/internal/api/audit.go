@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+)
+
+// auditKey is the context key for the *auditRecord of a mutating request
+const auditKey contextKey = "audit_record"
+
+// auditIgnoredRoutes are POST routes that only read, so they stay out of the
+// audit log
+var auditIgnoredRoutes = map[string]bool{
+	"POST /api/search":       true,
+	"POST /api/wiki/search":  true,
+	"POST /api/wiki/preview": true,
+}
+
+// auditEvent describes what a handler changed. Before and After are any
+// JSON-encodable values, usually the resource's API representation; the audit
+// log keeps the fields that differ between them.
+type auditEvent struct {
+	Action       string // e.g. "task.updated"
+	ResourceType string
+	ResourceID   int64
+	ProjectID    int64
+	TeamID       int64
+	Before       interface{} // nil for created resources
+	After        interface{} // nil for deleted resources
+}
+
+// auditRecord collects what the handler of a request reports for the audit log
+type auditRecord struct {
+	event *auditEvent
+}
+
+// auditChange reports what the current request changed. Requests that aren't
+// audited, like those of handlers called outside the router, ignore it.
+func auditChange(r *http.Request, ev auditEvent) {
+	if rec, ok := r.Context().Value(auditKey).(*auditRecord); ok {
+		rec.event = &ev
+	}
+}
+
+// AuditLog middleware records every successful mutating request in the audit
+// log: who made it, through which channel, and what changed. It must run
+// after the middleware that authenticates the request.
+func (s *Server) AuditLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &auditRecord{}
+		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), auditKey, rec)))
+
+		if wrapped.statusCode >= http.StatusBadRequest {
+			return
+		}
+		route := r.URL.Path
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		if auditIgnoredRoutes[r.Method+" "+route] {
+			return
+		}
+		s.recordAudit(r, rec, route, wrapped.statusCode)
+	})
+}
+
+// recordAudit stores the audit entry of a request. Failures are logged rather
+// than returned: the change has already been made.
+func (s *Server) recordAudit(r *http.Request, rec *auditRecord, route string, status int) {
+	e := &db.AuditEntry{
+		Channel:   db.AuditChannelWeb,
+		IPAddress: getClientIP(r),
+		UserAgent: r.UserAgent(),
+		Action:    r.Method + " " + route,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    status,
+	}
+	if userID, ok := GetUserID(r); ok {
+		e.ActorID = &userID
+	}
+	e.ActorEmail, _ = GetUserEmail(r)
+	if key, ok := r.Context().Value(APIKeyKey).(*db.APIKey); ok {
+		e.Channel = db.AuditChannelAPIKey
+		e.APIKeyPrefix = key.KeyPrefix
+	} else if teamID, ok := r.Context().Value(SCIMTeamIDKey).(int64); ok {
+		e.Channel = db.AuditChannelSCIM
+		e.TeamID = &teamID
+	}
+	if name := GetAgentName(r); name != nil {
+		e.AgentName = *name
+	}
+
+	if ev := rec.event; ev != nil {
+		e.Action = ev.Action
+		e.ResourceType = ev.ResourceType
+		e.ResourceID = auditID(ev.ResourceID)
+		e.ProjectID = auditID(ev.ProjectID)
+		if ev.TeamID != 0 {
+			e.TeamID = auditID(ev.TeamID)
+		}
+		e.Changes = auditDiff(ev.Before, ev.After)
+	}
+	// Fall back to the project in the route for unannotated handlers
+	if e.ProjectID == nil {
+		param := chi.URLParam(r, "projectId")
+		if param == "" && strings.HasPrefix(route, "/api/projects/{id}") {
+			param = chi.URLParam(r, "id")
+		}
+		if id, err := strconv.ParseInt(param, 10, 64); err == nil {
+			e.ProjectID = auditID(id)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+	if err := s.db.CreateAuditEntry(ctx, e); err != nil {
+		s.logger.Error("Failed to record audit entry",
+			zap.String("action", e.Action),
+			zap.String("path", e.Path),
+			zap.Error(err),
+		)
+	}
+}
+
+func auditID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// auditIgnoredFields change on every update and say nothing about it
+var auditIgnoredFields = map[string]bool{"updated_at": true}
+
+// auditDiff returns the top-level fields that differ between the JSON
+// encodings of before and after. Secrets are redacted, keeping only the fact
+// that they changed.
+func auditDiff(before, after interface{}) map[string]db.AuditChange {
+	b, a := auditFields(before), auditFields(after)
+	changes := map[string]db.AuditChange{}
+	for k, v := range a {
+		if old, ok := b[k]; ok && reflect.DeepEqual(old, v) {
+			continue
+		}
+		changes[k] = db.AuditChange{Before: b[k], After: v}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			changes[k] = db.AuditChange{Before: v}
+		}
+	}
+	for k, c := range changes {
+		if auditIgnoredFields[k] {
+			delete(changes, k)
+			continue
+		}
+		if isSecretField(k) {
+			changes[k] = db.AuditChange{Before: redact(c.Before), After: redact(c.After)}
+		}
+	}
+	return changes
+}
+
+// auditFields decodes v's JSON encoding into its fields. Values that aren't
+// objects, like a flag, become a single "value" field.
+func auditFields(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err == nil {
+		return fields
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return map[string]interface{}{"value": value}
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"password", "secret", "token", "api_key"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact hides secret strings. Other values, like a flag saying whether a
+// secret is set, are kept.
+func redact(v interface{}) interface{} {
+	if str, ok := v.(string); ok && str != "" {
+		return "[redacted]"
+	}
+	return v
+}
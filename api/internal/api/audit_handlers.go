@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+)
+
+// auditExportLimit caps the entries of a CSV or JSON export
+const auditExportLimit = 10000
+
+// AuditLogResponse is a page of the audit log
+type AuditLogResponse struct {
+	Entries []db.AuditEntry `json:"entries"`
+	Total   int             `json:"total"`
+}
+
+// HandleListAuditLog returns the audit log, newest first. Admins see every
+// entry; project owners see the entries of their projects. With format=csv or
+// format=json the matching entries are downloaded as a file instead of paged.
+// Route: GET /api/audit-log
+func (s *Server) HandleListAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	q, err := parseAuditQuery(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		respondError(w, http.StatusBadRequest, "format must be csv or json", "invalid_input")
+		return
+	}
+
+	if !s.isAdmin(ctx, userID) {
+		owned, err := s.db.AuditProjectsOwnedBy(ctx, userID)
+		if err != nil {
+			s.logger.Error("Failed to load owned projects", zap.Int64("user_id", userID), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to load audit log", "internal_error")
+			return
+		}
+		if len(owned) == 0 {
+			respondError(w, http.StatusForbidden, "only admins and project owners can read the audit log", "forbidden")
+			return
+		}
+		if q.ProjectID != 0 && !containsInt64(owned, q.ProjectID) {
+			respondError(w, http.StatusForbidden, "only the project's owners can read its audit log", "forbidden")
+			return
+		}
+		q.ProjectIDs = owned
+	}
+
+	// Keys limited to some projects only read those projects' entries
+	if key := apiKeyFromContext(ctx); key != nil && len(key.ProjectIDs) > 0 {
+		allowed := []int64{}
+		for _, id := range key.ProjectIDs {
+			if q.ProjectIDs == nil || containsInt64(q.ProjectIDs, id) {
+				allowed = append(allowed, id)
+			}
+		}
+		q.ProjectIDs = allowed
+	}
+
+	if format != "" {
+		q.Offset, q.Limit = 0, auditExportLimit
+	}
+	entries, total, err := s.db.ListAuditEntries(ctx, q)
+	if err != nil {
+		s.logger.Error("Failed to list audit log", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to load audit log", "internal_error")
+		return
+	}
+
+	filename := fmt.Sprintf("taskai-audit-log-%s.%s", time.Now().Format("20060102-150405"), format)
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.WriteHeader(http.StatusOK)
+		if err := writeAuditCSV(w, entries); err != nil {
+			s.logger.Error("Audit log export aborted", zap.Error(err))
+		}
+	case "json":
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		respondJSON(w, http.StatusOK, entries)
+	default:
+		respondJSON(w, http.StatusOK, AuditLogResponse{Entries: entries, Total: total})
+	}
+}
+
+// parseAuditQuery reads the audit log filters from the query string
+func parseAuditQuery(r *http.Request) (db.AuditQuery, error) {
+	values := r.URL.Query()
+	q := db.AuditQuery{
+		Action:       values.Get("action"),
+		ResourceType: values.Get("resource_type"),
+		Channel:      values.Get("channel"),
+		Limit:        50,
+	}
+
+	ids := []struct {
+		name string
+		dst  *int64
+	}{
+		{"project_id", &q.ProjectID},
+		{"actor_id", &q.ActorID},
+		{"resource_id", &q.ResourceID},
+	}
+	for _, id := range ids {
+		if v := values.Get(id.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				return q, fmt.Errorf("invalid %s", id.name)
+			}
+			*id.dst = n
+		}
+	}
+
+	switch q.Channel {
+	case "", db.AuditChannelWeb, db.AuditChannelAPIKey, db.AuditChannelSCIM:
+	default:
+		return q, fmt.Errorf("channel must be web, api_key or scim")
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := values.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return q, fmt.Errorf("%s must be an RFC 3339 time", name)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 200 {
+			return q, fmt.Errorf("limit must be between 1 and 200")
+		}
+		q.Limit = n
+	}
+	if v := values.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("invalid offset")
+		}
+		q.Offset = n
+	}
+	return q, nil
+}
+
+// writeAuditCSV writes entries as CSV with a header row; changes are kept as
+// their JSON encoding
+func writeAuditCSV(w http.ResponseWriter, entries []db.AuditEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"id", "created_at", "actor_id", "actor_email", "channel", "api_key_prefix", "agent_name", "ip_address",
+		"user_agent", "action", "resource_type", "resource_id", "project_id", "team_id", "method", "path",
+		"status", "changes",
+	})
+	optional := func(id *int64) string {
+		if id == nil {
+			return ""
+		}
+		return strconv.FormatInt(*id, 10)
+	}
+	for _, e := range entries {
+		changes := ""
+		if len(e.Changes) > 0 {
+			data, err := json.Marshal(e.Changes)
+			if err != nil {
+				return err
+			}
+			changes = string(data)
+		}
+		row := []string{
+			strconv.FormatInt(e.ID, 10), e.CreatedAt.UTC().Format(time.RFC3339), optional(e.ActorID), e.ActorEmail,
+			e.Channel, e.APIKeyPrefix, e.AgentName, e.IPAddress, e.UserAgent, e.Action, e.ResourceType,
+			optional(e.ResourceID), optional(e.ProjectID), optional(e.TeamID), e.Method, e.Path,
+			strconv.Itoa(e.Status), changes,
+		}
+		cw.Write(escapeCSVFormulas(row))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// escapeCSVFormulas keeps spreadsheets from evaluating user-supplied cells
+// as formulas
+func escapeCSVFormulas(row []string) []string {
+	for i, cell := range row {
+		if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+			row[i] = "'" + cell
+		}
+	}
+	return row
+}
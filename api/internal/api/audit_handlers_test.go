@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"taskai/internal/db"
+)
+
+// routerRequest calls the full router with the given Authorization header
+func routerRequest(t *testing.T, ts *TestServer, method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec, req := MakeRequest(t, method, path, body, headers)
+	buildTestRouter(ts.Server).ServeHTTP(rec, req)
+	return rec
+}
+
+// listAuditLog reads the audit log as a user
+func listAuditLog(t *testing.T, ts *TestServer, userID int64, email, query string) AuditLogResponse {
+	t.Helper()
+
+	rec := routerRequest(t, ts, http.MethodGet, "/api/audit-log"+query, nil, map[string]string{
+		"Authorization": "Bearer " + ts.GenerateTestToken(t, userID, email),
+	})
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var page AuditLogResponse
+	DecodeJSON(t, rec, &page)
+	return page
+}
+
+func TestAuditLogRecordsChanges(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Apollo")
+	bearer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+
+	t.Run("web change with diff", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/projects/%d", projectID),
+			map[string]string{"name": "Artemis"}, bearer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		page := listAuditLog(t, ts, ownerID, "owner@example.com", "?action=project.updated")
+		if page.Total != 1 || len(page.Entries) != 1 {
+			t.Fatalf("Expected one entry, got %+v", page)
+		}
+		e := page.Entries[0]
+		if e.Channel != db.AuditChannelWeb || e.ActorID == nil || *e.ActorID != ownerID || e.ActorEmail != "owner@example.com" {
+			t.Errorf("Unexpected actor: %+v", e)
+		}
+		if e.ProjectID == nil || *e.ProjectID != projectID || e.ResourceType != "project" || e.Status != http.StatusOK {
+			t.Errorf("Unexpected entry: %+v", e)
+		}
+		name, ok := e.Changes["name"]
+		if !ok || name.Before != "Apollo" || name.After != "Artemis" || len(e.Changes) != 1 {
+			t.Errorf("Expected only the name change, got %+v", e.Changes)
+		}
+	})
+
+	t.Run("failed requests are not recorded", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/projects/%d", projectID),
+			map[string]string{"name": ""}, bearer)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+
+		page := listAuditLog(t, ts, ownerID, "owner@example.com", "?action=project.")
+		if page.Total != 1 {
+			t.Errorf("Expected the failed update to be skipped, got %d entries", page.Total)
+		}
+	})
+
+	t.Run("API key and agent", func(t *testing.T) {
+		key, err := ts.DB.CreateAPIKey(context.Background(), ownerID, "Agent", nil)
+		if err != nil {
+			t.Fatalf("Failed to create API key: %v", err)
+		}
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID),
+			map[string]string{"title": "Write report"}, map[string]string{
+				"Authorization": "ApiKey " + key.Key,
+				"X-Agent-Name":  "report-bot",
+				"User-Agent":    "report-bot/1.0",
+			})
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+		page := listAuditLog(t, ts, ownerID, "owner@example.com", "?channel=api_key")
+		if page.Total != 1 {
+			t.Fatalf("Expected one API key entry, got %+v", page)
+		}
+		e := page.Entries[0]
+		if e.Action != "task.created" || e.APIKeyPrefix != key.KeyPrefix || e.AgentName != "report-bot" || e.UserAgent != "report-bot/1.0" {
+			t.Errorf("Unexpected entry: %+v", e)
+		}
+		if title := e.Changes["title"]; title.Before != nil || title.After != "Write report" {
+			t.Errorf("Expected the created title, got %+v", e.Changes)
+		}
+	})
+
+	t.Run("unannotated routes keep the route", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/swim-lanes", projectID),
+			map[string]interface{}{"name": "Review", "color": "#000000", "position": 3, "status_category": "in_progress"}, bearer)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+		page := listAuditLog(t, ts, ownerID, "owner@example.com", "?limit=1")
+		e := page.Entries[0]
+		if e.Action != "POST /api/projects/{projectId}/swim-lanes" || e.ProjectID == nil || *e.ProjectID != projectID {
+			t.Errorf("Unexpected entry: %+v", e)
+		}
+	})
+}
+
+func TestAuditLogAccess(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	otherID := ts.CreateTestUser(t, "other@example.com", "password123")
+	adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+	makeAdmin(t, ts, adminID)
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Mine")
+	ts.AddProjectMember(t, projectID, memberID, ownerID, "editor")
+	_, otherProjectID := createTestTeamAndProject(t, ts, otherID, "Theirs")
+
+	for _, p := range []struct {
+		userID    int64
+		email     string
+		projectID int64
+	}{{ownerID, "owner@example.com", projectID}, {otherID, "other@example.com", otherProjectID}} {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/projects/%d", p.projectID),
+			map[string]string{"description": "Updated"}, map[string]string{
+				"Authorization": "Bearer " + ts.GenerateTestToken(t, p.userID, p.email),
+			})
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+	}
+
+	t.Run("owner sees their projects", func(t *testing.T) {
+		page := listAuditLog(t, ts, ownerID, "owner@example.com", "")
+		if page.Total != 1 || *page.Entries[0].ProjectID != projectID {
+			t.Errorf("Expected only the owner's project, got %+v", page)
+		}
+	})
+
+	t.Run("admin sees everything", func(t *testing.T) {
+		page := listAuditLog(t, ts, adminID, "admin@example.com", "")
+		if page.Total != 2 {
+			t.Errorf("Expected every entry, got %+v", page)
+		}
+	})
+
+	tests := []struct {
+		name       string
+		userID     int64
+		email      string
+		query      string
+		wantStatus int
+	}{
+		{"member", memberID, "member@example.com", "", http.StatusForbidden},
+		{"other owner's project", ownerID, "owner@example.com", fmt.Sprintf("?project_id=%d", otherProjectID), http.StatusForbidden},
+		{"invalid channel", ownerID, "owner@example.com", "?channel=email", http.StatusBadRequest},
+		{"invalid since", ownerID, "owner@example.com", "?since=yesterday", http.StatusBadRequest},
+		{"invalid format", ownerID, "owner@example.com", "?format=xml", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := routerRequest(t, ts, http.MethodGet, "/api/audit-log"+tt.query, nil, map[string]string{
+				"Authorization": "Bearer " + ts.GenerateTestToken(t, tt.userID, tt.email),
+			})
+			AssertStatusCode(t, rec.Code, tt.wantStatus)
+		})
+	}
+}
+
+func TestAuditLogExport(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+	makeAdmin(t, ts, adminID)
+	_, projectID := createTestTeamAndProject(t, ts, adminID, "Exported")
+	bearer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, adminID, "admin@example.com")}
+
+	rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/projects/%d", projectID),
+		map[string]string{"name": "=HYPERLINK(\"x\")"}, bearer)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	t.Run("csv", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, "/api/audit-log?format=csv", nil, bearer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("Expected a CSV content type, got %q", ct)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.Contains(cd, ".csv") {
+			t.Errorf("Expected a CSV attachment, got %q", cd)
+		}
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		if len(rows) != 2 || rows[0][0] != "id" || rows[1][9] != "project.updated" {
+			t.Fatalf("Unexpected CSV: %v", rows)
+		}
+		if changes := rows[1][17]; strings.HasPrefix(changes, "=") || !strings.Contains(changes, "HYPERLINK") {
+			t.Errorf("Expected the changes to be kept but not evaluated, got %q", changes)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, "/api/audit-log?format=json", nil, bearer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var entries []db.AuditEntry
+		DecodeJSON(t, rec, &entries)
+		if len(entries) != 1 || entries[0].Action != "project.updated" {
+			t.Errorf("Unexpected export: %+v", entries)
+		}
+	})
+}
+
+func TestAuditDiff(t *testing.T) {
+	type settings struct {
+		Name      string `json:"name"`
+		APIKey    string `json:"api_key"`
+		TokenSet  bool   `json:"token_set"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	changes := auditDiff(
+		settings{Name: "a", APIKey: "old-secret", UpdatedAt: "1"},
+		settings{Name: "a", APIKey: "new-secret", TokenSet: true, UpdatedAt: "2"},
+	)
+	if len(changes) != 2 {
+		t.Fatalf("Expected api_key and token_set to change, got %+v", changes)
+	}
+	if c := changes["api_key"]; c.Before != "[redacted]" || c.After != "[redacted]" {
+		t.Errorf("Expected the key to be redacted, got %+v", c)
+	}
+	if c := changes["token_set"]; c.Before != false || c.After != true {
+		t.Errorf("Expected flags to be kept, got %+v", c)
+	}
+
+	flag := auditDiff(false, true)
+	if c := flag["value"]; c.Before != false || c.After != true {
+		t.Errorf("Expected a value change, got %+v", flag)
+	}
+}
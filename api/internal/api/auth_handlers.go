@@ -35,8 +35,10 @@ type LoginRequest struct {
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"` // access token lifetime in seconds
+	User         User   `json:"user"`
 }
 
 // User represents a user
@@ -197,21 +199,23 @@ func (s *Server) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	// Convert Ent user to API user struct
 	apiUser := entUserToAPI(newUser)
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(apiUser.ID, apiUser.Email, s.config.JWTSecret, s.config.JWTExpiry())
+	// Start a login session
+	tokens, err := s.startSession(ctx, r, apiUser.ID, apiUser.Email)
 	if err != nil {
-		s.logger.Error("Failed to generate token", zap.Error(err))
+		s.logger.Error("Failed to start session", zap.Error(err))
 		respondError(w, http.StatusInternalServerError, "failed to generate token", "internal_error")
 		return
 	}
 
 	respondJSON(w, http.StatusCreated, AuthResponse{
-		Token: token,
-		User:  apiUser,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         apiUser,
 	})
 }
 
-// HandleLogin authenticates a user and returns a JWT token
+// HandleLogin authenticates a user and starts a login session
 func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := decodeJSON(r, &req); err != nil {
@@ -243,42 +247,47 @@ func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Locked accounts are rejected before the password is checked
+	lockout, err := s.db.GetLockout(ctx, entUser.ID)
+	if err != nil {
+		s.logger.Error("Failed to query lockout", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if lockout.Locked(time.Now()) {
+		respondAccountLocked(w, *lockout.LockedUntil)
+		return
+	}
+
 	passwordHash := entUser.PasswordHash
 
 	// Verify password
 	if err := auth.VerifyPassword(passwordHash, req.Password); err != nil {
-		// Log failed login attempt
-		go func() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			s.logUserActivity(ctx, entUser.ID, "failed_login", getClientIP(r), r.UserAgent())
-		}()
+		if lockedUntil := s.recordFailedLogin(ctx, r, entUser.ID, entUser.Email); lockedUntil != nil {
+			respondAccountLocked(w, *lockedUntil)
+			return
+		}
 		respondError(w, http.StatusUnauthorized, "invalid email or password", "invalid_credentials")
 		return
 	}
 
-	// Log successful login
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		s.logUserActivity(ctx, entUser.ID, "login", getClientIP(r), r.UserAgent())
-	}()
-
-	// Convert Ent user to API user struct
-	apiUser := entUserToAPI(entUser)
+	if s.respondDeactivated(ctx, w, entUser.ID) || s.respondSSORequired(ctx, w, entUser.ID) {
+		return
+	}
 
-	// Generate JWT token
-	token, err := auth.GenerateToken(apiUser.ID, apiUser.Email, s.config.JWTSecret, s.config.JWTExpiry())
+	// Users with a second factor get a challenge instead of a session
+	strong, err := s.db.GetStrongAuth(ctx, entUser.ID)
 	if err != nil {
-		s.logger.Error("Failed to generate token", zap.Error(err))
-		respondError(w, http.StatusInternalServerError, "failed to generate token", "internal_error")
+		s.logger.Error("Failed to query second factors", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if strong.Enabled() {
+		s.respondSecondFactorRequired(ctx, w, entUser.ID, strong)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, AuthResponse{
-		Token: token,
-		User:  apiUser,
-	})
+	s.completeLogin(ctx, w, r, entUser, lockout.FailedLogins)
 }
 
 // HandleMe returns the current authenticated user
@@ -672,6 +681,11 @@ func (s *Server) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
 	_, _ = s.db.ExecContext(ctx, `UPDATE password_reset_tokens SET used_at = $1 WHERE token = $2`, now, req.Token)
 
+	// Log out every session; the new password is needed to log in again
+	if _, err := s.db.RevokeUserSessions(ctx, userID, 0); err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.Error(err), zap.Int64("user_id", userID))
+	}
+
 	s.logger.Info("Password reset completed", zap.Int64("user_id", userID))
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Password reset successfully"})
 }
@@ -2,45 +2,58 @@ package api
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
+
+	"taskai/internal/secrets"
 )
 
-// BackupData represents the complete database backup
-type BackupData struct {
-	Version       int         `json:"version"`        // Schema migration version
-	ExportedAt    time.Time   `json:"exported_at"`
-	ExportedBy    int64       `json:"exported_by"`
-	Tables        TableData   `json:"tables"`
+// backupFormat is the layout version of export files. Format 2 and later
+// store binary columns as base64; older files stored them as raw strings.
+const backupFormat = 2
+
+// backupChunkSize is how many rows are written or inserted between flushes
+// and progress logs.
+const backupChunkSize = 500
+
+var errInvalidBackup = errors.New("invalid backup file")
+
+// backupHeader holds the fields written before the table data. An export is
+// a single JSON object: these fields followed by "tables", an object mapping
+// each table name to an array of rows, in foreign key order.
+type backupHeader struct {
+	Version    int       `json:"version"` // Schema migration version
+	Format     int       `json:"format"`
+	ExportedAt time.Time `json:"exported_at"`
+	ExportedBy int64     `json:"exported_by"`
+}
+
+// BackupTableSummary reports how a table was restored
+type BackupTableSummary struct {
+	Table    string `json:"table"`
+	Rows     int    `json:"rows"`     // rows in the backup
+	Imported int    `json:"imported"` // rows written
+	Count    int64  `json:"count"`    // rows in the table after import
+	Verified bool   `json:"verified"`
 }
 
-// TableData contains all table data
-type TableData struct {
-	Users                  []map[string]interface{} `json:"users"`
-	Teams                  []map[string]interface{} `json:"teams"`
-	TeamMembers            []map[string]interface{} `json:"team_members"`
-	Projects               []map[string]interface{} `json:"projects"`
-	ProjectMembers         []map[string]interface{} `json:"project_members"`
-	ProjectInvitations     []map[string]interface{} `json:"project_invitations"`
-	Sprints                []map[string]interface{} `json:"sprints"`
-	SwimLanes              []map[string]interface{} `json:"swim_lanes"`
-	Tasks                  []map[string]interface{} `json:"tasks"`
-	TaskComments           []map[string]interface{} `json:"task_comments"`
-	TaskAttachments        []map[string]interface{} `json:"task_attachments"`
-	Tags                   []map[string]interface{} `json:"tags"`
-	TaskTags               []map[string]interface{} `json:"task_tags"`
-	Invites                []map[string]interface{} `json:"invites"`
-	TeamInvitations        []map[string]interface{} `json:"team_invitations"`
-	UserActivity           []map[string]interface{} `json:"user_activity"`
-	APIKeys                []map[string]interface{} `json:"api_keys"`
-	CloudinaryCredentials  []map[string]interface{} `json:"cloudinary_credentials"`
-	EmailProvider          []map[string]interface{} `json:"email_provider"`
+// BackupImportResult is the outcome of importing a backup
+type BackupImportResult struct {
+	Version       int                  `json:"version"`
+	Rows          int                  `json:"rows"`
+	Tables        []BackupTableSummary `json:"tables"`
+	SkippedTables []string             `json:"skipped_tables,omitempty"` // in the backup but not in this schema
 }
 
 // HandleExportData exports all database data (admin only)
@@ -70,57 +83,79 @@ func (s *Server) HandleExportData(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	backup := BackupData{
+	tables, err := s.backupTables(ctx)
+	if err != nil {
+		s.logger.Error("Failed to read database schema", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to read database schema", "internal_error")
+		return
+	}
+
+	// Set headers for download
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=taskai-backup-%s.json", time.Now().Format("20060102-150405")))
+	w.WriteHeader(http.StatusOK)
+
+	header := backupHeader{
 		Version:    version,
+		Format:     backupFormat,
 		ExportedAt: time.Now(),
 		ExportedBy: userID,
-		Tables:     TableData{},
-	}
-
-	// Export all tables
-	tables := []struct {
-		name   string
-		target *[]map[string]interface{}
-	}{
-		{"users", &backup.Tables.Users},
-		{"teams", &backup.Tables.Teams},
-		{"team_members", &backup.Tables.TeamMembers},
-		{"projects", &backup.Tables.Projects},
-		{"project_members", &backup.Tables.ProjectMembers},
-		{"project_invitations", &backup.Tables.ProjectInvitations},
-		{"sprints", &backup.Tables.Sprints},
-		{"swim_lanes", &backup.Tables.SwimLanes},
-		{"tasks", &backup.Tables.Tasks},
-		{"task_comments", &backup.Tables.TaskComments},
-		{"task_attachments", &backup.Tables.TaskAttachments},
-		{"tags", &backup.Tables.Tags},
-		{"task_tags", &backup.Tables.TaskTags},
-		{"invites", &backup.Tables.Invites},
-		{"team_invitations", &backup.Tables.TeamInvitations},
-		{"user_activity", &backup.Tables.UserActivity},
-		{"api_keys", &backup.Tables.APIKeys},
-		{"cloudinary_credentials", &backup.Tables.CloudinaryCredentials},
-		{"email_provider", &backup.Tables.EmailProvider},
+	}
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
 	}
 
-	for _, table := range tables {
-		data, err := s.exportTable(ctx, table.name)
-		if err != nil {
-			s.logger.Error("Failed to export table", zap.String("table", table.name), zap.Error(err))
-			respondError(w, http.StatusInternalServerError, fmt.Sprintf("failed to export %s", table.name), "internal_error")
-			return
-		}
-		*table.target = data
-		s.logger.Info("Exported table", zap.String("table", table.name), zap.Int("rows", len(data)))
+	// The status is already sent, so a failure can only truncate the file;
+	// the importer rejects truncated JSON.
+	if err := s.writeBackup(ctx, w, flush, header, tables); err != nil {
+		s.logger.Error("Export aborted", zap.Error(err))
+		return
 	}
 
 	s.logger.Info("Export completed", zap.Int("version", version), zap.Int64("user_id", userID))
+}
 
-	// Set headers for download
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=taskai-backup-%s.json", time.Now().Format("20060102-150405")))
+// writeBackup streams the backup JSON table by table, flushing every
+// backupChunkSize rows so large tables are never held in memory.
+func (s *Server) writeBackup(ctx context.Context, w io.Writer, flush func(), header backupHeader, tables []*backupTable) error {
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	// Reopen the header object to append the tables
+	if _, err := w.Write(headerJSON[:len(headerJSON)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"tables":{`); err != nil {
+		return err
+	}
+
+	for i, table := range tables {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		name, _ := json.Marshal(table.name)
+		if _, err := fmt.Fprintf(w, "%s:[", name); err != nil {
+			return err
+		}
+		rows, err := s.exportTable(ctx, w, flush, table)
+		if err != nil {
+			return fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		if _, err := io.WriteString(w, "]"); err != nil {
+			return err
+		}
+		s.logger.Info("Exported table", zap.String("table", table.name), zap.Int("rows", rows))
+	}
 
-	respondJSON(w, http.StatusOK, backup)
+	if _, err := io.WriteString(w, "}}\n"); err != nil {
+		return err
+	}
+	flush()
+	return nil
 }
 
 // HandleImportData imports database data (admin only)
@@ -140,79 +175,171 @@ func (s *Server) HandleImportData(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
 	defer cancel()
 
-	var data BackupData
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		respondError(w, http.StatusBadRequest, "invalid backup file", "validation_error")
-		return
-	}
-
-	s.logger.Info("Starting data import",
-		zap.Int64("user_id", userID),
-		zap.Int("version", data.Version),
-		zap.Time("backup_date", data.ExportedAt))
+	s.logger.Info("Starting data import", zap.Int64("user_id", userID))
 
-	rows, err := s.importBackupData(ctx, &data)
+	result, err := s.importBackupData(ctx, r.Body)
 	if err != nil {
 		s.logger.Error("Import failed", zap.Error(err))
 		respondError(w, http.StatusBadRequest, err.Error(), "import_error")
 		return
 	}
 
-	s.logger.Info("Import completed", zap.Int64("user_id", userID))
+	s.logger.Info("Import completed", zap.Int64("user_id", userID), zap.Int("rows", result.Rows))
+	auditChange(r, auditEvent{
+		Action: "backup.imported", ResourceType: "backup",
+		After: map[string]interface{}{"version": result.Version, "rows": result.Rows},
+	})
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Data imported successfully",
-		"version": data.Version,
-		"rows":    rows,
+		"message":        "Data imported successfully",
+		"version":        result.Version,
+		"rows":           result.Rows,
+		"tables":         result.Tables,
+		"skipped_tables": result.SkippedTables,
 	})
 }
 
-// importBackupData verifies the migration version and imports all tables.
-func (s *Server) importBackupData(ctx context.Context, data *BackupData) (int, error) {
+// importBackupData streams a backup into the database in one transaction,
+// then counts every imported table to verify the restore. The header must
+// precede the table data, which is how exports are written.
+func (s *Server) importBackupData(ctx context.Context, r io.Reader) (*BackupImportResult, error) {
 	currentVersion, err := s.getCurrentMigrationVersion(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get schema version: %w", err)
-	}
-	if data.Version != currentVersion {
-		return 0, fmt.Errorf("migration version mismatch: backup is v%d, database is v%d", data.Version, currentVersion)
-	}
-
-	tables := []struct {
-		name string
-		data []map[string]interface{}
-	}{
-		{"users", data.Tables.Users},
-		{"teams", data.Tables.Teams},
-		{"team_members", data.Tables.TeamMembers},
-		{"projects", data.Tables.Projects},
-		{"project_members", data.Tables.ProjectMembers},
-		{"project_invitations", data.Tables.ProjectInvitations},
-		{"sprints", data.Tables.Sprints},
-		{"swim_lanes", data.Tables.SwimLanes},
-		{"tasks", data.Tables.Tasks},
-		{"task_comments", data.Tables.TaskComments},
-		{"task_attachments", data.Tables.TaskAttachments},
-		{"tags", data.Tables.Tags},
-		{"task_tags", data.Tables.TaskTags},
-		{"invites", data.Tables.Invites},
-		{"team_invitations", data.Tables.TeamInvitations},
-		{"user_activity", data.Tables.UserActivity},
-		{"api_keys", data.Tables.APIKeys},
-		{"cloudinary_credentials", data.Tables.CloudinaryCredentials},
-		{"email_provider", data.Tables.EmailProvider},
-	}
-
-	totalRows := 0
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	tables, err := s.backupTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database schema: %w", err)
+	}
+	byName := make(map[string]*backupTable, len(tables))
 	for _, table := range tables {
-		if len(table.data) > 0 {
-			if err := s.importTable(ctx, table.name, table.data); err != nil {
-				return 0, fmt.Errorf("failed to import %s: %w", table.name, err)
+		byName[table.name] = table
+	}
+
+	dec := json.NewDecoder(r)
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return nil, errInvalidBackup
+	}
+
+	var header backupHeader
+	sawVersion := false
+	result := &BackupImportResult{}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return nil, errInvalidBackup
+		}
+		switch key {
+		case "version":
+			if err := dec.Decode(&header.Version); err != nil {
+				return nil, errInvalidBackup
+			}
+			sawVersion = true
+		case "format":
+			if err := dec.Decode(&header.Format); err != nil {
+				return nil, errInvalidBackup
+			}
+		case "tables":
+			if !sawVersion {
+				return nil, fmt.Errorf("invalid backup file: version must precede table data")
+			}
+			if header.Version != currentVersion {
+				return nil, fmt.Errorf("migration version mismatch: backup is v%d, database is v%d", header.Version, currentVersion)
+			}
+			if err := s.importBackupTables(ctx, dec, byName, header.Format >= backupFormat, result); err != nil {
+				return nil, err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, errInvalidBackup
+			}
+		}
+	}
+	if err := expectJSONDelim(dec, '}'); err != nil {
+		return nil, errInvalidBackup
+	}
+	result.Version = header.Version
+
+	for i := range result.Tables {
+		summary := &result.Tables[i]
+		if err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdent(summary.Table))).Scan(&summary.Count); err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %w", summary.Table, err)
+		}
+		summary.Verified = summary.Imported == summary.Rows && summary.Count >= int64(summary.Rows)
+	}
+	return result, nil
+}
+
+// importBackupTables reads the "tables" object and inserts every known table
+// inside a single transaction.
+func (s *Server) importBackupTables(ctx context.Context, dec *json.Decoder, tables map[string]*backupTable, base64Binary bool, result *BackupImportResult) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if s.db.Driver != "postgres" {
+		// Check foreign keys at commit so self-references and cycles
+		// restore regardless of row order
+		if _, err := tx.ExecContext(ctx, "PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+	}
+
+	var deferred []backupDeferredRef
+
+	if err := expectJSONDelim(dec, '{'); err != nil {
+		return errInvalidBackup
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return errInvalidBackup
+		}
+		name, _ := tok.(string)
+		table, ok := tables[name]
+		if !ok {
+			if err := skipJSONArray(dec); err != nil {
+				return errInvalidBackup
 			}
-			s.logger.Info("Imported table", zap.String("table", table.name), zap.Int("rows", len(table.data)))
-			totalRows += len(table.data)
+			result.SkippedTables = append(result.SkippedTables, name)
+			s.logger.Warn("Skipping unknown table in backup", zap.String("table", name))
+			continue
+		}
+
+		summary, err := s.importTable(ctx, tx, dec, table, base64Binary, &deferred)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", name, err)
 		}
+		result.Tables = append(result.Tables, summary)
+		result.Rows += summary.Imported
+		s.logger.Info("Imported table", zap.String("table", name), zap.Int("rows", summary.Imported))
+	}
+	if err := expectJSONDelim(dec, '}'); err != nil {
+		return errInvalidBackup
 	}
-	return totalRows, nil
+
+	// Every row exists now, so the references held back by importTable can
+	// be filled in without tripping Postgres' immediate foreign key checks
+	for _, ref := range deferred {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?",
+			quoteIdent(ref.table), quoteIdent(ref.column))), ref.value, ref.id); err != nil {
+			return fmt.Errorf("failed to restore %s.%s: %w", ref.table, ref.column, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// backupDeferredRef is a foreign key value inserted as NULL and set once all
+// tables are restored
+type backupDeferredRef struct {
+	table  string
+	column string
+	id     interface{}
+	value  interface{}
 }
 
 // HandleCopyFromEnv copies database from another environment (non-production only).
@@ -277,115 +404,222 @@ func (s *Server) HandleCopyFromEnv(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var data BackupData
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		respondError(w, http.StatusBadGateway, "failed to parse backup from source environment", "upstream_error")
-		return
-	}
-
 	s.logger.Info("Starting copy from environment",
 		zap.Int64("user_id", userID),
-		zap.String("source_url", req.SourceURL),
-		zap.Int("version", data.Version))
+		zap.String("source_url", req.SourceURL))
 
-	rows, err := s.importBackupData(ctx, &data)
+	result, err := s.importBackupData(ctx, resp.Body)
 	if err != nil {
 		s.logger.Error("Copy from environment failed", zap.Error(err))
 		respondError(w, http.StatusInternalServerError, err.Error(), "import_error")
 		return
 	}
 
-	s.logger.Info("Copy from environment completed", zap.Int64("user_id", userID), zap.Int("rows", rows))
+	s.logger.Info("Copy from environment completed", zap.Int64("user_id", userID), zap.Int("rows", result.Rows))
+	auditChange(r, auditEvent{
+		Action: "backup.copied_from_env", ResourceType: "backup",
+		After: map[string]interface{}{"source_url": req.SourceURL, "version": result.Version, "rows": result.Rows},
+	})
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Database copied successfully",
-		"version": data.Version,
-		"rows":    rows,
+		"message":        "Database copied successfully",
+		"version":        result.Version,
+		"rows":           result.Rows,
+		"tables":         result.Tables,
+		"skipped_tables": result.SkippedTables,
 	})
 }
 
-// exportTable exports all data from a table
-func (s *Server) exportTable(ctx context.Context, tableName string) ([]map[string]interface{}, error) {
-	query := fmt.Sprintf("SELECT * FROM %s", tableName)
+// exportSecret returns a stored secret as it's written to backups. Backups
+// never hold decrypted secrets: without an encryption key they are left out
+// and have to be entered again after a restore.
+func (s *Server) exportSecret(value string) (string, error) {
+	sealed, err := s.db.SealSecret(value)
+	if errors.Is(err, secrets.ErrNoKey) {
+		return "", nil
+	}
+	return sealed, err
+}
+
+// exportTable writes the rows of a table as comma-separated JSON objects
+func (s *Server) exportTable(ctx context.Context, w io.Writer, flush func(), table *backupTable) (int, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", joinStrings(table.columnList(), ", "), quoteIdent(table.name))
+	if table.columns["id"] {
+		query += " ORDER BY id"
+	}
 	rows, err := s.db.DB.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer rows.Close()
 
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	var result []map[string]interface{}
+	count := 0
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
 		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
+			return count, err
 		}
 
-		row := make(map[string]interface{})
+		row := make(map[string]interface{}, len(columns))
 		for i, col := range columns {
 			val := values[i]
-			// Convert []byte to string for easier JSON handling
 			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
+				if table.binary[col] {
+					row[col] = base64.StdEncoding.EncodeToString(b)
+				} else {
+					// Convert []byte to string for easier JSON handling
+					row[col] = string(b)
+				}
 			} else {
 				row[col] = val
 			}
+			if str, ok := row[col].(string); ok && table.secret[col] {
+				if row[col], err = s.exportSecret(str); err != nil {
+					return count, err
+				}
+			}
+		}
+
+		data, err := json.Marshal(row)
+		if err != nil {
+			return count, err
+		}
+		if count > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return count, err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return count, err
+		}
+		count++
+		if count%backupChunkSize == 0 {
+			flush()
 		}
-		result = append(result, row)
 	}
 
-	return result, rows.Err()
+	return count, rows.Err()
 }
 
-// importTable imports data into a table
-func (s *Server) importTable(ctx context.Context, tableName string, data []map[string]interface{}) error {
-	if len(data) == 0 {
-		return nil
-	}
-
-	// Get column names from first row
-	var columns []string
-	for col := range data[0] {
-		columns = append(columns, col)
+// importTable inserts the next JSON array of rows into a table, one
+// prepared statement per distinct column set. Values of the table's deferred
+// columns are appended to deferred instead of inserted.
+func (s *Server) importTable(ctx context.Context, tx *sql.Tx, dec *json.Decoder, table *backupTable, base64Binary bool, deferred *[]backupDeferredRef) (BackupTableSummary, error) {
+	summary := BackupTableSummary{Table: table.name}
+	if err := expectJSONDelim(dec, '['); err != nil {
+		return summary, errInvalidBackup
 	}
 
-	// Build INSERT query
-	placeholders := make([]string, len(columns))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
+	stmts := map[string]*sql.Stmt{}
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
 
-	query := fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)",
-		tableName,
-		joinStrings(columns, ", "),
-		joinStrings(placeholders, ", "))
+	for dec.More() {
+		var row map[string]interface{}
+		if err := dec.Decode(&row); err != nil {
+			return summary, errInvalidBackup
+		}
+		summary.Rows++
 
-	stmt, err := s.db.DB.PrepareContext(ctx, query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
+		columns := make([]string, 0, len(row))
+		for col := range row {
+			if !table.columns[col] {
+				return summary, fmt.Errorf("unknown column %q", col)
+			}
+			columns = append(columns, col)
+		}
+		sort.Strings(columns)
+
+		key := strings.Join(columns, ",")
+		stmt, ok := stmts[key]
+		if !ok {
+			var err error
+			stmt, err = tx.PrepareContext(ctx, s.backupInsertQuery(table, columns))
+			if err != nil {
+				return summary, err
+			}
+			stmts[key] = stmt
+		}
 
-	for _, row := range data {
 		values := make([]interface{}, len(columns))
 		for i, col := range columns {
 			values[i] = row[col]
+			if str, ok := row[col].(string); ok && table.binary[col] && base64Binary {
+				b, err := base64.StdEncoding.DecodeString(str)
+				if err != nil {
+					return summary, fmt.Errorf("invalid base64 in column %q", col)
+				}
+				values[i] = b
+			}
+			if table.deferred[col] && values[i] != nil {
+				*deferred = append(*deferred, backupDeferredRef{table: table.name, column: col, id: row["id"], value: values[i]})
+				values[i] = nil
+			}
 		}
 		if _, err := stmt.ExecContext(ctx, values...); err != nil {
-			return err
+			return summary, err
+		}
+		summary.Imported++
+		if summary.Imported%backupChunkSize == 0 {
+			s.logger.Debug("Importing table", zap.String("table", table.name), zap.Int("rows", summary.Imported))
+		}
+	}
+	if err := expectJSONDelim(dec, ']'); err != nil {
+		return summary, errInvalidBackup
+	}
+
+	// Explicit IDs bypass the Postgres sequence; move it past the restored rows
+	if s.db.Driver == "postgres" && table.columns["id"] && summary.Imported > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s", table.name, quoteIdent(table.name))); err != nil {
+			return summary, err
 		}
 	}
 
-	return nil
+	return summary, nil
+}
+
+// backupInsertQuery builds an insert that overwrites rows with the same key
+func (s *Server) backupInsertQuery(table *backupTable, columns []string) string {
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdent(col)
+		placeholders[i] = "?"
+	}
+
+	if s.db.Driver != "postgres" {
+		return fmt.Sprintf("INSERT OR REPLACE INTO %s (%s) VALUES (%s)",
+			quoteIdent(table.name), joinStrings(quoted, ", "), joinStrings(placeholders, ", "))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdent(table.name), joinStrings(quoted, ", "), joinStrings(placeholders, ", "))
+	if !table.columns["id"] {
+		return s.db.Rebind(query + " ON CONFLICT DO NOTHING")
+	}
+	updates := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if col != "id" {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(col), quoteIdent(col)))
+		}
+	}
+	if len(updates) == 0 {
+		return s.db.Rebind(query + " ON CONFLICT (id) DO NOTHING")
+	}
+	return s.db.Rebind(query + " ON CONFLICT (id) DO UPDATE SET " + joinStrings(updates, ", "))
 }
 
 // getCurrentMigrationVersion gets the current migration version from schema_migrations
@@ -408,24 +642,32 @@ func joinStrings(strs []string, sep string) string {
 	return result
 }
 
-func countTotalRows(tables TableData) int {
-	return len(tables.Users) +
-		len(tables.Teams) +
-		len(tables.TeamMembers) +
-		len(tables.Projects) +
-		len(tables.ProjectMembers) +
-		len(tables.ProjectInvitations) +
-		len(tables.Sprints) +
-		len(tables.SwimLanes) +
-		len(tables.Tasks) +
-		len(tables.TaskComments) +
-		len(tables.TaskAttachments) +
-		len(tables.Tags) +
-		len(tables.TaskTags) +
-		len(tables.Invites) +
-		len(tables.TeamInvitations) +
-		len(tables.UserActivity) +
-		len(tables.APIKeys) +
-		len(tables.CloudinaryCredentials) +
-		len(tables.EmailProvider)
+// quoteIdent quotes a table or column name taken from the schema catalog
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func expectJSONDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("expected %q", want)
+	}
+	return nil
+}
+
+// skipJSONArray consumes an array without keeping its elements
+func skipJSONArray(dec *json.Decoder) error {
+	if err := expectJSONDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return err
+		}
+	}
+	return expectJSONDelim(dec, ']')
 }
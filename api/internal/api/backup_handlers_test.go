@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"taskai/internal/secrets"
+)
+
+func TestBackupRoundTrip(t *testing.T) {
+	src := NewTestServer(t)
+	defer src.Close()
+
+	adminID := src.CreateTestUser(t, "admin@example.com", "password123")
+	if _, err := src.DB.Exec(`UPDATE users SET is_admin = 1 WHERE id = ?`, adminID); err != nil {
+		t.Fatalf("Failed to promote admin: %v", err)
+	}
+	projectID := src.CreateTestProject(t, adminID, "Backup Project")
+	pageID := src.createTestWikiPage(t, projectID, adminID, "Docs")
+	// The child page has a lower ID than its parent, so restoring rows in ID
+	// order inserts it before the page it references
+	parentID := src.createTestWikiPage(t, projectID, adminID, "Guides")
+	if _, err := src.DB.Exec(`UPDATE wiki_pages SET parent_id = ? WHERE id = ?`, parentID, pageID); err != nil {
+		t.Fatalf("Failed to nest page: %v", err)
+	}
+	blob := []byte{0, 0xff, 0x80, 'x'}
+	if _, err := src.DB.Exec(`INSERT INTO yjs_updates (page_id, update_data, created_by) VALUES (?, ?, ?)`,
+		pageID, blob, adminID); err != nil {
+		t.Fatalf("Failed to log update: %v", err)
+	}
+
+	rec, req := src.MakeAuthRequest(t, http.MethodGet, "/api/admin/backup/export", nil, adminID, nil)
+	src.HandleExportData(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	export := rec.Body.Bytes()
+
+	var backup struct {
+		Version int                                 `json:"version"`
+		Format  int                                 `json:"format"`
+		Tables  map[string][]map[string]interface{} `json:"tables"`
+	}
+	if err := json.Unmarshal(export, &backup); err != nil {
+		t.Fatalf("Export is not valid JSON: %v", err)
+	}
+	if backup.Format != backupFormat {
+		t.Errorf("Expected format %d, got %d", backupFormat, backup.Format)
+	}
+	for _, table := range []string{"wiki_pages", "yjs_updates", "page_versions", "project_members"} {
+		if _, ok := backup.Tables[table]; !ok {
+			t.Errorf("Expected table %s in export", table)
+		}
+	}
+	if _, ok := backup.Tables["schema_migrations"]; ok {
+		t.Error("Expected schema_migrations to be excluded")
+	}
+	if updates := backup.Tables["yjs_updates"]; len(updates) != 1 || updates[0]["update_data"] != base64.StdEncoding.EncodeToString(blob) {
+		t.Errorf("Expected binary update as base64, got %v", updates)
+	}
+	if bytes.Index(export, []byte(`"users":[`)) > bytes.Index(export, []byte(`"projects":[`)) ||
+		bytes.Index(export, []byte(`"wiki_pages":[`)) > bytes.Index(export, []byte(`"yjs_updates":[`)) {
+		t.Error("Expected referenced tables to be exported first")
+	}
+
+	tables, err := src.backupTables(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read backup tables: %v", err)
+	}
+	for _, table := range tables {
+		if table.name == "wiki_pages" && !table.deferred["parent_id"] {
+			t.Error("Expected wiki_pages.parent_id to be restored in a second pass")
+		}
+	}
+
+	t.Run("import restores and verifies every table", func(t *testing.T) {
+		dst := NewTestServer(t)
+		defer dst.Close()
+
+		result, err := dst.importBackupData(context.Background(), bytes.NewReader(export))
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if len(result.Tables) != len(backup.Tables) || len(result.SkippedTables) != 0 {
+			t.Errorf("Expected %d restored tables, got %d (skipped %v)", len(backup.Tables), len(result.Tables), result.SkippedTables)
+		}
+		for _, summary := range result.Tables {
+			if !summary.Verified {
+				t.Errorf("Table %s not verified: %+v", summary.Table, summary)
+			}
+		}
+
+		var restored []byte
+		if err := dst.DB.QueryRow(`SELECT update_data FROM yjs_updates WHERE page_id = ?`, pageID).Scan(&restored); err != nil {
+			t.Fatalf("Failed to load restored update: %v", err)
+		}
+		if !bytes.Equal(restored, blob) {
+			t.Errorf("Expected binary data to survive, got %v", restored)
+		}
+
+		var restoredParent int64
+		if err := dst.DB.QueryRow(`SELECT parent_id FROM wiki_pages WHERE id = ?`, pageID).Scan(&restoredParent); err != nil {
+			t.Fatalf("Failed to load restored page: %v", err)
+		}
+		if restoredParent != parentID {
+			t.Errorf("Expected page %d to keep parent %d, got %d", pageID, parentID, restoredParent)
+		}
+	})
+
+	t.Run("rejects version mismatch and truncated files", func(t *testing.T) {
+		dst := NewTestServer(t)
+		defer dst.Close()
+
+		if _, err := dst.importBackupData(context.Background(), bytes.NewReader(export[:len(export)/2])); err == nil {
+			t.Error("Expected truncated backup to be rejected")
+		}
+		if _, err := dst.importBackupData(context.Background(),
+			bytes.NewReader([]byte(`{"version":1,"tables":{"users":[]}}`))); err == nil || !contains(err.Error(), "version mismatch") {
+			t.Errorf("Expected version mismatch, got %v", err)
+		}
+	})
+}
+
+func TestBackupNeverExportsDecryptedSecrets(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+	if _, err := ts.DB.Exec(`UPDATE users SET is_admin = 1 WHERE id = ?`, adminID); err != nil {
+		t.Fatalf("Failed to promote admin: %v", err)
+	}
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/cloudinary/credentials", SaveCloudinaryCredentialRequest{
+		CloudName: "cloud", APIKey: "key", APISecret: "cloudinary-plaintext",
+	}, adminID, nil)
+	ts.HandleSaveCloudinaryCredential(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	exportSecret := func() string {
+		t.Helper()
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/admin/backup/export", nil, adminID, nil)
+		ts.HandleExportData(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if bytes.Contains(rec.Body.Bytes(), []byte("cloudinary-plaintext")) {
+			t.Fatal("Expected the export to never contain the plaintext secret")
+		}
+		var backup struct {
+			Tables map[string][]map[string]interface{} `json:"tables"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &backup); err != nil {
+			t.Fatalf("Export is not valid JSON: %v", err)
+		}
+		creds := backup.Tables["cloudinary_credentials"]
+		if len(creds) != 1 {
+			t.Fatalf("Expected one credential, got %v", creds)
+		}
+		secret, _ := creds[0]["api_secret"].(string)
+		return secret
+	}
+
+	// Without a key secrets are stored as they are, so they are left out
+	if secret := exportSecret(); secret != "" {
+		t.Errorf("Expected the secret to be left out without a key, got %q", secret)
+	}
+
+	keyring, _ := secrets.NewKeyring(bytes.Repeat([]byte{7}, 32))
+	ts.DB.SetSecretKeyring(keyring)
+	secret := exportSecret()
+	if !secrets.IsEncrypted(secret) {
+		t.Fatalf("Expected the legacy secret to be exported encrypted, got %q", secret)
+	}
+
+	rec, req = ts.MakeAuthRequest(t, http.MethodPost, "/api/cloudinary/credentials", SaveCloudinaryCredentialRequest{
+		CloudName: "cloud", APIKey: "key", APISecret: "rotated-secret",
+	}, adminID, nil)
+	ts.HandleSaveCloudinaryCredential(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var stored string
+	if err := ts.DB.QueryRow(`SELECT api_secret FROM cloudinary_credentials WHERE user_id = ?`, adminID).Scan(&stored); err != nil {
+		t.Fatalf("Failed to load secret: %v", err)
+	}
+	if !secrets.IsEncrypted(stored) {
+		t.Errorf("Expected the secret to be stored encrypted, got %q", stored)
+	}
+	if _, _, apiSecret, herr := ts.fetchCloudinarySecret(context.Background(), adminID); herr != nil || apiSecret != "rotated-secret" {
+		t.Errorf("Expected the stored secret to decrypt, got %q, %v", apiSecret, herr)
+	}
+	if exportSecret() != stored {
+		t.Error("Expected encrypted secrets to be exported as stored")
+	}
+}
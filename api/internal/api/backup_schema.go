@@ -0,0 +1,307 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"entgo.io/ent/schema/field"
+
+	"taskai/ent/migrate"
+	"taskai/internal/db"
+)
+
+// backupExcludedTables are never exported or imported. schema_migrations is
+// covered by the backup's version field instead.
+var backupExcludedTables = map[string]bool{
+	"schema_migrations": true,
+}
+
+// backupTable describes a table covered by backups
+type backupTable struct {
+	name    string
+	columns map[string]bool
+	notNull map[string]bool
+	binary  map[string]bool   // columns exported as base64
+	secret  map[string]bool   // columns holding third-party secrets, exported encrypted
+	refs    map[string]bool   // tables referenced by foreign keys
+	fks     map[string]string // foreign key column -> referenced table
+	// deferred holds nullable foreign key columns that point at the table
+	// itself or at a table restored after it. Imports leave them NULL and
+	// fill them in once every table is loaded.
+	deferred map[string]bool
+}
+
+// columnList returns the table's columns in name order
+func (t *backupTable) columnList() []string {
+	cols := make([]string, 0, len(t.columns))
+	for col := range t.columns {
+		cols = append(cols, quoteIdent(col))
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func newBackupTable(name string) *backupTable {
+	return &backupTable{
+		name:     name,
+		columns:  map[string]bool{},
+		notNull:  map[string]bool{},
+		binary:   map[string]bool{},
+		secret:   map[string]bool{},
+		refs:     map[string]bool{},
+		fks:      map[string]string{},
+		deferred: map[string]bool{},
+	}
+}
+
+// backupTables returns every table to back up, parents before the tables
+// that reference them. Ent's migrate.Tables provides the modelled schema;
+// tables and columns added by raw SQL migrations are picked up from the
+// database catalog, so nothing has to be listed by hand.
+func (s *Server) backupTables(ctx context.Context) ([]*backupTable, error) {
+	var catalog map[string]*backupTable
+	var err error
+	if s.db.Driver == "postgres" {
+		catalog, err = s.postgresBackupCatalog(ctx)
+	} else {
+		catalog, err = s.sqliteBackupCatalog(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range migrate.Tables {
+		table, ok := catalog[t.Name]
+		if !ok {
+			continue
+		}
+		for _, col := range t.Columns {
+			if col.Type == field.TypeBytes {
+				table.binary[col.Name] = true
+			}
+		}
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable != nil {
+				table.refs[fk.RefTable.Name] = true
+				for _, col := range fk.Columns {
+					table.fks[col.Name] = fk.RefTable.Name
+				}
+			}
+		}
+	}
+
+	for _, col := range db.SecretColumns {
+		if table, ok := catalog[col.Table]; ok {
+			table.secret[col.Column] = true
+		}
+	}
+
+	for name := range backupExcludedTables {
+		delete(catalog, name)
+	}
+	return sortBackupTables(catalog), nil
+}
+
+// sortBackupTables orders tables so referenced tables come first. Ties and
+// any reference cycles fall back to name order; the foreign keys that still
+// point forward are marked deferred.
+func sortBackupTables(catalog map[string]*backupTable) []*backupTable {
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ordered := make([]*backupTable, 0, len(names))
+	done := make(map[string]bool, len(names))
+	for len(ordered) < len(names) {
+		progressed := false
+		for _, name := range names {
+			if done[name] {
+				continue
+			}
+			ready := true
+			for ref := range catalog[name].refs {
+				if ref != name && !done[ref] && catalog[ref] != nil {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, catalog[name])
+				done[name] = true
+				progressed = true
+			}
+		}
+		if !progressed {
+			// Cycle: emit the first remaining table and carry on
+			for _, name := range names {
+				if !done[name] {
+					ordered = append(ordered, catalog[name])
+					done[name] = true
+					break
+				}
+			}
+		}
+	}
+
+	restored := make(map[string]bool, len(ordered))
+	for _, table := range ordered {
+		restored[table.name] = true
+		if !table.columns["id"] {
+			continue
+		}
+		for col, ref := range table.fks {
+			if table.notNull[col] {
+				continue
+			}
+			if ref == table.name || !restored[ref] && catalog[ref] != nil {
+				table.deferred[col] = true
+			}
+		}
+	}
+	return ordered
+}
+
+// sqliteBackupCatalog reads tables, columns and foreign keys from SQLite.
+// Virtual tables and their shadow tables (the FTS index) are skipped; triggers
+// rebuild them from the base tables. Queries run one after another because
+// tests use a single connection.
+func (s *Server) sqliteBackupCatalog(ctx context.Context) (map[string]*backupTable, error) {
+	names, err := queryStrings(ctx, s.db.DB,
+		`SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite_%'`)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := make(map[string]*backupTable, len(names))
+	for _, name := range names {
+		table := newBackupTable(name)
+
+		rows, err := s.db.QueryContext(ctx, `SELECT name, type, "notnull" FROM pragma_table_info(?)`, name)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var col, typ string
+			var notNull bool
+			if err := rows.Scan(&col, &typ, &notNull); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			table.columns[col] = true
+			table.notNull[col] = notNull
+			if strings.EqualFold(typ, "BLOB") {
+				table.binary[col] = true
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		rows, err = s.db.QueryContext(ctx, `SELECT "from", "table" FROM pragma_foreign_key_list(?)`, name)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var col, ref string
+			if err := rows.Scan(&col, &ref); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			table.refs[ref] = true
+			table.fks[col] = ref
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		catalog[name] = table
+	}
+	return catalog, nil
+}
+
+// postgresBackupCatalog reads tables, columns and foreign keys from the
+// current Postgres schema. Generated columns (search vectors) are left out.
+func (s *Server) postgresBackupCatalog(ctx context.Context) (map[string]*backupTable, error) {
+	names, err := queryStrings(ctx, s.db.DB,
+		`SELECT table_name FROM information_schema.tables
+		 WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'`)
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(map[string]*backupTable, len(names))
+	for _, name := range names {
+		catalog[name] = newBackupTable(name)
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns
+		 WHERE table_schema = current_schema() AND is_generated = 'NEVER'`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name, col, typ, nullable string
+		if err := rows.Scan(&name, &col, &typ, &nullable); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if table, ok := catalog[name]; ok {
+			table.columns[col] = true
+			table.notNull[col] = nullable == "NO"
+			if typ == "bytea" {
+				table.binary[col] = true
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx,
+		`SELECT tc.table_name, kcu.column_name, ccu.table_name
+		 FROM information_schema.table_constraints tc
+		 JOIN information_schema.key_column_usage kcu
+		   ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		 JOIN information_schema.constraint_column_usage ccu
+		   ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+		 WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, col, ref string
+		if err := rows.Scan(&name, &col, &ref); err != nil {
+			return nil, err
+		}
+		if table, ok := catalog[name]; ok {
+			table.refs[ref] = true
+			table.fks[col] = ref
+		}
+	}
+	return catalog, rows.Err()
+}
+
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
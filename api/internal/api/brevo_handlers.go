@@ -127,8 +127,20 @@ func (s *Server) HandleSaveEmailProvider(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var before interface{}
+	if prev, err := s.getEmailProvider(ctx); err == nil {
+		before = prev.toResponse()
+	}
+
+	apiKey, err := s.db.EncryptSecret(req.APIKey)
+	if err != nil {
+		s.logger.Error("Failed to encrypt email provider API key", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to save email provider", "internal_error")
+		return
+	}
+
 	// Upsert the email provider (singleton — always id=1)
-	_, err := s.db.ExecContext(ctx,
+	_, err = s.db.ExecContext(ctx,
 		`INSERT INTO email_provider (id, provider, api_key, sender_email, sender_name, updated_at)
 		 VALUES (1, 'brevo', $1, $2, $3, CURRENT_TIMESTAMP)
 		 ON CONFLICT(id) DO UPDATE SET
@@ -136,7 +148,7 @@ func (s *Server) HandleSaveEmailProvider(w http.ResponseWriter, r *http.Request)
 		   sender_email = excluded.sender_email,
 		   sender_name = excluded.sender_name,
 		   updated_at = CURRENT_TIMESTAMP`,
-		req.APIKey, req.SenderEmail, req.SenderName,
+		apiKey, req.SenderEmail, req.SenderName,
 	)
 	if err != nil {
 		s.logger.Error("Failed to save email provider", zap.Error(err))
@@ -173,6 +185,10 @@ func (s *Server) HandleSaveEmailProvider(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "email_provider.saved", ResourceType: "email_provider", ResourceID: ep.ID,
+		Before: before, After: ep.toResponse(),
+	})
 	respondJSON(w, http.StatusOK, ep.toResponse())
 }
 
@@ -191,6 +207,11 @@ func (s *Server) HandleDeleteEmailProvider(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	var before interface{}
+	if prev, err := s.getEmailProvider(ctx); err == nil {
+		before = prev.toResponse()
+	}
+
 	_, err := s.db.ExecContext(ctx, `DELETE FROM email_provider WHERE id = 1`)
 	if err != nil {
 		s.logger.Error("Failed to delete email provider", zap.Error(err))
@@ -201,6 +222,9 @@ func (s *Server) HandleDeleteEmailProvider(w http.ResponseWriter, r *http.Reques
 	s.invalidateEmailService()
 
 	s.logger.Info("Email provider deleted", zap.Int64("admin_id", userID))
+	auditChange(r, auditEvent{
+		Action: "email_provider.deleted", ResourceType: "email_provider", ResourceID: 1, Before: before,
+	})
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Email provider deleted"})
 }
 
@@ -224,6 +248,9 @@ func (s *Server) HandleTestEmailProvider(w http.ResponseWriter, r *http.Request)
 	err := s.db.QueryRowContext(ctx,
 		`SELECT api_key, consecutive_failures FROM email_provider WHERE id = 1`,
 	).Scan(&apiKey, &consecutiveFailures)
+	if err == nil {
+		apiKey, err = s.db.DecryptSecret(apiKey)
+	}
 
 	if err == sql.ErrNoRows {
 		respondError(w, http.StatusBadRequest, "no email provider configured", "no_credentials")
@@ -300,6 +327,9 @@ func (s *Server) getEmailProvider(ctx context.Context) (*EmailProvider, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ep.APIKey, err = s.db.DecryptSecret(ep.APIKey); err != nil {
+		return nil, err
+	}
 	return &ep, nil
 }
 
@@ -329,6 +359,9 @@ func (s *Server) checkBrevoHealth() {
 	err := s.db.QueryRowContext(ctx,
 		`SELECT api_key, consecutive_failures FROM email_provider WHERE id = 1`,
 	).Scan(&apiKey, &consecutiveFailures)
+	if err == nil {
+		apiKey, err = s.db.DecryptSecret(apiKey)
+	}
 
 	if err != nil {
 		// No provider configured — nothing to check
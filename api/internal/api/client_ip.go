@@ -0,0 +1,94 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are the networks allowed to report a client's address in
+// X-Forwarded-For or X-Real-IP. Requests from anywhere else are identified by
+// the address they connect from, so clients can't pick their own IP.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses CIDRs such as "10.0.0.0/8". A bare IP trusts
+// only that address.
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", cidr)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			cidr = fmt.Sprintf("%s/%d", cidr, bits)
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func (tp TrustedProxies) trusts(ip net.IP) bool {
+	for _, network := range tp {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that made r. Forwarding headers
+// are only believed when the connection comes from a trusted proxy.
+// X-Forwarded-For is read from the right, so entries the client added itself
+// are skipped; X-Real-IP is used when a proxy sent no X-Forwarded-For.
+func (tp TrustedProxies) ClientIP(r *http.Request) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	peer := net.ParseIP(remote)
+	if peer == nil || !tp.trusts(peer) {
+		return remote
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			if !tp.trusts(ip) || i == 0 {
+				return ip.String()
+			}
+		}
+		return remote
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); realIP != nil {
+		return realIP.String()
+	}
+	return remote
+}
+
+// RealIP replaces chi's middleware.RealIP. It sets r.RemoteAddr to ClientIP
+// and drops the forwarding headers so later handlers can't read a spoofed
+// address from them.
+func (tp TrustedProxies) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.RemoteAddr = tp.ClientIP(r)
+		r.Header.Del("X-Forwarded-For")
+		r.Header.Del("X-Real-IP")
+		next.ServeHTTP(w, r)
+	})
+}
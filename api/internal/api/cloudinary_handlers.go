@@ -197,7 +197,13 @@ func (s *Server) HandleSaveCloudinaryCredential(w http.ResponseWriter, r *http.R
 		maxSize = *req.MaxFileSizeMB
 	}
 
-	_, err := s.db.ExecContext(ctx,
+	apiSecret, err := s.db.EncryptSecret(req.APISecret)
+	if err != nil {
+		s.logger.Error("Failed to encrypt cloudinary secret", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to save credentials", "internal_error")
+		return
+	}
+	_, err = s.db.ExecContext(ctx,
 		`INSERT INTO cloudinary_credentials (user_id, cloud_name, api_key, api_secret, max_file_size_mb, updated_at)
 		 VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP)
 		 ON CONFLICT(user_id) DO UPDATE SET
@@ -206,7 +212,7 @@ func (s *Server) HandleSaveCloudinaryCredential(w http.ResponseWriter, r *http.R
 		   api_secret = excluded.api_secret,
 		   max_file_size_mb = excluded.max_file_size_mb,
 		   updated_at = CURRENT_TIMESTAMP`,
-		userID, req.CloudName, req.APIKey, req.APISecret, maxSize,
+		userID, req.CloudName, req.APIKey, apiSecret, maxSize,
 	)
 	if err != nil {
 		s.logger.Error("Failed to save cloudinary credentials", zap.Error(err))
@@ -310,6 +316,9 @@ func (s *Server) HandleTestCloudinaryConnection(w http.ResponseWriter, r *http.R
 		`SELECT cloud_name, api_key, api_secret, consecutive_failures
 		 FROM cloudinary_credentials WHERE user_id = $1`, userID,
 	).Scan(&cloudName, &apiKey, &apiSecret, &consecutiveFailures)
+	if err == nil {
+		apiSecret, err = s.db.DecryptSecret(apiSecret)
+	}
 
 	if err == sql.ErrNoRows {
 		respondError(w, http.StatusBadRequest, "no Cloudinary credentials configured", "no_credentials")
@@ -486,6 +495,9 @@ func (s *Server) fetchCloudinarySecret(ctx context.Context, userID int64) (cloud
 	err := s.db.QueryRowContext(ctx,
 		`SELECT cloud_name, api_key, api_secret FROM cloudinary_credentials WHERE user_id = $1`, userID,
 	).Scan(&cloudName, &apiKey, &apiSecret)
+	if err == nil {
+		apiSecret, err = s.db.DecryptSecret(apiSecret)
+	}
 	if err == sql.ErrNoRows {
 		return "", "", "", &httpError{http.StatusBadRequest, "no Cloudinary credentials configured", "no_credentials"}
 	}
@@ -565,12 +577,38 @@ func (s *Server) HandleListTaskAttachments(w http.ResponseWriter, r *http.Reques
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	userID := r.Context().Value(UserIDKey).(int64)
+
 	taskID, err := strconv.ParseInt(chi.URLParam(r, "taskId"), 10, 64)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "invalid task ID", "bad_request")
 		return
 	}
 
+	var projectID int64
+	err = s.db.QueryRowContext(ctx,
+		`SELECT project_id FROM tasks WHERE id = $1`, taskID,
+	).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "task not found", "not_found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up task", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to look up task", "internal_error")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT ta.id, ta.task_id, ta.project_id, ta.user_id, ta.filename, ta.alt_name,
 		        ta.file_type, ta.content_type, ta.file_size,
@@ -644,6 +682,16 @@ func (s *Server) HandleCreateTaskAttachment(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermTaskEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
 	// Enforce 99-attachment limit per task
 	var attachmentCount int
 	err = s.db.QueryRowContext(ctx,
@@ -758,7 +806,7 @@ func (s *Server) HandleListImages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -983,7 +1031,7 @@ func (s *Server) HandleListAssets(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the current user is a member of this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		s.logger.Error("Failed to check project access", zap.Error(err))
 		respondError(w, http.StatusInternalServerError, "internal error", "internal_error")
@@ -1098,12 +1146,38 @@ func (s *Server) HandleListWikiPageAttachments(w http.ResponseWriter, r *http.Re
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	userID := r.Context().Value(UserIDKey).(int64)
+
 	pageID, err := strconv.ParseInt(chi.URLParam(r, "pageId"), 10, 64)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "invalid page ID", "bad_request")
 		return
 	}
 
+	var projectID int64
+	err = s.db.QueryRowContext(ctx,
+		`SELECT project_id FROM wiki_pages WHERE id = $1`, pageID,
+	).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "wiki page not found", "not_found")
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up wiki page", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to look up wiki page", "internal_error")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT wa.id, wa.wiki_page_id, wa.project_id, wa.user_id, wa.filename, wa.alt_name,
 		        wa.file_type, wa.content_type, wa.file_size,
@@ -1177,6 +1251,16 @@ func (s *Server) HandleCreateWikiPageAttachment(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermWikiEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
 	// Enforce 100-attachment limit per wiki page
 	var attachmentCount int
 	err = s.db.QueryRowContext(ctx,
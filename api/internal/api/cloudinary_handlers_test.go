@@ -31,7 +31,7 @@ func createTestProjectForCloudinary(t *testing.T, ts *TestServer, ownerID int64)
 		t.Fatalf("Failed to get project ID: %v", err)
 	}
 
-	// Add creator as project member so checkProjectAccess passes
+	// Add creator as project member so authorizeProject passes
 	_, err = ts.DB.ExecContext(ctx,
 		`INSERT INTO project_members (project_id, user_id, role, granted_by) VALUES (?, ?, 'owner', ?)`,
 		id, ownerID, ownerID,
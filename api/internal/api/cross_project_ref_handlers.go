@@ -56,8 +56,8 @@ func (s *Server) HandleShareSprint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	srcAccess, _ := s.checkProjectAccess(ctx, userID, sourceProjectID)
-	dstAccess, _ := s.checkProjectAccess(ctx, userID, req.ProjectID)
+	srcAccess, _ := s.authorizeProject(ctx, userID, sourceProjectID, PermProjectEdit)
+	dstAccess, _ := s.authorizeProject(ctx, userID, req.ProjectID, PermProjectEdit)
 	if !srcAccess || !dstAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -96,7 +96,7 @@ func (s *Server) HandleUnshareSprint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, _ := s.checkProjectAccess(ctx, userID, targetProjectID)
+	hasAccess, _ := s.authorizeProject(ctx, userID, targetProjectID, PermProjectEdit)
 	if !hasAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -156,8 +156,8 @@ func (s *Server) HandleShareTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	srcAccess, _ := s.checkProjectAccess(ctx, userID, sourceProjectID)
-	dstAccess, _ := s.checkProjectAccess(ctx, userID, req.ProjectID)
+	srcAccess, _ := s.authorizeProject(ctx, userID, sourceProjectID, PermProjectEdit)
+	dstAccess, _ := s.authorizeProject(ctx, userID, req.ProjectID, PermProjectEdit)
 	if !srcAccess || !dstAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -196,7 +196,7 @@ func (s *Server) HandleUnshareTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, _ := s.checkProjectAccess(ctx, userID, targetProjectID)
+	hasAccess, _ := s.authorizeProject(ctx, userID, targetProjectID, PermProjectEdit)
 	if !hasAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -256,8 +256,8 @@ func (s *Server) HandleShareAttachment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	srcAccess, _ := s.checkProjectAccess(ctx, userID, sourceProjectID)
-	dstAccess, _ := s.checkProjectAccess(ctx, userID, req.ProjectID)
+	srcAccess, _ := s.authorizeProject(ctx, userID, sourceProjectID, PermTaskEdit)
+	dstAccess, _ := s.authorizeProject(ctx, userID, req.ProjectID, PermTaskEdit)
 	if !srcAccess || !dstAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -296,7 +296,7 @@ func (s *Server) HandleUnshareAttachment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	hasAccess, _ := s.checkProjectAccess(ctx, userID, targetProjectID)
+	hasAccess, _ := s.authorizeProject(ctx, userID, targetProjectID, PermTaskEdit)
 	if !hasAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
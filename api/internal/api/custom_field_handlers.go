@@ -0,0 +1,629 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+)
+
+const (
+	// maxCustomFields bounds the custom fields of a project
+	maxCustomFields = 50
+	// maxCustomFieldName bounds field names, in characters
+	maxCustomFieldName = 100
+	// maxCustomFieldOptions bounds the options of select fields
+	maxCustomFieldOptions = 100
+	// maxCustomFieldText bounds text values and option names, in characters
+	maxCustomFieldText = 1000
+)
+
+var validCustomFieldTypes = map[string]bool{
+	db.FieldText: true, db.FieldNumber: true, db.FieldSelect: true, db.FieldMultiSelect: true,
+	db.FieldDate: true, db.FieldUser: true, db.FieldURL: true, db.FieldCheckbox: true,
+}
+
+// CreateCustomFieldRequest defines a custom field on a project
+type CreateCustomFieldRequest struct {
+	Name      string   `json:"name"`
+	FieldType string   `json:"field_type"`
+	Options   []string `json:"options,omitempty"` // select and multi_select only
+	Required  bool     `json:"required"`
+	Position  *int     `json:"position,omitempty"` // defaults to after the last field
+}
+
+// UpdateCustomFieldRequest changes a custom field. The type can't change;
+// removing options clears them from tasks.
+type UpdateCustomFieldRequest struct {
+	Name     *string   `json:"name,omitempty"`
+	Options  *[]string `json:"options,omitempty"`
+	Required *bool     `json:"required,omitempty"`
+	Position *int      `json:"position,omitempty"`
+}
+
+// validateCustomFieldOptions trims the options of a select field and checks
+// there are some, without duplicates
+func validateCustomFieldOptions(options []string) ([]string, error) {
+	if len(options) == 0 {
+		return nil, errors.New("select fields need at least one option")
+	}
+	if len(options) > maxCustomFieldOptions {
+		return nil, fmt.Errorf("too many options (max %d)", maxCustomFieldOptions)
+	}
+	seen := map[string]bool{}
+	out := make([]string, 0, len(options))
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			return nil, errors.New("options cannot be empty")
+		}
+		if len(o) > maxCustomFieldText {
+			return nil, fmt.Errorf("option is too long (max %d characters)", maxCustomFieldText)
+		}
+		if seen[strings.ToLower(o)] {
+			return nil, fmt.Errorf("duplicate option %q", o)
+		}
+		seen[strings.ToLower(o)] = true
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+// customFieldNameTaken reports whether another field of the project has the
+// name, case-insensitively
+func customFieldNameTaken(fields []db.CustomField, name string, exceptID int64) bool {
+	for _, f := range fields {
+		if f.ID != exceptID && strings.EqualFold(f.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCustomField returns the field a request's {id} names, after checking
+// the user may edit its project. It responds with the error and returns nil
+// when it can't.
+func (s *Server) loadCustomField(w http.ResponseWriter, r *http.Request) *db.CustomField {
+	ctx := r.Context()
+	userID := ctx.Value(UserIDKey).(int64)
+	fieldID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid custom field ID", "invalid_input")
+		return nil
+	}
+	f, err := s.db.GetCustomField(ctx, fieldID)
+	if errors.Is(err, db.ErrCustomFieldNotFound) {
+		respondError(w, http.StatusNotFound, "custom field not found", "not_found")
+		return nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to get custom field", zap.Int64("field_id", fieldID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to get custom field", "internal_error")
+		return nil
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, f.ProjectID, PermProjectEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return nil
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return nil
+	}
+	return f
+}
+
+// HandleListCustomFields returns the custom fields of a project in display order
+// Route: GET /api/projects/{projectId}/custom-fields
+func (s *Server) HandleListCustomFields(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	fields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to list custom fields", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to list custom fields", "internal_error")
+		return
+	}
+	respondJSON(w, http.StatusOK, fields)
+}
+
+// HandleCreateCustomField adds a custom field to a project
+// Route: POST /api/projects/{projectId}/custom-fields
+func (s *Server) HandleCreateCustomField(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	var req CreateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+	f := db.CustomField{ProjectID: projectID, Name: strings.TrimSpace(req.Name), FieldType: req.FieldType, Required: req.Required}
+	if f.Name == "" {
+		respondError(w, http.StatusBadRequest, "custom field name is required", "invalid_input")
+		return
+	}
+	if len(f.Name) > maxCustomFieldName {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("custom field name is too long (max %d characters)", maxCustomFieldName), "invalid_input")
+		return
+	}
+	if !validCustomFieldTypes[f.FieldType] {
+		respondError(w, http.StatusBadRequest,
+			"invalid field_type (must be: text, number, select, multi_select, date, user, url, or checkbox)", "invalid_input")
+		return
+	}
+	if f.HasOptions() {
+		if f.Options, err = validateCustomFieldOptions(req.Options); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+			return
+		}
+	} else if len(req.Options) > 0 {
+		respondError(w, http.StatusBadRequest, "only select fields have options", "invalid_input")
+		return
+	}
+
+	fields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to list custom fields", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create custom field", "internal_error")
+		return
+	}
+	if len(fields) >= maxCustomFields {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("maximum %d custom fields allowed per project", maxCustomFields), "max_limit_reached")
+		return
+	}
+	if customFieldNameTaken(fields, f.Name, 0) {
+		respondError(w, http.StatusConflict, "a custom field with this name already exists", "conflict")
+		return
+	}
+	if req.Position != nil {
+		if *req.Position < 0 {
+			respondError(w, http.StatusBadRequest, "position cannot be negative", "invalid_input")
+			return
+		}
+		f.Position = *req.Position
+	} else if len(fields) > 0 {
+		f.Position = fields[len(fields)-1].Position + 1
+	}
+
+	if err := s.db.CreateCustomField(ctx, &f); err != nil {
+		s.logger.Error("Failed to create custom field", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create custom field", "internal_error")
+		return
+	}
+
+	auditChange(r, auditEvent{
+		Action: "custom_field.created", ResourceType: "custom_field", ResourceID: f.ID, ProjectID: projectID, After: f,
+	})
+	respondJSON(w, http.StatusCreated, f)
+}
+
+// HandleUpdateCustomField changes a custom field
+// Route: PATCH /api/custom-fields/{id}
+func (s *Server) HandleUpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	var req UpdateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+	f := s.loadCustomField(w, r)
+	if f == nil {
+		return
+	}
+	before := *f
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			respondError(w, http.StatusBadRequest, "custom field name cannot be empty", "invalid_input")
+			return
+		}
+		if len(name) > maxCustomFieldName {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("custom field name is too long (max %d characters)", maxCustomFieldName), "invalid_input")
+			return
+		}
+		fields, err := s.db.ListCustomFields(ctx, f.ProjectID)
+		if err != nil {
+			s.logger.Error("Failed to list custom fields", zap.Int64("project_id", f.ProjectID), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to update custom field", "internal_error")
+			return
+		}
+		if customFieldNameTaken(fields, name, f.ID) {
+			respondError(w, http.StatusConflict, "a custom field with this name already exists", "conflict")
+			return
+		}
+		f.Name = name
+	}
+	if req.Options != nil {
+		if !f.HasOptions() {
+			respondError(w, http.StatusBadRequest, "only select fields have options", "invalid_input")
+			return
+		}
+		options, err := validateCustomFieldOptions(*req.Options)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+			return
+		}
+		f.Options = options
+	}
+	if req.Required != nil {
+		f.Required = *req.Required
+	}
+	if req.Position != nil {
+		if *req.Position < 0 {
+			respondError(w, http.StatusBadRequest, "position cannot be negative", "invalid_input")
+			return
+		}
+		f.Position = *req.Position
+	}
+
+	if err := s.db.UpdateCustomField(ctx, f); err != nil {
+		s.logger.Error("Failed to update custom field", zap.Int64("field_id", f.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update custom field", "internal_error")
+		return
+	}
+
+	auditChange(r, auditEvent{
+		Action: "custom_field.updated", ResourceType: "custom_field", ResourceID: f.ID, ProjectID: f.ProjectID,
+		Before: before, After: *f,
+	})
+	respondJSON(w, http.StatusOK, f)
+}
+
+// HandleDeleteCustomField deletes a custom field and its values on every task
+// Route: DELETE /api/custom-fields/{id}
+func (s *Server) HandleDeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	f := s.loadCustomField(w, r)
+	if f == nil {
+		return
+	}
+	if err := s.db.DeleteCustomField(ctx, f.ID); err != nil {
+		s.logger.Error("Failed to delete custom field", zap.Int64("field_id", f.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to delete custom field", "internal_error")
+		return
+	}
+
+	auditChange(r, auditEvent{
+		Action: "custom_field.deleted", ResourceType: "custom_field", ResourceID: f.ID, ProjectID: f.ProjectID, Before: *f,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findCustomField returns the field a task request names by ID or,
+// case-insensitively, by name
+func findCustomField(fields []db.CustomField, key string) *db.CustomField {
+	if id, err := strconv.ParseInt(key, 10, 64); err == nil {
+		for i := range fields {
+			if fields[i].ID == id {
+				return &fields[i]
+			}
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].Name, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// normalizeCustomFieldValue checks one value of a field and returns the form
+// it's stored in. User values are checked to be members of the project by
+// the caller.
+func normalizeCustomFieldValue(f *db.CustomField, v string) (string, error) {
+	v = strings.TrimSpace(v)
+	switch f.FieldType {
+	case db.FieldText:
+		if len(v) > maxCustomFieldText {
+			return "", fmt.Errorf("%s: too long (max %d characters)", f.Name, maxCustomFieldText)
+		}
+	case db.FieldNumber:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", fmt.Errorf("%s: expected a number", f.Name)
+		}
+		v = strconv.FormatFloat(n, 'f', -1, 64)
+	case db.FieldSelect, db.FieldMultiSelect:
+		for _, o := range f.Options {
+			if strings.EqualFold(o, v) {
+				return o, nil
+			}
+		}
+		return "", fmt.Errorf("%s: %q is not an option", f.Name, v)
+	case db.FieldDate:
+		if _, err := parseDay(v); err != nil {
+			return "", fmt.Errorf("%s: expected YYYY-MM-DD", f.Name)
+		}
+	case db.FieldUser:
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return "", fmt.Errorf("%s: expected a user ID", f.Name)
+		}
+	case db.FieldURL:
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", fmt.Errorf("%s: expected an http or https URL", f.Name)
+		}
+	case db.FieldCheckbox:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", fmt.Errorf("%s: expected true or false", f.Name)
+		}
+		v = strconv.FormatBool(b)
+	}
+	return v, nil
+}
+
+// decodeCustomFieldValue reads the JSON value of a field from a task request
+// as the values to store; null and empty values clear the field
+func decodeCustomFieldValue(f *db.CustomField, raw json.RawMessage) ([]string, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+	var items []interface{}
+	if f.FieldType == db.FieldMultiSelect {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("%s: expected a list of options", f.Name)
+		}
+	} else {
+		var item interface{}
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, fmt.Errorf("%s: invalid value", f.Name)
+		}
+		items = []interface{}{item}
+	}
+
+	var values []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		var v string
+		switch item := item.(type) {
+		case string:
+			v = item
+		case float64:
+			v = strconv.FormatFloat(item, 'f', -1, 64)
+		case bool:
+			v = strconv.FormatBool(item)
+		default:
+			return nil, fmt.Errorf("%s: invalid value", f.Name)
+		}
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		v, err := normalizeCustomFieldValue(f, v)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// resolveCustomFieldValues checks the custom_fields of a task request against
+// the project's fields and returns the values to store, by field ID. Fields
+// are named by ID or name; required fields must be set on new tasks and
+// can't be cleared.
+func (s *Server) resolveCustomFieldValues(ctx context.Context, projectID int64, raw map[string]json.RawMessage, creating bool) (map[int64][]string, *httpError) {
+	if len(raw) == 0 && !creating {
+		return nil, nil
+	}
+	fields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to list custom fields", zap.Int64("project_id", projectID), zap.Error(err))
+		return nil, &httpError{http.StatusInternalServerError, "failed to load custom fields", "internal_error"}
+	}
+
+	values := map[int64][]string{}
+	for key, v := range raw {
+		f := findCustomField(fields, key)
+		if f == nil {
+			return nil, &httpError{http.StatusBadRequest, fmt.Sprintf("unknown custom field %q", key), "invalid_input"}
+		}
+		vs, err := decodeCustomFieldValue(f, v)
+		if err != nil {
+			return nil, &httpError{http.StatusBadRequest, err.Error(), "invalid_input"}
+		}
+		if f.FieldType == db.FieldUser {
+			for _, v := range vs {
+				id, _ := strconv.ParseInt(v, 10, 64)
+				member, err := s.authorizeProject(ctx, id, projectID, PermProjectView)
+				if err != nil {
+					return nil, &httpError{http.StatusInternalServerError, "failed to verify project access", "internal_error"}
+				}
+				if !member {
+					return nil, &httpError{http.StatusBadRequest, fmt.Sprintf("%s: user %d is not a project member", f.Name, id), "invalid_input"}
+				}
+			}
+		}
+		values[f.ID] = vs
+	}
+
+	for _, f := range fields {
+		vs, given := values[f.ID]
+		if f.Required && len(vs) == 0 && (given || creating) {
+			return nil, &httpError{http.StatusBadRequest, fmt.Sprintf("custom field %q is required", f.Name), "invalid_input"}
+		}
+	}
+	return values, nil
+}
+
+// customFieldJSON returns the stored values of a field as they're shown on
+// tasks: numbers, booleans and user IDs typed, multi_select as a list
+func customFieldJSON(f *db.CustomField, values []string) interface{} {
+	switch f.FieldType {
+	case db.FieldMultiSelect:
+		// Values come sorted from the database; show them in option order
+		order := make(map[string]int, len(f.Options))
+		for i, o := range f.Options {
+			order[o] = i
+		}
+		sorted := append([]string(nil), values...)
+		sort.SliceStable(sorted, func(i, j int) bool { return order[sorted[i]] < order[sorted[j]] })
+		return sorted
+	case db.FieldNumber:
+		if n, err := strconv.ParseFloat(values[0], 64); err == nil {
+			return n
+		}
+	case db.FieldCheckbox:
+		return values[0] == "true"
+	case db.FieldUser:
+		if id, err := strconv.ParseInt(values[0], 10, 64); err == nil {
+			return id
+		}
+	}
+	return values[0]
+}
+
+// applyCustomFields sets the custom field values of API tasks, keyed by
+// field ID. Errors are logged and leave the values out, like other
+// best-effort enrichments.
+func (s *Server) applyCustomFields(ctx context.Context, projectID int64, tasks []Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	fields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		s.logger.Warn("Failed to load custom fields", zap.Int64("project_id", projectID), zap.Error(err))
+		return
+	}
+	if len(fields) == 0 {
+		return
+	}
+	taskIDs := make([]int64, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = t.ID
+	}
+	values, err := s.db.GetTaskCustomFieldValues(ctx, taskIDs)
+	if err != nil {
+		s.logger.Warn("Failed to load custom field values", zap.Int64("project_id", projectID), zap.Error(err))
+		return
+	}
+	for i := range tasks {
+		for j := range fields {
+			vs := values[tasks[i].ID][fields[j].ID]
+			if len(vs) == 0 {
+				continue
+			}
+			if tasks[i].CustomFields == nil {
+				tasks[i].CustomFields = map[string]interface{}{}
+			}
+			tasks[i].CustomFields[strconv.FormatInt(fields[j].ID, 10)] = customFieldJSON(&fields[j], vs)
+		}
+	}
+}
+
+// resolveCustomFieldFilters checks the cf_<id> filters of a task listing
+// name fields of the project and stores their values normalized
+func (s *Server) resolveCustomFieldFilters(ctx context.Context, projectID int64, q *taskListQuery) *httpError {
+	if len(q.CustomFields) == 0 {
+		return nil
+	}
+	fields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to list custom fields", zap.Int64("project_id", projectID), zap.Error(err))
+		return &httpError{http.StatusInternalServerError, "failed to load custom fields", "internal_error"}
+	}
+	byID := make(map[int64]*db.CustomField, len(fields))
+	for i := range fields {
+		byID[fields[i].ID] = &fields[i]
+	}
+	for i := range q.CustomFields {
+		filter := &q.CustomFields[i]
+		f, ok := byID[filter.FieldID]
+		if !ok {
+			return &httpError{http.StatusBadRequest, fmt.Sprintf("cf_%d: unknown custom field", filter.FieldID), "invalid_input"}
+		}
+		for j, v := range filter.Values {
+			if filter.Values[j], err = normalizeCustomFieldValue(f, v); err != nil {
+				return &httpError{http.StatusBadRequest, fmt.Sprintf("cf_%d: %v", filter.FieldID, err), "invalid_input"}
+			}
+		}
+	}
+	return nil
+}
+
+// githubCustomFieldValues maps the Projects V2 field values of an item onto
+// custom fields with the same name, case-insensitively. Values that aren't
+// valid for the custom field, and user fields, are skipped.
+func githubCustomFieldValues(fields []db.CustomField, item ghProjectItemStatus) map[int64][]string {
+	values := map[int64][]string{}
+	for name, v := range item.Fields {
+		for i := range fields {
+			f := &fields[i]
+			if f.FieldType == db.FieldUser || !strings.EqualFold(f.Name, name) {
+				continue
+			}
+			if nv, err := normalizeCustomFieldValue(f, v); err == nil && nv != "" {
+				values[f.ID] = []string{nv}
+			}
+		}
+	}
+	return values
+}
+
+// syncGitHubCustomFields sets the custom fields of a GitHub-synced task from
+// its Projects V2 item. Fields the item has no value for are left alone.
+// Best-effort: failures are logged.
+func (s *Server) syncGitHubCustomFields(ctx context.Context, taskID int64, fields []db.CustomField, item ghProjectItemStatus) {
+	if len(fields) == 0 || len(item.Fields) == 0 {
+		return
+	}
+	if err := s.db.SetTaskCustomFieldValues(ctx, taskID, githubCustomFieldValues(fields, item)); err != nil {
+		s.logger.Warn("Failed to sync custom fields from GitHub", zap.Int64("task_id", taskID), zap.Error(err))
+	}
+}
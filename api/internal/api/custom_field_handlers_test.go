@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"taskai/internal/db"
+)
+
+func TestGitHubCustomFieldValues(t *testing.T) {
+	fields := []db.CustomField{
+		{ID: 1, Name: "Size", FieldType: db.FieldSelect, Options: []string{"S", "M"}},
+		{ID: 2, Name: "Estimate", FieldType: db.FieldNumber},
+		{ID: 3, Name: "Launch", FieldType: db.FieldDate},
+		{ID: 4, Name: "Owner", FieldType: db.FieldUser},
+		{ID: 5, Name: "Notes", FieldType: db.FieldText},
+	}
+	item := ghProjectItemStatus{Fields: map[string]string{
+		"size":     "m",
+		"Estimate": "three",
+		"Launch":   "2026-05-01",
+		"Owner":    "12",
+		"Notes":    "Ship it",
+		"Status":   "Todo",
+	}}
+
+	values := githubCustomFieldValues(fields, item)
+	want := map[int64][]string{1: {"M"}, 3: {"2026-05-01"}, 5: {"Ship it"}}
+	if fmt.Sprint(values) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, values)
+	}
+}
+
+func TestCustomFields(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	outsiderID := ts.CreateTestUser(t, "outsider@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Fields")
+	ts.AddProjectMember(t, projectID, memberID, ownerID, RoleMember)
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	member := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, memberID, "member@example.com")}
+
+	fieldsPath := fmt.Sprintf("/api/projects/%d/custom-fields", projectID)
+	createField := func(body map[string]interface{}) db.CustomField {
+		t.Helper()
+		rec := routerRequest(t, ts, http.MethodPost, fieldsPath, body, owner)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var f db.CustomField
+		DecodeJSON(t, rec, &f)
+		return f
+	}
+	size := createField(map[string]interface{}{
+		"name": "Size", "field_type": "select", "options": []string{"S", "M", "L"}, "required": true,
+	})
+	points := createField(map[string]interface{}{"name": "Points", "field_type": "number"})
+	labels := createField(map[string]interface{}{"name": "Labels", "field_type": "multi_select", "options": []string{"api", "ui"}})
+	reviewer := createField(map[string]interface{}{"name": "Reviewer", "field_type": "user"})
+	link := createField(map[string]interface{}{"name": "Link", "field_type": "url"})
+	approved := createField(map[string]interface{}{"name": "Approved", "field_type": "checkbox"})
+
+	t.Run("field definitions are validated", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, fieldsPath, map[string]interface{}{"name": "size", "field_type": "text"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusConflict)
+		rec = routerRequest(t, ts, http.MethodPost, fieldsPath, map[string]interface{}{"name": "Stage", "field_type": "select"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+		rec = routerRequest(t, ts, http.MethodPost, fieldsPath, map[string]interface{}{"name": "Color", "field_type": "colour"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+		rec = routerRequest(t, ts, http.MethodPost, fieldsPath, map[string]interface{}{"name": "Team", "field_type": "text"}, member)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+
+		rec = routerRequest(t, ts, http.MethodGet, fieldsPath, nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var fields []db.CustomField
+		DecodeJSON(t, rec, &fields)
+		if len(fields) != 6 || fields[0].ID != size.ID || fields[5].ID != approved.ID {
+			t.Errorf("Expected the fields in the order they were added, got %+v", fields)
+		}
+	})
+
+	createTask := func(title string, customFields map[string]interface{}) *httptest.ResponseRecorder {
+		return routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID), map[string]interface{}{
+			"title": title, "custom_fields": customFields,
+		}, member)
+	}
+	key := func(f db.CustomField) string { return fmt.Sprint(f.ID) }
+
+	AssertStatusCode(t, createTask("Missing size", nil).Code, http.StatusBadRequest)
+
+	rec := createTask("Build API", map[string]interface{}{
+		"size":      "m",
+		key(points): "3.50",
+		"Labels":    []string{"ui", "api", "ui"},
+		"Reviewer":  memberID,
+		"Link":      "https://example.com/spec",
+		"Approved":  true,
+	})
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var first Task
+	DecodeJSON(t, rec, &first)
+	cf := first.CustomFields
+	if cf[key(size)] != "M" || cf[key(points)] != 3.5 || fmt.Sprint(cf[key(labels)]) != "[api ui]" ||
+		cf[key(reviewer)] != float64(memberID) || cf[key(link)] != "https://example.com/spec" || cf[key(approved)] != true {
+		t.Fatalf("Unexpected custom fields: %v", cf)
+	}
+
+	rec = createTask("Polish UI", map[string]interface{}{"Size": "S"})
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var second Task
+	DecodeJSON(t, rec, &second)
+
+	t.Run("invalid values are rejected", func(t *testing.T) {
+		for _, values := range []map[string]interface{}{
+			{"Size": "XL"},
+			{"Size": "S", "Points": "many"},
+			{"Size": "S", "Link": "ftp://example.com"},
+			{"Size": "S", "Reviewer": outsiderID},
+			{"Size": "S", "Labels": "api"},
+			{"Size": "S", "Estimate": 2},
+		} {
+			if rec := createTask("Invalid", values); rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected %v to be rejected, got %d", values, rec.Code)
+			}
+		}
+	})
+
+	t.Run("updates change only the fields given", func(t *testing.T) {
+		path := fmt.Sprintf("/api/tasks/%d", first.ID)
+		rec := routerRequest(t, ts, http.MethodPatch, path, map[string]interface{}{
+			"custom_fields": map[string]interface{}{"Size": nil},
+		}, member)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+
+		rec = routerRequest(t, ts, http.MethodPatch, path, map[string]interface{}{
+			"custom_fields": map[string]interface{}{"Points": nil, "Approved": false},
+		}, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		if _, ok := task.CustomFields[key(points)]; ok || task.CustomFields[key(approved)] != false ||
+			task.CustomFields[key(size)] != "M" {
+			t.Errorf("Unexpected custom fields: %v", task.CustomFields)
+		}
+	})
+
+	t.Run("tasks can be filtered by custom fields", func(t *testing.T) {
+		list := func(query string) []Task {
+			t.Helper()
+			rec := routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks?%s", projectID, query), nil, member)
+			AssertStatusCode(t, rec.Code, http.StatusOK)
+			var tasks []Task
+			DecodeJSON(t, rec, &tasks)
+			return tasks
+		}
+		if tasks := list(fmt.Sprintf("cf_%d=m", size.ID)); len(tasks) != 1 || tasks[0].ID != first.ID {
+			t.Errorf("Expected the task sized M, got %+v", tasks)
+		}
+		if tasks := list(fmt.Sprintf("cf_%d=none", link.ID)); len(tasks) != 1 || tasks[0].ID != second.ID {
+			t.Errorf("Expected the task without a link, got %+v", tasks)
+		}
+		if tasks := list(fmt.Sprintf("cf_%d=ui&cf_%d=s,m", labels.ID, size.ID)); len(tasks) != 1 || tasks[0].ID != first.ID {
+			t.Errorf("Expected the task labelled ui, got %+v", tasks)
+		}
+
+		rec := routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks?cf_%d=huge", projectID, size.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+		rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks?cf_999999=x", projectID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("global search matches custom field values", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/search", map[string]string{
+			"query": "example.com/spec",
+		}, memberID, nil)
+		ts.HandleGlobalSearch(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var resp GlobalSearchResponse
+		DecodeJSON(t, rec, &resp)
+		if len(resp.Tasks) != 1 || resp.Tasks[0].ID != first.ID {
+			t.Errorf("Expected the task linking the spec, got %+v", resp.Tasks)
+		}
+	})
+
+	t.Run("removed options are cleared from tasks", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/custom-fields/%d", labels.ID), map[string]interface{}{
+			"options": []string{"api", "docs"},
+		}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks/%d", projectID, first.TaskNumber), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		if fmt.Sprint(task.CustomFields[key(labels)]) != "[api]" {
+			t.Errorf("Expected only the remaining label, got %v", task.CustomFields[key(labels)])
+		}
+	})
+
+	t.Run("deleting a field removes its values", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/custom-fields/%d", link.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+		rec = routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/custom-fields/%d", link.ID), nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		var n int
+		if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM task_custom_field_values WHERE field_id = ?`, link.ID).Scan(&n); err != nil {
+			t.Fatalf("Failed to count values: %v", err)
+		}
+		if n != 0 {
+			t.Errorf("Expected the field's values to be deleted, got %d", n)
+		}
+	})
+}
@@ -37,7 +37,7 @@ func (s *Server) HandleListProjectDrawings(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(r.Context(), userID, projectID)
+	hasAccess, err := s.authorizeProject(r.Context(), userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -89,7 +89,7 @@ func (s *Server) HandleRegisterProjectDrawing(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(r.Context(), userID, projectID)
+	hasAccess, err := s.authorizeProject(r.Context(), userID, projectID, PermWikiEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
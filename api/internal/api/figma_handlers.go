@@ -74,13 +74,20 @@ func (s *Server) HandleSaveFigmaCredentials(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	accessToken, err := s.db.EncryptSecret(req.AccessToken)
+	if err != nil {
+		s.logger.Error("Failed to encrypt figma token", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to save credentials", "internal_error")
+		return
+	}
+
 	query := convertToPostgresQuery(`
 		INSERT INTO figma_credentials (user_id, access_token, updated_at)
 		VALUES (?, ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(user_id) DO UPDATE SET
 		  access_token = excluded.access_token,
 		  updated_at = CURRENT_TIMESTAMP`)
-	if _, err := s.db.ExecContext(ctx, query, userID, req.AccessToken); err != nil {
+	if _, err := s.db.ExecContext(ctx, query, userID, accessToken); err != nil {
 		s.logger.Error("Failed to save figma credentials", zap.Error(err), zap.Int64("user_id", userID))
 		respondError(w, http.StatusInternalServerError, "failed to save credentials", "internal_error")
 		return
@@ -134,6 +141,9 @@ func (s *Server) HandleFigmaEmbed(w http.ResponseWriter, r *http.Request) {
 	var accessToken string
 	tokenQuery := convertToPostgresQuery(`SELECT access_token FROM figma_credentials WHERE user_id = ?`)
 	err = s.db.QueryRowContext(ctx, tokenQuery, userID).Scan(&accessToken)
+	if err == nil {
+		accessToken, err = s.db.DecryptSecret(accessToken)
+	}
 	if err == sql.ErrNoRows {
 		respondJSON(w, http.StatusOK, FigmaEmbedResponse{EmbedURL: embedURL, Configured: false})
 		return
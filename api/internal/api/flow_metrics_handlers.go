@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/swimlane"
+	"taskai/ent/task"
+	"taskai/internal/db"
+)
+
+const (
+	// defaultFlowDays is the period flow metrics cover when none is given
+	defaultFlowDays = 30
+	// maxFlowDays bounds the period of flow metrics
+	maxFlowDays = 366
+)
+
+// wipLimitExceeded is the WIP limit a task entering a lane would go over
+type wipLimitExceeded struct {
+	Limit int
+	Hard  bool
+}
+
+func (e *wipLimitExceeded) message() string {
+	return fmt.Sprintf("swim lane is at its WIP limit of %d tasks", e.Limit)
+}
+
+// laneWIPLimit returns the WIP limit of a swim lane, or nil when it has none.
+func (s *Server) laneWIPLimit(ctx context.Context, projectID, laneID int64) (*db.WIPLimit, error) {
+	limits, err := s.db.GetWIPLimits(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if l, ok := limits[laneID]; ok && l.Limit != nil {
+		return &l, nil
+	}
+	return nil, nil
+}
+
+// checkWIPLimit returns the WIP limit l that a task entering a lane would go
+// over, or nil when the lane has room. taskID is 0 for new tasks. It locks
+// the lane until tx ends, so concurrent moves into the lane are counted one
+// after another and the move must be written in tx.
+func checkWIPLimit(ctx context.Context, tx *ent.Tx, l *db.WIPLimit, laneID, taskID int64) (*wipLimitExceeded, error) {
+	if l == nil || l.Limit == nil {
+		return nil, nil
+	}
+	lane, err := tx.SwimLane.Get(ctx, laneID)
+	if err != nil {
+		return nil, err
+	}
+	// Rewriting the lane row takes its lock (the database lock on SQLite)
+	if err := tx.SwimLane.UpdateOne(lane).SetUpdatedAt(lane.UpdatedAt).Exec(ctx); err != nil {
+		return nil, err
+	}
+	n, err := tx.Task.Query().Where(task.SwimLaneID(laneID), task.IDNEQ(taskID)).Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n < *l.Limit {
+		return nil, nil
+	}
+	return &wipLimitExceeded{Limit: *l.Limit, Hard: l.Enforcement == db.WIPHard}, nil
+}
+
+// laneOverWIPLimit reports whether a swim lane holds more tasks than its
+// WIP limit allows.
+func (s *Server) laneOverWIPLimit(ctx context.Context, projectID, laneID int64) (bool, error) {
+	l, err := s.laneWIPLimit(ctx, projectID, laneID)
+	if err != nil || l == nil {
+		return false, err
+	}
+	n, err := s.db.CountLaneTasks(ctx, laneID, 0)
+	if err != nil {
+		return false, err
+	}
+	return n > *l.Limit, nil
+}
+
+// recordLaneTransition stores a task entering a swim lane or status, for flow
+// metrics, flagged when the lane is now over its WIP limit. before is nil for
+// new tasks. Failures are logged rather than returned: the task has already
+// been saved.
+func (s *Server) recordLaneTransition(r *http.Request, before, after *Task) {
+	s.storeLaneTransition(r.Context(), before, after)
+}
+
+func (s *Server) storeLaneTransition(ctx context.Context, before, after *Task) {
+	tr := db.LaneTransition{TaskID: after.ID, ProjectID: after.ProjectID, ToLaneID: after.SwimLaneID, ToStatus: after.Status}
+	sameLane := false
+	if before != nil {
+		sameLane = (before.SwimLaneID == nil) == (after.SwimLaneID == nil) &&
+			(before.SwimLaneID == nil || *before.SwimLaneID == *after.SwimLaneID)
+		if sameLane && before.Status == after.Status {
+			return
+		}
+		tr.FromLaneID, tr.FromStatus = before.SwimLaneID, &before.Status
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if !sameLane && after.SwimLaneID != nil {
+		over, err := s.laneOverWIPLimit(ctx, after.ProjectID, *after.SwimLaneID)
+		if err != nil {
+			s.logger.Warn("Failed to check WIP limit", zap.Int64("task_id", after.ID), zap.Error(err))
+		}
+		tr.WIPLimitExceeded = over
+	}
+	if err := s.db.CreateLaneTransition(ctx, &tr); err != nil {
+		s.logger.Error("Failed to record lane transition", zap.Int64("task_id", after.ID), zap.Error(err))
+	}
+}
+
+// FlowDuration summarizes how long completed tasks took, in hours
+type FlowDuration struct {
+	Count        int     `json:"count"`
+	AverageHours float64 `json:"average_hours"`
+	MedianHours  float64 `json:"median_hours"`
+	P85Hours     float64 `json:"p85_hours"`
+}
+
+// ThroughputDay is the number of tasks completed on one day
+type ThroughputDay struct {
+	Date      string `json:"date"`
+	Completed int    `json:"completed"`
+}
+
+// CumulativeFlowDay counts the tasks in each swim lane, by lane ID, at the
+// end of one day
+type CumulativeFlowDay struct {
+	Date  string        `json:"date"`
+	Lanes map[int64]int `json:"lanes"`
+}
+
+// FlowLane names a swim lane of the cumulative flow diagram
+type FlowLane struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	StatusCategory string `json:"status_category"`
+}
+
+// FlowMetrics describes how work flowed through a project's swim lanes over a
+// period. Lead time runs from a task's creation to its completion, cycle time
+// from when it was first started; tasks reopened since aren't counted.
+type FlowMetrics struct {
+	From           string              `json:"from"`
+	To             string              `json:"to"`
+	CompletedTasks int                 `json:"completed_tasks"`
+	LeadTime       FlowDuration        `json:"lead_time"`
+	CycleTime      FlowDuration        `json:"cycle_time"`
+	Throughput     []ThroughputDay     `json:"throughput"`
+	Lanes          []FlowLane          `json:"lanes"`
+	CumulativeFlow []CumulativeFlowDay `json:"cumulative_flow"`
+	// WIPLimitExceeded counts the moves in the period that took a lane over
+	// its WIP limit
+	WIPLimitExceeded int `json:"wip_limit_exceeded"`
+}
+
+// flowDuration summarizes durations in hours, with nearest-rank percentiles
+func flowDuration(hours []float64) FlowDuration {
+	d := FlowDuration{Count: len(hours)}
+	if len(hours) == 0 {
+		return d
+	}
+	sort.Float64s(hours)
+	total := 0.0
+	for _, h := range hours {
+		total += h
+	}
+	rank := func(p float64) float64 {
+		return hours[int(math.Ceil(p*float64(len(hours))))-1]
+	}
+	d.AverageHours = total / float64(len(hours))
+	d.MedianHours = rank(0.5)
+	d.P85Hours = rank(0.85)
+	return d
+}
+
+// computeFlowMetrics derives flow metrics for the days from..to from the
+// lane transitions of a project's tasks, given by task and oldest first, and
+// when each task was created.
+func computeFlowMetrics(transitions []db.LaneTransition, created map[int64]time.Time, lanes []FlowLane, from, to time.Time) FlowMetrics {
+	m := FlowMetrics{
+		From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Lanes: lanes,
+		Throughput: []ThroughputDay{}, CumulativeFlow: []CumulativeFlowDay{},
+	}
+	end := to.AddDate(0, 0, 1)
+	known := make(map[int64]bool, len(lanes))
+	for _, l := range lanes {
+		known[l.ID] = true
+	}
+
+	completedOn := map[string]int{}
+	var leadTimes, cycleTimes []float64
+	for d := from; d.Before(end); d = d.AddDate(0, 0, 1) {
+		m.CumulativeFlow = append(m.CumulativeFlow, CumulativeFlowDay{Date: d.Format("2006-01-02"), Lanes: map[int64]int{}})
+	}
+
+	for i := 0; i < len(transitions); {
+		j := i
+		for j < len(transitions) && transitions[j].TaskID == transitions[i].TaskID {
+			j++
+		}
+		history := transitions[i:j]
+		i = j
+
+		for _, t := range history {
+			if t.WIPLimitExceeded && !t.CreatedAt.Before(from) && t.CreatedAt.Before(end) {
+				m.WIPLimitExceeded++
+			}
+		}
+
+		// The lane the task was in at the end of each day
+		next := 0
+		for k := range m.CumulativeFlow {
+			dayEnd := from.AddDate(0, 0, k+1)
+			for next < len(history) && history[next].CreatedAt.Before(dayEnd) {
+				next++
+			}
+			if next > 0 {
+				if lane := history[next-1].ToLaneID; lane != nil && known[*lane] {
+					m.CumulativeFlow[k].Lanes[*lane]++
+				}
+			}
+		}
+
+		// Completed tasks are done as of the end of the period, and were
+		// completed within it
+		last := next - 1
+		if last < 0 || history[last].ToStatus != "done" {
+			continue
+		}
+		for last > 0 && history[last-1].ToStatus == "done" {
+			last--
+		}
+		done := history[last].CreatedAt
+		if done.Before(from) {
+			continue
+		}
+		completedOn[done.UTC().Format("2006-01-02")]++
+		m.CompletedTasks++
+
+		start, ok := created[history[0].TaskID]
+		if !ok {
+			start = history[0].CreatedAt
+		}
+		leadTimes = append(leadTimes, done.Sub(start).Hours())
+		for _, t := range history {
+			if t.ToStatus == "in_progress" && !t.CreatedAt.After(done) {
+				cycleTimes = append(cycleTimes, done.Sub(t.CreatedAt).Hours())
+				break
+			}
+		}
+	}
+
+	for _, day := range m.CumulativeFlow {
+		m.Throughput = append(m.Throughput, ThroughputDay{Date: day.Date, Completed: completedOn[day.Date]})
+	}
+	m.LeadTime = flowDuration(leadTimes)
+	m.CycleTime = flowDuration(cycleTimes)
+	return m
+}
+
+// HandleGetFlowMetrics returns the cycle time, lead time, throughput and
+// cumulative flow of a project over the days from..to (YYYY-MM-DD, UTC),
+// by default the last 30.
+// Route: GET /api/projects/{projectId}/flow-metrics
+func (s *Server) HandleGetFlowMetrics(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid to date (use YYYY-MM-DD)", "invalid_input")
+			return
+		}
+	}
+	from := to.AddDate(0, 0, 1-defaultFlowDays)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid from date (use YYYY-MM-DD)", "invalid_input")
+			return
+		}
+	}
+	if from.After(to) || to.Sub(from) >= maxFlowDays*24*time.Hour {
+		respondError(w, http.StatusBadRequest, "from must be before to, at most 366 days apart", "invalid_input")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	entLanes, err := s.db.Client.SwimLane.Query().
+		Where(swimlane.ProjectID(projectID)).
+		Order(ent.Asc(swimlane.FieldPosition)).
+		All(ctx)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch swim lanes", "internal_error")
+		return
+	}
+	lanes := make([]FlowLane, len(entLanes))
+	for i, l := range entLanes {
+		lanes[i] = FlowLane{ID: l.ID, Name: l.Name, StatusCategory: l.StatusCategory}
+	}
+
+	transitions, err := s.db.ListLaneTransitions(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to list lane transitions", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch lane transitions", "internal_error")
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, created_at FROM tasks WHERE project_id = $1`, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch tasks", "internal_error")
+		return
+	}
+	defer rows.Close()
+	created := map[int64]time.Time{}
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to scan task", "internal_error")
+			return
+		}
+		created[id] = at
+	}
+
+	respondJSON(w, http.StatusOK, computeFlowMetrics(transitions, created, lanes, from, to))
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"taskai/internal/db"
+)
+
+func TestComputeFlowMetrics(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return v
+	}
+	lane := func(id int64) *int64 { return &id }
+	transitions := []db.LaneTransition{
+		{TaskID: 1, ToLaneID: lane(10), ToStatus: "todo", CreatedAt: at("2026-03-01 09:00")},
+		{TaskID: 1, ToLaneID: lane(20), ToStatus: "in_progress", CreatedAt: at("2026-03-01 12:00")},
+		{TaskID: 1, ToLaneID: lane(30), ToStatus: "done", CreatedAt: at("2026-03-02 12:00")},
+		{TaskID: 2, ToLaneID: lane(10), ToStatus: "todo", CreatedAt: at("2026-02-20 10:00")},
+		{TaskID: 2, ToLaneID: lane(20), ToStatus: "in_progress", CreatedAt: at("2026-03-03 06:00")},
+		{TaskID: 3, ToLaneID: lane(30), ToStatus: "done", CreatedAt: at("2026-02-25 10:00")},
+		{TaskID: 4, ToLaneID: lane(10), ToStatus: "todo", CreatedAt: at("2026-03-02 00:00")},
+		{TaskID: 4, ToLaneID: lane(30), ToStatus: "done", CreatedAt: at("2026-03-02 06:00")},
+		{TaskID: 4, ToLaneID: lane(10), ToStatus: "todo", CreatedAt: at("2026-03-05 08:00")},
+	}
+	created := map[int64]time.Time{1: at("2026-03-01 09:00"), 4: at("2026-03-02 00:00")}
+	lanes := []FlowLane{{ID: 10, Name: "To Do"}, {ID: 20, Name: "In Progress"}, {ID: 30, Name: "Done"}}
+
+	m := computeFlowMetrics(transitions, created, lanes, at("2026-03-01 00:00"), at("2026-03-03 00:00"))
+	if m.From != "2026-03-01" || m.To != "2026-03-03" {
+		t.Errorf("Unexpected period: %s..%s", m.From, m.To)
+	}
+	if m.CompletedTasks != 2 {
+		t.Errorf("Expected tasks done within the period to count, got %d", m.CompletedTasks)
+	}
+	if len(m.Throughput) != 3 || m.Throughput[1].Date != "2026-03-02" || m.Throughput[1].Completed != 2 ||
+		m.Throughput[0].Completed != 0 {
+		t.Errorf("Unexpected throughput: %+v", m.Throughput)
+	}
+	if d := m.LeadTime; d.Count != 2 || d.AverageHours != 16.5 || d.MedianHours != 6 || d.P85Hours != 27 {
+		t.Errorf("Unexpected lead time: %+v", d)
+	}
+	if d := m.CycleTime; d.Count != 1 || d.AverageHours != 24 {
+		t.Errorf("Expected cycle time only for tasks that were started, got %+v", d)
+	}
+
+	want := []map[int64]int{
+		{10: 1, 20: 1, 30: 1},
+		{10: 1, 30: 3},
+		{20: 1, 30: 3},
+	}
+	if len(m.CumulativeFlow) != len(want) {
+		t.Fatalf("Expected a point for every day, got %+v", m.CumulativeFlow)
+	}
+	for i, day := range m.CumulativeFlow {
+		if fmt.Sprint(day.Lanes) != fmt.Sprint(want[i]) {
+			t.Errorf("%s: expected %v, got %v", day.Date, want[i], day.Lanes)
+		}
+	}
+}
+
+func TestWIPLimitsAndFlowMetrics(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, userID, "Flow")
+	auth := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, userID, "owner@example.com")}
+	lanes := createDefaultSwimLanes(t, ts, projectID)
+
+	createTask := func(title string, laneID int64) *httptest.ResponseRecorder {
+		return routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID), map[string]interface{}{
+			"title": title, "swim_lane_id": laneID,
+		}, auth)
+	}
+	moveTask := func(taskID, laneID int64) *httptest.ResponseRecorder {
+		return routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/tasks/%d", taskID), map[string]interface{}{
+			"swim_lane_id": laneID,
+		}, auth)
+	}
+	setLimit := func(limit int, enforcement string) SwimLane {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/swim-lanes/%d", lanes[1]), map[string]interface{}{
+			"wip_limit": limit, "wip_enforcement": enforcement,
+		}, auth)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var lane SwimLane
+		DecodeJSON(t, rec, &lane)
+		return lane
+	}
+
+	rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/swim-lanes/%d", lanes[1]), map[string]interface{}{
+		"wip_enforcement": "strict",
+	}, auth)
+	AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+
+	if lane := setLimit(1, "hard"); lane.WIPLimit == nil || *lane.WIPLimit != 1 || lane.WIPEnforcement != "hard" {
+		t.Fatalf("Unexpected lane: %+v", lane)
+	}
+
+	var tasks []Task
+	for _, title := range []string{"First", "Second"} {
+		rec := createTask(title, lanes[0])
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		tasks = append(tasks, task)
+	}
+
+	AssertStatusCode(t, moveTask(tasks[0].ID, lanes[1]).Code, http.StatusOK)
+
+	t.Run("hard limits reject moves and new tasks", func(t *testing.T) {
+		AssertStatusCode(t, moveTask(tasks[1].ID, lanes[1]).Code, http.StatusConflict)
+		AssertStatusCode(t, createTask("Third", lanes[1]).Code, http.StatusConflict)
+	})
+
+	t.Run("soft limits flag moves", func(t *testing.T) {
+		setLimit(1, "soft")
+		rec := moveTask(tasks[1].ID, lanes[1])
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		if !task.WIPLimitExceeded || task.Status != "in_progress" {
+			t.Errorf("Expected the move to be flagged, got %+v", task)
+		}
+	})
+
+	AssertStatusCode(t, moveTask(tasks[0].ID, lanes[2]).Code, http.StatusOK)
+
+	rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/flow-metrics", projectID), nil, auth)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var m FlowMetrics
+	DecodeJSON(t, rec, &m)
+	if m.CompletedTasks != 1 || m.LeadTime.Count != 1 || m.CycleTime.Count != 1 || len(m.Lanes) != 3 ||
+		m.WIPLimitExceeded != 1 {
+		t.Errorf("Unexpected flow metrics: %+v", m)
+	}
+	if len(m.CumulativeFlow) != defaultFlowDays {
+		t.Fatalf("Expected the last %d days, got %d", defaultFlowDays, len(m.CumulativeFlow))
+	}
+	if today := m.CumulativeFlow[len(m.CumulativeFlow)-1].Lanes; today[lanes[1]] != 1 || today[lanes[2]] != 1 {
+		t.Errorf("Unexpected cumulative flow today: %v", today)
+	}
+
+	rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/flow-metrics?from=2026-03-02&to=2026-03-01", projectID), nil, auth)
+	AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+
+	"taskai/internal/db"
 )
 
 // --- GitHub API response types ---
@@ -96,6 +98,7 @@ type ghProjectItemStatus struct {
 	DueDate        string                // "YYYY-MM-DD", may be empty
 	IterationTitle string                // Sprint/iteration name from Projects V2 iteration field
 	Issue          *ghProjectItemContent // full issue data from GraphQL (title, body, assignees, labels, repo…)
+	Fields         map[string]string     // every field value by field name, for custom field mapping
 }
 
 type ghReactions struct {
@@ -147,11 +150,13 @@ func (c *ghProjectItemContent) Repo() string {
 }
 
 type ghProjectFieldValue struct {
-	Name      string `json:"name"`      // selected option name (for single-select fields)
-	Date      string `json:"date"`      // date value (for date fields)
-	Title     string `json:"title"`     // iteration title (e.g. "Sprint 139")
-	StartDate string `json:"startDate"` // iteration start date
-	Duration  int    `json:"duration"`  // iteration duration in days
+	Name      string   `json:"name"`      // selected option name (for single-select fields)
+	Date      string   `json:"date"`      // date value (for date fields)
+	Title     string   `json:"title"`     // iteration title (e.g. "Sprint 139")
+	StartDate string   `json:"startDate"` // iteration start date
+	Duration  int      `json:"duration"`  // iteration duration in days
+	Text      string   `json:"text"`      // text field value
+	Number    *float64 `json:"number"`    // number field value
 	Field     struct {
 		ID   string `json:"id"`   // field GraphQL ID
 		Name string `json:"name"` // field name (e.g. "Status")
@@ -453,6 +458,14 @@ query($projectId: ID!, $cursor: String) {
                 duration
                 field { ... on ProjectV2IterationField { id name } }
               }
+              ... on ProjectV2ItemFieldTextValue {
+                text
+                field { ... on ProjectV2Field { id name } }
+              }
+              ... on ProjectV2ItemFieldNumberValue {
+                number
+                field { ... on ProjectV2Field { id name } }
+              }
             }
           }
         }
@@ -494,8 +507,23 @@ query($projectId: ID!, $cursor: String) {
 			if item.Content == nil || item.Content.Number == 0 {
 				continue
 			}
-			info := ghProjectItemStatus{ItemID: item.ID, Issue: item.Content}
+			info := ghProjectItemStatus{ItemID: item.ID, Issue: item.Content, Fields: map[string]string{}}
 			for _, fv := range item.FieldValues.Nodes {
+				// Keep every value by field name so custom fields can pick them up
+				if fv.Field.Name != "" {
+					switch {
+					case fv.Name != "":
+						info.Fields[fv.Field.Name] = fv.Name
+					case fv.Date != "":
+						info.Fields[fv.Field.Name] = fv.Date
+					case fv.Title != "":
+						info.Fields[fv.Field.Name] = fv.Title
+					case fv.Text != "":
+						info.Fields[fv.Field.Name] = fv.Text
+					case fv.Number != nil:
+						info.Fields[fv.Field.Name] = strconv.FormatFloat(*fv.Number, 'f', -1, 64)
+					}
+				}
 				// Capture status from single-select field
 				if fv.Name != "" {
 					// Match by field ID when available (handles "Stage", "Phase", etc.)
@@ -672,8 +700,8 @@ func (s *Server) loadGitHubConfig(projectID int) (owner, repo, token, projectURL
 		SELECT COALESCE(github_owner,''), COALESCE(github_repo_name,''), github_token, github_project_url
 		FROM projects WHERE id = $1
 	`, projectID).Scan(&owner, &repo, &tokenNull, &projectURLNull)
-	if tokenNull.Valid {
-		token = tokenNull.String
+	if err == nil && tokenNull.Valid {
+		token, err = s.db.DecryptSecret(tokenNull.String)
 	}
 	if projectURLNull.Valid {
 		projectURL = strings.TrimSpace(projectURLNull.String)
@@ -854,8 +882,8 @@ func (s *Server) HandleGitHubDiscoverMappings(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	hasAccess, err := s.userHasProjectAccess(int(userID), projectID)
-	if err != nil || !hasAccess {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -1040,8 +1068,8 @@ func (s *Server) handleGitHubImport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
-	if err != nil || !isOwnerOrAdmin {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -1070,7 +1098,11 @@ func (s *Server) handleGitHubImport(w http.ResponseWriter, r *http.Request) {
 	if req.Token != "" {
 		// Save the new token
 		token = req.Token
-		if _, err := s.db.ExecContext(r.Context(), `UPDATE projects SET github_token = $1 WHERE id = $2`, token, projectID); err != nil {
+		sealed, err := s.db.EncryptSecret(token)
+		if err == nil {
+			_, err = s.db.ExecContext(r.Context(), `UPDATE projects SET github_token = $1 WHERE id = $2`, sealed, projectID)
+		}
+		if err != nil {
 			s.logger.Warn("Failed to save GitHub token", zap.Error(err))
 		}
 	}
@@ -1479,6 +1511,12 @@ func (s *Server) handleGitHubImport(w http.ResponseWriter, r *http.Request) {
 			nextNumber = maxNumber.Int64 + 1
 		}
 
+		// Projects V2 fields fill in custom fields with the same name
+		customFields, cfErr := s.db.ListCustomFields(ctx, int64(projectID))
+		if cfErr != nil {
+			s.logger.Warn("Failed to load custom fields", zap.Int("project_id", projectID), zap.Error(cfErr))
+		}
+
 		for i, issue := range allIssues {
 			if i%25 == 0 && i > 0 {
 				progress("issues", fmt.Sprintf("Processed %d/%d issues...", i, len(allIssues)), i, len(allIssues))
@@ -1606,11 +1644,14 @@ func (s *Server) handleGitHubImport(w http.ResponseWriter, r *http.Request) {
 					s.insertTaskTags(ctx, existingID, issue.Labels, labelToTagID)
 					s.upsertReactions(ctx, existingID, 0, issue.Reactions)
 					s.syncGitHubTaskAssignees(ctx, existingID, allAssigneeIDs)
+					s.syncGitHubCustomFields(ctx, existingID, customFields, issueColumnMap[colKey])
+					s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, nil, existingID)
 				} else {
 					result.SkippedTasks++
 				}
 			} else if err == nil {
 				// Update existing
+				before, loadErr := s.loadTask(ctx, existingID)
 				_, _ = s.db.ExecContext(ctx, `
 					UPDATE tasks SET title = $1, description = $2, status = $3, assignee_id = $4, sprint_id = $5, swim_lane_id = $6, github_project_item_id = COALESCE(NULLIF($7,''), github_project_item_id),
 					start_date = COALESCE($8, start_date), due_date = COALESCE($9, due_date)
@@ -1620,6 +1661,10 @@ func (s *Server) handleGitHubImport(w http.ResponseWriter, r *http.Request) {
 				s.insertTaskTags(ctx, existingID, issue.Labels, labelToTagID)
 				s.upsertReactions(ctx, existingID, 0, issue.Reactions)
 				s.syncGitHubTaskAssignees(ctx, existingID, allAssigneeIDs)
+				s.syncGitHubCustomFields(ctx, existingID, customFields, issueColumnMap[colKey])
+				if loadErr == nil {
+					s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, &before, existingID)
+				}
 				result.UpdatedTasks++
 			}
 		}
@@ -1792,6 +1837,33 @@ func (s *Server) syncGitHubTaskAssignees(ctx context.Context, taskID int64, user
 	}
 }
 
+// resolveGitHubAssignees maps an issue's assignee logins to TaskAI user IDs using
+// the login → user_id mappings. The first mapped user becomes the primary assignee.
+func resolveGitHubAssignees(issue ghIssue, userAssignments map[string]int64) (*int64, []int64) {
+	var assigneeID *int64
+	var allAssigneeIDs []int64
+	seenAssignees := map[int64]bool{}
+	logins := make([]string, 0, len(issue.Assignees))
+	if issue.Assignee != nil {
+		logins = append(logins, issue.Assignee.Login)
+	}
+	for _, a := range issue.Assignees {
+		if a.Login != "" && (len(logins) == 0 || logins[0] != a.Login) {
+			logins = append(logins, a.Login)
+		}
+	}
+	for _, login := range logins {
+		if uid, ok := userAssignments[login]; ok && uid != 0 && !seenAssignees[uid] {
+			seenAssignees[uid] = true
+			allAssigneeIDs = append(allAssigneeIDs, uid)
+			if assigneeID == nil {
+				assigneeID = &allAssigneeIDs[0]
+			}
+		}
+	}
+	return assigneeID, allAssigneeIDs
+}
+
 // nullableStr returns nil if s is empty, otherwise returns &s (for use as SQL NULL).
 func nullableStr(s string) *string {
 	if s == "" {
@@ -1917,6 +1989,9 @@ func (s *Server) tryPushCommentToGitHub(ctx context.Context, taskID, commentID i
 		JOIN projects p ON p.id = t.project_id
 		WHERE t.id = $1
 	`, taskID).Scan(&issueNumber, &owner, &repo, &token, &pushEnabled)
+	if err == nil {
+		token, err = s.db.DecryptSecret(token)
+	}
 	if err != nil || !pushEnabled || issueNumber == 0 || owner == "" || token == "" {
 		return
 	}
@@ -2092,6 +2167,17 @@ func (s *Server) HandleGetGitHubMappings(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	userID, ok := GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	allowed, err := s.authorizeProject(ctx, userID, int64(projectID), PermProjectView)
+	if err != nil || !allowed {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	resp := GitHubMappingsResponse{
 		StatusMappings: map[string]int64{},
 		UserMappings:   map[string]int64{},
@@ -2141,6 +2227,17 @@ func (s *Server) HandleSaveGitHubMappings(w http.ResponseWriter, r *http.Request
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	userID, ok := GetUserID(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	allowed, err := s.authorizeProject(ctx, userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	var req GitHubMappingsResponse
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
@@ -2288,11 +2385,14 @@ func (s *Server) HandleGitHubPushTask(w http.ResponseWriter, r *http.Request) {
 
 	token := ""
 	if tokenNull.Valid {
-		token = tokenNull.String
+		if token, err = s.db.DecryptSecret(tokenNull.String); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to load task", "internal_error")
+			return
+		}
 	}
 
 	// Auth check
-	hasAccess, _ := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, _ := s.authorizeProject(ctx, userID, projectID, PermTaskEdit)
 	if !hasAccess {
 		respondError(w, http.StatusForbidden, "access denied", "forbidden")
 		return
@@ -2427,8 +2527,8 @@ func (s *Server) HandleGitHubPushAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
-	if err != nil || !isOwnerOrAdmin {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -2600,6 +2700,9 @@ func (s *Server) tryPushSwimLaneToGitHub(ctx context.Context, taskID int64, newL
 		JOIN swim_lanes sl ON sl.id = $2
 		WHERE t.id = $1
 	`, taskID, *newLaneID).Scan(&itemID, &projectID, &fieldID, &optionID, &token, &pushEnabled)
+	if err == nil {
+		token, err = s.db.DecryptSecret(token)
+	}
 	if err != nil || !pushEnabled || itemID == "" || projectID == "" || fieldID == "" || optionID == "" || token == "" {
 		return
 	}
@@ -2626,6 +2729,9 @@ func (s *Server) tryPushAssigneesToGitHub(ctx context.Context, taskID int64) {
 		JOIN projects p ON p.id = t.project_id
 		WHERE t.id = $1
 	`, taskID).Scan(&issueNumber, &projectID, &owner, &repo, &token, &pushEnabled)
+	if err == nil {
+		token, err = s.db.DecryptSecret(token)
+	}
 	if err != nil || !pushEnabled || issueNumber == 0 || owner == "" || token == "" {
 		return
 	}
@@ -2720,8 +2826,8 @@ func (s *Server) HandleGetGitHubSyncLogs(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	hasAccess, err := s.userHasProjectAccess(int(userID), projectID)
-	if err != nil || !hasAccess {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermProjectView)
+	if err != nil || !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -2862,6 +2968,12 @@ func (s *Server) runAutoSync(ctx context.Context) {
 		if err := rows.Scan(&p.ID, &p.Owner, &p.Repo, &p.Token, &p.ProjectURL, &p.SyncInterval, &p.SyncHour, &p.SyncDay, &p.LastSync); err != nil {
 			continue
 		}
+		token, err := s.db.DecryptSecret(p.Token)
+		if err != nil {
+			s.logger.Error("auto-sync: failed to decrypt GitHub token", zap.Int("project_id", p.ID), zap.Error(err))
+			continue
+		}
+		p.Token = token
 		projects = append(projects, p)
 	}
 	rows.Close()
@@ -3070,6 +3182,12 @@ func (s *Server) runGitHubImportCore(ctx context.Context, projectID int, owner,
 			nextNumber = maxNumber.Int64 + 1
 		}
 
+		// Projects V2 fields fill in custom fields with the same name
+		customFields, cfErr := s.db.ListCustomFields(ctx, int64(projectID))
+		if cfErr != nil {
+			s.logger.Warn("Failed to load custom fields", zap.Int("project_id", projectID), zap.Error(cfErr))
+		}
+
 		for _, issue := range allIssues {
 			if issue.PullRequest != nil {
 				continue
@@ -3080,27 +3198,7 @@ func (s *Server) runGitHubImportCore(ctx context.Context, projectID int, owner,
 			}
 
 			// Resolve assignees — collect all mapped user IDs from issue.Assignees.
-			var assigneeID *int64
-			var allAssigneeIDs []int64
-			seenAssignees := map[int64]bool{}
-			logins := make([]string, 0, len(issue.Assignees))
-			if issue.Assignee != nil {
-				logins = append(logins, issue.Assignee.Login)
-			}
-			for _, a := range issue.Assignees {
-				if a.Login != "" && (len(logins) == 0 || logins[0] != a.Login) {
-					logins = append(logins, a.Login)
-				}
-			}
-			for _, login := range logins {
-				if uid, ok := req.UserAssignments[login]; ok && uid != 0 && !seenAssignees[uid] {
-					seenAssignees[uid] = true
-					allAssigneeIDs = append(allAssigneeIDs, uid)
-					if assigneeID == nil {
-						assigneeID = &allAssigneeIDs[0]
-					}
-				}
-			}
+			assigneeID, allAssigneeIDs := resolveGitHubAssignees(issue, req.UserAssignments)
 
 			// Resolve sprint: prefer Projects V2 iteration, fall back to milestone
 			var sprintID *int64
@@ -3170,10 +3268,13 @@ func (s *Server) runGitHubImportCore(ctx context.Context, projectID int, owner,
 					result.CreatedTasks++
 					s.upsertReactions(ctx, existingID, 0, issue.Reactions)
 					s.syncGitHubTaskAssignees(ctx, existingID, allAssigneeIDs)
+					s.syncGitHubCustomFields(ctx, existingID, customFields, issueColumnMap[colKey])
+					s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, nil, existingID)
 				} else {
 					result.SkippedTasks++
 				}
 			} else if err == nil {
+				before, loadErr := s.loadTask(ctx, existingID)
 				_, _ = s.db.ExecContext(ctx, `
 					UPDATE tasks SET title = $1, description = $2, status = $3, assignee_id = $4, sprint_id = $5, swim_lane_id = $6,
 					github_project_item_id = COALESCE(NULLIF($7,''), github_project_item_id),
@@ -3182,6 +3283,10 @@ func (s *Server) runGitHubImportCore(ctx context.Context, projectID int, owner,
 				`, issue.Title, issue.Body, taskStatus, assigneeID, sprintID, swimLaneID, ghItemID, nullableStr(ghStartDate), nullableStr(ghDueDate), existingID)
 				s.upsertReactions(ctx, existingID, 0, issue.Reactions)
 				s.syncGitHubTaskAssignees(ctx, existingID, allAssigneeIDs)
+				s.syncGitHubCustomFields(ctx, existingID, customFields, issueColumnMap[colKey])
+				if loadErr == nil {
+					s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, &before, existingID)
+				}
 				result.UpdatedTasks++
 			}
 		}
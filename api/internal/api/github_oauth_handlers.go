@@ -86,8 +86,8 @@ func (s *Server) HandleGitHubOAuthInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
-	if err != nil || !isOwnerOrAdmin {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		respondError(w, http.StatusForbidden, "Forbidden", "forbidden")
 		return
 	}
@@ -162,6 +162,12 @@ func (s *Server) HandleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store token and login, clear manual repo fields
+	sealedToken, err := s.db.EncryptSecret(accessToken)
+	if err != nil {
+		s.logger.Error("Failed to encrypt GitHub OAuth token", zap.Error(err), zap.Int64("project_id", projectID))
+		http.Redirect(w, r, fmt.Sprintf("%s/app/projects/%d/settings?github=error&reason=db_save", s.config.AppURL, projectID), http.StatusFound)
+		return
+	}
 	_, err = s.db.ExecContext(r.Context(), `
 		UPDATE projects
 		SET github_token = $1,
@@ -170,7 +176,7 @@ func (s *Server) HandleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 		    github_repo_name = NULL,
 		    github_repo_url = NULL
 		WHERE id = $3
-	`, accessToken, ghUserInfo.Login, projectID)
+	`, sealedToken, ghUserInfo.Login, projectID)
 	if err != nil {
 		s.logger.Error("Failed to save GitHub OAuth token", zap.Error(err), zap.Int64("project_id", projectID))
 		http.Redirect(w, r, fmt.Sprintf("%s/app/projects/%d/settings?github=error&reason=db_save", s.config.AppURL, projectID), http.StatusFound)
@@ -247,8 +253,8 @@ func (s *Server) HandleGitHubListRepos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.userHasProjectAccess(int(userID), projectID)
-	if err != nil || !hasAccess {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		respondError(w, http.StatusForbidden, "Forbidden", "forbidden")
 		return
 	}
@@ -262,7 +268,12 @@ func (s *Server) HandleGitHubListRepos(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "GitHub is not connected for this project", "not_connected")
 		return
 	}
-	token := tokenNull.String
+	token, err := s.db.DecryptSecret(tokenNull.String)
+	if err != nil {
+		s.logger.Error("Failed to decrypt GitHub token", zap.Error(err), zap.Int("project_id", projectID))
+		respondError(w, http.StatusInternalServerError, "Failed to load project", "db_error")
+		return
+	}
 
 	// Fetch up to 3 pages of repos
 	type ghRepoRaw struct {
@@ -325,12 +336,19 @@ func (s *Server) HandleGitHubDisconnect(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
-	if err != nil || !isOwnerOrAdmin {
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
+	if err != nil || !allowed {
 		respondError(w, http.StatusForbidden, "Forbidden", "forbidden")
 		return
 	}
 
+	before, err := s.loadProjectGitHubSettings(projectID)
+	if err != nil {
+		s.logger.Error("Failed to fetch GitHub settings", zap.Error(err), zap.Int("project_id", projectID))
+		respondError(w, http.StatusInternalServerError, "Failed to disconnect GitHub", "db_error")
+		return
+	}
+
 	_, err = s.db.ExecContext(r.Context(), `
 		UPDATE projects
 		SET github_token = NULL,
@@ -346,5 +364,11 @@ func (s *Server) HandleGitHubDisconnect(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if after, err := s.loadProjectGitHubSettings(projectID); err == nil {
+		auditChange(r, auditEvent{
+			Action: "project.github_disconnected", ResourceType: "project", ResourceID: int64(projectID),
+			ProjectID: int64(projectID), Before: before, After: after,
+		})
+	}
 	respondJSON(w, http.StatusOK, map[string]string{"message": "GitHub disconnected successfully"})
 }
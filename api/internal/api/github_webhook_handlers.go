@@ -0,0 +1,794 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+)
+
+// maxGitHubWebhookBody caps the size of an inbound GitHub webhook payload.
+const maxGitHubWebhookBody = 5 << 20
+
+// --- GitHub webhook payload types ---
+
+type ghWebhookRepository struct {
+	FullName string `json:"full_name"`
+}
+
+type ghWebhookProjectItem struct {
+	NodeID        string `json:"node_id"`
+	ProjectNodeID string `json:"project_node_id"`
+	ContentNodeID string `json:"content_node_id"`
+	ContentType   string `json:"content_type"`
+}
+
+// ghWebhookFieldOption is the single-select option in a projects_v2_item
+// field_value change. Other field types send scalars, which fail to decode
+// into this struct and are ignored.
+type ghWebhookFieldOption struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type ghWebhookChanges struct {
+	Name *struct {
+		From string `json:"from"`
+	} `json:"name"`
+	FieldValue *struct {
+		FieldNodeID string          `json:"field_node_id"`
+		FieldType   string          `json:"field_type"`
+		FieldName   string          `json:"field_name"`
+		To          json.RawMessage `json:"to"`
+	} `json:"field_value"`
+}
+
+type ghWebhookPayload struct {
+	Action         string                `json:"action"`
+	Issue          *ghIssue              `json:"issue"`
+	Comment        *ghIssueComment       `json:"comment"`
+	Label          *ghLabel              `json:"label"`
+	Repository     *ghWebhookRepository  `json:"repository"`
+	ProjectsV2Item *ghWebhookProjectItem `json:"projects_v2_item"`
+	Changes        *ghWebhookChanges     `json:"changes"`
+}
+
+// githubWebhookProject is a sync-enabled TaskAI project affected by a webhook event.
+type githubWebhookProject struct {
+	ID      int64
+	Owner   string
+	Repo    string
+	Token   string
+	OwnerID int64 // project owner, used as author fallback for GitHub comments
+
+	WebhookSecret string
+}
+
+// verifyGitHubSignature checks an X-Hub-Signature-256 header against the body.
+func verifyGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// githubWebhookDeliveryTTL is how long delivery IDs are kept to spot
+// redelivered events.
+const githubWebhookDeliveryTTL = 7 * 24 * time.Hour
+
+// HandleGitHubWebhook receives GitHub webhook deliveries and applies issue,
+// comment, label and Projects V2 changes to linked projects incrementally.
+// Every project has its own webhook secret, and a delivery is only applied
+// to the projects whose secret signed it. Redelivered events (the same
+// X-GitHub-Delivery) are acknowledged without being applied again, and
+// processing happens in the background so GitHub gets a response well
+// within its 10s limit.
+// POST /api/github/webhook
+func (s *Server) HandleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGitHubWebhookBody))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "failed to read body", "invalid_input")
+		return
+	}
+	event := r.Header.Get("X-GitHub-Event")
+	delivery := r.Header.Get("X-GitHub-Delivery")
+
+	var payload ghWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid payload", "invalid_input")
+		return
+	}
+
+	projects, err := s.findGitHubWebhookProjects(ctx, event, &payload)
+	if err != nil {
+		s.logger.Error("github webhook: failed to find projects", zap.String("event", event), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to find projects", "internal_error")
+		return
+	}
+	projects = signedGitHubWebhookProjects(projects, body, r.Header.Get("X-Hub-Signature-256"))
+	if len(projects) == 0 {
+		s.logger.Warn("Rejected GitHub webhook with invalid signature",
+			zap.String("delivery", delivery),
+		)
+		respondError(w, http.StatusUnauthorized, "invalid signature", "unauthorized")
+		return
+	}
+
+	switch event {
+	case "ping":
+		respondJSON(w, http.StatusOK, map[string]string{"message": "pong"})
+		return
+	case "issues", "issue_comment", "label", "projects_v2_item":
+	default:
+		respondJSON(w, http.StatusAccepted, map[string]string{"message": "event ignored"})
+		return
+	}
+
+	if delivery == "" {
+		respondError(w, http.StatusBadRequest, "missing X-GitHub-Delivery header", "invalid_input")
+		return
+	}
+	fresh, err := s.recordGitHubWebhookDelivery(ctx, delivery)
+	if err != nil {
+		s.logger.Error("github webhook: failed to record delivery", zap.String("delivery", delivery), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to record delivery", "internal_error")
+		return
+	}
+	if !fresh {
+		respondJSON(w, http.StatusOK, map[string]string{"message": "delivery already processed"})
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		s.applyGitHubWebhookEvent(ctx, event, &payload, projects)
+	}()
+
+	respondJSON(w, http.StatusAccepted, map[string]string{"message": "accepted"})
+}
+
+// GitHubWebhookSecretResponse is what to enter when adding the webhook on GitHub
+type GitHubWebhookSecretResponse struct {
+	PayloadURL string `json:"payload_url"`
+	Secret     string `json:"secret"`
+}
+
+// HandleRotateGitHubWebhookSecret generates a new secret for a project's
+// GitHub webhook. The secret is only shown in this response; deliveries
+// signed with the previous one are rejected from now on.
+// POST /api/projects/{id}/github/webhook-secret
+func (s *Server) HandleRotateGitHubWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	userID, ok := GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	allowed, err := s.authorizeProject(ctx, userID, projectID, PermGitHubConfigure)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !allowed {
+		respondError(w, http.StatusForbidden, "you don't have permission to configure GitHub for this project", "forbidden")
+		return
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to generate secret", "internal_error")
+		return
+	}
+	sealed, err := s.db.EncryptSecret(secret)
+	if err != nil {
+		s.logger.Error("Failed to encrypt GitHub webhook secret", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to save secret", "internal_error")
+		return
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE projects SET github_webhook_secret = ? WHERE id = ?`),
+		sealed, projectID); err != nil {
+		s.logger.Error("Failed to save GitHub webhook secret", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to save secret", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, GitHubWebhookSecretResponse{
+		PayloadURL: strings.TrimRight(s.config.AppURL, "/") + "/api/github/webhook",
+		Secret:     secret,
+	})
+}
+
+// signedGitHubWebhookProjects returns the projects whose webhook secret
+// produced the signature.
+func signedGitHubWebhookProjects(projects []githubWebhookProject, body []byte, signature string) []githubWebhookProject {
+	var signed []githubWebhookProject
+	for _, p := range projects {
+		if p.WebhookSecret != "" && verifyGitHubSignature(p.WebhookSecret, body, signature) {
+			signed = append(signed, p)
+		}
+	}
+	return signed
+}
+
+// recordGitHubWebhookDelivery remembers a delivery ID and reports whether it
+// is new. Delivery IDs older than githubWebhookDeliveryTTL are forgotten.
+func (s *Server) recordGitHubWebhookDelivery(ctx context.Context, delivery string) (bool, error) {
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(
+		`DELETE FROM github_webhook_deliveries WHERE received_at < ?`), now.Add(-githubWebhookDeliveryTTL)); err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO github_webhook_deliveries (delivery_id, received_at) VALUES (?, ?)
+		ON CONFLICT (delivery_id) DO NOTHING
+	`), delivery, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// findGitHubWebhookProjects returns the projects an event may apply to: those
+// linked to the Projects V2 item, or else to the repository. A ping from a
+// hook outside a repository may belong to any project with a webhook secret.
+func (s *Server) findGitHubWebhookProjects(ctx context.Context, event string, payload *ghWebhookPayload) ([]githubWebhookProject, error) {
+	switch {
+	case event == "projects_v2_item":
+		if payload.ProjectsV2Item == nil || payload.ProjectsV2Item.NodeID == "" {
+			return nil, nil
+		}
+		return s.findGitHubWebhookProjectsByItem(ctx, payload.ProjectsV2Item.NodeID)
+	case payload.Repository != nil && payload.Repository.FullName != "":
+		return s.findGitHubWebhookProjectsByRepo(ctx, payload.Repository.FullName)
+	case event == "ping":
+		return s.queryGitHubWebhookProjects(ctx, `
+			SELECT p.id, COALESCE(p.github_owner,''), COALESCE(p.github_repo_name,''), COALESCE(p.github_token,''),
+			       COALESCE(p.github_webhook_secret,''),
+			       COALESCE((SELECT pm.user_id FROM project_members pm WHERE pm.project_id = p.id AND pm.role = 'owner' LIMIT 1), p.owner_id)
+			FROM projects p
+			WHERE p.github_webhook_secret IS NOT NULL
+		`)
+	}
+	return nil, nil
+}
+
+// applyGitHubWebhookEvent applies a verified webhook event to each of the
+// projects it was signed for and records one github_sync_logs entry per
+// project.
+func (s *Server) applyGitHubWebhookEvent(ctx context.Context, event string, payload *ghWebhookPayload, projects []githubWebhookProject) {
+	for _, p := range projects {
+		result := &GitHubPullResponse{}
+		var applyErr error
+		switch event {
+		case "issues":
+			applyErr = s.applyGitHubIssueEvent(ctx, p, payload, result)
+		case "issue_comment":
+			applyErr = s.applyGitHubIssueCommentEvent(ctx, p, payload, result)
+		case "label":
+			applyErr = s.applyGitHubLabelEvent(ctx, p, payload)
+		case "projects_v2_item":
+			applyErr = s.applyGitHubProjectItemEvent(ctx, p, payload, result)
+		}
+		if applyErr != nil {
+			s.logger.Warn("github webhook: failed to apply event",
+				zap.Int64("project_id", p.ID),
+				zap.String("event", event),
+				zap.String("action", payload.Action),
+				zap.Error(applyErr),
+			)
+		}
+		s.recordGitHubWebhookLog(ctx, p.ID, result, applyErr)
+	}
+}
+
+// findGitHubWebhookProjectsByRepo returns sync-enabled projects configured for
+// the repo, or already holding tasks imported from it (cross-repo boards).
+func (s *Server) findGitHubWebhookProjectsByRepo(ctx context.Context, fullName string) ([]githubWebhookProject, error) {
+	return s.queryGitHubWebhookProjects(ctx, `
+		SELECT p.id, COALESCE(p.github_owner,''), COALESCE(p.github_repo_name,''), COALESCE(p.github_token,''),
+		       COALESCE(p.github_webhook_secret,''),
+		       COALESCE((SELECT pm.user_id FROM project_members pm WHERE pm.project_id = p.id AND pm.role = 'owner' LIMIT 1), p.owner_id)
+		FROM projects p
+		WHERE p.github_sync_enabled = true
+		  AND (LOWER(p.github_owner || '/' || p.github_repo_name) = LOWER(?)
+		       OR EXISTS (SELECT 1 FROM tasks t WHERE t.project_id = p.id AND t.github_repo = ?))
+	`, fullName, fullName)
+}
+
+// findGitHubWebhookProjectsByItem returns sync-enabled projects with a task
+// linked to the given Projects V2 item.
+func (s *Server) findGitHubWebhookProjectsByItem(ctx context.Context, itemNodeID string) ([]githubWebhookProject, error) {
+	return s.queryGitHubWebhookProjects(ctx, `
+		SELECT p.id, COALESCE(p.github_owner,''), COALESCE(p.github_repo_name,''), COALESCE(p.github_token,''),
+		       COALESCE(p.github_webhook_secret,''),
+		       COALESCE((SELECT pm.user_id FROM project_members pm WHERE pm.project_id = p.id AND pm.role = 'owner' LIMIT 1), p.owner_id)
+		FROM projects p
+		WHERE p.github_sync_enabled = true
+		  AND EXISTS (SELECT 1 FROM tasks t WHERE t.project_id = p.id AND t.github_project_item_id = ?)
+	`, itemNodeID)
+}
+
+func (s *Server) queryGitHubWebhookProjects(ctx context.Context, query string, args ...interface{}) ([]githubWebhookProject, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []githubWebhookProject
+	for rows.Next() {
+		var p githubWebhookProject
+		if err := rows.Scan(&p.ID, &p.Owner, &p.Repo, &p.Token, &p.WebhookSecret, &p.OwnerID); err != nil {
+			return nil, err
+		}
+		if p.Token, err = s.db.DecryptSecret(p.Token); err != nil {
+			return nil, err
+		}
+		if p.WebhookSecret, err = s.db.DecryptSecret(p.WebhookSecret); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// recordGitHubWebhookLog writes a completed github_sync_logs entry for one
+// webhook event and trims the project's log to the last 100 entries.
+func (s *Server) recordGitHubWebhookLog(ctx context.Context, projectID int64, result *GitHubPullResponse, applyErr error) {
+	status := "success"
+	var errMsg *string
+	if applyErr != nil {
+		status = "failed"
+		msg := applyErr.Error()
+		errMsg = &msg
+	}
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		INSERT INTO github_sync_logs (project_id, triggered_by, sync_mode, status, completed_at, error_message,
+		                              created_tasks, updated_tasks, created_comments, skipped_tasks)
+		VALUES (?, 'webhook', 'webhook', ?, ?, ?, ?, ?, ?, ?)
+	`), projectID, status, time.Now(), errMsg,
+		result.CreatedTasks, result.UpdatedTasks, result.CreatedComments, result.SkippedTasks)
+	if err != nil {
+		s.logger.Warn("github webhook: failed to record sync log", zap.Int64("project_id", projectID), zap.Error(err))
+		return
+	}
+	_, _ = s.db.ExecContext(ctx, s.db.Rebind(`
+		DELETE FROM github_sync_logs WHERE project_id = ? AND id NOT IN (
+			SELECT id FROM github_sync_logs WHERE project_id = ? ORDER BY started_at DESC LIMIT 100
+		)
+	`), projectID, projectID)
+}
+
+// findSwimLaneForCategory returns the first swim lane (by position) with the given status category.
+func (s *Server) findSwimLaneForCategory(ctx context.Context, projectID int64, category string) *int64 {
+	var laneID int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT id FROM swim_lanes WHERE project_id = ? AND status_category = ? ORDER BY position ASC LIMIT 1`),
+		projectID, category).Scan(&laneID)
+	if err != nil {
+		return nil
+	}
+	return &laneID
+}
+
+// resolveGitHubIssueSprint maps an issue milestone to a sprint, by number for
+// the project's own repo and by name for other repos (as the full sync does).
+func (s *Server) resolveGitHubIssueSprint(ctx context.Context, p githubWebhookProject, issue ghIssue) *int64 {
+	if issue.Milestone == nil {
+		return nil
+	}
+	var sid int64
+	if strings.EqualFold(issue.Repo, p.Owner+"/"+p.Repo) {
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(
+			`SELECT id FROM sprints WHERE project_id = ? AND github_milestone_number = ?`),
+			p.ID, issue.Milestone.Number).Scan(&sid)
+		if err == nil {
+			return &sid
+		}
+	}
+	if issue.Milestone.Title != "" {
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(
+			`SELECT id FROM sprints WHERE project_id = ? AND github_milestone_number IS NOT NULL AND name = ?`),
+			p.ID, issue.Milestone.Title).Scan(&sid)
+		if err == nil {
+			return &sid
+		}
+	}
+	return nil
+}
+
+// setGitHubTaskAssignees replaces the task's assignees with the mapped users,
+// clearing them when none of the issue's assignees are mapped.
+func (s *Server) setGitHubTaskAssignees(ctx context.Context, taskID int64, assigneeID *int64, userIDs []int64) {
+	_, _ = s.db.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET assignee_id = ? WHERE id = ?`), assigneeID, taskID)
+	if len(userIDs) == 0 {
+		_, _ = s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM task_assignees WHERE task_id = ?`), taskID)
+		return
+	}
+	s.syncGitHubTaskAssignees(ctx, taskID, userIDs)
+}
+
+// applyGitHubIssueEvent creates or updates the task for an `issues` event.
+// Only the fields an action can change are touched, so board-driven swim
+// lanes and iteration sprints survive unrelated edits.
+func (s *Server) applyGitHubIssueEvent(ctx context.Context, p githubWebhookProject, payload *ghWebhookPayload, result *GitHubPullResponse) error {
+	if payload.Issue == nil || payload.Issue.PullRequest != nil {
+		return nil
+	}
+	issue := *payload.Issue
+	issue.Repo = payload.Repository.FullName
+	statusMap, userMap := s.loadSavedGitHubMappings(ctx, p.ID, nil, nil)
+
+	var taskID int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT id FROM tasks WHERE project_id = ? AND github_repo = ? AND github_issue_number = ?`),
+		p.ID, issue.Repo, issue.Number).Scan(&taskID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	exists := err == nil
+
+	// Deleted and transferred issues are left in place; the task keeps its history.
+	if payload.Action == "deleted" || payload.Action == "transferred" {
+		result.SkippedTasks++
+		return nil
+	}
+
+	taskStatus := "todo"
+	if issue.State == "closed" {
+		taskStatus = "done"
+	}
+	resolveLane := func() *int64 {
+		stateKey := issueStatusKey(issue.State, issue.StateReason)
+		if laneID, ok := statusMap[stateKey]; ok && laneID > 0 {
+			return &laneID
+		}
+		return s.findSwimLaneForCategory(ctx, p.ID, taskStatus)
+	}
+	assigneeID, allAssigneeIDs := resolveGitHubAssignees(issue, userMap)
+
+	if !exists {
+		var maxNumber sql.NullInt64
+		_ = s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT MAX(task_number) FROM tasks WHERE project_id = ?`), p.ID).Scan(&maxNumber)
+		nextNumber := maxNumber.Int64 + 1
+
+		err = s.db.QueryRowContext(ctx, s.db.Rebind(`
+			INSERT INTO tasks (project_id, task_number, title, description, status, priority, assignee_id, sprint_id, github_issue_number, github_repo, swim_lane_id)
+			VALUES (?, ?, ?, ?, ?, 'medium', ?, ?, ?, ?, ?)
+			ON CONFLICT (project_id, github_repo, github_issue_number) WHERE github_issue_number IS NOT NULL DO NOTHING
+			RETURNING id
+		`), p.ID, nextNumber, issue.Title, issue.Body, taskStatus, assigneeID, s.resolveGitHubIssueSprint(ctx, p, issue),
+			issue.Number, issue.Repo, resolveLane()).Scan(&taskID)
+		if err == sql.ErrNoRows {
+			// Created concurrently by a sync run
+			result.SkippedTasks++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.syncGitHubTaskAssignees(ctx, taskID, allAssigneeIDs)
+		s.insertTaskTags(ctx, taskID, issue.Labels, s.loadGitHubLabelTags(ctx, p.ID))
+		s.upsertReactions(ctx, taskID, 0, issue.Reactions)
+		s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, nil, taskID)
+		result.CreatedTasks++
+		return nil
+	}
+
+	before, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET title = ?, description = ? WHERE id = ?`),
+		issue.Title, issue.Body, taskID); err != nil {
+		return err
+	}
+
+	switch payload.Action {
+	case "closed", "reopened":
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET status = ?, swim_lane_id = ? WHERE id = ?`),
+			taskStatus, resolveLane(), taskID); err != nil {
+			return err
+		}
+	case "assigned", "unassigned":
+		s.setGitHubTaskAssignees(ctx, taskID, assigneeID, allAssigneeIDs)
+	case "milestoned", "demilestoned":
+		if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET sprint_id = ? WHERE id = ?`),
+			s.resolveGitHubIssueSprint(ctx, p, issue), taskID); err != nil {
+			return err
+		}
+	case "labeled", "unlabeled":
+		if payload.Label != nil {
+			var tagID int64
+			err := s.db.QueryRowContext(ctx, s.db.Rebind(
+				`SELECT id FROM tags WHERE project_id = ? AND github_label_name = ?`),
+				p.ID, payload.Label.Name).Scan(&tagID)
+			if err == nil {
+				if payload.Action == "labeled" {
+					_, _ = s.db.ExecContext(ctx, s.db.Rebind(
+						`INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING`), taskID, tagID)
+				} else {
+					_, _ = s.db.ExecContext(ctx, s.db.Rebind(
+						`DELETE FROM task_tags WHERE task_id = ? AND tag_id = ?`), taskID, tagID)
+				}
+			}
+		}
+	}
+	s.upsertReactions(ctx, taskID, 0, issue.Reactions)
+	s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, &before, taskID)
+	result.UpdatedTasks++
+	return nil
+}
+
+// loadGitHubLabelTags returns the project's label name → tag ID map.
+func (s *Server) loadGitHubLabelTags(ctx context.Context, projectID int64) map[string]int64 {
+	labelToTagID := map[string]int64{}
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT github_label_name, id FROM tags WHERE project_id = ? AND github_label_name IS NOT NULL`), projectID)
+	if err != nil {
+		return labelToTagID
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var id int64
+		if err := rows.Scan(&name, &id); err == nil {
+			labelToTagID[name] = id
+		}
+	}
+	return labelToTagID
+}
+
+// applyGitHubIssueCommentEvent mirrors comment creation, edits and deletions.
+func (s *Server) applyGitHubIssueCommentEvent(ctx context.Context, p githubWebhookProject, payload *ghWebhookPayload, result *GitHubPullResponse) error {
+	if payload.Issue == nil || payload.Comment == nil || payload.Issue.PullRequest != nil {
+		return nil
+	}
+	gc := *payload.Comment
+
+	var taskID int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT id FROM tasks WHERE project_id = ? AND github_repo = ? AND github_issue_number = ?`),
+		p.ID, payload.Repository.FullName, payload.Issue.Number).Scan(&taskID)
+	if err == sql.ErrNoRows {
+		result.SkippedTasks++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if payload.Action == "deleted" {
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`DELETE FROM task_comments WHERE task_id = ? AND github_comment_id = ?`), taskID, gc.ID)
+		return err
+	}
+
+	// Comments pushed from TaskAI come back as webhook events before their
+	// github_comment_id is stored; skip them to avoid duplicates.
+	if gc.Body == "" || strings.Contains(gc.Body, "** (via TaskAI):\n\n") {
+		return nil
+	}
+	userID, body := s.resolveGitHubCommentAuthor(ctx, gc, p.OwnerID)
+
+	switch payload.Action {
+	case "created":
+		var newID int64
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(`
+			INSERT INTO task_comments (task_id, user_id, comment, github_comment_id)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT (github_comment_id) WHERE github_comment_id IS NOT NULL DO NOTHING
+			RETURNING id
+		`), taskID, userID, body, gc.ID).Scan(&newID)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result.CreatedComments++
+	case "edited":
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`UPDATE task_comments SET comment = ?, updated_at = ? WHERE task_id = ? AND github_comment_id = ?`),
+			body, time.Now(), taskID, gc.ID)
+		return err
+	}
+	return nil
+}
+
+// applyGitHubLabelEvent keeps GitHub-linked tags in step with repository labels.
+func (s *Server) applyGitHubLabelEvent(ctx context.Context, p githubWebhookProject, payload *ghWebhookPayload) error {
+	if payload.Label == nil || payload.Label.Name == "" {
+		return nil
+	}
+	// Only the project's own repo owns its label set.
+	if !strings.EqualFold(payload.Repository.FullName, p.Owner+"/"+p.Repo) {
+		return nil
+	}
+	color := "#" + payload.Label.Color
+	if payload.Label.Color == "" {
+		color = "#6B7280"
+	}
+
+	switch payload.Action {
+	case "created":
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(`
+			INSERT INTO tags (user_id, project_id, name, color, github_label_name)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (project_id, github_label_name) DO NOTHING
+		`), p.OwnerID, p.ID, payload.Label.Name, color, payload.Label.Name)
+		return err
+	case "edited":
+		oldName := payload.Label.Name
+		if payload.Changes != nil && payload.Changes.Name != nil && payload.Changes.Name.From != "" {
+			oldName = payload.Changes.Name.From
+		}
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`UPDATE tags SET name = ?, color = ?, github_label_name = ? WHERE project_id = ? AND github_label_name = ?`),
+			payload.Label.Name, color, payload.Label.Name, p.ID, oldName)
+		return err
+	case "deleted":
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`DELETE FROM tags WHERE project_id = ? AND github_label_name = ?`), p.ID, payload.Label.Name)
+		return err
+	}
+	return nil
+}
+
+// applyGitHubProjectItemEvent moves a task to the swim lane matching its new
+// Projects V2 status column.
+func (s *Server) applyGitHubProjectItemEvent(ctx context.Context, p githubWebhookProject, payload *ghWebhookPayload, result *GitHubPullResponse) error {
+	if payload.Action != "edited" {
+		return nil
+	}
+	item := payload.ProjectsV2Item
+
+	var taskID int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT id FROM tasks WHERE project_id = ? AND github_project_item_id = ?`),
+		p.ID, item.NodeID).Scan(&taskID)
+	if err == sql.ErrNoRows {
+		result.SkippedTasks++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var statusFieldID string
+	_ = s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT COALESCE(github_status_field_id,'') FROM projects WHERE id = ?`), p.ID).Scan(&statusFieldID)
+
+	// Prefer the option carried in the change itself; fall back to asking GitHub.
+	var option ghWebhookFieldOption
+	if fv := payload.Changes; fv != nil && fv.FieldValue != nil {
+		isStatus := strings.EqualFold(fv.FieldValue.FieldName, "Status") ||
+			(statusFieldID != "" && fv.FieldValue.FieldNodeID == statusFieldID)
+		if !isStatus {
+			return nil
+		}
+		_ = json.Unmarshal(fv.FieldValue.To, &option)
+	}
+	if option.ID == "" && option.Name == "" {
+		if option, err = fetchProjectItemStatus(ctx, p.Token, item.NodeID); err != nil {
+			return err
+		}
+		if option.Name == "" {
+			return nil
+		}
+	}
+
+	var laneID int64
+	var category string
+	err = sql.ErrNoRows
+	if option.ID != "" {
+		err = s.db.QueryRowContext(ctx, s.db.Rebind(
+			`SELECT id, status_category FROM swim_lanes WHERE project_id = ? AND github_option_id = ?`),
+			p.ID, option.ID).Scan(&laneID, &category)
+	}
+	if err == sql.ErrNoRows && option.Name != "" {
+		statusMap, _ := s.loadSavedGitHubMappings(ctx, p.ID, nil, nil)
+		mapped, ok := statusMap[option.Name]
+		if !ok {
+			for k, v := range statusMap {
+				if strings.EqualFold(k, option.Name) {
+					mapped, ok = v, true
+					break
+				}
+			}
+		}
+		if !ok || mapped == 0 {
+			s.registerUnknownStatusKeys(ctx, p.ID, map[string]struct{}{option.Name: {}})
+			result.SkippedTasks++
+			return nil
+		}
+		err = s.db.QueryRowContext(ctx, s.db.Rebind(
+			`SELECT id, status_category FROM swim_lanes WHERE id = ? AND project_id = ?`),
+			mapped, p.ID).Scan(&laneID, &category)
+	}
+	if err == sql.ErrNoRows {
+		result.SkippedTasks++
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	before, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET swim_lane_id = ?, status = ? WHERE id = ?`),
+		laneID, category, taskID); err != nil {
+		return err
+	}
+	s.recordSyncedTaskChange(ctx, db.AuditChannelGitHub, &before, taskID)
+	result.UpdatedTasks++
+	return nil
+}
+
+// fetchProjectItemStatus reads the current Status column of a Projects V2 item.
+func fetchProjectItemStatus(ctx context.Context, token, itemNodeID string) (ghWebhookFieldOption, error) {
+	var option ghWebhookFieldOption
+	if token == "" {
+		return option, fmt.Errorf("project has no GitHub token")
+	}
+	const q = `
+query($id: ID!) {
+  node(id: $id) {
+    ... on ProjectV2Item {
+      fieldValueByName(name: "Status") {
+        ... on ProjectV2ItemFieldSingleSelectValue { name optionId }
+      }
+    }
+  }
+}`
+	var resp struct {
+		Data struct {
+			Node struct {
+				FieldValueByName *struct {
+					Name     string `json:"name"`
+					OptionID string `json:"optionId"`
+				} `json:"fieldValueByName"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+	if err := fetchGitHubGraphQL(ctx, token, q, map[string]interface{}{"id": itemNodeID}, &resp); err != nil {
+		return option, err
+	}
+	if fv := resp.Data.Node.FieldValueByName; fv != nil {
+		option.ID = fv.OptionID
+		option.Name = fv.Name
+	}
+	return option, nil
+}
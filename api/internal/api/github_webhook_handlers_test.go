@@ -0,0 +1,356 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupGitHubWebhookProject creates a sync-enabled project linked to acme/app
+// with webhook secret "gh-secret" and a todo and a done swim lane.
+func setupGitHubWebhookProject(t *testing.T, ts *TestServer) (ownerID, projectID int64) {
+	t.Helper()
+
+	ownerID = ts.CreateTestUser(t, "owner@example.com", "password123")
+	projectID = ts.CreateTestProject(t, ownerID, "Synced Project")
+	_, err := ts.DB.Exec(
+		`UPDATE projects SET github_owner = 'acme', github_repo_name = 'app', github_sync_enabled = 1,
+		 github_webhook_secret = 'gh-secret' WHERE id = ?`,
+		projectID)
+	if err != nil {
+		t.Fatalf("Failed to link project: %v", err)
+	}
+	for i, cat := range []string{"todo", "done"} {
+		_, err := ts.DB.Exec(
+			`INSERT INTO swim_lanes (project_id, name, color, position, status_category) VALUES (?, ?, ?, ?, ?)`,
+			projectID, cat, "#6B7280", i, cat)
+		if err != nil {
+			t.Fatalf("Failed to create swim lane: %v", err)
+		}
+	}
+	return ownerID, projectID
+}
+
+func decodeGitHubWebhookPayload(t *testing.T, raw string) *ghWebhookPayload {
+	t.Helper()
+	var payload ghWebhookPayload
+	if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	return &payload
+}
+
+// applyTestGitHubWebhookEvent applies an event to the projects it belongs to,
+// as HandleGitHubWebhook does once the signature is verified.
+func applyTestGitHubWebhookEvent(t *testing.T, ts *TestServer, event string, payload *ghWebhookPayload) {
+	t.Helper()
+	ctx := context.Background()
+	projects, err := ts.findGitHubWebhookProjects(ctx, event, payload)
+	if err != nil {
+		t.Fatalf("Failed to find projects: %v", err)
+	}
+	ts.applyGitHubWebhookEvent(ctx, event, payload, projects)
+}
+
+func TestHandleGitHubWebhook(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	_, projectID := setupGitHubWebhookProject(t, ts)
+	ownerID := ts.CreateTestUser(t, "other@example.com", "password123")
+	otherID := ts.CreateTestProject(t, ownerID, "Other Project")
+	_, err := ts.DB.Exec(
+		`UPDATE projects SET github_owner = 'acme', github_repo_name = 'other', github_sync_enabled = 1,
+		 github_webhook_secret = 'other-secret' WHERE id = ?`, otherID)
+	if err != nil {
+		t.Fatalf("Failed to link project: %v", err)
+	}
+
+	send := func(event, delivery, secret, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/github/webhook", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-GitHub-Delivery", delivery)
+		if secret != "" {
+			req.Header.Set("X-Hub-Signature-256", signWebhookPayload(secret, []byte(body)))
+		}
+		rec := httptest.NewRecorder()
+		ts.HandleGitHubWebhook(rec, req)
+		return rec
+	}
+
+	ping := `{"zen":"Keep it logically awesome.","repository":{"full_name":"acme/app"}}`
+	tests := []struct {
+		name       string
+		event      string
+		secret     string
+		body       string
+		wantStatus int
+	}{
+		{"valid ping", "ping", "gh-secret", ping, http.StatusOK},
+		{"ping without repository", "ping", "other-secret", `{"zen":"Hi"}`, http.StatusOK},
+		{"wrong secret", "ping", "wrong", ping, http.StatusUnauthorized},
+		{"another project's secret", "ping", "other-secret", ping, http.StatusUnauthorized},
+		{"missing signature", "ping", "", ping, http.StatusUnauthorized},
+		{"unlinked repository", "ping", "gh-secret", `{"repository":{"full_name":"acme/unknown"}}`, http.StatusUnauthorized},
+		{"unhandled event", "push", "gh-secret", ping, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			AssertStatusCode(t, send(tt.event, "d-"+tt.name, tt.secret, tt.body).Code, tt.wantStatus)
+		})
+	}
+
+	t.Run("redelivery is applied once", func(t *testing.T) {
+		label := `{"action":"created","label":{"name":"perf","color":"fbca04"},"repository":{"full_name":"acme/app"}}`
+
+		rec := send("label", "", "gh-secret", label)
+		AssertError(t, rec, http.StatusBadRequest, "X-GitHub-Delivery", "invalid_input")
+
+		rec = send("label", "delivery-1", "gh-secret", label)
+		AssertStatusCode(t, rec.Code, http.StatusAccepted)
+		rec = send("label", "delivery-1", "gh-secret", label)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), "already processed") {
+			t.Errorf("Expected redelivery to be acknowledged, got %s", rec.Body.String())
+		}
+
+		var logCount int
+		for range 50 {
+			_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM github_sync_logs WHERE project_id = ?`, projectID).Scan(&logCount)
+			if logCount > 0 {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond)
+		_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM github_sync_logs WHERE project_id = ?`, projectID).Scan(&logCount)
+		if logCount != 1 {
+			t.Errorf("Expected the event applied once, got %d sync log entries", logCount)
+		}
+	})
+}
+
+func TestHandleRotateGitHubWebhookSecret(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID, projectID := setupGitHubWebhookProject(t, ts)
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	ts.AddProjectMember(t, projectID, memberID, ownerID, "member")
+	rotate := func(userID int64) *httptest.ResponseRecorder {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+			fmt.Sprintf("/api/projects/%d/github/webhook-secret", projectID), nil, userID,
+			map[string]string{"id": fmt.Sprintf("%d", projectID)})
+		ts.HandleRotateGitHubWebhookSecret(rec, req)
+		return rec
+	}
+
+	AssertError(t, rotate(memberID), http.StatusForbidden, "", "forbidden")
+
+	rec := rotate(ownerID)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp GitHubWebhookSecretResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.Secret == "" || !strings.HasSuffix(resp.PayloadURL, "/api/github/webhook") {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+
+	projects, err := ts.findGitHubWebhookProjectsByRepo(context.Background(), "acme/app")
+	if err != nil {
+		t.Fatalf("Failed to find projects: %v", err)
+	}
+	body := []byte(`{"repository":{"full_name":"acme/app"}}`)
+	if len(signedGitHubWebhookProjects(projects, body, signWebhookPayload(resp.Secret, body))) != 1 {
+		t.Error("Expected deliveries signed with the new secret to verify")
+	}
+	if len(signedGitHubWebhookProjects(projects, body, signWebhookPayload("gh-secret", body))) != 0 {
+		t.Error("Expected the previous secret to be rejected")
+	}
+}
+
+func TestApplyGitHubIssueEvents(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	_, projectID := setupGitHubWebhookProject(t, ts)
+	ctx := context.Background()
+
+	_, err := ts.DB.Exec(
+		`INSERT INTO tags (user_id, project_id, name, color, github_label_name)
+		 SELECT owner_id, id, 'bug', '#d73a4a', 'bug' FROM projects WHERE id = ?`, projectID)
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+
+	applyTestGitHubWebhookEvent(t, ts, "issues", decodeGitHubWebhookPayload(t, `{
+		"action": "opened",
+		"issue": {"number": 7, "title": "Crash on save", "body": "Steps...", "state": "open",
+		          "labels": [{"name": "bug", "color": "d73a4a"}]},
+		"repository": {"full_name": "acme/app"}
+	}`))
+
+	var taskID int64
+	var title, status string
+	err = ts.DB.QueryRow(
+		`SELECT id, title, status FROM tasks WHERE project_id = ? AND github_repo = 'acme/app' AND github_issue_number = 7`,
+		projectID).Scan(&taskID, &title, &status)
+	if err != nil {
+		t.Fatalf("Expected task for opened issue: %v", err)
+	}
+	if title != "Crash on save" || status != "todo" {
+		t.Errorf("Unexpected task: title=%q status=%q", title, status)
+	}
+	var tagCount int
+	_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM task_tags WHERE task_id = ?`, taskID).Scan(&tagCount)
+	if tagCount != 1 {
+		t.Errorf("Expected label to be applied as tag, got %d tags", tagCount)
+	}
+
+	// Sync follows GitHub past hard WIP limits, but flags the move
+	if _, err := ts.DB.Exec(`UPDATE swim_lanes SET wip_limit = 0, wip_enforcement = 'hard'
+		WHERE project_id = ? AND status_category = 'done'`, projectID); err != nil {
+		t.Fatalf("Failed to set WIP limit: %v", err)
+	}
+	applyTestGitHubWebhookEvent(t, ts, "issues", decodeGitHubWebhookPayload(t, `{
+		"action": "closed",
+		"issue": {"number": 7, "title": "Crash on save (fixed)", "body": "Steps...", "state": "closed", "state_reason": "completed"},
+		"repository": {"full_name": "acme/app"}
+	}`))
+
+	var laneCategory string
+	err = ts.DB.QueryRow(
+		`SELECT t.title, t.status, sl.status_category FROM tasks t JOIN swim_lanes sl ON sl.id = t.swim_lane_id WHERE t.id = ?`,
+		taskID).Scan(&title, &status, &laneCategory)
+	if err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if title != "Crash on save (fixed)" || status != "done" || laneCategory != "done" {
+		t.Errorf("Expected closed issue in done lane, got title=%q status=%q lane=%q", title, status, laneCategory)
+	}
+
+	history, err := ts.DB.ListTaskHistory(ctx, taskID)
+	if err != nil {
+		t.Fatalf("Failed to list task history: %v", err)
+	}
+	changed := map[string]bool{}
+	for _, e := range history {
+		changed[e.Field] = true
+		if e.Source != "github" || e.UserID != nil {
+			t.Errorf("Expected history from github without a user, got %+v", e)
+		}
+	}
+	if !changed["title"] || !changed["status"] || !changed["swim_lane"] {
+		t.Errorf("Expected title, status and lane changes recorded, got %+v", history)
+	}
+	transitions, err := ts.DB.ListLaneTransitions(ctx, projectID)
+	if err != nil {
+		t.Fatalf("Failed to list lane transitions: %v", err)
+	}
+	if len(transitions) != 2 || transitions[1].ToStatus != "done" {
+		t.Fatalf("Expected the task's creation and close as lane transitions, got %+v", transitions)
+	}
+	if transitions[0].WIPLimitExceeded || !transitions[1].WIPLimitExceeded {
+		t.Errorf("Expected only the move into the full lane flagged, got %+v", transitions)
+	}
+
+	var logCount int
+	_ = ts.DB.QueryRow(
+		`SELECT COUNT(*) FROM github_sync_logs WHERE project_id = ? AND sync_mode = 'webhook' AND status = 'success'`,
+		projectID).Scan(&logCount)
+	if logCount != 2 {
+		t.Errorf("Expected 2 webhook sync log entries, got %d", logCount)
+	}
+
+	// Events for unrelated repos are ignored
+	applyTestGitHubWebhookEvent(t, ts, "issues", decodeGitHubWebhookPayload(t, `{
+		"action": "opened",
+		"issue": {"number": 1, "title": "Elsewhere", "state": "open"},
+		"repository": {"full_name": "acme/other"}
+	}`))
+	var taskCount int
+	_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM tasks WHERE project_id = ?`, projectID).Scan(&taskCount)
+	if taskCount != 1 {
+		t.Errorf("Expected 1 task, got %d", taskCount)
+	}
+}
+
+func TestApplyGitHubCommentAndLabelEvents(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	_, projectID := setupGitHubWebhookProject(t, ts)
+
+	taskID := ts.CreateTestTask(t, projectID, "Linked task")
+	_, _ = ts.DB.Exec(`UPDATE tasks SET github_repo = 'acme/app', github_issue_number = 3 WHERE id = ?`, taskID)
+
+	commentEvent := func(action, body string) {
+		applyTestGitHubWebhookEvent(t, ts, "issue_comment", decodeGitHubWebhookPayload(t, `{
+			"action": "`+action+`",
+			"issue": {"number": 3, "title": "Linked task", "state": "open"},
+			"comment": {"id": 555, "body": "`+body+`", "user": {"id": 1, "login": "octocat"}},
+			"repository": {"full_name": "acme/app"}
+		}`))
+	}
+	countComments := func() int {
+		var n int
+		_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM task_comments WHERE task_id = ?`, taskID).Scan(&n)
+		return n
+	}
+
+	commentEvent("created", "Looks good")
+	commentEvent("created", "Looks good") // redelivery
+	if n := countComments(); n != 1 {
+		t.Fatalf("Expected 1 comment, got %d", n)
+	}
+	commentEvent("created", `**Alice** (via TaskAI):\n\nEchoed`)
+	if n := countComments(); n != 1 {
+		t.Errorf("Expected TaskAI echo to be skipped, got %d comments", n)
+	}
+	commentEvent("deleted", "Looks good")
+	if n := countComments(); n != 0 {
+		t.Errorf("Expected comment to be deleted, got %d", n)
+	}
+
+	labelEvent := func(raw string) {
+		applyTestGitHubWebhookEvent(t, ts, "label", decodeGitHubWebhookPayload(t, raw))
+	}
+	labelEvent(`{"action": "created", "label": {"name": "perf", "color": "fbca04"}, "repository": {"full_name": "acme/app"}}`)
+	labelEvent(`{"action": "edited", "label": {"name": "performance", "color": "0e8a16"},
+		"changes": {"name": {"from": "perf"}}, "repository": {"full_name": "acme/app"}}`)
+
+	var name, color string
+	err := ts.DB.QueryRow(
+		`SELECT name, color FROM tags WHERE project_id = ? AND github_label_name = 'performance'`, projectID).Scan(&name, &color)
+	if err != nil {
+		t.Fatalf("Expected renamed tag: %v", err)
+	}
+	if name != "performance" || color != "#0e8a16" {
+		t.Errorf("Unexpected tag: name=%q color=%q", name, color)
+	}
+
+	labelEvent(`{"action": "deleted", "label": {"name": "performance"}, "repository": {"full_name": "acme/app"}}`)
+	var tagCount int
+	_ = ts.DB.QueryRow(`SELECT COUNT(*) FROM tags WHERE project_id = ?`, projectID).Scan(&tagCount)
+	if tagCount != 0 {
+		t.Errorf("Expected tag to be deleted, got %d", tagCount)
+	}
+}
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+	if !verifyGitHubSignature("s", body, signWebhookPayload("s", body)) {
+		t.Error("Expected valid signature to verify")
+	}
+	if verifyGitHubSignature("s", body, "sha1=abc") {
+		t.Error("Expected non-sha256 signature to be rejected")
+	}
+	if verifyGitHubSignature("s", []byte(`{}`), signWebhookPayload("s", body)) {
+		t.Error("Expected signature over a different body to be rejected")
+	}
+}
@@ -154,9 +154,10 @@ func (s *Server) syncGraphLinks(ctx context.Context, projectID int64, sourceType
 		targetNodeIDs = append(targetNodeIDs, targetNodeID)
 	}
 
-	// Delete all outgoing edges from source, then re-insert current ones.
+	// Delete all outgoing reference edges from source, then re-insert current ones.
+	// Typed task relations are managed separately and left alone.
 	if _, err = s.db.ExecContext(ctx, s.db.Rebind(
-		`DELETE FROM graph_edges WHERE source_node_id = ?`,
+		`DELETE FROM graph_edges WHERE source_node_id = ? AND relation_type = 'reference'`,
 	), sourceNodeID); err != nil {
 		s.logger.Warn("Failed to delete stale graph edges",
 			zap.Int64("source_node_id", sourceNodeID),
@@ -168,7 +169,7 @@ func (s *Server) syncGraphLinks(ctx context.Context, projectID int64, sourceType
 		if _, err = s.db.ExecContext(ctx, s.db.Rebind(`
 			INSERT INTO graph_edges (source_node_id, target_node_id, relation_type)
 			VALUES (?, ?, 'reference')
-			ON CONFLICT(source_node_id, target_node_id) DO NOTHING
+			ON CONFLICT(source_node_id, target_node_id, relation_type) DO NOTHING
 		`), sourceNodeID, targetNodeID); err != nil {
 			s.logger.Warn("Failed to insert graph edge",
 				zap.Int64("source", sourceNodeID),
@@ -191,7 +192,7 @@ func (s *Server) HandleGetProjectGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -312,8 +312,19 @@ func (s *Server) HandleAdminBoostInvites(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
+	target, err := s.db.Client.User.Get(ctx, targetUserID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "user not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to get user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update invite count", "internal_error")
+		return
+	}
+
 	// Update the user
-	err := s.db.Client.User.UpdateOneID(targetUserID).
+	err = s.db.Client.User.UpdateOneID(targetUserID).
 		SetInviteCount(req.InviteCount).
 		Exec(ctx)
 	if err != nil {
@@ -332,6 +343,11 @@ func (s *Server) HandleAdminBoostInvites(w http.ResponseWriter, r *http.Request)
 		zap.Int("invite_count", req.InviteCount),
 	)
 
+	auditChange(r, auditEvent{
+		Action: "user.invites_changed", ResourceType: "user", ResourceID: targetUserID,
+		Before: map[string]int{"invite_count": target.InviteCount},
+		After:  map[string]int{"invite_count": req.InviteCount},
+	})
 	respondJSON(w, http.StatusOK, map[string]interface{}{
 		"id":           targetUserID,
 		"invite_count": req.InviteCount,
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/auth"
+	"taskai/internal/db"
+)
+
+const (
+	// lockoutThreshold is how many consecutive failed logins lock an account.
+	// Every further lockoutThreshold failures lock it again, for longer.
+	lockoutThreshold = 5
+	// lockoutBaseDuration is the length of the first lockout; each following
+	// one is three times as long, up to lockoutMaxDuration
+	lockoutBaseDuration = 5 * time.Minute
+	lockoutMaxDuration  = 24 * time.Hour
+	// unlockTokenTTL is how long an emailed unlock link works
+	unlockTokenTTL = time.Hour
+	// loginHistorySize is how many past logins a new login is compared with
+	loginHistorySize = 100
+)
+
+// UnlockAccountRequest is the body of the account unlock endpoint
+type UnlockAccountRequest struct {
+	Token string `json:"token"`
+}
+
+// lockoutDuration returns how long an account is locked after failures
+// consecutive failed logins, or 0 if it isn't locked.
+func lockoutDuration(failures int) time.Duration {
+	if failures < lockoutThreshold || failures%lockoutThreshold != 0 {
+		return 0
+	}
+	d := lockoutBaseDuration
+	for i := lockoutThreshold; i < failures; i += lockoutThreshold {
+		d *= 3
+		if d >= lockoutMaxDuration {
+			return lockoutMaxDuration
+		}
+	}
+	return d
+}
+
+// respondAccountLocked rejects a login to a locked account
+func respondAccountLocked(w http.ResponseWriter, until time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(time.Until(until))))
+	respondError(w, http.StatusLocked,
+		"account locked after too many failed login attempts; check your email to unlock it or try again later",
+		"account_locked")
+}
+
+// recordFailedLogin counts a failed password for a user and locks the
+// account when the count crosses the threshold, emailing an unlock link. It
+// returns the time the account is locked until, or nil.
+func (s *Server) recordFailedLogin(ctx context.Context, r *http.Request, userID int64, email string) *time.Time {
+	ip, userAgent := getClientIP(r), r.UserAgent()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.logUserActivity(ctx, userID, "failed_login", ip, userAgent)
+	}()
+
+	failures, err := s.db.RecordFailedLogin(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to record failed login", zap.Error(err), zap.Int64("user_id", userID))
+		return nil
+	}
+	d := lockoutDuration(failures)
+	if d == 0 {
+		return nil
+	}
+
+	until := time.Now().Add(d)
+	if err := s.db.LockAccount(ctx, userID, until); err != nil {
+		s.logger.Error("Failed to lock account", zap.Error(err), zap.Int64("user_id", userID))
+		return nil
+	}
+	s.logger.Warn("Account locked after failed logins",
+		zap.Int64("user_id", userID), zap.Int("failures", failures), zap.Duration("duration", d), zap.String("ip", ip))
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.logUserActivity(ctx, userID, "account_locked", ip, userAgent)
+		s.sendUnlockEmail(ctx, userID, email, until)
+	}()
+	return &until
+}
+
+// issueUnlockToken creates a one-time account unlock token for a user
+func (s *Server) issueUnlockToken(ctx context.Context, userID int64) (string, error) {
+	token, hash, err := auth.GenerateEmailToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.db.CreateUnlockToken(ctx, userID, hash, time.Now().Add(unlockTokenTTL)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// sendUnlockEmail emails a locked-out user a link to unlock their account.
+// Nothing is sent when no email provider is configured.
+func (s *Server) sendUnlockEmail(ctx context.Context, userID int64, email string, until time.Time) {
+	emailSvc := s.GetEmailService()
+	if emailSvc == nil {
+		return
+	}
+	token, err := s.issueUnlockToken(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to create unlock token", zap.Error(err), zap.Int64("user_id", userID))
+		return
+	}
+	if err := emailSvc.SendAccountLocked(ctx, email, token, s.getAppURL(), until); err != nil {
+		s.logger.Error("Failed to send account locked email", zap.Error(err), zap.String("email", email))
+	}
+}
+
+// newLoginSource reports whether a login from ip with userAgent comes from a
+// device or network the user's earlier logins haven't. Devices are compared
+// by browser and platform so browser updates don't count as new devices. A
+// user's first login is never new.
+func (s *Server) newLoginSource(ctx context.Context, userID int64, ip, userAgent string) (newDevice, newIP bool, err error) {
+	history, err := s.db.RecentLoginSources(ctx, userID, loginHistorySize)
+	if err != nil || len(history) == 0 {
+		return false, false, err
+	}
+
+	device := describeUserAgent(userAgent)
+	newDevice, newIP = true, true
+	for _, past := range history {
+		if describeUserAgent(past.UserAgent) == device {
+			newDevice = false
+		}
+		if past.IPAddress == ip {
+			newIP = false
+		}
+	}
+	return newDevice, newIP, nil
+}
+
+// recordLogin logs a successful login and alerts the user by email when it
+// comes from a new device or IP address. History is checked before the login
+// is added to it; logging and email happen in the background.
+func (s *Server) recordLogin(ctx context.Context, userID int64, email, ip, userAgent string) {
+	newDevice, newIP, err := s.newLoginSource(ctx, userID, ip, userAgent)
+	if err != nil {
+		s.logger.Error("Failed to check login history", zap.Error(err), zap.Int64("user_id", userID))
+	}
+	alert := newDevice || newIP
+	if alert {
+		s.logger.Info("Login from new device or IP",
+			zap.Int64("user_id", userID), zap.Bool("new_device", newDevice), zap.Bool("new_ip", newIP), zap.String("ip", ip))
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		s.logUserActivity(ctx, userID, "login", ip, userAgent)
+		if !alert {
+			return
+		}
+		s.logUserActivity(ctx, userID, "login_alert", ip, userAgent)
+		if emailSvc := s.GetEmailService(); emailSvc != nil {
+			if err := emailSvc.SendNewLoginAlert(ctx, email, describeUserAgent(userAgent), ip, time.Now(), s.getAppURL()); err != nil {
+				s.logger.Error("Failed to send new login alert", zap.Error(err), zap.String("email", email))
+			}
+		}
+	}()
+}
+
+// HandleUnlockAccount unlocks an account with the token from the email sent
+// when it was locked.
+// Route: POST /api/auth/unlock
+func (s *Server) HandleUnlockAccount(w http.ResponseWriter, r *http.Request) {
+	var req UnlockAccountRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.Token == "" {
+		respondError(w, http.StatusBadRequest, "token is required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, err := s.db.UseUnlockToken(ctx, auth.HashEmailToken(req.Token), time.Now())
+	if err != nil {
+		if errors.Is(err, db.ErrUnlockTokenInvalid) {
+			respondError(w, http.StatusBadRequest, "invalid or expired unlock link", "invalid_token")
+			return
+		}
+		s.logger.Error("Failed to use unlock token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to unlock account", "internal_error")
+		return
+	}
+
+	if _, err := s.db.ClearLockout(ctx, userID); err != nil {
+		s.logger.Error("Failed to unlock account", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to unlock account", "internal_error")
+		return
+	}
+	s.logUserActivity(ctx, userID, "account_unlocked", getClientIP(r), r.UserAgent())
+
+	respondJSON(w, http.StatusOK, map[string]string{"message": "Account unlocked"})
+}
+
+// HandleGetLockedAccounts returns the accounts currently locked after failed
+// logins (admin only)
+// Route: GET /api/admin/users/locked
+func (s *Server) HandleGetLockedAccounts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "user not authenticated", "unauthorized")
+		return
+	}
+	if !s.isAdmin(r.Context(), userID) {
+		respondError(w, http.StatusForbidden, "admin access required", "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	lockouts, err := s.db.ListLockedAccounts(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to list locked accounts", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to get locked accounts", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, lockouts)
+}
+
+// HandleAdminUnlockUser unlocks a user's account and resets their failed
+// login count (admin only)
+// Route: POST /api/admin/users/{id}/unlock
+func (s *Server) HandleAdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "user not authenticated", "unauthorized")
+		return
+	}
+	if !s.isAdmin(r.Context(), userID) {
+		respondError(w, http.StatusForbidden, "admin access required", "forbidden")
+		return
+	}
+
+	var targetUserID int64
+	if _, err := fmt.Sscanf(r.PathValue("id"), "%d", &targetUserID); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid user id", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	found, err := s.db.ClearLockout(ctx, targetUserID)
+	if err != nil {
+		s.logger.Error("Failed to unlock account", zap.Error(err), zap.Int64("target_user_id", targetUserID))
+		respondError(w, http.StatusInternalServerError, "failed to unlock account", "internal_error")
+		return
+	}
+	if !found {
+		respondError(w, http.StatusNotFound, "user not found", "not_found")
+		return
+	}
+	s.logUserActivity(ctx, targetUserID, "account_unlocked", getClientIP(r), r.UserAgent())
+
+	s.logger.Info("Admin unlocked account", zap.Int64("admin_id", userID), zap.Int64("target_user_id", targetUserID))
+	auditChange(r, auditEvent{Action: "user.unlocked", ResourceType: "user", ResourceID: targetUserID})
+	respondJSON(w, http.StatusOK, map[string]interface{}{"id": targetUserID, "unlocked": true})
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// waitForActivity waits until a user has n activities of a type, which are
+// logged in the background
+func (ts *TestServer) waitForActivity(t *testing.T, userID int64, activityType string, n int) {
+	t.Helper()
+
+	var count int
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		err := ts.DB.QueryRow(`SELECT COUNT(*) FROM user_activity WHERE user_id = ? AND activity_type = ?`, userID, activityType).Scan(&count)
+		if err != nil {
+			t.Fatalf("Failed to count activity: %v", err)
+		}
+		if count >= n {
+			return
+		}
+	}
+	t.Fatalf("Expected %d %s activities, got %d", n, activityType, count)
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 0},
+		{4, 0},
+		{5, 5 * time.Minute},
+		{6, 0},
+		{10, 15 * time.Minute},
+		{15, 45 * time.Minute},
+		{20, 2*time.Hour + 15*time.Minute},
+		{30, 20*time.Hour + 15*time.Minute},
+		{35, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.failures); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+
+	login := func(password string) LoginRequest {
+		return LoginRequest{Email: "user@example.com", Password: password}
+	}
+	doLogin := func(password string) (int, string) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/login", login(password), nil)
+		ts.HandleLogin(rec, req)
+		return rec.Code, rec.Header().Get("Retry-After")
+	}
+
+	for i := 1; i < lockoutThreshold; i++ {
+		if code, _ := doLogin("wrong-password"); code != http.StatusUnauthorized {
+			t.Fatalf("Failed login %d: expected 401, got %d", i, code)
+		}
+	}
+
+	rec, req := MakeRequest(t, http.MethodPost, "/api/auth/login", login("wrong-password"), nil)
+	ts.HandleLogin(rec, req)
+	AssertError(t, rec, http.StatusLocked, "account locked", "account_locked")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	// The right password doesn't get through a lock
+	if code, retryAfter := doLogin("password123"); code != http.StatusLocked || retryAfter == "" {
+		t.Errorf("Expected 423 with Retry-After while locked, got %d %q", code, retryAfter)
+	}
+
+	ts.waitForActivity(t, userID, "failed_login", lockoutThreshold)
+	ts.waitForActivity(t, userID, "account_locked", 1)
+
+	t.Run("unlock link", func(t *testing.T) {
+		token, err := ts.issueUnlockToken(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("Failed to issue unlock token: %v", err)
+		}
+
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/unlock", UnlockAccountRequest{Token: token}, nil)
+		ts.HandleUnlockAccount(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		if code, _ := doLogin("password123"); code != http.StatusOK {
+			t.Errorf("Expected login to work after unlocking, got %d", code)
+		}
+		ts.waitForActivity(t, userID, "login", 1)
+
+		rec, req = MakeRequest(t, http.MethodPost, "/api/auth/unlock", UnlockAccountRequest{Token: token}, nil)
+		ts.HandleUnlockAccount(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "invalid or expired unlock link", "invalid_token")
+	})
+
+	t.Run("successful login resets the count", func(t *testing.T) {
+		lockout, err := ts.DB.GetLockout(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("Failed to get lockout: %v", err)
+		}
+		if lockout.FailedLogins != 0 || lockout.LockedUntil != nil {
+			t.Errorf("Expected a clean lockout state, got %+v", lockout)
+		}
+	})
+
+	t.Run("locks expire and grow", func(t *testing.T) {
+		for i := 0; i < lockoutThreshold; i++ {
+			doLogin("wrong-password")
+		}
+		// Let the first lock run out without a successful login
+		if _, err := ts.DB.Exec(`UPDATE users SET locked_until = ? WHERE id = ?`, time.Now().Add(-time.Second).UTC(), userID); err != nil {
+			t.Fatalf("Failed to expire lock: %v", err)
+		}
+		for i := 1; i < lockoutThreshold; i++ {
+			if code, _ := doLogin("wrong-password"); code != http.StatusUnauthorized {
+				t.Fatalf("Expected 401 after the lock expired, got %d", code)
+			}
+		}
+		doLogin("wrong-password")
+
+		lockout, err := ts.DB.GetLockout(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("Failed to get lockout: %v", err)
+		}
+		if lockout.LockedUntil == nil || time.Until(*lockout.LockedUntil) < 10*time.Minute {
+			t.Errorf("Expected the second lock to last longer than the first, got %+v", lockout)
+		}
+		ts.waitForActivity(t, userID, "account_locked", 3)
+		ts.waitForActivity(t, userID, "failed_login", 3*lockoutThreshold)
+	})
+}
+
+func TestAdminLockedAccounts(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+	if _, err := ts.DB.Exec(`UPDATE users SET is_admin = 1 WHERE id = ?`, adminID); err != nil {
+		t.Fatalf("Failed to make admin: %v", err)
+	}
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	ts.CreateTestUser(t, "other@example.com", "password123")
+
+	ctx := context.Background()
+	if _, err := ts.DB.RecordFailedLogin(ctx, userID); err != nil {
+		t.Fatalf("Failed to record failed login: %v", err)
+	}
+	if err := ts.DB.LockAccount(ctx, userID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to lock account: %v", err)
+	}
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/admin/users/locked", nil, adminID, nil)
+	ts.HandleGetLockedAccounts(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	var locked []struct {
+		UserID       int64  `json:"user_id"`
+		Email        string `json:"email"`
+		FailedLogins int    `json:"failed_logins"`
+	}
+	DecodeJSON(t, rec, &locked)
+	if len(locked) != 1 || locked[0].UserID != userID || locked[0].FailedLogins != 1 {
+		t.Fatalf("Expected only user@example.com to be locked, got %+v", locked)
+	}
+
+	t.Run("non-admin", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/admin/users/locked", nil, userID, nil)
+		ts.HandleGetLockedAccounts(rec, req)
+		AssertError(t, rec, http.StatusForbidden, "admin access required", "forbidden")
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodPost, "/api/admin/users/x/unlock", nil, userID, nil)
+		req.SetPathValue("id", strconv.FormatInt(userID, 10))
+		ts.HandleAdminUnlockUser(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("unlock", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/admin/users/x/unlock", nil, adminID, nil)
+		req.SetPathValue("id", strconv.FormatInt(userID, 10))
+		ts.HandleAdminUnlockUser(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		lockouts, err := ts.DB.ListLockedAccounts(ctx, time.Now())
+		if err != nil {
+			t.Fatalf("Failed to list locked accounts: %v", err)
+		}
+		if len(lockouts) != 0 {
+			t.Errorf("Expected no locked accounts, got %+v", lockouts)
+		}
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodPost, "/api/admin/users/x/unlock", nil, adminID, nil)
+		req.SetPathValue("id", "999")
+		ts.HandleAdminUnlockUser(rec, req)
+		AssertError(t, rec, http.StatusNotFound, "user not found", "not_found")
+	})
+}
+
+func TestNewLoginSource(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	ctx := context.Background()
+
+	newDevice, newIP, err := ts.newLoginSource(ctx, userID, "203.0.113.7", chromeMacUA)
+	if err != nil || newDevice || newIP {
+		t.Fatalf("Expected a first login not to be flagged, got %v %v %v", newDevice, newIP, err)
+	}
+
+	ts.logUserActivity(ctx, userID, "login", "203.0.113.7", chromeMacUA)
+	ts.logUserActivity(ctx, userID, "failed_login", "198.51.100.1", firefoxLinuxUA)
+
+	tests := []struct {
+		name       string
+		ip         string
+		userAgent  string
+		wantDevice bool
+		wantIP     bool
+	}{
+		{"known device and IP", "203.0.113.7", chromeMacUA, false, false},
+		{"browser update", "203.0.113.7", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/127.0.0.0 Safari/537.36", false, false},
+		{"new IP", "192.0.2.44", chromeMacUA, false, true},
+		{"new device", "203.0.113.7", firefoxLinuxUA, true, false},
+		{"failed logins don't count", "198.51.100.1", firefoxLinuxUA, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newDevice, newIP, err := ts.newLoginSource(ctx, userID, tt.ip, tt.userAgent)
+			if err != nil {
+				t.Fatalf("newLoginSource failed: %v", err)
+			}
+			if newDevice != tt.wantDevice || newIP != tt.wantIP {
+				t.Errorf("newLoginSource() = (%v, %v), want (%v, %v)", newDevice, newIP, tt.wantDevice, tt.wantIP)
+			}
+		})
+	}
+
+	t.Run("login records an alert", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/login",
+			LoginRequest{Email: "user@example.com", Password: "password123"},
+			map[string]string{"User-Agent": firefoxLinuxUA, "X-Forwarded-For": "192.0.2.44"})
+		ts.HandleLogin(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		ts.waitForActivity(t, userID, "login", 2)
+		ts.waitForActivity(t, userID, "login_alert", 1)
+	})
+}
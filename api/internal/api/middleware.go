@@ -7,13 +7,13 @@ import (
 	"net"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
 	"taskai/apm"
 	"taskai/internal/auth"
+	"taskai/internal/db"
 )
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -26,8 +26,42 @@ const (
 	UserEmailKey contextKey = "user_email"
 	// AgentNameKey is the context key for AI agent name (from X-Agent-Name header)
 	AgentNameKey contextKey = "agent_name"
+	// APIKeyKey is the context key for the *db.APIKey of API key requests
+	APIKeyKey contextKey = "api_key"
+	// SessionIDKey is the context key for the login session of JWT requests;
+	// 0 for tokens issued without a session
+	SessionIDKey contextKey = "session_id"
+	// SCIMTeamIDKey is the context key for the team of SCIM requests
+	SCIMTeamIDKey contextKey = "scim_team_id"
 )
 
+// authenticateToken validates a JWT access token and checks that its user
+// still exists and its session hasn't been revoked
+func (s *Server) authenticateToken(ctx context.Context, token string) (*auth.Claims, error) {
+	claims, err := auth.ValidateToken(token, s.config.JWTSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject tokens for soft-deleted users
+	if deleted, _ := s.db.IsUserDeleted(ctx, claims.UserID); deleted {
+		return nil, fmt.Errorf("user is deleted")
+	}
+
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := s.db.IsTokenRevoked(ctx, claims.UserID, claims.SessionID, issuedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("token has been revoked")
+	}
+	return claims, nil
+}
+
 // JWTAuth middleware validates JWT tokens or API keys from Authorization header
 func (s *Server) JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -50,12 +84,14 @@ func (s *Server) JWTAuth(next http.Handler) http.Handler {
 
 		var userID int64
 		var email string
+		var apiKey *db.APIKey
+		var sessionID int64
 		var err error
 
 		switch authType {
 		case "Bearer":
 			// JWT token authentication
-			claims, jwtErr := auth.ValidateToken(credential, s.config.JWTSecret)
+			claims, jwtErr := s.authenticateToken(r.Context(), credential)
 			if jwtErr != nil {
 				s.logger.Warn("Token validation failed", zap.Error(jwtErr))
 				respondError(w, http.StatusUnauthorized, "invalid or expired token", "unauthorized")
@@ -63,21 +99,23 @@ func (s *Server) JWTAuth(next http.Handler) http.Handler {
 			}
 			userID = claims.UserID
 			email = claims.Email
-
-			// Reject tokens for soft-deleted users
-			if deleted, _ := s.db.IsUserDeleted(r.Context(), userID); deleted {
-				respondError(w, http.StatusUnauthorized, "invalid or expired token", "unauthorized")
-				return
-			}
+			sessionID = claims.SessionID
 
 		case "ApiKey":
 			// API key authentication
-			userID, email, err = s.db.GetUserByAPIKey(r.Context(), credential)
+			apiKey, email, err = s.db.AuthenticateAPIKey(r.Context(), credential, getClientIP(r))
 			if err != nil {
 				s.logger.Warn("API key validation failed", zap.Error(err))
 				respondError(w, http.StatusUnauthorized, "invalid or expired API key", "unauthorized")
 				return
 			}
+			userID = apiKey.UserID
+
+			// Enforce the key's scopes and project allow-list
+			if msg := authorizeAPIKey(apiKey, r); msg != "" {
+				respondError(w, http.StatusForbidden, msg, "insufficient_scope")
+				return
+			}
 
 		default:
 			respondError(w, http.StatusUnauthorized, "unsupported authorization type", "unauthorized")
@@ -87,6 +125,12 @@ func (s *Server) JWTAuth(next http.Handler) http.Handler {
 		// Add user info to request context
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
 		ctx = context.WithValue(ctx, UserEmailKey, email)
+		if apiKey != nil {
+			ctx = context.WithValue(ctx, APIKeyKey, apiKey)
+		}
+		if authType == "Bearer" {
+			ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+		}
 
 		// Extract optional X-Agent-Name header for AI agent attribution
 		if agentName := strings.TrimSpace(r.Header.Get("X-Agent-Name")); agentName != "" {
@@ -178,108 +222,3 @@ func GetAgentName(r *http.Request) *string {
 	}
 	return &name
 }
-
-// tokenBucket implements a simple token bucket rate limiter
-type tokenBucket struct {
-	tokens      float64
-	capacity    float64
-	refillRate  float64
-	lastRefill  time.Time
-	mu          sync.Mutex
-}
-
-func newTokenBucket(capacity, refillRate float64) *tokenBucket {
-	return &tokenBucket{
-		tokens:     capacity,
-		capacity:   capacity,
-		refillRate: refillRate,
-		lastRefill: time.Now(),
-	}
-}
-
-func (tb *tokenBucket) allow() bool {
-	tb.mu.Lock()
-	defer tb.mu.Unlock()
-
-	now := time.Now()
-	elapsed := now.Sub(tb.lastRefill).Seconds()
-
-	// Refill tokens based on elapsed time
-	tb.tokens += elapsed * tb.refillRate
-	if tb.tokens > tb.capacity {
-		tb.tokens = tb.capacity
-	}
-	tb.lastRefill = now
-
-	// Check if we have tokens available
-	if tb.tokens >= 1.0 {
-		tb.tokens -= 1.0
-		return true
-	}
-
-	return false
-}
-
-// rateLimiter manages rate limits for different endpoints
-type rateLimiter struct {
-	buckets map[string]*tokenBucket
-	mu      sync.RWMutex
-}
-
-func newRateLimiter() *rateLimiter {
-	return &rateLimiter{
-		buckets: make(map[string]*tokenBucket),
-	}
-}
-
-func (rl *rateLimiter) getBucket(key string, capacity, refillRate float64) *tokenBucket {
-	rl.mu.RLock()
-	bucket, exists := rl.buckets[key]
-	rl.mu.RUnlock()
-
-	if exists {
-		return bucket
-	}
-
-	// Create new bucket
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	// Double-check after acquiring write lock
-	bucket, exists = rl.buckets[key]
-	if exists {
-		return bucket
-	}
-
-	bucket = newTokenBucket(capacity, refillRate)
-	rl.buckets[key] = bucket
-	return bucket
-}
-
-// RateLimitMiddleware creates a rate limiting middleware
-func RateLimitMiddleware(requestsPerMinute int) func(http.Handler) http.Handler {
-	limiter := newRateLimiter()
-	capacity := float64(requestsPerMinute)
-	refillRate := capacity / 60.0 // tokens per second
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Use IP address as key
-			ip := r.RemoteAddr
-			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-				ip = strings.Split(forwarded, ",")[0]
-			}
-			if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-				ip = realIP
-			}
-
-			bucket := limiter.getBucket(ip, capacity, refillRate)
-			if !bucket.allow() {
-				respondError(w, http.StatusTooManyRequests, "rate limit exceeded", "rate_limit_exceeded")
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
-}
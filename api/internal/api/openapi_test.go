@@ -59,23 +59,60 @@ func buildTestRouter(server *Server) chi.Router {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/signup", server.HandleSignup)
 			r.Post("/login", server.HandleLogin)
+			r.Post("/refresh", server.HandleRefreshToken)
+			r.Post("/logout", server.HandleLogout)
+			r.Post("/unlock", server.HandleUnlockAccount)
+			r.Post("/2fa/verify", server.HandleVerifySecondFactor)
+			r.Post("/webauthn/login/begin", server.HandleWebAuthnLoginBegin)
+			r.Post("/webauthn/login/finish", server.HandleWebAuthnLoginFinish)
+			r.Get("/sso/discover", server.HandleSSODiscover)
+			r.Get("/sso/{teamId}/login", server.HandleSSOLogin)
+			r.Get("/sso/{teamId}/link", server.HandleSSOLinkConfirm)
+			r.Get("/sso/{teamId}/oidc/callback", server.HandleSSOOIDCCallback)
+			r.Post("/sso/{teamId}/saml/acs", server.HandleSSOSAMLACS)
+			r.Get("/sso/{teamId}/saml/metadata", server.HandleSSOSAMLMetadata)
 		})
 
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(server.SCIMAuth)
+			r.Use(server.AuditLog)
+			r.Get("/ServiceProviderConfig", server.HandleSCIMServiceProviderConfig)
+			r.Get("/ResourceTypes", server.HandleSCIMResourceTypes)
+			r.Get("/Users", server.HandleSCIMListUsers)
+			r.Post("/Users", server.HandleSCIMCreateUser)
+			r.Get("/Users/{id}", server.HandleSCIMGetUser)
+			r.Put("/Users/{id}", server.HandleSCIMReplaceUser)
+			r.Patch("/Users/{id}", server.HandleSCIMPatchUser)
+			r.Delete("/Users/{id}", server.HandleSCIMDeleteUser)
+			r.Get("/Groups", server.HandleSCIMListGroups)
+			r.Post("/Groups", server.HandleSCIMCreateGroup)
+			r.Get("/Groups/{id}", server.HandleSCIMGetGroup)
+			r.Put("/Groups/{id}", server.HandleSCIMReplaceGroup)
+			r.Patch("/Groups/{id}", server.HandleSCIMPatchGroup)
+			r.Delete("/Groups/{id}", server.HandleSCIMDeleteGroup)
+		})
+
+		r.Post("/github/webhook", server.HandleGitHubWebhook)
+
 		r.Group(func(r chi.Router) {
 			r.Use(server.JWTAuth)
+			r.Use(server.AuditLog)
 
 			r.Get("/me", server.HandleMe)
 			r.Patch("/me", server.HandleUpdateProfile)
 
 			r.Get("/projects", server.HandleListProjects)
 			r.Post("/projects", server.HandleCreateProject)
+			r.Post("/projects/import", server.HandleImportProject)
 			r.Get("/projects/{id}", server.HandleGetProject)
 			r.Patch("/projects/{id}", server.HandleUpdateProject)
 			r.Delete("/projects/{id}", server.HandleDeleteProject)
+			r.Get("/projects/{id}/export", server.HandleExportProject)
 
 			r.Get("/projects/{projectId}/tasks", server.HandleListTasks)
 			r.Post("/projects/{projectId}/tasks", server.HandleCreateTask)
 			r.Get("/projects/{projectId}/tasks/{taskNumber}", server.HandleGetTaskByNumber)
+			r.Post("/projects/{projectId}/tasks/{taskNumber}/time-entries", server.HandleCreateTimeEntryByNumber)
 			r.Patch("/tasks/{id}", server.HandleUpdateTask)
 			r.Delete("/tasks/{id}", server.HandleDeleteTask)
 
@@ -83,14 +120,40 @@ func buildTestRouter(server *Server) chi.Router {
 			r.Post("/projects/{projectId}/swim-lanes", server.HandleCreateSwimLane)
 			r.Patch("/swim-lanes/{id}", server.HandleUpdateSwimLane)
 			r.Delete("/swim-lanes/{id}", server.HandleDeleteSwimLane)
+			r.Get("/projects/{projectId}/flow-metrics", server.HandleGetFlowMetrics)
+
+			r.Get("/projects/{projectId}/custom-fields", server.HandleListCustomFields)
+			r.Post("/projects/{projectId}/custom-fields", server.HandleCreateCustomField)
+			r.Patch("/custom-fields/{id}", server.HandleUpdateCustomField)
+			r.Delete("/custom-fields/{id}", server.HandleDeleteCustomField)
 
 			r.Get("/tasks/{taskId}/comments", server.HandleListTaskComments)
 			r.Post("/tasks/{taskId}/comments", server.HandleCreateTaskComment)
+			r.Get("/tasks/{taskId}/history", server.HandleGetTaskHistory)
+			r.Get("/tasks/{taskId}/time-entries", server.HandleListTimeEntries)
+			r.Post("/tasks/{taskId}/time-entries", server.HandleCreateTimeEntry)
+			r.Post("/tasks/{taskId}/timer/start", server.HandleStartTimer)
+			r.Patch("/time-entries/{id}", server.HandleUpdateTimeEntry)
+			r.Delete("/time-entries/{id}", server.HandleDeleteTimeEntry)
+			r.Get("/me/timer", server.HandleGetTimer)
+			r.Post("/me/timer/stop", server.HandleStopTimer)
+			r.Get("/timesheets", server.HandleGetTimesheet)
+			r.Get("/tasks/{taskId}/relations", server.HandleListTaskRelations)
+			r.Post("/tasks/{taskId}/relations", server.HandleCreateTaskRelation)
+			r.Delete("/tasks/{taskId}/relations/{relationId}", server.HandleDeleteTaskRelation)
+			r.Get("/tasks/{taskId}/recurrence", server.HandleGetTaskRecurrence)
+			r.Post("/tasks/{taskId}/recurrence", server.HandleCreateTaskRecurrence)
+			r.Get("/recurrences/{id}", server.HandleGetRecurrence)
+			r.Patch("/recurrences/{id}", server.HandleUpdateRecurrence)
+			r.Delete("/recurrences/{id}", server.HandleDeleteRecurrence)
 
 			r.Get("/sprints", server.HandleListSprints)
 			r.Post("/sprints", server.HandleCreateSprint)
 			r.Patch("/sprints/{id}", server.HandleUpdateSprint)
 			r.Delete("/sprints/{id}", server.HandleDeleteSprint)
+			r.Get("/projects/{id}/sprints/velocity", server.HandleGetSprintVelocity)
+			r.Get("/sprints/{id}/analytics", server.HandleGetSprintAnalytics)
+			r.Post("/sprints/{id}/complete", server.HandleCompleteSprint)
 
 			r.Get("/tags", server.HandleListTags)
 			r.Post("/tags", server.HandleCreateTag)
@@ -101,14 +164,31 @@ func buildTestRouter(server *Server) chi.Router {
 			r.Post("/projects/{id}/members", server.HandleAddProjectMember)
 			r.Patch("/projects/{id}/members/{memberId}", server.HandleUpdateProjectMember)
 			r.Delete("/projects/{id}/members/{memberId}", server.HandleRemoveProjectMember)
+			r.Get("/projects/{id}/permissions", server.HandleGetProjectPermissions)
 			r.Get("/projects/{id}/github", server.HandleGetProjectGitHubSettings)
 			r.Patch("/projects/{id}/github", server.HandleUpdateProjectGitHubSettings)
+			r.Post("/projects/{id}/github/webhook-secret", server.HandleRotateGitHubWebhookSecret)
+
+			r.Get("/projects/{id}/webhooks", server.HandleListWebhooks)
+			r.Post("/projects/{id}/webhooks", server.HandleCreateWebhook)
+			r.Patch("/projects/{id}/webhooks/{webhookId}", server.HandleUpdateWebhook)
+			r.Delete("/projects/{id}/webhooks/{webhookId}", server.HandleDeleteWebhook)
+			r.Get("/projects/{id}/webhooks/{webhookId}/deliveries", server.HandleListWebhookDeliveries)
+			r.Post("/projects/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver", server.HandleRedeliverWebhook)
 
 			r.Post("/settings/password", server.HandleChangePassword)
 			r.Get("/settings/2fa/status", server.Handle2FAStatus)
 			r.Post("/settings/2fa/setup", server.Handle2FASetup)
 			r.Post("/settings/2fa/enable", server.Handle2FAEnable)
 			r.Post("/settings/2fa/disable", server.Handle2FADisable)
+			r.Get("/settings/sessions", server.HandleListSessions)
+			r.Delete("/settings/sessions", server.HandleRevokeOtherSessions)
+			r.Delete("/settings/sessions/{id}", server.HandleRevokeSession)
+			r.Post("/settings/webauthn/register/begin", server.HandleWebAuthnRegisterBegin)
+			r.Post("/settings/webauthn/register/finish", server.HandleWebAuthnRegisterFinish)
+			r.Get("/settings/webauthn/credentials", server.HandleListWebAuthnCredentials)
+			r.Patch("/settings/webauthn/credentials/{id}", server.HandleRenameWebAuthnCredential)
+			r.Delete("/settings/webauthn/credentials/{id}", server.HandleDeleteWebAuthnCredential)
 
 			r.Get("/api-keys", server.HandleListAPIKeys)
 			r.Post("/api-keys", server.HandleCreateAPIKey)
@@ -118,6 +198,18 @@ func buildTestRouter(server *Server) chi.Router {
 
 			r.Get("/team", server.HandleGetMyTeam)
 			r.Get("/team/members", server.HandleGetTeamMembers)
+			r.Get("/team/sso", server.HandleGetTeamSSO)
+			r.Put("/team/sso", server.HandleUpdateTeamSSO)
+			r.Delete("/team/sso", server.HandleDeleteTeamSSO)
+			r.Post("/team/sso/domains/{domain}/verify", server.HandleVerifyTeamSSODomain)
+			r.Get("/team/scim", server.HandleGetTeamSCIM)
+			r.Post("/team/scim/token", server.HandleCreateTeamSCIMToken)
+			r.Delete("/team/scim/token", server.HandleDeleteTeamSCIMToken)
+			r.Put("/team/scim/groups/{groupId}", server.HandleUpdateSCIMGroupMapping)
+			r.Get("/team/roles", server.HandleListTeamRoles)
+			r.Post("/team/roles", server.HandleCreateTeamRole)
+			r.Put("/team/roles/{roleId}", server.HandleUpdateTeamRole)
+			r.Delete("/team/roles/{roleId}", server.HandleDeleteTeamRole)
 			r.Post("/team/invite", server.HandleInviteTeamMember)
 			r.Delete("/team/members/{memberId}", server.HandleRemoveTeamMember)
 
@@ -125,9 +217,13 @@ func buildTestRouter(server *Server) chi.Router {
 			r.Post("/team/invitations/{id}/accept", server.HandleAcceptInvitation)
 			r.Post("/team/invitations/{id}/reject", server.HandleRejectInvitation)
 
+			r.Get("/audit-log", server.HandleListAuditLog)
+
 			r.Get("/admin/users", server.HandleGetUsers)
 			r.Get("/admin/users/{id}/activity", server.HandleGetUserActivity)
 			r.Patch("/admin/users/{id}/admin", server.HandleUpdateUserAdmin)
+			r.Get("/admin/users/locked", server.HandleGetLockedAccounts)
+			r.Post("/admin/users/{id}/unlock", server.HandleAdminUnlockUser)
 		})
 	})
 
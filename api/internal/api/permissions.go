@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"taskai/internal/db"
+)
+
+// Permission is something a project role allows its members to do. Every
+// member can view the project; roles decide what else they may do.
+type Permission string
+
+const (
+	PermProjectView     Permission = "project.view"
+	PermProjectEdit     Permission = "project.edit"   // name, description, swim lanes, sprints and tags
+	PermProjectDelete   Permission = "project.delete" // includes exporting the project
+	PermMembersManage   Permission = "members.manage" // members, roles and invitations
+	PermTaskCreate      Permission = "task.create"
+	PermTaskEdit        Permission = "task.edit" // fields, relations and attachments
+	PermTaskDelete      Permission = "task.delete"
+	PermTaskComment     Permission = "task.comment" // comments and reactions
+	PermWikiEdit        Permission = "wiki.edit"
+	PermWikiDelete      Permission = "wiki.delete"
+	PermWikiComment     Permission = "wiki.comment" // annotations
+	PermGitHubConfigure Permission = "github.configure"
+	PermWebhooksManage  Permission = "webhooks.manage"
+)
+
+// PermissionInfo describes a permission for role editors
+type PermissionInfo struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+// permissionCatalog lists the permissions a role can grant, in display order
+var permissionCatalog = []PermissionInfo{
+	{PermTaskCreate, "Create tasks"},
+	{PermTaskEdit, "Edit tasks, their relations and attachments"},
+	{PermTaskDelete, "Delete tasks"},
+	{PermTaskComment, "Comment and react on tasks"},
+	{PermWikiEdit, "Create and edit wiki pages"},
+	{PermWikiDelete, "Delete wiki pages"},
+	{PermWikiComment, "Annotate wiki pages"},
+	{PermProjectEdit, "Edit the project, its swim lanes, sprints and tags"},
+	{PermMembersManage, "Add and remove members and change their roles"},
+	{PermGitHubConfigure, "Connect GitHub and import or push issues"},
+	{PermWebhooksManage, "Manage webhooks"},
+	{PermProjectDelete, "Export and delete the project"},
+}
+
+func validPermission(p Permission) bool {
+	return slices.ContainsFunc(permissionCatalog, func(info PermissionInfo) bool { return info.Name == p })
+}
+
+// Built-in project roles
+const (
+	RoleViewer = "viewer"
+	RoleMember = "member"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+	// roleLegacyAdmin is an older role some members still have. It can no
+	// longer be granted.
+	roleLegacyAdmin = "admin"
+)
+
+var (
+	memberPermissions = []Permission{PermTaskCreate, PermTaskEdit, PermTaskComment, PermWikiEdit, PermWikiComment}
+	editorPermissions = append(slices.Clone(memberPermissions), PermTaskDelete, PermWikiDelete, PermProjectEdit)
+	adminPermissions  = append(slices.Clone(editorPermissions), PermMembersManage, PermGitHubConfigure, PermWebhooksManage)
+)
+
+// builtInRoles maps the built-in roles onto their permissions
+var builtInRoles = map[string][]Permission{
+	RoleViewer:      {},
+	RoleMember:      memberPermissions,
+	RoleEditor:      editorPermissions,
+	roleLegacyAdmin: adminPermissions,
+	RoleOwner:       append(slices.Clone(adminPermissions), PermProjectDelete),
+}
+
+// assignableBuiltInRoles are the built-in roles members can be given, from
+// least to most access
+var assignableBuiltInRoles = []string{RoleViewer, RoleMember, RoleEditor, RoleOwner}
+
+func isBuiltInRole(name string) bool {
+	_, ok := builtInRoles[strings.ToLower(name)]
+	return ok
+}
+
+// projectRolePermissions returns the permissions a role grants in a project.
+// Roles that no longer exist grant nothing beyond viewing.
+func (s *Server) projectRolePermissions(ctx context.Context, projectID int64, role string) ([]Permission, error) {
+	if perms, ok := builtInRoles[role]; ok {
+		return perms, nil
+	}
+	custom, err := s.db.GetProjectTeamRole(ctx, projectID, role)
+	if errors.Is(err, db.ErrTeamRoleNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	perms := make([]Permission, len(custom.Permissions))
+	for i, p := range custom.Permissions {
+		perms[i] = Permission(p)
+	}
+	return perms, nil
+}
+
+// authorizeProject reports whether a user may do something in a project.
+// It is the one place project access is decided: the user must be a member
+// whose role grants the permission, and a request made with a
+// project-restricted API key must be for one of the key's projects.
+func (s *Server) authorizeProject(ctx context.Context, userID, projectID int64, perm Permission) (bool, error) {
+	role, err := s.projectMemberRole(ctx, userID, projectID)
+	if err == nil && role == "" {
+		role, err = s.projectOwnerRole(ctx, userID, projectID)
+	}
+	if err != nil || role == "" {
+		return false, err
+	}
+	if perm == PermProjectView {
+		return true, nil
+	}
+	perms, err := s.projectRolePermissions(ctx, projectID, role)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(perms, perm), nil
+}
+
+// projectOwnerRole returns the owner role when the user is the project's
+// owner_id. Older projects have no project_members row for their owner.
+func (s *Server) projectOwnerRole(ctx context.Context, userID, projectID int64) (string, error) {
+	if !apiKeyAllowsProject(ctx, projectID) {
+		return "", nil
+	}
+	var ownerID int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT owner_id FROM projects WHERE id = ?`), projectID).Scan(&ownerID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil || ownerID != userID {
+		return "", err
+	}
+	return RoleOwner, nil
+}
+
+// validateProjectRole checks that a role can be given to members of a
+// project: a built-in role or one of the custom roles of the project's team.
+func (s *Server) validateProjectRole(ctx context.Context, projectID int64, role string) (bool, error) {
+	if slices.Contains(assignableBuiltInRoles, role) {
+		return true, nil
+	}
+	if role == "" || isBuiltInRole(role) {
+		return false, nil
+	}
+	_, err := s.db.GetProjectTeamRole(ctx, projectID, role)
+	if errors.Is(err, db.ErrTeamRoleNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,1267 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/swimlane"
+	"taskai/ent/task"
+	"taskai/ent/taskassignee"
+	"taskai/ent/taskattachment"
+	"taskai/ent/taskcomment"
+	"taskai/ent/tasktag"
+	"taskai/ent/user"
+	"taskai/ent/wikipage"
+	"taskai/ent/wikipageversion"
+	"taskai/internal/db"
+)
+
+// A project archive is a zip file:
+//
+//	manifest.json                 format, version and counts
+//	project.json                  name, swim lanes, sprints, tags, custom fields
+//	tasks.json                    tasks with comments, assignees, tags, relations, custom field values, attachment metadata
+//	graph.json                    knowledge graph nodes and edges
+//	wiki/pages.json               page hierarchy and version metadata
+//	wiki/pages/<path>.md          current page content
+//	wiki/versions/<path>/<n>.md   content of each saved version
+//
+// IDs in the JSON files are the source instance's; import remaps them. Users
+// are referenced by email.
+const (
+	projectArchiveFormat  = "taskai-project"
+	projectArchiveVersion = 1
+	maxProjectArchiveSize = 100 << 20 // 100 MB
+
+	// Limits on what an uploaded archive may expand to
+	maxProjectArchiveEntries      = 10000
+	maxProjectArchiveUncompressed = 500 << 20 // 500 MB
+)
+
+// ProjectArchiveManifest describes a project archive
+type ProjectArchiveManifest struct {
+	Format      string         `json:"format"`
+	Version     int            `json:"version"`
+	ExportedAt  time.Time      `json:"exported_at"`
+	ProjectID   int64          `json:"project_id"` // ID in the source instance
+	ProjectName string         `json:"project_name"`
+	Counts      map[string]int `json:"counts"`
+}
+
+type archiveProject struct {
+	Name        string            `json:"name"`
+	Description *string           `json:"description,omitempty"`
+	SwimLanes   []archiveSwimLane `json:"swim_lanes"`
+	Sprints     []archiveSprint   `json:"sprints"`
+	Tags        []archiveTag      `json:"tags"`
+
+	CustomFields []archiveCustomField `json:"custom_fields,omitempty"`
+}
+
+type archiveSwimLane struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Color          string `json:"color"`
+	Position       int    `json:"position"`
+	StatusCategory string `json:"status_category"`
+	WIPLimit       *int   `json:"wip_limit,omitempty"`
+	WIPEnforcement string `json:"wip_enforcement,omitempty"`
+}
+
+type archiveSprint struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Goal      *string `json:"goal,omitempty"`
+	StartDate *string `json:"start_date,omitempty"` // YYYY-MM-DD
+	EndDate   *string `json:"end_date,omitempty"`
+	Status    string  `json:"status"`
+}
+
+type archiveTag struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type archiveCustomField struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	FieldType string   `json:"field_type"`
+	Options   []string `json:"options,omitempty"`
+	Required  bool     `json:"required"`
+	Position  int      `json:"position"`
+}
+
+type archiveTask struct {
+	ID             int64               `json:"id"`
+	TaskNumber     int64               `json:"task_number"`
+	Title          string              `json:"title"`
+	Description    *string             `json:"description,omitempty"`
+	Status         string              `json:"status"`
+	Priority       string              `json:"priority"`
+	SwimLaneID     *int64              `json:"swim_lane_id,omitempty"`
+	SprintID       *int64              `json:"sprint_id,omitempty"`
+	Assignees      []string            `json:"assignees,omitempty"` // emails
+	TagIDs         []int64             `json:"tag_ids,omitempty"`
+	EstimatedHours *float64            `json:"estimated_hours,omitempty"`
+	ActualHours    *float64            `json:"actual_hours,omitempty"`
+	StartDate      *time.Time          `json:"start_date,omitempty"`
+	DueDate        *time.Time          `json:"due_date,omitempty"`
+	AgentName      *string             `json:"agent_name,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	Comments       []archiveComment    `json:"comments,omitempty"`
+	Attachments    []archiveAttachment `json:"attachments,omitempty"`
+	Relations      []archiveRelation   `json:"relations,omitempty"` // outgoing only
+
+	CustomFields map[int64][]string `json:"custom_fields,omitempty"` // by field ID; user fields hold emails
+}
+
+type archiveRelation struct {
+	RelationType string `json:"relation_type"` // blocks, duplicates, relates_to
+	TaskID       int64  `json:"task_id"`       // target task
+}
+
+type archiveComment struct {
+	Author    string    `json:"author"` // email
+	Comment   string    `json:"comment"`
+	AgentName *string   `json:"agent_name,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type archiveAttachment struct {
+	UploadedBy         string    `json:"uploaded_by"` // email
+	Filename           string    `json:"filename"`
+	AltName            string    `json:"alt_name"`
+	FileType           string    `json:"file_type"`
+	ContentType        string    `json:"content_type"`
+	FileSize           int       `json:"file_size"`
+	CloudinaryURL      string    `json:"cloudinary_url"`
+	CloudinaryPublicID string    `json:"cloudinary_public_id"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type archiveWikiPage struct {
+	ID        int64                `json:"id"`
+	ParentID  *int64               `json:"parent_id,omitempty"`
+	Title     string               `json:"title"`
+	Slug      string               `json:"slug"`
+	Position  int                  `json:"position"`
+	File      string               `json:"file"`
+	CreatedBy string               `json:"created_by"` // email
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+	Versions  []archiveWikiVersion `json:"versions,omitempty"`
+}
+
+type archiveWikiVersion struct {
+	VersionNumber int       `json:"version_number"`
+	File          string    `json:"file"`
+	CreatedBy     string    `json:"created_by"` // email
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type archiveGraph struct {
+	Nodes []archiveGraphNode `json:"nodes"`
+	Edges []archiveGraphEdge `json:"edges"`
+}
+
+type archiveGraphNode struct {
+	ID         int64  `json:"id"`
+	EntityType string `json:"entity_type"`
+	EntityID   int64  `json:"entity_id"`
+	Title      string `json:"title"`
+}
+
+type archiveGraphEdge struct {
+	SourceNodeID int64  `json:"source_node_id"`
+	TargetNodeID int64  `json:"target_node_id"`
+	RelationType string `json:"relation_type"`
+}
+
+// projectArchive is the decoded content of an archive
+type projectArchive struct {
+	Manifest ProjectArchiveManifest
+	Project  archiveProject
+	Tasks    []archiveTask
+	Wiki     []archiveWikiPage
+	Graph    archiveGraph
+	Files    map[string]string // markdown files by archive path
+}
+
+// ImportProjectResponse is returned after importing an archive
+type ImportProjectResponse struct {
+	Project Project        `json:"project"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// HandleExportProject downloads a project as a zip archive (owners only).
+// Route: GET /api/projects/{id}/export
+func (s *Server) HandleExportProject(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+
+	allowed, err := s.authorizeProject(ctx, userID, projectID, PermProjectDelete)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !allowed {
+		respondError(w, http.StatusForbidden, "only project owners can export a project", "forbidden")
+		return
+	}
+
+	archive, err := s.buildProjectArchive(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to build project archive", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to export project", "internal_error")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := archive.write(&buf); err != nil {
+		s.logger.Error("Failed to write project archive", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to export project", "internal_error")
+		return
+	}
+
+	s.logger.Info("Exported project",
+		zap.Int64("project_id", projectID),
+		zap.Int64("user_id", userID),
+		zap.Int("tasks", len(archive.Tasks)),
+		zap.Int("wiki_pages", len(archive.Wiki)),
+	)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=taskai-project-%d-%s.zip",
+		projectID, time.Now().Format("20060102-150405")))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// HandleImportProject creates a new project from an archive sent as the
+// request body. The optional name query parameter overrides the project name.
+// Route: POST /api/projects/import
+func (s *Server) HandleImportProject(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 120*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	teamID, err := s.getUserTeamID(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get user team", "internal_error")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxProjectArchiveSize))
+	if err != nil {
+		respondError(w, http.StatusRequestEntityTooLarge, "archive is too large", "invalid_input")
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "request body is not a zip archive", "invalid_input")
+		return
+	}
+	archive, err := readProjectArchive(zr)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		archive.Project.Name = name
+	}
+	if archive.Project.Name == "" || len(archive.Project.Name) > 255 {
+		respondError(w, http.StatusBadRequest, "project name is required (max 255 characters)", "invalid_input")
+		return
+	}
+
+	projectID, counts, err := s.importProjectArchive(ctx, userID, teamID, archive)
+	if err != nil {
+		s.logger.Error("Failed to import project archive", zap.Int64("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to import project", "internal_error")
+		return
+	}
+
+	p, err := s.db.Client.Project.Get(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to load imported project", "internal_error")
+		return
+	}
+
+	s.logger.Info("Imported project",
+		zap.Int64("project_id", projectID),
+		zap.Int64("source_project_id", archive.Manifest.ProjectID),
+		zap.Int64("user_id", userID),
+	)
+
+	respondJSON(w, http.StatusCreated, ImportProjectResponse{
+		Project: Project{
+			ID:          p.ID,
+			OwnerID:     p.OwnerID,
+			Name:        p.Name,
+			Description: p.Description,
+			CreatedAt:   p.CreatedAt,
+			UpdatedAt:   p.UpdatedAt,
+		},
+		Counts: counts,
+	})
+}
+
+// buildProjectArchive collects everything belonging to a project.
+func (s *Server) buildProjectArchive(ctx context.Context, projectID int64) (*projectArchive, error) {
+	p, err := s.db.Client.Project.Get(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	archive := &projectArchive{
+		Project: archiveProject{Name: p.Name, Description: p.Description},
+		Files:   map[string]string{},
+	}
+
+	emails := map[int64]string{}
+	var userIDs []int64
+	wantUser := func(id int64) {
+		if _, ok := emails[id]; !ok {
+			emails[id] = ""
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	lanes, err := s.db.Client.SwimLane.Query().
+		Where(swimlane.ProjectID(projectID)).
+		Order(ent.Asc(swimlane.FieldPosition)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	limits, err := s.db.GetWIPLimits(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lanes {
+		archive.Project.SwimLanes = append(archive.Project.SwimLanes, archiveSwimLane{
+			ID: l.ID, Name: l.Name, Color: l.Color, Position: l.Position, StatusCategory: l.StatusCategory,
+			WIPLimit: limits[l.ID].Limit, WIPEnforcement: limits[l.ID].Enforcement,
+		})
+	}
+
+	if archive.Project.Sprints, err = s.archiveSprints(ctx, projectID); err != nil {
+		return nil, err
+	}
+	if archive.Project.Tags, err = s.archiveTags(ctx, projectID); err != nil {
+		return nil, err
+	}
+	customFields, err := s.db.ListCustomFields(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	userFields := map[int64]bool{}
+	for _, f := range customFields {
+		archive.Project.CustomFields = append(archive.Project.CustomFields, archiveCustomField{
+			ID: f.ID, Name: f.Name, FieldType: f.FieldType, Options: f.Options, Required: f.Required, Position: f.Position,
+		})
+		userFields[f.ID] = f.FieldType == db.FieldUser
+	}
+
+	tasks, err := s.db.Client.Task.Query().
+		Where(task.ProjectID(projectID)).
+		Order(ent.Asc(task.FieldTaskNumber), ent.Asc(task.FieldID)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	taskIDs := make([]int64, len(tasks))
+	taskIndex := make(map[int64]int, len(tasks))
+	for i, t := range tasks {
+		taskIDs[i] = t.ID
+		taskIndex[t.ID] = i
+		at := archiveTask{
+			ID:             t.ID,
+			Title:          t.Title,
+			Description:    t.Description,
+			Status:         t.Status,
+			Priority:       t.Priority,
+			SwimLaneID:     t.SwimLaneID,
+			SprintID:       t.SprintID,
+			EstimatedHours: t.EstimatedHours,
+			ActualHours:    t.ActualHours,
+			StartDate:      t.StartDate,
+			DueDate:        t.DueDate,
+			AgentName:      t.AgentName,
+			CreatedAt:      t.CreatedAt,
+			UpdatedAt:      t.UpdatedAt,
+		}
+		if t.TaskNumber != nil {
+			at.TaskNumber = int64(*t.TaskNumber)
+		}
+		archive.Tasks = append(archive.Tasks, at)
+	}
+
+	// Assignees come from task_assignees plus the legacy single assignee column
+	assignees := map[int64][]int64{}
+	for _, t := range tasks {
+		if t.AssigneeID != nil {
+			assignees[t.ID] = append(assignees[t.ID], *t.AssigneeID)
+		}
+	}
+	taskAssignees, err := s.db.Client.TaskAssignee.Query().
+		Where(taskassignee.TaskIDIn(taskIDs...)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range taskAssignees {
+		if !containsInt64(assignees[a.TaskID], a.UserID) {
+			assignees[a.TaskID] = append(assignees[a.TaskID], a.UserID)
+		}
+	}
+	for _, ids := range assignees {
+		for _, id := range ids {
+			wantUser(id)
+		}
+	}
+
+	taskTags, err := s.db.Client.TaskTag.Query().
+		Where(tasktag.TaskIDIn(taskIDs...)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tt := range taskTags {
+		at := &archive.Tasks[taskIndex[tt.TaskID]]
+		at.TagIDs = append(at.TagIDs, tt.TagID)
+	}
+
+	customValues, err := s.db.GetTaskCustomFieldValues(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range customValues {
+		for fieldID, values := range fields {
+			if !userFields[fieldID] {
+				continue
+			}
+			for _, v := range values {
+				if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+					wantUser(id)
+				}
+			}
+		}
+	}
+
+	relations, err := s.archiveRelations(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range relations {
+		if i, ok := taskIndex[rel.source]; ok {
+			archive.Tasks[i].Relations = append(archive.Tasks[i].Relations, rel.archiveRelation)
+		}
+	}
+
+	comments, err := s.db.Client.TaskComment.Query().
+		Where(taskcomment.TaskIDIn(taskIDs...)).
+		Order(ent.Asc(taskcomment.FieldCreatedAt), ent.Asc(taskcomment.FieldID)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		wantUser(c.UserID)
+	}
+
+	attachments, err := s.db.Client.TaskAttachment.Query().
+		Where(taskattachment.ProjectID(projectID)).
+		Order(ent.Asc(taskattachment.FieldID)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		wantUser(a.UserID)
+	}
+
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := s.db.Client.WikiPage.Query().
+		Where(wikipage.ProjectID(projectID)).
+		Order(ent.Asc(wikipage.FieldID)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pageIDs := make([]int64, len(pages))
+	for i, pg := range pages {
+		pageIDs[i] = pg.ID
+		wantUser(pg.CreatedBy)
+	}
+	versions, err := s.db.Client.WikiPageVersion.Query().
+		Where(wikipageversion.WikiPageIDIn(pageIDs...)).
+		Order(ent.Asc(wikipageversion.FieldVersionNumber)).
+		All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		wantUser(v.CreatedBy)
+	}
+
+	if len(userIDs) > 0 {
+		users, err := s.db.Client.User.Query().Where(user.IDIn(userIDs...)).All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			emails[u.ID] = u.Email
+		}
+	}
+
+	for taskID, ids := range assignees {
+		at := &archive.Tasks[taskIndex[taskID]]
+		for _, id := range ids {
+			at.Assignees = append(at.Assignees, emails[id])
+		}
+	}
+	for taskID, fields := range customValues {
+		at := &archive.Tasks[taskIndex[taskID]]
+		at.CustomFields = map[int64][]string{}
+		for fieldID, values := range fields {
+			if userFields[fieldID] {
+				var users []string
+				for _, v := range values {
+					if id, err := strconv.ParseInt(v, 10, 64); err == nil && emails[id] != "" {
+						users = append(users, emails[id])
+					}
+				}
+				values = users
+			}
+			if len(values) > 0 {
+				at.CustomFields[fieldID] = values
+			}
+		}
+	}
+	for _, c := range comments {
+		at := &archive.Tasks[taskIndex[c.TaskID]]
+		at.Comments = append(at.Comments, archiveComment{
+			Author: emails[c.UserID], Comment: c.Comment, AgentName: c.AgentName,
+			CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt,
+		})
+	}
+	for _, a := range attachments {
+		i, ok := taskIndex[a.TaskID]
+		if !ok {
+			continue
+		}
+		archive.Tasks[i].Attachments = append(archive.Tasks[i].Attachments, archiveAttachment{
+			UploadedBy: emails[a.UserID], Filename: a.Filename, AltName: a.AltName,
+			FileType: a.FileType, ContentType: a.ContentType, FileSize: a.FileSize,
+			CloudinaryURL: a.CloudinaryURL, CloudinaryPublicID: a.CloudinaryPublicID, CreatedAt: a.CreatedAt,
+		})
+	}
+
+	pageIndex := make(map[int64]int, len(pages))
+	for _, pg := range pages {
+		node := idx[pg.ID]
+		path := idx.path(pg.ID)
+		ap := archiveWikiPage{
+			ID:        pg.ID,
+			Title:     pg.Title,
+			Slug:      pg.Slug,
+			File:      "wiki/pages/" + path + ".md",
+			CreatedBy: emails[pg.CreatedBy],
+			CreatedAt: pg.CreatedAt,
+			UpdatedAt: pg.UpdatedAt,
+		}
+		if node != nil {
+			ap.ParentID = node.parentID
+			ap.Position = node.position
+		}
+		archive.Files[ap.File] = pg.Content
+		pageIndex[pg.ID] = len(archive.Wiki)
+		archive.Wiki = append(archive.Wiki, ap)
+	}
+	for _, v := range versions {
+		ap := &archive.Wiki[pageIndex[v.WikiPageID]]
+		file := fmt.Sprintf("wiki/versions/%s/%d.md", idx.path(v.WikiPageID), v.VersionNumber)
+		archive.Files[file] = v.Content
+		ap.Versions = append(ap.Versions, archiveWikiVersion{
+			VersionNumber: v.VersionNumber, File: file, CreatedBy: emails[v.CreatedBy], CreatedAt: v.CreatedAt,
+		})
+	}
+
+	if archive.Graph, err = s.archiveGraph(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	commentCount := 0
+	for _, t := range archive.Tasks {
+		commentCount += len(t.Comments)
+	}
+	archive.Manifest = ProjectArchiveManifest{
+		Format:      projectArchiveFormat,
+		Version:     projectArchiveVersion,
+		ExportedAt:  time.Now().UTC(),
+		ProjectID:   projectID,
+		ProjectName: p.Name,
+		Counts: map[string]int{
+			"swim_lanes":    len(archive.Project.SwimLanes),
+			"sprints":       len(archive.Project.Sprints),
+			"tags":          len(archive.Project.Tags),
+			"custom_fields": len(archive.Project.CustomFields),
+			"tasks":         len(archive.Tasks),
+			"relations":     len(relations),
+			"comments":      commentCount,
+			"attachments":   len(attachments),
+			"wiki_pages":    len(archive.Wiki),
+			"wiki_versions": len(versions),
+			"graph_nodes":   len(archive.Graph.Nodes),
+			"graph_edges":   len(archive.Graph.Edges),
+		},
+	}
+	return archive, nil
+}
+
+type archivedRelation struct {
+	archiveRelation
+	source int64
+}
+
+func (s *Server) archiveRelations(ctx context.Context, projectID int64) ([]archivedRelation, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT source_task_id, target_task_id, relation_type FROM task_relations WHERE project_id = ? ORDER BY id`), projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []archivedRelation
+	for rows.Next() {
+		var rel archivedRelation
+		if err := rows.Scan(&rel.source, &rel.TaskID, &rel.RelationType); err != nil {
+			return nil, err
+		}
+		relations = append(relations, rel)
+	}
+	return relations, rows.Err()
+}
+
+func (s *Server) archiveSprints(ctx context.Context, projectID int64) ([]archiveSprint, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT id, name, goal, start_date, end_date, status FROM sprints WHERE project_id = ? ORDER BY id`), projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sprints []archiveSprint
+	for rows.Next() {
+		var sp archiveSprint
+		var startDate, endDate *time.Time
+		if err := rows.Scan(&sp.ID, &sp.Name, &sp.Goal, &startDate, &endDate, &sp.Status); err != nil {
+			return nil, err
+		}
+		if startDate != nil {
+			d := startDate.Format("2006-01-02")
+			sp.StartDate = &d
+		}
+		if endDate != nil {
+			d := endDate.Format("2006-01-02")
+			sp.EndDate = &d
+		}
+		sprints = append(sprints, sp)
+	}
+	return sprints, rows.Err()
+}
+
+func (s *Server) archiveTags(ctx context.Context, projectID int64) ([]archiveTag, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT id, name, color FROM tags WHERE project_id = ? ORDER BY id`), projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []archiveTag
+	for rows.Next() {
+		var t archiveTag
+		if err := rows.Scan(&t.ID, &t.Name, &t.Color); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// archiveGraph returns the project's graph nodes and the edges between them.
+// Edges to other projects cannot be carried over and are left out.
+func (s *Server) archiveGraph(ctx context.Context, projectID int64) (archiveGraph, error) {
+	graph := archiveGraph{Nodes: []archiveGraphNode{}, Edges: []archiveGraphEdge{}}
+
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT id, entity_type, entity_id, title FROM graph_nodes WHERE project_id = ? ORDER BY id`), projectID)
+	if err != nil {
+		return graph, err
+	}
+	for rows.Next() {
+		var n archiveGraphNode
+		if err := rows.Scan(&n.ID, &n.EntityType, &n.EntityID, &n.Title); err != nil {
+			rows.Close()
+			return graph, err
+		}
+		graph.Nodes = append(graph.Nodes, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return graph, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT e.source_node_id, e.target_node_id, e.relation_type
+		FROM graph_edges e
+		JOIN graph_nodes src ON src.id = e.source_node_id
+		JOIN graph_nodes dst ON dst.id = e.target_node_id
+		WHERE src.project_id = ? AND dst.project_id = src.project_id
+		ORDER BY e.id`), projectID)
+	if err != nil {
+		return graph, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e archiveGraphEdge
+		if err := rows.Scan(&e.SourceNodeID, &e.TargetNodeID, &e.RelationType); err != nil {
+			return graph, err
+		}
+		graph.Edges = append(graph.Edges, e)
+	}
+	return graph, rows.Err()
+}
+
+// write encodes the archive as a zip file.
+func (a *projectArchive) write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	writeJSON := func(name string, v interface{}) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	if err := writeJSON("manifest.json", a.Manifest); err != nil {
+		return err
+	}
+	if err := writeJSON("project.json", a.Project); err != nil {
+		return err
+	}
+	if err := writeJSON("tasks.json", a.Tasks); err != nil {
+		return err
+	}
+	if err := writeJSON("graph.json", a.Graph); err != nil {
+		return err
+	}
+	if err := writeJSON("wiki/pages.json", a.Wiki); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(a.Files))
+	for name := range a.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, a.Files[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// readProjectArchive decodes and validates an archive.
+func readProjectArchive(zr *zip.Reader) (*projectArchive, error) {
+	archive := &projectArchive{Files: map[string]string{}}
+	found := map[string]bool{}
+
+	if len(zr.File) > maxProjectArchiveEntries {
+		return nil, fmt.Errorf("archive has more than %d entries", maxProjectArchiveEntries)
+	}
+	var total int64
+	for _, f := range zr.File {
+		var target interface{}
+		switch f.Name {
+		case "manifest.json":
+			target = &archive.Manifest
+		case "project.json":
+			target = &archive.Project
+		case "tasks.json":
+			target = &archive.Tasks
+		case "graph.json":
+			target = &archive.Graph
+		case "wiki/pages.json":
+			target = &archive.Wiki
+		default:
+			if !strings.HasSuffix(f.Name, ".md") {
+				continue
+			}
+		}
+
+		// Declared sizes can lie, so the bytes actually read are capped too
+		if f.UncompressedSize64 > maxProjectArchiveUncompressed {
+			return nil, fmt.Errorf("%s is too large", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive", f.Name)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxProjectArchiveUncompressed-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive", f.Name)
+		}
+		total += int64(len(data))
+		if total > maxProjectArchiveUncompressed {
+			return nil, fmt.Errorf("archive expands to more than %d MB", maxProjectArchiveUncompressed>>20)
+		}
+
+		if target == nil {
+			archive.Files[f.Name] = string(data)
+			continue
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return nil, fmt.Errorf("invalid %s in archive", f.Name)
+		}
+		found[f.Name] = true
+	}
+
+	if !found["manifest.json"] || archive.Manifest.Format != projectArchiveFormat {
+		return nil, fmt.Errorf("not a TaskAI project archive")
+	}
+	if archive.Manifest.Version > projectArchiveVersion {
+		return nil, fmt.Errorf("archive version %d is newer than supported version %d",
+			archive.Manifest.Version, projectArchiveVersion)
+	}
+	if !found["project.json"] {
+		return nil, fmt.Errorf("archive is missing project.json")
+	}
+	return archive, nil
+}
+
+// importProjectArchive creates a new project owned by userID from an archive
+// in a single transaction. Every ID is remapped, tasks are renumbered from 1
+// in their original order, and [[task:N]] / [[wiki:N]] links to archived
+// entities are rewritten to the new IDs. Pages, comments and attachments
+// are attributed to the importer, since archive emails are not proof of
+// authorship. Assignees and user field values are matched by email among
+// the active members of the importer's team; anyone else is dropped.
+func (s *Server) importProjectArchive(ctx context.Context, userID, teamID int64, archive *projectArchive) (int64, map[string]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	insert := func(query string, args ...interface{}) (int64, error) {
+		var id int64
+		err := tx.QueryRowContext(ctx, s.db.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	exec := func(query string, args ...interface{}) error {
+		_, err := tx.ExecContext(ctx, s.db.Rebind(query), args...)
+		return err
+	}
+
+	userIDs := map[string]*int64{}
+	lookupUser := func(email string) (*int64, error) {
+		if id, ok := userIDs[email]; ok {
+			return id, nil
+		}
+		var id *int64
+		if email != "" {
+			var found int64
+			err := tx.QueryRowContext(ctx, s.db.Rebind(`
+				SELECT u.id FROM users u
+				JOIN team_members tm ON tm.user_id = u.id
+				WHERE u.email = ? AND tm.team_id = ? AND tm.status = 'active'`), email, teamID).Scan(&found)
+			if err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+			if err == nil {
+				id = &found
+			}
+		}
+		userIDs[email] = id
+		return id, nil
+	}
+
+	counts := map[string]int{}
+
+	projectID, err := insert(`INSERT INTO projects (owner_id, team_id, name, description) VALUES (?, ?, ?, ?)`,
+		userID, teamID, archive.Project.Name, archive.Project.Description)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create project: %w", err)
+	}
+	if err := exec(`INSERT INTO project_members (project_id, user_id, role, granted_by) VALUES (?, ?, 'owner', ?)`,
+		projectID, userID, userID); err != nil {
+		return 0, nil, fmt.Errorf("add owner: %w", err)
+	}
+
+	laneIDs := map[int64]int64{}
+	for _, l := range archive.Project.SwimLanes {
+		wip := db.WIPLimit{Limit: l.WIPLimit, Enforcement: l.WIPEnforcement}
+		if wip.Limit != nil && *wip.Limit <= 0 {
+			wip.Limit = nil
+		}
+		if wip.Enforcement != db.WIPHard {
+			wip.Enforcement = db.WIPSoft
+		}
+		id, err := insert(`INSERT INTO swim_lanes (project_id, name, color, position, status_category, wip_limit, wip_enforcement)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			projectID, l.Name, l.Color, l.Position, l.StatusCategory, wip.Limit, wip.Enforcement)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create swim lane: %w", err)
+		}
+		laneIDs[l.ID] = id
+	}
+	counts["swim_lanes"] = len(laneIDs)
+
+	sprintIDs := map[int64]int64{}
+	for _, sp := range archive.Project.Sprints {
+		id, err := insert(`INSERT INTO sprints (user_id, team_id, project_id, name, goal, start_date, end_date, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, teamID, projectID, sp.Name, sp.Goal, sp.StartDate, sp.EndDate, sp.Status)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create sprint: %w", err)
+		}
+		sprintIDs[sp.ID] = id
+	}
+	counts["sprints"] = len(sprintIDs)
+
+	tagIDs := map[int64]int64{}
+	for _, t := range archive.Project.Tags {
+		id, err := insert(`INSERT INTO tags (user_id, team_id, project_id, name, color) VALUES (?, ?, ?, ?, ?)`,
+			userID, teamID, projectID, t.Name, t.Color)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create tag: %w", err)
+		}
+		tagIDs[t.ID] = id
+	}
+	counts["tags"] = len(tagIDs)
+
+	fieldIDs := map[int64]int64{}
+	fieldTypes := map[int64]string{}
+	for _, f := range archive.Project.CustomFields {
+		options, err := json.Marshal(append([]string{}, f.Options...))
+		if err != nil {
+			return 0, nil, err
+		}
+		id, err := insert(`INSERT INTO custom_fields (project_id, name, field_type, options, required, position)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			projectID, f.Name, f.FieldType, string(options), f.Required, f.Position)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create custom field %q: %w", f.Name, err)
+		}
+		fieldIDs[f.ID] = id
+		fieldTypes[f.ID] = f.FieldType
+	}
+	counts["custom_fields"] = len(fieldIDs)
+
+	// Wiki pages go in parents first so parent_id can be remapped
+	pages := append([]archiveWikiPage(nil), archive.Wiki...)
+	depth := archiveWikiDepths(pages)
+	sort.SliceStable(pages, func(i, j int) bool {
+		if depth[pages[i].ID] != depth[pages[j].ID] {
+			return depth[pages[i].ID] < depth[pages[j].ID]
+		}
+		return pages[i].Position < pages[j].Position
+	})
+	pageIDs := map[int64]int64{}
+	for _, pg := range pages {
+		var parentID *int64
+		if pg.ParentID != nil {
+			if id, ok := pageIDs[*pg.ParentID]; ok {
+				parentID = &id
+			}
+		}
+		id, err := insert(`INSERT INTO wiki_pages (project_id, title, slug, created_by, content, parent_id, position, created_at, updated_at)
+			VALUES (?, ?, ?, ?, '', ?, ?, ?, ?)`,
+			projectID, pg.Title, pg.Slug, userID, parentID, pg.Position, pg.CreatedAt, pg.UpdatedAt)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create wiki page %q: %w", pg.Title, err)
+		}
+		pageIDs[pg.ID] = id
+	}
+	counts["wiki_pages"] = len(pageIDs)
+
+	// Tasks are renumbered in their original order
+	tasks := append([]archiveTask(nil), archive.Tasks...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].TaskNumber != tasks[j].TaskNumber {
+			return tasks[i].TaskNumber < tasks[j].TaskNumber
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	taskIDs := map[int64]int64{}
+	taskNumbers := map[int64]int64{}
+	for i, t := range tasks {
+		number := int64(i + 1)
+		id, err := insert(`INSERT INTO tasks (project_id, task_number, title, status, priority, swim_lane_id, sprint_id,
+				estimated_hours, actual_hours, start_date, due_date, agent_name, created_by, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			projectID, number, t.Title, t.Status, t.Priority,
+			remapArchiveID(laneIDs, t.SwimLaneID), remapArchiveID(sprintIDs, t.SprintID),
+			t.EstimatedHours, t.ActualHours, t.StartDate, t.DueDate, t.AgentName, userID, t.CreatedAt, t.UpdatedAt)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create task %q: %w", t.Title, err)
+		}
+		taskIDs[t.ID] = id
+		taskNumbers[t.ID] = number
+	}
+	counts["tasks"] = len(taskIDs)
+
+	rewrite := func(content string) string {
+		return rewriteGraphLinks(content, taskIDs, pageIDs)
+	}
+
+	// With every ID known, write content, links and task children
+	for _, t := range tasks {
+		newID := taskIDs[t.ID]
+		if t.Description != nil {
+			if err := exec(`UPDATE tasks SET description = ? WHERE id = ?`, rewrite(*t.Description), newID); err != nil {
+				return 0, nil, err
+			}
+		}
+
+		assigned := false
+		for _, email := range t.Assignees {
+			id, err := lookupUser(email)
+			if err != nil {
+				return 0, nil, err
+			}
+			if id == nil {
+				continue
+			}
+			if err := exec(`INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?)`, newID, *id); err != nil {
+				return 0, nil, fmt.Errorf("assign task: %w", err)
+			}
+			if !assigned {
+				if err := exec(`UPDATE tasks SET assignee_id = ? WHERE id = ?`, *id, newID); err != nil {
+					return 0, nil, err
+				}
+				assigned = true
+			}
+		}
+
+		for _, tagID := range t.TagIDs {
+			if id, ok := tagIDs[tagID]; ok {
+				if err := exec(`INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?)`, newID, id); err != nil {
+					return 0, nil, fmt.Errorf("tag task: %w", err)
+				}
+			}
+		}
+
+		for fieldID, values := range t.CustomFields {
+			id, ok := fieldIDs[fieldID]
+			if !ok {
+				continue
+			}
+			for _, v := range values {
+				if fieldTypes[fieldID] == db.FieldUser {
+					user, err := lookupUser(v)
+					if err != nil {
+						return 0, nil, err
+					}
+					if user == nil {
+						continue
+					}
+					v = strconv.FormatInt(*user, 10)
+				}
+				if err := exec(`INSERT INTO task_custom_field_values (task_id, field_id, value) VALUES (?, ?, ?)`,
+					newID, id, v); err != nil {
+					return 0, nil, fmt.Errorf("set custom field value: %w", err)
+				}
+			}
+		}
+
+		for _, rel := range t.Relations {
+			target, ok := taskIDs[rel.TaskID]
+			if !ok {
+				continue
+			}
+			if err := exec(`INSERT INTO task_relations (project_id, source_task_id, target_task_id, relation_type, created_by)
+				VALUES (?, ?, ?, ?, ?)`, projectID, newID, target, rel.RelationType, userID); err != nil {
+				return 0, nil, fmt.Errorf("create task relation: %w", err)
+			}
+			counts["relations"]++
+		}
+
+		for _, c := range t.Comments {
+			if err := exec(`INSERT INTO task_comments (task_id, user_id, comment, agent_name, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)`, newID, userID, rewrite(c.Comment), c.AgentName, c.CreatedAt, c.UpdatedAt); err != nil {
+				return 0, nil, fmt.Errorf("create comment: %w", err)
+			}
+			counts["comments"]++
+		}
+
+		for _, a := range t.Attachments {
+			if err := exec(`INSERT INTO task_attachments (task_id, project_id, user_id, filename, alt_name, file_type,
+					content_type, file_size, cloudinary_url, cloudinary_public_id, created_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				newID, projectID, userID, a.Filename, a.AltName, a.FileType,
+				a.ContentType, a.FileSize, a.CloudinaryURL, a.CloudinaryPublicID, a.CreatedAt); err != nil {
+				return 0, nil, fmt.Errorf("create attachment: %w", err)
+			}
+			counts["attachments"]++
+		}
+	}
+
+	for _, pg := range pages {
+		newID := pageIDs[pg.ID]
+		if err := exec(`UPDATE wiki_pages SET content = ? WHERE id = ?`, rewrite(archive.Files[pg.File]), newID); err != nil {
+			return 0, nil, err
+		}
+		for _, v := range pg.Versions {
+			content := rewrite(archive.Files[v.File])
+			if err := exec(`INSERT INTO wiki_page_versions (wiki_page_id, version_number, content, content_hash, created_by, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)`,
+				newID, v.VersionNumber, content, fmt.Sprintf("%x", sha256.Sum256([]byte(content))), userID, v.CreatedAt); err != nil {
+				return 0, nil, fmt.Errorf("create wiki version: %w", err)
+			}
+			counts["wiki_versions"]++
+		}
+	}
+
+	nodeIDs := map[int64]int64{}
+	for _, n := range archive.Graph.Nodes {
+		var entityID int64
+		var entityNumber *int64
+		var ok bool
+		switch n.EntityType {
+		case "task":
+			if entityID, ok = taskIDs[n.EntityID]; ok {
+				number := taskNumbers[n.EntityID]
+				entityNumber = &number
+			}
+		case "wiki":
+			entityID, ok = pageIDs[n.EntityID]
+		}
+		if !ok {
+			continue
+		}
+		id, err := insert(`INSERT INTO graph_nodes (project_id, entity_type, entity_id, entity_number, title) VALUES (?, ?, ?, ?, ?)`,
+			projectID, n.EntityType, entityID, entityNumber, n.Title)
+		if err != nil {
+			return 0, nil, fmt.Errorf("create graph node: %w", err)
+		}
+		nodeIDs[n.ID] = id
+	}
+	counts["graph_nodes"] = len(nodeIDs)
+	for _, e := range archive.Graph.Edges {
+		source, okSource := nodeIDs[e.SourceNodeID]
+		target, okTarget := nodeIDs[e.TargetNodeID]
+		if !okSource || !okTarget {
+			continue
+		}
+		if err := exec(`INSERT INTO graph_edges (source_node_id, target_node_id, relation_type) VALUES (?, ?, ?)`,
+			source, target, e.RelationType); err != nil {
+			return 0, nil, fmt.Errorf("create graph edge: %w", err)
+		}
+		counts["graph_edges"]++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return projectID, counts, nil
+}
+
+// rewriteGraphLinks points [[task:N]] and [[wiki:N]] links at remapped IDs,
+// keeping any label. Links to entities outside the archive are left as is.
+func rewriteGraphLinks(content string, taskIDs, pageIDs map[int64]int64) string {
+	return graphLinkPattern.ReplaceAllStringFunc(content, func(link string) string {
+		m := graphLinkPattern.FindStringSubmatch(link)
+		oldID, err := strconv.ParseInt(m[2], 10, 64)
+		if err != nil {
+			return link
+		}
+		ids := taskIDs
+		if m[1] == "wiki" {
+			ids = pageIDs
+		}
+		newID, ok := ids[oldID]
+		if !ok {
+			return link
+		}
+		prefix := "[[" + m[1] + ":" + m[2]
+		return "[[" + m[1] + ":" + strconv.FormatInt(newID, 10) + strings.TrimPrefix(link, prefix)
+	})
+}
+
+// archiveWikiDepths returns each page's depth in the archived hierarchy.
+func archiveWikiDepths(pages []archiveWikiPage) map[int64]int {
+	parents := make(map[int64]*int64, len(pages))
+	for _, pg := range pages {
+		parents[pg.ID] = pg.ParentID
+	}
+	depth := make(map[int64]int, len(pages))
+	for _, pg := range pages {
+		d := 0
+		for p := pg.ParentID; p != nil && d < len(pages); p = parents[*p] {
+			d++
+		}
+		depth[pg.ID] = d
+	}
+	return depth
+}
+
+func remapArchiveID(ids map[int64]int64, id *int64) *int64 {
+	if id == nil {
+		return nil
+	}
+	if newID, ok := ids[*id]; ok {
+		return &newID
+	}
+	return nil
+}
+
+func containsInt64(values []int64, v int64) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProjectArchiveRoundTrip(t *testing.T) {
+	src := NewTestServer(t)
+	defer src.Close()
+
+	ownerID := src.CreateTestUser(t, "owner@example.com", "password123")
+	viewerID := src.CreateTestUser(t, "viewer@example.com", "password123")
+	projectID := src.CreateTestProject(t, ownerID, "Archived Project")
+	src.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
+
+	first := src.CreateTestTask(t, projectID, "First")
+	second := src.createTestTaskWithDescription(t, projectID, "Second",
+		fmt.Sprintf("Blocked by [[task:%d|the first task]]", first))
+	if _, err := src.DB.Exec(`INSERT INTO task_comments (task_id, user_id, comment) VALUES (?, ?, ?)`,
+		second, viewerID, "Looks good"); err != nil {
+		t.Fatalf("Failed to create comment: %v", err)
+	}
+	if _, err := src.DB.Exec(`INSERT INTO tags (user_id, project_id, name, color) VALUES (?, ?, ?, ?)`,
+		ownerID, projectID, "bug", "#FF0000"); err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	if _, err := src.DB.Exec(`INSERT INTO custom_fields (project_id, name, field_type, options) VALUES (?, 'Size', 'select', '["S","M"]'), (?, 'Reviewer', 'user', '[]')`,
+		projectID, projectID); err != nil {
+		t.Fatalf("Failed to create custom fields: %v", err)
+	}
+	if _, err := src.DB.Exec(`INSERT INTO task_custom_field_values (task_id, field_id, value)
+		SELECT ?, id, CASE name WHEN 'Size' THEN 'M' ELSE ? END FROM custom_fields WHERE project_id = ?`,
+		second, fmt.Sprint(viewerID), projectID); err != nil {
+		t.Fatalf("Failed to set custom field values: %v", err)
+	}
+
+	parent := src.createTestWikiChild(t, projectID, ownerID, "Guide", nil)
+	child := src.createTestWikiChild(t, projectID, ownerID, "Setup", &parent.ID)
+	content := fmt.Sprintf("See [[wiki:%d]] and [[task:%d]]", parent.ID, second)
+	if _, err := src.DB.Exec(`UPDATE wiki_pages SET content = ? WHERE id = ?`, content, child.ID); err != nil {
+		t.Fatalf("Failed to set content: %v", err)
+	}
+	if _, err := src.DB.Exec(`INSERT INTO wiki_page_versions (wiki_page_id, version_number, content, content_hash, created_by)
+		VALUES (?, 1, ?, 'hash', ?)`, child.ID, content, ownerID); err != nil {
+		t.Fatalf("Failed to create version: %v", err)
+	}
+
+	urlParams := map[string]string{"id": fmt.Sprintf("%d", projectID)}
+	path := fmt.Sprintf("/api/projects/%d/export", projectID)
+
+	t.Run("non-owners cannot export", func(t *testing.T) {
+		rec, req := src.MakeAuthRequest(t, http.MethodGet, path, nil, viewerID, urlParams)
+		src.HandleExportProject(rec, req)
+		AssertError(t, rec, http.StatusForbidden, "owners", "forbidden")
+	})
+
+	rec, req := src.MakeAuthRequest(t, http.MethodGet, path, nil, ownerID, urlParams)
+	src.HandleExportProject(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	archive := rec.Body.Bytes()
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Export is not a zip: %v", err)
+	}
+	files := map[string]bool{}
+	for _, f := range zr.File {
+		files[f.Name] = true
+	}
+	for _, name := range []string{"manifest.json", "project.json", "tasks.json", "graph.json",
+		"wiki/pages.json", "wiki/pages/guide.md", "wiki/pages/guide/setup.md", "wiki/versions/guide/setup/1.md"} {
+		if !files[name] {
+			t.Errorf("Expected %s in archive, got %v", name, files)
+		}
+	}
+
+	// The destination already has data so every ID changes on import
+	dst := NewTestServer(t)
+	defer dst.Close()
+
+	importerID := dst.CreateTestUser(t, "importer@example.com", "password123")
+	dstViewerID := dst.CreateTestUser(t, "viewer@example.com", "password123")
+	// The owner has an account here but isn't on the importer's team
+	dst.CreateTestUser(t, "owner@example.com", "password123")
+	dstTeamID := createTestTeamForUser(t, dst, importerID)
+	addTeamMember(t, dst, dstTeamID, dstViewerID, "member")
+	existing := dst.CreateTestProject(t, importerID, "Existing")
+	for i := 0; i < 3; i++ {
+		dst.CreateTestTask(t, existing, "Filler")
+	}
+	dst.createTestWikiPage(t, existing, importerID, "Filler")
+
+	importReq := httptest.NewRequest(http.MethodPost, "/api/projects/import?name=Copy", bytes.NewReader(archive))
+	importReq = importReq.WithContext(context.WithValue(importReq.Context(), UserIDKey, importerID))
+	importRec := httptest.NewRecorder()
+	dst.HandleImportProject(importRec, importReq)
+	AssertStatusCode(t, importRec.Code, http.StatusCreated)
+
+	var resp ImportProjectResponse
+	DecodeJSON(t, importRec, &resp)
+	if resp.Project.Name != "Copy" || resp.Project.OwnerID != importerID {
+		t.Errorf("Expected project Copy owned by importer, got %+v", resp.Project)
+	}
+	if resp.Counts["tasks"] != 2 || resp.Counts["wiki_pages"] != 2 || resp.Counts["comments"] != 1 ||
+		resp.Counts["tags"] != 1 || resp.Counts["wiki_versions"] != 1 || resp.Counts["custom_fields"] != 2 {
+		t.Errorf("Unexpected counts: %v", resp.Counts)
+	}
+	newProject := resp.Project.ID
+
+	taskID := func(number int) int64 {
+		t.Helper()
+		var id int64
+		if err := dst.DB.QueryRow(`SELECT id FROM tasks WHERE project_id = ? AND task_number = ?`,
+			newProject, number).Scan(&id); err != nil {
+			t.Fatalf("Failed to find task %d: %v", number, err)
+		}
+		return id
+	}
+	newFirst, newSecond := taskID(1), taskID(2)
+	if newFirst == first {
+		t.Fatalf("Expected task IDs to be remapped")
+	}
+
+	var description string
+	if err := dst.DB.QueryRow(`SELECT description FROM tasks WHERE id = ?`, newSecond).Scan(&description); err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if want := fmt.Sprintf("Blocked by [[task:%d|the first task]]", newFirst); description != want {
+		t.Errorf("Expected description %q, got %q", want, description)
+	}
+
+	values := map[string]string{}
+	rows, err := dst.DB.Query(`SELECT f.name, v.value FROM task_custom_field_values v
+		JOIN custom_fields f ON f.id = v.field_id WHERE v.task_id = ?`, newSecond)
+	if err != nil {
+		t.Fatalf("Failed to load custom field values: %v", err)
+	}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			t.Fatalf("Failed to scan custom field value: %v", err)
+		}
+		values[name] = value
+	}
+	rows.Close()
+	if values["Size"] != "M" || values["Reviewer"] != fmt.Sprint(dstViewerID) {
+		t.Errorf("Expected custom field values with users matched by email, got %v", values)
+	}
+
+	var commentAuthor int64
+	if err := dst.DB.QueryRow(`SELECT user_id FROM task_comments WHERE task_id = ?`, newSecond).Scan(&commentAuthor); err != nil {
+		t.Fatalf("Failed to load comment: %v", err)
+	}
+	if commentAuthor != importerID {
+		t.Errorf("Expected comments attributed to the importer, got %d", commentAuthor)
+	}
+
+	var newParent, newChild int64
+	var childParent *int64
+	var childContent string
+	if err := dst.DB.QueryRow(`SELECT id FROM wiki_pages WHERE project_id = ? AND slug = 'guide'`,
+		newProject).Scan(&newParent); err != nil {
+		t.Fatalf("Failed to load parent page: %v", err)
+	}
+	if err := dst.DB.QueryRow(`SELECT id, parent_id, content FROM wiki_pages WHERE project_id = ? AND slug = 'setup'`,
+		newProject).Scan(&newChild, &childParent, &childContent); err != nil {
+		t.Fatalf("Failed to load child page: %v", err)
+	}
+	var parentAuthor int64
+	if err := dst.DB.QueryRow(`SELECT created_by FROM wiki_pages WHERE id = ?`, newParent).Scan(&parentAuthor); err != nil {
+		t.Fatalf("Failed to load page author: %v", err)
+	}
+	if parentAuthor != importerID {
+		t.Errorf("Expected pages attributed to the importer, got %d", parentAuthor)
+	}
+	if childParent == nil || *childParent != newParent {
+		t.Errorf("Expected child under page %d, got %v", newParent, childParent)
+	}
+	wantContent := fmt.Sprintf("See [[wiki:%d]] and [[task:%d]]", newParent, newSecond)
+	if childContent != wantContent {
+		t.Errorf("Expected content %q, got %q", wantContent, childContent)
+	}
+
+	var versionContent string
+	if err := dst.DB.QueryRow(`SELECT content FROM wiki_page_versions WHERE wiki_page_id = ?`,
+		newChild).Scan(&versionContent); err != nil {
+		t.Fatalf("Failed to load version: %v", err)
+	}
+	if versionContent != wantContent {
+		t.Errorf("Expected version links rewritten, got %q", versionContent)
+	}
+}
+
+func TestImportProjectRejectsInvalidArchive(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	createTestTeamForUser(t, ts, userID)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("manifest.json")
+	fmt.Fprintf(f, `{"format":%q,"version":%d}`, projectArchiveFormat, projectArchiveVersion+1)
+	zw.Close()
+
+	var many bytes.Buffer
+	zw = zip.NewWriter(&many)
+	for i := 0; i <= maxProjectArchiveEntries; i++ {
+		zw.Create(fmt.Sprintf("wiki/pages/%d.md", i))
+	}
+	zw.Close()
+
+	var oversized bytes.Buffer
+	zw = zip.NewWriter(&oversized)
+	f, _ = zw.CreateRaw(&zip.FileHeader{
+		Name:               "wiki/pages/big.md",
+		Method:             zip.Store,
+		CompressedSize64:   1,
+		UncompressedSize64: maxProjectArchiveUncompressed + 1,
+	})
+	f.Write([]byte("x"))
+	zw.Close()
+
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"not a zip", []byte("hello"), "zip"},
+		{"newer version", buf.Bytes(), "newer"},
+		{"too many entries", many.Bytes(), "entries"},
+		{"oversized entry", oversized.Bytes(), "too large"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/projects/import", bytes.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+			rec := httptest.NewRecorder()
+			ts.HandleImportProject(rec, req)
+			AssertError(t, rec, http.StatusBadRequest, tt.want, "invalid_input")
+		})
+	}
+}
@@ -23,6 +23,18 @@ type Project struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// projectFromEnt converts an ent project to its API representation
+func projectFromEnt(ep *ent.Project) Project {
+	return Project{
+		ID:          ep.ID,
+		OwnerID:     ep.OwnerID,
+		Name:        ep.Name,
+		Description: ep.Description,
+		CreatedAt:   ep.CreatedAt,
+		UpdatedAt:   ep.UpdatedAt,
+	}
+}
+
 type CreateProjectRequest struct {
 	Name        string  `json:"name"`
 	Description *string `json:"description,omitempty"`
@@ -41,8 +53,12 @@ func (s *Server) HandleListProjects(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(int64)
 
 	// Query projects where user is a member using Ent
-	entProjects, err := s.db.Client.Project.Query().
-		Where(project.HasMembersWith(projectmember.UserID(userID))).
+	query := s.db.Client.Project.Query().
+		Where(project.HasMembersWith(projectmember.UserID(userID)))
+	if key := apiKeyFromContext(ctx); key != nil && len(key.ProjectIDs) > 0 {
+		query = query.Where(project.IDIn(key.ProjectIDs...))
+	}
+	entProjects, err := query.
 		Order(ent.Desc(project.FieldUpdatedAt)).
 		All(ctx)
 	if err != nil {
@@ -78,13 +94,17 @@ func (s *Server) HandleGetProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Query project with authorization check using Ent
-	ep, err := s.db.Client.Project.Query().
-		Where(
-			project.ID(projectID),
-			project.HasMembersWith(projectmember.UserID(userID)),
-		).
-		Only(ctx)
+	allowed, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch project", "internal_error")
+		return
+	}
+	if !allowed {
+		respondError(w, http.StatusNotFound, "project not found", "not_found")
+		return
+	}
+
+	ep, err := s.db.Client.Project.Get(ctx, projectID)
 	if err != nil {
 		if ent.IsNotFound(err) {
 			respondError(w, http.StatusNotFound, "project not found", "not_found")
@@ -211,6 +231,10 @@ func (s *Server) HandleCreateProject(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   newProject.UpdatedAt,
 	}
 
+	auditChange(r, auditEvent{
+		Action: "project.created", ResourceType: "project", ResourceID: p.ID, ProjectID: p.ID, TeamID: teamID,
+		After: p,
+	})
 	respondJSON(w, http.StatusCreated, p)
 }
 
@@ -232,24 +256,22 @@ func (s *Server) HandleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check user has access and is owner or editor
-	projectMember, err := s.db.Client.ProjectMember.Query().
-		Where(
-			projectmember.ProjectID(projectID),
-			projectmember.UserID(userID),
-		).
-		Only(ctx)
+	// Check user has access and may edit the project
+	isMember, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
-		if ent.IsNotFound(err) {
-			respondError(w, http.StatusNotFound, "project not found", "not_found")
-			return
-		}
 		respondError(w, http.StatusInternalServerError, "failed to check project access", "internal_error")
 		return
 	}
-
-	// Only owners and editors can update projects
-	if projectMember.Role != "owner" && projectMember.Role != "editor" {
+	if !isMember {
+		respondError(w, http.StatusNotFound, "project not found", "not_found")
+		return
+	}
+	canEdit, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check project access", "internal_error")
+		return
+	}
+	if !canEdit {
 		respondError(w, http.StatusForbidden, "only project owners and editors can update projects", "forbidden")
 		return
 	}
@@ -266,6 +288,12 @@ func (s *Server) HandleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	before, err := s.db.Client.Project.Get(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to update project", "internal_error")
+		return
+	}
+
 	// Build update using Ent
 	updateBuilder := s.db.Client.Project.UpdateOneID(projectID)
 
@@ -297,6 +325,11 @@ func (s *Server) HandleUpdateProject(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:   updatedProject.UpdatedAt,
 	}
 
+	auditChange(r, auditEvent{
+		Action: "project.updated", ResourceType: "project", ResourceID: projectID, ProjectID: projectID,
+		Before: projectFromEnt(before),
+		After:  p,
+	})
 	respondJSON(w, http.StatusOK, p)
 }
 
@@ -312,7 +345,6 @@ func (s *Server) HandleDeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is project owner
 	projectEntity, err := s.db.Client.Project.Get(ctx, projectID)
 	if err != nil {
 		if ent.IsNotFound(err) {
@@ -323,7 +355,12 @@ func (s *Server) HandleDeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if projectEntity.OwnerID != userID {
+	allowed, err := s.authorizeProject(ctx, userID, projectID, PermProjectDelete)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check project ownership", "internal_error")
+		return
+	}
+	if !allowed {
 		respondError(w, http.StatusForbidden, "only project owner can delete project", "forbidden")
 		return
 	}
@@ -339,5 +376,9 @@ func (s *Server) HandleDeleteProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "project.deleted", ResourceType: "project", ResourceID: projectID, ProjectID: projectID,
+		Before: projectFromEnt(projectEntity),
+	})
 	w.WriteHeader(http.StatusNoContent)
 }
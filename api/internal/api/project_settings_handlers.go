@@ -82,12 +82,12 @@ func (s *Server) HandleGetProjectMembers(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Check if user has access to this project
-	hasAccess, err := s.userHasProjectAccess(int(userID), projectID)
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermProjectView)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !hasAccess {
+	if !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -136,14 +136,14 @@ func (s *Server) HandleAddProjectMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check if user is owner or admin of the project
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	// Check that the user may manage members
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
-		http.Error(w, "Forbidden - only project owners and admins can add members", http.StatusForbidden)
+	if !allowed {
+		http.Error(w, "Forbidden - you don't have permission to add members", http.StatusForbidden)
 		return
 	}
 
@@ -154,8 +154,13 @@ func (s *Server) HandleAddProjectMember(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Validate role
-	if req.Role != "viewer" && req.Role != "member" && req.Role != "editor" && req.Role != "owner" {
-		http.Error(w, "Invalid role. Must be viewer, member, editor, or owner", http.StatusBadRequest)
+	validRole, err := s.validateProjectRole(r.Context(), int64(projectID), req.Role)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !validRole {
+		http.Error(w, "Invalid role. Must be viewer, member, editor, owner or one of the team's custom roles", http.StatusBadRequest)
 		return
 	}
 
@@ -224,6 +229,11 @@ func (s *Server) HandleAddProjectMember(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "project.member_added", ResourceType: "project_member", ResourceID: memberID,
+		ProjectID: int64(projectID),
+		After:     map[string]interface{}{"user_id": memberUserID, "email": req.Email, "role": req.Role},
+	})
 	respondJSON(w, http.StatusCreated, map[string]interface{}{
 		"message":   "Member added successfully",
 		"member_id": memberID,
@@ -250,14 +260,14 @@ func (s *Server) HandleUpdateProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Check if user is owner or admin
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	// Check that the user may manage members
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
-		http.Error(w, "Forbidden - only project owners and admins can update member roles", http.StatusForbidden)
+	if !allowed {
+		http.Error(w, "Forbidden - you don't have permission to update member roles", http.StatusForbidden)
 		return
 	}
 
@@ -268,14 +278,20 @@ func (s *Server) HandleUpdateProjectMember(w http.ResponseWriter, r *http.Reques
 	}
 
 	// Validate role
-	if req.Role != "viewer" && req.Role != "member" && req.Role != "editor" && req.Role != "owner" {
-		http.Error(w, "Invalid role. Must be viewer, member, editor, or owner", http.StatusBadRequest)
+	validRole, err := s.validateProjectRole(r.Context(), int64(projectID), req.Role)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !validRole {
+		http.Error(w, "Invalid role. Must be viewer, member, editor, owner or one of the team's custom roles", http.StatusBadRequest)
 		return
 	}
 
 	// Check if changing from owner to another role
 	var currentRole string
-	err = s.db.QueryRow(`SELECT role FROM project_members WHERE id = $1 AND project_id = $2`, memberID, projectID).Scan(&currentRole)
+	var memberUserID int64
+	err = s.db.QueryRow(`SELECT role, user_id FROM project_members WHERE id = $1 AND project_id = $2`, memberID, projectID).Scan(&currentRole, &memberUserID)
 	if err != nil {
 		http.Error(w, "Member not found", http.StatusNotFound)
 		return
@@ -307,6 +323,16 @@ func (s *Server) HandleUpdateProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Apply the new role to any open wiki collaboration sessions
+	go s.syncWikiCollabMembership(int64(projectID), memberUserID)
+
+	auditChange(r, auditEvent{
+		Action: "project.member_role_changed", ResourceType: "project_member", ResourceID: int64(memberID),
+		ProjectID: int64(projectID),
+		Before:    map[string]interface{}{"user_id": memberUserID, "role": currentRole},
+		After:     map[string]interface{}{"user_id": memberUserID, "role": req.Role},
+	})
+
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Member role updated successfully"})
 }
 
@@ -330,20 +356,21 @@ func (s *Server) HandleRemoveProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Check if user is owner or admin
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	// Check that the user may manage members
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
-		http.Error(w, "Forbidden - only project owners and admins can remove members", http.StatusForbidden)
+	if !allowed {
+		http.Error(w, "Forbidden - you don't have permission to remove members", http.StatusForbidden)
 		return
 	}
 
 	// Check if member being removed is an owner
 	var memberRole string
-	err = s.db.QueryRow(`SELECT role FROM project_members WHERE id = $1 AND project_id = $2`, memberID, projectID).Scan(&memberRole)
+	var memberUserID int64
+	err = s.db.QueryRow(`SELECT role, user_id FROM project_members WHERE id = $1 AND project_id = $2`, memberID, projectID).Scan(&memberRole, &memberUserID)
 	if err != nil {
 		http.Error(w, "Member not found", http.StatusNotFound)
 		return
@@ -374,6 +401,15 @@ func (s *Server) HandleRemoveProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Drop the removed member's live wiki collaboration sockets
+	go s.syncWikiCollabMembership(int64(projectID), memberUserID)
+
+	auditChange(r, auditEvent{
+		Action: "project.member_removed", ResourceType: "project_member", ResourceID: int64(memberID),
+		ProjectID: int64(projectID),
+		Before:    map[string]interface{}{"user_id": memberUserID, "role": memberRole},
+	})
+
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Member removed successfully"})
 }
 
@@ -392,16 +428,28 @@ func (s *Server) HandleGetProjectGitHubSettings(w http.ResponseWriter, r *http.R
 	}
 
 	// Check if user has access
-	hasAccess, err := s.userHasProjectAccess(int(userID), projectID)
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermProjectView)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !hasAccess {
+	if !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
+	settings, err := s.loadProjectGitHubSettings(projectID)
+	if err != nil {
+		s.logger.Error("Failed to fetch GitHub settings", zap.Int("project_id", projectID), zap.Error(err))
+		http.Error(w, "Failed to fetch GitHub settings", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, settings)
+}
+
+// loadProjectGitHubSettings reads a project's GitHub integration settings
+func (s *Server) loadProjectGitHubSettings(projectID int) (ProjectGitHubSettings, error) {
 	var settings ProjectGitHubSettings
 	var lastSync sql.NullTime
 	var token sql.NullString
@@ -409,7 +457,7 @@ func (s *Server) HandleGetProjectGitHubSettings(w http.ResponseWriter, r *http.R
 	var projectURLNull sql.NullString
 	var syncIntervalNull sql.NullString
 
-	err = s.db.QueryRow(`
+	err := s.db.QueryRow(`
 		SELECT
 			COALESCE(github_repo_url, ''),
 			COALESCE(github_owner, ''),
@@ -443,9 +491,7 @@ func (s *Server) HandleGetProjectGitHubSettings(w http.ResponseWriter, r *http.R
 	)
 
 	if err != nil {
-		s.logger.Error("Failed to fetch GitHub settings", zap.Int("project_id", projectID), zap.Error(err))
-		http.Error(w, "Failed to fetch GitHub settings", http.StatusInternalServerError)
-		return
+		return settings, err
 	}
 
 	if lastSync.Valid {
@@ -460,7 +506,7 @@ func (s *Server) HandleGetProjectGitHubSettings(w http.ResponseWriter, r *http.R
 	}
 	settings.SyncInterval = syncIntervalNull.String
 
-	respondJSON(w, http.StatusOK, settings)
+	return settings, nil
 }
 
 // HandleUpdateProjectGitHubSettings updates GitHub settings for a project
@@ -477,14 +523,14 @@ func (s *Server) HandleUpdateProjectGitHubSettings(w http.ResponseWriter, r *htt
 		return
 	}
 
-	// Check if user is owner or admin
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	// Check that the user may configure GitHub
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermGitHubConfigure)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
-		http.Error(w, "Forbidden - only project owners and admins can update GitHub settings", http.StatusForbidden)
+	if !allowed {
+		http.Error(w, "Forbidden - you don't have permission to update GitHub settings", http.StatusForbidden)
 		return
 	}
 
@@ -495,7 +541,20 @@ func (s *Server) HandleUpdateProjectGitHubSettings(w http.ResponseWriter, r *htt
 	}
 	req.ProjectURL = strings.TrimSpace(req.ProjectURL)
 
+	before, err := s.loadProjectGitHubSettings(projectID)
+	if err != nil {
+		s.logger.Error("Failed to fetch GitHub settings", zap.Int("project_id", projectID), zap.Error(err))
+		http.Error(w, "Failed to update GitHub settings", http.StatusInternalServerError)
+		return
+	}
+
 	if req.Token != "" {
+		var token string
+		if token, err = s.db.EncryptSecret(req.Token); err != nil {
+			s.logger.Error("Failed to encrypt GitHub token", zap.Int("project_id", projectID), zap.Error(err))
+			http.Error(w, "Failed to update GitHub settings", http.StatusInternalServerError)
+			return
+		}
 		_, err = s.db.Exec(`
 			UPDATE projects
 			SET
@@ -511,7 +570,7 @@ func (s *Server) HandleUpdateProjectGitHubSettings(w http.ResponseWriter, r *htt
 				github_sync_hour = $10,
 				github_sync_day = $11
 			WHERE id = $12
-		`, req.RepoURL, req.Owner, req.RepoName, req.Branch, req.SyncEnabled, req.PushEnabled, req.Token, req.ProjectURL, req.SyncInterval, req.SyncHour, req.SyncDay, projectID)
+		`, req.RepoURL, req.Owner, req.RepoName, req.Branch, req.SyncEnabled, req.PushEnabled, token, req.ProjectURL, req.SyncInterval, req.SyncHour, req.SyncDay, projectID)
 	} else {
 		_, err = s.db.Exec(`
 			UPDATE projects
@@ -536,57 +595,13 @@ func (s *Server) HandleUpdateProjectGitHubSettings(w http.ResponseWriter, r *htt
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]string{"message": "GitHub settings updated successfully"})
-}
-
-// Helper function to check if user has access to a project
-func (s *Server) userHasProjectAccess(userID, projectID int) (bool, error) {
-	// Check if user is owner
-	var ownerID int
-	err := s.db.QueryRow("SELECT owner_id FROM projects WHERE id = $1", projectID).Scan(&ownerID)
-	if err != nil {
-		return false, err
-	}
-	if ownerID == userID {
-		return true, nil
-	}
-
-	// Check if user is a member
-	var memberID int
-	err = s.db.QueryRow("SELECT id FROM project_members WHERE project_id = $1 AND user_id = $2", projectID, userID).Scan(&memberID)
-	if err == sql.ErrNoRows {
-		return false, nil
-	}
-	if err != nil {
-		return false, err
-	}
-
-	return true, nil
-}
-
-// Helper function to check if user is owner or admin of a project
-func (s *Server) userIsProjectOwnerOrAdmin(userID, projectID int) (bool, error) {
-	// Check if user is owner
-	var ownerID int
-	err := s.db.QueryRow("SELECT owner_id FROM projects WHERE id = $1", projectID).Scan(&ownerID)
-	if err != nil {
-		return false, err
-	}
-	if ownerID == userID {
-		return true, nil
-	}
-
-	// Check if user is an admin member
-	var role string
-	err = s.db.QueryRow("SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2", projectID, userID).Scan(&role)
-	if err == sql.ErrNoRows {
-		return false, nil
+	if after, err := s.loadProjectGitHubSettings(projectID); err == nil {
+		auditChange(r, auditEvent{
+			Action: "project.github_settings_updated", ResourceType: "project", ResourceID: int64(projectID),
+			ProjectID: int64(projectID), Before: before, After: after,
+		})
 	}
-	if err != nil {
-		return false, err
-	}
-
-	return role == "admin", nil
+	respondJSON(w, http.StatusOK, map[string]string{"message": "GitHub settings updated successfully"})
 }
 
 // ProjectInvitation represents a project membership invitation
@@ -627,13 +642,13 @@ func (s *Server) HandleInviteProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
-		http.Error(w, "Forbidden - only project owners and admins can invite members", http.StatusForbidden)
+	if !allowed {
+		http.Error(w, "Forbidden - you don't have permission to invite members", http.StatusForbidden)
 		return
 	}
 
@@ -643,8 +658,13 @@ func (s *Server) HandleInviteProjectMember(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if req.Role != "viewer" && req.Role != "member" && req.Role != "editor" && req.Role != "owner" {
-		http.Error(w, "Invalid role. Must be viewer, member, editor, or owner", http.StatusBadRequest)
+	validRole, err := s.validateProjectRole(r.Context(), int64(projectID), req.Role)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !validRole {
+		http.Error(w, "Invalid role. Must be viewer, member, editor, owner or one of the team's custom roles", http.StatusBadRequest)
 		return
 	}
 
@@ -756,12 +776,12 @@ func (s *Server) HandleGetProjectInvitations(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), projectID)
+	allowed, err := s.authorizeProject(r.Context(), userID, int64(projectID), PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
+	if !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -966,12 +986,12 @@ func (s *Server) HandleWithdrawProjectInvitation(w http.ResponseWriter, r *http.
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), int(projectID))
+	allowed, err := s.authorizeProject(r.Context(), userID, projectID, PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
+	if !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -1021,12 +1041,12 @@ func (s *Server) HandleResendProjectInvitation(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	isOwnerOrAdmin, err := s.userIsProjectOwnerOrAdmin(int(userID), int(projectID))
+	allowed, err := s.authorizeProject(r.Context(), userID, projectID, PermMembersManage)
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	if !isOwnerOrAdmin {
+	if !allowed {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -1296,218 +1296,96 @@ func TestHandleUpdateProjectGitHubSettings(t *testing.T) {
 	})
 }
 
-// TestUserHasProjectAccess tests the userHasProjectAccess helper function.
-func TestUserHasProjectAccess(t *testing.T) {
-	t.Run("owner has access", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Access Test")
-
-		hasAccess, err := ts.userHasProjectAccess(int(ownerID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if !hasAccess {
-			t.Error("Expected owner to have access")
-		}
-	})
-
-	t.Run("member has access", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		memberID := ts.CreateTestUser(t, "member@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Member Access")
-
-		ts.AddProjectMember(t, projectID, memberID, ownerID, "viewer")
-
-		hasAccess, err := ts.userHasProjectAccess(int(memberID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if !hasAccess {
-			t.Error("Expected member to have access")
-		}
-	})
-
-	t.Run("non-member does not have access", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
+// TestAuthorizeProjectView checks which users may view a project, as
+// userHasProjectAccess did before permissions.
+func TestAuthorizeProjectView(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	ctx := context.Background()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Access Test")
+
+	users := map[string]int64{"owner": ownerID, "non-member": strangerID}
+	for _, role := range []string{"viewer", "member", "editor", "admin"} {
+		userID := ts.CreateTestUser(t, role+"@example.com", "password123")
+		ts.AddProjectMember(t, projectID, userID, ownerID, role)
+		users[role] = userID
+	}
 
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		otherID := ts.CreateTestUser(t, "other@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "No Access")
+	tests := []struct {
+		user string
+		want bool
+	}{
+		{"owner", true},
+		{"admin", true},
+		{"editor", true},
+		{"member", true},
+		{"viewer", true},
+		{"non-member", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			got, err := ts.authorizeProject(ctx, users[tt.user], projectID, PermProjectView)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s view access %v, got %v", tt.user, tt.want, got)
+			}
+		})
+	}
 
-		hasAccess, err := ts.userHasProjectAccess(int(otherID), int(projectID))
+	t.Run("nonexistent project", func(t *testing.T) {
+		got, err := ts.authorizeProject(ctx, ownerID, 99999, PermProjectView)
 		if err != nil {
 			t.Fatalf("Unexpected error: %v", err)
 		}
-		if hasAccess {
-			t.Error("Expected non-member to not have access")
-		}
-	})
-
-	t.Run("nonexistent project returns error", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-
-		_, err := ts.userHasProjectAccess(int(ownerID), 99999)
-		if err == nil {
-			t.Error("Expected error for nonexistent project")
-		}
-	})
-
-	t.Run("all member roles have access", func(t *testing.T) {
-		roles := []string{"viewer", "member", "editor", "owner"}
-
-		for _, role := range roles {
-			t.Run(role, func(t *testing.T) {
-				ts := NewTestServer(t)
-				defer ts.Close()
-
-				ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-				userID := ts.CreateTestUser(t, "user@example.com", "password123")
-				projectID := ts.CreateTestProject(t, ownerID, "Role Access "+role)
-
-				ts.AddProjectMember(t, projectID, userID, ownerID, role)
-
-				hasAccess, err := ts.userHasProjectAccess(int(userID), int(projectID))
-				if err != nil {
-					t.Fatalf("Unexpected error: %v", err)
-				}
-				if !hasAccess {
-					t.Errorf("Expected user with role %q to have access", role)
-				}
-			})
+		if got {
+			t.Error("Expected no access to a nonexistent project")
 		}
 	})
 }
 
-// TestUserIsProjectOwnerOrAdmin tests the userIsProjectOwnerOrAdmin helper function.
-func TestUserIsProjectOwnerOrAdmin(t *testing.T) {
-	t.Run("project owner is owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Owner Check")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(ownerID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if !isOwnerOrAdmin {
-			t.Error("Expected project owner to be identified as owner or admin")
-		}
-	})
-
-	t.Run("admin member is owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Admin Check")
-
-		ts.AddProjectMember(t, projectID, adminID, ownerID, "admin")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(adminID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if !isOwnerOrAdmin {
-			t.Error("Expected admin to be identified as owner or admin")
-		}
-	})
-
-	t.Run("viewer is not owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Viewer Check")
-
-		ts.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(viewerID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if isOwnerOrAdmin {
-			t.Error("Expected viewer to not be identified as owner or admin")
-		}
-	})
-
-	t.Run("editor is not owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		editorID := ts.CreateTestUser(t, "editor@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Editor Check")
-
-		ts.AddProjectMember(t, projectID, editorID, ownerID, "editor")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(editorID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if isOwnerOrAdmin {
-			t.Error("Expected editor to not be identified as owner or admin")
-		}
-	})
-
-	t.Run("member is not owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		memberID := ts.CreateTestUser(t, "member@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Member Check")
-
-		ts.AddProjectMember(t, projectID, memberID, ownerID, "member")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(memberID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if isOwnerOrAdmin {
-			t.Error("Expected member to not be identified as owner or admin")
-		}
-	})
-
-	t.Run("non-member is not owner or admin", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
-		strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
-		projectID := ts.CreateTestProject(t, ownerID, "Stranger Check")
-
-		isOwnerOrAdmin, err := ts.userIsProjectOwnerOrAdmin(int(strangerID), int(projectID))
-		if err != nil {
-			t.Fatalf("Unexpected error: %v", err)
-		}
-		if isOwnerOrAdmin {
-			t.Error("Expected non-member to not be identified as owner or admin")
-		}
-	})
-
-	t.Run("nonexistent project returns error", func(t *testing.T) {
-		ts := NewTestServer(t)
-		defer ts.Close()
-
-		ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+// TestAuthorizeProjectManage checks which users may manage a project's
+// members, as userIsProjectOwnerOrAdmin did before permissions.
+func TestAuthorizeProjectManage(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	ctx := context.Background()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Manage Test")
+
+	users := map[string]int64{"owner": ownerID, "non-member": strangerID}
+	for _, role := range []string{"viewer", "member", "editor", "admin"} {
+		userID := ts.CreateTestUser(t, role+"@example.com", "password123")
+		ts.AddProjectMember(t, projectID, userID, ownerID, role)
+		users[role] = userID
+	}
 
-		_, err := ts.userIsProjectOwnerOrAdmin(int(ownerID), 99999)
-		if err == nil {
-			t.Error("Expected error for nonexistent project")
-		}
-	})
+	tests := []struct {
+		user string
+		want bool
+	}{
+		{"owner", true},
+		{"admin", true},
+		{"editor", false},
+		{"member", false},
+		{"viewer", false},
+		{"non-member", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			got, err := ts.authorizeProject(ctx, users[tt.user], projectID, PermMembersManage)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s to manage members: %v, got %v", tt.user, tt.want, got)
+			}
+		})
+	}
 }
@@ -0,0 +1,284 @@
+package api
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/config"
+	"taskai/internal/db"
+)
+
+// RateLimitPolicy limits how many requests a client may make per window.
+// Requests may come in a burst of up to Requests; after that the allowance is
+// restored evenly over Window.
+type RateLimitPolicy struct {
+	Name     string // keeps the counters of different policies apart
+	Requests int
+	Window   time.Duration
+}
+
+// Per-route policies. They stack with the policy of the route group.
+var (
+	// AuthRateLimit covers all public /api/auth routes
+	AuthRateLimit = RateLimitPolicy{Name: "auth", Requests: 20, Window: time.Minute}
+	// LoginRateLimit slows down password guessing on /api/auth/login
+	LoginRateLimit = RateLimitPolicy{Name: "login", Requests: 10, Window: 5 * time.Minute}
+	// PasswordResetRateLimit keeps /api/auth/forgot-password from being used to send mail in bulk
+	PasswordResetRateLimit = RateLimitPolicy{Name: "password_reset", Requests: 5, Window: 15 * time.Minute}
+	// PublicRateLimit covers the other unauthenticated routes
+	PublicRateLimit = RateLimitPolicy{Name: "public", Requests: 30, Window: time.Minute}
+	// SearchRateLimit covers the search endpoints, which are expensive to serve
+	SearchRateLimit = RateLimitPolicy{Name: "search", Requests: 30, Window: time.Minute}
+	// SCIMRateLimit covers /api/scim/v2 per team; identity providers sync
+	// in bursts, one request per user and group
+	SCIMRateLimit = RateLimitPolicy{Name: "scim", Requests: 600, Window: time.Minute}
+)
+
+// interval is the share of the window one request uses up
+func (p RateLimitPolicy) interval() time.Duration {
+	if p.Requests <= 0 {
+		return p.Window
+	}
+	return p.Window / time.Duration(p.Requests)
+}
+
+// RateLimitStore holds the state of rate limits. Limits use GCRA (the
+// generic cell rate algorithm), which needs a single timestamp per key: the
+// theoretical arrival time (TAT) at which the key's allowance is fully
+// restored.
+type RateLimitStore interface {
+	// Take spends one request for key at now. A request is allowed when the
+	// key's TAT is at most tolerance ahead of now, and then moves the TAT on
+	// by interval. It returns the TAT after the request.
+	Take(ctx context.Context, key string, now time.Time, interval, tolerance time.Duration) (tat time.Time, allowed bool, err error)
+}
+
+// defaultRateLimitKeys is how many clients a memory store tracks by default
+const defaultRateLimitKeys = 100000
+
+// MemoryRateLimitStore keeps rate limits in process. Once it holds maxKeys
+// keys the least recently used one is evicted; an evicted client starts over
+// with a full allowance.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	maxKeys int
+	keys    map[string]*list.Element
+	lru     *list.List // front is most recently used
+}
+
+type memoryRateLimit struct {
+	key string
+	tat time.Time
+}
+
+// NewMemoryRateLimitStore creates an in-process store tracking up to maxKeys keys
+func NewMemoryRateLimitStore(maxKeys int) *MemoryRateLimitStore {
+	if maxKeys <= 0 {
+		maxKeys = defaultRateLimitKeys
+	}
+	return &MemoryRateLimitStore{
+		maxKeys: maxKeys,
+		keys:    make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Take implements RateLimitStore
+func (m *MemoryRateLimitStore) Take(_ context.Context, key string, now time.Time, interval, tolerance time.Duration) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.keys[key]
+	if !ok {
+		if m.lru.Len() >= m.maxKeys {
+			oldest := m.lru.Back()
+			m.lru.Remove(oldest)
+			delete(m.keys, oldest.Value.(*memoryRateLimit).key)
+		}
+		elem = m.lru.PushFront(&memoryRateLimit{key: key, tat: now})
+		m.keys[key] = elem
+	} else {
+		m.lru.MoveToFront(elem)
+	}
+
+	entry := elem.Value.(*memoryRateLimit)
+	tat := entry.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	if tat.Sub(now) > tolerance {
+		return entry.tat, false, nil
+	}
+	entry.tat = tat.Add(interval)
+	return entry.tat, true, nil
+}
+
+// Len returns the number of keys the store holds
+func (m *MemoryRateLimitStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// tokenBucket limits a single caller in process: capacity requests at once,
+// refilled at refillRate per second. It is GCRA over a one-key memory store.
+type tokenBucket struct {
+	store     *MemoryRateLimitStore
+	interval  time.Duration
+	tolerance time.Duration
+}
+
+func newTokenBucket(capacity, refillRate float64) *tokenBucket {
+	interval := time.Duration(float64(time.Second) / refillRate)
+	return &tokenBucket{
+		store:     NewMemoryRateLimitStore(1),
+		interval:  interval,
+		tolerance: time.Duration(capacity-1) * interval,
+	}
+}
+
+func (tb *tokenBucket) allow() bool {
+	_, allowed, _ := tb.store.Take(context.Background(), "", time.Now(), tb.interval, tb.tolerance)
+	return allowed
+}
+
+// rateLimitPruneInterval is how often DBRateLimitStore deletes stale keys
+const rateLimitPruneInterval = 5 * time.Minute
+
+// DBRateLimitStore keeps rate limits in the database so every API replica
+// enforces the same limits.
+type DBRateLimitStore struct {
+	db        *db.DB
+	logger    *zap.Logger
+	lastPrune atomic.Int64 // unix nanoseconds
+}
+
+// NewDBRateLimitStore creates a store backed by the rate_limits table
+func NewDBRateLimitStore(database *db.DB, logger *zap.Logger) *DBRateLimitStore {
+	return &DBRateLimitStore{db: database, logger: logger}
+}
+
+// Take implements RateLimitStore. Every few minutes one request also deletes
+// the keys whose allowance has been restored.
+func (s *DBRateLimitStore) Take(ctx context.Context, key string, now time.Time, interval, tolerance time.Duration) (time.Time, bool, error) {
+	last := s.lastPrune.Load()
+	if now.UnixNano()-last > int64(rateLimitPruneInterval) && s.lastPrune.CompareAndSwap(last, now.UnixNano()) {
+		if _, err := s.db.PruneRateLimits(ctx, now); err != nil {
+			s.logger.Warn("Failed to prune rate limits", zap.Error(err))
+		}
+	}
+	return s.db.TakeRateLimit(ctx, key, now, interval, tolerance)
+}
+
+// RateLimiter enforces rate limit policies. Authenticated requests are
+// limited per API key or user; other requests per client IP.
+type RateLimiter struct {
+	store   RateLimitStore
+	proxies TrustedProxies
+	logger  *zap.Logger
+	now     func() time.Time
+}
+
+// NewRateLimiter creates a rate limiter. proxies decides which forwarding
+// headers are believed when limiting by IP.
+func NewRateLimiter(store RateLimitStore, proxies TrustedProxies, logger *zap.Logger) *RateLimiter {
+	return &RateLimiter{
+		store:   store,
+		proxies: proxies,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// identity returns the key a request is limited by. Routes that limit by
+// user or SCIM team must run the limiter after JWTAuth or SCIMAuth.
+func (rl *RateLimiter) identity(r *http.Request) string {
+	if apiKey, ok := r.Context().Value(APIKeyKey).(*db.APIKey); ok && apiKey != nil {
+		return fmt.Sprintf("key:%d", apiKey.ID)
+	}
+	if userID, ok := r.Context().Value(UserIDKey).(int64); ok {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	if teamID, ok := r.Context().Value(SCIMTeamIDKey).(int64); ok {
+		return fmt.Sprintf("scim:%d", teamID)
+	}
+	return "ip:" + rl.proxies.ClientIP(r)
+}
+
+// Limit returns a middleware enforcing policy. Responses carry RateLimit-*
+// headers (draft-ietf-httpapi-ratelimit-headers) and rejected requests get
+// 429 with Retry-After. If the store fails, requests are let through.
+func (rl *RateLimiter) Limit(policy RateLimitPolicy) func(http.Handler) http.Handler {
+	interval := policy.interval()
+	tolerance := policy.Window - interval
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+			defer cancel()
+
+			now := rl.now()
+			tat, allowed, err := rl.store.Take(ctx, policy.Name+":"+rl.identity(r), now, interval, tolerance)
+			if err != nil {
+				rl.logger.Warn("Rate limit check failed; allowing request",
+					zap.String("policy", policy.Name), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			remaining := 0
+			if allowed {
+				remaining = int((now.Add(policy.Window).Sub(tat)) / interval)
+			}
+			setRateLimitHeaders(w, policy, remaining, ceilSeconds(tat.Sub(now)))
+
+			if !allowed {
+				// The next request fits once the TAT is back within tolerance
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(tat.Sub(now)-tolerance)))
+				respondError(w, http.StatusTooManyRequests, "rate limit exceeded", "rate_limit_exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRateLimitHeaders describes the policy closest to its limit. When several
+// policies apply to a route the outer one sets the headers first, and an inner
+// one only replaces them if it has fewer requests remaining.
+func setRateLimitHeaders(w http.ResponseWriter, policy RateLimitPolicy, remaining, reset int) {
+	h := w.Header()
+	if current, err := strconv.Atoi(h.Get("RateLimit-Remaining")); err == nil && current <= remaining {
+		return
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(policy.Requests))
+	h.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(reset))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Requests, ceilSeconds(policy.Window)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// RateLimitMiddleware limits each client to requestsPerMinute using an
+// in-memory store and the default trusted proxies. Deployments with several
+// replicas should build a RateLimiter with a shared store instead.
+func RateLimitMiddleware(requestsPerMinute int) func(http.Handler) http.Handler {
+	proxies, _ := ParseTrustedProxies(config.DefaultTrustedProxies)
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(defaultRateLimitKeys), proxies, zap.NewNop())
+	return limiter.Limit(RateLimitPolicy{Name: "default", Requests: requestsPerMinute, Window: time.Minute})
+}
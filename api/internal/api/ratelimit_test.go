@@ -0,0 +1,278 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/config"
+	"taskai/internal/db"
+)
+
+func defaultTrustedProxies(t *testing.T) TrustedProxies {
+	t.Helper()
+	proxies, err := ParseTrustedProxies(config.DefaultTrustedProxies)
+	if err != nil {
+		t.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+	return proxies
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := defaultTrustedProxies(t)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    string
+		xri    string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"untrusted client spoofing X-Forwarded-For", "203.0.113.7:5000", "198.51.100.1", "", "203.0.113.7"},
+		{"untrusted client spoofing X-Real-IP", "203.0.113.7:5000", "", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:5000", "203.0.113.7", "", "203.0.113.7"},
+		{"client-supplied entries are skipped", "10.0.0.1:5000", "198.51.100.1, 203.0.113.7", "", "203.0.113.7"},
+		{"chain of trusted proxies", "10.0.0.1:5000", "203.0.113.7, 192.168.1.2, 10.0.0.9", "", "203.0.113.7"},
+		{"X-Real-IP from trusted proxy", "127.0.0.1:5000", "", "203.0.113.7", "203.0.113.7"},
+		{"malformed X-Forwarded-For", "10.0.0.1:5000", "not-an-ip", "", "10.0.0.1"},
+		{"IPv6", "[2001:db8::1]:5000", "203.0.113.7", "", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				req.Header.Set("X-Real-IP", tt.xri)
+			}
+			if got := proxies.ClientIP(req); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("RealIP rewrites RemoteAddr and drops forwarding headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+
+		var gotRemote, gotIP string
+		proxies.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotRemote = r.RemoteAddr
+			gotIP = getClientIP(r)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+
+		if gotRemote != "203.0.113.7" || gotIP != "203.0.113.7" {
+			t.Errorf("Expected client IP 203.0.113.7, got RemoteAddr %q and getClientIP %q", gotRemote, gotIP)
+		}
+	})
+
+	t.Run("invalid proxies", func(t *testing.T) {
+		if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+			t.Error("Expected an error for an invalid CIDR")
+		}
+		if _, err := ParseTrustedProxies([]string{"proxy.internal"}); err == nil {
+			t.Error("Expected an error for a hostname")
+		}
+		proxies, err := ParseTrustedProxies([]string{"203.0.113.1"})
+		if err != nil || len(proxies) != 1 || proxies[0].String() != "203.0.113.1/32" {
+			t.Errorf("Expected a bare IP to be trusted alone, got %v (%v)", proxies, err)
+		}
+	})
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(10), defaultTrustedProxies(t), zap.NewNop())
+	limiter.now = func() time.Time { return now }
+
+	handler := limiter.Limit(RateLimitPolicy{Name: "test", Requests: 3, Window: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i, wantRemaining := range []string{"2", "1", "0"} {
+		rec := do()
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if got := rec.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("Request %d: RateLimit-Remaining = %q, want %q", i+1, got, wantRemaining)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "3" {
+			t.Errorf("Request %d: RateLimit-Limit = %q, want 3", i+1, got)
+		}
+	}
+
+	rec := do()
+	AssertError(t, rec, http.StatusTooManyRequests, "rate limit exceeded", "rate_limit_exceeded")
+	if got := rec.Header().Get("Retry-After"); got != "20" {
+		t.Errorf("Retry-After = %q, want 20", got)
+	}
+	if got := rec.Header().Get("RateLimit-Reset"); got != "60" {
+		t.Errorf("RateLimit-Reset = %q, want 60", got)
+	}
+	if got := rec.Header().Get("RateLimit-Policy"); got != "3;w=60" {
+		t.Errorf("RateLimit-Policy = %q, want 3;w=60", got)
+	}
+
+	// One request's worth of allowance comes back every 20 seconds
+	now = now.Add(20 * time.Second)
+	AssertStatusCode(t, do().Code, http.StatusOK)
+	AssertStatusCode(t, do().Code, http.StatusTooManyRequests)
+}
+
+func TestRateLimiterIdentity(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(10), defaultTrustedProxies(t), zap.NewNop())
+	handler := limiter.Limit(RateLimitPolicy{Name: "test", Requests: 1, Window: time.Minute})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
+
+	do := func(ctx context.Context) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil).WithContext(ctx)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	user1 := context.WithValue(context.Background(), UserIDKey, int64(1))
+	user2 := context.WithValue(context.Background(), UserIDKey, int64(2))
+	apiKey := context.WithValue(user1, APIKeyKey, &db.APIKey{ID: 7, UserID: 1})
+
+	// Users behind the same IP (e.g. an office NAT) are limited separately,
+	// as are a user's API keys
+	for _, ctx := range []context.Context{user1, user2, apiKey, context.Background()} {
+		if got := do(ctx); got != http.StatusOK {
+			t.Errorf("Expected the first request of each identity to pass, got %d", got)
+		}
+	}
+	for _, ctx := range []context.Context{user1, user2, apiKey, context.Background()} {
+		if got := do(ctx); got != http.StatusTooManyRequests {
+			t.Errorf("Expected the second request of each identity to be limited, got %d", got)
+		}
+	}
+}
+
+func TestRateLimiterStackedPolicies(t *testing.T) {
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(10), defaultTrustedProxies(t), zap.NewNop())
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := limiter.Limit(RateLimitPolicy{Name: "group", Requests: 10, Window: time.Minute})(
+		limiter.Limit(RateLimitPolicy{Name: "route", Requests: 2, Window: time.Minute})(ok))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	req.RemoteAddr = "203.0.113.7:5000"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected the headers of the policy closest to its limit, got RateLimit-Limit %q", got)
+	}
+	if got := rec.Header().Get("RateLimit-Remaining"); got != "1" {
+		t.Errorf("RateLimit-Remaining = %q, want 1", got)
+	}
+}
+
+func TestMemoryRateLimitStoreBurst(t *testing.T) {
+	store := NewMemoryRateLimitStore(10)
+	ctx := context.Background()
+	now := time.Now()
+
+	// A burst of 3 at one request per second
+	take := func(at time.Time) bool {
+		_, allowed, err := store.Take(ctx, "client", at, time.Second, 2*time.Second)
+		if err != nil {
+			t.Fatalf("Take failed: %v", err)
+		}
+		return allowed
+	}
+	for i := 0; i < 3; i++ {
+		if !take(now) {
+			t.Errorf("Request %d should be allowed", i+1)
+		}
+	}
+	if take(now) {
+		t.Error("Request 4 should be denied (burst used up)")
+	}
+	if !take(now.Add(time.Second)) {
+		t.Error("Expected a request to be allowed once the interval has passed")
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	store := NewMemoryRateLimitStore(2)
+	ctx := context.Background()
+	now := time.Now()
+
+	take := func(key string) bool {
+		_, allowed, err := store.Take(ctx, key, now, time.Minute, 0)
+		if err != nil {
+			t.Fatalf("Take(%q) failed: %v", key, err)
+		}
+		return allowed
+	}
+
+	take("a")
+	take("b")
+	take("a") // a is now the most recently used
+	take("c") // evicts b
+
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 keys, got %d", store.Len())
+	}
+	if !take("b") {
+		t.Error("Expected the evicted key to start over")
+	}
+	if take("c") {
+		t.Error("Expected the retained key to stay limited")
+	}
+}
+
+func TestDBRateLimitStore(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	// Two replicas sharing one database enforce a single limit
+	store := NewDBRateLimitStore(ts.DB, zap.NewNop())
+	policy := RateLimitPolicy{Name: "test", Requests: 3, Window: time.Minute}
+	replicas := []http.Handler{}
+	for i := 0; i < 2; i++ {
+		limiter := NewRateLimiter(store, defaultTrustedProxies(t), zap.NewNop())
+		replicas = append(replicas, limiter.Limit(policy)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })))
+	}
+
+	codes := []int{}
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+		req.RemoteAddr = "203.0.113.7:5000"
+		rec := httptest.NewRecorder()
+		replicas[i%2].ServeHTTP(rec, req)
+		codes = append(codes, rec.Code)
+	}
+	if fmt.Sprint(codes) != "[200 200 200 429]" {
+		t.Errorf("Expected 3 requests allowed across replicas, got %v", codes)
+	}
+
+	t.Run("prune", func(t *testing.T) {
+		n, err := ts.DB.PruneRateLimits(context.Background(), time.Now().Add(2*time.Minute))
+		if err != nil {
+			t.Fatalf("PruneRateLimits failed: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("Expected 1 key pruned, got %d", n)
+		}
+	})
+}
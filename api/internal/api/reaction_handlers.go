@@ -62,7 +62,7 @@ func (s *Server) HandleToggleReaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermTaskComment)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -227,6 +227,9 @@ func (s *Server) tryPushReactionToGitHub(ctx context.Context, taskID, commentID
 		JOIN projects p ON p.id = t.project_id
 		WHERE t.id = $1
 	`, taskID).Scan(&issueNumber, &owner, &repo, &token, &pushEnabled)
+	if err == nil {
+		token, err = s.db.DecryptSecret(token)
+	}
 	if err != nil || !pushEnabled || owner == "" || token == "" {
 		return
 	}
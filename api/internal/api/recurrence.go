@@ -0,0 +1,321 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	recurrenceDaily   = "daily"
+	recurrenceWeekly  = "weekly"
+	recurrenceMonthly = "monthly"
+	recurrenceCron    = "cron"
+)
+
+// maxRecurrenceScan bounds the search for the next occurrence so a rule that
+// can never match (e.g. cron "0 0 31 2 *") doesn't loop forever.
+const maxRecurrenceScan = 5 * 366
+
+// recurrenceRule is a schedule anchored at startsAt. All times are UTC.
+// Occurrences happen at the time of day of startsAt, except for cron rules
+// which carry their own minute and hour.
+type recurrenceRule struct {
+	frequency string
+	interval  int            // every N days, weeks or months
+	weekdays  []time.Weekday // weekly only; defaults to the weekday of startsAt
+	cron      *cronSchedule
+	startsAt  time.Time
+	endsAt    *time.Time // no occurrence after this time
+	maxCount  *int       // total occurrences, including the first
+}
+
+// newRecurrenceRule validates and builds a rule.
+func newRecurrenceRule(frequency string, interval int, weekdays []int, cronExpr string, startsAt time.Time, endsAt *time.Time, maxCount *int) (*recurrenceRule, error) {
+	rule := &recurrenceRule{
+		frequency: frequency,
+		interval:  interval,
+		startsAt:  startsAt.UTC().Truncate(time.Minute),
+		maxCount:  maxCount,
+	}
+	if rule.interval == 0 {
+		rule.interval = 1
+	}
+	if rule.interval < 1 || rule.interval > 365 {
+		return nil, fmt.Errorf("interval must be between 1 and 365")
+	}
+	if endsAt != nil {
+		end := endsAt.UTC()
+		if end.Before(rule.startsAt) {
+			return nil, fmt.Errorf("ends_at must be after starts_at")
+		}
+		rule.endsAt = &end
+	}
+	if maxCount != nil && *maxCount < 1 {
+		return nil, fmt.Errorf("count must be at least 1")
+	}
+
+	switch frequency {
+	case recurrenceDaily, recurrenceMonthly:
+	case recurrenceWeekly:
+		seen := map[int]bool{}
+		for _, d := range weekdays {
+			if d < 0 || d > 6 {
+				return nil, fmt.Errorf("weekdays must be between 0 (Sunday) and 6 (Saturday)")
+			}
+			if !seen[d] {
+				seen[d] = true
+				rule.weekdays = append(rule.weekdays, time.Weekday(d))
+			}
+		}
+		if len(rule.weekdays) == 0 {
+			rule.weekdays = []time.Weekday{rule.startsAt.Weekday()}
+		}
+		sort.Slice(rule.weekdays, func(i, j int) bool { return rule.weekdays[i] < rule.weekdays[j] })
+	case recurrenceCron:
+		schedule, err := parseCronSchedule(cronExpr)
+		if err != nil {
+			return nil, err
+		}
+		rule.cron = schedule
+	default:
+		return nil, fmt.Errorf("frequency must be one of: daily, weekly, monthly, cron")
+	}
+	return rule, nil
+}
+
+// first returns the first occurrence of the rule, or false if there is none.
+func (r *recurrenceRule) first() (time.Time, bool) {
+	return r.next(r.startsAt.Add(-time.Nanosecond), 0)
+}
+
+// next returns the first occurrence strictly after `after`, given that
+// `count` occurrences exist already. It returns false when the rule has ended.
+func (r *recurrenceRule) next(after time.Time, count int) (time.Time, bool) {
+	if r.maxCount != nil && count >= *r.maxCount {
+		return time.Time{}, false
+	}
+	after = after.UTC()
+
+	var t time.Time
+	var ok bool
+	switch r.frequency {
+	case recurrenceDaily:
+		t, ok = r.nextDaily(after), true
+	case recurrenceWeekly:
+		t, ok = r.nextWeekly(after)
+	case recurrenceMonthly:
+		t, ok = r.nextMonthly(after), true
+	case recurrenceCron:
+		from := after
+		if from.Before(r.startsAt) {
+			from = r.startsAt.Add(-time.Minute)
+		}
+		t, ok = r.cron.next(from)
+	}
+	if !ok || (r.endsAt != nil && t.After(*r.endsAt)) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func (r *recurrenceRule) nextDaily(after time.Time) time.Time {
+	if after.Before(r.startsAt) {
+		return r.startsAt
+	}
+	step := 24 * time.Hour * time.Duration(r.interval)
+	k := after.Sub(r.startsAt)/step + 1
+	return r.startsAt.Add(k * step)
+}
+
+func (r *recurrenceRule) nextWeekly(after time.Time) (time.Time, bool) {
+	// Weeks are counted from the Sunday of the week containing startsAt
+	weekStart := r.startsAt.AddDate(0, 0, -int(r.startsAt.Weekday()))
+	week := 0
+	if after.After(weekStart) {
+		week = int(after.Sub(weekStart)/(7*24*time.Hour)) / r.interval * r.interval
+	}
+	for i := 0; i < maxRecurrenceScan; i++ {
+		base := weekStart.AddDate(0, 0, 7*week)
+		for _, d := range r.weekdays {
+			t := base.AddDate(0, 0, int(d))
+			if !t.Before(r.startsAt) && t.After(after) {
+				return t, true
+			}
+		}
+		week += r.interval
+	}
+	return time.Time{}, false
+}
+
+func (r *recurrenceRule) nextMonthly(after time.Time) time.Time {
+	months := 0
+	if after.After(r.startsAt) {
+		months = ((after.Year()-r.startsAt.Year())*12 + int(after.Month()-r.startsAt.Month())) / r.interval * r.interval
+	}
+	for {
+		t := addMonthsClamped(r.startsAt, months)
+		if t.After(after) {
+			return t
+		}
+		months += r.interval
+	}
+}
+
+// addMonthsClamped adds months to t, keeping its day of month but clamping it
+// to the length of the target month (Jan 31 + 1 month = Feb 28/29).
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// weekdayList encodes weekdays for storage ("1,3,5").
+func weekdayList(days []time.Weekday) string {
+	parts := make([]string, len(days))
+	for i, d := range days {
+		parts[i] = strconv.Itoa(int(d))
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseWeekdayList decodes a stored weekday list.
+func parseWeekdayList(s string) []int {
+	var days []int
+	for _, part := range strings.Split(s, ",") {
+		if d, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// cronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week), evaluated in UTC.
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool
+}
+
+// parseCronSchedule parses expressions such as "0 9 * * 1-5" or "*/30 8-17 1,15 * *".
+// Fields support *, lists, ranges and steps; day-of-week 7 is Sunday.
+func parseCronSchedule(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields (minute hour day month weekday)")
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	names := [5]string{"minute", "hour", "day of month", "month", "day of week"}
+
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron %s %q: %v", names[i], field, err)
+		}
+		sets[i] = set
+	}
+	if sets[4][7] {
+		sets[4][0] = true
+		delete(sets[4], 7)
+	}
+	return &cronSchedule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     fields[2] == "*",
+		anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("bad step")
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("bad value")
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("bad range")
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("out of range %d-%d", min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matchesDay applies cron's rule that when both day fields are restricted a
+// day matching either one counts.
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.months[int(t.Month())] {
+		return false
+	}
+	dayOK, weekdayOK := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return weekdayOK
+	case c.anyWeekday:
+		return dayOK
+	default:
+		return dayOK || weekdayOK
+	}
+}
+
+// next returns the first matching minute strictly after `after`.
+func (c *cronSchedule) next(after time.Time) (time.Time, bool) {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRecurrenceScan; i++ {
+		if c.matchesDay(day) {
+			for h := 0; h < 24; h++ {
+				if !c.hours[h] {
+					continue
+				}
+				for m := 0; m < 60; m++ {
+					if !c.minutes[m] {
+						continue
+					}
+					candidate := day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+					if !candidate.Before(t) {
+						return candidate, true
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
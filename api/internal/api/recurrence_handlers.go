@@ -0,0 +1,1035 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/task"
+	"taskai/ent/tasktag"
+)
+
+const (
+	recurrencePollInterval = time.Minute
+	recurrenceBatchSize    = 100
+)
+
+// TaskRecurrence is a recurring task series: a schedule plus the fields
+// copied into every occurrence. Occurrences are ordinary tasks.
+type TaskRecurrence struct {
+	ID              int64      `json:"id"`
+	ProjectID       int64      `json:"project_id"`
+	Frequency       string     `json:"frequency"` // daily, weekly, monthly, cron
+	Interval        int        `json:"interval"`
+	Weekdays        []int      `json:"weekdays,omitempty"` // 0 = Sunday
+	Cron            string     `json:"cron,omitempty"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at,omitempty"`
+	Count           *int       `json:"count,omitempty"` // total occurrences
+	OccurrenceCount int        `json:"occurrence_count"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	Active          bool       `json:"active"`
+	Title           string     `json:"title"`
+	Description     *string    `json:"description,omitempty"`
+	Priority        string     `json:"priority"`
+	SwimLaneID      *int64     `json:"swim_lane_id,omitempty"`
+	EstimatedHours  *float64   `json:"estimated_hours,omitempty"`
+	DueOffsetDays   *int       `json:"due_offset_days,omitempty"` // due date relative to the occurrence
+	TagIDs          []int64    `json:"tag_ids"`
+	AssigneeIDs     []int64    `json:"assignee_ids"`
+	CreatedBy       *int64     `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// RecurrenceOccurrence summarizes one task of a series
+type RecurrenceOccurrence struct {
+	TaskID       int64     `json:"task_id"`
+	TaskNumber   int64     `json:"task_number"`
+	Title        string    `json:"title"`
+	Status       string    `json:"status"`
+	OccurrenceAt time.Time `json:"occurrence_at"`
+}
+
+// TaskRecurrenceSeries is a series with its occurrences, oldest first
+type TaskRecurrenceSeries struct {
+	TaskRecurrence
+	Occurrences []RecurrenceOccurrence `json:"occurrences"`
+}
+
+// CreateRecurrenceRequest makes a task recurring. The task becomes the first
+// occurrence and the template for the ones that follow.
+type CreateRecurrenceRequest struct {
+	Frequency string     `json:"frequency"`
+	Interval  int        `json:"interval,omitempty"`
+	Weekdays  []int      `json:"weekdays,omitempty"`
+	Cron      string     `json:"cron,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"` // defaults to the task's start date, or now
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+	Count     *int       `json:"count,omitempty"`
+}
+
+// UpdateRecurrenceRequest edits a whole series. Template fields also apply to
+// its unfinished occurrences; to edit a single occurrence update its task.
+type UpdateRecurrenceRequest struct {
+	Frequency      *string    `json:"frequency,omitempty"`
+	Interval       *int       `json:"interval,omitempty"`
+	Weekdays       *[]int     `json:"weekdays,omitempty"`
+	Cron           *string    `json:"cron,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	Count          *int       `json:"count,omitempty"`
+	Active         *bool      `json:"active,omitempty"`
+	Title          *string    `json:"title,omitempty"`
+	Description    *string    `json:"description,omitempty"`
+	Priority       *string    `json:"priority,omitempty"`
+	SwimLaneID     *int64     `json:"swim_lane_id,omitempty"`
+	EstimatedHours *float64   `json:"estimated_hours,omitempty"`
+	DueOffsetDays  *int       `json:"due_offset_days,omitempty"`
+	TagIDs         *[]int64   `json:"tag_ids,omitempty"`
+	AssigneeIDs    *[]int64   `json:"assignee_ids,omitempty"`
+}
+
+const recurrenceSelectCols = `id, project_id, frequency, interval_count, weekdays, cron_expr, starts_at, ends_at,
+	max_occurrences, occurrence_count, next_run_at, active, title, description, priority, swim_lane_id,
+	estimated_hours, due_offset_days, tag_ids, assignee_ids, created_by, created_at, updated_at`
+
+// scanRecurrence scans a series row selected with recurrenceSelectCols.
+func scanRecurrence(row interface {
+	Scan(...interface{}) error
+}) (TaskRecurrence, error) {
+	var rec TaskRecurrence
+	var weekdays, tagIDs, assigneeIDs string
+	var endsAt, nextRunAt sql.NullTime
+	var maxCount, dueOffset sql.NullInt64
+	var description sql.NullString
+	var swimLaneID, createdBy sql.NullInt64
+	var estimatedHours sql.NullFloat64
+	if err := row.Scan(&rec.ID, &rec.ProjectID, &rec.Frequency, &rec.Interval, &weekdays, &rec.Cron, &rec.StartsAt, &endsAt,
+		&maxCount, &rec.OccurrenceCount, &nextRunAt, &rec.Active, &rec.Title, &description, &rec.Priority, &swimLaneID,
+		&estimatedHours, &dueOffset, &tagIDs, &assigneeIDs, &createdBy, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+		return rec, err
+	}
+	if rec.Frequency == recurrenceWeekly {
+		rec.Weekdays = parseWeekdayList(weekdays)
+	}
+	if endsAt.Valid {
+		rec.EndsAt = &endsAt.Time
+	}
+	if maxCount.Valid {
+		n := int(maxCount.Int64)
+		rec.Count = &n
+	}
+	if nextRunAt.Valid {
+		rec.NextRunAt = &nextRunAt.Time
+	}
+	if description.Valid {
+		rec.Description = &description.String
+	}
+	if swimLaneID.Valid {
+		rec.SwimLaneID = &swimLaneID.Int64
+	}
+	if estimatedHours.Valid {
+		rec.EstimatedHours = &estimatedHours.Float64
+	}
+	if dueOffset.Valid {
+		n := int(dueOffset.Int64)
+		rec.DueOffsetDays = &n
+	}
+	if createdBy.Valid {
+		rec.CreatedBy = &createdBy.Int64
+	}
+	rec.TagIDs = parseStoredIDList(tagIDs)
+	rec.AssigneeIDs = parseStoredIDList(assigneeIDs)
+	return rec, nil
+}
+
+// rule builds the schedule of a stored series.
+func (rec TaskRecurrence) rule() (*recurrenceRule, error) {
+	return newRecurrenceRule(rec.Frequency, rec.Interval, rec.Weekdays, rec.Cron, rec.StartsAt, rec.EndsAt, rec.Count)
+}
+
+// idList encodes IDs for storage ("3,7,12").
+func idList(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseStoredIDList decodes a stored ID list.
+func parseStoredIDList(s string) []int64 {
+	ids := []int64{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// loadRecurrence loads a series, or sql.ErrNoRows.
+func (s *Server) loadRecurrence(ctx context.Context, id int64) (TaskRecurrence, error) {
+	return scanRecurrence(s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT `+recurrenceSelectCols+` FROM task_recurrences WHERE id = ?`), id))
+}
+
+// recurrenceForRequest loads the series named by the {id} URL parameter and
+// checks that the caller has perm in its project.
+func (s *Server) recurrenceForRequest(ctx context.Context, w http.ResponseWriter, r *http.Request, perm Permission) (TaskRecurrence, bool) {
+	userID := r.Context().Value(UserIDKey).(int64)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid recurrence ID", "invalid_input")
+		return TaskRecurrence{}, false
+	}
+
+	rec, err := s.loadRecurrence(ctx, id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "recurrence not found", "not_found")
+		return rec, false
+	}
+	if err != nil {
+		s.logger.Error("Failed to load recurrence", zap.Int64("recurrence_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch recurrence", "internal_error")
+		return rec, false
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, rec.ProjectID, perm)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return rec, false
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return rec, false
+	}
+	return rec, true
+}
+
+// loadRecurrenceSeries loads a series together with its occurrences.
+func (s *Server) loadRecurrenceSeries(ctx context.Context, rec TaskRecurrence) (TaskRecurrenceSeries, error) {
+	series := TaskRecurrenceSeries{TaskRecurrence: rec, Occurrences: []RecurrenceOccurrence{}}
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT id, COALESCE(task_number, 0), title, status, occurrence_at
+		FROM tasks
+		WHERE recurrence_id = ?
+		ORDER BY occurrence_at, id
+	`), rec.ID)
+	if err != nil {
+		return series, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o RecurrenceOccurrence
+		if err := rows.Scan(&o.TaskID, &o.TaskNumber, &o.Title, &o.Status, &o.OccurrenceAt); err != nil {
+			return series, err
+		}
+		series.Occurrences = append(series.Occurrences, o)
+	}
+	return series, rows.Err()
+}
+
+// respondRecurrenceSeries reloads a series and writes it with its occurrences.
+func (s *Server) respondRecurrenceSeries(ctx context.Context, w http.ResponseWriter, status int, id int64) {
+	rec, err := s.loadRecurrence(ctx, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch recurrence", "internal_error")
+		return
+	}
+	series, err := s.loadRecurrenceSeries(ctx, rec)
+	if err != nil {
+		s.logger.Error("Failed to load occurrences", zap.Int64("recurrence_id", id), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch occurrences", "internal_error")
+		return
+	}
+	respondJSON(w, status, series)
+}
+
+// HandleGetTaskRecurrence returns the series a task belongs to.
+// Route: GET /api/tasks/{taskId}/recurrence
+func (s *Server) HandleGetTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	taskEntity, ok := s.taskForRelations(ctx, w, r, PermProjectView)
+	if !ok {
+		return
+	}
+
+	var recurrenceID sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT recurrence_id FROM tasks WHERE id = ?`),
+		taskEntity.ID).Scan(&recurrenceID); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch recurrence", "internal_error")
+		return
+	}
+	if !recurrenceID.Valid {
+		respondError(w, http.StatusNotFound, "task is not recurring", "not_found")
+		return
+	}
+
+	s.respondRecurrenceSeries(ctx, w, http.StatusOK, recurrenceID.Int64)
+}
+
+// HandleCreateTaskRecurrence makes a task recurring. The task is the first
+// occurrence; its lane, tags, assignees and due date offset are copied into
+// the following ones by the recurrence worker.
+// Route: POST /api/tasks/{taskId}/recurrence
+func (s *Server) HandleCreateTaskRecurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	taskEntity, ok := s.taskForRelations(ctx, w, r, PermTaskCreate)
+	if !ok {
+		return
+	}
+
+	var req CreateRecurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+
+	var existing sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT recurrence_id FROM tasks WHERE id = ?`),
+		taskEntity.ID).Scan(&existing); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to create recurrence", "internal_error")
+		return
+	}
+	if existing.Valid {
+		respondError(w, http.StatusConflict, "task already belongs to a recurring series", "already_recurring")
+		return
+	}
+
+	startsAt := time.Now().UTC().Truncate(time.Minute)
+	switch {
+	case req.StartsAt != nil:
+		startsAt = req.StartsAt.UTC()
+	case taskEntity.StartDate != nil:
+		startsAt = taskEntity.StartDate.UTC()
+	}
+	rule, err := newRecurrenceRule(req.Frequency, req.Interval, req.Weekdays, req.Cron, startsAt, req.EndsAt, req.Count)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	first, ok := rule.first()
+	if !ok {
+		respondError(w, http.StatusBadRequest, "schedule has no occurrences", "invalid_input")
+		return
+	}
+
+	// The due date keeps its distance from the start of the occurrence
+	var dueOffset *int
+	if taskEntity.DueDate != nil {
+		anchor := first
+		if taskEntity.StartDate != nil {
+			anchor = *taskEntity.StartDate
+		}
+		days := int(math.Round(taskEntity.DueDate.Sub(anchor).Hours() / 24))
+		dueOffset = &days
+	}
+
+	// Tasks finished into a done lane shouldn't spawn occurrences there
+	var swimLaneID *int64
+	if taskEntity.SwimLaneID != nil {
+		var category string
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT status_category FROM swim_lanes WHERE id = ?`),
+			*taskEntity.SwimLaneID).Scan(&category)
+		if err == nil && category != "done" {
+			swimLaneID = taskEntity.SwimLaneID
+		}
+	}
+
+	tagIDs, assigneeIDs, err := s.taskTemplateLinks(ctx, taskEntity.ID, taskEntity.AssigneeID)
+	if err != nil {
+		s.logger.Error("Failed to load task links", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create recurrence", "internal_error")
+		return
+	}
+
+	var nextRunAt *time.Time
+	if next, ok := rule.next(first, 1); ok {
+		nextRunAt = &next
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to start transaction", "internal_error")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var recurrenceID int64
+	err = tx.QueryRowContext(ctx, s.db.Rebind(`
+		INSERT INTO task_recurrences (project_id, frequency, interval_count, weekdays, cron_expr, starts_at, ends_at,
+			max_occurrences, occurrence_count, next_run_at, active, title, description, priority, swim_lane_id,
+			estimated_hours, due_offset_days, tag_ids, assignee_ids, created_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), taskEntity.ProjectID, rule.frequency, rule.interval, weekdayList(rule.weekdays), strings.TrimSpace(req.Cron),
+		rule.startsAt, rule.endsAt, rule.maxCount, nextRunAt, nextRunAt != nil,
+		taskEntity.Title, taskEntity.Description, taskEntity.Priority, swimLaneID, taskEntity.EstimatedHours, dueOffset,
+		idList(tagIDs), idList(assigneeIDs), userID, now, now).Scan(&recurrenceID)
+	if err != nil {
+		s.logger.Error("Failed to create recurrence", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create recurrence", "internal_error")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET recurrence_id = ?, occurrence_at = ? WHERE id = ?`),
+		recurrenceID, first, taskEntity.ID); err != nil {
+		s.logger.Error("Failed to link task to recurrence", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create recurrence", "internal_error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to commit recurrence", "internal_error")
+		return
+	}
+
+	s.respondRecurrenceSeries(ctx, w, http.StatusCreated, recurrenceID)
+}
+
+// taskTemplateLinks returns the tag and assignee IDs of a task. The legacy
+// single assignee counts when the task has no multi-assignees.
+func (s *Server) taskTemplateLinks(ctx context.Context, taskID int64, assigneeID *int64) ([]int64, []int64, error) {
+	tags, err := s.db.Client.TaskTag.Query().
+		Where(tasktag.TaskID(taskID)).
+		Select(tasktag.FieldTagID).
+		Ints(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	tagIDs := make([]int64, len(tags))
+	for i, id := range tags {
+		tagIDs[i] = int64(id)
+	}
+	assigneeIDs := []int64{}
+	for _, a := range s.loadTaskAssigneesMap(ctx, []int64{taskID})[taskID] {
+		assigneeIDs = append(assigneeIDs, a.UserID)
+	}
+	if len(assigneeIDs) == 0 && assigneeID != nil {
+		assigneeIDs = append(assigneeIDs, *assigneeID)
+	}
+	return tagIDs, assigneeIDs, nil
+}
+
+// HandleGetRecurrence returns a series and its occurrences.
+// Route: GET /api/recurrences/{id}
+func (s *Server) HandleGetRecurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rec, ok := s.recurrenceForRequest(ctx, w, r, PermProjectView)
+	if !ok {
+		return
+	}
+
+	s.respondRecurrenceSeries(ctx, w, http.StatusOK, rec.ID)
+}
+
+// HandleUpdateRecurrence edits a whole series. Schedule changes move the
+// next run; title, description, priority, estimate, tags and assignees are
+// also applied to every unfinished occurrence. Lane and due offset changes
+// only affect occurrences created from now on.
+// Route: PATCH /api/recurrences/{id}
+func (s *Server) HandleUpdateRecurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rec, ok := s.recurrenceForRequest(ctx, w, r, PermTaskEdit)
+	if !ok {
+		return
+	}
+
+	var req UpdateRecurrenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+
+	scheduleChanged := req.Frequency != nil || req.Interval != nil || req.Weekdays != nil || req.Cron != nil ||
+		req.StartsAt != nil || req.EndsAt != nil || req.Count != nil || (req.Active != nil && *req.Active && !rec.Active)
+	if req.Frequency != nil {
+		rec.Frequency = *req.Frequency
+		if rec.Frequency != recurrenceWeekly {
+			rec.Weekdays = nil
+		}
+		if rec.Frequency != recurrenceCron {
+			rec.Cron = ""
+		}
+	}
+	if req.Interval != nil {
+		rec.Interval = *req.Interval
+	}
+	if req.Weekdays != nil {
+		rec.Weekdays = *req.Weekdays
+	}
+	if req.Cron != nil {
+		rec.Cron = strings.TrimSpace(*req.Cron)
+	}
+	if req.StartsAt != nil {
+		rec.StartsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil {
+		rec.EndsAt = req.EndsAt
+	}
+	if req.Count != nil {
+		rec.Count = req.Count
+	}
+	rule, err := rec.rule()
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+
+	if req.Title != nil {
+		if *req.Title == "" || len(*req.Title) > 255 {
+			respondError(w, http.StatusBadRequest, "title must be 1-255 characters", "invalid_input")
+			return
+		}
+		rec.Title = *req.Title
+	}
+	if req.Priority != nil {
+		if p := *req.Priority; p != "low" && p != "medium" && p != "high" && p != "urgent" {
+			respondError(w, http.StatusBadRequest, "invalid priority (must be: low, medium, high, or urgent)", "invalid_input")
+			return
+		}
+		rec.Priority = *req.Priority
+	}
+	if req.SwimLaneID != nil {
+		var laneProject int64
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT project_id FROM swim_lanes WHERE id = ?`),
+			*req.SwimLaneID).Scan(&laneProject)
+		if err != nil || laneProject != rec.ProjectID {
+			respondError(w, http.StatusBadRequest, "swim lane not found in this project", "invalid_input")
+			return
+		}
+		rec.SwimLaneID = req.SwimLaneID
+	}
+	if req.Description != nil {
+		rec.Description = req.Description
+	}
+	if req.EstimatedHours != nil {
+		rec.EstimatedHours = req.EstimatedHours
+	}
+	if req.DueOffsetDays != nil {
+		rec.DueOffsetDays = req.DueOffsetDays
+	}
+	if req.TagIDs != nil {
+		rec.TagIDs = *req.TagIDs
+	}
+	if req.AssigneeIDs != nil {
+		rec.AssigneeIDs = *req.AssigneeIDs
+	}
+	if req.Active != nil {
+		rec.Active = *req.Active
+	}
+
+	if scheduleChanged {
+		var last sql.NullTime
+		err := s.db.QueryRowContext(ctx, s.db.Rebind(`
+			SELECT occurrence_at FROM tasks WHERE recurrence_id = ? ORDER BY occurrence_at DESC LIMIT 1
+		`), rec.ID).Scan(&last)
+		if err != nil && err != sql.ErrNoRows {
+			respondError(w, http.StatusInternalServerError, "failed to update recurrence", "internal_error")
+			return
+		}
+		next, ok := rule.first()
+		if last.Valid {
+			next, ok = rule.next(last.Time, rec.OccurrenceCount)
+		}
+		rec.NextRunAt = nil
+		if ok {
+			rec.NextRunAt = &next
+		}
+	}
+	if rec.NextRunAt == nil {
+		rec.Active = false
+	}
+
+	// Snapshot the occurrences the template edit is copied to, for their history
+	occurrences, err := s.loadOpenOccurrences(ctx, rec.ID)
+	if err != nil {
+		s.logger.Error("Failed to load occurrences", zap.Int64("recurrence_id", rec.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update recurrence", "internal_error")
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to start transaction", "internal_error")
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, s.db.Rebind(`
+		UPDATE task_recurrences
+		SET frequency = ?, interval_count = ?, weekdays = ?, cron_expr = ?, starts_at = ?, ends_at = ?,
+			max_occurrences = ?, next_run_at = ?, active = ?, title = ?, description = ?, priority = ?,
+			swim_lane_id = ?, estimated_hours = ?, due_offset_days = ?, tag_ids = ?, assignee_ids = ?, updated_at = ?
+		WHERE id = ?
+	`), rule.frequency, rule.interval, weekdayList(rule.weekdays), rec.Cron, rule.startsAt, rule.endsAt,
+		rule.maxCount, rec.NextRunAt, rec.Active, rec.Title, rec.Description, rec.Priority,
+		rec.SwimLaneID, rec.EstimatedHours, rec.DueOffsetDays, idList(rec.TagIDs), idList(rec.AssigneeIDs), now, rec.ID)
+	if err != nil {
+		s.logger.Error("Failed to update recurrence", zap.Int64("recurrence_id", rec.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update recurrence", "internal_error")
+		return
+	}
+
+	if err := s.applyRecurrenceTemplate(ctx, tx, rec, req); err != nil {
+		s.logger.Error("Failed to update occurrences", zap.Int64("recurrence_id", rec.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update occurrences", "internal_error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to commit recurrence", "internal_error")
+		return
+	}
+
+	for i := range occurrences {
+		if after, err := s.loadTask(ctx, occurrences[i].ID); err == nil {
+			s.recordTaskHistory(r, &occurrences[i], &after)
+		}
+	}
+
+	s.respondRecurrenceSeries(ctx, w, http.StatusOK, rec.ID)
+}
+
+// loadOpenOccurrences loads the unfinished tasks of a series, which template
+// edits are copied to.
+func (s *Server) loadOpenOccurrences(ctx context.Context, recurrenceID int64) ([]Task, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`SELECT id FROM tasks WHERE recurrence_id = ? AND status <> 'done'`), recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	tasks := make([]Task, 0, len(ids))
+	for _, id := range ids {
+		t, err := s.loadTask(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// applyRecurrenceTemplate copies the template fields changed by req onto the
+// unfinished occurrences of a series.
+func (s *Server) applyRecurrenceTemplate(ctx context.Context, tx *sql.Tx, rec TaskRecurrence, req UpdateRecurrenceRequest) error {
+	sets := []string{}
+	args := []interface{}{}
+	if req.Title != nil {
+		sets = append(sets, "title = ?")
+		args = append(args, rec.Title)
+	}
+	if req.Description != nil {
+		sets = append(sets, "description = ?")
+		args = append(args, rec.Description)
+	}
+	if req.Priority != nil {
+		sets = append(sets, "priority = ?")
+		args = append(args, rec.Priority)
+	}
+	if req.EstimatedHours != nil {
+		sets = append(sets, "estimated_hours = ?")
+		args = append(args, rec.EstimatedHours)
+	}
+	if req.AssigneeIDs != nil {
+		var assigneeID *int64
+		if len(rec.AssigneeIDs) > 0 {
+			assigneeID = &rec.AssigneeIDs[0]
+		}
+		sets = append(sets, "assignee_id = ?")
+		args = append(args, assigneeID)
+	}
+	if len(sets) == 0 && req.TagIDs == nil {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, s.db.Rebind(`SELECT id FROM tasks WHERE recurrence_id = ? AND status <> 'done'`), rec.ID)
+	if err != nil {
+		return err
+	}
+	var taskIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		taskIDs = append(taskIDs, id)
+	}
+	rows.Close()
+
+	for _, taskID := range taskIDs {
+		if len(sets) > 0 {
+			taskArgs := append(append([]interface{}{}, args...), time.Now().UTC(), taskID)
+			if _, err := tx.ExecContext(ctx, s.db.Rebind(
+				`UPDATE tasks SET `+strings.Join(sets, ", ")+`, updated_at = ? WHERE id = ?`), taskArgs...); err != nil {
+				return err
+			}
+		}
+		if req.TagIDs != nil {
+			if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM task_tags WHERE task_id = ?`), taskID); err != nil {
+				return err
+			}
+			if err := s.insertTaskLinks(ctx, tx, insertOccurrenceTag, taskID, rec.TagIDs); err != nil {
+				return err
+			}
+		}
+		if req.AssigneeIDs != nil {
+			if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM task_assignees WHERE task_id = ?`), taskID); err != nil {
+				return err
+			}
+			if err := s.insertTaskLinks(ctx, tx, insertOccurrenceAssignee, taskID, rec.AssigneeIDs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Copy a template tag or assignee only if it still exists
+const (
+	insertOccurrenceTag      = `INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE id = ?`
+	insertOccurrenceAssignee = `INSERT INTO task_assignees (task_id, user_id) SELECT ?, id FROM users WHERE id = ?`
+)
+
+// insertTaskLinks runs one of the insertOccurrence queries for each distinct ID.
+func (s *Server) insertTaskLinks(ctx context.Context, tx *sql.Tx, query string, taskID int64, ids []int64) error {
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(query), taskID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleDeleteRecurrence ends a series. Its occurrences are kept as regular tasks.
+// Route: DELETE /api/recurrences/{id}
+func (s *Server) HandleDeleteRecurrence(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rec, ok := s.recurrenceForRequest(ctx, w, r, PermTaskDelete)
+	if !ok {
+		return
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to start transaction", "internal_error")
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE tasks SET recurrence_id = NULL WHERE recurrence_id = ?`), rec.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to delete recurrence", "internal_error")
+		return
+	}
+	if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM task_recurrences WHERE id = ?`), rec.ID); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to delete recurrence", "internal_error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to commit deletion", "internal_error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StartRecurrenceWorker periodically creates the due occurrences of recurring tasks
+func (s *Server) StartRecurrenceWorker(ctx context.Context) {
+	ticker := time.NewTicker(recurrencePollInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting recurrence worker",
+		zap.Duration("interval", recurrencePollInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Recurrence worker shutting down")
+			return
+		case <-ticker.C:
+			s.processRecurrences(ctx, time.Now().UTC())
+		}
+	}
+}
+
+// processRecurrences materializes the next occurrence of every due series.
+func (s *Server) processRecurrences(parentCtx context.Context, now time.Time) {
+	ctx, cancel := context.WithTimeout(parentCtx, 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT id FROM task_recurrences
+		WHERE active = ? AND next_run_at <= ?
+		ORDER BY next_run_at
+		LIMIT ?
+	`), true, now, recurrenceBatchSize)
+	if err != nil {
+		s.logger.Error("recurrences: failed to query due series", zap.Error(err))
+		return
+	}
+	var due []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			due = append(due, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range due {
+		if _, err := s.materializeOccurrence(ctx, id, now); err != nil {
+			s.logger.Error("recurrences: failed to create occurrence",
+				zap.Int64("recurrence_id", id),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// materializeOccurrence creates the task for the latest due occurrence of a
+// series and schedules the one after it. Occurrences missed while the worker
+// was down are skipped rather than created in a burst. It returns the new
+// task ID, or 0 when nothing was due or another worker got there first.
+func (s *Server) materializeOccurrence(ctx context.Context, recurrenceID int64, now time.Time) (int64, error) {
+	rec, err := s.loadRecurrence(ctx, recurrenceID)
+	if err != nil {
+		return 0, err
+	}
+	if !rec.Active || rec.NextRunAt == nil || rec.NextRunAt.After(now) {
+		return 0, nil
+	}
+	rule, err := rec.rule()
+	if err != nil {
+		return 0, err
+	}
+
+	occurrenceAt := rec.NextRunAt.UTC()
+	for {
+		later, ok := rule.next(occurrenceAt, rec.OccurrenceCount)
+		if !ok || later.After(now) {
+			break
+		}
+		occurrenceAt = later
+	}
+	var nextRunAt *time.Time
+	if next, ok := rule.next(occurrenceAt, rec.OccurrenceCount+1); ok {
+		nextRunAt = &next
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Claim the occurrence; a concurrent worker sees a changed count and backs off
+	res, err := tx.ExecContext(ctx, s.db.Rebind(`
+		UPDATE task_recurrences
+		SET occurrence_count = occurrence_count + 1, next_run_at = ?, active = ?, updated_at = ?
+		WHERE id = ? AND occurrence_count = ? AND active = ?
+	`), nextRunAt, nextRunAt != nil, now, rec.ID, rec.OccurrenceCount, true)
+	if err != nil {
+		return 0, fmt.Errorf("claim occurrence: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, nil
+	}
+
+	// The stored lane may have been deleted; fall back to the first todo lane
+	var laneID sql.NullInt64
+	status := "todo"
+	if rec.SwimLaneID != nil {
+		err = tx.QueryRowContext(ctx, s.db.Rebind(`SELECT id, status_category FROM swim_lanes WHERE id = ? AND project_id = ?`),
+			*rec.SwimLaneID, rec.ProjectID).Scan(&laneID, &status)
+	}
+	if rec.SwimLaneID == nil || err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, s.db.Rebind(`
+			SELECT id, status_category FROM swim_lanes
+			WHERE project_id = ? AND status_category = 'todo'
+			ORDER BY position LIMIT 1
+		`), rec.ProjectID).Scan(&laneID, &status)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("find swim lane: %w", err)
+	}
+
+	// The sprint whose dates cover the occurrence, if any
+	var sprintID sql.NullInt64
+	day, nextDay := occurrenceAt.Format("2006-01-02"), occurrenceAt.AddDate(0, 0, 1).Format("2006-01-02")
+	err = tx.QueryRowContext(ctx, s.db.Rebind(`
+		SELECT id FROM sprints
+		WHERE project_id = ? AND status <> 'completed' AND start_date < ? AND end_date >= ?
+		ORDER BY start_date DESC LIMIT 1
+	`), rec.ProjectID, nextDay, day).Scan(&sprintID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("find sprint: %w", err)
+	}
+
+	var maxNumber sql.NullInt64
+	if err := tx.QueryRowContext(ctx, s.db.Rebind(`SELECT MAX(task_number) FROM tasks WHERE project_id = ?`),
+		rec.ProjectID).Scan(&maxNumber); err != nil {
+		return 0, fmt.Errorf("next task number: %w", err)
+	}
+
+	var dueDate *time.Time
+	if rec.DueOffsetDays != nil {
+		d := occurrenceAt.AddDate(0, 0, *rec.DueOffsetDays)
+		dueDate = &d
+	}
+	var assigneeID *int64
+	if len(rec.AssigneeIDs) > 0 {
+		assigneeID = &rec.AssigneeIDs[0]
+	}
+
+	var taskID int64
+	err = tx.QueryRowContext(ctx, s.db.Rebind(`
+		INSERT INTO tasks (project_id, task_number, title, description, status, priority, swim_lane_id, sprint_id,
+			assignee_id, estimated_hours, start_date, due_date, created_by, recurrence_id, occurrence_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`), rec.ProjectID, maxNumber.Int64+1, rec.Title, rec.Description, status, rec.Priority, laneID, sprintID,
+		assigneeID, rec.EstimatedHours, occurrenceAt, dueDate, rec.CreatedBy, rec.ID, occurrenceAt, now, now).Scan(&taskID)
+	if err != nil {
+		return 0, fmt.Errorf("create task: %w", err)
+	}
+	if err := s.insertTaskLinks(ctx, tx, insertOccurrenceTag, taskID, rec.TagIDs); err != nil {
+		return 0, fmt.Errorf("copy tags: %w", err)
+	}
+	if err := s.insertTaskLinks(ctx, tx, insertOccurrenceAssignee, taskID, rec.AssigneeIDs); err != nil {
+		return 0, fmt.Errorf("copy assignees: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Created recurring task occurrence",
+		zap.Int64("recurrence_id", rec.ID),
+		zap.Int64("task_id", taskID),
+		zap.Time("occurrence_at", occurrenceAt),
+	)
+
+	t, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		s.logger.Warn("recurrences: failed to load created task", zap.Int64("task_id", taskID), zap.Error(err))
+		return taskID, nil
+	}
+	s.storeLaneTransition(ctx, nil, &t)
+	go s.broadcastToProjectMembers(t.ProjectID, "task_created", t)
+	go s.emitWebhookEvent(t.ProjectID, "task.created", t)
+	if t.Description != nil {
+		taskNum := t.TaskNumber
+		go s.syncGraphLinks(context.Background(), t.ProjectID, "task", t.ID, &taskNum, t.Title, *t.Description)
+	}
+	return taskID, nil
+}
+
+// loadTask loads a task in API form for events and audit entries.
+func (s *Server) loadTask(ctx context.Context, taskID int64) (Task, error) {
+	taskEntity, err := s.db.Client.Task.Query().
+		Where(task.ID(taskID)).
+		WithAssignee().
+		WithSprint().
+		WithSwimLane().
+		WithTaskTags(func(q *ent.TaskTagQuery) { q.WithTag() }).
+		Only(ctx)
+	if err != nil {
+		return Task{}, err
+	}
+
+	t := Task{
+		ID:             taskEntity.ID,
+		ProjectID:      taskEntity.ProjectID,
+		Title:          taskEntity.Title,
+		Description:    taskEntity.Description,
+		Status:         taskEntity.Status,
+		Priority:       taskEntity.Priority,
+		EstimatedHours: taskEntity.EstimatedHours,
+		ActualHours:    taskEntity.ActualHours,
+		AgentName:      taskEntity.AgentName,
+		CreatedAt:      taskEntity.CreatedAt,
+		UpdatedAt:      taskEntity.UpdatedAt,
+		Tags:           []Tag{},
+	}
+	if taskEntity.TaskNumber != nil {
+		t.TaskNumber = int64(*taskEntity.TaskNumber)
+	}
+	if taskEntity.StartDate != nil {
+		startDateStr := taskEntity.StartDate.Format(time.RFC3339)
+		t.StartDate = &startDateStr
+	}
+	if taskEntity.DueDate != nil {
+		dueDateStr := taskEntity.DueDate.Format(time.RFC3339)
+		t.DueDate = &dueDateStr
+	}
+	if taskEntity.Edges.Assignee != nil {
+		t.AssigneeID = &taskEntity.Edges.Assignee.ID
+		t.AssigneeName = userDisplayNamePtr(taskEntity.Edges.Assignee)
+	}
+	t.Assignees = s.loadTaskAssigneesMap(ctx, []int64{taskID})[taskID]
+	if len(t.Assignees) == 0 && t.AssigneeID != nil && t.AssigneeName != nil {
+		t.Assignees = []TaskAssigneeInfo{{UserID: *t.AssigneeID, UserName: *t.AssigneeName}}
+	}
+	if taskEntity.Edges.Sprint != nil {
+		t.SprintID = &taskEntity.Edges.Sprint.ID
+		t.SprintName = &taskEntity.Edges.Sprint.Name
+	}
+	if taskEntity.Edges.SwimLane != nil {
+		t.SwimLaneID = &taskEntity.Edges.SwimLane.ID
+		t.SwimLaneName = &taskEntity.Edges.SwimLane.Name
+	}
+	for _, tt := range taskEntity.Edges.TaskTags {
+		if tt.Edges.Tag != nil {
+			t.Tags = append(t.Tags, Tag{
+				ID:        int(tt.Edges.Tag.ID),
+				UserID:    int(tt.Edges.Tag.UserID),
+				Name:      tt.Edges.Tag.Name,
+				Color:     tt.Edges.Tag.Color,
+				CreatedAt: tt.Edges.Tag.CreatedAt,
+			})
+		}
+	}
+	tasks := []Task{t}
+	s.applyCustomFields(ctx, t.ProjectID, tasks)
+	return tasks[0], nil
+}
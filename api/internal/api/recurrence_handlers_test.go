@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTaskRecurrenceSeries(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	assigneeID := ts.CreateTestUser(t, "assignee@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+	ts.AddProjectMember(t, projectID, assigneeID, userID, "member")
+
+	var laneIDs []int64
+	for i, cat := range []string{"todo", "done"} {
+		res, err := ts.DB.Exec(
+			`INSERT INTO swim_lanes (project_id, name, color, position, status_category) VALUES (?, ?, ?, ?, ?)`,
+			projectID, cat, "#6B7280", i, cat)
+		if err != nil {
+			t.Fatalf("Failed to create swim lane: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		laneIDs = append(laneIDs, id)
+	}
+	res, err := ts.DB.Exec(`INSERT INTO tags (user_id, project_id, name, color) VALUES (?, ?, ?, ?)`,
+		userID, projectID, "chore", "#00FF00")
+	if err != nil {
+		t.Fatalf("Failed to create tag: %v", err)
+	}
+	tagID, _ := res.LastInsertId()
+	res, err = ts.DB.Exec(`INSERT INTO sprints (user_id, project_id, name, status, start_date, end_date) VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, projectID, "Sprint 1", "active", "2026-01-03", "2026-01-16")
+	if err != nil {
+		t.Fatalf("Failed to create sprint: %v", err)
+	}
+	sprintID, _ := res.LastInsertId()
+
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	taskID := ts.CreateTestTask(t, projectID, "Water the plants")
+	if err := ts.DB.Client.Task.UpdateOneID(taskID).
+		SetSwimLaneID(laneIDs[0]).
+		SetStartDate(start).
+		SetDueDate(start.AddDate(0, 0, 2)).
+		Exec(context.Background()); err != nil {
+		t.Fatalf("Failed to update task: %v", err)
+	}
+	if _, err := ts.DB.Exec(`INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?)`, taskID, tagID); err != nil {
+		t.Fatalf("Failed to tag task: %v", err)
+	}
+	if _, err := ts.DB.Exec(`INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?)`, taskID, assigneeID); err != nil {
+		t.Fatalf("Failed to assign task: %v", err)
+	}
+
+	taskParams := map[string]string{"taskId": fmt.Sprintf("%d", taskID)}
+	taskPath := fmt.Sprintf("/api/tasks/%d/recurrence", taskID)
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost, taskPath, CreateRecurrenceRequest{Frequency: "daily"}, userID, taskParams)
+	ts.HandleCreateTaskRecurrence(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+	var series TaskRecurrenceSeries
+	DecodeJSON(t, rec, &series)
+	if !series.StartsAt.Equal(start) || series.NextRunAt == nil || !series.NextRunAt.Equal(start.AddDate(0, 0, 1)) {
+		t.Fatalf("Expected series from the task start, got starts_at=%v next_run_at=%v", series.StartsAt, series.NextRunAt)
+	}
+	if len(series.Occurrences) != 1 || series.Occurrences[0].TaskID != taskID {
+		t.Fatalf("Expected the task as first occurrence, got %+v", series.Occurrences)
+	}
+	if series.DueOffsetDays == nil || *series.DueOffsetDays != 2 || len(series.TagIDs) != 1 || len(series.AssigneeIDs) != 1 {
+		t.Errorf("Expected template copied from the task, got %+v", series.TaskRecurrence)
+	}
+	seriesParams := map[string]string{"id": fmt.Sprintf("%d", series.ID)}
+	seriesPath := fmt.Sprintf("/api/recurrences/%d", series.ID)
+
+	t.Run("a task can only start one series", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, taskPath, CreateRecurrenceRequest{Frequency: "weekly"}, userID, taskParams)
+		ts.HandleCreateTaskRecurrence(rec, req)
+		AssertError(t, rec, http.StatusConflict, "already", "already_recurring")
+	})
+
+	t.Run("worker creates the latest due occurrence", func(t *testing.T) {
+		// Jan 2 was missed; only Jan 3 is created
+		now := time.Date(2026, 1, 3, 12, 0, 0, 0, time.UTC)
+		ts.processRecurrences(context.Background(), now)
+		ts.processRecurrences(context.Background(), now)
+
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, seriesPath, nil, userID, seriesParams)
+		ts.HandleGetRecurrence(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		DecodeJSON(t, rec, &series)
+		if len(series.Occurrences) != 2 {
+			t.Fatalf("Expected 2 occurrences, got %+v", series.Occurrences)
+		}
+		occurrence := series.Occurrences[1]
+		if want := start.AddDate(0, 0, 2); !occurrence.OccurrenceAt.Equal(want) {
+			t.Errorf("Expected occurrence at %v, got %v", want, occurrence.OccurrenceAt)
+		}
+		if series.OccurrenceCount != 2 || series.NextRunAt == nil || !series.NextRunAt.Equal(start.AddDate(0, 0, 3)) {
+			t.Errorf("Expected next run on Jan 4, got count=%d next=%v", series.OccurrenceCount, series.NextRunAt)
+		}
+
+		var laneID, gotSprint, gotTag, gotAssignee int64
+		var dueDate time.Time
+		if err := ts.DB.QueryRow(`SELECT swim_lane_id, sprint_id, due_date FROM tasks WHERE id = ?`,
+			occurrence.TaskID).Scan(&laneID, &gotSprint, &dueDate); err != nil {
+			t.Fatalf("Failed to load occurrence: %v", err)
+		}
+		if laneID != laneIDs[0] || gotSprint != sprintID || !dueDate.Equal(start.AddDate(0, 0, 4)) {
+			t.Errorf("Expected todo lane, sprint %d and due Jan 5, got lane=%d sprint=%d due=%v", sprintID, laneID, gotSprint, dueDate)
+		}
+		if err := ts.DB.QueryRow(`SELECT tag_id FROM task_tags WHERE task_id = ?`, occurrence.TaskID).Scan(&gotTag); err != nil || gotTag != tagID {
+			t.Errorf("Expected tag %d to be copied, got %d (%v)", tagID, gotTag, err)
+		}
+		if err := ts.DB.QueryRow(`SELECT user_id FROM task_assignees WHERE task_id = ?`, occurrence.TaskID).Scan(&gotAssignee); err != nil || gotAssignee != assigneeID {
+			t.Errorf("Expected assignee %d to be copied, got %d (%v)", assigneeID, gotAssignee, err)
+		}
+	})
+
+	t.Run("series edits apply to unfinished occurrences", func(t *testing.T) {
+		if _, err := ts.DB.Exec(`UPDATE tasks SET status = 'done' WHERE id = ?`, taskID); err != nil {
+			t.Fatalf("Failed to complete task: %v", err)
+		}
+		title, priority := "Water all the plants", "high"
+		rec, req := ts.MakeAuthRequest(t, http.MethodPatch, seriesPath,
+			UpdateRecurrenceRequest{Title: &title, Priority: &priority, AssigneeIDs: &[]int64{userID}}, userID, seriesParams)
+		ts.HandleUpdateRecurrence(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		DecodeJSON(t, rec, &series)
+
+		titles := map[int64]string{}
+		for _, o := range series.Occurrences {
+			titles[o.TaskID] = o.Title
+		}
+		open := series.Occurrences[1].TaskID
+		if titles[taskID] != "Water the plants" || titles[open] != title {
+			t.Errorf("Expected only the open occurrence to be renamed, got %v", titles)
+		}
+		var gotAssignee int64
+		if err := ts.DB.QueryRow(`SELECT user_id FROM task_assignees WHERE task_id = ?`, open).Scan(&gotAssignee); err != nil || gotAssignee != userID {
+			t.Errorf("Expected assignees to be replaced, got %d (%v)", gotAssignee, err)
+		}
+
+		history, err := ts.DB.ListTaskHistory(context.Background(), open)
+		if err != nil {
+			t.Fatalf("Failed to list task history: %v", err)
+		}
+		changed := map[string]bool{}
+		for _, e := range history {
+			changed[e.Field] = e.UserID != nil && *e.UserID == userID
+		}
+		if !changed["title"] || !changed["priority"] || !changed["assignees"] {
+			t.Errorf("Expected the edit recorded in the occurrence's history, got %+v", history)
+		}
+	})
+
+	t.Run("schedule edits move the next run", func(t *testing.T) {
+		count := 2
+		rec, req := ts.MakeAuthRequest(t, http.MethodPatch, seriesPath, UpdateRecurrenceRequest{Count: &count}, userID, seriesParams)
+		ts.HandleUpdateRecurrence(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var ended TaskRecurrenceSeries
+		DecodeJSON(t, rec, &ended)
+		if ended.Active || ended.NextRunAt != nil {
+			t.Errorf("Expected the series to end after 2 occurrences, got active=%v next=%v", ended.Active, ended.NextRunAt)
+		}
+	})
+
+	t.Run("deleting the series keeps its tasks", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete, seriesPath, nil, userID, seriesParams)
+		ts.HandleDeleteRecurrence(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		var tasks int
+		if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM tasks WHERE project_id = ?`, projectID).Scan(&tasks); err != nil {
+			t.Fatalf("Failed to count tasks: %v", err)
+		}
+		if tasks != 2 {
+			t.Errorf("Expected 2 tasks, got %d", tasks)
+		}
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodGet, taskPath, nil, userID, taskParams)
+		ts.HandleGetTaskRecurrence(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+	})
+}
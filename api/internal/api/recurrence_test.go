@@ -0,0 +1,136 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// occurrences returns up to n occurrences of a rule.
+func occurrences(t *testing.T, rule *recurrenceRule, n int) []time.Time {
+	t.Helper()
+	var out []time.Time
+	next, ok := rule.first()
+	for ok && len(out) < n {
+		out = append(out, next)
+		next, ok = rule.next(next, len(out))
+	}
+	return out
+}
+
+func TestRecurrenceRuleSchedules(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatalf("bad time %q", s)
+		}
+		return v
+	}
+	three := 3
+	end := at("2026-01-05 00:00")
+
+	tests := []struct {
+		name      string
+		frequency string
+		interval  int
+		weekdays  []int
+		cron      string
+		startsAt  string
+		endsAt    *time.Time
+		count     *int
+		want      []string
+	}{
+		{
+			name: "every other day", frequency: "daily", interval: 2, startsAt: "2026-01-01 09:00",
+			want: []string{"2026-01-01 09:00", "2026-01-03 09:00", "2026-01-05 09:00"},
+		},
+		{
+			// 2026-01-07 is a Wednesday; the Monday of its week is before the start
+			name: "weekdays every other week", frequency: "weekly", interval: 2, weekdays: []int{1, 3, 5}, startsAt: "2026-01-07 08:00",
+			want: []string{"2026-01-07 08:00", "2026-01-09 08:00", "2026-01-19 08:00", "2026-01-21 08:00"},
+		},
+		{
+			name: "weekly defaults to the start weekday", frequency: "weekly", startsAt: "2026-01-07 08:00",
+			want: []string{"2026-01-07 08:00", "2026-01-14 08:00", "2026-01-21 08:00"},
+		},
+		{
+			name: "monthly clamps to month end", frequency: "monthly", startsAt: "2026-01-31 10:00",
+			want: []string{"2026-01-31 10:00", "2026-02-28 10:00", "2026-03-31 10:00", "2026-04-30 10:00"},
+		},
+		{
+			name: "cron on weekday mornings", frequency: "cron", cron: "0 9 * * 1-5", startsAt: "2026-01-09 10:00",
+			want: []string{"2026-01-12 09:00", "2026-01-13 09:00", "2026-01-14 09:00"},
+		},
+		{
+			name: "cron days of month or Wednesdays", frequency: "cron", cron: "30 8 1,15 * 3", startsAt: "2026-02-10 00:00",
+			want: []string{"2026-02-11 08:30", "2026-02-15 08:30", "2026-02-18 08:30"},
+		},
+		{
+			name: "count limits occurrences", frequency: "daily", count: &three, startsAt: "2026-01-01 09:00",
+			want: []string{"2026-01-01 09:00", "2026-01-02 09:00", "2026-01-03 09:00"},
+		},
+		{
+			name: "end date limits occurrences", frequency: "daily", endsAt: &end, startsAt: "2026-01-01 09:00",
+			want: []string{"2026-01-01 09:00", "2026-01-02 09:00", "2026-01-03 09:00", "2026-01-04 09:00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newRecurrenceRule(tt.frequency, tt.interval, tt.weekdays, tt.cron, at(tt.startsAt), tt.endsAt, tt.count)
+			if err != nil {
+				t.Fatalf("newRecurrenceRule: %v", err)
+			}
+			got := occurrences(t, rule, 6)
+			if len(got) > len(tt.want) && tt.count == nil && tt.endsAt == nil {
+				got = got[:len(tt.want)]
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d occurrences, got %v", len(tt.want), got)
+			}
+			for i, w := range tt.want {
+				if !got[i].Equal(at(w)) {
+					t.Errorf("Occurrence %d: expected %s, got %s", i, w, got[i].Format("2006-01-02 15:04"))
+				}
+			}
+		})
+	}
+}
+
+func TestRecurrenceRuleValidation(t *testing.T) {
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	before := start.Add(-time.Hour)
+	zero := 0
+
+	tests := []struct {
+		name      string
+		frequency string
+		interval  int
+		weekdays  []int
+		cron      string
+		endsAt    *time.Time
+		count     *int
+	}{
+		{name: "unknown frequency", frequency: "hourly"},
+		{name: "negative interval", frequency: "daily", interval: -1},
+		{name: "bad weekday", frequency: "weekly", weekdays: []int{7}},
+		{name: "short cron", frequency: "cron", cron: "0 9 * *"},
+		{name: "cron out of range", frequency: "cron", cron: "0 24 * * *"},
+		{name: "end before start", frequency: "daily", endsAt: &before},
+		{name: "zero count", frequency: "daily", count: &zero},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newRecurrenceRule(tt.frequency, tt.interval, tt.weekdays, tt.cron, start, tt.endsAt, tt.count); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	// A cron rule that can never match ends instead of looping
+	rule, err := newRecurrenceRule("cron", 0, nil, "0 0 31 2 *", start, nil, nil)
+	if err != nil {
+		t.Fatalf("newRecurrenceRule: %v", err)
+	}
+	if _, ok := rule.first(); ok {
+		t.Error("Expected no occurrence on February 31st")
+	}
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+)
+
+// ProjectRole is a role project members can be given, built-in or defined by
+// the team
+type ProjectRole struct {
+	ID          int64        `json:"id,omitempty"` // 0 for built-in roles
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	BuiltIn     bool         `json:"built_in"`
+}
+
+// TeamRolesResponse lists the roles of a team and the permissions they can grant
+type TeamRolesResponse struct {
+	Permissions []PermissionInfo `json:"permissions"`
+	Roles       []ProjectRole    `json:"roles"`
+}
+
+// TeamRoleRequest creates or replaces a custom role
+type TeamRoleRequest struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// ProjectPermissionsResponse is what the current user may do in a project
+type ProjectPermissionsResponse struct {
+	Role        string       `json:"role"`
+	Permissions []Permission `json:"permissions"`
+}
+
+var builtInRoleDescriptions = map[string]string{
+	RoleViewer: "Can view the project",
+	RoleMember: "Can create, edit and discuss tasks and wiki pages",
+	RoleEditor: "Members who can also delete content and edit the project",
+	RoleOwner:  "Full control of the project",
+}
+
+func projectRoleFromTeamRole(r *db.TeamRole) ProjectRole {
+	perms := make([]Permission, len(r.Permissions))
+	for i, p := range r.Permissions {
+		perms[i] = Permission(p)
+	}
+	return ProjectRole{ID: r.ID, Name: r.Name, Description: r.Description, Permissions: perms}
+}
+
+// requireTeamRoleAdmin returns the team of a user who may manage its roles,
+// or responds with an error.
+func (s *Server) requireTeamRoleAdmin(ctx context.Context, w http.ResponseWriter, userID int64) (int64, bool) {
+	teamID, err := s.getUserTeamID(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "no active team found", "not_found")
+		return 0, false
+	}
+	role, err := s.getUserTeamRole(ctx, userID, teamID)
+	if err != nil || (role != "owner" && role != "admin") {
+		respondError(w, http.StatusForbidden, "only team owners and admins can manage roles", "forbidden")
+		return 0, false
+	}
+	return teamID, true
+}
+
+// HandleListTeamRoles returns the built-in roles, the team's custom roles and
+// the permissions roles can grant
+// Route: GET /api/team/roles
+func (s *Server) HandleListTeamRoles(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, err := s.getUserTeamID(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "no active team found", "not_found")
+		return
+	}
+
+	custom, err := s.db.ListTeamRoles(ctx, teamID)
+	if err != nil {
+		s.logger.Error("Failed to list team roles", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to list roles", "internal_error")
+		return
+	}
+
+	resp := TeamRolesResponse{Permissions: permissionCatalog, Roles: []ProjectRole{}}
+	for _, name := range assignableBuiltInRoles {
+		resp.Roles = append(resp.Roles, ProjectRole{
+			Name:        name,
+			Description: builtInRoleDescriptions[name],
+			Permissions: builtInRoles[name],
+			BuiltIn:     true,
+		})
+	}
+	for i := range custom {
+		resp.Roles = append(resp.Roles, projectRoleFromTeamRole(&custom[i]))
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleCreateTeamRole defines a custom role for the team's projects
+// Route: POST /api/team/roles
+func (s *Server) HandleCreateTeamRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamRoleAdmin(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	var req TeamRoleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	role, ok := teamRoleFromRequest(w, req)
+	if !ok {
+		return
+	}
+	role.TeamID = teamID
+
+	if err := s.db.CreateTeamRole(ctx, role); err != nil {
+		if errors.Is(err, db.ErrTeamRoleExists) {
+			respondError(w, http.StatusConflict, "a role with this name already exists", "role_exists")
+			return
+		}
+		s.logger.Error("Failed to create team role", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to create role", "internal_error")
+		return
+	}
+
+	s.logger.Info("Team role created",
+		zap.Int64("team_id", teamID),
+		zap.Int64("role_id", role.ID),
+		zap.Int64("user_id", userID),
+	)
+	created := projectRoleFromTeamRole(role)
+	auditChange(r, auditEvent{
+		Action: "team_role.created", ResourceType: "team_role", ResourceID: role.ID, TeamID: teamID,
+		After: created,
+	})
+	respondJSON(w, http.StatusCreated, created)
+}
+
+// HandleUpdateTeamRole replaces a custom role. Members who have it get its new
+// permissions right away.
+// Route: PUT /api/team/roles/{roleId}
+func (s *Server) HandleUpdateTeamRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamRoleAdmin(ctx, w, userID)
+	if !ok {
+		return
+	}
+	roleID, err := strconv.ParseInt(chi.URLParam(r, "roleId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid role ID", "invalid_input")
+		return
+	}
+
+	var req TeamRoleRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	role, ok := teamRoleFromRequest(w, req)
+	if !ok {
+		return
+	}
+	role.ID, role.TeamID = roleID, teamID
+
+	before, err := s.db.GetTeamRole(ctx, teamID, roleID)
+	if err == nil {
+		err = s.db.UpdateTeamRole(ctx, role)
+	}
+	switch {
+	case errors.Is(err, db.ErrTeamRoleNotFound):
+		respondError(w, http.StatusNotFound, "role not found", "not_found")
+		return
+	case errors.Is(err, db.ErrTeamRoleExists):
+		respondError(w, http.StatusConflict, "a role with this name already exists", "role_exists")
+		return
+	case err != nil:
+		s.logger.Error("Failed to update team role", zap.Error(err), zap.Int64("role_id", roleID))
+		respondError(w, http.StatusInternalServerError, "failed to update role", "internal_error")
+		return
+	}
+
+	updated := projectRoleFromTeamRole(role)
+	auditChange(r, auditEvent{
+		Action: "team_role.updated", ResourceType: "team_role", ResourceID: roleID, TeamID: teamID,
+		Before: projectRoleFromTeamRole(before), After: updated,
+	})
+	respondJSON(w, http.StatusOK, updated)
+}
+
+// HandleDeleteTeamRole deletes a custom role. Roles that members or pending
+// invitations still have can't be deleted.
+// Route: DELETE /api/team/roles/{roleId}
+func (s *Server) HandleDeleteTeamRole(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamRoleAdmin(ctx, w, userID)
+	if !ok {
+		return
+	}
+	roleID, err := strconv.ParseInt(chi.URLParam(r, "roleId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid role ID", "invalid_input")
+		return
+	}
+
+	before, err := s.db.GetTeamRole(ctx, teamID, roleID)
+	if err == nil {
+		err = s.db.DeleteTeamRole(ctx, teamID, roleID)
+	}
+	switch {
+	case errors.Is(err, db.ErrTeamRoleNotFound):
+		respondError(w, http.StatusNotFound, "role not found", "not_found")
+		return
+	case errors.Is(err, db.ErrTeamRoleInUse):
+		respondError(w, http.StatusConflict, "the role is still given to project members or invitations; change their role first", "role_in_use")
+		return
+	case err != nil:
+		s.logger.Error("Failed to delete team role", zap.Error(err), zap.Int64("role_id", roleID))
+		respondError(w, http.StatusInternalServerError, "failed to delete role", "internal_error")
+		return
+	}
+
+	auditChange(r, auditEvent{
+		Action: "team_role.deleted", ResourceType: "team_role", ResourceID: roleID, TeamID: teamID,
+		Before: projectRoleFromTeamRole(before),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// teamRoleFromRequest validates a custom role, responding with an error when
+// it's invalid
+func teamRoleFromRequest(w http.ResponseWriter, req TeamRoleRequest) (*db.TeamRole, bool) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 50 {
+		respondError(w, http.StatusBadRequest, "role name must be between 1 and 50 characters", "validation_error")
+		return nil, false
+	}
+	if isBuiltInRole(name) {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("role name %q is reserved for a built-in role", name), "validation_error")
+		return nil, false
+	}
+
+	role := &db.TeamRole{Name: name, Description: strings.TrimSpace(req.Description), Permissions: []string{}}
+	for _, p := range req.Permissions {
+		if p == PermProjectView {
+			continue // every role can view its projects
+		}
+		if !validPermission(p) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("unknown permission %q", p), "validation_error")
+			return nil, false
+		}
+		if !slices.Contains(role.Permissions, string(p)) {
+			role.Permissions = append(role.Permissions, string(p))
+		}
+	}
+	return role, true
+}
+
+// HandleGetProjectPermissions returns the current user's role in a project and
+// what it allows
+// Route: GET /api/projects/{id}/permissions
+func (s *Server) HandleGetProjectPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+
+	role, err := s.projectMemberRole(ctx, userID, projectID)
+	if err != nil {
+		s.logger.Error("Failed to load project role", zap.Error(err), zap.Int64("project_id", projectID))
+		respondError(w, http.StatusInternalServerError, "failed to load permissions", "internal_error")
+		return
+	}
+	if role == "" {
+		respondError(w, http.StatusNotFound, "project not found", "not_found")
+		return
+	}
+	perms, err := s.projectRolePermissions(ctx, projectID, role)
+	if err != nil {
+		s.logger.Error("Failed to load role permissions", zap.Error(err), zap.Int64("project_id", projectID))
+		respondError(w, http.StatusInternalServerError, "failed to load permissions", "internal_error")
+		return
+	}
+
+	resp := ProjectPermissionsResponse{Role: role, Permissions: []Permission{PermProjectView}}
+	resp.Permissions = append(resp.Permissions, perms...)
+	respondJSON(w, http.StatusOK, resp)
+}
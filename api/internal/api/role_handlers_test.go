@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"taskai/internal/db"
+)
+
+func TestAuthorizeProject(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	ctx := context.Background()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+	teamID, projectID := createTestTeamAndProject(t, ts, ownerID, "Access Test")
+
+	triager := &db.TeamRole{TeamID: teamID, Name: "Triager", Permissions: []string{string(PermTaskEdit), string(PermTaskComment)}}
+	if err := ts.DB.CreateTeamRole(ctx, triager); err != nil {
+		t.Fatalf("Failed to create role: %v", err)
+	}
+
+	users := map[string]int64{"owner": ownerID}
+	for i, role := range []string{"viewer", "member", "editor", "admin", "Triager", "Retired"} {
+		userID := ts.CreateTestUser(t, fmt.Sprintf("user%d@example.com", i), "password123")
+		ts.AddProjectMember(t, projectID, userID, ownerID, role)
+		users[role] = userID
+	}
+
+	// Projects created before owners got a project_members row
+	legacyID := ts.CreateTestProject(t, strangerID, "Legacy")
+	if _, err := ts.DB.Exec(`DELETE FROM project_members WHERE project_id = ?`, legacyID); err != nil {
+		t.Fatalf("Failed to remove owner row: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		userID    int64
+		projectID int64
+		perm      Permission
+		want      bool
+	}{
+		{"owner can delete the project", ownerID, projectID, PermProjectDelete, true},
+		{"every role can view", users["viewer"], projectID, PermProjectView, true},
+		{"viewer can't create tasks", users["viewer"], projectID, PermTaskCreate, false},
+		{"member creates tasks", users["member"], projectID, PermTaskCreate, true},
+		{"member can't delete tasks", users["member"], projectID, PermTaskDelete, false},
+		{"editor deletes tasks", users["editor"], projectID, PermTaskDelete, true},
+		{"editor can't manage members", users["editor"], projectID, PermMembersManage, false},
+		{"admin manages members", users["admin"], projectID, PermMembersManage, true},
+		{"admin can't delete the project", users["admin"], projectID, PermProjectDelete, false},
+		{"custom role grants its permissions", users["Triager"], projectID, PermTaskEdit, true},
+		{"custom role grants nothing else", users["Triager"], projectID, PermTaskCreate, false},
+		{"removed role can still view", users["Retired"], projectID, PermProjectView, true},
+		{"removed role grants nothing", users["Retired"], projectID, PermTaskComment, false},
+		{"non-member can't view", strangerID, projectID, PermProjectView, false},
+		{"nonexistent project", ownerID, 99999, PermProjectView, false},
+		{"owner without a member row", strangerID, legacyID, PermProjectDelete, true},
+		{"others on a legacy project", ownerID, legacyID, PermProjectView, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ts.authorizeProject(ctx, tt.userID, tt.projectID, tt.perm)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("authorizeProject(%s) = %v, want %v", tt.perm, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectPermissionsEnforced(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Read Only")
+	ts.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	viewer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, viewerID, "viewer@example.com")}
+
+	rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID),
+		map[string]string{"title": "Viewer task"}, viewer)
+	AssertStatusCode(t, rec.Code, http.StatusForbidden)
+
+	rec = routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID),
+		map[string]string{"title": "Owner task"}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var task Task
+	DecodeJSON(t, rec, &task)
+
+	rec = routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/tasks/%d", task.ID), nil, viewer)
+	AssertStatusCode(t, rec.Code, http.StatusForbidden)
+
+	rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks", projectID), nil, viewer)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/permissions", projectID), nil, viewer)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var perms ProjectPermissionsResponse
+	DecodeJSON(t, rec, &perms)
+	if perms.Role != "viewer" || !slices.Equal(perms.Permissions, []Permission{PermProjectView}) {
+		t.Errorf("Expected the viewer to only view, got %+v", perms)
+	}
+}
+
+func TestTeamRoles(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	teamID, projectID := createTestTeamAndProject(t, ts, ownerID, "Custom Roles")
+	addUserToTeam(t, ts, teamID, memberID, "member")
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	member := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, memberID, "member@example.com")}
+
+	rec := routerRequest(t, ts, http.MethodPost, "/api/team/roles", TeamRoleRequest{
+		Name: "Reporter", Permissions: []Permission{PermTaskCreate, PermTaskComment, PermTaskCreate},
+	}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var reporter ProjectRole
+	DecodeJSON(t, rec, &reporter)
+	if reporter.ID == 0 || reporter.BuiltIn || !slices.Equal(reporter.Permissions, []Permission{PermTaskCreate, PermTaskComment}) {
+		t.Fatalf("Unexpected role: %+v", reporter)
+	}
+	rolePath := fmt.Sprintf("/api/team/roles/%d", reporter.ID)
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			req        TeamRoleRequest
+			headers    map[string]string
+			wantStatus int
+		}{
+			{"team members can't manage roles", TeamRoleRequest{Name: "Mine"}, member, http.StatusForbidden},
+			{"empty name", TeamRoleRequest{Name: " "}, owner, http.StatusBadRequest},
+			{"built-in name", TeamRoleRequest{Name: "Editor"}, owner, http.StatusBadRequest},
+			{"legacy built-in name", TeamRoleRequest{Name: "admin"}, owner, http.StatusBadRequest},
+			{"unknown permission", TeamRoleRequest{Name: "Root", Permissions: []Permission{"everything"}}, owner, http.StatusBadRequest},
+			{"duplicate name", TeamRoleRequest{Name: "reporter"}, owner, http.StatusConflict},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rec := routerRequest(t, ts, http.MethodPost, "/api/team/roles", tt.req, tt.headers)
+				AssertStatusCode(t, rec.Code, tt.wantStatus)
+			})
+		}
+	})
+
+	t.Run("team members list roles", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, "/api/team/roles", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var roles TeamRolesResponse
+		DecodeJSON(t, rec, &roles)
+		if len(roles.Roles) != 5 || !roles.Roles[0].BuiltIn || roles.Roles[4].Name != "Reporter" {
+			t.Errorf("Expected the built-in roles then Reporter, got %+v", roles.Roles)
+		}
+		if len(roles.Permissions) != len(permissionCatalog) {
+			t.Errorf("Expected the permission catalog, got %+v", roles.Permissions)
+		}
+	})
+
+	t.Run("members get the role's permissions", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/members", projectID),
+			AddMemberRequest{Email: "member@example.com", Role: "Reporter"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+		rec = routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID),
+			map[string]string{"title": "Reported bug"}, member)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var task Task
+		DecodeJSON(t, rec, &task)
+
+		rec = routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/tasks/%d", task.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+
+		rec = routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/members", projectID),
+			AddMemberRequest{Email: "owner@example.com", Role: "Auditor"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("renaming renames members' roles", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPut, rolePath, TeamRoleRequest{
+			Name: "Bug Reporter", Permissions: []Permission{PermTaskCreate, PermTaskDelete},
+		}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/permissions", projectID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var perms ProjectPermissionsResponse
+		DecodeJSON(t, rec, &perms)
+		if perms.Role != "Bug Reporter" || !slices.Contains(perms.Permissions, PermTaskDelete) {
+			t.Errorf("Expected the renamed role and its new permissions, got %+v", perms)
+		}
+	})
+
+	t.Run("roles in use can't be deleted", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodDelete, rolePath, nil, owner)
+		AssertError(t, rec, http.StatusConflict, "still given", "role_in_use")
+
+		memberRowID := getProjectMemberID(t, ts, projectID, memberID)
+		rec = routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/projects/%d/members/%d", projectID, memberRowID),
+			UpdateMemberRoleRequest{Role: "viewer"}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		rec = routerRequest(t, ts, http.MethodDelete, rolePath, nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		rec = routerRequest(t, ts, http.MethodDelete, rolePath, nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,773 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent/project"
+	"taskai/internal/db"
+	"taskai/internal/scim"
+)
+
+// maxSCIMBody caps the size of SCIM request bodies
+const maxSCIMBody = 1 << 20
+
+// TeamSCIMResponse is the team's SCIM provisioning setup. Token is nil
+// until the owner generates one.
+type TeamSCIMResponse struct {
+	BaseURL string         `json:"base_url"`
+	Token   *db.SCIMToken  `json:"token"`
+	Groups  []db.SCIMGroup `json:"groups"`
+}
+
+// SCIMGroupMappingRequest sets what a SCIM group's members get
+type SCIMGroupMappingRequest struct {
+	TeamRole string                `json:"team_role"`
+	Projects []db.SCIMGroupProject `json:"projects"`
+}
+
+// scimURL returns the URL of a SCIM endpoint
+func (s *Server) scimURL(path string) string {
+	return strings.TrimRight(s.config.AppURL, "/") + "/api/scim/v2" + path
+}
+
+// HandleGetTeamSCIM returns the team's SCIM token and groups
+// Route: GET /api/team/scim
+func (s *Server) HandleGetTeamSCIM(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	token, err := s.db.GetSCIMToken(ctx, teamID)
+	if errors.Is(err, db.ErrSCIMTokenNotFound) {
+		token, err = nil, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to get scim token", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to get provisioning settings", "internal_error")
+		return
+	}
+	groups, _, err := s.db.ListSCIMGroups(ctx, teamID, db.SCIMQuery{Limit: -1})
+	if err != nil {
+		s.logger.Error("Failed to list scim groups", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to get provisioning settings", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TeamSCIMResponse{BaseURL: s.scimURL(""), Token: token, Groups: groups})
+}
+
+// HandleCreateTeamSCIMToken generates the team's SCIM token, replacing the
+// current one. The token is only shown in this response.
+// Route: POST /api/team/scim/token
+func (s *Server) HandleCreateTeamSCIMToken(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	token, err := s.db.CreateSCIMToken(ctx, teamID, userID)
+	if err != nil {
+		s.logger.Error("Failed to create scim token", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to create provisioning token", "internal_error")
+		return
+	}
+
+	s.logger.Info("SCIM token created", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	respondJSON(w, http.StatusCreated, token)
+}
+
+// HandleDeleteTeamSCIMToken revokes the team's SCIM token
+// Route: DELETE /api/team/scim/token
+func (s *Server) HandleDeleteTeamSCIMToken(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteSCIMToken(ctx, teamID); err != nil {
+		if errors.Is(err, db.ErrSCIMTokenNotFound) {
+			respondError(w, http.StatusNotFound, "provisioning is not set up", "not_found")
+			return
+		}
+		s.logger.Error("Failed to delete scim token", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to revoke provisioning token", "internal_error")
+		return
+	}
+
+	s.logger.Info("SCIM token revoked", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleUpdateSCIMGroupMapping sets the team role and project access a SCIM
+// group's members get
+// Route: PUT /api/team/scim/groups/{groupId}
+func (s *Server) HandleUpdateSCIMGroupMapping(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+	groupID, err := strconv.ParseInt(chi.URLParam(r, "groupId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid group ID", "invalid_input")
+		return
+	}
+
+	var req SCIMGroupMappingRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+	if req.TeamRole != "member" && req.TeamRole != "admin" {
+		respondError(w, http.StatusBadRequest, "team_role must be member or admin", "invalid_input")
+		return
+	}
+	seen := map[int64]bool{}
+	for _, p := range req.Projects {
+		if !db.ValidSCIMProjectRole(p.Role) {
+			respondError(w, http.StatusBadRequest, "project role must be viewer, member or editor", "invalid_input")
+			return
+		}
+		if seen[p.ProjectID] {
+			respondError(w, http.StatusBadRequest, "each project can only be mapped once", "invalid_input")
+			return
+		}
+		seen[p.ProjectID] = true
+		exists, err := s.db.Client.Project.Query().
+			Where(project.ID(p.ProjectID), project.TeamID(teamID)).
+			Exist(ctx)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check project", "internal_error")
+			return
+		}
+		if !exists {
+			respondError(w, http.StatusBadRequest, "project not found in your team", "invalid_input")
+			return
+		}
+	}
+
+	group, err := s.db.SetSCIMGroupMapping(ctx, teamID, groupID, req.TeamRole, req.Projects)
+	if err != nil {
+		if errors.Is(err, db.ErrSCIMGroupNotFound) {
+			respondError(w, http.StatusNotFound, "group not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to map scim group", zap.Error(err), zap.Int64("group_id", groupID))
+		respondError(w, http.StatusInternalServerError, "failed to update group", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, group)
+}
+
+// SCIMAuth authenticates an identity provider by its team's SCIM token and
+// puts the team in the request context.
+func (s *Server) SCIMAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			respondSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "Missing bearer token"))
+			return
+		}
+		teamID, err := s.db.AuthenticateSCIMToken(r.Context(), token)
+		if err != nil {
+			if !errors.Is(err, db.ErrSCIMTokenNotFound) {
+				s.logger.Error("Failed to validate scim token", zap.Error(err))
+			}
+			respondSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "Invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), SCIMTeamIDKey, teamID)))
+	})
+}
+
+func respondSCIM(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		pkgLogger.Error("Error encoding SCIM response", zap.Error(err))
+	}
+}
+
+func respondSCIMError(w http.ResponseWriter, err *scim.Error) {
+	respondSCIM(w, err.StatusCode(), err)
+}
+
+// scimError maps an error from the SCIM store to a SCIM error response
+func (s *Server) scimError(w http.ResponseWriter, err error) {
+	var scimErr *scim.Error
+	switch {
+	case errors.As(err, &scimErr):
+		respondSCIMError(w, scimErr)
+	case errors.Is(err, db.ErrSCIMUserNotFound):
+		respondSCIMError(w, scim.NewError(http.StatusNotFound, "", "User not found"))
+	case errors.Is(err, db.ErrSCIMGroupNotFound):
+		respondSCIMError(w, scim.NewError(http.StatusNotFound, "", "Group not found"))
+	case errors.Is(err, db.ErrSCIMUserExists):
+		respondSCIMError(w, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "User is already a team member"))
+	case errors.Is(err, db.ErrSCIMAccountOutsideDomains):
+		respondSCIMError(w, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "An account outside the team's verified domains uses this email address"))
+	case errors.Is(err, db.ErrSCIMEmailTaken):
+		respondSCIMError(w, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "Email address is already in use"))
+	case errors.Is(err, db.ErrSCIMGroupExists):
+		respondSCIMError(w, scim.NewError(http.StatusConflict, scim.ErrUniqueness, "A group with this displayName already exists"))
+	case errors.Is(err, db.ErrSCIMTeamOwner):
+		respondSCIMError(w, scim.BadRequest(scim.ErrMutability, "The team owner can't be deactivated or removed"))
+	case errors.Is(err, db.ErrSCIMInvalidMember):
+		respondSCIMError(w, scim.BadRequest(scim.ErrInvalidValue, "Group members must be users of the team"))
+	default:
+		s.logger.Error("SCIM request failed", zap.Error(err))
+		respondSCIMError(w, scim.NewError(http.StatusInternalServerError, "", "Internal error"))
+	}
+}
+
+func readSCIMBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSCIMBody))
+	if err != nil {
+		return nil, scim.NewError(http.StatusRequestEntityTooLarge, "", "Request body is too large")
+	}
+	return body, nil
+}
+
+// scimResourceID parses the {id} of a SCIM resource. IDs that can't exist
+// are reported as not found.
+func scimResourceID(r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func (s *Server) scimUser(u *db.SCIMUser) *scim.User {
+	id := strconv.FormatInt(u.ID, 10)
+	su := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  u.ExternalID,
+		UserName:    u.Email,
+		DisplayName: strings.TrimSpace(u.FirstName + " " + u.LastName),
+		Emails:      []scim.Email{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      u.Active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+			Location:     s.scimURL("/Users/" + id),
+		},
+	}
+	if u.FirstName != "" || u.LastName != "" {
+		su.Name = &scim.Name{Formatted: su.DisplayName, GivenName: u.FirstName, FamilyName: u.LastName}
+	}
+	for _, g := range u.Groups {
+		groupID := strconv.FormatInt(g.ID, 10)
+		su.Groups = append(su.Groups, scim.Reference{
+			Value:   groupID,
+			Display: g.DisplayName,
+			Ref:     s.scimURL("/Groups/" + groupID),
+		})
+	}
+	return su
+}
+
+// scimUserInput takes what we store from a SCIM user. The email address
+// is the primary email, or the userName if no emails are given.
+func scimUserInput(u *scim.User) (db.SCIMUserInput, error) {
+	in := db.SCIMUserInput{
+		Email:      strings.ToLower(strings.TrimSpace(u.Email())),
+		ExternalID: u.ExternalID,
+		Active:     u.Active,
+	}
+	if !isValidEmail(in.Email) {
+		return in, scim.BadRequest(scim.ErrInvalidValue, "The user needs an email address as userName or in emails")
+	}
+	if u.Name != nil {
+		in.FirstName, in.LastName = strings.TrimSpace(u.Name.GivenName), strings.TrimSpace(u.Name.FamilyName)
+	}
+	if in.FirstName == "" && in.LastName == "" && u.DisplayName != "" {
+		first, last, _ := strings.Cut(strings.TrimSpace(u.DisplayName), " ")
+		in.FirstName, in.LastName = first, strings.TrimSpace(last)
+	}
+	return in, nil
+}
+
+// HandleSCIMServiceProviderConfig describes the SCIM features supported
+// Route: GET /api/scim/v2/ServiceProviderConfig
+func (s *Server) HandleSCIMServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := scim.NewServiceProviderConfig()
+	cfg.Meta = &scim.Meta{ResourceType: "ServiceProviderConfig", Location: s.scimURL("/ServiceProviderConfig")}
+	respondSCIM(w, http.StatusOK, cfg)
+}
+
+// HandleSCIMResourceTypes lists the SCIM resource types
+// Route: GET /api/scim/v2/ResourceTypes
+func (s *Server) HandleSCIMResourceTypes(w http.ResponseWriter, r *http.Request) {
+	types := scim.ResourceTypes(s.scimURL(""))
+	resources := make([]interface{}, len(types))
+	for i := range types {
+		resources[i] = types[i]
+	}
+	respondSCIM(w, http.StatusOK, scim.NewListResponse(resources, len(resources), 1))
+}
+
+// scimQuery reads the filter and paging parameters of a list request.
+// attrs maps the filterable attributes to the query field they set.
+func scimQuery(r *http.Request, attrs map[string]func(q *db.SCIMQuery, value string)) (db.SCIMQuery, int, error) {
+	startIndex, count, err := scim.ParsePagination(r.URL.Query())
+	if err != nil {
+		return db.SCIMQuery{}, 0, err
+	}
+	q := db.SCIMQuery{Offset: startIndex - 1, Limit: count}
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		f, err := scim.ParseFilter(filter)
+		if err != nil {
+			return q, 0, err
+		}
+		set, ok := attrs[f.Attribute]
+		if !ok {
+			return q, 0, scim.BadRequest(scim.ErrInvalidFilter, "Filtering on "+f.Attribute+" is not supported")
+		}
+		set(&q, f.Value)
+	}
+	return q, startIndex, nil
+}
+
+var scimUserFilters = map[string]func(q *db.SCIMQuery, value string){
+	"username":     func(q *db.SCIMQuery, v string) { q.Email = v },
+	"emails":       func(q *db.SCIMQuery, v string) { q.Email = v },
+	"emails.value": func(q *db.SCIMQuery, v string) { q.Email = v },
+	"externalid":   func(q *db.SCIMQuery, v string) { q.ExternalID = v },
+}
+
+var scimGroupFilters = map[string]func(q *db.SCIMQuery, value string){
+	"displayname": func(q *db.SCIMQuery, v string) { q.DisplayName = v },
+	"externalid":  func(q *db.SCIMQuery, v string) { q.ExternalID = v },
+}
+
+// HandleSCIMListUsers lists the team's users
+// Route: GET /api/scim/v2/Users
+func (s *Server) HandleSCIMListUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	q, startIndex, err := scimQuery(r, scimUserFilters)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	users, total, err := s.db.ListSCIMUsers(ctx, teamID, q)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	resources := make([]interface{}, len(users))
+	for i := range users {
+		resources[i] = s.scimUser(&users[i])
+	}
+	respondSCIM(w, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// HandleSCIMGetUser returns one of the team's users
+// Route: GET /api/scim/v2/Users/{id}
+func (s *Server) HandleSCIMGetUser(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	userID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMUserNotFound)
+		return
+	}
+
+	u, err := s.db.GetSCIMUser(ctx, teamID, userID)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	respondSCIM(w, http.StatusOK, s.scimUser(u))
+}
+
+// HandleSCIMCreateUser provisions a user into the team
+// Route: POST /api/scim/v2/Users
+func (s *Server) HandleSCIMCreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	body, err := readSCIMBody(w, r)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	su, err := scim.ParseUser(body)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	in, err := scimUserInput(su)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	u, err := s.db.CreateSCIMUser(ctx, teamID, in)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	s.logger.Info("SCIM user provisioned", zap.Int64("team_id", teamID), zap.Int64("user_id", u.ID), zap.Bool("managed", u.Managed))
+	respondSCIM(w, http.StatusCreated, s.scimUser(u))
+}
+
+// HandleSCIMReplaceUser replaces a user
+// Route: PUT /api/scim/v2/Users/{id}
+func (s *Server) HandleSCIMReplaceUser(w http.ResponseWriter, r *http.Request) {
+	s.updateSCIMUser(w, r, func(_ *scim.User, body []byte) (*scim.User, error) {
+		return scim.ParseUser(body)
+	})
+}
+
+// HandleSCIMPatchUser updates some of a user's attributes
+// Route: PATCH /api/scim/v2/Users/{id}
+func (s *Server) HandleSCIMPatchUser(w http.ResponseWriter, r *http.Request) {
+	s.updateSCIMUser(w, r, func(su *scim.User, body []byte) (*scim.User, error) {
+		patch, err := scim.ParsePatch(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := su.ApplyPatch(patch.Operations); err != nil {
+			return nil, err
+		}
+		return su, nil
+	})
+}
+
+// updateSCIMUser loads a user, lets update compute the new version from the
+// request body, and stores it. Deactivating a user takes effect at once.
+func (s *Server) updateSCIMUser(w http.ResponseWriter, r *http.Request, update func(su *scim.User, body []byte) (*scim.User, error)) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	userID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMUserNotFound)
+		return
+	}
+	body, err := readSCIMBody(w, r)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	current, err := s.db.GetSCIMUser(ctx, teamID, userID)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	su, err := update(s.scimUser(current), body)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	in, err := scimUserInput(su)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	u, err := s.db.UpdateSCIMUser(ctx, teamID, userID, in)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	if current.Active != u.Active {
+		s.logger.Info("SCIM user active changed", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Bool("active", u.Active))
+	}
+	respondSCIM(w, http.StatusOK, s.scimUser(u))
+}
+
+// HandleSCIMDeleteUser removes a user from the team
+// Route: DELETE /api/scim/v2/Users/{id}
+func (s *Server) HandleSCIMDeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	userID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMUserNotFound)
+		return
+	}
+
+	if err := s.db.DeleteSCIMUser(ctx, teamID, userID); err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	s.logger.Info("SCIM user removed", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) scimGroup(g *db.SCIMGroup, withMembers bool) *scim.Group {
+	id := strconv.FormatInt(g.ID, 10)
+	sg := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+			Location:     s.scimURL("/Groups/" + id),
+		},
+	}
+	if withMembers {
+		sg.Members = []scim.Reference{}
+		for _, m := range g.Members {
+			userID := strconv.FormatInt(m.UserID, 10)
+			sg.Members = append(sg.Members, scim.Reference{
+				Value:   userID,
+				Display: m.Email,
+				Ref:     s.scimURL("/Users/" + userID),
+			})
+		}
+	}
+	return sg
+}
+
+// scimMemberIDs parses the user IDs of a group's members
+func scimMemberIDs(g *scim.Group) ([]int64, error) {
+	ids := make([]int64, 0, len(g.Members))
+	for _, m := range g.Members {
+		id, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return nil, db.ErrSCIMInvalidMember
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// excludesMembers reports whether a request asked to leave group members
+// out, as identity providers do when they only check that a group exists
+func excludesMembers(r *http.Request) bool {
+	for _, attr := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleSCIMListGroups lists the team's SCIM groups
+// Route: GET /api/scim/v2/Groups
+func (s *Server) HandleSCIMListGroups(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	q, startIndex, err := scimQuery(r, scimGroupFilters)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	groups, total, err := s.db.ListSCIMGroups(ctx, teamID, q)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	withMembers := !excludesMembers(r)
+	resources := make([]interface{}, len(groups))
+	for i := range groups {
+		resources[i] = s.scimGroup(&groups[i], withMembers)
+	}
+	respondSCIM(w, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+}
+
+// HandleSCIMGetGroup returns one of the team's SCIM groups
+// Route: GET /api/scim/v2/Groups/{id}
+func (s *Server) HandleSCIMGetGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	groupID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMGroupNotFound)
+		return
+	}
+
+	g, err := s.db.GetSCIMGroup(ctx, teamID, groupID)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	respondSCIM(w, http.StatusOK, s.scimGroup(g, !excludesMembers(r)))
+}
+
+// HandleSCIMCreateGroup creates a group. It grants the member role and no
+// projects until the team owner maps it.
+// Route: POST /api/scim/v2/Groups
+func (s *Server) HandleSCIMCreateGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	body, err := readSCIMBody(w, r)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	sg, err := scim.ParseGroup(body)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	memberIDs, err := scimMemberIDs(sg)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	g, err := s.db.CreateSCIMGroup(ctx, teamID, sg.DisplayName, sg.ExternalID, memberIDs)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	s.logger.Info("SCIM group created", zap.Int64("team_id", teamID), zap.Int64("group_id", g.ID))
+	respondSCIM(w, http.StatusCreated, s.scimGroup(g, true))
+}
+
+// HandleSCIMReplaceGroup replaces a group's name and members
+// Route: PUT /api/scim/v2/Groups/{id}
+func (s *Server) HandleSCIMReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	s.updateSCIMGroup(w, r, func(_ *scim.Group, body []byte) (*scim.Group, error) {
+		return scim.ParseGroup(body)
+	})
+}
+
+// HandleSCIMPatchGroup renames a group or adds and removes members
+// Route: PATCH /api/scim/v2/Groups/{id}
+func (s *Server) HandleSCIMPatchGroup(w http.ResponseWriter, r *http.Request) {
+	s.updateSCIMGroup(w, r, func(sg *scim.Group, body []byte) (*scim.Group, error) {
+		patch, err := scim.ParsePatch(body)
+		if err != nil {
+			return nil, err
+		}
+		if err := sg.ApplyPatch(patch.Operations); err != nil {
+			return nil, err
+		}
+		return sg, nil
+	})
+}
+
+// updateSCIMGroup loads a group, lets update compute the new version from
+// the request body, and stores it along with the access it grants.
+func (s *Server) updateSCIMGroup(w http.ResponseWriter, r *http.Request, update func(sg *scim.Group, body []byte) (*scim.Group, error)) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	groupID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMGroupNotFound)
+		return
+	}
+	body, err := readSCIMBody(w, r)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	current, err := s.db.GetSCIMGroup(ctx, teamID, groupID)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	sg, err := update(s.scimGroup(current, true), body)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	memberIDs, err := scimMemberIDs(sg)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	g, err := s.db.UpdateSCIMGroup(ctx, teamID, groupID, sg.DisplayName, sg.ExternalID, memberIDs)
+	if err != nil {
+		s.scimError(w, err)
+		return
+	}
+	respondSCIM(w, http.StatusOK, s.scimGroup(g, true))
+}
+
+// HandleSCIMDeleteGroup deletes a group and takes back what it granted
+// Route: DELETE /api/scim/v2/Groups/{id}
+func (s *Server) HandleSCIMDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	teamID := r.Context().Value(SCIMTeamIDKey).(int64)
+	groupID, ok := scimResourceID(r)
+	if !ok {
+		s.scimError(w, db.ErrSCIMGroupNotFound)
+		return
+	}
+
+	if err := s.db.DeleteSCIMGroup(ctx, teamID, groupID); err != nil {
+		s.scimError(w, err)
+		return
+	}
+
+	s.logger.Info("SCIM group deleted", zap.Int64("team_id", teamID), zap.Int64("group_id", groupID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondDeactivated refuses a login to an account its identity provider
+// deactivated. It reports whether it did.
+func (s *Server) respondDeactivated(ctx context.Context, w http.ResponseWriter, userID int64) bool {
+	deactivated, err := s.db.IsUserDeactivated(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to check deactivated user", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return true
+	}
+	if !deactivated {
+		return false
+	}
+	respondError(w, http.StatusForbidden, "your account has been deactivated by your team", "account_deactivated")
+	return true
+}
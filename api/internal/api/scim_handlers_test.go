@@ -0,0 +1,420 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"taskai/internal/db"
+	"taskai/internal/scim"
+)
+
+// scimRequest calls the SCIM API with a team's bearer token
+func scimRequest(t *testing.T, ts *TestServer, token, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, "/api/scim/v2"+path, nil)
+	} else {
+		req = httptest.NewRequest(method, "/api/scim/v2"+path, strings.NewReader(body))
+		req.Header.Set("Content-Type", scim.ContentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	buildTestRouter(ts.Server).ServeHTTP(rec, req)
+	return rec
+}
+
+// assertSCIMError checks a SCIM error response
+func assertSCIMError(t *testing.T, rec *httptest.ResponseRecorder, wantCode int, wantType string) {
+	t.Helper()
+
+	AssertStatusCode(t, rec.Code, wantCode)
+	var e scim.Error
+	DecodeJSON(t, rec, &e)
+	if len(e.Schemas) != 1 || e.Schemas[0] != scim.SchemaError || e.ScimType != wantType {
+		t.Errorf("Expected a %q SCIM error, got %+v", wantType, e)
+	}
+}
+
+// setupSCIM creates a team with a SCIM token and returns the owner, team
+// and token
+func setupSCIM(t *testing.T, ts *TestServer) (int64, int64, string) {
+	t.Helper()
+
+	ts.config.AppURL = testAppURL
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	teamID := createTestTeam(t, ts, ownerID, "Corp")
+
+	rec, r := ts.MakeAuthRequest(t, http.MethodPost, "/api/team/scim/token", nil, ownerID, nil)
+	ts.HandleCreateTeamSCIMToken(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var token db.SCIMTokenWithSecret
+	DecodeJSON(t, rec, &token)
+	return ownerID, teamID, token.Token
+}
+
+// provisionUser creates a user through SCIM and returns its ID
+func provisionUser(t *testing.T, ts *TestServer, token, email string) string {
+	t.Helper()
+
+	rec := scimRequest(t, ts, token, http.MethodPost, "/Users", fmt.Sprintf(`{
+		"schemas":["%s"],"userName":%q,"externalId":"ext-%s",
+		"name":{"givenName":"Jane","familyName":"Doe"},
+		"emails":[{"value":%q,"type":"work","primary":true}],"active":true}`,
+		scim.SchemaUser, email, email, email))
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var u scim.User
+	DecodeJSON(t, rec, &u)
+	if u.ID == "" || u.UserName != email || !u.Active {
+		t.Fatalf("Unexpected user: %+v", u)
+	}
+	return u.ID
+}
+
+func TestSCIMToken(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ts.config.AppURL = testAppURL
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+	teamID := createTestTeam(t, ts, ownerID, "Corp")
+	addTeamMember(t, ts, teamID, adminID, "admin")
+
+	rec, r := ts.MakeAuthRequest(t, http.MethodGet, "/api/team/scim", nil, ownerID, nil)
+	ts.HandleGetTeamSCIM(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var setup TeamSCIMResponse
+	DecodeJSON(t, rec, &setup)
+	if setup.Token != nil || setup.BaseURL != testAppURL+"/api/scim/v2" {
+		t.Errorf("Expected no token and the SCIM base URL, got %+v", setup)
+	}
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodPost, "/api/team/scim/token", nil, adminID, nil)
+	ts.HandleCreateTeamSCIMToken(rec, r)
+	AssertError(t, rec, http.StatusForbidden, "team owner", "forbidden")
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodPost, "/api/team/scim/token", nil, ownerID, nil)
+	ts.HandleCreateTeamSCIMToken(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var first db.SCIMTokenWithSecret
+	DecodeJSON(t, rec, &first)
+	if !strings.HasPrefix(first.Token, "scim_") || !strings.HasPrefix(first.Token, first.TokenPrefix) {
+		t.Fatalf("Unexpected token %+v", first)
+	}
+
+	rec = scimRequest(t, ts, first.Token, http.MethodGet, "/ServiceProviderConfig", "")
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != scim.ContentType {
+		t.Errorf("Expected %s, got %s", scim.ContentType, ct)
+	}
+
+	assertSCIMError(t, scimRequest(t, ts, "", http.MethodGet, "/Users", ""), http.StatusUnauthorized, "")
+	assertSCIMError(t, scimRequest(t, ts, "scim_wrong", http.MethodGet, "/Users", ""), http.StatusUnauthorized, "")
+
+	// A new token replaces the old one
+	rec, r = ts.MakeAuthRequest(t, http.MethodPost, "/api/team/scim/token", nil, ownerID, nil)
+	ts.HandleCreateTeamSCIMToken(rec, r)
+	var second db.SCIMTokenWithSecret
+	DecodeJSON(t, rec, &second)
+	assertSCIMError(t, scimRequest(t, ts, first.Token, http.MethodGet, "/Users", ""), http.StatusUnauthorized, "")
+	AssertStatusCode(t, scimRequest(t, ts, second.Token, http.MethodGet, "/Users", "").Code, http.StatusOK)
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodGet, "/api/team/scim", nil, ownerID, nil)
+	ts.HandleGetTeamSCIM(rec, r)
+	DecodeJSON(t, rec, &setup)
+	if setup.Token == nil || setup.Token.TokenPrefix != second.TokenPrefix || setup.Token.LastUsedAt == nil {
+		t.Errorf("Expected the used token, got %+v", setup.Token)
+	}
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodDelete, "/api/team/scim/token", nil, ownerID, nil)
+	ts.HandleDeleteTeamSCIMToken(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusNoContent)
+	assertSCIMError(t, scimRequest(t, ts, second.Token, http.MethodGet, "/Users", ""), http.StatusUnauthorized, "")
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodDelete, "/api/team/scim/token", nil, ownerID, nil)
+	ts.HandleDeleteTeamSCIMToken(rec, r)
+	AssertError(t, rec, http.StatusNotFound, "not set up", "not_found")
+}
+
+func TestSCIMUserProvisioning(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ctx := context.Background()
+	ownerID, teamID, token := setupSCIM(t, ts)
+	id := provisionUser(t, ts, token, "jane@example.com")
+
+	var userID, managedBy int64
+	var provider string
+	if err := ts.DB.QueryRowContext(ctx,
+		`SELECT id, auth_provider, COALESCE(managed_by_team_id, 0) FROM users WHERE email = ?`, "jane@example.com",
+	).Scan(&userID, &provider, &managedBy); err != nil {
+		t.Fatalf("Provisioned user not found: %v", err)
+	}
+	if fmt.Sprint(userID) != id || provider != db.AuthProviderSCIM || managedBy != teamID {
+		t.Errorf("Expected a SCIM account managed by team %d, got id %d, provider %q, team %d", teamID, userID, provider, managedBy)
+	}
+	if role, err := ts.getUserTeamRole(ctx, userID, teamID); err != nil || role != "member" {
+		t.Errorf("Expected a team member, got %q (%v)", role, err)
+	}
+
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodPost, "/Users",
+		`{"userName":"JANE@example.com"}`), http.StatusConflict, scim.ErrUniqueness)
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodPost, "/Users",
+		`{"userName":"not-an-email"}`), http.StatusBadRequest, scim.ErrInvalidValue)
+
+	rec := scimRequest(t, ts, token, http.MethodGet, `/Users?filter=userName+eq+%22Jane@Example.com%22`, "")
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var list struct {
+		TotalResults int         `json:"totalResults"`
+		Resources    []scim.User `json:"Resources"`
+	}
+	DecodeJSON(t, rec, &list)
+	if list.TotalResults != 1 || len(list.Resources) != 1 || list.Resources[0].ID != id {
+		t.Errorf("Expected the provisioned user, got %+v", list)
+	}
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodGet, `/Users?filter=title+eq+%22x%22`, ""),
+		http.StatusBadRequest, scim.ErrInvalidFilter)
+
+	// The identity provider controls the profile of accounts it created
+	rec = scimRequest(t, ts, token, http.MethodPatch, "/Users/"+id, `{"schemas":["`+scim.SchemaPatchOp+`"],
+		"Operations":[{"op":"Replace","path":"name.familyName","value":"Smith"}]}`)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var lastName string
+	ts.DB.QueryRowContext(ctx, `SELECT last_name FROM users WHERE id = ?`, userID).Scan(&lastName)
+	if lastName != "Smith" {
+		t.Errorf("Expected the last name to change, got %q", lastName)
+	}
+
+	// Deactivation signs the user out everywhere and takes away their access
+	projectID := ts.CreateTestProject(t, ownerID, "Roadmap")
+	ts.DB.ExecContext(ctx, `UPDATE projects SET team_id = ? WHERE id = ?`, teamID, projectID)
+	ts.AddProjectMember(t, projectID, userID, ownerID, "member")
+	ts.DB.ExecContext(ctx, `UPDATE users SET password_hash = (SELECT password_hash FROM users WHERE id = ?) WHERE id = ?`, ownerID, userID)
+	if _, err := ts.DB.CreateAPIKey(ctx, userID, "cli", nil); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	session := ts.GenerateTestToken(t, userID, "jane@example.com")
+
+	rec = scimRequest(t, ts, token, http.MethodPatch, "/Users/"+id,
+		`{"Operations":[{"op":"Replace","path":"active","value":"False"}]}`)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var u scim.User
+	DecodeJSON(t, rec, &u)
+	if u.Active {
+		t.Error("Expected the user to be inactive")
+	}
+
+	rec, r := MakeRequest(t, http.MethodPost, "/api/auth/login",
+		LoginRequest{Email: "jane@example.com", Password: "password123"}, nil)
+	ts.HandleLogin(rec, r)
+	AssertError(t, rec, http.StatusForbidden, "deactivated", "account_deactivated")
+	if code := ts.authStatus(t, session, func(w http.ResponseWriter, r *http.Request) {}); code != http.StatusUnauthorized {
+		t.Errorf("Expected the session to be revoked, got %d", code)
+	}
+	if keys, _ := ts.DB.GetAPIKeysByUserID(ctx, userID); len(keys) != 0 {
+		t.Errorf("Expected API keys to be deleted, got %d", len(keys))
+	}
+	var grants int
+	ts.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM project_members WHERE user_id = ? AND project_id = ?`, userID, projectID).Scan(&grants)
+	if grants != 0 {
+		t.Error("Expected project access to be removed")
+	}
+
+	rec = scimRequest(t, ts, token, http.MethodPatch, "/Users/"+id,
+		`{"Operations":[{"op":"replace","value":{"active":true}}]}`)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	rec, r = MakeRequest(t, http.MethodPost, "/api/auth/login",
+		LoginRequest{Email: "jane@example.com", Password: "password123"}, nil)
+	ts.HandleLogin(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	// The owner is outside the identity provider's control
+	rec = scimRequest(t, ts, token, http.MethodPatch, fmt.Sprintf("/Users/%d", ownerID),
+		`{"Operations":[{"op":"replace","path":"active","value":false}]}`)
+	assertSCIMError(t, rec, http.StatusBadRequest, scim.ErrMutability)
+
+	AssertStatusCode(t, scimRequest(t, ts, token, http.MethodDelete, "/Users/"+id, "").Code, http.StatusNoContent)
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodGet, "/Users/"+id, ""), http.StatusNotFound, "")
+	if deactivated, _ := ts.DB.IsUserDeactivated(ctx, userID); !deactivated {
+		t.Error("Expected the deleted user to be deactivated")
+	}
+}
+
+func TestSCIMAdoptsExistingAccount(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ctx := context.Background()
+	ownerID, teamID, token := setupSCIM(t, ts)
+	userID := ts.CreateTestUser(t, "bob@example.com", "password123")
+	ts.DB.ExecContext(ctx, `UPDATE users SET first_name = 'Bob' WHERE id = ?`, userID)
+
+	// Accounts are only adopted in domains the team proved it controls
+	adopt := `{"userName":"bob@example.com","name":{"givenName":"Robert"},"externalId":"00u2"}`
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodPost, "/Users", adopt), http.StatusConflict, scim.ErrUniqueness)
+	if _, err := ts.getUserTeamRole(ctx, userID, teamID); err == nil {
+		t.Fatal("Expected the account to stay outside the team")
+	}
+	if _, err := ts.DB.ExecContext(ctx,
+		`INSERT INTO team_sso_domains (team_id, domain, token, verified_at) VALUES (?, 'example.com', 'x', CURRENT_TIMESTAMP)`, teamID); err != nil {
+		t.Fatalf("Failed to verify domain: %v", err)
+	}
+
+	rec := scimRequest(t, ts, token, http.MethodPost, "/Users", adopt)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var u scim.User
+	DecodeJSON(t, rec, &u)
+	if u.ID != fmt.Sprint(userID) || u.ExternalID != "00u2" {
+		t.Errorf("Expected the existing account, got %+v", u)
+	}
+
+	// Only the membership of an account the team didn't create is managed
+	var firstName string
+	ts.DB.QueryRowContext(ctx, `SELECT first_name FROM users WHERE id = ?`, userID).Scan(&firstName)
+	if firstName != "Bob" {
+		t.Errorf("Expected the profile to be left alone, got %q", firstName)
+	}
+
+	// Deactivating signs the user out and deletes keys for the team's
+	// projects, but leaves the account usable outside the team
+	projectID := ts.CreateTestProject(t, ownerID, "Roadmap")
+	ts.DB.ExecContext(ctx, `UPDATE projects SET team_id = ? WHERE id = ?`, teamID, projectID)
+	teamKey, err := ts.DB.CreateScopedAPIKey(ctx, userID, "team", nil, []string{"read"}, []int64{projectID})
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	otherKey, err := ts.DB.CreateAPIKey(ctx, userID, "other", nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	session := ts.GenerateTestToken(t, userID, "bob@example.com")
+
+	rec = scimRequest(t, ts, token, http.MethodPatch, "/Users/"+u.ID,
+		`{"Operations":[{"op":"replace","path":"active","value":false}]}`)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	if deactivated, _ := ts.DB.IsUserDeactivated(ctx, userID); deactivated {
+		t.Error("Expected the account to stay usable outside the team")
+	}
+	if code := ts.authStatus(t, session, func(w http.ResponseWriter, r *http.Request) {}); code != http.StatusUnauthorized {
+		t.Errorf("Expected the session to be revoked, got %d", code)
+	}
+	keys, _ := ts.DB.GetAPIKeysByUserID(ctx, userID)
+	if len(keys) != 1 || keys[0].ID != otherKey.ID || keys[0].ID == teamKey.ID {
+		t.Errorf("Expected only the key for the team's projects to be deleted, got %+v", keys)
+	}
+	var status string
+	ts.DB.QueryRowContext(ctx, `SELECT status FROM team_members WHERE team_id = ? AND user_id = ?`, teamID, userID).Scan(&status)
+	if status == "active" {
+		t.Error("Expected the team membership to be inactive")
+	}
+	rec, r := MakeRequest(t, http.MethodPost, "/api/auth/login",
+		LoginRequest{Email: "bob@example.com", Password: "password123"}, nil)
+	ts.HandleLogin(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+}
+
+func TestSCIMGroups(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ctx := context.Background()
+	ownerID, teamID, token := setupSCIM(t, ts)
+	id := provisionUser(t, ts, token, "jane@example.com")
+	var userID int64
+	fmt.Sscan(id, &userID)
+
+	roadmap := ts.CreateTestProject(t, ownerID, "Roadmap")
+	manual := ts.CreateTestProject(t, ownerID, "Manual")
+	ts.DB.ExecContext(ctx, `UPDATE projects SET team_id = ? WHERE id IN (?, ?)`, teamID, roadmap, manual)
+	ts.AddProjectMember(t, manual, userID, ownerID, "viewer")
+	outside := ts.CreateTestProject(t, ownerID, "Elsewhere")
+
+	rec := scimRequest(t, ts, token, http.MethodPost, "/Groups",
+		fmt.Sprintf(`{"displayName":"Engineering","externalId":"g1","members":[{"value":%q}]}`, id))
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var g scim.Group
+	DecodeJSON(t, rec, &g)
+	if len(g.Members) != 1 || g.Members[0].Value != id {
+		t.Fatalf("Unexpected group %+v", g)
+	}
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodPost, "/Groups", `{"displayName":"engineering"}`),
+		http.StatusConflict, scim.ErrUniqueness)
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodPost, "/Groups",
+		fmt.Sprintf(`{"displayName":"Ops","members":[{"value":"%d"}]}`, userID+100)),
+		http.StatusBadRequest, scim.ErrInvalidValue)
+
+	mapping := func(req SCIMGroupMappingRequest) *httptest.ResponseRecorder {
+		rec, r := ts.MakeAuthRequest(t, http.MethodPut, "/api/team/scim/groups/"+g.ID, req, ownerID,
+			map[string]string{"groupId": g.ID})
+		ts.HandleUpdateSCIMGroupMapping(rec, r)
+		return rec
+	}
+	AssertError(t, mapping(SCIMGroupMappingRequest{TeamRole: "owner"}), http.StatusBadRequest, "team_role", "invalid_input")
+	AssertError(t, mapping(SCIMGroupMappingRequest{TeamRole: "member", Projects: []db.SCIMGroupProject{{ProjectID: outside, Role: "editor"}}}),
+		http.StatusBadRequest, "not found", "invalid_input")
+
+	rec = mapping(SCIMGroupMappingRequest{TeamRole: "admin", Projects: []db.SCIMGroupProject{
+		{ProjectID: roadmap, Role: "editor"}, {ProjectID: manual, Role: "editor"},
+	}})
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	access := func(projectID int64) (role string, managed bool) {
+		ts.DB.QueryRowContext(ctx, `SELECT role, scim_managed FROM project_members WHERE project_id = ? AND user_id = ?`,
+			projectID, userID).Scan(&role, &managed)
+		return role, managed
+	}
+	if role, _ := ts.getUserTeamRole(ctx, userID, teamID); role != "admin" {
+		t.Errorf("Expected the group to make the user an admin, got %q", role)
+	}
+	if role, managed := access(roadmap); role != "editor" || !managed {
+		t.Errorf("Expected editor access from the group, got %q (managed %v)", role, managed)
+	}
+	if role, managed := access(manual); role != "viewer" || managed {
+		t.Errorf("Expected access granted by hand to be kept, got %q (managed %v)", role, managed)
+	}
+
+	rec = scimRequest(t, ts, token, http.MethodPatch, "/Groups/"+g.ID,
+		fmt.Sprintf(`{"Operations":[{"op":"remove","path":"members[value eq \"%s\"]"}]}`, id))
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	if role, _ := ts.getUserTeamRole(ctx, userID, teamID); role != "member" {
+		t.Errorf("Expected leaving the group to take admin away, got %q", role)
+	}
+	if role, _ := access(roadmap); role != "" {
+		t.Errorf("Expected group access to be taken back, got %q", role)
+	}
+	if role, _ := access(manual); role != "viewer" {
+		t.Errorf("Expected access granted by hand to be kept, got %q", role)
+	}
+
+	rec = scimRequest(t, ts, token, http.MethodGet, `/Groups?filter=displayName+eq+%22ENGINEERING%22`, "")
+	var list struct {
+		TotalResults int          `json:"totalResults"`
+		Resources    []scim.Group `json:"Resources"`
+	}
+	DecodeJSON(t, rec, &list)
+	if list.TotalResults != 1 || list.Resources[0].ID != g.ID || len(list.Resources[0].Members) != 0 {
+		t.Errorf("Expected the empty group, got %+v", list)
+	}
+
+	AssertStatusCode(t, scimRequest(t, ts, token, http.MethodDelete, "/Groups/"+g.ID, "").Code, http.StatusNoContent)
+	assertSCIMError(t, scimRequest(t, ts, token, http.MethodGet, "/Groups/"+g.ID, ""), http.StatusNotFound, "")
+
+	var body json.RawMessage
+	rec, r := ts.MakeAuthRequest(t, http.MethodGet, "/api/team/scim", nil, ownerID, nil)
+	ts.HandleGetTeamSCIM(rec, r)
+	DecodeJSON(t, rec, &body)
+	if !strings.Contains(string(body), `"groups":[]`) {
+		t.Errorf("Expected no groups, got %s", body)
+	}
+}
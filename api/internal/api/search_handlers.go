@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"taskai/ent"
+	"taskai/ent/predicate"
 	"taskai/ent/project"
 	"taskai/ent/task"
 	"taskai/ent/wikiblock"
@@ -172,6 +174,9 @@ func (s *Server) HandleGlobalSearch(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "failed to search", "internal_error")
 		return
 	}
+	if req.ProjectID != nil && !slices.Contains(accessibleProjects, *req.ProjectID) {
+		accessibleProjects = nil
+	}
 
 	if len(accessibleProjects) == 0 {
 		respondJSON(w, http.StatusOK, GlobalSearchResponse{
@@ -203,13 +208,25 @@ func (s *Server) searchTasks(ctx context.Context, req GlobalSearchRequest, acces
 
 // searchTasksSQLite uses Ent ORM with ContainsFold (LIKE) for SQLite
 func (s *Server) searchTasksSQLite(ctx context.Context, req GlobalSearchRequest, accessibleProjects []int64, projectNameMap map[int64]string) ([]SearchTaskResult, error) {
+	projectIDs := accessibleProjects
+	if req.ProjectID != nil {
+		projectIDs = []int64{*req.ProjectID}
+	}
+	matches := []predicate.Task{
+		task.TitleContainsFold(req.Query),
+		task.DescriptionContainsFold(req.Query),
+	}
+	// Custom field values live outside the ent schema
+	fieldMatches, err := s.db.SearchCustomFieldTasks(ctx, req.Query, projectIDs, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldMatches) > 0 {
+		matches = append(matches, task.IDIn(fieldMatches...))
+	}
+
 	query := s.db.Client.Task.Query().
-		Where(
-			task.Or(
-				task.TitleContainsFold(req.Query),
-				task.DescriptionContainsFold(req.Query),
-			),
-		)
+		Where(task.Or(matches...))
 
 	// Filter by project
 	if req.ProjectID != nil {
@@ -265,6 +282,12 @@ func (s *Server) searchTasksPostgres(ctx context.Context, req GlobalSearchReques
 		WHERE (
 			t.search_vector @@ plainto_tsquery('english', $1)
 			OR t.title ILIKE '%%' || $2 || '%%'
+			OR EXISTS (
+				SELECT 1 FROM task_custom_field_values v
+				JOIN custom_fields f ON f.id = v.field_id
+				WHERE v.task_id = t.id AND f.field_type IN ('text', 'url', 'select', 'multi_select')
+				  AND v.value ILIKE '%%' || $2 || '%%'
+			)
 			%s
 		)
 		%s
@@ -8,6 +8,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"taskai/internal/db"
 )
 
 // ---------------------------------------------------------------------------
@@ -260,6 +262,36 @@ func TestHandleGlobalSearch(t *testing.T) {
 		}
 	})
 
+	t.Run("restricted API key only searches its projects", func(t *testing.T) {
+		ts := NewTestServer(t)
+		defer ts.Close()
+
+		userID := ts.CreateTestUser(t, "test@example.com", "password123")
+		allowed := ts.CreateTestProject(t, userID, "Allowed")
+		other := ts.CreateTestProject(t, userID, "Other")
+		ts.CreateTestTask(t, allowed, "Fix login bug")
+		ts.CreateTestTask(t, other, "Fix login page")
+		key := &db.APIKey{Scopes: []string{ScopeTasksRead}, ProjectIDs: []int64{allowed}}
+
+		search := func(body map[string]interface{}) GlobalSearchResponse {
+			t.Helper()
+			rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/search", body, userID, nil)
+			req = req.WithContext(context.WithValue(req.Context(), APIKeyKey, key))
+			ts.HandleGlobalSearch(rec, req)
+			AssertStatusCode(t, rec.Code, http.StatusOK)
+			var resp GlobalSearchResponse
+			DecodeJSON(t, rec, &resp)
+			return resp
+		}
+
+		if resp := search(map[string]interface{}{"query": "login"}); len(resp.Tasks) != 1 || resp.Tasks[0].ProjectID != allowed {
+			t.Errorf("expected only the allowed project's task, got %+v", resp.Tasks)
+		}
+		if resp := search(map[string]interface{}{"query": "login", "project_id": other}); len(resp.Tasks) != 0 {
+			t.Errorf("expected no tasks from a project the key doesn't allow, got %+v", resp.Tasks)
+		}
+	})
+
 	t.Run("case insensitive search", func(t *testing.T) {
 		ts := NewTestServer(t)
 		defer ts.Close()
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+
+	"taskai/ent"
+	"taskai/internal/db"
+	"taskai/internal/webauthn"
+)
+
+// Second factor methods offered after the password
+const (
+	SecondFactorTOTP       = "totp"
+	SecondFactorBackupCode = "backup_code"
+	SecondFactorWebAuthn   = "webauthn"
+)
+
+// SecondFactorChallengeResponse is returned instead of tokens when a user
+// with a second factor logs in with their password. The login is finished by
+// answering the challenge at /api/auth/2fa/verify.
+type SecondFactorChallengeResponse struct {
+	TwoFactorRequired bool     `json:"two_factor_required"`
+	Challenge         string   `json:"challenge"`
+	Methods           []string `json:"methods"`
+	// WebAuthn holds the options for navigator.credentials.get() when the
+	// user has security keys or passkeys; their challenge is Challenge
+	WebAuthn *webauthn.RequestOptions `json:"webauthn,omitempty"`
+}
+
+// SecondFactorRequest answers a login's second factor challenge with either a
+// TOTP or backup code or a WebAuthn assertion
+type SecondFactorRequest struct {
+	Challenge  string                      `json:"challenge"`
+	Code       string                      `json:"code,omitempty"`
+	Credential *webauthn.AssertionResponse `json:"credential,omitempty"`
+}
+
+// completeLogin finishes a login once every factor has been checked: it
+// clears failed logins, records the login and starts a session.
+func (s *Server) completeLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, entUser *ent.User, failedLogins int) {
+	if failedLogins > 0 {
+		if _, err := s.db.ClearLockout(ctx, entUser.ID); err != nil {
+			s.logger.Error("Failed to reset failed logins", zap.Error(err), zap.Int64("user_id", entUser.ID))
+		}
+	}
+
+	// Log successful login, alerting the user if it's from a new device or IP
+	s.recordLogin(ctx, entUser.ID, entUser.Email, getClientIP(r), r.UserAgent())
+
+	// Convert Ent user to API user struct
+	apiUser := entUserToAPI(entUser)
+
+	// Start a login session
+	tokens, err := s.startSession(ctx, r, apiUser.ID, apiUser.Email)
+	if err != nil {
+		s.logger.Error("Failed to start session", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to generate token", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         apiUser,
+	})
+}
+
+// respondSecondFactorRequired asks a user who passed their password for one
+// of their second factors.
+func (s *Server) respondSecondFactorRequired(ctx context.Context, w http.ResponseWriter, userID int64, strong db.StrongAuth) {
+	resp := SecondFactorChallengeResponse{TwoFactorRequired: true, Methods: []string{}}
+
+	if strong.WebAuthnCredentials > 0 {
+		rp, err := s.relyingParty()
+		if err != nil {
+			s.logger.Error("WebAuthn is misconfigured", zap.Error(err))
+		} else if creds, err := s.db.ListWebAuthnCredentials(ctx, userID); err != nil {
+			s.logger.Error("Failed to list webauthn credentials", zap.Error(err), zap.Int64("user_id", userID))
+		} else if resp.WebAuthn, err = rp.RequestOptions(credentialDescriptors(creds), "preferred"); err != nil {
+			s.logger.Error("Failed to create webauthn options", zap.Error(err))
+		} else {
+			resp.Challenge = resp.WebAuthn.Challenge
+			resp.Methods = append(resp.Methods, SecondFactorWebAuthn)
+		}
+	}
+	if strong.TOTPEnabled {
+		resp.Methods = append(resp.Methods, SecondFactorTOTP, SecondFactorBackupCode)
+	}
+	if len(resp.Methods) == 0 {
+		respondError(w, http.StatusInternalServerError, "failed to start two-factor authentication", "internal_error")
+		return
+	}
+
+	if resp.Challenge == "" {
+		challenge, err := webauthn.NewChallenge()
+		if err != nil {
+			s.logger.Error("Failed to create challenge", zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+			return
+		}
+		resp.Challenge = challenge
+	}
+	if err := s.db.CreateAuthChallenge(ctx, resp.Challenge, db.ChallengeSecondFactor, userID, time.Now().Add(webauthn.Timeout)); err != nil {
+		s.logger.Error("Failed to store challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleVerifySecondFactor finishes a password login with a TOTP code, a
+// backup code or a WebAuthn assertion. Wrong codes count as failed logins.
+// Route: POST /api/auth/2fa/verify
+func (s *Server) HandleVerifySecondFactor(w http.ResponseWriter, r *http.Request) {
+	var req SecondFactorRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.Challenge == "" || (req.Code == "") == (req.Credential == nil) {
+		respondError(w, http.StatusBadRequest, "challenge and either a code or a credential are required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID, err := s.db.GetAuthChallenge(ctx, req.Challenge, db.ChallengeSecondFactor, time.Now())
+	if err != nil {
+		if errors.Is(err, db.ErrAuthChallengeInvalid) {
+			respondError(w, http.StatusUnauthorized, "login expired, please sign in again", "invalid_challenge")
+			return
+		}
+		s.logger.Error("Failed to query challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+
+	entUser, err := s.db.Client.User.Get(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to query user", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	lockout, err := s.db.GetLockout(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to query lockout", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if lockout.Locked(time.Now()) {
+		respondAccountLocked(w, *lockout.LockedUntil)
+		return
+	}
+
+	var valid bool
+	if req.Credential != nil {
+		valid, err = s.verifyWebAuthnFactor(ctx, userID, req.Challenge, req.Credential)
+	} else {
+		valid, err = s.verifyCodeFactor(ctx, userID, req.Code)
+	}
+	if err != nil {
+		s.logger.Error("Failed to verify second factor", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if !valid {
+		if lockedUntil := s.recordFailedLogin(ctx, r, userID, entUser.Email); lockedUntil != nil {
+			respondAccountLocked(w, *lockedUntil)
+			return
+		}
+		respondError(w, http.StatusUnauthorized, "invalid verification code", "invalid_code")
+		return
+	}
+
+	// Only one request may finish the login
+	if err := s.db.ConsumeAuthChallenge(ctx, req.Challenge); err != nil {
+		respondError(w, http.StatusUnauthorized, "login expired, please sign in again", "invalid_challenge")
+		return
+	}
+	if s.respondDeactivated(ctx, w, userID) {
+		return
+	}
+
+	s.completeLogin(ctx, w, r, entUser, lockout.FailedLogins)
+}
+
+// verifyCodeFactor checks a TOTP code or, failing that, a backup code. A
+// backup code works once.
+func (s *Server) verifyCodeFactor(ctx context.Context, userID int64, code string) (bool, error) {
+	var enabled bool
+	var secret, backupCodes *string
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT totp_enabled, totp_secret, backup_codes FROM users WHERE id = ?`), userID,
+	).Scan(&enabled, &secret, &backupCodes)
+	if err != nil || !enabled || secret == nil {
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if totp.Validate(code, *secret) {
+		return true, nil
+	}
+	if backupCodes == nil {
+		return false, nil
+	}
+
+	var codes []BackupCode
+	if err := json.Unmarshal([]byte(*backupCodes), &codes); err != nil {
+		return false, err
+	}
+	for i, c := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(c.Hash), []byte(strings.ToUpper(code))) != nil {
+			continue
+		}
+		remaining, err := json.Marshal(append(codes[:i:i], codes[i+1:]...))
+		if err != nil {
+			return false, err
+		}
+		// The old value is matched so a code can't be used by two requests at once
+		res, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`UPDATE users SET backup_codes = ? WHERE id = ? AND backup_codes = ?`),
+			string(remaining), userID, *backupCodes)
+		if err != nil {
+			return false, err
+		}
+		n, _ := res.RowsAffected()
+		return n == 1, nil
+	}
+	return false, nil
+}
+
+// verifyWebAuthnFactor checks an assertion from one of the user's credentials
+// against a login challenge.
+func (s *Server) verifyWebAuthnFactor(ctx context.Context, userID int64, challenge string, assertion *webauthn.AssertionResponse) (bool, error) {
+	cred, err := s.db.GetWebAuthnCredential(ctx, assertion.ID)
+	if errors.Is(err, db.ErrWebAuthnCredentialNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if cred.UserID != userID {
+		return false, nil
+	}
+	return s.verifyAssertion(ctx, cred, challenge, assertion, false)
+}
+
+// verifyAssertion checks an assertion against a stored credential and records
+// its use. Responses that don't verify are reported as invalid, not errors.
+func (s *Server) verifyAssertion(ctx context.Context, cred *db.WebAuthnCredential, challenge string, assertion *webauthn.AssertionResponse, requireUserVerification bool) (bool, error) {
+	rp, err := s.relyingParty()
+	if err != nil {
+		return false, err
+	}
+	result, err := rp.VerifyAssertion(assertion, challenge, cred.PublicKey, cred.SignCount, requireUserVerification)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCount) {
+			s.logger.Warn("WebAuthn signature counter went backwards, credential may be cloned",
+				zap.Int64("user_id", cred.UserID), zap.Int64("credential_id", cred.ID))
+		}
+		if errors.Is(err, webauthn.ErrVerification) {
+			s.logger.Info("WebAuthn assertion rejected", zap.Error(err), zap.Int64("user_id", cred.UserID))
+			return false, nil
+		}
+		return false, err
+	}
+	if err := s.db.RecordWebAuthnCredentialUse(ctx, cred.ID, result.SignCount, result.BackedUp); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// enableTOTP turns on TOTP for a user with one backup code
+func (ts *TestServer) enableTOTP(t *testing.T, userID int64, backupCode string) {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(backupCode), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("Failed to hash backup code: %v", err)
+	}
+	codes, _ := json.Marshal([]BackupCode{{Code: backupCode, Hash: string(hash)}})
+	if _, err := ts.DB.Exec("UPDATE users SET totp_enabled = 1, totp_secret = ?, backup_codes = ? WHERE id = ?",
+		testTOTPSecret, string(codes), userID); err != nil {
+		t.Fatalf("Failed to enable TOTP: %v", err)
+	}
+}
+
+// passwordLogin logs in with a password and returns the second factor challenge
+func (ts *TestServer) passwordLogin(t *testing.T, email string) SecondFactorChallengeResponse {
+	t.Helper()
+
+	rec, req := MakeRequest(t, http.MethodPost, "/api/auth/login", LoginRequest{Email: email, Password: "password123"}, nil)
+	ts.HandleLogin(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp SecondFactorChallengeResponse
+	DecodeJSON(t, rec, &resp)
+	if !resp.TwoFactorRequired || resp.Challenge == "" {
+		t.Fatalf("Expected a second factor challenge, got %+v", resp)
+	}
+	return resp
+}
+
+func (ts *TestServer) verifySecondFactor(t *testing.T, body SecondFactorRequest) *AuthResponse {
+	t.Helper()
+
+	rec, req := MakeRequest(t, http.MethodPost, "/api/auth/2fa/verify", body, nil)
+	ts.HandleVerifySecondFactor(rec, req)
+	if rec.Code != http.StatusOK {
+		return nil
+	}
+	var resp AuthResponse
+	DecodeJSON(t, rec, &resp)
+	return &resp
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	ts.enableTOTP(t, userID, "ABCD-1234")
+
+	challenge := ts.passwordLogin(t, "user@example.com")
+	if len(challenge.Methods) != 2 || challenge.Methods[0] != SecondFactorTOTP || challenge.WebAuthn != nil {
+		t.Errorf("Expected TOTP and backup codes, got %+v", challenge)
+	}
+
+	t.Run("wrong code counts as a failed login", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/2fa/verify", SecondFactorRequest{Challenge: challenge.Challenge, Code: "000000"}, nil)
+		ts.HandleVerifySecondFactor(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "invalid verification code", "invalid_code")
+
+		lockout, err := ts.DB.GetLockout(t.Context(), userID)
+		if err != nil {
+			t.Fatalf("GetLockout failed: %v", err)
+		}
+		if lockout.FailedLogins != 1 {
+			t.Errorf("Expected 1 failed login, got %d", lockout.FailedLogins)
+		}
+	})
+
+	code, err := totp.GenerateCode(testTOTPSecret, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to generate code: %v", err)
+	}
+	resp := ts.verifySecondFactor(t, SecondFactorRequest{Challenge: challenge.Challenge, Code: code})
+	if resp == nil || resp.Token == "" || resp.User.ID != userID {
+		t.Fatalf("Expected a session after the TOTP code, got %+v", resp)
+	}
+
+	lockout, err := ts.DB.GetLockout(t.Context(), userID)
+	if err != nil {
+		t.Fatalf("GetLockout failed: %v", err)
+	}
+	if lockout.FailedLogins != 0 {
+		t.Errorf("Expected failed logins to be cleared, got %d", lockout.FailedLogins)
+	}
+
+	t.Run("challenge is single use", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/2fa/verify", SecondFactorRequest{Challenge: challenge.Challenge, Code: code}, nil)
+		ts.HandleVerifySecondFactor(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "login expired", "invalid_challenge")
+	})
+
+	t.Run("backup code works once", func(t *testing.T) {
+		challenge := ts.passwordLogin(t, "user@example.com")
+		if resp := ts.verifySecondFactor(t, SecondFactorRequest{Challenge: challenge.Challenge, Code: "abcd-1234"}); resp == nil {
+			t.Fatal("Expected the backup code to log in")
+		}
+
+		challenge = ts.passwordLogin(t, "user@example.com")
+		if resp := ts.verifySecondFactor(t, SecondFactorRequest{Challenge: challenge.Challenge, Code: "ABCD-1234"}); resp != nil {
+			t.Error("Expected a used backup code to be rejected")
+		}
+	})
+
+	t.Run("code and credential are exclusive", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/2fa/verify", SecondFactorRequest{Challenge: challenge.Challenge}, nil)
+		ts.HandleVerifySecondFactor(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "", "validation_error")
+	})
+}
+
+func TestLoginWithSecurityKey(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	otherID := ts.CreateTestUser(t, "other@example.com", "password123")
+	a, _ := ts.registerWebAuthnCredential(t, userID, "YubiKey")
+	other, _ := ts.registerWebAuthnCredential(t, otherID, "")
+
+	challenge := ts.passwordLogin(t, "user@example.com")
+	if len(challenge.Methods) != 1 || challenge.Methods[0] != SecondFactorWebAuthn || challenge.WebAuthn == nil {
+		t.Fatalf("Expected a security key challenge, got %+v", challenge)
+	}
+	if len(challenge.WebAuthn.AllowCredentials) != 1 || challenge.WebAuthn.AllowCredentials[0].ID != a.ID() {
+		t.Errorf("Expected only the user's key to be allowed, got %+v", challenge.WebAuthn.AllowCredentials)
+	}
+
+	t.Run("another user's key is rejected", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/2fa/verify",
+			SecondFactorRequest{Challenge: challenge.Challenge, Credential: other.Assert(challenge.WebAuthn, testOrigin)}, nil)
+		ts.HandleVerifySecondFactor(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "", "invalid_code")
+	})
+
+	// Security keys without a PIN are fine as a second factor
+	a.UserVerified = false
+	resp := ts.verifySecondFactor(t, SecondFactorRequest{Challenge: challenge.Challenge, Credential: a.Assert(challenge.WebAuthn, testOrigin)})
+	if resp == nil || resp.User.ID != userID {
+		t.Fatalf("Expected a session after the security key, got %+v", resp)
+	}
+	ts.waitForActivity(t, userID, "login", 1)
+
+	creds, err := ts.DB.ListWebAuthnCredentials(t.Context(), userID)
+	if err != nil {
+		t.Fatalf("ListWebAuthnCredentials failed: %v", err)
+	}
+	if creds[0].SignCount != a.SignCount || creds[0].LastUsedAt == nil {
+		t.Errorf("Expected the key's use to be recorded, got %+v", creds[0])
+	}
+
+	t.Run("admin sees second factors", func(t *testing.T) {
+		ts.enableTOTP(t, otherID, "ABCD-1234")
+		adminID := ts.CreateTestUser(t, "admin@example.com", "password123")
+		if _, err := ts.DB.Exec("UPDATE users SET is_admin = 1 WHERE id = ?", adminID); err != nil {
+			t.Fatalf("Failed to make admin: %v", err)
+		}
+
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/admin/users", nil, adminID, nil)
+		ts.HandleGetUsers(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var users []UserWithStats
+		DecodeJSON(t, rec, &users)
+
+		byID := map[int64]UserWithStats{}
+		for _, u := range users {
+			byID[u.ID] = u
+		}
+		if u := byID[userID]; u.TOTPEnabled || u.WebAuthnCredentials != 1 {
+			t.Errorf("Expected one security key for user, got %+v", u)
+		}
+		if u := byID[otherID]; !u.TOTPEnabled || u.WebAuthnCredentials != 1 {
+			t.Errorf("Expected TOTP and a passkey for other, got %+v", u)
+		}
+		if u := byID[adminID]; u.TOTPEnabled || u.WebAuthnCredentials != 0 {
+			t.Errorf("Expected no second factors for admin, got %+v", u)
+		}
+	})
+}
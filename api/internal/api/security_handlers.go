@@ -20,6 +20,14 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password"`
 }
 
+// PasswordChangedResponse is returned when a password or second factor
+// changes. Other sessions are logged out; a caller whose token had no session
+// gets the tokens of a new one.
+type PasswordChangedResponse struct {
+	Message string `json:"message"`
+	*SessionTokens
+}
+
 // TwoFactorSetupResponse contains the TOTP secret and QR code
 type TwoFactorSetupResponse struct {
 	Secret    string   `json:"secret"`
@@ -103,8 +111,15 @@ func (s *Server) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Log out every other session; the caller stays logged in
+	_, tokens, err := s.revokeOtherSessions(r.Context(), r, userID)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "Password changed successfully"})
+	json.NewEncoder(w).Encode(PasswordChangedResponse{Message: "Password changed successfully", SessionTokens: tokens})
 }
 
 // Handle2FASetup initiates 2FA setup by generating a secret and QR code
@@ -268,8 +283,15 @@ func (s *Server) Handle2FADisable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Sessions may have been started by someone without the second factor
+	_, tokens, err := s.revokeOtherSessions(r.Context(), r, userID)
+	if err != nil {
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"message": "2FA disabled successfully"})
+	json.NewEncoder(w).Encode(PasswordChangedResponse{Message: "2FA disabled successfully", SessionTokens: tokens})
 }
 
 // Handle2FAStatus returns the current 2FA status
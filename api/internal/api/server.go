@@ -92,6 +92,9 @@ func (s *Server) GetEmailService() *email.BrevoService {
 	err := s.db.QueryRowContext(ctx,
 		`SELECT api_key, sender_email, sender_name, status FROM email_provider WHERE id = 1`,
 	).Scan(&apiKey, &senderEmail, &senderName, &status)
+	if err == nil {
+		apiKey, err = s.db.DecryptSecret(apiKey)
+	}
 	if err != nil {
 		return nil
 	}
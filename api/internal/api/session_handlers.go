@@ -0,0 +1,317 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/internal/auth"
+	"taskai/internal/db"
+)
+
+// SessionTokens are the tokens of a login session. Token is a short-lived
+// access token; RefreshToken is exchanged for new tokens at /api/auth/refresh.
+type SessionTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// RefreshTokenRequest is the body of the refresh and logout endpoints
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionResponse represents an active login session
+type SessionResponse struct {
+	ID         int64     `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// RevokeSessionsResponse is returned when a user logs out their other
+// sessions. Callers whose own token had no session are logged out with them
+// and get the tokens of a new session.
+type RevokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+	*SessionTokens
+}
+
+// sessionIDFromContext returns the login session of the request, or 0 for API
+// keys and tokens issued without a session.
+func sessionIDFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(SessionIDKey).(int64)
+	return id
+}
+
+// startSession creates a login session for the client of r and returns its tokens.
+func (s *Server) startSession(ctx context.Context, r *http.Request, userID int64, email string) (*SessionTokens, error) {
+	refreshToken, refreshHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	session, err := s.db.CreateSession(ctx, userID, refreshHash, r.UserAgent(), getClientIP(r), s.config.RefreshTokenExpiry())
+	if err != nil {
+		return nil, err
+	}
+	return s.sessionTokens(userID, email, session.ID, refreshToken)
+}
+
+// sessionTokens signs an access token for a session.
+func (s *Server) sessionTokens(userID int64, email string, sessionID int64, refreshToken string) (*SessionTokens, error) {
+	expiry := s.config.AccessTokenExpiry()
+	token, err := auth.GenerateSessionToken(userID, email, sessionID, s.config.JWTSecret, expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionTokens{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(expiry.Seconds()),
+	}, nil
+}
+
+// revokeOtherSessions ends all of a user's sessions except the caller's and
+// invalidates tokens issued without a session. Such tokens can't be told
+// apart, so a caller using one is logged out too and gets a new session.
+func (s *Server) revokeOtherSessions(ctx context.Context, r *http.Request, userID int64) (int64, *SessionTokens, error) {
+	current, isJWT := r.Context().Value(SessionIDKey).(int64)
+	revoked, err := s.db.RevokeUserSessions(ctx, userID, current)
+	if err != nil || !isJWT || current != 0 {
+		return revoked, nil, err
+	}
+	email, _ := r.Context().Value(UserEmailKey).(string)
+	tokens, err := s.startSession(ctx, r, userID, email)
+	return revoked, tokens, err
+}
+
+// HandleRefreshToken exchanges a refresh token for a new access token and
+// refresh token. The presented refresh token stops working; presenting it
+// again revokes the session.
+// Route: POST /api/auth/refresh
+func (s *Server) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	newToken, newHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to refresh session", "internal_error")
+		return
+	}
+
+	session, err := s.db.RotateSession(ctx, auth.HashRefreshToken(req.RefreshToken), newHash,
+		r.UserAgent(), getClientIP(r), s.config.RefreshTokenExpiry())
+	if err != nil {
+		if errors.Is(err, db.ErrRefreshTokenReused) {
+			s.logger.Warn("Rotated refresh token was reused; session revoked", zap.String("ip", getClientIP(r)))
+		}
+		if errors.Is(err, db.ErrRefreshTokenReused) || errors.Is(err, db.ErrSessionNotFound) {
+			respondError(w, http.StatusUnauthorized, "invalid or expired refresh token", "invalid_refresh_token")
+			return
+		}
+		s.logger.Error("Failed to rotate session", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to refresh session", "internal_error")
+		return
+	}
+
+	entUser, err := s.db.Client.User.Get(ctx, session.UserID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusUnauthorized, "invalid or expired refresh token", "invalid_refresh_token")
+			return
+		}
+		s.logger.Error("Failed to query user", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to refresh session", "internal_error")
+		return
+	}
+
+	tokens, err := s.sessionTokens(entUser.ID, entUser.Email, session.ID, newToken)
+	if err != nil {
+		s.logger.Error("Failed to generate token", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to generate token", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         entUserToAPI(entUser),
+	})
+}
+
+// HandleLogout ends the session a refresh token belongs to. Unknown tokens
+// are ignored so logging out twice is harmless.
+// Route: POST /api/auth/logout
+func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	var req RefreshTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "refresh_token is required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := s.db.RevokeSessionByRefreshToken(ctx, auth.HashRefreshToken(req.RefreshToken))
+	if err != nil && !errors.Is(err, db.ErrSessionNotFound) {
+		s.logger.Error("Failed to revoke session", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to log out", "internal_error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListSessions returns the current user's active login sessions
+// Route: GET /api/settings/sessions
+func (s *Server) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	sessions, err := s.db.ListActiveSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list sessions", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to list sessions", "internal_error")
+		return
+	}
+
+	current := sessionIDFromContext(r.Context())
+	resp := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		resp = append(resp, SessionResponse{
+			ID:         session.ID,
+			Device:     describeUserAgent(session.UserAgent),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == current,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// HandleRevokeSession logs out one of the current user's sessions, which may
+// be the current one
+// Route: DELETE /api/settings/sessions/{id}
+func (s *Server) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid session ID", "invalid_input")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.db.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, db.ErrSessionNotFound) {
+			respondError(w, http.StatusNotFound, "session not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to revoke session", zap.Error(err), zap.Int64("session_id", sessionID))
+		respondError(w, http.StatusInternalServerError, "failed to revoke session", "internal_error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRevokeOtherSessions logs out all of the current user's sessions
+// except the one making the request
+// Route: DELETE /api/settings/sessions
+func (s *Server) HandleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	revoked, tokens, err := s.revokeOtherSessions(ctx, r, userID)
+	if err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to revoke sessions", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, RevokeSessionsResponse{Revoked: revoked, SessionTokens: tokens})
+}
+
+// describeUserAgent turns a User-Agent header into a short label such as
+// "Chrome on macOS" for the session list.
+func describeUserAgent(ua string) string {
+	if strings.TrimSpace(ua) == "" {
+		return "Unknown device"
+	}
+
+	var browser string
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	default:
+		// Non-browser clients usually lead with "name/version"
+		browser = strings.SplitN(strings.Fields(ua)[0], "/", 2)[0]
+	}
+
+	var platform string
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "Mac OS X"), strings.Contains(ua, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "CrOS"):
+		platform = "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
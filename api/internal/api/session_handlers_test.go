@@ -0,0 +1,247 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+const (
+	chromeMacUA    = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+	firefoxLinuxUA = "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0"
+)
+
+// loginSession logs a user in from a client and returns the session tokens.
+func (ts *TestServer) loginSession(t *testing.T, email, userAgent string) AuthResponse {
+	t.Helper()
+
+	rec, req := MakeRequest(t, http.MethodPost, "/api/auth/login",
+		LoginRequest{Email: email, Password: "password123"}, map[string]string{"User-Agent": userAgent})
+	ts.HandleLogin(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	var resp AuthResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.Token == "" || resp.RefreshToken == "" || resp.ExpiresIn <= 0 {
+		t.Fatalf("Expected session tokens, got %+v", resp)
+	}
+	return resp
+}
+
+// authStatus calls a JWT-protected handler with a bearer token.
+func (ts *TestServer) authStatus(t *testing.T, token string, handler http.HandlerFunc) int {
+	t.Helper()
+
+	rec, req := MakeRequest(t, http.MethodGet, "/api/me", nil, map[string]string{"Authorization": "Bearer " + token})
+	ts.JWTAuth(handler).ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ts.CreateTestUser(t, "user@example.com", "password123")
+	login := ts.loginSession(t, "user@example.com", chromeMacUA)
+
+	refresh := func(token string) *AuthResponse {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/refresh", RefreshTokenRequest{RefreshToken: token}, nil)
+		ts.HandleRefreshToken(rec, req)
+		if rec.Code != http.StatusOK {
+			AssertError(t, rec, http.StatusUnauthorized, "refresh token", "invalid_refresh_token")
+			return nil
+		}
+		var resp AuthResponse
+		DecodeJSON(t, rec, &resp)
+		return &resp
+	}
+
+	rotated := refresh(login.RefreshToken)
+	if rotated == nil || rotated.RefreshToken == login.RefreshToken || rotated.User.Email != "user@example.com" {
+		t.Fatalf("Expected a new refresh token, got %+v", rotated)
+	}
+	if got := ts.authStatus(t, rotated.Token, ts.HandleMe); got != http.StatusOK {
+		t.Fatalf("Expected the refreshed access token to work, got %d", got)
+	}
+
+	// Replaying the rotated-out token revokes the session
+	if refresh(login.RefreshToken) != nil {
+		t.Fatal("Expected the old refresh token to be rejected")
+	}
+	if refresh(rotated.RefreshToken) != nil {
+		t.Error("Expected the session to be revoked after refresh token reuse")
+	}
+	if got := ts.authStatus(t, rotated.Token, ts.HandleMe); got != http.StatusUnauthorized {
+		t.Errorf("Expected the access token of a revoked session to be rejected, got %d", got)
+	}
+
+	t.Run("missing token", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/refresh", RefreshTokenRequest{}, nil)
+		ts.HandleRefreshToken(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "refresh_token", "validation_error")
+	})
+}
+
+func TestLogout(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ts.CreateTestUser(t, "user@example.com", "password123")
+	login := ts.loginSession(t, "user@example.com", chromeMacUA)
+
+	for i := 0; i < 2; i++ {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/logout", RefreshTokenRequest{RefreshToken: login.RefreshToken}, nil)
+		ts.HandleLogout(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+	}
+
+	if got := ts.authStatus(t, login.Token, ts.HandleMe); got != http.StatusUnauthorized {
+		t.Errorf("Expected the access token to stop working after logout, got %d", got)
+	}
+}
+
+func TestSessionManagement(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	laptop := ts.loginSession(t, "user@example.com", chromeMacUA)
+	desktop := ts.loginSession(t, "user@example.com", firefoxLinuxUA)
+	cli := ts.loginSession(t, "user@example.com", "curl/8.4.0")
+
+	rec, req := MakeRequest(t, http.MethodGet, "/api/settings/sessions", nil, map[string]string{"Authorization": "Bearer " + laptop.Token})
+	ts.JWTAuth(http.HandlerFunc(ts.HandleListSessions)).ServeHTTP(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	var sessions []SessionResponse
+	DecodeJSON(t, rec, &sessions)
+	if len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d", len(sessions))
+	}
+	devices := map[string]bool{}
+	var currentID, cliID int64
+	for _, s := range sessions {
+		devices[s.Device] = true
+		if s.Current {
+			currentID = s.ID
+		}
+		if s.Device == "curl" {
+			cliID = s.ID
+		}
+	}
+	if !devices["Chrome on macOS"] || !devices["Firefox on Linux"] || currentID == 0 {
+		t.Errorf("Expected devices and the current session to be reported, got %+v", sessions)
+	}
+
+	t.Run("log out one session", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete, "/api/settings/sessions/x", nil, userID,
+			map[string]string{"id": fmt.Sprintf("%d", cliID)})
+		ts.HandleRevokeSession(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		if got := ts.authStatus(t, cli.Token, ts.HandleMe); got != http.StatusUnauthorized {
+			t.Errorf("Expected the revoked session's token to be rejected, got %d", got)
+		}
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodDelete, "/api/settings/sessions/x", nil, userID,
+			map[string]string{"id": fmt.Sprintf("%d", cliID)})
+		ts.HandleRevokeSession(rec, req)
+		AssertError(t, rec, http.StatusNotFound, "session not found", "not_found")
+	})
+
+	t.Run("sessions of other users are not found", func(t *testing.T) {
+		otherID := ts.CreateTestUser(t, "other@example.com", "password123")
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete, "/api/settings/sessions/x", nil, otherID,
+			map[string]string{"id": fmt.Sprintf("%d", currentID)})
+		ts.HandleRevokeSession(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("log out other sessions", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodDelete, "/api/settings/sessions", nil, map[string]string{"Authorization": "Bearer " + laptop.Token})
+		ts.JWTAuth(http.HandlerFunc(ts.HandleRevokeOtherSessions)).ServeHTTP(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var resp RevokeSessionsResponse
+		DecodeJSON(t, rec, &resp)
+		if resp.Revoked != 1 || resp.SessionTokens != nil {
+			t.Errorf("Expected 1 session revoked and no new tokens, got %+v", resp)
+		}
+		if got := ts.authStatus(t, desktop.Token, ts.HandleMe); got != http.StatusUnauthorized {
+			t.Errorf("Expected the other session to be logged out, got %d", got)
+		}
+		if got := ts.authStatus(t, laptop.Token, ts.HandleMe); got != http.StatusOK {
+			t.Errorf("Expected the current session to stay logged in, got %d", got)
+		}
+	})
+}
+
+func TestPasswordChangeRevokesSessions(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	current := ts.loginSession(t, "user@example.com", chromeMacUA)
+	other := ts.loginSession(t, "user@example.com", firefoxLinuxUA)
+	legacy := ts.GenerateTestToken(t, userID, "user@example.com")
+
+	body := ChangePasswordRequest{CurrentPassword: "password123", NewPassword: "newpassword456"}
+	rec, req := MakeRequest(t, http.MethodPost, "/api/settings/password", body, map[string]string{"Authorization": "Bearer " + current.Token})
+	ts.JWTAuth(http.HandlerFunc(ts.HandleChangePassword)).ServeHTTP(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	if got := ts.authStatus(t, current.Token, ts.HandleMe); got != http.StatusOK {
+		t.Errorf("Expected the current session to stay logged in, got %d", got)
+	}
+	if got := ts.authStatus(t, other.Token, ts.HandleMe); got != http.StatusUnauthorized {
+		t.Errorf("Expected other sessions to be logged out, got %d", got)
+	}
+	if got := ts.authStatus(t, legacy, ts.HandleMe); got != http.StatusUnauthorized {
+		t.Errorf("Expected tokens without a session to be revoked, got %d", got)
+	}
+
+	t.Run("callers without a session get a new one", func(t *testing.T) {
+		legacy := ts.GenerateTestToken(t, userID, "user@example.com")
+		// Issued in the same second as the last revocation
+		if _, err := ts.DB.Exec(`UPDATE users SET tokens_revoked_at = NULL WHERE id = ?`, userID); err != nil {
+			t.Fatalf("Failed to reset revocation: %v", err)
+		}
+
+		body := ChangePasswordRequest{CurrentPassword: "newpassword456", NewPassword: "password789"}
+		rec, req := MakeRequest(t, http.MethodPost, "/api/settings/password", body, map[string]string{"Authorization": "Bearer " + legacy})
+		ts.JWTAuth(http.HandlerFunc(ts.HandleChangePassword)).ServeHTTP(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var resp PasswordChangedResponse
+		DecodeJSON(t, rec, &resp)
+		if resp.SessionTokens == nil || resp.RefreshToken == "" {
+			t.Fatalf("Expected tokens for a new session, got %+v", resp)
+		}
+		if got := ts.authStatus(t, resp.Token, ts.HandleMe); got != http.StatusOK {
+			t.Errorf("Expected the new session's token to work, got %d", got)
+		}
+		if got := ts.authStatus(t, legacy, ts.HandleMe); got != http.StatusUnauthorized {
+			t.Errorf("Expected the caller's old token to be revoked, got %d", got)
+		}
+	})
+}
+
+func TestDescribeUserAgent(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want string
+	}{
+		{chromeMacUA, "Chrome on macOS"},
+		{firefoxLinuxUA, "Firefox on Linux"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"taskai-mcp/1.2.0", "taskai-mcp"},
+		{"", "Unknown device"},
+	}
+	for _, tt := range tests {
+		if got := describeUserAgent(tt.ua); got != tt.want {
+			t.Errorf("describeUserAgent(%q) = %q, want %q", tt.ua, got, tt.want)
+		}
+	}
+}
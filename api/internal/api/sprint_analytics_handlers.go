@@ -0,0 +1,561 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent/task"
+	"taskai/internal/db"
+)
+
+const (
+	// maxSprintDays bounds the daily series of a sprint
+	maxSprintDays = 366
+	// defaultVelocitySprints and maxVelocitySprints bound the sprints a
+	// velocity report covers
+	defaultVelocitySprints = 5
+	maxVelocitySprints     = 20
+	// sprintSnapshotInterval is how often running sprints are snapshotted
+	sprintSnapshotInterval = time.Hour
+)
+
+// SprintDay is the state of a sprint at the end of one day. The remaining
+// values trace its burndown, the completed and total values its burnup. The
+// ideal values fall linearly from the committed scope to zero at the end
+// date, and are omitted for sprints without one.
+type SprintDay struct {
+	Date                string   `json:"date"`
+	TotalTasks          int      `json:"total_tasks"`
+	CompletedTasks      int      `json:"completed_tasks"`
+	RemainingTasks      int      `json:"remaining_tasks"`
+	TotalHours          float64  `json:"total_hours"`
+	CompletedHours      float64  `json:"completed_hours"`
+	RemainingHours      float64  `json:"remaining_hours"`
+	IdealRemainingTasks *float64 `json:"ideal_remaining_tasks,omitempty"`
+	IdealRemainingHours *float64 `json:"ideal_remaining_hours,omitempty"`
+}
+
+// SprintScopeChange is a task added to or removed from a sprint after its
+// first day. Title is empty for tasks that have since been deleted.
+type SprintScopeChange struct {
+	Date           string   `json:"date"`
+	TaskID         int64    `json:"task_id"`
+	TaskNumber     *int     `json:"task_number,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	Change         string   `json:"change"` // "added" or "removed"
+	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
+}
+
+// SprintAnalytics is the burndown, burnup and scope changes of a sprint.
+// Committed values are the scope at the end of the first recorded day.
+type SprintAnalytics struct {
+	Sprint         Sprint              `json:"sprint"`
+	CommittedTasks int                 `json:"committed_tasks"`
+	CommittedHours float64             `json:"committed_hours"`
+	CompletedTasks int                 `json:"completed_tasks"`
+	CompletedHours float64             `json:"completed_hours"`
+	AddedTasks     int                 `json:"added_tasks"`
+	RemovedTasks   int                 `json:"removed_tasks"`
+	Days           []SprintDay         `json:"days"`
+	ScopeChanges   []SprintScopeChange `json:"scope_changes"`
+}
+
+// SprintVelocity is what one completed sprint got done. Committed values are
+// nil for sprints completed before snapshots were recorded.
+type SprintVelocity struct {
+	SprintID       int      `json:"sprint_id"`
+	Name           string   `json:"name"`
+	StartDate      string   `json:"start_date,omitempty"`
+	EndDate        string   `json:"end_date,omitempty"`
+	CommittedTasks *int     `json:"committed_tasks"`
+	CommittedHours *float64 `json:"committed_hours"`
+	CompletedTasks int      `json:"completed_tasks"`
+	CompletedHours float64  `json:"completed_hours"`
+}
+
+// VelocityReport is the velocity of a project's last completed sprints,
+// oldest first.
+type VelocityReport struct {
+	Sprints               []SprintVelocity `json:"sprints"`
+	AverageCompletedTasks float64          `json:"average_completed_tasks"`
+	AverageCompletedHours float64          `json:"average_completed_hours"`
+}
+
+// CompleteSprintRequest chooses where a completed sprint's unfinished tasks
+// go. Without a next sprint they're taken out of any sprint.
+type CompleteSprintRequest struct {
+	NextSprintID *int64 `json:"next_sprint_id,omitempty"`
+}
+
+// CompleteSprintResponse reports what a sprint got done and which tasks were
+// moved on.
+type CompleteSprintResponse struct {
+	Sprint          Sprint          `json:"sprint"`
+	CompletedTasks  int             `json:"completed_tasks"`
+	CompletedHours  float64         `json:"completed_hours"`
+	UnfinishedTasks []db.SprintTask `json:"unfinished_tasks"`
+	NextSprintID    *int64          `json:"next_sprint_id,omitempty"`
+}
+
+// sprintTotals counts the tasks and estimated hours of one day's snapshot
+func sprintTotals(tasks []db.SprintTaskSnapshot) (day SprintDay) {
+	for _, t := range tasks {
+		hours := 0.0
+		if t.EstimatedHours != nil {
+			hours = *t.EstimatedHours
+		}
+		day.TotalTasks++
+		day.TotalHours += hours
+		if t.Status == "done" {
+			day.CompletedTasks++
+			day.CompletedHours += hours
+		}
+	}
+	day.RemainingTasks = day.TotalTasks - day.CompletedTasks
+	day.RemainingHours = day.TotalHours - day.CompletedHours
+	return day
+}
+
+// sprintSeries builds the daily series and scope changes of a sprint from
+// its snapshots, for the days from start to last. A day without a snapshot
+// keeps the state of the day before; days before the first snapshot are left
+// out. end is the zero time for sprints without an end date.
+func sprintSeries(snapshots []db.SprintTaskSnapshot, start, end, last time.Time) ([]SprintDay, []SprintScopeChange) {
+	byDay := map[string][]db.SprintTaskSnapshot{}
+	for _, s := range snapshots {
+		byDay[s.Day] = append(byDay[s.Day], s)
+	}
+
+	// Snapshots from before the start date stand in for a missing first day
+	startDay := db.SnapshotDay(start)
+	var current []db.SprintTaskSnapshot
+	recorded := false
+	for _, s := range snapshots {
+		if s.Day < startDay {
+			current, recorded = byDay[s.Day], true
+		}
+	}
+
+	days := []SprintDay{}
+	changes := []SprintScopeChange{}
+	var committed SprintDay
+	for d := start; !d.After(last) && len(days) < maxSprintDays; d = d.AddDate(0, 0, 1) {
+		date := db.SnapshotDay(d)
+		if tasks, ok := byDay[date]; ok {
+			if recorded && date > startDay {
+				changes = append(changes, sprintScopeChanges(date, current, tasks)...)
+			}
+			current, recorded = tasks, true
+		}
+		if !recorded {
+			continue
+		}
+
+		day := sprintTotals(current)
+		day.Date = date
+		if len(days) == 0 {
+			committed = day
+		}
+		if !end.IsZero() {
+			left := 0.0
+			if total := end.Sub(start).Hours() / 24; total > 0 {
+				left = max(0, 1-d.Sub(start).Hours()/24/total)
+			}
+			tasks, hours := float64(committed.TotalTasks)*left, committed.TotalHours*left
+			day.IdealRemainingTasks, day.IdealRemainingHours = &tasks, &hours
+		}
+		days = append(days, day)
+	}
+	return days, changes
+}
+
+// sprintScopeChanges lists the tasks that entered or left a sprint between
+// two snapshots.
+func sprintScopeChanges(date string, before, after []db.SprintTaskSnapshot) []SprintScopeChange {
+	was := make(map[int64]bool, len(before))
+	for _, t := range before {
+		was[t.TaskID] = true
+	}
+	is := make(map[int64]bool, len(after))
+	var changes []SprintScopeChange
+	for _, t := range after {
+		is[t.TaskID] = true
+		if !was[t.TaskID] {
+			changes = append(changes, SprintScopeChange{Date: date, TaskID: t.TaskID, Change: "added", EstimatedHours: t.EstimatedHours})
+		}
+	}
+	for _, t := range before {
+		if !is[t.TaskID] {
+			changes = append(changes, SprintScopeChange{Date: date, TaskID: t.TaskID, Change: "removed", EstimatedHours: t.EstimatedHours})
+		}
+	}
+	return changes
+}
+
+// parseSprintDay parses a sprint's "2006-01-02" date
+func parseSprintDay(date string) time.Time {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// sprintWindow returns the days a sprint's series covers: from its start date,
+// or the day it was created, to its end date or today, whichever is first.
+func sprintWindow(sp Sprint, now time.Time) (start, end, last time.Time) {
+	start = parseSprintDay(sp.StartDate)
+	if start.IsZero() {
+		start = parseSprintDay(db.SnapshotDay(sp.CreatedAt))
+	}
+	end = parseSprintDay(sp.EndDate)
+	last = parseSprintDay(db.SnapshotDay(now))
+	if !end.IsZero() && end.Before(last) {
+		last = end
+	}
+	return start, end, last
+}
+
+// loadSprint fetches a sprint and checks the user holds perm in its project.
+// Sprints without a project are only open to their creator. It writes the
+// error response and returns false when the request can't go on.
+func (s *Server) loadSprint(w http.ResponseWriter, r *http.Request, perm Permission) (Sprint, *int64, bool) {
+	ctx := r.Context()
+	userID, ok := GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return Sprint{}, nil, false
+	}
+	sprintID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid sprint ID", "invalid_input")
+		return Sprint{}, nil, false
+	}
+
+	var projectID *int64
+	err = s.db.QueryRowContext(ctx, `SELECT project_id FROM sprints WHERE id = $1`, sprintID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "sprint not found", "not_found")
+		return Sprint{}, nil, false
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch sprint", "internal_error")
+		return Sprint{}, nil, false
+	}
+	sp, err := scanSprint(s.db.QueryRowContext(ctx, `SELECT `+sprintSelectCols+` FROM sprints WHERE id = $1`, sprintID))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch sprint", "internal_error")
+		return Sprint{}, nil, false
+	}
+
+	hasAccess := int64(sp.UserID) == userID
+	if projectID != nil {
+		hasAccess, err = s.authorizeProject(ctx, userID, *projectID, perm)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+			return Sprint{}, nil, false
+		}
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "Forbidden", "forbidden")
+		return Sprint{}, nil, false
+	}
+	return sp, projectID, true
+}
+
+// HandleGetSprintAnalytics returns the daily burndown and burnup of a sprint,
+// by task count and by estimated hours, and the tasks added or removed after
+// it started.
+// Route: GET /api/sprints/{id}/analytics
+func (s *Server) HandleGetSprintAnalytics(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sp, _, ok := s.loadSprint(w, r, PermProjectView)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	start, end, last := sprintWindow(sp, now)
+	// Bring today up to date rather than waiting for the snapshot worker
+	if sp.Status != "completed" && !start.After(last) {
+		if err := s.db.SnapshotSprint(ctx, int64(sp.ID), now); err != nil {
+			s.logger.Error("Failed to snapshot sprint", zap.Int("sprint_id", sp.ID), zap.Error(err))
+		}
+	}
+
+	snapshots, err := s.db.ListSprintSnapshots(ctx, int64(sp.ID))
+	if err != nil {
+		s.logger.Error("Failed to list sprint snapshots", zap.Int("sprint_id", sp.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch sprint snapshots", "internal_error")
+		return
+	}
+	// A completed sprint's series ends with its final snapshot
+	if sp.Status == "completed" && len(snapshots) > 0 {
+		if final := parseSprintDay(snapshots[len(snapshots)-1].Day); final.Before(last) {
+			last = final
+		}
+	}
+
+	days, changes := sprintSeries(snapshots, start, end, last)
+	analytics := SprintAnalytics{Sprint: sp, Days: days, ScopeChanges: changes}
+	if len(days) > 0 {
+		analytics.CommittedTasks, analytics.CommittedHours = days[0].TotalTasks, days[0].TotalHours
+		final := days[len(days)-1]
+		analytics.CompletedTasks, analytics.CompletedHours = final.CompletedTasks, final.CompletedHours
+	}
+	for _, c := range changes {
+		if c.Change == "added" {
+			analytics.AddedTasks++
+		} else {
+			analytics.RemovedTasks++
+		}
+	}
+
+	// Name the changed tasks in one query
+	if len(changes) > 0 {
+		ids := make([]int64, len(changes))
+		for i, c := range changes {
+			ids[i] = c.TaskID
+		}
+		tasks, err := s.db.Client.Task.Query().Where(task.IDIn(ids...)).All(ctx)
+		if err == nil {
+			byID := make(map[int64]int, len(tasks))
+			for i, t := range tasks {
+				byID[t.ID] = i
+			}
+			for i, c := range changes {
+				if j, ok := byID[c.TaskID]; ok {
+					changes[i].TaskNumber, changes[i].Title = tasks[j].TaskNumber, tasks[j].Title
+				}
+			}
+		}
+	}
+
+	respondJSON(w, http.StatusOK, analytics)
+}
+
+// HandleGetSprintVelocity returns what the last completed sprints of a
+// project got done. The count query parameter picks how many (default 5).
+// Route: GET /api/projects/{id}/sprints/velocity
+func (s *Server) HandleGetSprintVelocity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, ok := GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return
+	}
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	count := defaultVelocitySprints
+	if v := r.URL.Query().Get("count"); v != "" {
+		count, err = strconv.Atoi(v)
+		if err != nil || count < 1 || count > maxVelocitySprints {
+			respondError(w, http.StatusBadRequest, "count must be between 1 and 20", "invalid_input")
+			return
+		}
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "Forbidden", "forbidden")
+		return
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+sprintSelectCols+` FROM sprints WHERE project_id = $1 AND status = 'completed'`, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch sprints", "internal_error")
+		return
+	}
+	var sprints []Sprint
+	for rows.Next() {
+		sp, err := scanSprint(rows)
+		if err != nil {
+			rows.Close()
+			respondError(w, http.StatusInternalServerError, "failed to scan sprint", "internal_error")
+			return
+		}
+		sprints = append(sprints, sp)
+	}
+	rows.Close()
+
+	// Most recent first by end date, or by last update for sprints without one
+	ended := func(sp Sprint) string {
+		if sp.EndDate != "" {
+			return sp.EndDate
+		}
+		return db.SnapshotDay(sp.UpdatedAt)
+	}
+	sort.SliceStable(sprints, func(i, j int) bool { return ended(sprints[i]) > ended(sprints[j]) })
+	if len(sprints) > count {
+		sprints = sprints[:count]
+	}
+
+	now := time.Now().UTC()
+	report := VelocityReport{Sprints: []SprintVelocity{}}
+	for i := len(sprints) - 1; i >= 0; i-- {
+		sp := sprints[i]
+		v, err := s.sprintVelocity(ctx, sp, now)
+		if err != nil {
+			s.logger.Error("Failed to compute sprint velocity", zap.Int("sprint_id", sp.ID), zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to compute velocity", "internal_error")
+			return
+		}
+		report.Sprints = append(report.Sprints, v)
+		report.AverageCompletedTasks += float64(v.CompletedTasks)
+		report.AverageCompletedHours += v.CompletedHours
+	}
+	if n := len(report.Sprints); n > 0 {
+		report.AverageCompletedTasks /= float64(n)
+		report.AverageCompletedHours /= float64(n)
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// sprintVelocity measures a completed sprint from its snapshots, or from the
+// tasks still in it when it has none.
+func (s *Server) sprintVelocity(ctx context.Context, sp Sprint, now time.Time) (SprintVelocity, error) {
+	v := SprintVelocity{SprintID: sp.ID, Name: sp.Name, StartDate: sp.StartDate, EndDate: sp.EndDate}
+
+	snapshots, err := s.db.ListSprintSnapshots(ctx, int64(sp.ID))
+	if err != nil {
+		return v, err
+	}
+	if len(snapshots) > 0 {
+		start, end, _ := sprintWindow(sp, now)
+		days, _ := sprintSeries(snapshots, start, end, parseSprintDay(snapshots[len(snapshots)-1].Day))
+		if len(days) > 0 {
+			committed, final := days[0], days[len(days)-1]
+			v.CommittedTasks, v.CommittedHours = &committed.TotalTasks, &committed.TotalHours
+			v.CompletedTasks, v.CompletedHours = final.CompletedTasks, final.CompletedHours
+			return v, nil
+		}
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COALESCE(SUM(estimated_hours), 0) FROM tasks WHERE sprint_id = $1 AND status = 'done'`,
+		sp.ID).Scan(&v.CompletedTasks, &v.CompletedHours)
+	return v, err
+}
+
+// HandleCompleteSprint completes a sprint and moves its unfinished tasks to
+// the chosen next sprint, which must be an open sprint of the same project.
+// Route: POST /api/sprints/{id}/complete
+func (s *Server) HandleCompleteSprint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sp, projectID, ok := s.loadSprint(w, r, PermProjectEdit)
+	if !ok {
+		return
+	}
+
+	var req CompleteSprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+
+	var nextName *string
+	if req.NextSprintID != nil {
+		var nextProjectID *int64
+		var name, status string
+		err := s.db.QueryRowContext(ctx, `SELECT project_id, name, status FROM sprints WHERE id = $1`,
+			*req.NextSprintID).Scan(&nextProjectID, &name, &status)
+		sameProject := nextProjectID != nil && projectID != nil && *nextProjectID == *projectID
+		if err != nil || *req.NextSprintID == int64(sp.ID) || !sameProject || status == "completed" {
+			if err != nil && err != sql.ErrNoRows {
+				respondError(w, http.StatusInternalServerError, "failed to fetch next sprint", "internal_error")
+				return
+			}
+			respondError(w, http.StatusBadRequest, "next sprint must be another open sprint of the same project", "invalid_input")
+			return
+		}
+		nextName = &name
+	}
+
+	unfinished, err := s.db.CompleteSprint(ctx, int64(sp.ID), req.NextSprintID, time.Now())
+	if errors.Is(err, db.ErrSprintCompleted) {
+		respondError(w, http.StatusConflict, "sprint is already completed", "conflict")
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to complete sprint", zap.Int("sprint_id", sp.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to complete sprint", "internal_error")
+		return
+	}
+
+	moves := make([]db.TaskHistoryEntry, len(unfinished))
+	for i, t := range unfinished {
+		moves[i] = db.TaskHistoryEntry{
+			TaskID: t.ID, ProjectID: t.ProjectID, Field: "sprint", OldValue: &sp.Name, NewValue: nextName,
+		}
+	}
+	s.saveTaskHistory(r, moves)
+
+	resp := CompleteSprintResponse{UnfinishedTasks: unfinished, NextSprintID: req.NextSprintID}
+	resp.Sprint, err = scanSprint(s.db.QueryRowContext(ctx, `SELECT `+sprintSelectCols+` FROM sprints WHERE id = $1`, sp.ID))
+	if err == nil {
+		err = s.db.QueryRowContext(ctx,
+			`SELECT COUNT(*), COALESCE(SUM(estimated_hours), 0) FROM tasks WHERE sprint_id = $1`,
+			sp.ID).Scan(&resp.CompletedTasks, &resp.CompletedHours)
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch completed sprint", "internal_error")
+		return
+	}
+
+	var auditProjectID int64
+	if projectID != nil {
+		auditProjectID = *projectID
+	}
+	auditChange(r, auditEvent{
+		Action: "sprint.completed", ResourceType: "sprint", ResourceID: int64(sp.ID), ProjectID: auditProjectID,
+		Before: sp, After: resp.Sprint,
+	})
+	respondJSON(w, http.StatusOK, resp)
+	if projectID != nil {
+		go s.emitWebhookEvent(*projectID, "sprint.updated", resp.Sprint)
+	}
+}
+
+// StartSprintSnapshotWorker periodically snapshots running sprints, so their
+// burndown has a point for every day
+func (s *Server) StartSprintSnapshotWorker(ctx context.Context) {
+	ticker := time.NewTicker(sprintSnapshotInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting sprint snapshot worker",
+		zap.Duration("interval", sprintSnapshotInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Sprint snapshot worker shutting down")
+			return
+		case <-ticker.C:
+			snapCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			if _, err := s.db.SnapshotRunningSprints(snapCtx, time.Now()); err != nil {
+				s.logger.Error("sprints: failed to snapshot running sprints", zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"taskai/internal/db"
+)
+
+func TestSprintSeries(t *testing.T) {
+	hours := func(h float64) *float64 { return &h }
+	snapshots := []db.SprintTaskSnapshot{
+		{Day: "2026-03-01", TaskID: 1, Status: "todo", EstimatedHours: hours(3)},
+		{Day: "2026-03-01", TaskID: 2, Status: "todo", EstimatedHours: hours(5)},
+		{Day: "2026-03-02", TaskID: 1, Status: "done", EstimatedHours: hours(3)},
+		{Day: "2026-03-02", TaskID: 2, Status: "todo", EstimatedHours: hours(5)},
+		{Day: "2026-03-02", TaskID: 3, Status: "in_progress"},
+		{Day: "2026-03-04", TaskID: 1, Status: "done", EstimatedHours: hours(3)},
+		{Day: "2026-03-04", TaskID: 3, Status: "done"},
+	}
+	start, end, last := parseSprintDay("2026-03-01"), parseSprintDay("2026-03-05"), parseSprintDay("2026-03-04")
+
+	days, changes := sprintSeries(snapshots, start, end, last)
+	if len(days) != 4 {
+		t.Fatalf("Expected a point for every day up to the last, got %+v", days)
+	}
+	if d := days[0]; d.TotalTasks != 2 || d.RemainingHours != 8 || *d.IdealRemainingTasks != 2 {
+		t.Errorf("Unexpected first day: %+v", d)
+	}
+	if d := days[2]; d.Date != "2026-03-03" || d.CompletedTasks != 1 || d.RemainingTasks != 2 || d.TotalHours != 8 {
+		t.Errorf("Expected a day without a snapshot to keep the day before, got %+v", d)
+	}
+	if d := days[3]; d.CompletedTasks != 2 || d.RemainingTasks != 0 || d.CompletedHours != 3 || *d.IdealRemainingTasks != 0.5 {
+		t.Errorf("Unexpected last day: %+v", d)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("Expected one task added and one removed, got %+v", changes)
+	}
+	if c := changes[0]; c.TaskID != 3 || c.Change != "added" || c.Date != "2026-03-02" {
+		t.Errorf("Unexpected scope change: %+v", c)
+	}
+	if c := changes[1]; c.TaskID != 2 || c.Change != "removed" || c.Date != "2026-03-04" || *c.EstimatedHours != 5 {
+		t.Errorf("Unexpected scope change: %+v", c)
+	}
+
+	t.Run("days before the first snapshot are left out", func(t *testing.T) {
+		days, changes := sprintSeries(snapshots[2:], start, time.Time{}, last)
+		if len(days) != 3 || days[0].Date != "2026-03-02" || days[0].IdealRemainingTasks != nil {
+			t.Errorf("Unexpected series: %+v", days)
+		}
+		if len(changes) != 1 || changes[0].Change != "removed" {
+			t.Errorf("Unexpected scope changes: %+v", changes)
+		}
+	})
+}
+
+func TestCompleteSprint(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Sprints")
+	ts.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	viewer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, viewerID, "viewer@example.com")}
+	for i, lane := range []struct{ name, category string }{{"To Do", "todo"}, {"Done", "done"}} {
+		if _, err := ts.DB.Exec(
+			`INSERT INTO swim_lanes (project_id, name, color, position, status_category) VALUES (?, ?, ?, ?, ?)`,
+			projectID, lane.name, "#6B7280", i, lane.category); err != nil {
+			t.Fatalf("Failed to create swim lane: %v", err)
+		}
+	}
+	current := createTestSprint(t, ts, ownerID, projectID, "Sprint 1", "active")
+	next := createTestSprint(t, ts, ownerID, projectID, "Sprint 2", "planned")
+
+	var tasks []Task
+	for _, status := range []string{"done", "todo"} {
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID), map[string]interface{}{
+			"title": "Task " + status, "status": status, "sprint_id": current, "estimated_hours": 2,
+		}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		tasks = append(tasks, task)
+	}
+	completePath := fmt.Sprintf("/api/sprints/%d/complete", current)
+
+	t.Run("viewers can't complete it", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, completePath, map[string]interface{}{"next_sprint_id": next}, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("next sprint must be another open sprint", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, completePath, map[string]interface{}{"next_sprint_id": current}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	rec := routerRequest(t, ts, http.MethodPost, completePath, map[string]interface{}{"next_sprint_id": next}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp CompleteSprintResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.Sprint.Status != "completed" || resp.CompletedTasks != 1 || resp.CompletedHours != 2 {
+		t.Errorf("Unexpected completion: %+v", resp)
+	}
+	if len(resp.UnfinishedTasks) != 1 || resp.UnfinishedTasks[0].ID != tasks[1].ID {
+		t.Fatalf("Expected the open task to be reported, got %+v", resp.UnfinishedTasks)
+	}
+
+	var sprintID *int64
+	if err := ts.DB.QueryRow(`SELECT sprint_id FROM tasks WHERE id = ?`, tasks[1].ID).Scan(&sprintID); err != nil {
+		t.Fatalf("Failed to load task: %v", err)
+	}
+	if sprintID == nil || *sprintID != next {
+		t.Errorf("Expected the open task to move to the next sprint, got %v", sprintID)
+	}
+	history, err := ts.DB.ListTaskHistory(context.Background(), tasks[1].ID)
+	if err != nil {
+		t.Fatalf("ListTaskHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Field != "sprint" || *history[0].OldValue != "Sprint 1" || *history[0].NewValue != "Sprint 2" {
+		t.Errorf("Expected the move in the task's history, got %+v", history)
+	}
+
+	rec = routerRequest(t, ts, http.MethodPost, completePath, nil, owner)
+	AssertStatusCode(t, rec.Code, http.StatusConflict)
+
+	t.Run("analytics keep the final state", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/sprints/%d/analytics", current), nil, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var analytics SprintAnalytics
+		DecodeJSON(t, rec, &analytics)
+		if len(analytics.Days) != 1 || analytics.CommittedTasks != 2 || analytics.CompletedTasks != 1 ||
+			analytics.Days[0].RemainingHours != 2 {
+			t.Errorf("Unexpected analytics: %+v", analytics)
+		}
+	})
+
+	t.Run("velocity covers completed sprints", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/sprints/velocity", projectID), nil, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var report VelocityReport
+		DecodeJSON(t, rec, &report)
+		if len(report.Sprints) != 1 || report.Sprints[0].SprintID != int(current) || report.Sprints[0].CommittedTasks == nil ||
+			*report.Sprints[0].CommittedTasks != 2 || report.AverageCompletedTasks != 1 || report.AverageCompletedHours != 2 {
+			t.Errorf("Unexpected velocity: %+v", report)
+		}
+
+		rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/sprints/velocity?count=0", projectID), nil, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	})
+}
@@ -110,7 +110,7 @@ func (s *Server) HandleListSprints(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -181,7 +181,7 @@ func (s *Server) HandleCreateSprint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -243,6 +243,7 @@ func (s *Server) HandleCreateSprint(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondJSON(w, http.StatusCreated, sp)
+	go s.emitWebhookEvent(projectID, "sprint.created", sp)
 }
 
 // HandleUpdateSprint updates a sprint (verifies project membership via stored project_id).
@@ -275,7 +276,7 @@ func (s *Server) HandleUpdateSprint(w http.ResponseWriter, r *http.Request) {
 
 	// If sprint has a project_id, verify membership
 	if projectID != nil {
-		hasAccess, err := s.checkProjectAccess(ctx, userID, *projectID)
+		hasAccess, err := s.authorizeProject(ctx, userID, *projectID, PermProjectEdit)
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
@@ -361,6 +362,9 @@ func (s *Server) HandleUpdateSprint(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondJSON(w, http.StatusOK, sprint)
+	if projectID != nil {
+		go s.emitWebhookEvent(*projectID, "sprint.updated", sprint)
+	}
 }
 
 // HandleDeleteSprint deletes a sprint (verifies project membership via stored project_id).
@@ -393,7 +397,7 @@ func (s *Server) HandleDeleteSprint(w http.ResponseWriter, r *http.Request) {
 
 	// If sprint has a project_id, verify membership
 	if projectID != nil {
-		hasAccess, err := s.checkProjectAccess(ctx, userID, *projectID)
+		hasAccess, err := s.authorizeProject(ctx, userID, *projectID, PermProjectEdit)
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
@@ -411,6 +415,12 @@ func (s *Server) HandleDeleteSprint(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondJSON(w, http.StatusOK, map[string]string{"message": "Sprint deleted successfully"})
+	if projectID != nil {
+		go s.emitWebhookEvent(*projectID, "sprint.deleted", map[string]int64{
+			"id":         sprintID,
+			"project_id": *projectID,
+		})
+	}
 }
 
 // HandleListTags returns all tags for a project.
@@ -429,7 +439,7 @@ func (s *Server) HandleListTags(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -489,7 +499,7 @@ func (s *Server) HandleCreateTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to check access", "internal_error")
 		return
@@ -575,7 +585,7 @@ func (s *Server) HandleUpdateTag(w http.ResponseWriter, r *http.Request) {
 
 	// If tag has a project_id, verify membership
 	if projectID != nil {
-		hasAccess, err := s.checkProjectAccess(ctx, userID, *projectID)
+		hasAccess, err := s.authorizeProject(ctx, userID, *projectID, PermProjectEdit)
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
@@ -655,7 +665,7 @@ func (s *Server) HandleDeleteTag(w http.ResponseWriter, r *http.Request) {
 
 	// If tag has a project_id, verify membership
 	if projectID != nil {
-		hasAccess, err := s.checkProjectAccess(ctx, userID, *projectID)
+		hasAccess, err := s.authorizeProject(ctx, userID, *projectID, PermProjectEdit)
 		if err != nil {
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
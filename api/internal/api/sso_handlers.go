@@ -0,0 +1,848 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	gologin "github.com/anchoo2kewl/go-login"
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent/teammember"
+	"taskai/internal/auth"
+	"taskai/internal/db"
+	"taskai/internal/sso"
+)
+
+const (
+	// ssoStateExpiry is how long a user has to log in at the identity provider
+	ssoStateExpiry = 10 * time.Minute
+	// ssoCookie binds an OpenID Connect login to the browser that started it
+	ssoCookie = "taskai_sso"
+	// ssoLinkExpiry is how long an emailed account link confirmation lasts
+	ssoLinkExpiry = time.Hour
+)
+
+// emailDomainPattern matches the DNS names accepted as single sign-on email
+// domains
+var emailDomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+// lookupTXT resolves the TXT records that verify email domains; tests
+// replace it
+var lookupTXT = net.DefaultResolver.LookupTXT
+
+// TeamSSORequest configures a team's single sign-on
+type TeamSSORequest struct {
+	Protocol     string   `json:"protocol"`
+	Enabled      bool     `json:"enabled"`
+	Enforced     bool     `json:"enforced"`
+	EmailDomains []string `json:"email_domains"`
+	OIDCIssuer   string   `json:"oidc_issuer"`
+	OIDCClientID string   `json:"oidc_client_id"`
+	// OIDCClientSecret is write-only; leave it empty to keep the current one
+	OIDCClientSecret string `json:"oidc_client_secret"`
+	SAMLMetadata     string `json:"saml_metadata"`
+}
+
+// TeamSSOResponse is a team's single sign-on configuration, with the URLs
+// to register at the identity provider. Protocol is empty when single
+// sign-on isn't configured yet.
+type TeamSSOResponse struct {
+	db.TeamSSOConfig
+	HasClientSecret bool   `json:"has_client_secret"`
+	LoginURL        string `json:"login_url"`
+	OIDCRedirectURL string `json:"oidc_redirect_url"`
+	SAMLEntityID    string `json:"saml_entity_id"`
+	SAMLACSURL      string `json:"saml_acs_url"`
+}
+
+// SSODiscoveryResponse tells the login page where to send a user
+type SSODiscoveryResponse struct {
+	TeamID   int64  `json:"team_id"`
+	Protocol string `json:"protocol"`
+	Enforced bool   `json:"enforced"`
+	LoginURL string `json:"login_url"`
+}
+
+// ssoStateClaims travel through the identity provider as the OIDC state or
+// SAML RelayState
+type ssoStateClaims struct {
+	TeamID    int64  `json:"team_id"`
+	Nonce     string `json:"nonce"`
+	RequestID string `json:"request_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ssoLinkClaims confirm connecting an existing account to the identity
+// provider login that matched its email address
+type ssoLinkClaims struct {
+	TeamID  int64  `json:"team_id"`
+	UserID  int64  `json:"user_id"`
+	Subject string `json:"subject"`
+	Email   string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// ssoKey derives a key for purpose from the JWT secret. State tokens are
+// signed with their own key so they can never pass for access tokens.
+func (s *Server) ssoKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.JWTSecret))
+	mac.Write([]byte("sso:" + purpose))
+	return mac.Sum(nil)
+}
+
+func (s *Server) signSSOState(claims ssoStateClaims) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ssoStateExpiry)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.ssoKey("state"))
+}
+
+func (s *Server) parseSSOState(tokenStr string, teamID int64) (*ssoStateClaims, error) {
+	claims := &ssoStateClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.ssoKey("state"), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if claims.TeamID != teamID {
+		return nil, errors.New("state is for another team")
+	}
+	return claims, nil
+}
+
+// codeVerifier derives the PKCE code verifier of an OIDC login from its
+// nonce, so it doesn't have to be stored
+func (s *Server) codeVerifier(nonce string) string {
+	mac := hmac.New(sha256.New, s.ssoKey("pkce"))
+	mac.Write([]byte(nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ssoURL returns the URL of a team's single sign-on endpoint
+func (s *Server) ssoURL(teamID int64, path string) string {
+	return fmt.Sprintf("%s/api/auth/sso/%d%s", strings.TrimRight(s.config.AppURL, "/"), teamID, path)
+}
+
+func (s *Server) serviceProvider(teamID int64) *sso.ServiceProvider {
+	return &sso.ServiceProvider{
+		EntityID: s.ssoURL(teamID, "/saml/metadata"),
+		ACSURL:   s.ssoURL(teamID, "/saml/acs"),
+	}
+}
+
+func (s *Server) oidcClient(cfg *db.TeamSSOConfig) sso.OIDCClient {
+	return sso.OIDCClient{
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  s.ssoURL(cfg.TeamID, "/oidc/callback"),
+	}
+}
+
+func (s *Server) teamSSOResponse(cfg *db.TeamSSOConfig) TeamSSOResponse {
+	return TeamSSOResponse{
+		TeamSSOConfig:   *cfg,
+		HasClientSecret: cfg.OIDCClientSecret != "",
+		LoginURL:        s.ssoURL(cfg.TeamID, "/login"),
+		OIDCRedirectURL: s.ssoURL(cfg.TeamID, "/oidc/callback"),
+		SAMLEntityID:    s.ssoURL(cfg.TeamID, "/saml/metadata"),
+		SAMLACSURL:      s.ssoURL(cfg.TeamID, "/saml/acs"),
+	}
+}
+
+// requireTeamOwner returns the team of a user who owns it, or responds with
+// an error.
+func (s *Server) requireTeamOwner(ctx context.Context, w http.ResponseWriter, userID int64) (int64, bool) {
+	teamID, err := s.getUserTeamID(ctx, userID)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "no active team found", "not_found")
+		return 0, false
+	}
+	role, err := s.getUserTeamRole(ctx, userID, teamID)
+	if err != nil || role != "owner" {
+		respondError(w, http.StatusForbidden, "only the team owner can manage identity provider settings", "forbidden")
+		return 0, false
+	}
+	return teamID, true
+}
+
+// HandleGetTeamSSO returns the team's single sign-on configuration
+// Route: GET /api/team/sso
+func (s *Server) HandleGetTeamSSO(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	cfg, err := s.db.GetTeamSSOConfig(ctx, teamID)
+	if errors.Is(err, db.ErrTeamSSONotFound) {
+		cfg, err = &db.TeamSSOConfig{TeamID: teamID, EmailDomains: []db.TeamSSODomain{}}, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to get team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to get single sign-on settings", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, s.teamSSOResponse(cfg))
+}
+
+// HandleUpdateTeamSSO configures the team's identity provider
+// Route: PUT /api/team/sso
+func (s *Server) HandleUpdateTeamSSO(w http.ResponseWriter, r *http.Request) {
+	// Discovery can take a while with a slow identity provider
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	var req TeamSSORequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+
+	existing, err := s.db.GetTeamSSOConfig(ctx, teamID)
+	if err != nil && !errors.Is(err, db.ErrTeamSSONotFound) {
+		s.logger.Error("Failed to get team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to save single sign-on settings", "internal_error")
+		return
+	}
+
+	cfg := &db.TeamSSOConfig{
+		TeamID:       teamID,
+		Protocol:     req.Protocol,
+		Enabled:      req.Enabled,
+		Enforced:     req.Enforced,
+		EmailDomains: []db.TeamSSODomain{},
+	}
+	if existing != nil {
+		cfg.CreatedAt = existing.CreatedAt
+	}
+	if req.Enforced && !req.Enabled {
+		respondError(w, http.StatusBadRequest, "single sign-on must be enabled to enforce it", "validation_error")
+		return
+	}
+
+	// Domains only take effect once verified; see HandleVerifyTeamSSODomain
+	for _, d := range req.EmailDomains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+		if d == "" || slices.ContainsFunc(cfg.EmailDomains, func(c db.TeamSSODomain) bool { return c.Domain == d }) {
+			continue
+		}
+		if !emailDomainPattern.MatchString(d) {
+			respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid email domain %q", d), "validation_error")
+			return
+		}
+		if other, err := s.db.FindTeamSSOByEmailDomain(ctx, d); err == nil && other.TeamID != teamID {
+			respondError(w, http.StatusConflict, fmt.Sprintf("%s is already used by another team's single sign-on", d), "domain_taken")
+			return
+		} else if err != nil && !errors.Is(err, db.ErrTeamSSONotFound) {
+			s.logger.Error("Failed to check email domain", zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to save single sign-on settings", "internal_error")
+			return
+		}
+		cfg.EmailDomains = append(cfg.EmailDomains, db.TeamSSODomain{Domain: d})
+	}
+
+	switch req.Protocol {
+	case sso.ProtocolOIDC:
+		cfg.OIDCIssuer = strings.TrimSpace(req.OIDCIssuer)
+		cfg.OIDCClientID = strings.TrimSpace(req.OIDCClientID)
+		cfg.OIDCClientSecret = req.OIDCClientSecret
+		if cfg.OIDCClientSecret == "" && existing != nil && existing.OIDCIssuer == cfg.OIDCIssuer {
+			cfg.OIDCClientSecret = existing.OIDCClientSecret
+		}
+		if err := sso.ValidateIssuerURL(cfg.OIDCIssuer); err != nil {
+			respondError(w, http.StatusBadRequest, "issuer "+err.Error(), "validation_error")
+			return
+		}
+		if cfg.OIDCClientID == "" || cfg.OIDCClientSecret == "" {
+			respondError(w, http.StatusBadRequest, "client ID and client secret are required", "validation_error")
+			return
+		}
+		if _, err := sso.DiscoverOIDC(ctx, cfg.OIDCIssuer); err != nil {
+			respondError(w, http.StatusBadRequest, "could not reach the issuer: "+err.Error(), "invalid_issuer")
+			return
+		}
+	case sso.ProtocolSAML:
+		cfg.SAMLMetadata = strings.TrimSpace(req.SAMLMetadata)
+		if _, err := sso.ParseIdPMetadata([]byte(cfg.SAMLMetadata)); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error(), "invalid_metadata")
+			return
+		}
+	default:
+		respondError(w, http.StatusBadRequest, "protocol must be oidc or saml", "validation_error")
+		return
+	}
+
+	if err := s.db.SaveTeamSSOConfig(ctx, cfg); err != nil {
+		s.logger.Error("Failed to save team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to save single sign-on settings", "internal_error")
+		return
+	}
+
+	s.logger.Info("Team single sign-on updated",
+		zap.Int64("team_id", teamID),
+		zap.Int64("user_id", userID),
+		zap.String("protocol", cfg.Protocol),
+		zap.Bool("enabled", cfg.Enabled),
+		zap.Bool("enforced", cfg.Enforced),
+	)
+	respondJSON(w, http.StatusOK, s.teamSSOResponse(cfg))
+}
+
+// HandleVerifyTeamSSODomain checks that the team published the verification
+// TXT record on an email domain, so its single sign-on can sign in users
+// with addresses there
+// Route: POST /api/team/sso/domains/{domain}/verify
+func (s *Server) HandleVerifyTeamSSODomain(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	cfg, err := s.db.GetTeamSSOConfig(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, db.ErrTeamSSONotFound) {
+			respondError(w, http.StatusNotFound, "single sign-on is not configured", "not_found")
+			return
+		}
+		s.logger.Error("Failed to get team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to verify domain", "internal_error")
+		return
+	}
+	name := strings.ToLower(chi.URLParam(r, "domain"))
+	i := slices.IndexFunc(cfg.EmailDomains, func(d db.TeamSSODomain) bool { return d.Domain == name })
+	if i < 0 {
+		respondError(w, http.StatusNotFound, "email domain not found", "not_found")
+		return
+	}
+	domain := cfg.EmailDomains[i]
+
+	if !domain.Verified() {
+		records, err := lookupTXT(ctx, domain.Domain)
+		if err != nil || !slices.Contains(records, domain.VerificationRecord) {
+			respondError(w, http.StatusBadRequest,
+				fmt.Sprintf("TXT record %q not found on %s", domain.VerificationRecord, domain.Domain), "domain_not_verified")
+			return
+		}
+		if err := s.db.VerifyTeamSSODomain(ctx, teamID, domain.Domain); err != nil {
+			if errors.Is(err, db.ErrSSODomainTaken) {
+				respondError(w, http.StatusConflict, fmt.Sprintf("%s is already used by another team's single sign-on", domain.Domain), "domain_taken")
+				return
+			}
+			s.logger.Error("Failed to verify team sso domain", zap.Error(err), zap.Int64("team_id", teamID))
+			respondError(w, http.StatusInternalServerError, "failed to verify domain", "internal_error")
+			return
+		}
+		s.logger.Info("Team single sign-on domain verified",
+			zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.String("domain", domain.Domain))
+		if cfg, err = s.db.GetTeamSSOConfig(ctx, teamID); err != nil {
+			s.logger.Error("Failed to get team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+			respondError(w, http.StatusInternalServerError, "failed to verify domain", "internal_error")
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, s.teamSSOResponse(cfg))
+}
+
+// HandleDeleteTeamSSO removes the team's single sign-on
+// Route: DELETE /api/team/sso
+func (s *Server) HandleDeleteTeamSSO(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	teamID, ok := s.requireTeamOwner(ctx, w, userID)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteTeamSSOConfig(ctx, teamID); err != nil {
+		if errors.Is(err, db.ErrTeamSSONotFound) {
+			respondError(w, http.StatusNotFound, "single sign-on is not configured", "not_found")
+			return
+		}
+		s.logger.Error("Failed to delete team sso config", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to remove single sign-on", "internal_error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSSODiscover finds the single sign-on for an email address's domain
+// Route: GET /api/auth/sso/discover
+func (s *Server) HandleSSODiscover(w http.ResponseWriter, r *http.Request) {
+	email := strings.TrimSpace(r.URL.Query().Get("email"))
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		respondError(w, http.StatusBadRequest, "a valid email address is required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	cfg, err := s.db.FindTeamSSOByEmailDomain(ctx, email[at+1:])
+	if err != nil {
+		if errors.Is(err, db.ErrTeamSSONotFound) {
+			respondError(w, http.StatusNotFound, "single sign-on is not set up for this email domain", "not_found")
+			return
+		}
+		s.logger.Error("Failed to discover sso", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to look up single sign-on", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SSODiscoveryResponse{
+		TeamID:   cfg.TeamID,
+		Protocol: cfg.Protocol,
+		Enforced: cfg.Enforced,
+		LoginURL: s.ssoURL(cfg.TeamID, "/login"),
+	})
+}
+
+// ssoTeamConfig returns the enabled single sign-on of the team in the URL
+func (s *Server) ssoTeamConfig(ctx context.Context, r *http.Request) (*db.TeamSSOConfig, error) {
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "teamId"), 10, 64)
+	if err != nil {
+		return nil, db.ErrTeamSSONotFound
+	}
+	cfg, err := s.db.GetTeamSSOConfig(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, db.ErrTeamSSONotFound
+	}
+	return cfg, nil
+}
+
+// redirectSSOError ends a failed single sign-on on the login page
+func (s *Server) redirectSSOError(w http.ResponseWriter, r *http.Request, message string) {
+	target := s.config.OAuthErrorURL
+	if target == "" {
+		target = strings.TrimRight(s.config.AppURL, "/") + "/login"
+	}
+	http.Redirect(w, r, target+"?oauth_error="+url.QueryEscape(message), http.StatusFound)
+}
+
+// HandleSSOLogin sends the user to the team's identity provider
+// Route: GET /api/auth/sso/{teamId}/login
+func (s *Server) HandleSSOLogin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	cfg, err := s.ssoTeamConfig(ctx, r)
+	if err != nil {
+		if !errors.Is(err, db.ErrTeamSSONotFound) {
+			s.logger.Error("Failed to get team sso config", zap.Error(err))
+		}
+		s.redirectSSOError(w, r, "Single sign-on is not available for this team")
+		return
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+		return
+	}
+	claims := ssoStateClaims{TeamID: cfg.TeamID, Nonce: base64.RawURLEncoding.EncodeToString(raw)}
+
+	var target string
+	switch cfg.Protocol {
+	case sso.ProtocolOIDC:
+		provider, err := sso.DiscoverOIDC(ctx, cfg.OIDCIssuer)
+		if err != nil {
+			s.logger.Warn("OIDC discovery failed", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+			s.redirectSSOError(w, r, "Your identity provider could not be reached")
+			return
+		}
+		state, err := s.signSSOState(claims)
+		if err != nil {
+			s.logger.Error("Failed to sign sso state", zap.Error(err))
+			s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     ssoCookie,
+			Value:    claims.Nonce,
+			Path:     "/api/auth/sso/",
+			MaxAge:   int(ssoStateExpiry.Seconds()),
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(s.config.AppURL, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+		target = provider.AuthCodeURL(s.oidcClient(cfg), state, claims.Nonce, s.codeVerifier(claims.Nonce))
+
+	case sso.ProtocolSAML:
+		idp, err := sso.ParseIdPMetadata([]byte(cfg.SAMLMetadata))
+		if err != nil {
+			s.logger.Error("Invalid SAML metadata", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+			s.redirectSSOError(w, r, "Single sign-on is misconfigured")
+			return
+		}
+		if claims.RequestID, err = sso.NewRequestID(); err != nil {
+			s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+			return
+		}
+		state, err := s.signSSOState(claims)
+		if err != nil {
+			s.logger.Error("Failed to sign sso state", zap.Error(err))
+			s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+			return
+		}
+		if target, err = s.serviceProvider(cfg.TeamID).AuthnRequestURL(idp, claims.RequestID, state, time.Now()); err != nil {
+			s.logger.Error("Failed to build SAML request", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+			s.redirectSSOError(w, r, "Single sign-on is misconfigured")
+			return
+		}
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// HandleSSOOIDCCallback completes an OpenID Connect login
+// Route: GET /api/auth/sso/{teamId}/oidc/callback
+func (s *Server) HandleSSOOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	cfg, err := s.ssoTeamConfig(ctx, r)
+	if err != nil || cfg.Protocol != sso.ProtocolOIDC {
+		s.redirectSSOError(w, r, "Single sign-on is not available for this team")
+		return
+	}
+
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		s.logger.Info("Identity provider refused login",
+			zap.Int64("team_id", cfg.TeamID), zap.String("error", q.Get("error")), zap.String("description", q.Get("error_description")))
+		s.redirectSSOError(w, r, "Your identity provider refused the login")
+		return
+	}
+
+	// The state must come back to the browser that started the login
+	claims, err := s.parseSSOState(q.Get("state"), cfg.TeamID)
+	cookie, cookieErr := r.Cookie(ssoCookie)
+	if err != nil || cookieErr != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(claims.Nonce)) != 1 {
+		s.redirectSSOError(w, r, "Single sign-on expired, please try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: ssoCookie, Path: "/api/auth/sso/", MaxAge: -1, HttpOnly: true})
+
+	provider, err := sso.DiscoverOIDC(ctx, cfg.OIDCIssuer)
+	if err != nil {
+		s.logger.Warn("OIDC discovery failed", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Your identity provider could not be reached")
+		return
+	}
+	identity, err := provider.Exchange(ctx, s.oidcClient(cfg), q.Get("code"), s.codeVerifier(claims.Nonce), claims.Nonce)
+	if err != nil {
+		s.logger.Warn("OIDC login failed", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Your identity provider's response could not be verified")
+		return
+	}
+
+	s.completeSSOLogin(ctx, w, r, cfg, identity)
+}
+
+// HandleSSOSAMLACS completes a SAML login: the assertion consumer service
+// Route: POST /api/auth/sso/{teamId}/saml/acs
+func (s *Server) HandleSSOSAMLACS(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	cfg, err := s.ssoTeamConfig(ctx, r)
+	if err != nil || cfg.Protocol != sso.ProtocolSAML {
+		s.redirectSSOError(w, r, "Single sign-on is not available for this team")
+		return
+	}
+	idp, err := sso.ParseIdPMetadata([]byte(cfg.SAMLMetadata))
+	if err != nil {
+		s.logger.Error("Invalid SAML metadata", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Single sign-on is misconfigured")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := r.ParseForm(); err != nil {
+		s.redirectSSOError(w, r, "Invalid single sign-on response")
+		return
+	}
+	// The identity provider posts cross-site, so there's no cookie to bind
+	// the login to; the signed request ID and one-time assertions stand in
+	claims, err := s.parseSSOState(r.PostForm.Get("RelayState"), cfg.TeamID)
+	if err != nil || claims.RequestID == "" {
+		s.redirectSSOError(w, r, "Single sign-on expired, please try again")
+		return
+	}
+
+	assertion, err := s.serviceProvider(cfg.TeamID).ParseResponse(r.PostForm.Get("SAMLResponse"), idp, claims.RequestID, time.Now())
+	if err != nil {
+		s.logger.Warn("SAML login failed", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Your identity provider's response could not be verified")
+		return
+	}
+	if err := s.db.UseSAMLAssertion(ctx, cfg.TeamID, assertion.ID, assertion.Expires.Add(5*time.Minute)); err != nil {
+		if !errors.Is(err, db.ErrSAMLAssertionReplayed) {
+			s.logger.Error("Failed to record SAML assertion", zap.Error(err))
+		}
+		s.redirectSSOError(w, r, "Single sign-on expired, please try again")
+		return
+	}
+
+	s.completeSSOLogin(ctx, w, r, cfg, &assertion.Identity)
+}
+
+// HandleSSOSAMLMetadata serves the service provider metadata to register
+// with the team's identity provider
+// Route: GET /api/auth/sso/{teamId}/saml/metadata
+func (s *Server) HandleSSOSAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "teamId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid team ID", "invalid_id")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(s.serviceProvider(teamID).Metadata())
+}
+
+// completeSSOLogin logs in the user an identity provider vouched for and
+// hands a token to the frontend, like Google and GitHub logins. Only
+// addresses in the team's verified domains are accepted. First-time users
+// are provisioned into the team. An existing account is never linked on
+// the identity provider's word alone: its owner has to confirm by email,
+// so an identity provider can't take over accounts.
+func (s *Server) completeSSOLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, cfg *db.TeamSSOConfig, identity *sso.Identity) {
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if !cfg.MatchesEmail(email) {
+		s.redirectSSOError(w, r, "Your email address can't be used with this team's single sign-on")
+		return
+	}
+
+	store := db.NewOAuthStore(s.db)
+	provider := db.SSOProvider(cfg.TeamID)
+	fail := func(err error) {
+		if errors.Is(err, db.ErrUserDeactivated) {
+			s.redirectSSOError(w, r, "Your account has been deactivated. Contact your team owner.")
+			return
+		}
+		s.logger.Error("SSO login failed", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+	}
+
+	u, err := store.FindUserByProviderID(ctx, provider, identity.Subject)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if u == nil {
+		existing, err := store.FindUserByEmail(ctx, email)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if existing == nil {
+			u, err = store.ProvisionSSOUser(ctx, gologin.ProviderUserInfo{
+				ProviderUserID: identity.Subject,
+				Email:          email,
+				FirstName:      identity.FirstName,
+				LastName:       identity.LastName,
+				Name:           identity.Name,
+			}, cfg.TeamID)
+			if err != nil {
+				fail(err)
+				return
+			}
+		} else {
+			s.sendSSOLinkConfirmation(ctx, w, r, cfg, existing.ID, email, identity.Subject)
+			return
+		}
+	} else if _, err := s.getUserTeamRole(ctx, u.ID, cfg.TeamID); err != nil {
+		s.redirectSSOError(w, r, "You are no longer a member of this team")
+		return
+	}
+
+	s.recordLogin(ctx, u.ID, u.Email, getClientIP(r), r.UserAgent())
+
+	token, err := auth.GenerateToken(u.ID, u.Email, s.config.JWTSecret, s.config.JWTExpiry())
+	if err != nil {
+		fail(err)
+		return
+	}
+	target := s.config.OAuthSuccessURL
+	if target == "" {
+		target = strings.TrimRight(s.config.AppURL, "/") + "/oauth/callback"
+	}
+	http.Redirect(w, r, target+"?token="+url.QueryEscape(token), http.StatusFound)
+}
+
+// signSSOLink signs an account link confirmation with a one-time ID
+func (s *Server) signSSOLink(claims ssoLinkClaims) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        base64.RawURLEncoding.EncodeToString(raw),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ssoLinkExpiry)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.ssoKey("link"))
+}
+
+// sendSSOLinkConfirmation emails the owner of an existing account a link
+// that connects it to the identity provider login that matched it
+func (s *Server) sendSSOLinkConfirmation(ctx context.Context, w http.ResponseWriter, r *http.Request, cfg *db.TeamSSOConfig, userID int64, email, subject string) {
+	emailSvc := s.GetEmailService()
+	if emailSvc == nil {
+		s.redirectSSOError(w, r, "An account with your email already exists. Sign in with your password to continue.")
+		return
+	}
+	team, err := s.db.Client.Team.Get(ctx, cfg.TeamID)
+	if err != nil {
+		s.logger.Error("Failed to get team", zap.Error(err), zap.Int64("team_id", cfg.TeamID))
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+		return
+	}
+
+	token, err := s.signSSOLink(ssoLinkClaims{TeamID: cfg.TeamID, UserID: userID, Subject: subject, Email: email})
+	if err != nil {
+		s.logger.Error("Failed to sign sso link", zap.Error(err))
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+		return
+	}
+
+	linkURL := s.ssoURL(cfg.TeamID, "/link?token="+url.QueryEscape(token))
+	if err := emailSvc.SendSSOLinkConfirmation(ctx, email, team.Name, linkURL); err != nil {
+		s.logger.Error("Failed to send sso link confirmation", zap.Error(err), zap.Int64("user_id", userID))
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+		return
+	}
+	s.redirectSSOError(w, r, "An account with your email already exists. We emailed you a link to connect it to your team's single sign-on.")
+}
+
+// HandleSSOLinkConfirm connects an existing account to a team's identity
+// provider once its owner follows the emailed link, then sends them through
+// the identity provider to log in. Following the link also joins the team.
+// Route: GET /api/auth/sso/{teamId}/link
+func (s *Server) HandleSSOLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	cfg, err := s.ssoTeamConfig(ctx, r)
+	if err != nil {
+		s.redirectSSOError(w, r, "Single sign-on is not available for this team")
+		return
+	}
+
+	claims := &ssoLinkClaims{}
+	if _, err := jwt.ParseWithClaims(r.URL.Query().Get("token"), claims, func(t *jwt.Token) (interface{}, error) {
+		return s.ssoKey("link"), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired()); err != nil || claims.TeamID != cfg.TeamID || claims.ID == "" {
+		s.redirectSSOError(w, r, "This link is invalid or has expired")
+		return
+	}
+	// The address must still be the account's and still belong to the team
+	user, err := s.db.Client.User.Get(ctx, claims.UserID)
+	if err != nil || !strings.EqualFold(user.Email, claims.Email) || !cfg.MatchesEmail(claims.Email) {
+		s.redirectSSOError(w, r, "This link is invalid or has expired")
+		return
+	}
+	if err := s.db.UseSSOLink(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		if !errors.Is(err, db.ErrSSOLinkUsed) {
+			s.logger.Error("Failed to record sso link", zap.Error(err))
+		}
+		s.redirectSSOError(w, r, "This link has already been used")
+		return
+	}
+
+	if _, err := s.getUserTeamRole(ctx, user.ID, cfg.TeamID); err != nil {
+		exists, err := s.db.Client.TeamMember.Query().
+			Where(teammember.TeamID(cfg.TeamID), teammember.UserID(user.ID)).
+			Exist(ctx)
+		if err != nil {
+			s.logger.Error("Failed to check team membership", zap.Error(err))
+			s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+			return
+		}
+		if exists {
+			s.redirectSSOError(w, r, "You are no longer a member of this team")
+			return
+		}
+		if _, err := s.db.Client.TeamMember.Create().
+			SetTeamID(cfg.TeamID).
+			SetUserID(user.ID).
+			SetRole("member").
+			SetStatus("active").
+			Save(ctx); err != nil {
+			s.logger.Error("Failed to add team member", zap.Error(err))
+			s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+			return
+		}
+	}
+	if _, err := db.NewOAuthStore(s.db).LinkOAuthProvider(ctx, user.ID, db.SSOProvider(cfg.TeamID), claims.Subject); err != nil {
+		if errors.Is(err, db.ErrUserDeactivated) {
+			s.redirectSSOError(w, r, "Your account has been deactivated. Contact your team owner.")
+			return
+		}
+		s.logger.Error("Failed to link sso identity", zap.Error(err), zap.Int64("user_id", user.ID))
+		s.redirectSSOError(w, r, "Single sign-on failed, please try again")
+		return
+	}
+
+	s.logger.Info("Account connected to team single sign-on",
+		zap.Int64("team_id", cfg.TeamID), zap.Int64("user_id", user.ID))
+	http.Redirect(w, r, s.ssoURL(cfg.TeamID, "/login"), http.StatusFound)
+}
+
+// respondSSORequired refuses a password or passkey login from a member of a
+// team that enforces single sign-on. It reports whether it did.
+func (s *Server) respondSSORequired(ctx context.Context, w http.ResponseWriter, userID int64) bool {
+	teamID, err := s.db.SSOEnforcedTeam(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to check enforced sso", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return true
+	}
+	if teamID == 0 {
+		return false
+	}
+	respondError(w, http.StatusForbidden, "your team requires you to sign in with single sign-on", "sso_required")
+	return true
+}
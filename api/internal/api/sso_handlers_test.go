@@ -0,0 +1,542 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"taskai/internal/auth"
+	"taskai/internal/db"
+	"taskai/internal/sso"
+	"taskai/internal/sso/ssotest"
+)
+
+const testAppURL = "http://localhost:8080"
+
+// ssoRequest builds a request to a public single sign-on endpoint of a team
+func ssoRequest(method, target string, body *strings.Reader, teamID int64) *http.Request {
+	var req *http.Request
+	if body == nil {
+		req = httptest.NewRequest(method, target, nil)
+	} else {
+		req = httptest.NewRequest(method, target, body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("teamId", strconv.FormatInt(teamID, 10))
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// ssoToken returns the token a finished single sign-on hands to the
+// frontend, failing with the error it redirected with instead
+func ssoToken(t *testing.T, ts *TestServer, rec *httptest.ResponseRecorder) *auth.Claims {
+	t.Helper()
+
+	AssertStatusCode(t, rec.Code, http.StatusFound)
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+	if msg := loc.Query().Get("oauth_error"); msg != "" {
+		t.Fatalf("Login failed: %s", msg)
+	}
+	if loc.Path != "/oauth/callback" {
+		t.Fatalf("Expected a redirect to the OAuth callback, got %s", loc)
+	}
+	claims, err := auth.ValidateToken(loc.Query().Get("token"), ts.config.JWTSecret)
+	if err != nil {
+		t.Fatalf("Invalid token: %v", err)
+	}
+	return claims
+}
+
+// assertSSOError checks that a single sign-on ended on the login page
+func assertSSOError(t *testing.T, rec *httptest.ResponseRecorder, wantContains string) {
+	t.Helper()
+
+	AssertStatusCode(t, rec.Code, http.StatusFound)
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect: %v", err)
+	}
+	if loc.Path != "/login" || !strings.Contains(loc.Query().Get("oauth_error"), wantContains) {
+		t.Errorf("Expected a login error containing %q, got %s", wantContains, loc)
+	}
+}
+
+// allowPrivateIssuers lets a test reach the identity provider on loopback
+func allowPrivateIssuers(t *testing.T) {
+	t.Helper()
+	sso.AllowPrivateIssuers = true
+	t.Cleanup(func() { sso.AllowPrivateIssuers = false })
+}
+
+// stubTXT makes domain verification see the given TXT records
+func stubTXT(t *testing.T, records map[string][]string) {
+	t.Helper()
+	orig := lookupTXT
+	lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		return records[name], nil
+	}
+	t.Cleanup(func() { lookupTXT = orig })
+}
+
+// verifySSODomain publishes a team's verification record and verifies the
+// domain
+func verifySSODomain(t *testing.T, ts *TestServer, ownerID int64, domain string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	teamID, err := ts.getUserTeamID(context.Background(), ownerID)
+	if err != nil {
+		t.Fatalf("Failed to get team: %v", err)
+	}
+	cfg, err := ts.DB.GetTeamSSOConfig(context.Background(), teamID)
+	if err != nil {
+		t.Fatalf("Failed to get sso config: %v", err)
+	}
+	records := map[string][]string{}
+	for _, d := range cfg.EmailDomains {
+		records[d.Domain] = []string{"v=spf1 -all", d.VerificationRecord}
+	}
+	stubTXT(t, records)
+
+	rec, r := ts.MakeAuthRequest(t, http.MethodPost, "/api/team/sso/domains/"+domain+"/verify", nil, ownerID, map[string]string{"domain": domain})
+	ts.HandleVerifyTeamSSODomain(rec, r)
+	return rec
+}
+
+// setupSSOTeam creates a team whose single sign-on has verified
+// corp.example
+func setupSSOTeam(t *testing.T, ts *TestServer, idp *ssotest.IdP, protocol string) int64 {
+	t.Helper()
+
+	allowPrivateIssuers(t)
+	ts.config.AppURL = testAppURL
+	ownerID := ts.CreateTestUser(t, "owner@corp.example", "password123")
+	teamID := createTestTeam(t, ts, ownerID, "Corp")
+
+	req := TeamSSORequest{Protocol: protocol, Enabled: true, EmailDomains: []string{"Corp.example"}}
+	if protocol == "oidc" {
+		req.OIDCIssuer = idp.Issuer()
+		req.OIDCClientID = idp.ClientID
+		req.OIDCClientSecret = idp.ClientSecret
+	} else {
+		req.SAMLMetadata = string(idp.Metadata())
+	}
+	rec, r := ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", req, ownerID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	AssertStatusCode(t, verifySSODomain(t, ts, ownerID, "corp.example").Code, http.StatusOK)
+	return teamID
+}
+
+func TestTeamSSOSettings(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	idp := ssotest.New()
+	defer idp.Close()
+
+	allowPrivateIssuers(t)
+	ts.config.AppURL = testAppURL
+	ownerID := ts.CreateTestUser(t, "owner@corp.example", "password123")
+	memberID := ts.CreateTestUser(t, "member@corp.example", "password123")
+	teamID := createTestTeam(t, ts, ownerID, "Corp")
+	addTeamMember(t, ts, teamID, memberID, "admin")
+
+	rec, r := ts.MakeAuthRequest(t, http.MethodGet, "/api/team/sso", nil, ownerID, nil)
+	ts.HandleGetTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp TeamSSOResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.Protocol != "" || resp.SAMLACSURL != testAppURL+"/api/auth/sso/"+strconv.FormatInt(teamID, 10)+"/saml/acs" {
+		t.Errorf("Unexpected settings before configuring: %+v", resp)
+	}
+
+	oidc := TeamSSORequest{
+		Protocol:         "oidc",
+		Enabled:          true,
+		EmailDomains:     []string{"@Corp.example", "corp.example"},
+		OIDCIssuer:       idp.Issuer(),
+		OIDCClientID:     idp.ClientID,
+		OIDCClientSecret: idp.ClientSecret,
+	}
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", oidc, memberID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertError(t, rec, http.StatusForbidden, "team owner", "forbidden")
+
+	invalid := []struct {
+		name   string
+		modify func(req *TeamSSORequest)
+		code   string
+	}{
+		{"unknown protocol", func(req *TeamSSORequest) { req.Protocol = "ldap" }, "validation_error"},
+		{"enforced but disabled", func(req *TeamSSORequest) { req.Enabled = false; req.Enforced = true }, "validation_error"},
+		{"invalid domain", func(req *TeamSSORequest) { req.EmailDomains = []string{"corp"} }, "validation_error"},
+		{"domain with a path", func(req *TeamSSORequest) { req.EmailDomains = []string{"corp.example/x"} }, "validation_error"},
+		{"insecure issuer", func(req *TeamSSORequest) { req.OIDCIssuer = "http://idp.example" }, "validation_error"},
+		{"missing client secret", func(req *TeamSSORequest) { req.OIDCClientSecret = "" }, "validation_error"},
+		{"unreachable issuer", func(req *TeamSSORequest) { req.OIDCIssuer = idp.Issuer() + "/other" }, "invalid_issuer"},
+		{"invalid SAML metadata", func(req *TeamSSORequest) { req.Protocol = "saml"; req.SAMLMetadata = "<x/>" }, "invalid_metadata"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			req := oidc
+			tt.modify(&req)
+			rec, r := ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", req, ownerID, nil)
+			ts.HandleUpdateTeamSSO(rec, r)
+			AssertError(t, rec, http.StatusBadRequest, "", tt.code)
+		})
+	}
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", oidc, ownerID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	if strings.Contains(rec.Body.String(), idp.ClientSecret) {
+		t.Error("Response must not contain the client secret")
+	}
+	DecodeJSON(t, rec, &resp)
+	if !resp.HasClientSecret || len(resp.EmailDomains) != 1 || resp.EmailDomains[0].Domain != "corp.example" ||
+		resp.EmailDomains[0].VerifiedAt != nil || !strings.HasPrefix(resp.EmailDomains[0].VerificationRecord, db.SSODomainVerificationPrefix) {
+		t.Errorf("Unexpected settings: %+v", resp)
+	}
+	record := resp.EmailDomains[0].VerificationRecord
+
+	// Leaving the secret out keeps it
+	oidc.OIDCClientSecret = ""
+	oidc.Enforced = true
+	rec, r = ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", oidc, ownerID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	cfg, err := ts.DB.GetTeamSSOConfig(context.Background(), teamID)
+	if err != nil || cfg.OIDCClientSecret != idp.ClientSecret || !cfg.Enforced {
+		t.Errorf("Expected the secret to be kept, got %+v, %v", cfg, err)
+	}
+
+	if cfg.EmailDomains[0].VerificationRecord != record {
+		t.Errorf("Expected saving again to keep the verification token, got %+v", cfg.EmailDomains)
+	}
+
+	// Until verified, another team can claim the domain too
+	otherOwnerID := ts.CreateTestUser(t, "owner@other.example", "password123")
+	createTestTeam(t, ts, otherOwnerID, "Other")
+	oidc.OIDCClientSecret = idp.ClientSecret
+	rec, r = ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", oidc, otherOwnerID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	stubTXT(t, map[string][]string{"corp.example": {"v=spf1 -all"}})
+	rec, r = ts.MakeAuthRequest(t, http.MethodPost, "/api/team/sso/domains/corp.example/verify", nil, ownerID, map[string]string{"domain": "corp.example"})
+	ts.HandleVerifyTeamSSODomain(rec, r)
+	AssertError(t, rec, http.StatusBadRequest, record, "domain_not_verified")
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodPost, "/api/team/sso/domains/other.example/verify", nil, ownerID, map[string]string{"domain": "other.example"})
+	ts.HandleVerifyTeamSSODomain(rec, r)
+	AssertError(t, rec, http.StatusNotFound, "domain", "not_found")
+
+	rec = verifySSODomain(t, ts, ownerID, "corp.example")
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	DecodeJSON(t, rec, &resp)
+	if resp.EmailDomains[0].VerifiedAt == nil {
+		t.Errorf("Expected the domain to be verified, got %+v", resp.EmailDomains)
+	}
+
+	// Once verified, no other team can verify or claim it
+	AssertError(t, verifySSODomain(t, ts, otherOwnerID, "corp.example"), http.StatusConflict, "corp.example", "domain_taken")
+	rec, r = ts.MakeAuthRequest(t, http.MethodPut, "/api/team/sso", oidc, otherOwnerID, nil)
+	ts.HandleUpdateTeamSSO(rec, r)
+	AssertError(t, rec, http.StatusConflict, "corp.example", "domain_taken")
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodDelete, "/api/team/sso", nil, ownerID, nil)
+	ts.HandleDeleteTeamSSO(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+	rec, r = ts.MakeAuthRequest(t, http.MethodDelete, "/api/team/sso", nil, ownerID, nil)
+	ts.HandleDeleteTeamSSO(rec, r)
+	AssertError(t, rec, http.StatusNotFound, "not configured", "not_found")
+}
+
+func TestSSODiscover(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	idp := ssotest.New()
+	defer idp.Close()
+
+	teamID := setupSSOTeam(t, ts, idp, "saml")
+
+	rec, r := MakeRequest(t, http.MethodGet, "/api/auth/sso/discover?email=Jane@CORP.example", nil, nil)
+	ts.HandleSSODiscover(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp SSODiscoveryResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.TeamID != teamID || resp.Protocol != "saml" || resp.LoginURL != testAppURL+"/api/auth/sso/"+strconv.FormatInt(teamID, 10)+"/login" {
+		t.Errorf("Unexpected discovery: %+v", resp)
+	}
+
+	rec, r = MakeRequest(t, http.MethodGet, "/api/auth/sso/discover?email=jane@example.com", nil, nil)
+	ts.HandleSSODiscover(rec, r)
+	AssertError(t, rec, http.StatusNotFound, "not set up", "not_found")
+
+	rec, r = MakeRequest(t, http.MethodGet, "/api/auth/sso/discover?email=jane", nil, nil)
+	ts.HandleSSODiscover(rec, r)
+	AssertError(t, rec, http.StatusBadRequest, "email", "validation_error")
+}
+
+func TestOIDCSingleSignOn(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	idp := ssotest.New()
+	defer idp.Close()
+
+	teamID := setupSSOTeam(t, ts, idp, "oidc")
+	jane := ssotest.User{Subject: "u-1", Email: "jane@corp.example", FirstName: "Jane", LastName: "Doe"}
+
+	// login goes through the identity provider and returns the callback
+	login := func(t *testing.T, user ssotest.User) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLogin(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/login", nil, teamID))
+		AssertStatusCode(t, rec.Code, http.StatusFound)
+		callback, err := idp.Authorize(rec.Header().Get("Location"), user)
+		if err != nil {
+			t.Fatalf("Authorize failed: %v", err)
+		}
+		req := ssoRequest(http.MethodGet, callback, nil, teamID)
+		for _, c := range rec.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rec = httptest.NewRecorder()
+		ts.HandleSSOOIDCCallback(rec, req)
+		return rec
+	}
+
+	claims := ssoToken(t, ts, login(t, jane))
+	if claims.Email != "jane@corp.example" {
+		t.Errorf("Expected a token for jane, got %+v", claims)
+	}
+	if role, err := ts.getUserTeamRole(context.Background(), claims.UserID, teamID); err != nil || role != "member" {
+		t.Errorf("Expected jane to join the team as a member, got %q, %v", role, err)
+	}
+	ts.waitForActivity(t, claims.UserID, "login", 1)
+
+	// The same identity logs into the same account
+	if again := ssoToken(t, ts, login(t, jane)); again.UserID != claims.UserID {
+		t.Errorf("Expected user %d, got %d", claims.UserID, again.UserID)
+	}
+
+	t.Run("state from another browser", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLogin(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/login", nil, teamID))
+		callback, err := idp.Authorize(rec.Header().Get("Location"), jane)
+		if err != nil {
+			t.Fatalf("Authorize failed: %v", err)
+		}
+		rec = httptest.NewRecorder()
+		ts.HandleSSOOIDCCallback(rec, ssoRequest(http.MethodGet, callback, nil, teamID))
+		assertSSOError(t, rec, "expired")
+	})
+
+	t.Run("email outside the team's domains", func(t *testing.T) {
+		assertSSOError(t, login(t, ssotest.User{Subject: "u-2", Email: "eve@evil.example"}), "can't be used")
+	})
+
+	t.Run("unverified domain", func(t *testing.T) {
+		if _, err := ts.DB.Exec(`UPDATE team_sso_domains SET verified_at = NULL WHERE team_id = ?`, teamID); err != nil {
+			t.Fatalf("Failed to unverify domain: %v", err)
+		}
+		defer ts.DB.Exec(`UPDATE team_sso_domains SET verified_at = CURRENT_TIMESTAMP WHERE team_id = ?`, teamID)
+		assertSSOError(t, login(t, ssotest.User{Subject: "u-6", Email: "joan@corp.example"}), "can't be used")
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		assertSSOError(t, login(t, ssotest.User{Subject: "u-3", Email: "joe@corp.example", Unverified: true}), "could not be verified")
+	})
+
+	// confirmLink follows an emailed account link confirmation
+	confirmLink := func(t *testing.T, claims ssoLinkClaims) *httptest.ResponseRecorder {
+		t.Helper()
+		token, err := ts.signSSOLink(claims)
+		if err != nil {
+			t.Fatalf("Failed to sign link: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLinkConfirm(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/link?token="+url.QueryEscape(token), nil, teamID))
+		return rec
+	}
+
+	t.Run("existing account needs its owner's confirmation", func(t *testing.T) {
+		bobID := ts.CreateTestUser(t, "bob@corp.example", "password123")
+		bob := ssotest.User{Subject: "u-4", Email: "bob@corp.example"}
+		assertSSOError(t, login(t, bob), "already exists")
+
+		// Membership alone doesn't let the identity provider in
+		addTeamMember(t, ts, teamID, bobID, "member")
+		assertSSOError(t, login(t, bob), "already exists")
+
+		rec := confirmLink(t, ssoLinkClaims{TeamID: teamID, UserID: bobID, Subject: bob.Subject, Email: bob.Email})
+		AssertStatusCode(t, rec.Code, http.StatusFound)
+		if loc := rec.Header().Get("Location"); loc != testAppURL+"/api/auth/sso/"+strconv.FormatInt(teamID, 10)+"/login" {
+			t.Fatalf("Expected a redirect to single sign-on, got %s", loc)
+		}
+		if claims := ssoToken(t, ts, login(t, bob)); claims.UserID != bobID {
+			t.Errorf("Expected user %d, got %d", bobID, claims.UserID)
+		}
+	})
+
+	t.Run("confirming joins the team", func(t *testing.T) {
+		aliceID := ts.CreateTestUser(t, "alice@corp.example", "password123")
+		alice := ssotest.User{Subject: "u-5", Email: "alice@corp.example"}
+		AssertStatusCode(t, confirmLink(t, ssoLinkClaims{TeamID: teamID, UserID: aliceID, Subject: alice.Subject, Email: alice.Email}).Code, http.StatusFound)
+		if role, err := ts.getUserTeamRole(context.Background(), aliceID, teamID); err != nil || role != "member" {
+			t.Errorf("Expected alice to join the team as a member, got %q, %v", role, err)
+		}
+		if claims := ssoToken(t, ts, login(t, alice)); claims.UserID != aliceID {
+			t.Errorf("Expected user %d, got %d", aliceID, claims.UserID)
+		}
+	})
+
+	t.Run("invalid link confirmations", func(t *testing.T) {
+		carolID := ts.CreateTestUser(t, "carol@corp.example", "password123")
+		token, err := ts.signSSOLink(ssoLinkClaims{TeamID: teamID, UserID: carolID, Subject: "u-7", Email: "carol@corp.example"})
+		if err != nil {
+			t.Fatalf("Failed to sign link: %v", err)
+		}
+		follow := func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			ts.HandleSSOLinkConfirm(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/link?token="+url.QueryEscape(token), nil, teamID))
+			return rec
+		}
+		AssertStatusCode(t, follow().Code, http.StatusFound)
+		assertSSOError(t, follow(), "already been used")
+
+		assertSSOError(t, confirmLink(t, ssoLinkClaims{TeamID: teamID, UserID: carolID, Subject: "u-8", Email: "carol@other.example"}), "invalid")
+		assertSSOError(t, confirmLink(t, ssoLinkClaims{TeamID: teamID + 1, UserID: carolID, Subject: "u-8", Email: "carol@corp.example"}), "invalid")
+
+		// A state token is no link confirmation
+		state, err := ts.signSSOState(ssoStateClaims{TeamID: teamID})
+		if err != nil {
+			t.Fatalf("Failed to sign state: %v", err)
+		}
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLinkConfirm(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/link?token="+url.QueryEscape(state), nil, teamID))
+		assertSSOError(t, rec, "invalid")
+	})
+
+	t.Run("state is not an access token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLogin(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/login", nil, teamID))
+		loc, _ := url.Parse(rec.Header().Get("Location"))
+		if _, err := auth.ValidateToken(loc.Query().Get("state"), ts.config.JWTSecret); err == nil {
+			t.Error("Expected the state to be rejected as an access token")
+		}
+	})
+}
+
+func TestSAMLSingleSignOn(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	idp := ssotest.New()
+	defer idp.Close()
+
+	teamID := setupSSOTeam(t, ts, idp, "saml")
+	jane := ssotest.User{Subject: "jane@corp.example", Email: "jane@corp.example", FirstName: "Jane", LastName: "Doe"}
+
+	// login returns what the identity provider posts back
+	login := func(t *testing.T) (*ssotest.Response, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		ts.HandleSSOLogin(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/login", nil, teamID))
+		AssertStatusCode(t, rec.Code, http.StatusFound)
+		resp, relayState, err := idp.Login(rec.Header().Get("Location"), jane)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		return resp, relayState
+	}
+	post := func(resp *ssotest.Response, relayState string) *httptest.ResponseRecorder {
+		form := url.Values{"SAMLResponse": {idp.Encode(resp)}, "RelayState": {relayState}}
+		rec := httptest.NewRecorder()
+		ts.HandleSSOSAMLACS(rec, ssoRequest(http.MethodPost, "/api/auth/sso/x/saml/acs", strings.NewReader(form.Encode()), teamID))
+		return rec
+	}
+
+	resp, relayState := login(t)
+	claims := ssoToken(t, ts, post(resp, relayState))
+	if claims.Email != "jane@corp.example" {
+		t.Errorf("Expected a token for jane, got %+v", claims)
+	}
+
+	t.Run("replayed assertion", func(t *testing.T) {
+		assertSSOError(t, post(resp, relayState), "expired")
+	})
+
+	t.Run("unsolicited response", func(t *testing.T) {
+		resp, _ := login(t)
+		assertSSOError(t, post(resp, ""), "expired")
+	})
+
+	t.Run("answer to another request", func(t *testing.T) {
+		resp, _ := login(t)
+		_, relayState := login(t)
+		assertSSOError(t, post(resp, relayState), "could not be verified")
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		ts.HandleSSOSAMLMetadata(rec, ssoRequest(http.MethodGet, "/api/auth/sso/x/saml/metadata", nil, teamID))
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if !strings.Contains(rec.Body.String(), `entityID="`+testAppURL+"/api/auth/sso/"+strconv.FormatInt(teamID, 10)+`/saml/metadata"`) {
+			t.Errorf("Unexpected metadata: %s", rec.Body.String())
+		}
+	})
+}
+
+func TestSSOEnforced(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+	idp := ssotest.New()
+	defer idp.Close()
+
+	teamID := setupSSOTeam(t, ts, idp, "saml")
+	memberID := ts.CreateTestUser(t, "member@corp.example", "password123")
+	addTeamMember(t, ts, teamID, memberID, "member")
+	outsiderID := ts.CreateTestUser(t, "outsider@other.example", "password123")
+	addTeamMember(t, ts, teamID, outsiderID, "member")
+
+	passwordLogin := func(email string) *httptest.ResponseRecorder {
+		rec, r := MakeRequest(t, http.MethodPost, "/api/auth/login", LoginRequest{Email: email, Password: "password123"}, nil)
+		ts.HandleLogin(rec, r)
+		return rec
+	}
+	linkSSO := func(userID int64, subject string) {
+		if _, err := db.NewOAuthStore(ts.DB).LinkOAuthProvider(context.Background(), userID, db.SSOProvider(teamID), subject); err != nil {
+			t.Fatalf("Failed to link sso: %v", err)
+		}
+	}
+
+	linkSSO(memberID, "member@corp.example")
+	AssertStatusCode(t, passwordLogin("member@corp.example").Code, http.StatusOK)
+
+	if _, err := ts.DB.Exec(`UPDATE team_sso_configs SET enforced = 1 WHERE team_id = ?`, teamID); err != nil {
+		t.Fatalf("Failed to enforce sso: %v", err)
+	}
+
+	AssertError(t, passwordLogin("member@corp.example"), http.StatusForbidden, "single sign-on", "sso_required")
+
+	// Members the team added without them signing in through its identity
+	// provider, or outside its verified domains, aren't locked out
+	forcedID := ts.CreateTestUser(t, "forced@corp.example", "password123")
+	addTeamMember(t, ts, teamID, forcedID, "member")
+	AssertStatusCode(t, passwordLogin("forced@corp.example").Code, http.StatusOK)
+	linkSSO(outsiderID, "outsider@other.example")
+	AssertStatusCode(t, passwordLogin("outsider@other.example").Code, http.StatusOK)
+
+	// The owner can still get in if the identity provider breaks
+	AssertStatusCode(t, passwordLogin("owner@corp.example").Code, http.StatusOK)
+}
@@ -12,6 +12,7 @@ import (
 
 	"taskai/ent"
 	"taskai/ent/swimlane"
+	"taskai/internal/db"
 )
 
 type SwimLane struct {
@@ -21,15 +22,19 @@ type SwimLane struct {
 	Color          string    `json:"color"`
 	Position       int       `json:"position"`
 	StatusCategory string    `json:"status_category"`
+	WIPLimit       *int      `json:"wip_limit,omitempty"`
+	WIPEnforcement string    `json:"wip_enforcement"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 type CreateSwimLaneRequest struct {
-	Name           string `json:"name"`
-	Color          string `json:"color"`
-	Position       int    `json:"position"`
-	StatusCategory string `json:"status_category"`
+	Name           string  `json:"name"`
+	Color          string  `json:"color"`
+	Position       int     `json:"position"`
+	StatusCategory string  `json:"status_category"`
+	WIPLimit       *int    `json:"wip_limit,omitempty"`
+	WIPEnforcement *string `json:"wip_enforcement,omitempty"`
 }
 
 type UpdateSwimLaneRequest struct {
@@ -37,6 +42,33 @@ type UpdateSwimLaneRequest struct {
 	Color          *string `json:"color,omitempty"`
 	Position       *int    `json:"position,omitempty"`
 	StatusCategory *string `json:"status_category,omitempty"`
+	WIPLimit       *int    `json:"wip_limit,omitempty"`
+	WIPEnforcement *string `json:"wip_enforcement,omitempty"`
+}
+
+// applyWIPLimit applies the WIP limit fields of a request to a lane's
+// current limit: a limit of 0 removes it, and enforcement defaults to soft.
+// It returns false if they're invalid.
+func applyWIPLimit(l db.WIPLimit, limit *int, enforcement *string) (db.WIPLimit, bool) {
+	if limit != nil {
+		if *limit < 0 {
+			return l, false
+		}
+		l.Limit = limit
+		if *limit == 0 {
+			l.Limit = nil
+		}
+	}
+	if enforcement != nil {
+		if *enforcement != db.WIPSoft && *enforcement != db.WIPHard {
+			return l, false
+		}
+		l.Enforcement = *enforcement
+	}
+	if l.Enforcement == "" {
+		l.Enforcement = db.WIPSoft
+	}
+	return l, true
 }
 
 // HandleListSwimLanes returns all swim lanes for a project
@@ -53,7 +85,7 @@ func (s *Server) HandleListSwimLanes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		s.logger.Error("Failed to verify project access", zap.Error(err), zap.Int64("userID", userID), zap.Int64("projectID", projectID))
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
@@ -75,6 +107,13 @@ func (s *Server) HandleListSwimLanes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	limits, err := s.db.GetWIPLimits(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to fetch WIP limits", zap.Error(err), zap.Int64("projectID", projectID))
+		respondError(w, http.StatusInternalServerError, "failed to fetch swim lanes", "internal_error")
+		return
+	}
+
 	swimLanes := make([]SwimLane, 0, len(entSwimLanes))
 	for _, esl := range entSwimLanes {
 		swimLanes = append(swimLanes, SwimLane{
@@ -84,6 +123,8 @@ func (s *Server) HandleListSwimLanes(w http.ResponseWriter, r *http.Request) {
 			Color:          esl.Color,
 			Position:       esl.Position,
 			StatusCategory: esl.StatusCategory,
+			WIPLimit:       limits[esl.ID].Limit,
+			WIPEnforcement: limits[esl.ID].Enforcement,
 			CreatedAt:      esl.CreatedAt,
 			UpdatedAt:      esl.UpdatedAt,
 		})
@@ -106,7 +147,7 @@ func (s *Server) HandleCreateSwimLane(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
 	if err != nil {
 		s.logger.Error("Failed to verify project access", zap.Error(err), zap.Int64("userID", userID), zap.Int64("projectID", projectID))
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
@@ -148,6 +189,12 @@ func (s *Server) HandleCreateSwimLane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	wip, ok := applyWIPLimit(db.WIPLimit{}, req.WIPLimit, req.WIPEnforcement)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid WIP limit (wip_limit must be 0 or more, wip_enforcement soft or hard)", "invalid_input")
+		return
+	}
+
 	// Check swim lane count limit (max 6)
 	count, err := s.db.Client.SwimLane.Query().
 		Where(swimlane.ProjectID(projectID)).
@@ -180,6 +227,13 @@ func (s *Server) HandleCreateSwimLane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// WIP limits aren't in the ent schema
+	if err := s.db.SetWIPLimit(ctx, newSwimLane.ID, wip); err != nil {
+		s.logger.Error("Failed to set WIP limit", zap.Error(err), zap.Int64("swimLaneID", newSwimLane.ID))
+		respondError(w, http.StatusInternalServerError, "failed to create swim lane", "internal_error")
+		return
+	}
+
 	sl := SwimLane{
 		ID:             newSwimLane.ID,
 		ProjectID:      newSwimLane.ProjectID,
@@ -187,12 +241,15 @@ func (s *Server) HandleCreateSwimLane(w http.ResponseWriter, r *http.Request) {
 		Color:          newSwimLane.Color,
 		Position:       newSwimLane.Position,
 		StatusCategory: newSwimLane.StatusCategory,
+		WIPLimit:       wip.Limit,
+		WIPEnforcement: wip.Enforcement,
 		CreatedAt:      newSwimLane.CreatedAt,
 		UpdatedAt:      newSwimLane.UpdatedAt,
 	}
 
 	s.logger.Info("Swim lane created", zap.Int64("swimLaneID", sl.ID), zap.Int64("projectID", projectID), zap.String("name", req.Name))
 	respondJSON(w, http.StatusCreated, sl)
+	go s.emitWebhookEvent(projectID, "swim_lane.created", sl)
 }
 
 // HandleUpdateSwimLane updates an existing swim lane
@@ -230,7 +287,7 @@ func (s *Server) HandleUpdateSwimLane(w http.ResponseWriter, r *http.Request) {
 	projectID := swimLaneEntity.ProjectID
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
 	if err != nil {
 		s.logger.Error("Failed to verify project access", zap.Error(err), zap.Int64("userID", userID), zap.Int64("projectID", projectID))
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
@@ -277,12 +334,31 @@ func (s *Server) HandleUpdateSwimLane(w http.ResponseWriter, r *http.Request) {
 		updateBuilder.SetStatusCategory(*req.StatusCategory)
 	}
 
+	limits, err := s.db.GetWIPLimits(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to fetch WIP limits", zap.Error(err), zap.Int64("projectID", projectID))
+		respondError(w, http.StatusInternalServerError, "failed to update swim lane", "internal_error")
+		return
+	}
+	wip, ok := applyWIPLimit(limits[swimLaneID], req.WIPLimit, req.WIPEnforcement)
+	if !ok {
+		respondError(w, http.StatusBadRequest, "invalid WIP limit (wip_limit must be 0 or more, wip_enforcement soft or hard)", "invalid_input")
+		return
+	}
+
 	updatedSwimLane, err := updateBuilder.Save(ctx)
 	if err != nil {
 		s.logger.Error("Failed to update swim lane", zap.Error(err), zap.Int64("swimLaneID", swimLaneID))
 		respondError(w, http.StatusInternalServerError, "failed to update swim lane", "internal_error")
 		return
 	}
+	if req.WIPLimit != nil || req.WIPEnforcement != nil {
+		if err := s.db.SetWIPLimit(ctx, swimLaneID, wip); err != nil {
+			s.logger.Error("Failed to set WIP limit", zap.Error(err), zap.Int64("swimLaneID", swimLaneID))
+			respondError(w, http.StatusInternalServerError, "failed to update swim lane", "internal_error")
+			return
+		}
+	}
 
 	sl := SwimLane{
 		ID:             updatedSwimLane.ID,
@@ -291,12 +367,15 @@ func (s *Server) HandleUpdateSwimLane(w http.ResponseWriter, r *http.Request) {
 		Color:          updatedSwimLane.Color,
 		Position:       updatedSwimLane.Position,
 		StatusCategory: updatedSwimLane.StatusCategory,
+		WIPLimit:       wip.Limit,
+		WIPEnforcement: wip.Enforcement,
 		CreatedAt:      updatedSwimLane.CreatedAt,
 		UpdatedAt:      updatedSwimLane.UpdatedAt,
 	}
 
 	s.logger.Info("Swim lane updated", zap.Int64("swimLaneID", swimLaneID), zap.Int64("projectID", projectID))
 	respondJSON(w, http.StatusOK, sl)
+	go s.emitWebhookEvent(projectID, "swim_lane.updated", sl)
 }
 
 // HandleDeleteSwimLane deletes a swim lane
@@ -327,7 +406,7 @@ func (s *Server) HandleDeleteSwimLane(w http.ResponseWriter, r *http.Request) {
 	projectID := swimLaneEntity.ProjectID
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectEdit)
 	if err != nil {
 		s.logger.Error("Failed to verify project access", zap.Error(err), zap.Int64("userID", userID), zap.Int64("projectID", projectID))
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
@@ -362,4 +441,8 @@ func (s *Server) HandleDeleteSwimLane(w http.ResponseWriter, r *http.Request) {
 
 	s.logger.Info("Swim lane deleted", zap.Int64("swimLaneID", swimLaneID), zap.Int64("projectID", projectID))
 	w.WriteHeader(http.StatusNoContent)
+	go s.emitWebhookEvent(projectID, "swim_lane.deleted", map[string]int64{
+		"id":         swimLaneID,
+		"project_id": projectID,
+	})
 }
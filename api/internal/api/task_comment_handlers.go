@@ -61,7 +61,7 @@ func (s *Server) HandleListTaskComments(w http.ResponseWriter, r *http.Request)
 	projectID := taskEntity.ProjectID
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -158,7 +158,7 @@ func (s *Server) HandleCreateTaskComment(w http.ResponseWriter, r *http.Request)
 	projectID := taskEntity.ProjectID
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermTaskComment)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -242,6 +242,7 @@ func (s *Server) HandleCreateTaskComment(w http.ResponseWriter, r *http.Request)
 	}
 	taskLink := "/app/projects/" + int64ToStr(projectID) + "/tasks/" + strconv.Itoa(taskNum)
 	go s.notifyTaskComment(context.Background(), taskID, projectID, userID, c.ID, taskEntity.Title, c.Comment, displayName, taskLink, taskEntity.AssigneeID)
+	go s.emitWebhookEvent(projectID, "comment.created", c)
 
 	respondJSON(w, http.StatusCreated, c)
 }
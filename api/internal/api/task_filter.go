@@ -0,0 +1,567 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	entsql "entgo.io/ent/dialect/sql"
+
+	"taskai/ent"
+	"taskai/ent/task"
+)
+
+const (
+	// maxTaskPageSize caps the limit query param on task listings.
+	maxTaskPageSize = 500
+	// defaultTaskSort matches the historical ordering of HandleListTasks.
+	defaultTaskSort = "-created_at"
+)
+
+// taskSortField describes a sortable task column. expr uses {t} as the table
+// alias placeholder so the same expression can be evaluated against the
+// cursor's anchor row.
+type taskSortField struct {
+	expr     string
+	nullable bool
+}
+
+// taskSortFields lists the columns accepted by the sort query param.
+var taskSortFields = map[string]taskSortField{
+	"created_at":  {expr: "{t}.created_at"},
+	"updated_at":  {expr: "{t}.updated_at"},
+	"title":       {expr: "{t}.title"},
+	"task_number": {expr: "{t}.task_number", nullable: true},
+	"due_date":    {expr: "{t}.due_date", nullable: true},
+	"start_date":  {expr: "{t}.start_date", nullable: true},
+	"priority": {expr: "CASE {t}.priority WHEN 'urgent' THEN 4 WHEN 'high' THEN 3 " +
+		"WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END"},
+}
+
+// errInvalidTaskCursor is returned when a cursor cannot be decoded or no longer
+// points at a task in the project.
+var errInvalidTaskCursor = errors.New("invalid cursor")
+
+// taskCursor is the decoded form of the opaque cursor handed to clients.
+// It records the last task of the previous page and the sort it was taken from.
+type taskCursor struct {
+	ID   int64  `json:"id"`
+	Sort string `json:"sort"`
+}
+
+// encodeTaskCursor serialises a cursor into an opaque URL-safe token.
+func encodeTaskCursor(c taskCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeTaskCursor parses a token produced by encodeTaskCursor.
+func decodeTaskCursor(token string) (taskCursor, error) {
+	var c taskCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, errInvalidTaskCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return c, errInvalidTaskCursor
+	}
+	return c, nil
+}
+
+// taskListQuery holds the parsed filters, sort and pagination for HandleListTasks.
+type taskListQuery struct {
+	Statuses    []string
+	Priorities  []string
+	SwimLaneIDs []int64
+	NoSwimLane  bool
+	SprintIDs   []int64
+	NoSprint    bool
+	AssigneeIDs []int64
+	Unassigned  bool
+	TagIDs      []int64
+	MatchAllTag bool
+	DueFrom     *time.Time
+	DueBefore   *time.Time // exclusive upper bound
+	AgentName   *string
+	Text        string
+
+	CustomFields []customFieldFilter // sorted by field ID
+
+	SortField string
+	SortDesc  bool
+	Limit     int // 0 means unlimited
+	Cursor    *taskCursor
+}
+
+// customFieldFilter matches tasks with any of the values in a custom field,
+// or with the field unset when None is true
+type customFieldFilter struct {
+	FieldID int64
+	Values  []string
+	None    bool
+}
+
+// parseIDList parses a comma-separated list of IDs. The keyword "none" sets
+// the returned flag instead of adding an ID. When me is non-zero the keyword
+// "me" is replaced by that ID.
+func parseIDList(raw string, me int64) ([]int64, bool, error) {
+	var ids []int64
+	none := false
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case part == "none":
+			none = true
+		case part == "me" && me != 0:
+			ids = append(ids, me)
+		default:
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil || id <= 0 {
+				return nil, false, fmt.Errorf("invalid id %q", part)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, none, nil
+}
+
+// parseStringList splits a comma-separated list and validates every entry
+// against the allowed set.
+func parseStringList(raw string, allowed map[string]bool) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !allowed[part] {
+			return nil, fmt.Errorf("invalid value %q", part)
+		}
+		out = append(out, part)
+	}
+	return out, nil
+}
+
+var (
+	validTaskStatuses   = map[string]bool{"todo": true, "in_progress": true, "done": true}
+	validTaskPriorities = map[string]bool{"low": true, "medium": true, "high": true, "urgent": true}
+)
+
+// parseTaskListQuery reads the HandleListTasks query string. Every parameter is
+// optional; with none set the result matches the unfiltered listing.
+//
+//	status=todo,in_progress       priority=high,urgent
+//	swim_lane_id=3,4|none         sprint_id=7|none
+//	assignee_id=me,12|none        tag_ids=1,2 (tag_match=all)
+//	due_from=2025-01-01           due_to=2025-01-31 (inclusive)
+//	agent_name=claude             query=free text
+//	cf_<field id>=a,b|none        (custom field values)
+//	sort=-priority                limit=50  cursor=<opaque>
+//
+// Custom field values are checked against the fields by the caller.
+func parseTaskListQuery(values url.Values, userID int64) (*taskListQuery, error) {
+	q := &taskListQuery{}
+	var err error
+
+	if v := values.Get("status"); v != "" {
+		if q.Statuses, err = parseStringList(v, validTaskStatuses); err != nil {
+			return nil, fmt.Errorf("status: %w", err)
+		}
+	}
+	if v := values.Get("priority"); v != "" {
+		if q.Priorities, err = parseStringList(v, validTaskPriorities); err != nil {
+			return nil, fmt.Errorf("priority: %w", err)
+		}
+	}
+	if v := values.Get("swim_lane_id"); v != "" {
+		if q.SwimLaneIDs, q.NoSwimLane, err = parseIDList(v, 0); err != nil {
+			return nil, fmt.Errorf("swim_lane_id: %w", err)
+		}
+	}
+	if v := values.Get("sprint_id"); v != "" {
+		if q.SprintIDs, q.NoSprint, err = parseIDList(v, 0); err != nil {
+			return nil, fmt.Errorf("sprint_id: %w", err)
+		}
+	}
+	if v := values.Get("assignee_id"); v != "" {
+		if q.AssigneeIDs, q.Unassigned, err = parseIDList(v, userID); err != nil {
+			return nil, fmt.Errorf("assignee_id: %w", err)
+		}
+	}
+	if v := values.Get("tag_ids"); v != "" {
+		if q.TagIDs, _, err = parseIDList(v, 0); err != nil {
+			return nil, fmt.Errorf("tag_ids: %w", err)
+		}
+	}
+	switch values.Get("tag_match") {
+	case "", "any":
+	case "all":
+		q.MatchAllTag = true
+	default:
+		return nil, errors.New("tag_match: must be any or all")
+	}
+
+	if v := values.Get("due_from"); v != "" {
+		if q.DueFrom = parseDate(v); q.DueFrom == nil {
+			return nil, errors.New("due_from: expected YYYY-MM-DD or RFC3339")
+		}
+	}
+	if v := values.Get("due_to"); v != "" {
+		d := parseDate(v)
+		if d == nil {
+			return nil, errors.New("due_to: expected YYYY-MM-DD or RFC3339")
+		}
+		// A plain date covers the whole day.
+		if len(v) == len("2006-01-02") {
+			next := d.AddDate(0, 0, 1)
+			d = &next
+		} else {
+			next := d.Add(time.Nanosecond)
+			d = &next
+		}
+		q.DueBefore = d
+	}
+
+	if v := strings.TrimSpace(values.Get("agent_name")); v != "" {
+		q.AgentName = &v
+	}
+	q.Text = strings.TrimSpace(values.Get("query"))
+
+	for key := range values {
+		if !strings.HasPrefix(key, "cf_") {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, "cf_"), 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%s: invalid custom field", key)
+		}
+		filter := customFieldFilter{FieldID: id}
+		for _, part := range strings.Split(values.Get(key), ",") {
+			part = strings.TrimSpace(part)
+			switch part {
+			case "":
+			case "none":
+				filter.None = true
+			default:
+				filter.Values = append(filter.Values, part)
+			}
+		}
+		if len(filter.Values) > 0 || filter.None {
+			q.CustomFields = append(q.CustomFields, filter)
+		}
+	}
+	sort.Slice(q.CustomFields, func(i, j int) bool { return q.CustomFields[i].FieldID < q.CustomFields[j].FieldID })
+
+	sortSpec := values.Get("sort")
+	if sortSpec == "" {
+		sortSpec = defaultTaskSort
+	}
+	q.SortDesc = strings.HasPrefix(sortSpec, "-")
+	q.SortField = strings.TrimPrefix(sortSpec, "-")
+	if _, ok := taskSortFields[q.SortField]; !ok {
+		return nil, fmt.Errorf("sort: unsupported field %q", q.SortField)
+	}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxTaskPageSize {
+			return nil, fmt.Errorf("limit: must be between 1 and %d", maxTaskPageSize)
+		}
+		q.Limit = n
+	}
+	if v := values.Get("cursor"); v != "" {
+		c, err := decodeTaskCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		if c.Sort != sortSpec {
+			return nil, errors.New("cursor does not match sort")
+		}
+		q.Cursor = &c
+	}
+
+	return q, nil
+}
+
+// sortSpec returns the normalised sort string, as stored in cursors.
+func (q *taskListQuery) sortSpec() string {
+	if q.SortDesc {
+		return "-" + q.SortField
+	}
+	return q.SortField
+}
+
+// inPlaceholders returns "?, ?, ?" for n values.
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// likeEscaper escapes LIKE wildcards so free-text search matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// build returns the SQL (with ? placeholders) selecting matching task IDs in
+// sort order. anchorNull is only consulted when a cursor is set and the sort
+// column is nullable.
+func (q *taskListQuery) build(projectID int64, anchorNull bool) (string, []interface{}) {
+	where := []string{"t.project_id = ?"}
+	args := []interface{}{projectID}
+
+	if len(q.Statuses) > 0 {
+		where = append(where, "t.status IN ("+inPlaceholders(len(q.Statuses))+")")
+		for _, s := range q.Statuses {
+			args = append(args, s)
+		}
+	}
+	if len(q.Priorities) > 0 {
+		where = append(where, "t.priority IN ("+inPlaceholders(len(q.Priorities))+")")
+		for _, p := range q.Priorities {
+			args = append(args, p)
+		}
+	}
+
+	// nullableIn matches a nullable FK column against a list and/or NULL.
+	nullableIn := func(col string, ids []int64, orNull bool) {
+		var parts []string
+		if len(ids) > 0 {
+			parts = append(parts, col+" IN ("+inPlaceholders(len(ids))+")")
+			for _, id := range ids {
+				args = append(args, id)
+			}
+		}
+		if orNull {
+			parts = append(parts, col+" IS NULL")
+		}
+		if len(parts) > 0 {
+			where = append(where, "("+strings.Join(parts, " OR ")+")")
+		}
+	}
+	nullableIn("t.swim_lane_id", q.SwimLaneIDs, q.NoSwimLane)
+	nullableIn("t.sprint_id", q.SprintIDs, q.NoSprint)
+
+	// Assignees live in both the legacy tasks.assignee_id column and the
+	// task_assignees table; a task matches if either side does.
+	if len(q.AssigneeIDs) > 0 || q.Unassigned {
+		var parts []string
+		if len(q.AssigneeIDs) > 0 {
+			ph := inPlaceholders(len(q.AssigneeIDs))
+			parts = append(parts, "t.assignee_id IN ("+ph+")",
+				"EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.task_id = t.id AND ta.user_id IN ("+ph+"))")
+			for range 2 {
+				for _, id := range q.AssigneeIDs {
+					args = append(args, id)
+				}
+			}
+		}
+		if q.Unassigned {
+			parts = append(parts, "(t.assignee_id IS NULL AND NOT EXISTS (SELECT 1 FROM task_assignees ta WHERE ta.task_id = t.id))")
+		}
+		where = append(where, "("+strings.Join(parts, " OR ")+")")
+	}
+
+	if len(q.TagIDs) > 0 {
+		ph := inPlaceholders(len(q.TagIDs))
+		if q.MatchAllTag {
+			where = append(where, "(SELECT COUNT(DISTINCT tt.tag_id) FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id IN ("+ph+")) = ?")
+		} else {
+			where = append(where, "EXISTS (SELECT 1 FROM task_tags tt WHERE tt.task_id = t.id AND tt.tag_id IN ("+ph+"))")
+		}
+		for _, id := range q.TagIDs {
+			args = append(args, id)
+		}
+		if q.MatchAllTag {
+			args = append(args, len(q.TagIDs))
+		}
+	}
+
+	if q.DueFrom != nil {
+		where = append(where, "t.due_date >= ?")
+		args = append(args, *q.DueFrom)
+	}
+	if q.DueBefore != nil {
+		where = append(where, "t.due_date < ?")
+		args = append(args, *q.DueBefore)
+	}
+	if q.AgentName != nil {
+		where = append(where, "t.agent_name = ?")
+		args = append(args, *q.AgentName)
+	}
+	for _, cf := range q.CustomFields {
+		var parts []string
+		if len(cf.Values) > 0 {
+			parts = append(parts, "EXISTS (SELECT 1 FROM task_custom_field_values v WHERE v.task_id = t.id AND v.field_id = ? AND v.value IN ("+inPlaceholders(len(cf.Values))+"))")
+			args = append(args, cf.FieldID)
+			for _, v := range cf.Values {
+				args = append(args, v)
+			}
+		}
+		if cf.None {
+			parts = append(parts, "NOT EXISTS (SELECT 1 FROM task_custom_field_values v WHERE v.task_id = t.id AND v.field_id = ?)")
+			args = append(args, cf.FieldID)
+		}
+		where = append(where, "("+strings.Join(parts, " OR ")+")")
+	}
+	if q.Text != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(q.Text)) + "%"
+		where = append(where, `(LOWER(t.title) LIKE ? ESCAPE '\' OR LOWER(COALESCE(t.description, '')) LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	field := taskSortFields[q.SortField]
+	expr := strings.ReplaceAll(field.expr, "{t}", "t")
+	op := ">"
+	if q.SortDesc {
+		op = "<"
+	}
+
+	// Keyset pagination: compare against the anchor row's own value so the
+	// comparison never depends on how a driver formats bound parameters.
+	if q.Cursor != nil {
+		anchor := "(SELECT " + strings.ReplaceAll(field.expr, "{t}", "a") + " FROM tasks a WHERE a.id = ?)"
+		switch {
+		case field.nullable && anchorNull:
+			// Nulls sort last, so only the remaining null rows can follow.
+			where = append(where, "("+expr+" IS NULL AND t.id "+op+" ?)")
+			args = append(args, q.Cursor.ID)
+		case field.nullable:
+			where = append(where, "("+expr+" "+op+" "+anchor+" OR ("+expr+" = "+anchor+" AND t.id "+op+" ?) OR "+expr+" IS NULL)")
+			args = append(args, q.Cursor.ID, q.Cursor.ID, q.Cursor.ID)
+		default:
+			where = append(where, "("+expr+" "+op+" "+anchor+" OR ("+expr+" = "+anchor+" AND t.id "+op+" ?))")
+			args = append(args, q.Cursor.ID, q.Cursor.ID, q.Cursor.ID)
+		}
+	}
+
+	query := "SELECT t.id FROM tasks t WHERE " + strings.Join(where, " AND ") + " ORDER BY " + q.orderBy("t")
+	if q.Limit > 0 {
+		// Fetch one extra row to learn whether another page exists.
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+	return query, args
+}
+
+// orderBy returns the ORDER BY list for the sort, with table as the tasks
+// table alias. Ties are broken by ID and nulls sort last in both directions.
+func (q *taskListQuery) orderBy(table string) string {
+	field := taskSortFields[q.SortField]
+	expr := strings.ReplaceAll(field.expr, "{t}", table)
+	dir := "ASC"
+	if q.SortDesc {
+		dir = "DESC"
+	}
+	order := expr + " " + dir + ", " + table + ".id " + dir
+	if field.nullable {
+		order = expr + " IS NULL, " + order
+	}
+	return order
+}
+
+// cursorAnchorNull reports whether the cursor's anchor task has a NULL sort
+// value, and fails with errInvalidTaskCursor when the anchor is gone.
+func (s *Server) cursorAnchorNull(ctx context.Context, projectID int64, q *taskListQuery) (bool, error) {
+	if q.Cursor == nil {
+		return false, nil
+	}
+	field := taskSortFields[q.SortField]
+	var isNull bool
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		"SELECT "+strings.ReplaceAll(field.expr, "{t}", "a")+" IS NULL FROM tasks a WHERE a.id = ? AND a.project_id = ?",
+	), q.Cursor.ID, projectID).Scan(&isNull)
+	if err == sql.ErrNoRows {
+		return false, errInvalidTaskCursor
+	}
+	return isNull, err
+}
+
+// queryTasks loads the tasks of the current page in sort order, plus the
+// cursor for the next page ("" when this is the last page). A paged listing
+// resolves the page's IDs first; an unlimited one runs the filter as a
+// subquery so a large project doesn't bind one parameter per task.
+func (s *Server) queryTasks(ctx context.Context, projectID int64, q *taskListQuery) ([]*ent.Task, string, error) {
+	anchorNull, err := s.cursorAnchorNull(ctx, projectID, q)
+	if err != nil {
+		return nil, "", err
+	}
+	query := s.db.Client.Task.Query().WithAssignee().WithSprint()
+
+	if q.Limit == 0 {
+		filter, args := q.build(projectID, anchorNull)
+		tasks, err := query.
+			Where(func(sel *entsql.Selector) {
+				sel.Where(entsql.P(func(b *entsql.Builder) {
+					b.WriteString(sel.C(task.FieldID) + " IN (")
+					for i, part := range strings.Split(filter, "?") {
+						if i > 0 {
+							b.Arg(args[i-1])
+						}
+						b.WriteString(part)
+					}
+					b.WriteString(")")
+				}))
+			}).
+			Order(func(sel *entsql.Selector) {
+				sel.OrderExpr(entsql.Expr(q.orderBy(sel.TableName())))
+			}).
+			All(ctx)
+		return tasks, "", err
+	}
+
+	ids, nextCursor, err := s.queryTaskIDs(ctx, projectID, q, anchorNull)
+	if err != nil || len(ids) == 0 {
+		return nil, "", err
+	}
+	tasks, err := query.Where(task.IDIn(ids...)).All(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Restore the SQL sort order
+	position := make(map[int64]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return position[tasks[i].ID] < position[tasks[j].ID]
+	})
+	return tasks, nextCursor, nil
+}
+
+// queryTaskIDs runs the listing query and returns the IDs of the current page in
+// order, plus the cursor for the next page ("" when this is the last page).
+func (s *Server) queryTaskIDs(ctx context.Context, projectID int64, q *taskListQuery, anchorNull bool) ([]int64, string, error) {
+	query, args := q.build(projectID, anchorNull)
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, "", err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if q.Limit > 0 && len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+		nextCursor = encodeTaskCursor(taskCursor{ID: ids[len(ids)-1], Sort: q.sortSpec()})
+	}
+	return ids, nextCursor, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -12,12 +13,12 @@ import (
 	"go.uber.org/zap"
 
 	"taskai/ent"
-	"taskai/ent/projectmember"
 	"taskai/ent/swimlane"
 	"taskai/ent/tag"
 	"taskai/ent/task"
 	"taskai/ent/taskassignee"
 	"taskai/ent/tasktag"
+	"taskai/internal/db"
 )
 
 // parseDate parses a date string in RFC3339 or YYYY-MM-DD format.
@@ -63,6 +64,10 @@ type Task struct {
 	GithubRepo          string             `json:"github_repo,omitempty"`
 	GithubReactions     []GitHubReaction   `json:"github_reactions,omitempty"`
 	AgentName           *string            `json:"agent_name,omitempty"`
+	Blocked             bool               `json:"blocked"`              // has unfinished blocking tasks
+	BlockedBy           []int64            `json:"blocked_by,omitempty"` // IDs of the unfinished blocking tasks
+	WIPLimitExceeded    bool               `json:"wip_limit_exceeded,omitempty"` // moved into a lane over its soft WIP limit
+	CustomFields        map[string]interface{} `json:"custom_fields,omitempty"` // values by custom field ID
 	CreatedAt           time.Time          `json:"created_at"`
 	UpdatedAt           time.Time          `json:"updated_at"`
 }
@@ -81,6 +86,9 @@ type CreateTaskRequest struct {
 	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
 	ActualHours    *float64 `json:"actual_hours,omitempty"`
 	TagIDs         []int64  `json:"tag_ids,omitempty"`
+
+	// Values by custom field ID or name
+	CustomFields map[string]json.RawMessage `json:"custom_fields,omitempty"`
 }
 
 type UpdateTaskRequest struct {
@@ -97,9 +105,16 @@ type UpdateTaskRequest struct {
 	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
 	ActualHours    *float64 `json:"actual_hours,omitempty"`
 	TagIDs         *[]int64 `json:"tag_ids,omitempty"`
+
+	// Values by custom field ID or name; only the fields given change, and
+	// null clears a field
+	CustomFields map[string]json.RawMessage `json:"custom_fields,omitempty"`
 }
 
-// HandleListTasks returns all tasks for a project
+// HandleListTasks returns the tasks of a project. Filters, sort order and
+// cursor pagination are read from the query string (see parseTaskListQuery);
+// when a page limit is set the cursor for the next page is returned in the
+// X-Next-Cursor header.
 func (s *Server) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -112,7 +127,7 @@ func (s *Server) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -122,13 +137,21 @@ func (s *Server) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch all tasks with eager loading of related entities
-	entTasks, err := s.db.Client.Task.Query().
-		Where(task.ProjectID(projectID)).
-		WithAssignee().
-		WithSprint().
-		Order(ent.Desc(task.FieldCreatedAt)).
-		All(ctx)
+	listQuery, err := parseTaskListQuery(r.URL.Query(), userID)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	if herr := s.resolveCustomFieldFilters(ctx, projectID, listQuery); herr != nil {
+		respondError(w, herr.status, herr.msg, herr.code)
+		return
+	}
+
+	entTasks, nextCursor, err := s.queryTasks(ctx, projectID, listQuery)
+	if err == errInvalidTaskCursor {
+		respondError(w, http.StatusBadRequest, "invalid cursor", "invalid_input")
+		return
+	}
 	if err != nil {
 		s.logger.Error("Failed to fetch tasks",
 			zap.Int64("project_id", projectID),
@@ -137,6 +160,9 @@ func (s *Server) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "failed to fetch tasks", "internal_error")
 		return
 	}
+	if nextCursor != "" {
+		w.Header().Set("X-Next-Cursor", nextCursor)
+	}
 
 	// Manually load swim lanes to handle orphaned foreign keys
 	if len(entTasks) > 0 {
@@ -317,6 +343,10 @@ func (s *Server) HandleListTasks(w http.ResponseWriter, r *http.Request) {
 		tasks = append(tasks, t)
 	}
 
+	// Computed blocked state from task relations
+	s.applyBlockedState(ctx, projectID, tasks)
+	s.applyCustomFields(ctx, projectID, tasks)
+
 	// Bulk-fetch github_issue_number and github_repo (not in ent schema)
 	if len(tasks) > 0 {
 		ghRows, ghErr := s.db.QueryContext(ctx, `
@@ -390,7 +420,7 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermTaskCreate)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -468,6 +498,12 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	customValues, herr := s.resolveCustomFieldValues(ctx, projectID, req.CustomFields, true)
+	if herr != nil {
+		respondError(w, herr.status, herr.msg, herr.code)
+		return
+	}
+
 	// Get next task_number for this project
 	// Note: The UNIQUE index on (project_id, task_number) will prevent duplicates
 	var maxNumber sql.NullInt64
@@ -481,6 +517,14 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		nextNumber = int(maxNumber.Int64) + 1
 	}
 
+	var wipLimit *db.WIPLimit
+	if swimLaneID != nil {
+		if wipLimit, err = s.laneWIPLimit(ctx, projectID, *swimLaneID); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check WIP limit", "internal_error")
+			return
+		}
+	}
+
 	// Parse start_date / due_date — accept RFC3339 or plain YYYY-MM-DD.
 	var startDate *time.Time
 	if req.StartDate != nil {
@@ -499,6 +543,21 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 	}
 	defer entTx.Rollback()
 
+	// Tasks created in a full lane are flagged, or rejected by hard WIP limits
+	var wipExceeded bool
+	if swimLaneID != nil {
+		exceeded, err := checkWIPLimit(ctx, entTx, wipLimit, *swimLaneID, 0)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check WIP limit", "internal_error")
+			return
+		}
+		if exceeded != nil && exceeded.Hard {
+			respondError(w, http.StatusConflict, exceeded.message(), "wip_limit_reached")
+			return
+		}
+		wipExceeded = exceeded != nil
+	}
+
 	// Create task using Ent
 	newTask, err := entTx.Task.Create().
 		SetProjectID(projectID).
@@ -561,6 +620,13 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Custom field values aren't in the ent schema
+	if err := s.db.SetTaskCustomFieldValues(ctx, newTask.ID, customValues); err != nil {
+		s.logger.Error("Failed to save custom field values", zap.Error(err), zap.Int64("task_id", newTask.ID))
+		respondError(w, http.StatusInternalServerError, "failed to save custom fields", "internal_error")
+		return
+	}
+
 	// Fetch the created task with all related entities
 	createdTask, err := s.db.Client.Task.Query().
 		Where(task.ID(newTask.ID)).
@@ -656,8 +722,18 @@ func (s *Server) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	tasks := []Task{t}
+	s.applyCustomFields(ctx, t.ProjectID, tasks)
+	t = tasks[0]
+
+	auditChange(r, auditEvent{
+		Action: "task.created", ResourceType: "task", ResourceID: t.ID, ProjectID: t.ProjectID, After: t,
+	})
+	s.recordLaneTransition(r, nil, &t)
+	t.WIPLimitExceeded = wipExceeded
 	respondJSON(w, http.StatusCreated, t)
 	go s.broadcastToProjectMembers(t.ProjectID, "task_created", t)
+	go s.emitWebhookEvent(t.ProjectID, "task.created", t)
 	if createdTask.Description != nil {
 		taskNum := t.TaskNumber
 		go s.syncGraphLinks(context.Background(), createdTask.ProjectID, "task", createdTask.ID, &taskNum, createdTask.Title, *createdTask.Description)
@@ -694,7 +770,7 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, taskEntity.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, taskEntity.ProjectID, PermTaskEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -704,6 +780,12 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get task", "internal_error")
+		return
+	}
+
 	// Validations
 	if req.Title != nil {
 		if *req.Title == "" {
@@ -776,6 +858,48 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		finalSwimLaneID = req.SwimLaneID
 	}
 
+	// A task can't be completed while tasks blocking it are unfinished
+	if finalStatus != nil && *finalStatus == "done" && taskEntity.Status != "done" {
+		blockers, err := s.loadOpenBlockers(ctx, taskEntity.ProjectID, taskID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check task blockers", "internal_error")
+			return
+		}
+		if n := len(blockers[taskID]); n > 0 {
+			respondError(w, http.StatusConflict,
+				fmt.Sprintf("task is blocked by %d unfinished task(s); complete or unlink them first", n), "task_blocked")
+			return
+		}
+	}
+
+	var wipLimit *db.WIPLimit
+	movingLane := finalSwimLaneID != nil && (before.SwimLaneID == nil || *before.SwimLaneID != *finalSwimLaneID)
+	if movingLane {
+		if wipLimit, err = s.laneWIPLimit(ctx, taskEntity.ProjectID, *finalSwimLaneID); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check WIP limit", "internal_error")
+			return
+		}
+	}
+
+	// Once time is logged on a task, its actual hours are the sum of the entries
+	if req.ActualHours != nil {
+		logged, err := s.db.HasTimeEntries(ctx, taskID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check time entries", "internal_error")
+			return
+		}
+		if logged {
+			respondError(w, http.StatusConflict, "actual_hours is the sum of the task's time entries; log or edit time instead", "time_tracked")
+			return
+		}
+	}
+
+	customValues, herr := s.resolveCustomFieldValues(ctx, taskEntity.ProjectID, req.CustomFields, false)
+	if herr != nil {
+		respondError(w, herr.status, herr.msg, herr.code)
+		return
+	}
+
 	// Parse start_date / due_date — accept RFC3339 or plain YYYY-MM-DD.
 	var startDate *time.Time
 	if req.StartDate != nil {
@@ -786,8 +910,30 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		dueDate = parseDate(*req.DueDate)
 	}
 
+	entTx, err := s.db.Client.Tx(ctx)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to start transaction", "internal_error")
+		return
+	}
+	defer entTx.Rollback()
+
+	// Moves into a full lane are flagged, or rejected by hard WIP limits
+	var wipExceeded bool
+	if movingLane {
+		exceeded, err := checkWIPLimit(ctx, entTx, wipLimit, *finalSwimLaneID, taskID)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to check WIP limit", "internal_error")
+			return
+		}
+		if exceeded != nil && exceeded.Hard {
+			respondError(w, http.StatusConflict, exceeded.message(), "wip_limit_reached")
+			return
+		}
+		wipExceeded = exceeded != nil
+	}
+
 	// Build update using Ent
-	updateBuilder := s.db.Client.Task.UpdateOneID(taskID)
+	updateBuilder := entTx.Task.UpdateOneID(taskID)
 
 	if req.Title != nil {
 		updateBuilder.SetTitle(*req.Title)
@@ -832,6 +978,10 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "failed to update task", "internal_error")
 		return
 	}
+	if err := entTx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to commit task update", "internal_error")
+		return
+	}
 
 	// Best-effort push swim lane change to GitHub Projects V2
 	if finalSwimLaneID != nil {
@@ -876,6 +1026,12 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		go s.tryPushAssigneesToGitHub(context.Background(), taskID)
 	}
 
+	if err := s.db.SetTaskCustomFieldValues(ctx, taskID, customValues); err != nil {
+		s.logger.Error("Failed to save custom field values", zap.Error(err), zap.Int64("task_id", taskID))
+		respondError(w, http.StatusInternalServerError, "failed to update custom fields", "internal_error")
+		return
+	}
+
 	// Fetch the updated task with all related entities
 	updatedTask, err := s.db.Client.Task.Query().
 		Where(task.ID(taskID)).
@@ -994,8 +1150,25 @@ func (s *Server) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	tasks := []Task{t}
+	s.applyCustomFields(ctx, t.ProjectID, tasks)
+	t = tasks[0]
+
+	// Blocked state is derived from other tasks, so it stays out of the diff
+	auditChange(r, auditEvent{
+		Action: "task.updated", ResourceType: "task", ResourceID: taskID, ProjectID: t.ProjectID,
+		Before: before, After: t,
+	})
+	s.recordTaskHistory(r, &before, &t)
+	s.recordLaneTransition(r, &before, &t)
+
+	s.applyBlockedState(ctx, t.ProjectID, tasks)
+	t = tasks[0]
+	t.WIPLimitExceeded = wipExceeded
+
 	respondJSON(w, http.StatusOK, t)
 	go s.broadcastToProjectMembers(t.ProjectID, "task_updated", t)
+	go s.emitWebhookEvent(t.ProjectID, "task.updated", t)
 	if updatedTask.Description != nil {
 		taskNum := t.TaskNumber
 		go s.syncGraphLinks(context.Background(), updatedTask.ProjectID, "task", taskID, &taskNum, updatedTask.Title, *updatedTask.Description)
@@ -1026,7 +1199,7 @@ func (s *Server) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, taskEntity.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, taskEntity.ProjectID, PermTaskDelete)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -1036,6 +1209,12 @@ func (s *Server) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get task", "internal_error")
+		return
+	}
+
 	// Delete task using Ent
 	err = s.db.Client.Task.DeleteOneID(taskID).Exec(ctx)
 	if err != nil {
@@ -1047,11 +1226,17 @@ func (s *Server) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "task.deleted", ResourceType: "task", ResourceID: taskID, ProjectID: taskEntity.ProjectID,
+		Before: before,
+	})
 	w.WriteHeader(http.StatusNoContent)
-	go s.broadcastToProjectMembers(taskEntity.ProjectID, "task_deleted", map[string]int64{
+	deleted := map[string]int64{
 		"id":         taskID,
 		"project_id": taskEntity.ProjectID,
-	})
+	}
+	go s.broadcastToProjectMembers(taskEntity.ProjectID, "task_deleted", deleted)
+	go s.emitWebhookEvent(taskEntity.ProjectID, "task.deleted", deleted)
 }
 
 // HandleGetTaskByNumber returns a single task by project-scoped task number
@@ -1072,7 +1257,7 @@ func (s *Server) HandleGetTaskByNumber(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -1238,7 +1423,11 @@ func (s *Server) HandleGetTaskByNumber(w http.ResponseWriter, r *http.Request) {
 		reactionRows.Close()
 	}
 
-	respondJSON(w, http.StatusOK, t)
+	tasks := []Task{t}
+	s.applyBlockedState(ctx, projectID, tasks)
+	s.applyCustomFields(ctx, projectID, tasks)
+
+	respondJSON(w, http.StatusOK, tasks[0])
 }
 
 // loadTaskAssigneesMap loads task_assignees for a set of task IDs and returns a map[taskID][]TaskAssigneeInfo.
@@ -1277,14 +1466,3 @@ func (s *Server) replaceTaskAssignees(ctx context.Context, taskID int64, userIDs
 	}
 	return nil
 }
-
-// checkProjectAccess verifies that a user has access to a project via project_members table
-func (s *Server) checkProjectAccess(ctx context.Context, userID, projectID int64) (bool, error) {
-	exists, err := s.db.Client.ProjectMember.Query().
-		Where(
-			projectmember.ProjectID(projectID),
-			projectmember.UserID(userID),
-		).
-		Exist(ctx)
-	return exists, err
-}
@@ -498,3 +498,214 @@ func TestHandleListTasksEmptyProject(t *testing.T) {
 		t.Errorf("Expected 0 tasks for empty project, got %d", len(tasks))
 	}
 }
+
+func TestHandleListTasksFilters(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	otherID := ts.CreateTestUser(t, "other@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	insert := func(number int, title, status, priority string) int64 {
+		res, err := ts.DB.ExecContext(ctx,
+			`INSERT INTO tasks (project_id, task_number, title, status, priority) VALUES (?, ?, ?, ?, ?)`,
+			projectID, number, title, status, priority,
+		)
+		if err != nil {
+			t.Fatalf("Failed to create task: %v", err)
+		}
+		id, _ := res.LastInsertId()
+		return id
+	}
+
+	loginID := insert(1, "Fix login bug", "todo", "urgent")
+	docsID := insert(2, "Write docs", "in_progress", "low")
+	releaseID := insert(3, "Ship release", "done", "high")
+
+	// Multi-assignee on one task, legacy assignee_id on another
+	if _, err := ts.DB.ExecContext(ctx, `INSERT INTO task_assignees (task_id, user_id) VALUES (?, ?)`, docsID, otherID); err != nil {
+		t.Fatalf("Failed to add assignee: %v", err)
+	}
+	if _, err := ts.DB.ExecContext(ctx, `UPDATE tasks SET assignee_id = ? WHERE id = ?`, otherID, releaseID); err != nil {
+		t.Fatalf("Failed to set assignee: %v", err)
+	}
+
+	tagRes, _ := ts.DB.ExecContext(ctx, "INSERT INTO tags (user_id, name, color) VALUES (?, ?, ?)", userID, "bug", "#FF0000")
+	tagID, _ := tagRes.LastInsertId()
+	if _, err := ts.DB.ExecContext(ctx, `INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?)`, loginID, tagID); err != nil {
+		t.Fatalf("Failed to tag task: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantIDs   []int64
+		wantError bool
+	}{
+		{name: "no filters", query: "", wantIDs: []int64{releaseID, docsID, loginID}},
+		{name: "status list", query: "status=todo,done", wantIDs: []int64{releaseID, loginID}},
+		{name: "priority", query: "priority=urgent", wantIDs: []int64{loginID}},
+		{name: "assignee via both tables", query: fmt.Sprintf("assignee_id=%d", otherID), wantIDs: []int64{releaseID, docsID}},
+		{name: "unassigned", query: "assignee_id=none", wantIDs: []int64{loginID}},
+		{name: "tag", query: fmt.Sprintf("tag_ids=%d", tagID), wantIDs: []int64{loginID}},
+		{name: "free text", query: "query=DOCS", wantIDs: []int64{docsID}},
+		{name: "sort by priority", query: "sort=-priority", wantIDs: []int64{loginID, releaseID, docsID}},
+		{name: "sort by title ascending", query: "sort=title", wantIDs: []int64{loginID, releaseID, docsID}},
+		{name: "invalid status", query: "status=blocked", wantError: true},
+		{name: "invalid sort", query: "sort=-password", wantError: true},
+		{name: "invalid limit", query: "limit=0", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/projects/1/tasks?"+tt.query, nil, userID,
+				map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+
+			ts.HandleListTasks(rec, req)
+
+			if tt.wantError {
+				AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+				return
+			}
+			AssertStatusCode(t, rec.Code, http.StatusOK)
+
+			var tasks []Task
+			DecodeJSON(t, rec, &tasks)
+
+			if len(tasks) != len(tt.wantIDs) {
+				t.Fatalf("Expected %d tasks, got %d", len(tt.wantIDs), len(tasks))
+			}
+			for i, id := range tt.wantIDs {
+				if tasks[i].ID != id {
+					t.Errorf("Position %d: expected task %d, got %d", i, id, tasks[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleListTasksCursorPagination(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+
+	for i := 1; i <= 5; i++ {
+		ts.CreateTestTask(t, projectID, fmt.Sprintf("Task %d", i))
+	}
+
+	seen := map[int64]bool{}
+	cursor := ""
+	for page := 0; page < 5; page++ {
+		path := "/api/projects/1/tasks?sort=task_number&limit=2"
+		if cursor != "" {
+			path += "&cursor=" + cursor
+		}
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, path, nil, userID,
+			map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+
+		ts.HandleListTasks(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var tasks []Task
+		DecodeJSON(t, rec, &tasks)
+		for _, task := range tasks {
+			if seen[task.ID] {
+				t.Fatalf("Task %d returned twice", task.ID)
+			}
+			seen[task.ID] = true
+			if task.TaskNumber != int64(len(seen)) {
+				t.Errorf("Expected task number %d, got %d", len(seen), task.TaskNumber)
+			}
+		}
+
+		cursor = rec.Header().Get("X-Next-Cursor")
+		if cursor == "" {
+			break
+		}
+	}
+
+	if len(seen) != 5 {
+		t.Errorf("Expected to page through 5 tasks, got %d", len(seen))
+	}
+
+	// A cursor must be used with the sort it was issued for
+	rec, req := ts.MakeAuthRequest(t, http.MethodGet,
+		"/api/projects/1/tasks?sort=title&cursor="+encodeTaskCursor(taskCursor{ID: 1, Sort: "task_number"}), nil, userID,
+		map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+	ts.HandleListTasks(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+}
+
+func TestHandleListTasksDueDateFilter(t *testing.T) {
+	for _, driver := range []string{"sqlite", "postgres"} {
+		t.Run(driver, func(t *testing.T) {
+			ts := NewTestServerForDriver(t, driver)
+			defer ts.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			// Seed through Ent so the data is written the same way on both drivers
+			user := ts.DB.Client.User.Create().SetEmail("test@example.com").SetPasswordHash("x").SaveX(ctx)
+			project := ts.DB.Client.Project.Create().SetOwnerID(user.ID).SetName("Test Project").SaveX(ctx)
+			ts.DB.Client.ProjectMember.Create().SetProjectID(project.ID).SetUserID(user.ID).SetRole("owner").SetGrantedBy(user.ID).SaveX(ctx)
+
+			create := func(number int, due *time.Time) int64 {
+				return ts.DB.Client.Task.Create().
+					SetProjectID(project.ID).
+					SetTaskNumber(number).
+					SetTitle(fmt.Sprintf("Task %d", number)).
+					SetNillableDueDate(due).
+					SaveX(ctx).ID
+			}
+			date := func(day, hour int) *time.Time {
+				d := time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC)
+				return &d
+			}
+			earlyID := create(1, date(1, 9))
+			midID := create(2, date(10, 15))
+			lateID := create(3, date(20, 9))
+			create(4, nil)
+
+			tests := []struct {
+				name    string
+				query   string
+				wantIDs []int64
+			}{
+				{name: "from", query: "due_from=2025-03-10", wantIDs: []int64{midID, lateID}},
+				{name: "to covers the whole day", query: "due_to=2025-03-10", wantIDs: []int64{earlyID, midID}},
+				{name: "range", query: "due_from=2025-03-05&due_to=2025-03-15", wantIDs: []int64{midID}},
+				{name: "to with a time", query: "due_to=2025-03-10T12:00:00Z", wantIDs: []int64{earlyID}},
+				{name: "paged", query: "due_from=2025-03-01&limit=2", wantIDs: []int64{earlyID, midID}},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/projects/1/tasks?sort=due_date&"+tt.query, nil, user.ID,
+						map[string]string{"projectId": fmt.Sprintf("%d", project.ID)})
+
+					ts.HandleListTasks(rec, req)
+					AssertStatusCode(t, rec.Code, http.StatusOK)
+
+					var tasks []Task
+					DecodeJSON(t, rec, &tasks)
+
+					if len(tasks) != len(tt.wantIDs) {
+						t.Fatalf("Expected %d tasks, got %d", len(tt.wantIDs), len(tasks))
+					}
+					for i, id := range tt.wantIDs {
+						if tasks[i].ID != id {
+							t.Errorf("Position %d: expected task %d, got %d", i, id, tasks[i].ID)
+						}
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/internal/db"
+)
+
+// taskHistoryFields are the task fields whose changes are recorded, with how
+// each is shown. Unset fields are nil.
+var taskHistoryFields = []struct {
+	name  string
+	value func(t *Task) *string
+}{
+	{"title", func(t *Task) *string { return &t.Title }},
+	{"description", func(t *Task) *string { return t.Description }},
+	{"status", func(t *Task) *string { return &t.Status }},
+	{"swim_lane", func(t *Task) *string { return t.SwimLaneName }},
+	{"priority", func(t *Task) *string { return &t.Priority }},
+	{"assignees", func(t *Task) *string {
+		names := make([]string, len(t.Assignees))
+		for i, a := range t.Assignees {
+			names[i] = a.UserName
+		}
+		return joinedOrNil(names)
+	}},
+	{"sprint", func(t *Task) *string { return t.SprintName }},
+	{"start_date", func(t *Task) *string { return historyDate(t.StartDate) }},
+	{"due_date", func(t *Task) *string { return historyDate(t.DueDate) }},
+	{"estimated_hours", func(t *Task) *string { return historyHours(t.EstimatedHours) }},
+	{"actual_hours", func(t *Task) *string { return historyHours(t.ActualHours) }},
+	{"tags", func(t *Task) *string {
+		names := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			names[i] = tag.Name
+		}
+		return joinedOrNil(names)
+	}},
+}
+
+// joinedOrNil shows a set of names, in name order so reordering isn't a change
+func joinedOrNil(values []string) *string {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Strings(values)
+	s := strings.Join(values, ", ")
+	return &s
+}
+
+// historyDate shows the day of an RFC 3339 date
+func historyDate(date *string) *string {
+	if date == nil || len(*date) < len("2006-01-02") {
+		return date
+	}
+	day := (*date)[:len("2006-01-02")]
+	return &day
+}
+
+func historyHours(hours *float64) *string {
+	if hours == nil {
+		return nil
+	}
+	s := strconv.FormatFloat(*hours, 'f', -1, 64)
+	return &s
+}
+
+// taskHistoryChanges returns the tracked fields that differ between two
+// versions of a task.
+func taskHistoryChanges(before, after *Task) []db.TaskHistoryEntry {
+	var changes []db.TaskHistoryEntry
+	for _, f := range taskHistoryFields {
+		old, cur := f.value(before), f.value(after)
+		if (old == nil && cur == nil) || (old != nil && cur != nil && *old == *cur) {
+			continue
+		}
+		changes = append(changes, db.TaskHistoryEntry{
+			TaskID: after.ID, ProjectID: after.ProjectID, Field: f.name, OldValue: old, NewValue: cur,
+		})
+	}
+	return changes
+}
+
+// recordTaskHistory stores who changed which fields of a task.
+func (s *Server) recordTaskHistory(r *http.Request, before, after *Task) {
+	s.saveTaskHistory(r, taskHistoryChanges(before, after))
+}
+
+// saveTaskHistory stores field changes made by a request, attributed to its
+// user. Failures are logged rather than returned: the tasks have already been
+// updated.
+func (s *Server) saveTaskHistory(r *http.Request, changes []db.TaskHistoryEntry) {
+	if len(changes) == 0 {
+		return
+	}
+	source := db.AuditChannelWeb
+	if _, ok := r.Context().Value(APIKeyKey).(*db.APIKey); ok {
+		source = db.AuditChannelAPIKey
+	}
+	for i := range changes {
+		if userID, ok := GetUserID(r); ok {
+			changes[i].UserID = &userID
+		}
+		if name := GetAgentName(r); name != nil {
+			changes[i].AgentName = *name
+		}
+		changes[i].Source = source
+	}
+	s.storeTaskHistory(r.Context(), changes)
+}
+
+// recordSyncedTaskChange stores the history and lane transition of a change
+// made without a user, such as a GitHub sync, under source. before is nil
+// when the task was just created.
+func (s *Server) recordSyncedTaskChange(ctx context.Context, source string, before *Task, taskID int64) {
+	after, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		s.logger.Warn("Failed to load task for history", zap.Int64("task_id", taskID), zap.Error(err))
+		return
+	}
+	if before != nil {
+		changes := taskHistoryChanges(before, &after)
+		for i := range changes {
+			changes[i].Source = source
+		}
+		s.storeTaskHistory(ctx, changes)
+	}
+	s.storeLaneTransition(ctx, before, &after)
+}
+
+func (s *Server) storeTaskHistory(ctx context.Context, changes []db.TaskHistoryEntry) {
+	if len(changes) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.db.CreateTaskHistory(ctx, changes); err != nil {
+		s.logger.Error("Failed to record task history", zap.Int64("task_id", changes[0].TaskID), zap.Error(err))
+	}
+}
+
+// HandleGetTaskHistory returns the field changes of a task, oldest first
+// Route: GET /api/tasks/{taskId}/history
+func (s *Server) HandleGetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "taskId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid task ID", "invalid_input")
+		return
+	}
+
+	taskEntity, err := s.db.Client.Task.Get(ctx, taskID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "task not found", "not_found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to get task", "internal_error")
+		return
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, taskEntity.ProjectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	entries, err := s.db.ListTaskHistory(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to list task history", zap.Int64("task_id", taskID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch task history", "internal_error")
+		return
+	}
+
+	// Resolve display names in one query
+	var userIDs []int64
+	for _, e := range entries {
+		if e.UserID != nil {
+			userIDs = append(userIDs, *e.UserID)
+		}
+	}
+	names := s.userDisplayNames(ctx, userIDs)
+	for i, e := range entries {
+		if e.UserID != nil {
+			entries[i].UserName = names[*e.UserID]
+		}
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"taskai/internal/db"
+)
+
+func TestTaskHistory(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
+	strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "History")
+	ts.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	for i, lane := range []struct{ name, category string }{{"To Do", "todo"}, {"In Progress", "in_progress"}} {
+		if _, err := ts.DB.Exec(
+			`INSERT INTO swim_lanes (project_id, name, color, position, status_category) VALUES (?, ?, ?, ?, ?)`,
+			projectID, lane.name, "#6B7280", i, lane.category); err != nil {
+			t.Fatalf("Failed to create swim lane: %v", err)
+		}
+	}
+
+	rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID),
+		map[string]interface{}{"title": "Fix login", "priority": "low"}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var task Task
+	DecodeJSON(t, rec, &task)
+	taskPath := fmt.Sprintf("/api/tasks/%d", task.ID)
+
+	agent := map[string]string{"Authorization": owner["Authorization"], "X-Agent-Name": "triage-bot"}
+	rec = routerRequest(t, ts, http.MethodPatch, taskPath, map[string]interface{}{
+		"priority": "urgent", "status": "in_progress", "title": "Fix login", "estimated_hours": 2.5,
+	}, agent)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	// Unchanged values aren't recorded
+	rec = routerRequest(t, ts, http.MethodPatch, taskPath, map[string]interface{}{"priority": "urgent"}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	rec = routerRequest(t, ts, http.MethodPatch, taskPath, map[string]interface{}{"assignee_ids": []int64{viewerID}}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	viewer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, viewerID, "viewer@example.com")}
+	rec = routerRequest(t, ts, http.MethodGet, taskPath+"/history", nil, viewer)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var history []db.TaskHistoryEntry
+	DecodeJSON(t, rec, &history)
+
+	byField := map[string]db.TaskHistoryEntry{}
+	for _, e := range history {
+		byField[e.Field] = e
+	}
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 field changes, got %+v", history)
+	}
+	priority := byField["priority"]
+	if priority.OldValue == nil || *priority.OldValue != "low" || *priority.NewValue != "urgent" || priority.AgentName != "triage-bot" ||
+		priority.Source != db.AuditChannelWeb || priority.UserName == "" {
+		t.Errorf("Unexpected priority change: %+v", priority)
+	}
+	if lane := byField["swim_lane"]; lane.NewValue == nil || *lane.NewValue != "In Progress" {
+		t.Errorf("Expected the swim lane move to be recorded by name, got %+v", lane)
+	}
+	if hours := byField["estimated_hours"]; hours.OldValue != nil || hours.NewValue == nil || *hours.NewValue != "2.5" {
+		t.Errorf("Unexpected estimate change: %+v", hours)
+	}
+	if assignees := byField["assignees"]; assignees.AgentName != "" || assignees.NewValue == nil {
+		t.Errorf("Unexpected assignee change: %+v", assignees)
+	}
+
+	t.Run("non-members can't read it", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, taskPath+"/history", nil, map[string]string{
+			"Authorization": "Bearer " + ts.GenerateTestToken(t, strangerID, "stranger@example.com"),
+		})
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("updates show in profile activity", func(t *testing.T) {
+		items, err := ts.fetchUserActivity(context.Background(), viewerID, ownerID, nil)
+		if err != nil {
+			t.Fatalf("fetchUserActivity: %v", err)
+		}
+		updates := 0
+		for _, item := range items {
+			if item.Type == "task_updated" && item.EntityID == task.ID {
+				updates++
+			}
+		}
+		if updates != 2 {
+			t.Errorf("Expected one activity item per update, got %d in %+v", updates, items)
+		}
+	})
+}
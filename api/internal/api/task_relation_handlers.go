@@ -0,0 +1,459 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/task"
+)
+
+// taskRelationKinds maps every relation type accepted by the API to the type
+// stored in task_relations. Inverse kinds are stored with source and target
+// swapped, so "A is blocked by B" is the same row as "B blocks A".
+var taskRelationKinds = map[string]struct {
+	stored  string
+	inverse bool
+}{
+	"blocks":        {"blocks", false},
+	"blocked_by":    {"blocks", true},
+	"duplicates":    {"duplicates", false},
+	"duplicated_by": {"duplicates", true},
+	"relates_to":    {"relates_to", false},
+}
+
+// TaskRelation is a relation seen from one task's side
+type TaskRelation struct {
+	ID           int64            `json:"id"`
+	RelationType string           `json:"relation_type"` // blocks, blocked_by, duplicates, duplicated_by, relates_to
+	Task         TaskRelationTask `json:"task"`          // the other task
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// TaskRelationTask summarizes the related task
+type TaskRelationTask struct {
+	ID         int64  `json:"id"`
+	TaskNumber int64  `json:"task_number"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+}
+
+// CreateTaskRelationRequest relates a task to another task in the same
+// project, identified by task_id or task_number.
+type CreateTaskRelationRequest struct {
+	RelationType string `json:"relation_type"`
+	TaskID       *int64 `json:"task_id,omitempty"`
+	TaskNumber   *int64 `json:"task_number,omitempty"`
+}
+
+// HandleListTaskRelations returns all relations of a task, from its side.
+// Route: GET /api/tasks/{taskId}/relations
+func (s *Server) HandleListTaskRelations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	taskEntity, ok := s.taskForRelations(ctx, w, r, PermProjectView)
+	if !ok {
+		return
+	}
+
+	relations, err := s.loadTaskRelations(ctx, taskEntity.ID)
+	if err != nil {
+		s.logger.Error("Failed to load task relations", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch relations", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, relations)
+}
+
+// HandleCreateTaskRelation relates a task to another task in its project.
+// Blocking and duplicate relations may not form cycles.
+// Route: POST /api/tasks/{taskId}/relations
+func (s *Server) HandleCreateTaskRelation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	taskEntity, ok := s.taskForRelations(ctx, w, r, PermTaskEdit)
+	if !ok {
+		return
+	}
+
+	var req CreateTaskRelationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+
+	kind, ok := taskRelationKinds[req.RelationType]
+	if !ok {
+		respondError(w, http.StatusBadRequest,
+			"invalid relation_type (must be: blocks, blocked_by, duplicates, duplicated_by, or relates_to)", "invalid_input")
+		return
+	}
+
+	other, err := s.relatedTask(ctx, taskEntity.ProjectID, req)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "related task not found in this project", "not_found")
+			return
+		}
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	if other.ID == taskEntity.ID {
+		respondError(w, http.StatusBadRequest, "a task cannot be related to itself", "invalid_input")
+		return
+	}
+
+	sourceID, targetID := taskEntity.ID, other.ID
+	if kind.inverse {
+		sourceID, targetID = targetID, sourceID
+	}
+
+	if msg, err := s.checkTaskRelation(ctx, taskEntity.ProjectID, kind.stored, sourceID, targetID); err != nil {
+		s.logger.Error("Failed to check task relation", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create relation", "internal_error")
+		return
+	} else if msg != "" {
+		respondError(w, http.StatusConflict, msg, "invalid_relation")
+		return
+	}
+
+	var relationID int64
+	var createdAt time.Time
+	err = s.db.QueryRowContext(ctx, s.db.Rebind(`
+		INSERT INTO task_relations (project_id, source_task_id, target_task_id, relation_type, created_by)
+		VALUES (?, ?, ?, ?, ?)
+		RETURNING id, created_at
+	`), taskEntity.ProjectID, sourceID, targetID, kind.stored, userID).Scan(&relationID, &createdAt)
+	if err != nil {
+		s.logger.Error("Failed to create task relation", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create relation", "internal_error")
+		return
+	}
+
+	s.syncTaskRelationEdge(ctx, taskEntity.ProjectID, kind.stored, sourceID, targetID, true)
+
+	relation := TaskRelation{
+		ID:           relationID,
+		RelationType: req.RelationType,
+		Task:         relationTaskSummary(other),
+		CreatedAt:    createdAt,
+	}
+	respondJSON(w, http.StatusCreated, relation)
+	go s.broadcastToProjectMembers(taskEntity.ProjectID, "task_relations_updated", map[string]int64{
+		"task_id": taskEntity.ID, "related_task_id": other.ID,
+	})
+}
+
+// HandleDeleteTaskRelation removes a relation involving the task.
+// Route: DELETE /api/tasks/{taskId}/relations/{relationId}
+func (s *Server) HandleDeleteTaskRelation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	taskEntity, ok := s.taskForRelations(ctx, w, r, PermTaskEdit)
+	if !ok {
+		return
+	}
+	relationID, err := strconv.ParseInt(chi.URLParam(r, "relationId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid relation ID", "invalid_input")
+		return
+	}
+
+	var sourceID, targetID int64
+	var relationType string
+	err = s.db.QueryRowContext(ctx, s.db.Rebind(`
+		SELECT source_task_id, target_task_id, relation_type FROM task_relations
+		WHERE id = ? AND (source_task_id = ? OR target_task_id = ?)
+	`), relationID, taskEntity.ID, taskEntity.ID).Scan(&sourceID, &targetID, &relationType)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "relation not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to delete relation", "internal_error")
+		return
+	}
+
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM task_relations WHERE id = ?`), relationID); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to delete relation", "internal_error")
+		return
+	}
+
+	s.syncTaskRelationEdge(ctx, taskEntity.ProjectID, relationType, sourceID, targetID, false)
+
+	w.WriteHeader(http.StatusNoContent)
+	go s.broadcastToProjectMembers(taskEntity.ProjectID, "task_relations_updated", map[string]int64{
+		"task_id": sourceID, "related_task_id": targetID,
+	})
+}
+
+// taskForRelations loads the task from the taskId URL parameter and checks
+// that the caller has perm in its project, writing the error response when it
+// returns false.
+func (s *Server) taskForRelations(ctx context.Context, w http.ResponseWriter, r *http.Request, perm Permission) (*ent.Task, bool) {
+	userID := r.Context().Value(UserIDKey).(int64)
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "taskId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid task ID", "invalid_input")
+		return nil, false
+	}
+
+	taskEntity, err := s.db.Client.Task.Get(ctx, taskID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "task not found", "not_found")
+			return nil, false
+		}
+		respondError(w, http.StatusInternalServerError, "failed to get task", "internal_error")
+		return nil, false
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, taskEntity.ProjectID, perm)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return nil, false
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return nil, false
+	}
+	return taskEntity, true
+}
+
+// relatedTask resolves the other task of a relation request within the project.
+func (s *Server) relatedTask(ctx context.Context, projectID int64, req CreateTaskRelationRequest) (*ent.Task, error) {
+	query := s.db.Client.Task.Query().Where(task.ProjectID(projectID))
+	switch {
+	case req.TaskID != nil:
+		query = query.Where(task.ID(*req.TaskID))
+	case req.TaskNumber != nil:
+		query = query.Where(task.TaskNumber(int(*req.TaskNumber)))
+	default:
+		return nil, fmt.Errorf("task_id or task_number is required")
+	}
+	return query.Only(ctx)
+}
+
+// checkTaskRelation returns a non-empty message when a new relation would
+// duplicate an existing one or close a cycle of blocking or duplicate
+// relations.
+func (s *Server) checkTaskRelation(ctx context.Context, projectID int64, relationType string, sourceID, targetID int64) (string, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT source_task_id, target_task_id FROM task_relations
+		WHERE project_id = ? AND relation_type = ?
+	`), projectID, relationType)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	next := map[int64][]int64{}
+	for rows.Next() {
+		var from, to int64
+		if err := rows.Scan(&from, &to); err != nil {
+			return "", err
+		}
+		if from == sourceID && to == targetID {
+			return "relation already exists", nil
+		}
+		next[from] = append(next[from], to)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if relationType == "relates_to" {
+		// relates_to is symmetric; the reverse row means the same thing
+		for _, to := range next[targetID] {
+			if to == sourceID {
+				return "relation already exists", nil
+			}
+		}
+		return "", nil
+	}
+
+	// The new edge source -> target closes a cycle if target already reaches source
+	if taskRelationReaches(next, targetID, sourceID) {
+		return fmt.Sprintf("relation would create a %s cycle", strings.TrimSuffix(relationType, "s")), nil
+	}
+	return "", nil
+}
+
+// taskRelationReaches reports whether to can be reached from from by
+// following the given edges.
+func taskRelationReaches(next map[int64][]int64, from, to int64) bool {
+	seen := map[int64]bool{from: true}
+	queue := []int64{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			return true
+		}
+		for _, n := range next[id] {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return false
+}
+
+// loadTaskRelations returns every relation of a task, from its side.
+func (s *Server) loadTaskRelations(ctx context.Context, taskID int64) ([]TaskRelation, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT r.id, r.source_task_id, r.relation_type, r.created_at,
+		       t.id, t.task_number, t.title, t.status
+		FROM task_relations r
+		JOIN tasks t ON t.id = CASE WHEN r.source_task_id = ? THEN r.target_task_id ELSE r.source_task_id END
+		WHERE r.source_task_id = ? OR r.target_task_id = ?
+		ORDER BY r.relation_type, t.task_number
+	`), taskID, taskID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	relations := make([]TaskRelation, 0)
+	for rows.Next() {
+		var rel TaskRelation
+		var sourceID int64
+		var taskNumber sql.NullInt64
+		if err := rows.Scan(&rel.ID, &sourceID, &rel.RelationType, &rel.CreatedAt,
+			&rel.Task.ID, &taskNumber, &rel.Task.Title, &rel.Task.Status); err != nil {
+			return nil, err
+		}
+		rel.Task.TaskNumber = taskNumber.Int64
+		if sourceID != taskID {
+			switch rel.RelationType {
+			case "blocks":
+				rel.RelationType = "blocked_by"
+			case "duplicates":
+				rel.RelationType = "duplicated_by"
+			}
+		}
+		relations = append(relations, rel)
+	}
+	return relations, rows.Err()
+}
+
+// loadOpenBlockers returns, for each blocked task of the project, the IDs of
+// the tasks blocking it that are not done yet. When taskIDs are given only
+// those tasks are considered.
+func (s *Server) loadOpenBlockers(ctx context.Context, projectID int64, taskIDs ...int64) (map[int64][]int64, error) {
+	query := `
+		SELECT r.target_task_id, r.source_task_id
+		FROM task_relations r
+		JOIN tasks b ON b.id = r.source_task_id
+		WHERE r.project_id = ? AND r.relation_type = 'blocks' AND b.status <> 'done'`
+	args := []interface{}{projectID}
+	if len(taskIDs) > 0 {
+		placeholders := make([]string, len(taskIDs))
+		for i, id := range taskIDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		query += ` AND r.target_task_id IN (` + strings.Join(placeholders, ",") + `)`
+	}
+	query += ` ORDER BY b.task_number`
+
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blockers := make(map[int64][]int64)
+	for rows.Next() {
+		var taskID, blockerID int64
+		if err := rows.Scan(&taskID, &blockerID); err != nil {
+			return nil, err
+		}
+		blockers[taskID] = append(blockers[taskID], blockerID)
+	}
+	return blockers, rows.Err()
+}
+
+// applyBlockedState sets the computed blocked fields on API tasks. Errors are
+// logged and leave the tasks unblocked, like other best-effort enrichments.
+func (s *Server) applyBlockedState(ctx context.Context, projectID int64, tasks []Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	var taskIDs []int64
+	if len(tasks) == 1 {
+		taskIDs = []int64{tasks[0].ID}
+	}
+	blockers, err := s.loadOpenBlockers(ctx, projectID, taskIDs...)
+	if err != nil {
+		s.logger.Warn("Failed to load task blockers", zap.Int64("project_id", projectID), zap.Error(err))
+		return
+	}
+	for i := range tasks {
+		if ids, ok := blockers[tasks[i].ID]; ok {
+			tasks[i].Blocked = true
+			tasks[i].BlockedBy = ids
+		}
+	}
+}
+
+// syncTaskRelationEdge adds or removes the knowledge graph edge mirroring a
+// task relation, so relations show up in the project graph with their type.
+// Best-effort: failures are logged.
+func (s *Server) syncTaskRelationEdge(ctx context.Context, projectID int64, relationType string, sourceID, targetID int64, add bool) {
+	nodeIDs := make([]int64, 0, 2)
+	for _, id := range []int64{sourceID, targetID} {
+		t, err := s.db.Client.Task.Get(ctx, id)
+		if err != nil {
+			s.logger.Warn("Failed to load task for graph edge", zap.Int64("task_id", id), zap.Error(err))
+			return
+		}
+		var number *int64
+		if t.TaskNumber != nil {
+			n := int64(*t.TaskNumber)
+			number = &n
+		}
+		nodeID, err := s.upsertGraphNode(ctx, projectID, "task", t.ID, number, t.Title)
+		if err != nil {
+			s.logger.Warn("Failed to upsert graph node", zap.Int64("task_id", id), zap.Error(err))
+			return
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+
+	query := `DELETE FROM graph_edges WHERE source_node_id = ? AND target_node_id = ? AND relation_type = ?`
+	if add {
+		query = `INSERT INTO graph_edges (source_node_id, target_node_id, relation_type) VALUES (?, ?, ?)
+			ON CONFLICT(source_node_id, target_node_id, relation_type) DO NOTHING`
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(query), nodeIDs[0], nodeIDs[1], relationType); err != nil {
+		s.logger.Warn("Failed to sync task relation graph edge",
+			zap.Int64("source_task_id", sourceID),
+			zap.Int64("target_task_id", targetID),
+			zap.Error(err),
+		)
+	}
+}
+
+func relationTaskSummary(t *ent.Task) TaskRelationTask {
+	summary := TaskRelationTask{ID: t.ID, Title: t.Title, Status: t.Status}
+	if t.TaskNumber != nil {
+		summary.TaskNumber = int64(*t.TaskNumber)
+	}
+	return summary
+}
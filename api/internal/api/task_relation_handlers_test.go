@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createTestTaskRelation relates taskID to otherID and returns the status code.
+func (ts *TestServer) createTestTaskRelation(t *testing.T, userID, taskID int64, relationType string, otherID int64) int {
+	t.Helper()
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost, fmt.Sprintf("/api/tasks/%d/relations", taskID),
+		CreateTaskRelationRequest{RelationType: relationType, TaskID: &otherID}, userID,
+		map[string]string{"taskId": fmt.Sprintf("%d", taskID)})
+	ts.HandleCreateTaskRelation(rec, req)
+	return rec.Code
+}
+
+func TestTaskRelations(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+	design := ts.CreateTestTask(t, projectID, "Design")
+	build := ts.CreateTestTask(t, projectID, "Build")
+	ship := ts.CreateTestTask(t, projectID, "Ship")
+
+	// Build is blocked by design; build blocks ship
+	if code := ts.createTestTaskRelation(t, userID, build, "blocked_by", design); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+	if code := ts.createTestTaskRelation(t, userID, build, "blocks", ship); code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", code)
+	}
+
+	t.Run("relations are listed from each side", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, fmt.Sprintf("/api/tasks/%d/relations", design), nil, userID,
+			map[string]string{"taskId": fmt.Sprintf("%d", design)})
+		ts.HandleListTaskRelations(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var relations []TaskRelation
+		DecodeJSON(t, rec, &relations)
+		if len(relations) != 1 || relations[0].RelationType != "blocks" || relations[0].Task.ID != build {
+			t.Errorf("Expected design to block build, got %+v", relations)
+		}
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodGet, fmt.Sprintf("/api/tasks/%d/relations", ship), nil, userID,
+			map[string]string{"taskId": fmt.Sprintf("%d", ship)})
+		ts.HandleListTaskRelations(rec, req)
+		DecodeJSON(t, rec, &relations)
+		if len(relations) != 1 || relations[0].RelationType != "blocked_by" || relations[0].Task.ID != build {
+			t.Errorf("Expected ship to be blocked by build, got %+v", relations)
+		}
+	})
+
+	t.Run("rejects cycles, duplicates and self relations", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			taskID   int64
+			kind     string
+			otherID  int64
+			wantCode int
+		}{
+			{"transitive cycle", ship, "blocks", design, http.StatusConflict},
+			{"inverse cycle", design, "blocked_by", build, http.StatusConflict},
+			{"duplicate", design, "blocks", build, http.StatusConflict},
+			{"self", design, "relates_to", design, http.StatusBadRequest},
+			{"unknown type", design, "follows", ship, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if code := ts.createTestTaskRelation(t, userID, tt.taskID, tt.kind, tt.otherID); code != tt.wantCode {
+					t.Errorf("Expected %d, got %d", tt.wantCode, code)
+				}
+			})
+		}
+
+		// relates_to is symmetric, so the reverse is a duplicate
+		if code := ts.createTestTaskRelation(t, userID, design, "relates_to", ship); code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d", code)
+		}
+		if code := ts.createTestTaskRelation(t, userID, ship, "relates_to", design); code != http.StatusConflict {
+			t.Errorf("Expected reverse relates_to to conflict, got %d", code)
+		}
+	})
+
+	t.Run("blocked tasks cannot be completed", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks/2", projectID), nil, userID,
+			map[string]string{"projectId": fmt.Sprintf("%d", projectID), "taskNumber": "2"})
+		ts.HandleGetTaskByNumber(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var got Task
+		DecodeJSON(t, rec, &got)
+		if !got.Blocked || len(got.BlockedBy) != 1 || got.BlockedBy[0] != design {
+			t.Errorf("Expected build to be blocked by design, got blocked=%v by %v", got.Blocked, got.BlockedBy)
+		}
+
+		done := "done"
+		update := func(taskID int64) int {
+			rec, req := ts.MakeAuthRequest(t, http.MethodPatch, fmt.Sprintf("/api/tasks/%d", taskID),
+				UpdateTaskRequest{Status: &done}, userID, map[string]string{"id": fmt.Sprintf("%d", taskID)})
+			ts.HandleUpdateTask(rec, req)
+			return rec.Code
+		}
+		if code := update(build); code != http.StatusConflict {
+			t.Fatalf("Expected blocked task to be rejected, got %d", code)
+		}
+		if code := update(design); code != http.StatusOK {
+			t.Fatalf("Expected blocker to complete, got %d", code)
+		}
+		if code := update(build); code != http.StatusOK {
+			t.Errorf("Expected unblocked task to complete, got %d", code)
+		}
+	})
+
+	t.Run("relations appear in the project graph", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, fmt.Sprintf("/api/projects/%d/graph", projectID), nil, userID,
+			map[string]string{"id": fmt.Sprintf("%d", projectID)})
+		ts.HandleGetProjectGraph(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var graph GraphData
+		DecodeJSON(t, rec, &graph)
+		types := map[string]int{}
+		for _, e := range graph.Edges {
+			types[e.RelationType]++
+		}
+		if types["blocks"] != 2 || types["relates_to"] != 1 {
+			t.Errorf("Expected typed relation edges, got %v", types)
+		}
+	})
+
+	t.Run("deleting a relation removes its graph edge", func(t *testing.T) {
+		var relationID int64
+		if err := ts.DB.QueryRow(`SELECT id FROM task_relations WHERE source_task_id = ? AND target_task_id = ?`,
+			build, ship).Scan(&relationID); err != nil {
+			t.Fatalf("Failed to find relation: %v", err)
+		}
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete, fmt.Sprintf("/api/tasks/%d/relations/%d", ship, relationID), nil, userID,
+			map[string]string{"taskId": fmt.Sprintf("%d", ship), "relationId": fmt.Sprintf("%d", relationID)})
+		ts.HandleDeleteTaskRelation(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		var edges int
+		if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM graph_edges WHERE relation_type = 'blocks'`).Scan(&edges); err != nil {
+			t.Fatalf("Failed to count edges: %v", err)
+		}
+		if edges != 1 {
+			t.Errorf("Expected 1 remaining blocks edge, got %d", edges)
+		}
+	})
+}
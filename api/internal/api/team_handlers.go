@@ -668,6 +668,10 @@ func (s *Server) HandleRemoveTeamMember(w http.ResponseWriter, r *http.Request)
 		zap.Int64("team_id", teamID),
 	)
 
+	auditChange(r, auditEvent{
+		Action: "team.member_removed", ResourceType: "team_member", ResourceID: memberID, TeamID: teamID,
+		Before: map[string]interface{}{"user_id": memberUserID, "role": member.Role},
+	})
 	respondJSON(w, http.StatusOK, map[string]string{"message": "member removed"})
 }
 
@@ -708,6 +712,13 @@ func (s *Server) HandleUpdateTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := s.db.Client.Team.Get(ctx, teamID)
+	if err != nil {
+		s.logger.Error("Failed to get team", zap.Error(err), zap.Int64("team_id", teamID))
+		respondError(w, http.StatusInternalServerError, "failed to update team", "internal_error")
+		return
+	}
+
 	// Update team name
 	entTeam, err := s.db.Client.Team.UpdateOneID(teamID).
 		SetName(name).
@@ -724,6 +735,11 @@ func (s *Server) HandleUpdateTeam(w http.ResponseWriter, r *http.Request) {
 		zap.Int64("updated_by", userID),
 	)
 
+	auditChange(r, auditEvent{
+		Action: "team.updated", ResourceType: "team", ResourceID: teamID, TeamID: teamID,
+		Before: map[string]string{"name": before.Name},
+		After:  map[string]string{"name": entTeam.Name},
+	})
 	respondJSON(w, http.StatusOK, Team{
 		ID:        entTeam.ID,
 		Name:      entTeam.Name,
@@ -952,7 +968,7 @@ func (s *Server) HandleAddTeamMember(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback()
 
 	// Add user as team member
-	_, err = tx.TeamMember.Create().
+	newMember, err := tx.TeamMember.Create().
 		SetTeamID(teamID).
 		SetUserID(req.UserID).
 		SetRole("member").
@@ -977,6 +993,10 @@ func (s *Server) HandleAddTeamMember(w http.ResponseWriter, r *http.Request) {
 		zap.Int64("added_by", userID),
 	)
 
+	auditChange(r, auditEvent{
+		Action: "team.member_added", ResourceType: "team_member", ResourceID: newMember.ID, TeamID: teamID,
+		After: map[string]interface{}{"user_id": req.UserID, "email": targetUser.Email, "role": "member"},
+	})
 	respondJSON(w, http.StatusCreated, map[string]string{"message": "member added"})
 }
 
@@ -3,10 +3,13 @@ package api
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -63,6 +66,65 @@ func NewTestServer(t testing.TB) *TestServer {
 	}
 }
 
+// NewTestServerForDriver creates a test server on the given database driver.
+// "sqlite" is NewTestServer; "postgres" migrates a throwaway schema on the
+// server named by TEST_POSTGRES_DSN and skips the test when that is unset.
+// Postgres servers only support helpers that go through Ent or Rebind.
+func NewTestServerForDriver(t *testing.T, driver string) *TestServer {
+	t.Helper()
+	if driver != "postgres" {
+		return NewTestServer(t)
+	}
+
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	auth.SetBcryptCost(bcrypt.MinCost)
+	logger := zaptest.NewLogger(t)
+
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("Failed to open Postgres: %v", err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("taskai_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("Failed to create test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if admin, err := sql.Open("pgx", dsn); err == nil {
+			_, _ = admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+			admin.Close()
+		}
+	})
+
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "&"
+		if !strings.Contains(dsn, "?") {
+			sep = "?"
+		}
+	}
+	database, err := db.New(db.Config{
+		Driver:         "postgres",
+		DSN:            dsn + sep + "search_path=" + schema,
+		MigrationsPath: "./../../internal/db/migrations",
+	}, logger)
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	testCfg := &config.Config{
+		JWTSecret:      "test-secret-key",
+		JWTExpiryHours: 24,
+	}
+	return &TestServer{
+		Server: NewServer(database, testCfg, logger),
+		DB:     database,
+	}
+}
+
 // Close cleans up test server resources
 func (ts *TestServer) Close() {
 	if ts.DB != nil {
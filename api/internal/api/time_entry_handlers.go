@@ -0,0 +1,818 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/task"
+	"taskai/ent/user"
+	"taskai/internal/db"
+)
+
+const (
+	// maxTimeEntryMinutes bounds a single time entry to a day
+	maxTimeEntryMinutes = 24 * 60
+	// maxTimeEntryNote bounds the note of a time entry, in characters
+	maxTimeEntryNote = 1000
+	// defaultTimesheetDays is the period a timesheet covers when none is given
+	defaultTimesheetDays = 7
+	// maxTimesheetDays bounds the period of a timesheet
+	maxTimesheetDays = 366
+)
+
+// CreateTimeEntryRequest logs time on a task, as minutes or as the start and
+// end of the work
+type CreateTimeEntryRequest struct {
+	Minutes   *int       `json:"minutes,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+	Date      *string    `json:"date,omitempty"` // defaults to the day the work started, or today
+	Note      string     `json:"note"`
+}
+
+// UpdateTimeEntryRequest changes a time entry; running timers can only
+// change their note
+type UpdateTimeEntryRequest struct {
+	Minutes *int    `json:"minutes,omitempty"`
+	Date    *string `json:"date,omitempty"`
+	Note    *string `json:"note,omitempty"`
+}
+
+// StartTimerRequest starts a timer on a task
+type StartTimerRequest struct {
+	Note string `json:"note"`
+}
+
+// TimesheetGroup totals the time logged by one user, or on one project,
+// sprint or tag
+type TimesheetGroup struct {
+	ID      *int64  `json:"id,omitempty"` // omitted for entries without a sprint or tag, or by deleted users
+	Name    string  `json:"name"`
+	Seconds int64   `json:"seconds"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+// Timesheet is the time logged over a period, grouped by user, project,
+// sprint or tag. Entries on tasks with several tags count towards each of
+// them, so tag groups can add up to more than the total.
+type Timesheet struct {
+	From         string              `json:"from"`
+	To           string              `json:"to"`
+	GroupBy      string              `json:"group_by"`
+	TotalSeconds int64               `json:"total_seconds"`
+	TotalHours   float64             `json:"total_hours"`
+	Groups       []TimesheetGroup    `json:"groups"`
+	Entries      []db.TimesheetEntry `json:"entries"`
+}
+
+// secondsToHours converts seconds to hours rounded to two decimals
+func secondsToHours(seconds int64) float64 {
+	return math.Round(float64(seconds)/3600*100) / 100
+}
+
+// userDisplayNames returns the display names of users, by ID. Users that
+// can't be loaded are left out.
+func (s *Server) userDisplayNames(ctx context.Context, ids []int64) map[int64]string {
+	names := make(map[int64]string, len(ids))
+	if len(ids) == 0 {
+		return names
+	}
+	users, err := s.db.Client.User.Query().Where(user.IDIn(ids...)).All(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to load user names", zap.Error(err))
+		return names
+	}
+	for _, u := range users {
+		names[u.ID] = userDisplayName(u)
+	}
+	return names
+}
+
+// nameTimeEntries fills in the user names of time entries
+func (s *Server) nameTimeEntries(ctx context.Context, entries []*db.TimeEntry) {
+	var ids []int64
+	for _, e := range entries {
+		if e.UserID != nil {
+			ids = append(ids, *e.UserID)
+		}
+	}
+	names := s.userDisplayNames(ctx, ids)
+	for _, e := range entries {
+		if e.UserID != nil {
+			e.UserName = names[*e.UserID]
+		}
+	}
+}
+
+// parseDay checks a 'YYYY-MM-DD' date
+func parseDay(v string) (time.Time, error) {
+	return time.Parse("2006-01-02", v)
+}
+
+// syncActualHours updates a task's actual_hours after its time entries
+// changed, recording the change in the task's history and telling the
+// project's members. Failures are logged rather than returned: the time
+// entry has already been saved.
+func (s *Server) syncActualHours(r *http.Request, taskID int64) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), 5*time.Second)
+	defer cancel()
+
+	before, after, err := s.db.SyncTaskActualHours(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to update actual hours", zap.Int64("task_id", taskID), zap.Error(err))
+		return
+	}
+	old, cur := historyHours(before), historyHours(after)
+	if (old == nil && cur == nil) || (old != nil && cur != nil && *old == *cur) {
+		return
+	}
+	t, err := s.loadTask(ctx, taskID)
+	if err != nil {
+		s.logger.Error("Failed to load task", zap.Int64("task_id", taskID), zap.Error(err))
+		return
+	}
+	s.saveTaskHistory(r, []db.TaskHistoryEntry{{
+		TaskID: taskID, ProjectID: t.ProjectID, Field: "actual_hours", OldValue: old, NewValue: cur,
+	}})
+	go s.broadcastToProjectMembers(t.ProjectID, "task_updated", t)
+}
+
+// loadTimeEntryTask returns the task a request's {taskId} names, after
+// checking the user may do perm in its project. It responds with the error
+// and returns nil when it can't.
+func (s *Server) loadTimeEntryTask(w http.ResponseWriter, r *http.Request, perm Permission) *ent.Task {
+	ctx := r.Context()
+	userID := ctx.Value(UserIDKey).(int64)
+	taskID, err := strconv.ParseInt(chi.URLParam(r, "taskId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid task ID", "invalid_input")
+		return nil
+	}
+	taskEntity, err := s.db.Client.Task.Get(ctx, taskID)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "task not found", "not_found")
+			return nil
+		}
+		respondError(w, http.StatusInternalServerError, "failed to get task", "internal_error")
+		return nil
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, taskEntity.ProjectID, perm)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return nil
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return nil
+	}
+	return taskEntity
+}
+
+// HandleListTimeEntries returns the time logged on a task, newest first
+// Route: GET /api/tasks/{taskId}/time-entries
+func (s *Server) HandleListTimeEntries(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	taskEntity := s.loadTimeEntryTask(w, r, PermProjectView)
+	if taskEntity == nil {
+		return
+	}
+
+	entries, err := s.db.ListTaskTimeEntries(ctx, taskEntity.ID)
+	if err != nil {
+		s.logger.Error("Failed to list time entries", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch time entries", "internal_error")
+		return
+	}
+	named := make([]*db.TimeEntry, len(entries))
+	for i := range entries {
+		named[i] = &entries[i]
+	}
+	s.nameTimeEntries(ctx, named)
+
+	respondJSON(w, http.StatusOK, entries)
+}
+
+// HandleCreateTimeEntry logs time on a task
+// Route: POST /api/tasks/{taskId}/time-entries
+func (s *Server) HandleCreateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	taskEntity := s.loadTimeEntryTask(w, r, PermTaskEdit)
+	if taskEntity == nil {
+		return
+	}
+	s.createTimeEntry(w, r, taskEntity)
+}
+
+// HandleCreateTimeEntryByNumber logs time on a task named by its number in
+// the project, so agents can log their work without looking up task IDs
+// Route: POST /api/projects/{projectId}/tasks/{taskNumber}/time-entries
+func (s *Server) HandleCreateTimeEntryByNumber(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	userID := ctx.Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	taskNumber, err := strconv.Atoi(chi.URLParam(r, "taskNumber"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid task number", "invalid_input")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermTaskEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	taskEntity, err := s.db.Client.Task.Query().
+		Where(task.ProjectID(projectID), task.TaskNumber(taskNumber)).
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "task not found", "not_found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to fetch task", "internal_error")
+		return
+	}
+	s.createTimeEntry(w, r, taskEntity)
+}
+
+// createTimeEntry logs the time a request describes on a task
+func (s *Server) createTimeEntry(w http.ResponseWriter, r *http.Request, taskEntity *ent.Task) {
+	ctx := r.Context()
+	userID := ctx.Value(UserIDKey).(int64)
+
+	var req CreateTimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+	if len(req.Note) > maxTimeEntryNote {
+		respondError(w, http.StatusBadRequest, "note is too long (max 1000 characters)", "invalid_input")
+		return
+	}
+
+	e := db.TimeEntry{
+		TaskID: taskEntity.ID, ProjectID: taskEntity.ProjectID, UserID: &userID,
+		Date: time.Now().UTC().Format("2006-01-02"), Note: req.Note,
+	}
+	if name := GetAgentName(r); name != nil {
+		e.AgentName = *name
+	}
+	switch {
+	case req.Minutes != nil && req.StartedAt == nil && req.EndedAt == nil:
+		if *req.Minutes <= 0 || *req.Minutes > maxTimeEntryMinutes {
+			respondError(w, http.StatusBadRequest, "minutes must be between 1 and 1440", "invalid_input")
+			return
+		}
+		e.DurationSeconds = int64(*req.Minutes) * 60
+	case req.Minutes == nil && req.StartedAt != nil && req.EndedAt != nil:
+		d := req.EndedAt.Sub(*req.StartedAt)
+		if d <= 0 || d > maxTimeEntryMinutes*time.Minute {
+			respondError(w, http.StatusBadRequest, "ended_at must be after started_at, at most 24 hours later", "invalid_input")
+			return
+		}
+		started, ended := req.StartedAt.UTC(), req.EndedAt.UTC()
+		e.StartedAt, e.EndedAt = &started, &ended
+		e.DurationSeconds = int64(d / time.Second)
+		e.Date = started.Format("2006-01-02")
+	default:
+		respondError(w, http.StatusBadRequest, "give either minutes or started_at and ended_at", "invalid_input")
+		return
+	}
+	if req.Date != nil {
+		if _, err := parseDay(*req.Date); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid date (use YYYY-MM-DD)", "invalid_input")
+			return
+		}
+		e.Date = *req.Date
+	}
+
+	if err := s.db.CreateTimeEntry(ctx, &e); err != nil {
+		s.logger.Error("Failed to create time entry", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to log time", "internal_error")
+		return
+	}
+	s.syncActualHours(r, taskEntity.ID)
+	s.nameTimeEntries(ctx, []*db.TimeEntry{&e})
+
+	auditChange(r, auditEvent{
+		Action: "time_entry.created", ResourceType: "time_entry", ResourceID: e.ID, ProjectID: e.ProjectID, After: e,
+	})
+	respondJSON(w, http.StatusCreated, e)
+}
+
+// HandleStartTimer starts a timer on a task for the current user, who can
+// only have one running
+// Route: POST /api/tasks/{taskId}/timer/start
+func (s *Server) HandleStartTimer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	userID := ctx.Value(UserIDKey).(int64)
+	taskEntity := s.loadTimeEntryTask(w, r, PermTaskEdit)
+	if taskEntity == nil {
+		return
+	}
+
+	var req StartTimerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+	if len(req.Note) > maxTimeEntryNote {
+		respondError(w, http.StatusBadRequest, "note is too long (max 1000 characters)", "invalid_input")
+		return
+	}
+
+	now := time.Now().UTC()
+	e := db.TimeEntry{
+		TaskID: taskEntity.ID, ProjectID: taskEntity.ProjectID, UserID: &userID,
+		Date: now.Format("2006-01-02"), StartedAt: &now, Running: true, Note: req.Note,
+	}
+	if name := GetAgentName(r); name != nil {
+		e.AgentName = *name
+	}
+	if err := s.db.CreateTimeEntry(ctx, &e); err != nil {
+		if errors.Is(err, db.ErrTimerRunning) {
+			respondError(w, http.StatusConflict, "you already have a timer running; stop it first", "timer_running")
+			return
+		}
+		s.logger.Error("Failed to start timer", zap.Int64("task_id", taskEntity.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to start timer", "internal_error")
+		return
+	}
+	s.nameTimeEntries(ctx, []*db.TimeEntry{&e})
+
+	auditChange(r, auditEvent{
+		Action: "time_entry.created", ResourceType: "time_entry", ResourceID: e.ID, ProjectID: e.ProjectID, After: e,
+	})
+	respondJSON(w, http.StatusCreated, e)
+}
+
+// HandleGetTimer returns the current user's running timer, or null
+// Route: GET /api/me/timer
+func (s *Server) HandleGetTimer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := ctx.Value(UserIDKey).(int64)
+	e, err := s.db.RunningTimeEntry(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get running timer", zap.Int64("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to fetch timer", "internal_error")
+		return
+	}
+	if e != nil {
+		s.nameTimeEntries(ctx, []*db.TimeEntry{e})
+	}
+	respondJSON(w, http.StatusOK, e)
+}
+
+// HandleStopTimer stops the current user's running timer, logging the time
+// since it started
+// Route: POST /api/me/timer/stop
+func (s *Server) HandleStopTimer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	userID := ctx.Value(UserIDKey).(int64)
+	e, err := s.db.RunningTimeEntry(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get running timer", zap.Int64("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to stop timer", "internal_error")
+		return
+	}
+	if e == nil {
+		respondError(w, http.StatusNotFound, "no timer is running", "not_found")
+		return
+	}
+	if !apiKeyAllowsProject(ctx, e.ProjectID) {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	before := *e
+	now := time.Now().UTC()
+	e.EndedAt, e.Running = &now, false
+	e.DurationSeconds = int64(now.Sub(*e.StartedAt) / time.Second)
+	if e.DurationSeconds > maxTimeEntryMinutes*60 {
+		// Timers left running overnight are capped at a day; the entry can be
+		// corrected afterwards
+		e.DurationSeconds = maxTimeEntryMinutes * 60
+	}
+	if err := s.db.UpdateTimeEntry(ctx, e); err != nil {
+		s.logger.Error("Failed to stop timer", zap.Int64("time_entry_id", e.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to stop timer", "internal_error")
+		return
+	}
+	s.syncActualHours(r, e.TaskID)
+	s.nameTimeEntries(ctx, []*db.TimeEntry{e})
+
+	auditChange(r, auditEvent{
+		Action: "time_entry.updated", ResourceType: "time_entry", ResourceID: e.ID, ProjectID: e.ProjectID,
+		Before: before, After: *e,
+	})
+	respondJSON(w, http.StatusOK, e)
+}
+
+// loadOwnTimeEntry returns the time entry a request's {id} names, after
+// checking the user may change it: their own entries need task.edit, other
+// people's project.edit. It responds with the error and returns nil when
+// they can't.
+func (s *Server) loadOwnTimeEntry(w http.ResponseWriter, r *http.Request) *db.TimeEntry {
+	ctx := r.Context()
+	userID := ctx.Value(UserIDKey).(int64)
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid time entry ID", "invalid_input")
+		return nil
+	}
+	e, err := s.db.GetTimeEntry(ctx, id)
+	if errors.Is(err, db.ErrTimeEntryNotFound) {
+		respondError(w, http.StatusNotFound, "time entry not found", "not_found")
+		return nil
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to get time entry", "internal_error")
+		return nil
+	}
+
+	perm := PermProjectEdit
+	if e.UserID != nil && *e.UserID == userID {
+		perm = PermTaskEdit
+	}
+	hasAccess, err := s.authorizeProject(ctx, userID, e.ProjectID, perm)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return nil
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return nil
+	}
+	return e
+}
+
+// HandleUpdateTimeEntry changes the duration, date or note of a time entry
+// Route: PATCH /api/time-entries/{id}
+func (s *Server) HandleUpdateTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	e := s.loadOwnTimeEntry(w, r)
+	if e == nil {
+		return
+	}
+
+	var req UpdateTimeEntryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_input")
+		return
+	}
+
+	before := *e
+	if req.Minutes != nil {
+		if e.Running {
+			respondError(w, http.StatusConflict, "stop the timer before changing its duration", "timer_running")
+			return
+		}
+		if *req.Minutes <= 0 || *req.Minutes > maxTimeEntryMinutes {
+			respondError(w, http.StatusBadRequest, "minutes must be between 1 and 1440", "invalid_input")
+			return
+		}
+		e.DurationSeconds = int64(*req.Minutes) * 60
+		if e.StartedAt != nil {
+			ended := e.StartedAt.Add(time.Duration(e.DurationSeconds) * time.Second)
+			e.EndedAt = &ended
+		}
+	}
+	if req.Date != nil {
+		if _, err := parseDay(*req.Date); err != nil {
+			respondError(w, http.StatusBadRequest, "invalid date (use YYYY-MM-DD)", "invalid_input")
+			return
+		}
+		e.Date = *req.Date
+	}
+	if req.Note != nil {
+		if len(*req.Note) > maxTimeEntryNote {
+			respondError(w, http.StatusBadRequest, "note is too long (max 1000 characters)", "invalid_input")
+			return
+		}
+		e.Note = *req.Note
+	}
+
+	if err := s.db.UpdateTimeEntry(ctx, e); err != nil {
+		s.logger.Error("Failed to update time entry", zap.Int64("time_entry_id", e.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update time entry", "internal_error")
+		return
+	}
+	if e.DurationSeconds != before.DurationSeconds {
+		s.syncActualHours(r, e.TaskID)
+	}
+	s.nameTimeEntries(ctx, []*db.TimeEntry{e})
+
+	auditChange(r, auditEvent{
+		Action: "time_entry.updated", ResourceType: "time_entry", ResourceID: e.ID, ProjectID: e.ProjectID,
+		Before: before, After: *e,
+	})
+	respondJSON(w, http.StatusOK, e)
+}
+
+// HandleDeleteTimeEntry deletes a time entry, or discards a running timer
+// Route: DELETE /api/time-entries/{id}
+func (s *Server) HandleDeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	e := s.loadOwnTimeEntry(w, r)
+	if e == nil {
+		return
+	}
+	if err := s.db.DeleteTimeEntry(ctx, e.ID); err != nil {
+		s.logger.Error("Failed to delete time entry", zap.Int64("time_entry_id", e.ID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to delete time entry", "internal_error")
+		return
+	}
+	s.syncActualHours(r, e.TaskID)
+
+	auditChange(r, auditEvent{
+		Action: "time_entry.deleted", ResourceType: "time_entry", ResourceID: e.ID, ProjectID: e.ProjectID, Before: *e,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTimesheetQuery reads the period and filters of a timesheet from the
+// query string. user_id may be "me".
+func parseTimesheetQuery(r *http.Request, userID int64) (db.TimesheetQuery, error) {
+	values := r.URL.Query()
+	var q db.TimesheetQuery
+
+	to := time.Now().UTC()
+	if v := values.Get("to"); v != "" {
+		t, err := parseDay(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid to date (use YYYY-MM-DD)")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, 1-defaultTimesheetDays)
+	if v := values.Get("from"); v != "" {
+		t, err := parseDay(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid from date (use YYYY-MM-DD)")
+		}
+		from = t
+	}
+	q.From, q.To = from.Format("2006-01-02"), to.Format("2006-01-02")
+	if q.From > q.To || to.Sub(from) >= maxTimesheetDays*24*time.Hour {
+		return q, fmt.Errorf("from must be before to, at most 366 days apart")
+	}
+
+	if values.Get("user_id") == "me" {
+		q.UserID = userID
+	}
+	ids := []struct {
+		name string
+		dst  *int64
+	}{
+		{"project_id", &q.ProjectID},
+		{"user_id", &q.UserID},
+		{"sprint_id", &q.SprintID},
+		{"tag_id", &q.TagID},
+	}
+	for _, id := range ids {
+		if v := values.Get(id.name); v != "" && v != "me" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				return q, fmt.Errorf("invalid %s", id.name)
+			}
+			*id.dst = n
+		}
+	}
+	return q, nil
+}
+
+// groupTimesheet totals timesheet entries by user, project, sprint or tag,
+// most time first
+func groupTimesheet(entries []db.TimesheetEntry, groupBy string) []TimesheetGroup {
+	type key struct {
+		id   int64
+		none bool
+	}
+	groups := map[key]*TimesheetGroup{}
+	add := func(id *int64, name, none string, e db.TimesheetEntry) {
+		k := key{none: id == nil}
+		if id != nil {
+			k.id = *id
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &TimesheetGroup{ID: id, Name: name}
+			if id == nil {
+				g.Name = none
+			}
+			groups[k] = g
+		}
+		g.Seconds += e.DurationSeconds
+		g.Entries++
+	}
+	for _, e := range entries {
+		switch groupBy {
+		case "project":
+			add(&e.ProjectID, e.ProjectName, "", e)
+		case "sprint":
+			name := ""
+			if e.SprintName != nil {
+				name = *e.SprintName
+			}
+			add(e.SprintID, name, "No sprint", e)
+		case "tag":
+			if len(e.Tags) == 0 {
+				add(nil, "", "No tag", e)
+			}
+			for _, tag := range e.Tags {
+				add(&tag.ID, tag.Name, "", e)
+			}
+		default:
+			add(e.UserID, e.UserName, "Deleted user", e)
+		}
+	}
+
+	result := make([]TimesheetGroup, 0, len(groups))
+	for _, g := range groups {
+		g.Hours = secondsToHours(g.Seconds)
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Seconds != result[j].Seconds {
+			return result[i].Seconds > result[j].Seconds
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// HandleGetTimesheet returns the time logged over the days from..to
+// (YYYY-MM-DD, by default the last 7) on the projects the user can see,
+// grouped by user, project, sprint or tag. With format=csv the entries are
+// downloaded as a file instead, one row per entry and group.
+// Route: GET /api/timesheets
+func (s *Server) HandleGetTimesheet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	userID := ctx.Value(UserIDKey).(int64)
+	q, err := parseTimesheetQuery(r, userID)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error(), "invalid_input")
+		return
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	switch groupBy {
+	case "":
+		groupBy = "user"
+	case "user", "project", "sprint", "tag":
+	default:
+		respondError(w, http.StatusBadRequest, "group_by must be user, project, sprint or tag", "invalid_input")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "json" {
+		respondError(w, http.StatusBadRequest, "format must be csv or json", "invalid_input")
+		return
+	}
+
+	q.ProjectIDs, err = s.getUserAccessibleProjects(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get accessible projects", zap.Int64("user_id", userID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to load timesheet", "internal_error")
+		return
+	}
+	if q.ProjectID != 0 && !containsInt64(q.ProjectIDs, q.ProjectID) {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	entries, err := s.db.ListTimesheetEntries(ctx, q)
+	if err != nil {
+		s.logger.Error("Failed to load timesheet", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to load timesheet", "internal_error")
+		return
+	}
+	named := make([]*db.TimeEntry, len(entries))
+	for i := range entries {
+		named[i] = &entries[i].TimeEntry
+	}
+	s.nameTimeEntries(ctx, named)
+
+	if format == "csv" {
+		filename := fmt.Sprintf("taskai-timesheet-%s-to-%s.csv", q.From, q.To)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+		w.WriteHeader(http.StatusOK)
+		if err := writeTimesheetCSV(w, entries, groupBy); err != nil {
+			s.logger.Error("Timesheet export aborted", zap.Error(err))
+		}
+		return
+	}
+
+	sheet := Timesheet{From: q.From, To: q.To, GroupBy: groupBy, Groups: groupTimesheet(entries, groupBy), Entries: entries}
+	for _, e := range entries {
+		sheet.TotalSeconds += e.DurationSeconds
+	}
+	sheet.TotalHours = secondsToHours(sheet.TotalSeconds)
+	if format == "json" {
+		w.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=taskai-timesheet-%s-to-%s.json", q.From, q.To))
+	}
+	respondJSON(w, http.StatusOK, sheet)
+}
+
+// writeTimesheetCSV writes timesheet entries as CSV with a header row, one
+// row per entry and group: entries on tasks with several tags appear once for
+// each when grouped by tag.
+func writeTimesheetCSV(w http.ResponseWriter, entries []db.TimesheetEntry, groupBy string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		groupBy, "date", "user", "agent_name", "project", "task_number", "task", "sprint", "tags", "hours",
+		"note", "started_at", "ended_at",
+	})
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	for _, e := range entries {
+		tags := make([]string, len(e.Tags))
+		for i, tag := range e.Tags {
+			tags[i] = tag.Name
+		}
+		taskNumber, sprint := "", ""
+		if e.TaskNumber != nil {
+			taskNumber = strconv.FormatInt(*e.TaskNumber, 10)
+		}
+		if e.SprintName != nil {
+			sprint = *e.SprintName
+		}
+
+		groups := []string{e.UserName}
+		switch groupBy {
+		case "project":
+			groups = []string{e.ProjectName}
+		case "sprint":
+			groups = []string{sprint}
+		case "tag":
+			groups = tags
+			if len(groups) == 0 {
+				groups = []string{""}
+			}
+		}
+		for _, group := range groups {
+			cw.Write(escapeCSVFormulas([]string{
+				group, e.Date, e.UserName, e.AgentName, e.ProjectName, taskNumber, e.TaskTitle, sprint,
+				strings.Join(tags, ", "), strconv.FormatFloat(secondsToHours(e.DurationSeconds), 'f', 2, 64),
+				e.Note, optionalTime(e.StartedAt), optionalTime(e.EndedAt),
+			}))
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"taskai/internal/db"
+)
+
+func TestGroupTimesheet(t *testing.T) {
+	id := func(v int64) *int64 { return &v }
+	name := func(v string) *string { return &v }
+	entry := func(userID *int64, user string, seconds int64, sprintID *int64, sprint *string, tags ...db.TimesheetTag) db.TimesheetEntry {
+		return db.TimesheetEntry{
+			TimeEntry:   db.TimeEntry{UserID: userID, UserName: user, ProjectID: 1, DurationSeconds: seconds},
+			ProjectName: "Web", SprintID: sprintID, SprintName: sprint, Tags: tags,
+		}
+	}
+	bug, ui := db.TimesheetTag{ID: 1, Name: "bug"}, db.TimesheetTag{ID: 2, Name: "ui"}
+	entries := []db.TimesheetEntry{
+		entry(id(1), "Ada", 3600, id(5), name("Sprint 1"), bug, ui),
+		entry(id(2), "Bob", 1800, nil, nil, bug),
+		entry(id(1), "Ada", 1800, id(5), name("Sprint 1")),
+		entry(nil, "", 900, nil, nil),
+	}
+
+	byUser := groupTimesheet(entries, "user")
+	if len(byUser) != 3 || byUser[0].Name != "Ada" || byUser[0].Seconds != 5400 || byUser[0].Hours != 1.5 ||
+		byUser[0].Entries != 2 || byUser[2].Name != "Deleted user" || byUser[2].ID != nil {
+		t.Errorf("Unexpected user groups: %+v", byUser)
+	}
+	if bySprint := groupTimesheet(entries, "sprint"); len(bySprint) != 2 || bySprint[1].Name != "No sprint" || bySprint[1].Seconds != 2700 {
+		t.Errorf("Unexpected sprint groups: %+v", bySprint)
+	}
+	byTag := groupTimesheet(entries, "tag")
+	if len(byTag) != 3 || byTag[0].Name != "bug" || byTag[0].Seconds != 5400 || byTag[1].Name != "ui" ||
+		byTag[1].Seconds != 3600 || byTag[2].Name != "No tag" {
+		t.Errorf("Expected entries to count towards each of their tags, got %+v", byTag)
+	}
+}
+
+func TestTimeEntries(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
+	_, projectID := createTestTeamAndProject(t, ts, ownerID, "Timesheets")
+	ts.AddProjectMember(t, projectID, memberID, ownerID, RoleMember)
+	ts.AddProjectMember(t, projectID, viewerID, ownerID, RoleViewer)
+	owner := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, ownerID, "owner@example.com")}
+	member := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, memberID, "member@example.com")}
+	viewer := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, viewerID, "viewer@example.com")}
+	createDefaultSwimLanes(t, ts, projectID)
+	sprintID := createTestSprint(t, ts, ownerID, projectID, "Sprint 1", "active")
+	tagID := createTestTag(t, ts, ownerID, projectID, "backend", "#3B82F6")
+
+	rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks", projectID), map[string]interface{}{
+		"title": "Fix login", "sprint_id": sprintID, "tag_ids": []int64{tagID}, "actual_hours": 3,
+	}, owner)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var task Task
+	DecodeJSON(t, rec, &task)
+	entriesPath := fmt.Sprintf("/api/tasks/%d/time-entries", task.ID)
+
+	getTask := func() Task {
+		rec := routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/projects/%d/tasks/%d", projectID, task.TaskNumber), nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var task Task
+		DecodeJSON(t, rec, &task)
+		return task
+	}
+	logTime := func(body map[string]interface{}, headers map[string]string) db.TimeEntry {
+		rec := routerRequest(t, ts, http.MethodPost, entriesPath, body, headers)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var e db.TimeEntry
+		DecodeJSON(t, rec, &e)
+		return e
+	}
+
+	for _, body := range []map[string]interface{}{
+		{},
+		{"minutes": 0},
+		{"minutes": 1441},
+		{"minutes": 30, "started_at": "2026-03-02T09:00:00Z", "ended_at": "2026-03-02T10:00:00Z"},
+		{"started_at": "2026-03-02T10:00:00Z", "ended_at": "2026-03-02T09:00:00Z"},
+		{"minutes": 30, "date": "02/03/2026"},
+	} {
+		rec := routerRequest(t, ts, http.MethodPost, entriesPath, body, owner)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	}
+
+	manual := logTime(map[string]interface{}{"minutes": 90, "note": "Reproduced it", "date": "2026-03-02"}, owner)
+	if manual.DurationSeconds != 5400 || manual.Date != "2026-03-02" || manual.UserName == "" || manual.Running {
+		t.Errorf("Unexpected time entry: %+v", manual)
+	}
+	ranged := logTime(map[string]interface{}{"started_at": "2026-03-03T09:00:00Z", "ended_at": "2026-03-03T09:45:00Z"}, member)
+	if ranged.DurationSeconds != 2700 || ranged.Date != "2026-03-03" {
+		t.Errorf("Unexpected time entry: %+v", ranged)
+	}
+	if got := getTask(); got.ActualHours == nil || *got.ActualHours != 2.25 {
+		t.Errorf("Expected actual hours to be the sum of the time entries, got %v", got.ActualHours)
+	}
+
+	t.Run("actual hours can't be set once time is logged", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/tasks/%d", task.ID), map[string]interface{}{"actual_hours": 10}, owner)
+		AssertStatusCode(t, rec.Code, http.StatusConflict)
+	})
+
+	t.Run("viewers can list but not log time", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, entriesPath, nil, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var entries []db.TimeEntry
+		DecodeJSON(t, rec, &entries)
+		if len(entries) != 2 || entries[0].ID != ranged.ID {
+			t.Errorf("Expected entries newest first, got %+v", entries)
+		}
+		rec = routerRequest(t, ts, http.MethodPost, entriesPath, map[string]interface{}{"minutes": 5}, viewer)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("members can only change their own entries", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/time-entries/%d", manual.ID), map[string]interface{}{"minutes": 5}, member)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+		rec = routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/time-entries/%d", ranged.ID), map[string]interface{}{"minutes": 60}, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var e db.TimeEntry
+		DecodeJSON(t, rec, &e)
+		if e.DurationSeconds != 3600 || e.EndedAt == nil || !e.EndedAt.Equal(time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)) {
+			t.Errorf("Unexpected time entry: %+v", e)
+		}
+		if got := getTask(); got.ActualHours == nil || *got.ActualHours != 2.5 {
+			t.Errorf("Expected actual hours to follow the change, got %v", got.ActualHours)
+		}
+	})
+
+	t.Run("timers", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/tasks/%d/timer/start", task.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var timer db.TimeEntry
+		DecodeJSON(t, rec, &timer)
+		if !timer.Running || timer.StartedAt == nil {
+			t.Fatalf("Expected a running timer, got %+v", timer)
+		}
+
+		rec = routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/tasks/%d/timer/start", task.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusConflict)
+		rec = routerRequest(t, ts, http.MethodPatch, fmt.Sprintf("/api/time-entries/%d", timer.ID), map[string]interface{}{"minutes": 5}, member)
+		AssertStatusCode(t, rec.Code, http.StatusConflict)
+
+		rec = routerRequest(t, ts, http.MethodGet, "/api/me/timer", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var running *db.TimeEntry
+		DecodeJSON(t, rec, &running)
+		if running == nil || running.ID != timer.ID {
+			t.Errorf("Expected the running timer, got %+v", running)
+		}
+
+		rec = routerRequest(t, ts, http.MethodPost, "/api/me/timer/stop", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var stopped db.TimeEntry
+		DecodeJSON(t, rec, &stopped)
+		if stopped.Running || stopped.EndedAt == nil {
+			t.Errorf("Expected the timer to be stopped, got %+v", stopped)
+		}
+
+		rec = routerRequest(t, ts, http.MethodPost, "/api/me/timer/stop", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+		rec = routerRequest(t, ts, http.MethodGet, "/api/me/timer", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		if body := strings.TrimSpace(rec.Body.String()); body != "null" {
+			t.Errorf("Expected no running timer, got %s", body)
+		}
+
+		rec = routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/time-entries/%d", stopped.ID), nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+	})
+
+	t.Run("agents log time by task number", func(t *testing.T) {
+		agent := map[string]string{"Authorization": owner["Authorization"], "X-Agent-Name": "triage-bot"}
+		rec := routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks/%d/time-entries", projectID, task.TaskNumber),
+			map[string]interface{}{"minutes": 15, "note": "Triaged", "date": "2026-03-03"}, agent)
+		AssertStatusCode(t, rec.Code, http.StatusCreated)
+		var e db.TimeEntry
+		DecodeJSON(t, rec, &e)
+		if e.AgentName != "triage-bot" || e.TaskID != task.ID {
+			t.Errorf("Unexpected time entry: %+v", e)
+		}
+		rec = routerRequest(t, ts, http.MethodPost, fmt.Sprintf("/api/projects/%d/tasks/999/time-entries", projectID),
+			map[string]interface{}{"minutes": 15}, agent)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+	})
+
+	t.Run("timesheets", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodGet, "/api/timesheets?from=2026-03-02&to=2026-03-03&group_by=user", nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var sheet Timesheet
+		DecodeJSON(t, rec, &sheet)
+		if sheet.TotalSeconds != 5400+3600+900 || len(sheet.Entries) != 3 || len(sheet.Groups) != 2 ||
+			sheet.Groups[0].Seconds != 6300 || sheet.Groups[0].ID == nil || *sheet.Groups[0].ID != ownerID {
+			t.Errorf("Unexpected timesheet: %+v", sheet)
+		}
+		if e := sheet.Entries[0]; e.TaskTitle != "Fix login" || e.ProjectName != "Timesheets" || e.SprintName == nil ||
+			*e.SprintName != "Sprint 1" || len(e.Tags) != 1 || e.Tags[0].Name != "backend" {
+			t.Errorf("Unexpected timesheet entry: %+v", e)
+		}
+
+		rec = routerRequest(t, ts, http.MethodGet, "/api/timesheets?from=2026-03-02&to=2026-03-03&user_id=me&group_by=tag", nil, member)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		DecodeJSON(t, rec, &sheet)
+		if sheet.TotalSeconds != 3600 || len(sheet.Groups) != 1 || sheet.Groups[0].Name != "backend" {
+			t.Errorf("Unexpected timesheet: %+v", sheet)
+		}
+
+		rec = routerRequest(t, ts, http.MethodGet, "/api/timesheets?from=2026-03-02&to=2026-03-03&group_by=sprint&format=csv", nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("Invalid CSV: %v", err)
+		}
+		if len(rows) != 4 || rows[0][0] != "sprint" || rows[1][0] != "Sprint 1" || rows[1][9] != "1.50" {
+			t.Errorf("Unexpected CSV: %v", rows)
+		}
+
+		for _, query := range []string{"group_by=team", "from=2026-03-04&to=2026-03-03", "user_id=abc", "format=xml"} {
+			rec := routerRequest(t, ts, http.MethodGet, "/api/timesheets?"+query, nil, owner)
+			AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+		}
+
+		strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+		stranger := map[string]string{"Authorization": "Bearer " + ts.GenerateTestToken(t, strangerID, "stranger@example.com")}
+		rec = routerRequest(t, ts, http.MethodGet, "/api/timesheets?from=2026-03-02&to=2026-03-03", nil, stranger)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		DecodeJSON(t, rec, &sheet)
+		if len(sheet.Entries) != 0 {
+			t.Errorf("Expected no entries from other people's projects, got %+v", sheet.Entries)
+		}
+		rec = routerRequest(t, ts, http.MethodGet, fmt.Sprintf("/api/timesheets?project_id=%d", projectID), nil, stranger)
+		AssertStatusCode(t, rec.Code, http.StatusForbidden)
+	})
+
+	t.Run("deleting an entry updates actual hours", func(t *testing.T) {
+		rec := routerRequest(t, ts, http.MethodDelete, fmt.Sprintf("/api/time-entries/%d", manual.ID), nil, owner)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+		if got := getTask(); got.ActualHours == nil || *got.ActualHours != 1.25 {
+			t.Errorf("Expected actual hours to drop, got %v", got.ActualHours)
+		}
+	})
+}
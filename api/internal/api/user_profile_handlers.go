@@ -262,6 +262,32 @@ func (s *Server) fetchUserActivity(ctx context.Context, viewerID, targetUserID i
 		}
 	}
 
+	// Task updates; the field changes of one update share a timestamp
+	taskUpdatedRows, err := s.db.QueryContext(ctx, `
+		SELECT th.task_id, t.title, t.project_id, p.name, t.task_number, th.created_at
+		FROM task_history th
+		JOIN tasks t ON t.id = th.task_id
+		JOIN projects p ON p.id = t.project_id
+		WHERE th.user_id = $2
+		  AND t.project_id IN (`+sharedProjectsSubquery+`)
+		`+cc("th.created_at")+`
+		GROUP BY th.task_id, t.title, t.project_id, p.name, t.task_number, th.created_at
+		ORDER BY th.created_at DESC
+		LIMIT `+strconv.Itoa(pageSize)+`
+	`, args...)
+	if err == nil {
+		defer taskUpdatedRows.Close()
+		for taskUpdatedRows.Next() {
+			var item UserActivityItem
+			var taskNumber int64
+			if taskUpdatedRows.Scan(&item.EntityID, &item.EntityTitle, &item.ProjectID, &item.ProjectName, &taskNumber, &item.CreatedAt) == nil {
+				item.Type = "task_updated"
+				item.Link = "/app/projects/" + int64ToStr(item.ProjectID) + "/tasks/" + int64ToStr(taskNumber)
+				items = append(items, item)
+			}
+		}
+	}
+
 	// Wiki annotations created
 	waRows, err := s.db.QueryContext(ctx, `
 		SELECT wa.id, wp.title, wp.project_id, p.name, wp.id, wa.created_at
@@ -24,7 +24,7 @@ func (s *Server) HandleUserWebSocket(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		claims, err := s.auth.ValidateToken(token)
+		claims, err := s.authenticateToken(r.Context(), token)
 		if err != nil {
 			s.logger.Warn("Invalid user WS token", zap.Error(err))
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
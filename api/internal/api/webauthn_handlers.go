@@ -0,0 +1,390 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/db"
+	"taskai/internal/webauthn"
+)
+
+// maxCredentialNameLength bounds the name of a WebAuthn credential
+const maxCredentialNameLength = 100
+
+// WebAuthnRegisterRequest finishes registering a credential
+type WebAuthnRegisterRequest struct {
+	Challenge  string                         `json:"challenge"`
+	Name       string                         `json:"name"`
+	Credential *webauthn.RegistrationResponse `json:"credential"`
+}
+
+// WebAuthnLoginRequest finishes a passwordless login
+type WebAuthnLoginRequest struct {
+	Challenge  string                      `json:"challenge"`
+	Credential *webauthn.AssertionResponse `json:"credential"`
+}
+
+// RenameWebAuthnCredentialRequest renames a credential
+type RenameWebAuthnCredentialRequest struct {
+	Name string `json:"name"`
+}
+
+// relyingParty returns the WebAuthn relying party for the configured
+// frontend origins.
+func (s *Server) relyingParty() (*webauthn.RelyingParty, error) {
+	origins := s.config.WebAuthnOrigins
+	if len(origins) == 0 {
+		origins = []string{s.getAppURL()}
+	}
+	return webauthn.New(webauthn.Config{RPID: s.config.WebAuthnRPID, RPName: "TaskAI", Origins: origins})
+}
+
+// webAuthnUserHandle is the opaque user handle credentials are registered
+// with, returned by authenticators during passwordless login
+func webAuthnUserHandle(userID int64) string {
+	return base64.RawURLEncoding.EncodeToString(binary.BigEndian.AppendUint64(nil, uint64(userID)))
+}
+
+func credentialDescriptors(creds []db.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(creds))
+	for _, c := range creds {
+		descriptors = append(descriptors, webauthn.NewCredentialDescriptor(c.CredentialID, c.Transports))
+	}
+	return descriptors
+}
+
+// HandleWebAuthnRegisterBegin returns the options to register a new passkey
+// or security key for the current user
+// Route: POST /api/settings/webauthn/register/begin
+func (s *Server) HandleWebAuthnRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		s.logger.Error("WebAuthn is misconfigured", zap.Error(err))
+		respondError(w, http.StatusServiceUnavailable, "passkeys are not configured", "webauthn_unavailable")
+		return
+	}
+
+	entUser, err := s.db.Client.User.Get(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to query user", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to start registration", "internal_error")
+		return
+	}
+	creds, err := s.db.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list webauthn credentials", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to start registration", "internal_error")
+		return
+	}
+
+	options, err := rp.CreationOptions(webauthn.User{
+		ID:          webAuthnUserHandle(userID),
+		Name:        entUser.Email,
+		DisplayName: userDisplayName(entUser),
+	}, credentialDescriptors(creds))
+	if err == nil {
+		err = s.db.CreateAuthChallenge(ctx, options.Challenge, db.ChallengeWebAuthnRegister, userID, time.Now().Add(webauthn.Timeout))
+	}
+	if err != nil {
+		s.logger.Error("Failed to create webauthn challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to start registration", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, options)
+}
+
+// HandleWebAuthnRegisterFinish verifies the authenticator's response and
+// saves the new credential
+// Route: POST /api/settings/webauthn/register/finish
+func (s *Server) HandleWebAuthnRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	var req WebAuthnRegisterRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.Challenge == "" || req.Credential == nil {
+		respondError(w, http.StatusBadRequest, "challenge and credential are required", "validation_error")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if len(req.Name) > maxCredentialNameLength {
+		respondError(w, http.StatusBadRequest, "name is too long", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		s.logger.Error("WebAuthn is misconfigured", zap.Error(err))
+		respondError(w, http.StatusServiceUnavailable, "passkeys are not configured", "webauthn_unavailable")
+		return
+	}
+
+	challengeUser, err := s.db.GetAuthChallenge(ctx, req.Challenge, db.ChallengeWebAuthnRegister, time.Now())
+	if err != nil && !errors.Is(err, db.ErrAuthChallengeInvalid) {
+		s.logger.Error("Failed to query challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to register credential", "internal_error")
+		return
+	}
+	if err != nil || challengeUser != userID {
+		respondError(w, http.StatusBadRequest, "registration expired, please try again", "invalid_challenge")
+		return
+	}
+
+	cred, err := rp.VerifyRegistration(req.Credential, req.Challenge)
+	if err != nil {
+		s.logger.Info("WebAuthn registration rejected", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusBadRequest, "the security key's response could not be verified", "webauthn_failed")
+		return
+	}
+	if err := s.db.ConsumeAuthChallenge(ctx, req.Challenge); err != nil {
+		respondError(w, http.StatusBadRequest, "registration expired, please try again", "invalid_challenge")
+		return
+	}
+
+	if req.Name == "" {
+		// Synced credentials are passkeys; device-bound ones are usually security keys
+		req.Name = "Security key"
+		if cred.BackupEligible {
+			req.Name = "Passkey"
+		}
+	}
+	stored := &db.WebAuthnCredential{
+		UserID:         userID,
+		CredentialID:   cred.ID,
+		PublicKey:      cred.PublicKey,
+		SignCount:      cred.SignCount,
+		Transports:     cred.Transports,
+		Name:           req.Name,
+		BackupEligible: cred.BackupEligible,
+		BackedUp:       cred.BackedUp,
+	}
+	if stored.Transports == nil {
+		stored.Transports = []string{}
+	}
+	if err := s.db.CreateWebAuthnCredential(ctx, stored); err != nil {
+		if isUniqueConstraintError(err) {
+			respondError(w, http.StatusConflict, "this security key is already registered", "conflict")
+			return
+		}
+		s.logger.Error("Failed to save webauthn credential", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to register credential", "internal_error")
+		return
+	}
+
+	s.logger.Info("WebAuthn credential registered", zap.Int64("user_id", userID), zap.Int64("credential_id", stored.ID))
+	respondJSON(w, http.StatusCreated, stored)
+}
+
+// HandleListWebAuthnCredentials lists the current user's passkeys and
+// security keys
+// Route: GET /api/settings/webauthn/credentials
+func (s *Server) HandleListWebAuthnCredentials(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	creds, err := s.db.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to list webauthn credentials", zap.Error(err), zap.Int64("user_id", userID))
+		respondError(w, http.StatusInternalServerError, "failed to list credentials", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, creds)
+}
+
+// HandleRenameWebAuthnCredential renames one of the current user's credentials
+// Route: PATCH /api/settings/webauthn/credentials/{id}
+func (s *Server) HandleRenameWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	credentialID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid credential ID", "invalid_input")
+		return
+	}
+
+	var req RenameWebAuthnCredentialRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxCredentialNameLength {
+		respondError(w, http.StatusBadRequest, "name must be between 1 and 100 characters", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.db.RenameWebAuthnCredential(ctx, userID, credentialID, req.Name); err != nil {
+		if errors.Is(err, db.ErrWebAuthnCredentialNotFound) {
+			respondError(w, http.StatusNotFound, "credential not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to rename webauthn credential", zap.Error(err), zap.Int64("credential_id", credentialID))
+		respondError(w, http.StatusInternalServerError, "failed to rename credential", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"id": credentialID, "name": req.Name})
+}
+
+// HandleDeleteWebAuthnCredential revokes one of the current user's credentials
+// Route: DELETE /api/settings/webauthn/credentials/{id}
+func (s *Server) HandleDeleteWebAuthnCredential(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(int64)
+
+	credentialID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid credential ID", "invalid_input")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := s.db.DeleteWebAuthnCredential(ctx, userID, credentialID); err != nil {
+		if errors.Is(err, db.ErrWebAuthnCredentialNotFound) {
+			respondError(w, http.StatusNotFound, "credential not found", "not_found")
+			return
+		}
+		s.logger.Error("Failed to delete webauthn credential", zap.Error(err), zap.Int64("credential_id", credentialID))
+		respondError(w, http.StatusInternalServerError, "failed to delete credential", "internal_error")
+		return
+	}
+
+	s.logger.Info("WebAuthn credential revoked", zap.Int64("user_id", userID), zap.Int64("credential_id", credentialID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleWebAuthnLoginBegin returns the options for a passwordless login with
+// any passkey registered for this site
+// Route: POST /api/auth/webauthn/login/begin
+func (s *Server) HandleWebAuthnLoginBegin(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rp, err := s.relyingParty()
+	if err != nil {
+		s.logger.Error("WebAuthn is misconfigured", zap.Error(err))
+		respondError(w, http.StatusServiceUnavailable, "passkeys are not configured", "webauthn_unavailable")
+		return
+	}
+
+	options, err := rp.RequestOptions(nil, "required")
+	if err == nil {
+		err = s.db.CreateAuthChallenge(ctx, options.Challenge, db.ChallengeWebAuthnLogin, 0, time.Now().Add(webauthn.Timeout))
+	}
+	if err != nil {
+		s.logger.Error("Failed to create webauthn challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to start login", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, options)
+}
+
+// HandleWebAuthnLoginFinish logs in with a passkey. The authenticator must
+// verify the user (PIN or biometrics), which stands in for both the password
+// and the second factor.
+// Route: POST /api/auth/webauthn/login/finish
+func (s *Server) HandleWebAuthnLoginFinish(w http.ResponseWriter, r *http.Request) {
+	var req WebAuthnLoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		respondError(w, http.StatusBadRequest, "invalid request body", "invalid_request")
+		return
+	}
+	if req.Challenge == "" || req.Credential == nil {
+		respondError(w, http.StatusBadRequest, "challenge and credential are required", "validation_error")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.db.GetAuthChallenge(ctx, req.Challenge, db.ChallengeWebAuthnLogin, time.Now()); err != nil {
+		if errors.Is(err, db.ErrAuthChallengeInvalid) {
+			respondError(w, http.StatusUnauthorized, "login expired, please try again", "invalid_challenge")
+			return
+		}
+		s.logger.Error("Failed to query challenge", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+
+	cred, err := s.db.GetWebAuthnCredential(ctx, req.Credential.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrWebAuthnCredentialNotFound) {
+			respondError(w, http.StatusUnauthorized, "this passkey is not registered", "invalid_credentials")
+			return
+		}
+		s.logger.Error("Failed to query webauthn credential", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if handle := req.Credential.Response.UserHandle; handle != "" && strings.TrimRight(handle, "=") != webAuthnUserHandle(cred.UserID) {
+		respondError(w, http.StatusUnauthorized, "this passkey is not registered", "invalid_credentials")
+		return
+	}
+
+	lockout, err := s.db.GetLockout(ctx, cred.UserID)
+	if err != nil {
+		s.logger.Error("Failed to query lockout", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if lockout.Locked(time.Now()) {
+		respondAccountLocked(w, *lockout.LockedUntil)
+		return
+	}
+
+	valid, err := s.verifyAssertion(ctx, cred, req.Challenge, req.Credential, true)
+	if err != nil {
+		s.logger.Error("Failed to verify passkey", zap.Error(err), zap.Int64("user_id", cred.UserID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+	if !valid {
+		respondError(w, http.StatusUnauthorized, "the passkey could not be verified", "invalid_credentials")
+		return
+	}
+	if err := s.db.ConsumeAuthChallenge(ctx, req.Challenge); err != nil {
+		respondError(w, http.StatusUnauthorized, "login expired, please try again", "invalid_challenge")
+		return
+	}
+	if s.respondDeactivated(ctx, w, cred.UserID) || s.respondSSORequired(ctx, w, cred.UserID) {
+		return
+	}
+
+	entUser, err := s.db.Client.User.Get(ctx, cred.UserID)
+	if err != nil {
+		s.logger.Error("Failed to query user", zap.Error(err), zap.Int64("user_id", cred.UserID))
+		respondError(w, http.StatusInternalServerError, "failed to authenticate", "internal_error")
+		return
+	}
+
+	s.completeLogin(ctx, w, r, entUser, lockout.FailedLogins)
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"taskai/internal/webauthn"
+	"taskai/internal/webauthn/webauthntest"
+)
+
+// testOrigin is the frontend URL test servers accept WebAuthn responses from
+const testOrigin = "http://localhost:5173"
+
+// registerWebAuthnCredential registers a new software authenticator for a user
+func (ts *TestServer) registerWebAuthnCredential(t *testing.T, userID int64, name string) (*webauthntest.Authenticator, int64) {
+	t.Helper()
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/begin", nil, userID, nil)
+	ts.HandleWebAuthnRegisterBegin(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var options webauthn.CreationOptions
+	DecodeJSON(t, rec, &options)
+
+	a := webauthntest.New()
+	rec, req = ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/finish", WebAuthnRegisterRequest{
+		Challenge:  options.Challenge,
+		Name:       name,
+		Credential: a.Register(&options, testOrigin),
+	}, userID, nil)
+	ts.HandleWebAuthnRegisterFinish(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+	var cred struct {
+		ID int64 `json:"id"`
+	}
+	DecodeJSON(t, rec, &cred)
+	return a, cred.ID
+}
+
+func TestWebAuthnRegistration(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	otherID := ts.CreateTestUser(t, "other@example.com", "password123")
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/begin", nil, userID, nil)
+	ts.HandleWebAuthnRegisterBegin(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var options webauthn.CreationOptions
+	DecodeJSON(t, rec, &options)
+	if options.RP.ID != "localhost" || options.User.Name != "user@example.com" || options.User.ID != webAuthnUserHandle(userID) {
+		t.Errorf("Unexpected creation options: %+v", options)
+	}
+
+	a := webauthntest.New()
+	a.Synced = true
+	finish := WebAuthnRegisterRequest{Challenge: options.Challenge, Credential: a.Register(&options, testOrigin)}
+
+	t.Run("another user can't use the challenge", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/finish", finish, otherID, nil)
+		ts.HandleWebAuthnRegisterFinish(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "registration expired", "invalid_challenge")
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		bad := WebAuthnRegisterRequest{Challenge: options.Challenge, Credential: a.Register(&options, "https://evil.example")}
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/finish", bad, userID, nil)
+		ts.HandleWebAuthnRegisterFinish(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "could not be verified", "webauthn_failed")
+	})
+
+	rec, req = ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/finish", finish, userID, nil)
+	ts.HandleWebAuthnRegisterFinish(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+	var created map[string]interface{}
+	DecodeJSON(t, rec, &created)
+	if created["name"] != "Passkey" || created["backup_eligible"] != true || created["public_key"] != nil {
+		t.Errorf("Expected a passkey without its public key, got %v", created)
+	}
+
+	t.Run("challenge is single use", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/finish", finish, userID, nil)
+		ts.HandleWebAuthnRegisterFinish(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "registration expired", "invalid_challenge")
+	})
+
+	t.Run("existing credentials are excluded", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost, "/api/settings/webauthn/register/begin", nil, userID, nil)
+		ts.HandleWebAuthnRegisterBegin(rec, req)
+		var options webauthn.CreationOptions
+		DecodeJSON(t, rec, &options)
+		if len(options.ExcludeCredentials) != 1 || options.ExcludeCredentials[0].ID != a.ID() {
+			t.Errorf("Expected the registered credential to be excluded, got %+v", options.ExcludeCredentials)
+		}
+	})
+
+	credID := strconv.FormatInt(int64(created["id"].(float64)), 10)
+
+	t.Run("rename", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPatch, "/api/settings/webauthn/credentials/"+credID,
+			RenameWebAuthnCredentialRequest{Name: "  MacBook  "}, userID, map[string]string{"id": credID})
+		ts.HandleRenameWebAuthnCredential(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodPatch, "/api/settings/webauthn/credentials/"+credID,
+			RenameWebAuthnCredentialRequest{Name: "Mine now"}, otherID, map[string]string{"id": credID})
+		ts.HandleRenameWebAuthnCredential(rec, req)
+		AssertError(t, rec, http.StatusNotFound, "credential not found", "not_found")
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodPatch, "/api/settings/webauthn/credentials/"+credID,
+			RenameWebAuthnCredentialRequest{Name: " "}, userID, map[string]string{"id": credID})
+		ts.HandleRenameWebAuthnCredential(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusBadRequest)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		ts.registerWebAuthnCredential(t, userID, "YubiKey")
+
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet, "/api/settings/webauthn/credentials", nil, userID, nil)
+		ts.HandleListWebAuthnCredentials(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var creds []struct {
+			Name       string   `json:"name"`
+			Transports []string `json:"transports"`
+		}
+		DecodeJSON(t, rec, &creds)
+		if len(creds) != 2 || creds[0].Name != "MacBook" || creds[1].Name != "YubiKey" || len(creds[0].Transports) != 1 {
+			t.Errorf("Expected both credentials, oldest first, got %+v", creds)
+		}
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodGet, "/api/settings/webauthn/credentials", nil, otherID, nil)
+		ts.HandleListWebAuthnCredentials(rec, req)
+		DecodeJSON(t, rec, &creds)
+		if len(creds) != 0 {
+			t.Errorf("Expected no credentials for another user, got %+v", creds)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete, "/api/settings/webauthn/credentials/"+credID, nil, otherID, map[string]string{"id": credID})
+		ts.HandleDeleteWebAuthnCredential(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNotFound)
+
+		rec, req = ts.MakeAuthRequest(t, http.MethodDelete, "/api/settings/webauthn/credentials/"+credID, nil, userID, map[string]string{"id": credID})
+		ts.HandleDeleteWebAuthnCredential(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+
+		strong, err := ts.DB.GetStrongAuth(context.Background(), userID)
+		if err != nil {
+			t.Fatalf("GetStrongAuth failed: %v", err)
+		}
+		if strong.WebAuthnCredentials != 1 {
+			t.Errorf("Expected one credential left, got %+v", strong)
+		}
+	})
+}
+
+func TestWebAuthnPasswordlessLogin(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "user@example.com", "password123")
+	a, _ := ts.registerWebAuthnCredential(t, userID, "")
+
+	begin := func() *webauthn.RequestOptions {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/begin", nil, nil)
+		ts.HandleWebAuthnLoginBegin(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var options webauthn.RequestOptions
+		DecodeJSON(t, rec, &options)
+		return &options
+	}
+	finish := func(challenge string, credential *webauthn.AssertionResponse) *WebAuthnLoginRequest {
+		return &WebAuthnLoginRequest{Challenge: challenge, Credential: credential}
+	}
+
+	options := begin()
+	if options.UserVerification != "required" || len(options.AllowCredentials) != 0 {
+		t.Errorf("Expected discoverable credentials with user verification, got %+v", options)
+	}
+
+	body := finish(options.Challenge, a.Assert(options, testOrigin))
+	rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", body, nil)
+	ts.HandleWebAuthnLoginFinish(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+	var resp AuthResponse
+	DecodeJSON(t, rec, &resp)
+	if resp.Token == "" || resp.RefreshToken == "" || resp.User.ID != userID {
+		t.Errorf("Expected a session for the passkey's user, got %+v", resp)
+	}
+	ts.waitForActivity(t, userID, "login", 1)
+
+	t.Run("challenge is single use", func(t *testing.T) {
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", body, nil)
+		ts.HandleWebAuthnLoginFinish(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "login expired", "invalid_challenge")
+	})
+
+	t.Run("user verification is required", func(t *testing.T) {
+		a.UserVerified = false
+		defer func() { a.UserVerified = true }()
+
+		options := begin()
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", finish(options.Challenge, a.Assert(options, testOrigin)), nil)
+		ts.HandleWebAuthnLoginFinish(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "could not be verified", "invalid_credentials")
+	})
+
+	t.Run("unknown passkey", func(t *testing.T) {
+		options := begin()
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", finish(options.Challenge, webauthntest.New().Assert(options, testOrigin)), nil)
+		ts.HandleWebAuthnLoginFinish(rec, req)
+		AssertError(t, rec, http.StatusUnauthorized, "not registered", "invalid_credentials")
+	})
+
+	t.Run("mismatched user handle", func(t *testing.T) {
+		options := begin()
+		assertion := a.Assert(options, testOrigin)
+		assertion.Response.UserHandle = webAuthnUserHandle(userID + 1)
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", finish(options.Challenge, assertion), nil)
+		ts.HandleWebAuthnLoginFinish(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("cloned credential", func(t *testing.T) {
+		options := begin()
+		a.SignCount = 0
+		rec, req := MakeRequest(t, http.MethodPost, "/api/auth/webauthn/login/finish", finish(options.Challenge, a.Assert(options, testOrigin)), nil)
+		ts.HandleWebAuthnLoginFinish(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,320 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/netguard"
+)
+
+const (
+	webhookPollInterval   = 10 * time.Second
+	webhookMaxAttempts    = 8
+	webhookBaseBackoff    = 30 * time.Second
+	webhookMaxBackoff     = 6 * time.Hour
+	webhookRequestTimeout = 10 * time.Second
+	webhookClaimTimeout   = webhookRequestTimeout * 3
+	webhookBatchSize      = 50
+	webhookMaxLoggedBody  = 2048
+
+	// WebhookSignatureHeader carries "sha256=<hex HMAC of the body>" keyed with the webhook secret.
+	WebhookSignatureHeader = "X-TaskAI-Signature-256"
+	WebhookEventHeader     = "X-TaskAI-Event"
+	WebhookDeliveryHeader  = "X-TaskAI-Delivery"
+)
+
+// allowPrivateWebhookTargets lets webhooks reach internal addresses. Tests
+// enable it to deliver to a local receiver.
+var allowPrivateWebhookTargets = false
+
+// webhookHTTPClient delivers webhooks. It only connects to public addresses
+// and never follows redirects; a 3xx response is recorded as a failed attempt.
+var webhookHTTPClient = netguard.NewClient(webhookRequestTimeout, func() bool { return allowPrivateWebhookTargets })
+
+// webhookEnvelope is the JSON body POSTed to subscribers.
+type webhookEnvelope struct {
+	Event     string      `json:"event"`
+	ProjectID int64       `json:"project_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// signWebhookPayload returns the signature header value for a payload.
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before retry number `attempts` (1-based).
+func webhookBackoff(attempts int) time.Duration {
+	d := webhookBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return d
+}
+
+// webhookSubscribed reports whether a stored event list includes event.
+func webhookSubscribed(events, event string) bool {
+	for _, e := range strings.Split(events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// emitWebhookEvent queues a delivery of event to every active webhook of the
+// project that subscribes to it. Called alongside broadcastToProjectMembers,
+// usually in its own goroutine; failures are logged and never surface to the
+// request that triggered the event.
+func (s *Server) emitWebhookEvent(projectID int64, event string, data interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT id, events FROM project_webhooks WHERE project_id = ? AND active = ?`), projectID, true)
+	if err != nil {
+		s.logger.Warn("emitWebhookEvent: query failed",
+			zap.Int64("project_id", projectID),
+			zap.Error(err),
+		)
+		return
+	}
+	var targets []int64
+	for rows.Next() {
+		var id int64
+		var events string
+		if err := rows.Scan(&id, &events); err != nil {
+			continue
+		}
+		if webhookSubscribed(events, event) {
+			targets = append(targets, id)
+		}
+	}
+	rows.Close()
+
+	if len(targets) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(webhookEnvelope{
+		Event:     event,
+		ProjectID: projectID,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		s.logger.Error("emitWebhookEvent: failed to marshal payload",
+			zap.String("event", event),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, webhookID := range targets {
+		if _, err := s.enqueueWebhookDelivery(ctx, webhookID, event, string(payload)); err != nil {
+			s.logger.Warn("emitWebhookEvent: failed to queue delivery",
+				zap.Int64("webhook_id", webhookID),
+				zap.String("event", event),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// enqueueWebhookDelivery inserts a pending delivery due immediately.
+func (s *Server) enqueueWebhookDelivery(ctx context.Context, webhookID int64, event, payload string) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at)
+		 VALUES (?, ?, ?, 'pending', ?) RETURNING id`),
+		webhookID, event, payload, time.Now().UTC(),
+	).Scan(&id)
+	return id, err
+}
+
+// StartWebhookDeliveryWorker polls the delivery queue and POSTs due deliveries
+func (s *Server) StartWebhookDeliveryWorker(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	s.logger.Info("Starting webhook delivery worker",
+		zap.Duration("interval", webhookPollInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Webhook delivery worker shutting down")
+			return
+		case <-ticker.C:
+			s.processWebhookDeliveries(ctx)
+		}
+	}
+}
+
+// pendingWebhookDelivery is a queued delivery joined with its webhook config.
+type pendingWebhookDelivery struct {
+	id       int64
+	event    string
+	payload  string
+	attempts int
+	url      string
+	secret   string
+	active   bool
+}
+
+// processWebhookDeliveries sends one batch of due deliveries.
+func (s *Server) processWebhookDeliveries(parentCtx context.Context) {
+	ctx, cancel := context.WithTimeout(parentCtx, 2*time.Minute)
+	defer cancel()
+
+	now := time.Now().UTC()
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`
+		SELECT d.id, d.event, d.payload, d.attempts, w.url, w.secret, w.active
+		FROM webhook_deliveries d
+		JOIN project_webhooks w ON w.id = d.webhook_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= ?
+		ORDER BY d.next_attempt_at, d.id
+		LIMIT ?`), now, webhookBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to fetch pending webhook deliveries", zap.Error(err))
+		return
+	}
+	var due []pendingWebhookDelivery
+	for rows.Next() {
+		var d pendingWebhookDelivery
+		if err := rows.Scan(&d.id, &d.event, &d.payload, &d.attempts, &d.url, &d.secret, &d.active); err != nil {
+			s.logger.Warn("Failed to scan webhook delivery", zap.Error(err))
+			continue
+		}
+		if d.secret, err = s.db.DecryptSecret(d.secret); err != nil {
+			s.logger.Warn("Failed to decrypt webhook secret", zap.Int64("delivery_id", d.id), zap.Error(err))
+			continue
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		// Claim the delivery by pushing its next attempt out, so a concurrent
+		// worker (another API instance) skips it while we send. The claim is
+		// taken per delivery, so it covers this request however long the
+		// batch has been running.
+		res, err := s.db.ExecContext(ctx, s.db.Rebind(
+			`UPDATE webhook_deliveries SET next_attempt_at = ?
+			 WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?`),
+			time.Now().UTC().Add(webhookClaimTimeout), d.id, now)
+		if err != nil {
+			s.logger.Warn("Failed to claim webhook delivery", zap.Int64("delivery_id", d.id), zap.Error(err))
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		s.attemptWebhookDelivery(ctx, d)
+	}
+}
+
+// attemptWebhookDelivery performs one HTTP attempt and records the outcome.
+func (s *Server) attemptWebhookDelivery(ctx context.Context, d pendingWebhookDelivery) {
+	attempts := d.attempts + 1
+	started := time.Now().UTC()
+
+	if !d.active {
+		s.finishWebhookDelivery(ctx, d.id, attempts, started, "failed", nil, nil, "webhook is disabled", 0)
+		return
+	}
+
+	body := []byte(d.payload)
+	var respStatus *int
+	var respBody *string
+	var errMsg string
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		errMsg = err.Error()
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "TaskAI-Webhooks/1.0")
+		req.Header.Set(WebhookEventHeader, d.event)
+		req.Header.Set(WebhookDeliveryHeader, fmt.Sprintf("%d", d.id))
+		req.Header.Set(WebhookSignatureHeader, signWebhookPayload(d.secret, body))
+
+		resp, err := webhookHTTPClient.Do(req)
+		if err != nil {
+			errMsg = err.Error()
+		} else {
+			snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxLoggedBody))
+			resp.Body.Close()
+			code := resp.StatusCode
+			text := string(snippet)
+			respStatus, respBody = &code, &text
+			if code < 200 || code >= 300 {
+				errMsg = fmt.Sprintf("unexpected status %d", code)
+			}
+		}
+	}
+	elapsed := time.Since(started)
+
+	switch {
+	case errMsg == "":
+		s.finishWebhookDelivery(ctx, d.id, attempts, started, "succeeded", respStatus, respBody, "", elapsed)
+	case attempts >= webhookMaxAttempts:
+		s.logger.Warn("Webhook delivery failed permanently",
+			zap.Int64("delivery_id", d.id),
+			zap.String("url", d.url),
+			zap.Int("attempts", attempts),
+			zap.String("error", errMsg),
+		)
+		s.finishWebhookDelivery(ctx, d.id, attempts, started, "failed", respStatus, respBody, errMsg, elapsed)
+	default:
+		_, err := s.db.ExecContext(ctx, s.db.Rebind(`
+			UPDATE webhook_deliveries
+			SET attempts = ?, last_attempt_at = ?, next_attempt_at = ?, response_status = ?,
+			    response_body = ?, error_message = ?, duration_ms = ?
+			WHERE id = ?`),
+			attempts, started, started.Add(webhookBackoff(attempts)), respStatus, respBody, errMsg,
+			elapsed.Milliseconds(), d.id)
+		if err != nil {
+			s.logger.Error("Failed to reschedule webhook delivery", zap.Int64("delivery_id", d.id), zap.Error(err))
+		}
+	}
+}
+
+// finishWebhookDelivery records a terminal delivery state.
+func (s *Server) finishWebhookDelivery(ctx context.Context, id int64, attempts int, attemptedAt time.Time, status string, respStatus *int, respBody *string, errMsg string, elapsed time.Duration) {
+	var deliveredAt interface{}
+	if status == "succeeded" {
+		deliveredAt = time.Now().UTC()
+	}
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(`
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = ?, last_attempt_at = ?, next_attempt_at = NULL, response_status = ?,
+		    response_body = ?, error_message = NULLIF(?, ''), duration_ms = ?, delivered_at = ?
+		WHERE id = ?`),
+		status, attempts, attemptedAt, respStatus, respBody, errMsg, elapsed.Milliseconds(), deliveredAt, id)
+	if err != nil {
+		s.logger.Error("Failed to record webhook delivery result", zap.Int64("delivery_id", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,601 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/internal/netguard"
+)
+
+// Webhook events emitted for project activity. Subscriptions list the events
+// they want, or "*" for everything.
+var webhookEvents = map[string]bool{
+	"task.created":              true,
+	"task.updated":              true,
+	"task.deleted":              true,
+	"comment.created":           true,
+	"sprint.created":            true,
+	"sprint.updated":            true,
+	"sprint.deleted":            true,
+	"swim_lane.created":         true,
+	"swim_lane.updated":         true,
+	"swim_lane.deleted":         true,
+	"wiki_page.created":         true,
+	"wiki_page.updated":         true,
+	"wiki_page.deleted":         true,
+	"wiki_page.content_updated": true,
+}
+
+const errPrivateWebhookURL = "url must not point at a private or internal address"
+
+// Webhook represents an outgoing webhook subscription
+type Webhook struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"` // only returned on create / rotate
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery represents a single queued or attempted webhook delivery
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	WebhookID      int64      `json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	ResponseBody   *string    `json:"response_body,omitempty"`
+	ErrorMessage   *string    `json:"error_message,omitempty"`
+	DurationMs     *int       `json:"duration_ms,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// CreateWebhookRequest represents a request to create a webhook subscription
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+// UpdateWebhookRequest represents a request to update a webhook subscription
+type UpdateWebhookRequest struct {
+	URL          *string   `json:"url,omitempty"`
+	Events       *[]string `json:"events,omitempty"`
+	Active       *bool     `json:"active,omitempty"`
+	RotateSecret bool      `json:"rotate_secret,omitempty"`
+}
+
+const webhookSelectCols = `id, project_id, url, events, active, created_by, created_at, updated_at`
+
+// scanWebhook scans a webhook row selected with webhookSelectCols.
+func scanWebhook(row interface {
+	Scan(...interface{}) error
+}) (Webhook, error) {
+	var wh Webhook
+	var events string
+	var createdBy sql.NullInt64
+	if err := row.Scan(&wh.ID, &wh.ProjectID, &wh.URL, &events, &wh.Active, &createdBy, &wh.CreatedAt, &wh.UpdatedAt); err != nil {
+		return wh, err
+	}
+	wh.Events = splitWebhookEvents(events)
+	if createdBy.Valid {
+		wh.CreatedBy = &createdBy.Int64
+	}
+	return wh, nil
+}
+
+// splitWebhookEvents turns the stored comma-separated event list into a slice.
+func splitWebhookEvents(raw string) []string {
+	events := []string{}
+	for _, e := range strings.Split(raw, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// normalizeWebhookEvents validates the requested events and returns them in
+// storage form. An empty list subscribes to everything.
+func normalizeWebhookEvents(events []string) (string, bool) {
+	if len(events) == 0 {
+		return "*", true
+	}
+	seen := make(map[string]bool, len(events))
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if e == "*" {
+			return "*", true
+		}
+		if !webhookEvents[e] {
+			return "", false
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return strings.Join(out, ","), true
+}
+
+// validWebhookURL accepts absolute http(s) URLs only.
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// publicWebhookHost reports whether the URL's host is allowed as a webhook
+// target. Hosts that resolve to an internal address are rejected; hosts that
+// don't resolve are let through, since the delivery dialer checks again.
+func publicWebhookHost(ctx context.Context, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return allowPrivateWebhookTargets || netguard.PublicIP(ip)
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return allowPrivateWebhookTargets
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return true
+	}
+	for _, a := range addrs {
+		if !allowPrivateWebhookTargets && !netguard.PublicIP(a.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// generateWebhookSecret returns a random hex-encoded signing secret.
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// authorizeWebhookAdmin parses the project ID and verifies the caller may
+// manage the project's webhooks. It writes the error response and returns ok=false
+// when the request should not proceed.
+func (s *Server) authorizeWebhookAdmin(w http.ResponseWriter, r *http.Request) (projectID, userID int64, ok bool) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return 0, 0, false
+	}
+	userID, ok = GetUserID(r)
+	if !ok {
+		respondError(w, http.StatusUnauthorized, "unauthorized", "unauthorized")
+		return 0, 0, false
+	}
+
+	allowed, err := s.authorizeProject(r.Context(), userID, projectID, PermWebhooksManage)
+	if err != nil {
+		s.logger.Error("Failed to check project role", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return 0, 0, false
+	}
+	if !allowed {
+		respondError(w, http.StatusForbidden, "you don't have permission to manage this project's webhooks", "forbidden")
+		return 0, 0, false
+	}
+	return projectID, userID, true
+}
+
+// loadProjectWebhook fetches a webhook by ID, scoped to the given project.
+func (s *Server) loadProjectWebhook(r *http.Request, projectID int64) (Webhook, int64, error) {
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "webhookId"), 10, 64)
+	if err != nil {
+		return Webhook{}, 0, err
+	}
+	wh, err := scanWebhook(s.db.QueryRowContext(r.Context(), s.db.Rebind(
+		`SELECT `+webhookSelectCols+` FROM project_webhooks WHERE id = ? AND project_id = ?`),
+		webhookID, projectID))
+	return wh, webhookID, err
+}
+
+// HandleListWebhooks returns all webhook subscriptions for a project.
+// Route: GET /api/projects/{id}/webhooks
+func (s *Server) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	projectID, _, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), s.db.Rebind(
+		`SELECT `+webhookSelectCols+` FROM project_webhooks WHERE project_id = ? ORDER BY id`), projectID)
+	if err != nil {
+		s.logger.Error("Failed to list webhooks", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to list webhooks", "internal_error")
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		wh, err := scanWebhook(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan webhook", zap.Error(err))
+			continue
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	respondJSON(w, http.StatusOK, webhooks)
+}
+
+// HandleCreateWebhook creates a webhook subscription. The signing secret is
+// returned once in the response; generate one when the caller omits it.
+// Route: POST /api/projects/{id}/webhooks
+func (s *Server) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, userID, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errInvalidRequestBody, "invalid_input")
+		return
+	}
+
+	req.URL = strings.TrimSpace(req.URL)
+	if !validWebhookURL(req.URL) {
+		respondError(w, http.StatusBadRequest, "url must be an absolute http or https URL", "invalid_input")
+		return
+	}
+	if !publicWebhookHost(r.Context(), req.URL) {
+		respondError(w, http.StatusBadRequest, errPrivateWebhookURL, "invalid_input")
+		return
+	}
+	events, valid := normalizeWebhookEvents(req.Events)
+	if !valid {
+		respondError(w, http.StatusBadRequest, "unknown webhook event", "invalid_input")
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to generate secret", "internal_error")
+			return
+		}
+	}
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	sealedSecret, err := s.db.EncryptSecret(secret)
+	if err != nil {
+		s.logger.Error("Failed to encrypt webhook secret", zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create webhook", "internal_error")
+		return
+	}
+
+	var newID int64
+	err = s.db.QueryRowContext(r.Context(), s.db.Rebind(
+		`INSERT INTO project_webhooks (project_id, url, secret, events, active, created_by)
+		 VALUES (?, ?, ?, ?, ?, ?) RETURNING id`),
+		projectID, req.URL, sealedSecret, events, active, userID,
+	).Scan(&newID)
+	if err != nil {
+		s.logger.Error("Failed to create webhook", zap.Int64("project_id", projectID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to create webhook", "internal_error")
+		return
+	}
+
+	wh, err := scanWebhook(s.db.QueryRowContext(r.Context(), s.db.Rebind(
+		`SELECT `+webhookSelectCols+` FROM project_webhooks WHERE id = ?`), newID))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch new webhook", "internal_error")
+		return
+	}
+	wh.Secret = secret
+
+	respondJSON(w, http.StatusCreated, wh)
+}
+
+// HandleUpdateWebhook updates a webhook's URL, events or active flag, and can
+// rotate its signing secret.
+// Route: PATCH /api/projects/{id}/webhooks/{webhookId}
+func (s *Server) HandleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, _, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	_, webhookID, err := s.loadProjectWebhook(r, projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "webhook not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid webhook ID", "invalid_input")
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errInvalidRequestBody, "invalid_input")
+		return
+	}
+
+	sets := []string{}
+	args := []interface{}{}
+	if req.URL != nil {
+		u := strings.TrimSpace(*req.URL)
+		if !validWebhookURL(u) {
+			respondError(w, http.StatusBadRequest, "url must be an absolute http or https URL", "invalid_input")
+			return
+		}
+		if !publicWebhookHost(r.Context(), u) {
+			respondError(w, http.StatusBadRequest, errPrivateWebhookURL, "invalid_input")
+			return
+		}
+		sets = append(sets, "url = ?")
+		args = append(args, u)
+	}
+	if req.Events != nil {
+		events, valid := normalizeWebhookEvents(*req.Events)
+		if !valid {
+			respondError(w, http.StatusBadRequest, "unknown webhook event", "invalid_input")
+			return
+		}
+		sets = append(sets, "events = ?")
+		args = append(args, events)
+	}
+	if req.Active != nil {
+		sets = append(sets, "active = ?")
+		args = append(args, *req.Active)
+	}
+	var newSecret string
+	if req.RotateSecret {
+		if newSecret, err = generateWebhookSecret(); err != nil {
+			respondError(w, http.StatusInternalServerError, "failed to generate secret", "internal_error")
+			return
+		}
+		sealedSecret, err := s.db.EncryptSecret(newSecret)
+		if err != nil {
+			s.logger.Error("Failed to encrypt webhook secret", zap.Error(err))
+			respondError(w, http.StatusInternalServerError, "failed to update webhook", "internal_error")
+			return
+		}
+		sets = append(sets, "secret = ?")
+		args = append(args, sealedSecret)
+	}
+	if len(sets) == 0 {
+		respondError(w, http.StatusBadRequest, "no fields to update", "invalid_input")
+		return
+	}
+
+	sets = append(sets, "updated_at = ?")
+	args = append(args, time.Now().UTC(), webhookID)
+	if _, err := s.db.ExecContext(r.Context(), s.db.Rebind(
+		`UPDATE project_webhooks SET `+strings.Join(sets, ", ")+` WHERE id = ?`), args...); err != nil {
+		s.logger.Error("Failed to update webhook", zap.Int64("webhook_id", webhookID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to update webhook", "internal_error")
+		return
+	}
+
+	wh, _, err := s.loadProjectWebhook(r, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch webhook", "internal_error")
+		return
+	}
+	wh.Secret = newSecret
+
+	respondJSON(w, http.StatusOK, wh)
+}
+
+// HandleDeleteWebhook deletes a webhook subscription and its delivery log.
+// Route: DELETE /api/projects/{id}/webhooks/{webhookId}
+func (s *Server) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, _, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	_, webhookID, err := s.loadProjectWebhook(r, projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "webhook not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid webhook ID", "invalid_input")
+		return
+	}
+
+	// Deliveries are removed by ON DELETE CASCADE
+	if _, err := s.db.ExecContext(r.Context(), s.db.Rebind(
+		`DELETE FROM project_webhooks WHERE id = ?`), webhookID); err != nil {
+		s.logger.Error("Failed to delete webhook", zap.Int64("webhook_id", webhookID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to delete webhook", "internal_error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const webhookDeliverySelectCols = `id, webhook_id, event, payload, status, attempts, next_attempt_at, last_attempt_at,
+	response_status, response_body, error_message, duration_ms, created_at, delivered_at`
+
+// scanWebhookDelivery scans a delivery row selected with webhookDeliverySelectCols.
+func scanWebhookDelivery(row interface {
+	Scan(...interface{}) error
+}) (WebhookDelivery, error) {
+	var d WebhookDelivery
+	var nextAttempt, lastAttempt, deliveredAt sql.NullTime
+	var respStatus, durationMs sql.NullInt64
+	var respBody, errMsg sql.NullString
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &nextAttempt, &lastAttempt,
+		&respStatus, &respBody, &errMsg, &durationMs, &d.CreatedAt, &deliveredAt); err != nil {
+		return d, err
+	}
+	if nextAttempt.Valid {
+		d.NextAttemptAt = &nextAttempt.Time
+	}
+	if lastAttempt.Valid {
+		d.LastAttemptAt = &lastAttempt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	if respStatus.Valid {
+		v := int(respStatus.Int64)
+		d.ResponseStatus = &v
+	}
+	if durationMs.Valid {
+		v := int(durationMs.Int64)
+		d.DurationMs = &v
+	}
+	if respBody.Valid {
+		d.ResponseBody = &respBody.String
+	}
+	if errMsg.Valid && errMsg.String != "" {
+		d.ErrorMessage = &errMsg.String
+	}
+	return d, nil
+}
+
+// HandleListWebhookDeliveries returns the most recent deliveries for a webhook.
+// Route: GET /api/projects/{id}/webhooks/{webhookId}/deliveries
+func (s *Server) HandleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	projectID, _, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	_, webhookID, err := s.loadProjectWebhook(r, projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "webhook not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid webhook ID", "invalid_input")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+
+	rows, err := s.db.QueryContext(r.Context(), s.db.Rebind(
+		`SELECT `+webhookDeliverySelectCols+` FROM webhook_deliveries
+		 WHERE webhook_id = ? ORDER BY id DESC LIMIT ?`), webhookID, limit)
+	if err != nil {
+		s.logger.Error("Failed to list webhook deliveries", zap.Int64("webhook_id", webhookID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to list deliveries", "internal_error")
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan webhook delivery", zap.Error(err))
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	respondJSON(w, http.StatusOK, deliveries)
+}
+
+// HandleRedeliverWebhook queues a fresh delivery with the same event and
+// payload as an earlier one. The original delivery log entry is kept.
+// Route: POST /api/projects/{id}/webhooks/{webhookId}/deliveries/{deliveryId}/redeliver
+func (s *Server) HandleRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, _, ok := s.authorizeWebhookAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	_, webhookID, err := s.loadProjectWebhook(r, projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "webhook not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid webhook ID", "invalid_input")
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid delivery ID", "invalid_input")
+		return
+	}
+
+	var event, payload string
+	err = s.db.QueryRowContext(r.Context(), s.db.Rebind(
+		`SELECT event, payload FROM webhook_deliveries WHERE id = ? AND webhook_id = ?`),
+		deliveryID, webhookID).Scan(&event, &payload)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "delivery not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch delivery", "internal_error")
+		return
+	}
+
+	newID, err := s.enqueueWebhookDelivery(r.Context(), webhookID, event, payload)
+	if err != nil {
+		s.logger.Error("Failed to queue redelivery",
+			zap.Int64("webhook_id", webhookID),
+			zap.Int64("delivery_id", deliveryID),
+			zap.Error(err),
+		)
+		respondError(w, http.StatusInternalServerError, "failed to queue redelivery", "internal_error")
+		return
+	}
+
+	d, err := scanWebhookDelivery(s.db.QueryRowContext(r.Context(), s.db.Rebind(
+		`SELECT `+webhookDeliverySelectCols+` FROM webhook_deliveries WHERE id = ?`), newID))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch delivery", "internal_error")
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, d)
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createTestWebhook creates a webhook through the handler and returns it (including its secret).
+func createTestWebhook(t *testing.T, ts *TestServer, userID, projectID int64, body map[string]interface{}) Webhook {
+	t.Helper()
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+		fmt.Sprintf("/api/projects/%d/webhooks", projectID), body, userID,
+		map[string]string{"id": fmt.Sprintf("%d", projectID)})
+	ts.HandleCreateWebhook(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+	var wh Webhook
+	DecodeJSON(t, rec, &wh)
+	return wh
+}
+
+func TestHandleCreateWebhook(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	memberID := ts.CreateTestUser(t, "member@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Test Project")
+	ts.AddProjectMember(t, projectID, memberID, ownerID, "member")
+
+	tests := []struct {
+		name       string
+		userID     int64
+		body       map[string]interface{}
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "owner creates webhook",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "https://ci.example.com/hook", "events": []string{"task.created"}},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "member is forbidden",
+			userID:     memberID,
+			body:       map[string]interface{}{"url": "https://ci.example.com/hook"},
+			wantStatus: http.StatusForbidden,
+			wantCode:   "forbidden",
+		},
+		{
+			name:       "invalid url",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "ftp://ci.example.com/hook"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_input",
+		},
+		{
+			name:       "loopback url",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "http://127.0.0.1:8080/hook"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_input",
+		},
+		{
+			name:       "localhost url",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "http://localhost/hook"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_input",
+		},
+		{
+			name:       "metadata url",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "http://169.254.169.254/latest/meta-data/"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_input",
+		},
+		{
+			name:       "unknown event",
+			userID:     ownerID,
+			body:       map[string]interface{}{"url": "https://ci.example.com/hook", "events": []string{"task.exploded"}},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+				fmt.Sprintf("/api/projects/%d/webhooks", projectID), tt.body, tt.userID,
+				map[string]string{"id": fmt.Sprintf("%d", projectID)})
+			ts.HandleCreateWebhook(rec, req)
+
+			if tt.wantCode != "" {
+				AssertError(t, rec, tt.wantStatus, "", tt.wantCode)
+				return
+			}
+			AssertStatusCode(t, rec.Code, tt.wantStatus)
+
+			var wh Webhook
+			DecodeJSON(t, rec, &wh)
+			if wh.Secret == "" {
+				t.Error("Expected generated secret in create response")
+			}
+			if len(wh.Events) != 1 || wh.Events[0] != "task.created" {
+				t.Errorf("Expected events [task.created], got %v", wh.Events)
+			}
+			if !wh.Active {
+				t.Error("Expected webhook to be active by default")
+			}
+		})
+	}
+
+	t.Run("list hides secret", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet,
+			fmt.Sprintf("/api/projects/%d/webhooks", projectID), nil, ownerID,
+			map[string]string{"id": fmt.Sprintf("%d", projectID)})
+		ts.HandleListWebhooks(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var webhooks []Webhook
+		DecodeJSON(t, rec, &webhooks)
+		if len(webhooks) != 1 {
+			t.Fatalf("Expected 1 webhook, got %d", len(webhooks))
+		}
+		if webhooks[0].Secret != "" {
+			t.Error("Expected secret to be omitted from list response")
+		}
+	})
+}
+
+func TestHandleUpdateWebhook(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Test Project")
+	wh := createTestWebhook(t, ts, ownerID, projectID, map[string]interface{}{"url": "https://ci.example.com/hook"})
+
+	params := map[string]string{"id": fmt.Sprintf("%d", projectID), "webhookId": fmt.Sprintf("%d", wh.ID)}
+	path := fmt.Sprintf("/api/projects/%d/webhooks/%d", projectID, wh.ID)
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodPatch, path,
+		map[string]interface{}{"active": false, "rotate_secret": true}, ownerID, params)
+	ts.HandleUpdateWebhook(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	var updated Webhook
+	DecodeJSON(t, rec, &updated)
+	if updated.Active {
+		t.Error("Expected webhook to be inactive")
+	}
+	if updated.Secret == "" || updated.Secret == wh.Secret {
+		t.Error("Expected a new secret after rotation")
+	}
+
+	// Webhooks in other projects are not reachable through this project
+	otherProjectID := ts.CreateTestProject(t, ownerID, "Other Project")
+	rec, req = ts.MakeAuthRequest(t, http.MethodDelete,
+		fmt.Sprintf("/api/projects/%d/webhooks/%d", otherProjectID, wh.ID), nil, ownerID,
+		map[string]string{"id": fmt.Sprintf("%d", otherProjectID), "webhookId": fmt.Sprintf("%d", wh.ID)})
+	ts.HandleDeleteWebhook(rec, req)
+	AssertError(t, rec, http.StatusNotFound, "", "not_found")
+
+	rec, req = ts.MakeAuthRequest(t, http.MethodDelete, path, nil, ownerID, params)
+	ts.HandleDeleteWebhook(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusNoContent)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	allowPrivateWebhookTargets = true
+	defer func() { allowPrivateWebhookTargets = false }()
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	fail := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Test Project")
+	wh := createTestWebhook(t, ts, ownerID, projectID, map[string]interface{}{
+		"url":    receiver.URL,
+		"events": []string{"task.created"},
+		"secret": "s3cret",
+	})
+
+	// Only subscribed events are queued
+	ts.emitWebhookEvent(projectID, "task.deleted", map[string]int64{"id": 1})
+	ts.emitWebhookEvent(projectID, "task.created", map[string]interface{}{"id": 1, "title": "Ship it"})
+
+	ctx := context.Background()
+	ts.processWebhookDeliveries(ctx)
+
+	mu.Lock()
+	if len(received) != 1 {
+		mu.Unlock()
+		t.Fatalf("Expected 1 delivery attempt, got %d", len(received))
+	}
+	req, body := received[0], bodies[0]
+	mu.Unlock()
+
+	if got := req.Header.Get(WebhookEventHeader); got != "task.created" {
+		t.Errorf("Expected event header task.created, got %q", got)
+	}
+	if got, want := req.Header.Get(WebhookSignatureHeader), signWebhookPayload("s3cret", body); got != want {
+		t.Errorf("Signature mismatch: got %q, want %q", got, want)
+	}
+	var envelope struct {
+		Event     string `json:"event"`
+		ProjectID int64  `json:"project_id"`
+		Data      struct {
+			Title string `json:"title"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	if envelope.Event != "task.created" || envelope.ProjectID != projectID || envelope.Data.Title != "Ship it" {
+		t.Errorf("Unexpected payload: %s", body)
+	}
+
+	// The failed attempt is rescheduled with backoff, not retried immediately
+	listDeliveries := func() []WebhookDelivery {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet,
+			fmt.Sprintf("/api/projects/%d/webhooks/%d/deliveries", projectID, wh.ID), nil, ownerID,
+			map[string]string{"id": fmt.Sprintf("%d", projectID), "webhookId": fmt.Sprintf("%d", wh.ID)})
+		ts.HandleListWebhookDeliveries(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+		var deliveries []WebhookDelivery
+		DecodeJSON(t, rec, &deliveries)
+		return deliveries
+	}
+
+	deliveries := listDeliveries()
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected 1 delivery, got %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Status != "pending" || d.Attempts != 1 {
+		t.Errorf("Expected pending delivery with 1 attempt, got %s/%d", d.Status, d.Attempts)
+	}
+	if d.ResponseStatus == nil || *d.ResponseStatus != http.StatusServiceUnavailable {
+		t.Errorf("Expected logged response status 503, got %v", d.ResponseStatus)
+	}
+	if d.NextAttemptAt == nil || time.Until(*d.NextAttemptAt) < webhookBaseBackoff/2 {
+		t.Errorf("Expected next attempt to be backed off, got %v", d.NextAttemptAt)
+	}
+
+	// Redeliver queues a fresh copy that is sent on the next run
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	rec, r := ts.MakeAuthRequest(t, http.MethodPost,
+		fmt.Sprintf("/api/projects/%d/webhooks/%d/deliveries/%d/redeliver", projectID, wh.ID, d.ID), nil, ownerID,
+		map[string]string{
+			"id":         fmt.Sprintf("%d", projectID),
+			"webhookId":  fmt.Sprintf("%d", wh.ID),
+			"deliveryId": fmt.Sprintf("%d", d.ID),
+		})
+	ts.HandleRedeliverWebhook(rec, r)
+	AssertStatusCode(t, rec.Code, http.StatusAccepted)
+
+	ts.processWebhookDeliveries(ctx)
+
+	deliveries = listDeliveries()
+	if len(deliveries) != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != "succeeded" || deliveries[0].DeliveredAt == nil {
+		t.Errorf("Expected redelivery to succeed, got %s", deliveries[0].Status)
+	}
+	if deliveries[0].Payload != deliveries[1].Payload {
+		t.Error("Expected redelivery to reuse the original payload")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{20, webhookMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookHTTPClient(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer receiver.Close()
+
+	t.Run("private targets are refused at connect time", func(t *testing.T) {
+		resp, err := webhookHTTPClient.Post(receiver.URL, "application/json", nil)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatal("Expected the connection to a loopback receiver to be refused")
+		}
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		allowPrivateWebhookTargets = true
+		defer func() { allowPrivateWebhookTargets = false }()
+
+		resp, err := webhookHTTPClient.Post(receiver.URL, "application/json", nil)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Errorf("Expected the redirect response itself, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -55,12 +55,12 @@ var validAnnotationColors = map[string]bool{
 
 // ── Access helper ──────────────────────────────────────────────────────────
 
-func (s *Server) checkWikiPageAccess(ctx context.Context, userID, pageID int64) error {
+func (s *Server) checkWikiPageAccess(ctx context.Context, userID, pageID int64, perm Permission) error {
 	page, err := s.db.Client.WikiPage.Get(ctx, pageID)
 	if err != nil {
 		return err
 	}
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, perm)
 	if err != nil {
 		return err
 	}
@@ -94,7 +94,7 @@ func (s *Server) HandleListWikiAnnotations(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := s.checkWikiPageAccess(ctx, userID, pageID); err != nil {
+	if err := s.checkWikiPageAccess(ctx, userID, pageID, PermProjectView); err != nil {
 		handleWikiAccessError(w, err)
 		return
 	}
@@ -178,7 +178,7 @@ func (s *Server) HandleCreateWikiAnnotation(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := s.checkWikiPageAccess(ctx, userID, pageID); err != nil {
+	if err := s.checkWikiPageAccess(ctx, userID, pageID, PermWikiComment); err != nil {
 		handleWikiAccessError(w, err)
 		return
 	}
@@ -265,7 +265,7 @@ func (s *Server) HandleDeleteWikiAnnotation(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := s.checkWikiPageAccess(ctx, userID, pageID); err != nil {
+	if err := s.checkWikiPageAccess(ctx, userID, pageID, PermWikiComment); err != nil {
 		handleWikiAccessError(w, err)
 		return
 	}
@@ -312,7 +312,7 @@ func (s *Server) HandleUpdateWikiAnnotation(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if err := s.checkWikiPageAccess(ctx, userID, pageID); err != nil {
+	if err := s.checkWikiPageAccess(ctx, userID, pageID, PermWikiComment); err != nil {
 		handleWikiAccessError(w, err)
 		return
 	}
@@ -389,7 +389,7 @@ func (s *Server) HandleCreateAnnotationComment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := s.checkWikiPageAccess(ctx, userID, pageID); err != nil {
+	if err := s.checkWikiPageAccess(ctx, userID, pageID, PermWikiComment); err != nil {
 		handleWikiAccessError(w, err)
 		return
 	}
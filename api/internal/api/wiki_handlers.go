@@ -34,11 +34,17 @@ type WikiPageResponse struct {
 	Content     *string   `json:"content,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	ParentID    *int64           `json:"parent_id"`
+	Position    int              `json:"position"`
+	Path        string           `json:"path,omitempty"`        // nested slug path, e.g. "engineering/runbooks/db"
+	Breadcrumbs []WikiBreadcrumb `json:"breadcrumbs,omitempty"` // ancestors, root first
 }
 
 // CreateWikiPageRequest represents a request to create a wiki page
 type CreateWikiPageRequest struct {
-	Title string `json:"title"`
+	Title    string `json:"title"`
+	ParentID *int64 `json:"parent_id,omitempty"`
 }
 
 // UpdateWikiPageRequest represents a request to update a wiki page
@@ -59,7 +65,7 @@ func (s *Server) HandleListWikiPages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -85,6 +91,12 @@ func (s *Server) HandleListWikiPages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki pages", "internal_error")
+		return
+	}
+
 	// Convert to response format
 	response := make([]WikiPageResponse, 0, len(pages))
 	for _, p := range pages {
@@ -104,6 +116,7 @@ func (s *Server) HandleListWikiPages(w http.ResponseWriter, r *http.Request) {
 		if p.Edges.Updater != nil && p.Edges.Updater.Name != nil {
 			wp.UpdaterName = p.Edges.Updater.Name
 		}
+		idx.applyWikiHierarchy(&wp)
 		response = append(response, wp)
 	}
 
@@ -139,7 +152,7 @@ func (s *Server) HandleCreateWikiPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to this project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, projectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermWikiEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -165,6 +178,18 @@ func (s *Server) HandleCreateWikiPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki pages", "internal_error")
+		return
+	}
+	if req.ParentID != nil {
+		if _, ok := idx[*req.ParentID]; !ok {
+			respondError(w, http.StatusBadRequest, "parent page not found in this project", "invalid_input")
+			return
+		}
+	}
+
 	// Generate slug
 	baseSlug := generateSlug(req.Title)
 	slug := baseSlug
@@ -204,6 +229,20 @@ func (s *Server) HandleCreateWikiPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// New pages go last among their siblings
+	position := len(idx.children(req.ParentID))
+	_, err = s.db.ExecContext(ctx, s.db.Rebind(`UPDATE wiki_pages SET parent_id = ?, position = ? WHERE id = ?`),
+		req.ParentID, position, page.ID)
+	if err != nil {
+		s.logger.Error("Failed to place wiki page",
+			zap.Int64("page_id", page.ID),
+			zap.Error(err),
+		)
+		respondError(w, http.StatusInternalServerError, "failed to create wiki page", "internal_error")
+		return
+	}
+	idx[page.ID] = &wikiPageNode{id: page.ID, parentID: req.ParentID, position: position, title: page.Title, slug: page.Slug}
+
 	response := WikiPageResponse{
 		ID:        page.ID,
 		ProjectID: page.ProjectID,
@@ -213,8 +252,14 @@ func (s *Server) HandleCreateWikiPage(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: page.CreatedAt,
 		UpdatedAt: page.UpdatedAt,
 	}
+	idx.applyWikiHierarchy(&response)
 
+	auditChange(r, auditEvent{
+		Action: "wiki_page.created", ResourceType: "wiki_page", ResourceID: page.ID, ProjectID: projectID,
+		After: response,
+	})
 	respondJSON(w, http.StatusCreated, response)
+	go s.emitWebhookEvent(projectID, "wiki_page.created", response)
 }
 
 // HandleGetWikiPage returns a single wiki page
@@ -250,7 +295,7 @@ func (s *Server) HandleGetWikiPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -277,6 +322,9 @@ func (s *Server) HandleGetWikiPage(w http.ResponseWriter, r *http.Request) {
 	if page.Edges.Updater != nil && page.Edges.Updater.Name != nil {
 		response.UpdaterName = page.Edges.Updater.Name
 	}
+	if idx, err := s.loadWikiPageIndex(ctx, page.ProjectID); err == nil {
+		idx.applyWikiHierarchy(&response)
+	}
 
 	respondJSON(w, http.StatusOK, response)
 }
@@ -307,7 +355,7 @@ func (s *Server) HandleUpdateWikiPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermWikiEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -363,8 +411,17 @@ func (s *Server) HandleUpdateWikiPage(w http.ResponseWriter, r *http.Request) {
 		CreatedAt: updatedPage.CreatedAt,
 		UpdatedAt: updatedPage.UpdatedAt,
 	}
+	if idx, err := s.loadWikiPageIndex(ctx, updatedPage.ProjectID); err == nil {
+		idx.applyWikiHierarchy(&response)
+	}
 
+	auditChange(r, auditEvent{
+		Action: "wiki_page.updated", ResourceType: "wiki_page", ResourceID: pageID, ProjectID: page.ProjectID,
+		Before: map[string]string{"title": page.Title, "slug": page.Slug},
+		After:  map[string]string{"title": updatedPage.Title, "slug": updatedPage.Slug},
+	})
 	respondJSON(w, http.StatusOK, response)
+	go s.emitWebhookEvent(updatedPage.ProjectID, "wiki_page.updated", response)
 }
 
 // UpdateWikiPageContentRequest represents a request to update wiki page content
@@ -427,7 +484,7 @@ func (s *Server) HandleGetWikiPageContent(w http.ResponseWriter, r *http.Request
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -470,7 +527,7 @@ func (s *Server) HandleUpdateWikiPageContent(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermWikiEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -509,11 +566,27 @@ func (s *Server) HandleUpdateWikiPageContent(w http.ResponseWriter, r *http.Requ
 	// Sync knowledge graph links in background (best-effort).
 	go s.syncGraphLinks(context.Background(), page.ProjectID, "wiki", pageID, nil, page.Title, req.Content)
 
-	respondJSON(w, http.StatusOK, WikiPageContentResponse{
+	contentResponse := WikiPageContentResponse{
 		PageID:    updatedPage.ID,
 		Content:   updatedPage.Content,
 		UpdatedAt: updatedPage.UpdatedAt,
+	}
+	auditChange(r, auditEvent{
+		Action: "wiki_page.content_updated", ResourceType: "wiki_page", ResourceID: pageID, ProjectID: page.ProjectID,
+		Before: wikiContentSummary(page.Content),
+		After:  wikiContentSummary(updatedPage.Content),
 	})
+	respondJSON(w, http.StatusOK, contentResponse)
+	go s.emitWebhookEvent(page.ProjectID, "wiki_page.content_updated", contentResponse)
+}
+
+// wikiContentSummary stands in for page content in the audit log, which
+// would otherwise hold a copy of every revision
+func wikiContentSummary(content string) map[string]interface{} {
+	return map[string]interface{}{
+		"content_length": len(content),
+		"content_sha256": fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+	}
 }
 
 // maybeCreateVersion creates a version snapshot if versioning criteria are met.
@@ -625,7 +698,7 @@ func (s *Server) HandleListWikiPageVersions(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -697,7 +770,7 @@ func (s *Server) HandleGetWikiPageVersion(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermProjectView)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -770,7 +843,7 @@ func (s *Server) HandleRestoreWikiPageVersion(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermWikiEdit)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -817,6 +890,11 @@ func (s *Server) HandleRestoreWikiPageVersion(w http.ResponseWriter, r *http.Req
 		)
 	}
 
+	auditChange(r, auditEvent{
+		Action: "wiki_page.version_restored", ResourceType: "wiki_page", ResourceID: pageID, ProjectID: page.ProjectID,
+		Before: wikiContentSummary(page.Content),
+		After:  wikiContentSummary(updatedPage.Content),
+	})
 	respondJSON(w, http.StatusOK, WikiPageContentResponse{
 		PageID:    updatedPage.ID,
 		Content:   updatedPage.Content,
@@ -824,7 +902,8 @@ func (s *Server) HandleRestoreWikiPageVersion(w http.ResponseWriter, r *http.Req
 	})
 }
 
-// HandleDeleteWikiPage deletes a wiki page
+// HandleDeleteWikiPage deletes a wiki page. Child pages are moved up to the
+// deleted page's parent by default; ?children=cascade deletes the whole subtree.
 func (s *Server) HandleDeleteWikiPage(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
@@ -850,7 +929,7 @@ func (s *Server) HandleDeleteWikiPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	hasAccess, err := s.authorizeProject(ctx, userID, page.ProjectID, PermWikiDelete)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
 		return
@@ -860,8 +939,14 @@ func (s *Server) HandleDeleteWikiPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mode := r.URL.Query().Get("children")
+	if mode != "" && mode != "cascade" && mode != "reparent" {
+		respondError(w, http.StatusBadRequest, "children must be 'cascade' or 'reparent'", "invalid_input")
+		return
+	}
+
 	// Delete the wiki page (cascades to related records)
-	err = s.db.Client.WikiPage.DeleteOneID(pageID).Exec(ctx)
+	deleted, err := s.deleteWikiPageTree(ctx, page.ProjectID, pageID, mode == "cascade")
 	if err != nil {
 		s.logger.Error("Failed to delete wiki page",
 			zap.Int64("page_id", pageID),
@@ -871,5 +956,15 @@ func (s *Server) HandleDeleteWikiPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditChange(r, auditEvent{
+		Action: "wiki_page.deleted", ResourceType: "wiki_page", ResourceID: pageID, ProjectID: page.ProjectID,
+		Before: map[string]interface{}{"title": page.Title, "slug": page.Slug, "deleted_page_ids": deleted},
+	})
 	w.WriteHeader(http.StatusNoContent)
+	for _, id := range deleted {
+		go s.emitWebhookEvent(page.ProjectID, "wiki_page.deleted", map[string]int64{
+			"id":         id,
+			"project_id": page.ProjectID,
+		})
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
@@ -33,6 +34,9 @@ type SearchResultBlock struct {
 	HeadingsPath string  `json:"headings_path,omitempty"`
 	Snippet      string  `json:"snippet"`
 	Rank         float64 `json:"rank,omitempty"`
+
+	PagePath    string           `json:"page_path,omitempty"`
+	Breadcrumbs []WikiBreadcrumb `json:"breadcrumbs,omitempty"`
 }
 
 // SearchWikiResponse represents a wiki search response
@@ -102,6 +106,9 @@ func (s *Server) searchWikiBlocks(ctx context.Context, userID int64, req SearchW
 	if err != nil {
 		return nil, err
 	}
+	if req.ProjectID != nil && !slices.Contains(accessibleProjects, *req.ProjectID) {
+		accessibleProjects = nil
+	}
 
 	if len(accessibleProjects) == 0 {
 		return []SearchResultBlock{}, nil
@@ -151,6 +158,7 @@ func (s *Server) searchWikiBlocks(ctx context.Context, userID int64, req SearchW
 	}
 
 	// Convert to response format
+	indexes := map[int64]wikiPageIndex{}
 	results := make([]SearchResultBlock, 0, len(blocks))
 	for _, block := range blocks {
 		page := block.Edges.Page
@@ -171,6 +179,7 @@ func (s *Server) searchWikiBlocks(ctx context.Context, userID int64, req SearchW
 			headingsPath = *block.HeadingsPath
 		}
 
+		idx := s.cachedWikiPageIndex(ctx, indexes, page.ProjectID)
 		results = append(results, SearchResultBlock{
 			PageID:       page.ID,
 			PageTitle:    page.Title,
@@ -179,6 +188,8 @@ func (s *Server) searchWikiBlocks(ctx context.Context, userID int64, req SearchW
 			BlockType:    block.BlockType,
 			HeadingsPath: headingsPath,
 			Snippet:      snippet,
+			PagePath:     idx.path(page.ID),
+			Breadcrumbs:  idx.breadcrumbs(page.ID),
 		})
 	}
 
@@ -194,9 +205,11 @@ type AutocompletePageRequest struct {
 
 // AutocompletePageResult represents an autocomplete result
 type AutocompletePageResult struct {
-	ID    int64  `json:"id"`
-	Title string `json:"title"`
-	Slug  string `json:"slug"`
+	ID          int64            `json:"id"`
+	Title       string           `json:"title"`
+	Slug        string           `json:"slug"`
+	Path        string           `json:"path,omitempty"`
+	Breadcrumbs []WikiBreadcrumb `json:"breadcrumbs,omitempty"`
 }
 
 // HandleAutocompletePages provides fuzzy page title autocomplete
@@ -243,6 +256,9 @@ func (s *Server) HandleAutocompletePages(w http.ResponseWriter, r *http.Request)
 		respondError(w, http.StatusInternalServerError, "failed to get accessible projects", "internal_error")
 		return
 	}
+	if projectID != nil && !slices.Contains(accessibleProjects, *projectID) {
+		accessibleProjects = nil
+	}
 
 	if len(accessibleProjects) == 0 {
 		respondJSON(w, http.StatusOK, []AutocompletePageResult{})
@@ -276,12 +292,16 @@ func (s *Server) HandleAutocompletePages(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Convert to response format
+	indexes := map[int64]wikiPageIndex{}
 	results := make([]AutocompletePageResult, len(pages))
 	for i, page := range pages {
+		idx := s.cachedWikiPageIndex(ctx, indexes, page.ProjectID)
 		results[i] = AutocompletePageResult{
-			ID:    page.ID,
-			Title: page.Title,
-			Slug:  page.Slug,
+			ID:          page.ID,
+			Title:       page.Title,
+			Slug:        page.Slug,
+			Path:        idx.path(page.ID),
+			Breadcrumbs: idx.breadcrumbs(page.ID),
 		}
 	}
 
@@ -290,6 +310,7 @@ func (s *Server) HandleAutocompletePages(w http.ResponseWriter, r *http.Request)
 
 // getUserAccessibleProjects returns the list of project IDs the user has access to
 // via a single query on the project_members table (avoids N+1 queries).
+// Requests made with an API key only get the projects the key allows.
 func (s *Server) getUserAccessibleProjects(ctx context.Context, userID int64) ([]int64, error) {
 	members, err := s.db.Client.ProjectMember.Query().
 		Where(projectmember.UserID(userID)).
@@ -301,7 +322,9 @@ func (s *Server) getUserAccessibleProjects(ctx context.Context, userID int64) ([
 
 	projectIDs := make([]int64, 0, len(members))
 	for _, m := range members {
-		projectIDs = append(projectIDs, m.ProjectID)
+		if apiKeyAllowsProject(ctx, m.ProjectID) {
+			projectIDs = append(projectIDs, m.ProjectID)
+		}
 	}
 
 	return projectIDs, nil
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"taskai/internal/db"
 )
 
 // ---------------------------------------------------------------------------
@@ -673,6 +675,28 @@ func TestGetUserAccessibleProjectsUnit(t *testing.T) {
 			t.Errorf("Expected project ID %d, got %d", projectID, projectIDs[0])
 		}
 	})
+
+	t.Run("limits API keys to their projects", func(t *testing.T) {
+		ts := NewTestServer(t)
+		defer ts.Close()
+
+		userID := ts.CreateTestUser(t, "test@example.com", "password123")
+		p1 := ts.CreateTestProject(t, userID, "Project 1")
+		ts.CreateTestProject(t, userID, "Project 2")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ctx = context.WithValue(ctx, APIKeyKey, &db.APIKey{ProjectIDs: []int64{p1}})
+
+		projectIDs, err := ts.Server.getUserAccessibleProjects(ctx, userID)
+		if err != nil {
+			t.Fatalf("getUserAccessibleProjects failed: %v", err)
+		}
+
+		if len(projectIDs) != 1 || projectIDs[0] != p1 {
+			t.Errorf("Expected only project %d, got %v", p1, projectIDs)
+		}
+	})
 }
 
 // ---------------------------------------------------------------------------
@@ -1,19 +1,25 @@
 package api
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
+	"fmt"
 	"time"
 
 	"go.uber.org/zap"
 
 	"taskai/ent"
 	"taskai/ent/pageversion"
-	"taskai/ent/wikipage"
 	"taskai/ent/yjsupdate"
+	"taskai/internal/yjs"
 )
 
-// StartSnapshotWorker starts a background worker that periodically creates snapshots of wiki pages
+// snapshotsToKeep is how many compacted states are kept per page. Only the
+// latest is needed for sync and indexing; a few more help when debugging.
+const snapshotsToKeep = 5
+
+// StartSnapshotWorker starts a background worker that periodically compacts
+// the Yjs update log of wiki pages into snapshots
 func (s *Server) StartSnapshotWorker(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -36,39 +42,46 @@ func (s *Server) StartSnapshotWorker(ctx context.Context) {
 	}
 }
 
-// generateSnapshots finds pages that need snapshots and generates them
+// generateSnapshots compacts every page that has updates in its log
 func (s *Server) generateSnapshots(parentCtx context.Context) {
 	ctx, cancel := context.WithTimeout(parentCtx, 2*time.Minute)
 	defer cancel()
 
-	// Find pages that have been updated since their last snapshot
-	pages, err := s.db.Client.WikiPage.Query().
-		Where(wikipage.UpdatedAtGTE(time.Now().Add(-6 * time.Minute))).
-		All(ctx)
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT page_id FROM yjs_updates`)
 	if err != nil {
 		s.logger.Error("Failed to fetch pages for snapshot",
 			zap.Error(err),
 		)
 		return
 	}
+	var pageIDs []int64
+	for rows.Next() {
+		var pageID int64
+		if err := rows.Scan(&pageID); err != nil {
+			rows.Close()
+			s.logger.Error("Failed to scan page for snapshot", zap.Error(err))
+			return
+		}
+		pageIDs = append(pageIDs, pageID)
+	}
+	rows.Close()
 
-	if len(pages) == 0 {
+	if len(pageIDs) == 0 {
 		s.logger.Debug("No pages need snapshots")
 		return
 	}
 
 	s.logger.Info("Generating snapshots",
-		zap.Int("page_count", len(pages)),
+		zap.Int("page_count", len(pageIDs)),
 	)
 
 	successCount := 0
 	failCount := 0
 
-	for _, page := range pages {
-		if err := s.generatePageSnapshot(ctx, page); err != nil {
+	for _, pageID := range pageIDs {
+		if err := s.compactPageUpdates(ctx, pageID); err != nil {
 			s.logger.Error("Failed to generate snapshot",
-				zap.Int64("page_id", page.ID),
-				zap.String("page_title", page.Title),
+				zap.Int64("page_id", pageID),
 				zap.Error(err),
 			)
 			failCount++
@@ -83,12 +96,18 @@ func (s *Server) generateSnapshots(parentCtx context.Context) {
 	)
 }
 
-// generatePageSnapshot generates a snapshot for a single wiki page
-func (s *Server) generatePageSnapshot(ctx context.Context, page *ent.WikiPage) error {
-	// Fetch all Yjs updates for the page
+// compactPageUpdates merges a page's latest snapshot and its logged updates
+// into a new snapshot, then deletes the updates that were merged. Updates
+// persisted while compaction runs have higher IDs and stay in the log.
+//
+// Compaction bounds the number of updates a client loads, not the size of
+// the snapshot: yjs.MergeUpdates keeps deleted content, so snapshots of
+// heavily edited pages keep growing. Shrinking them needs a garbage-collected
+// encode by a Yjs document, which the native merge doesn't do.
+func (s *Server) compactPageUpdates(ctx context.Context, pageID int64) error {
 	updates, err := s.db.Client.YjsUpdate.Query().
-		Where(yjsupdate.PageID(page.ID)).
-		Order(ent.Asc(yjsupdate.FieldCreatedAt)).
+		Where(yjsupdate.PageID(pageID)).
+		Order(ent.Asc(yjsupdate.FieldID)).
 		All(ctx)
 	if err != nil {
 		return err
@@ -96,62 +115,85 @@ func (s *Server) generatePageSnapshot(ctx context.Context, page *ent.WikiPage) e
 
 	if len(updates) == 0 {
 		s.logger.Debug("No updates to snapshot",
-			zap.Int64("page_id", page.ID),
+			zap.Int64("page_id", pageID),
 		)
 		return nil
 	}
 
-	// Convert updates to base64 strings
-	updateStrings := make([]string, len(updates))
-	for i, update := range updates {
-		updateStrings[i] = base64.StdEncoding.EncodeToString(update.UpdateData)
+	lastVersion, err := s.db.Client.PageVersion.Query().
+		Where(pageversion.PageID(pageID)).
+		Order(ent.Desc(pageversion.FieldVersionNumber)).
+		First(ctx)
+	if err != nil && !ent.IsNotFound(err) {
+		return err
 	}
 
-	// Call Yjs processor to apply all updates
-	state, err := s.yjsClient.ApplyUpdates(ctx, updateStrings)
+	parts := make([][]byte, 0, len(updates)+1)
+	versionNumber := 1
+	if lastVersion != nil {
+		parts = append(parts, lastVersion.YjsState)
+		versionNumber = lastVersion.VersionNumber + 1
+	}
+	maxID := updates[0].ID
+	for _, update := range updates {
+		parts = append(parts, update.UpdateData)
+		if update.ID > maxID {
+			maxID = update.ID
+		}
+	}
+
+	state, err := yjs.MergeUpdates(parts)
 	if err != nil {
-		return err
+		return fmt.Errorf("merge updates: %w", err)
 	}
 
-	// Decode the state back to binary
-	stateBytes, err := base64.StdEncoding.DecodeString(state)
+	tx, err := s.db.Client.Tx(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	// Updates that only repeat the snapshot are pruned without a new version
+	if lastVersion == nil || !bytes.Equal(lastVersion.YjsState, state) {
+		if _, err := tx.PageVersion.Create().
+			SetPageID(pageID).
+			SetVersionNumber(versionNumber).
+			SetYjsState(state).
+			Save(ctx); err != nil {
+			return err
+		}
 
-	// Get the current version number
-	lastVersion, err := s.db.Client.PageVersion.Query().
-		Where(pageversion.PageID(page.ID)).
-		Order(ent.Desc(pageversion.FieldVersionNumber)).
-		First(ctx)
-
-	versionNumber := 1
-	if err == nil {
-		// Check if the state has changed
-		if string(lastVersion.YjsState) == string(stateBytes) {
-			s.logger.Debug("State unchanged, skipping snapshot",
-				zap.Int64("page_id", page.ID),
-			)
-			return nil
+		if _, err := tx.PageVersion.Delete().
+			Where(
+				pageversion.PageID(pageID),
+				pageversion.VersionNumberLTE(versionNumber-snapshotsToKeep),
+			).
+			Exec(ctx); err != nil {
+			return err
 		}
-		versionNumber = lastVersion.VersionNumber + 1
+	} else {
+		versionNumber = lastVersion.VersionNumber
 	}
 
-	// Create new snapshot
-	_, err = s.db.Client.PageVersion.Create().
-		SetPageID(page.ID).
-		SetVersionNumber(versionNumber).
-		SetYjsState(stateBytes).
-		Save(ctx)
+	pruned, err := tx.YjsUpdate.Delete().
+		Where(
+			yjsupdate.PageID(pageID),
+			yjsupdate.IDLTE(maxID),
+		).
+		Exec(ctx)
 	if err != nil {
 		return err
 	}
 
-	s.logger.Info("Generated page snapshot",
-		zap.Int64("page_id", page.ID),
-		zap.String("page_title", page.Title),
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Compacted page updates",
+		zap.Int64("page_id", pageID),
 		zap.Int("version", versionNumber),
-		zap.Int("update_count", len(updates)),
+		zap.Int("update_count", pruned),
+		zap.Int("state_size", len(state)),
 	)
 
 	return nil
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"taskai/internal/collab"
+)
+
+// Hand-encoded v1 Yjs updates from one client typing "ab", then "c", then "d"
+// into the root text "t".
+var (
+	testYjsUpdateAB = []byte{1, 1, 1, 0, 4, 1, 1, 't', 2, 'a', 'b', 0}
+	testYjsUpdateC  = []byte{1, 1, 1, 2, 0x84, 1, 1, 1, 'c', 0}
+	testYjsUpdateD  = []byte{1, 1, 1, 3, 0x84, 1, 2, 1, 'd', 0}
+	testYjsStateABC = []byte{1, 2, 1, 0, 4, 1, 1, 't', 2, 'a', 'b', 0x84, 1, 1, 1, 'c', 0}
+)
+
+func TestCompactPageUpdates(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ctx := context.Background()
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+	pageID := ts.createTestWikiPage(t, projectID, userID, "Compacted Page")
+
+	logUpdate := func(update []byte) {
+		t.Helper()
+		if _, err := ts.DB.Exec(`INSERT INTO yjs_updates (page_id, update_data, created_by) VALUES (?, ?, ?)`,
+			pageID, update, userID); err != nil {
+			t.Fatalf("Failed to log update: %v", err)
+		}
+	}
+	countUpdates := func() int {
+		t.Helper()
+		var n int
+		if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM yjs_updates WHERE page_id = ?`, pageID).Scan(&n); err != nil {
+			t.Fatalf("Failed to count updates: %v", err)
+		}
+		return n
+	}
+	latestState := func() (int, []byte) {
+		t.Helper()
+		var version int
+		var state []byte
+		if err := ts.DB.QueryRow(`SELECT version_number, yjs_state FROM page_versions WHERE page_id = ?
+			ORDER BY version_number DESC LIMIT 1`, pageID).Scan(&version, &state); err != nil {
+			t.Fatalf("Failed to load snapshot: %v", err)
+		}
+		return version, state
+	}
+
+	logUpdate(testYjsUpdateAB)
+	logUpdate(testYjsUpdateC)
+	if err := ts.compactPageUpdates(ctx, pageID); err != nil {
+		t.Fatalf("Compaction failed: %v", err)
+	}
+
+	version, state := latestState()
+	if version != 1 || !bytes.Equal(state, testYjsStateABC) {
+		t.Fatalf("Expected version 1 with merged state, got version %d state %v", version, state)
+	}
+	if n := countUpdates(); n != 0 {
+		t.Errorf("Expected merged updates to be pruned, %d remain", n)
+	}
+
+	t.Run("sync sends snapshot then tail", func(t *testing.T) {
+		logUpdate(testYjsUpdateD)
+
+		client := newTestCollabClient(userID, projectID, pageID, "owner")
+		ts.handleSyncRequest(ctx, client, json.RawMessage(`{"page_id":0}`))
+
+		var msg collab.Message
+		var payload SyncResponsePayload
+		if err := json.Unmarshal(<-client.Send, &msg); err != nil || msg.Type != "sync_response" {
+			t.Fatalf("Expected sync_response, got %+v (%v)", msg, err)
+		}
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			t.Fatalf("Failed to decode sync payload: %v", err)
+		}
+		want := []string{
+			base64.StdEncoding.EncodeToString(testYjsStateABC),
+			base64.StdEncoding.EncodeToString(testYjsUpdateD),
+		}
+		if len(payload.Updates) != len(want) || payload.Updates[0] != want[0] || payload.Updates[1] != want[1] {
+			t.Errorf("Expected snapshot followed by tail, got %v", payload.Updates)
+		}
+	})
+
+	t.Run("duplicate updates do not create a version", func(t *testing.T) {
+		if err := ts.compactPageUpdates(ctx, pageID); err != nil {
+			t.Fatalf("Compaction failed: %v", err)
+		}
+		if version, _ := latestState(); version != 2 {
+			t.Fatalf("Expected version 2, got %d", version)
+		}
+
+		logUpdate(testYjsUpdateC)
+		if err := ts.compactPageUpdates(ctx, pageID); err != nil {
+			t.Fatalf("Compaction failed: %v", err)
+		}
+		if version, _ := latestState(); version != 2 {
+			t.Errorf("Expected no new version for a replayed update, got %d", version)
+		}
+		if n := countUpdates(); n != 0 {
+			t.Errorf("Expected replayed update to be pruned, %d remain", n)
+		}
+	})
+}
@@ -0,0 +1,514 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"taskai/ent"
+	"taskai/ent/wikipage"
+)
+
+// WikiBreadcrumb is one ancestor in a wiki page's breadcrumb trail
+type WikiBreadcrumb struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// WikiTreeNode is a wiki page with its ordered children, used for the sidebar tree
+type WikiTreeNode struct {
+	ID       int64           `json:"id"`
+	ParentID *int64          `json:"parent_id"`
+	Title    string          `json:"title"`
+	Slug     string          `json:"slug"`
+	Path     string          `json:"path"`
+	Position int             `json:"position"`
+	Children []*WikiTreeNode `json:"children"`
+}
+
+// MoveWikiPageRequest moves a page under a new parent (null for top level)
+// at the given position among its siblings (appended when omitted).
+type MoveWikiPageRequest struct {
+	ParentID *int64 `json:"parent_id"`
+	Position *int   `json:"position,omitempty"`
+}
+
+// wikiPageNode is the hierarchy-relevant part of a wiki page row.
+type wikiPageNode struct {
+	id       int64
+	parentID *int64
+	position int
+	title    string
+	slug     string
+}
+
+// wikiPageIndex holds every page of one project keyed by ID.
+type wikiPageIndex map[int64]*wikiPageNode
+
+// loadWikiPageIndex loads the page hierarchy of a project.
+func (s *Server) loadWikiPageIndex(ctx context.Context, projectID int64) (wikiPageIndex, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(
+		`SELECT id, parent_id, position, title, slug FROM wiki_pages WHERE project_id = ?`), projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idx := wikiPageIndex{}
+	for rows.Next() {
+		var n wikiPageNode
+		var parentID sql.NullInt64
+		if err := rows.Scan(&n.id, &parentID, &n.position, &n.title, &n.slug); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			n.parentID = &parentID.Int64
+		}
+		idx[n.id] = &n
+	}
+	return idx, rows.Err()
+}
+
+// ancestors returns the page's ancestors, root first. Dangling parents and
+// cycles end the walk rather than looping.
+func (idx wikiPageIndex) ancestors(pageID int64) []*wikiPageNode {
+	n, ok := idx[pageID]
+	if !ok {
+		return nil
+	}
+	var chain []*wikiPageNode
+	seen := map[int64]bool{pageID: true}
+	for n.parentID != nil && !seen[*n.parentID] {
+		parent, ok := idx[*n.parentID]
+		if !ok {
+			break
+		}
+		seen[parent.id] = true
+		chain = append(chain, parent)
+		n = parent
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// breadcrumbs returns the ancestor trail of a page, root first.
+func (idx wikiPageIndex) breadcrumbs(pageID int64) []WikiBreadcrumb {
+	chain := idx.ancestors(pageID)
+	crumbs := make([]WikiBreadcrumb, 0, len(chain))
+	for _, a := range chain {
+		crumbs = append(crumbs, WikiBreadcrumb{ID: a.id, Title: a.title, Slug: a.slug})
+	}
+	return crumbs
+}
+
+// path returns the nested slug path of a page, e.g. "engineering/runbooks/db".
+func (idx wikiPageIndex) path(pageID int64) string {
+	n, ok := idx[pageID]
+	if !ok {
+		return ""
+	}
+	parts := []string{}
+	for _, a := range idx.ancestors(pageID) {
+		parts = append(parts, a.slug)
+	}
+	return strings.Join(append(parts, n.slug), "/")
+}
+
+// isDescendant reports whether pageID sits somewhere below ancestorID.
+func (idx wikiPageIndex) isDescendant(pageID, ancestorID int64) bool {
+	for _, a := range idx.ancestors(pageID) {
+		if a.id == ancestorID {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns the ordered child pages of parentID (nil for top level).
+func (idx wikiPageIndex) children(parentID *int64) []*wikiPageNode {
+	var out []*wikiPageNode
+	for _, n := range idx {
+		if (parentID == nil && n.parentID == nil) ||
+			(parentID != nil && n.parentID != nil && *n.parentID == *parentID) {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].position != out[j].position {
+			return out[i].position < out[j].position
+		}
+		return out[i].title < out[j].title
+	})
+	return out
+}
+
+// applyWikiHierarchy fills the hierarchy fields of a page response.
+func (idx wikiPageIndex) applyWikiHierarchy(resp *WikiPageResponse) {
+	n, ok := idx[resp.ID]
+	if !ok {
+		return
+	}
+	resp.ParentID = n.parentID
+	resp.Position = n.position
+	resp.Path = idx.path(resp.ID)
+	resp.Breadcrumbs = idx.breadcrumbs(resp.ID)
+}
+
+// writeWikiSiblings renumbers a sibling list 0..n-1 under parentID.
+func writeWikiSiblings(ctx context.Context, tx *sql.Tx, rebind func(string) string, parentID *int64, siblings []*wikiPageNode) error {
+	for i, n := range siblings {
+		if _, err := tx.ExecContext(ctx, rebind(
+			`UPDATE wiki_pages SET parent_id = ?, position = ? WHERE id = ?`), parentID, i, n.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutWikiPage returns siblings minus pageID.
+func withoutWikiPage(siblings []*wikiPageNode, pageID int64) []*wikiPageNode {
+	out := make([]*wikiPageNode, 0, len(siblings))
+	for _, n := range siblings {
+		if n.id != pageID {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// HandleGetWikiTree returns the project's wiki pages as an ordered tree
+// GET /api/projects/{projectId}/wiki/tree
+func (s *Server) HandleGetWikiTree(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to load wiki tree",
+			zap.Int64("project_id", projectID),
+			zap.Error(err),
+		)
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki tree", "internal_error")
+		return
+	}
+
+	var build func(parentID *int64, prefix string, seen map[int64]bool) []*WikiTreeNode
+	build = func(parentID *int64, prefix string, seen map[int64]bool) []*WikiTreeNode {
+		nodes := []*WikiTreeNode{}
+		for _, n := range idx.children(parentID) {
+			if seen[n.id] {
+				continue
+			}
+			seen[n.id] = true
+			id := n.id
+			node := &WikiTreeNode{
+				ID:       n.id,
+				ParentID: n.parentID,
+				Title:    n.title,
+				Slug:     n.slug,
+				Path:     prefix + n.slug,
+				Position: n.position,
+			}
+			node.Children = build(&id, node.Path+"/", seen)
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+	respondJSON(w, http.StatusOK, build(nil, "", map[int64]bool{}))
+}
+
+// HandleGetWikiPageByPath resolves a nested slug path to a page
+// GET /api/projects/{projectId}/wiki/by-path?path=engineering/runbooks/db
+func (s *Server) HandleGetWikiPageByPath(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid project ID", "invalid_input")
+		return
+	}
+	segments := strings.Split(strings.Trim(r.URL.Query().Get("path"), "/"), "/")
+	if len(segments) == 0 || segments[0] == "" {
+		respondError(w, http.StatusBadRequest, "path is required", "invalid_input")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermProjectView)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki pages", "internal_error")
+		return
+	}
+
+	// Walk the path one level at a time so every segment must match its parent
+	var parentID *int64
+	var found *wikiPageNode
+	for _, seg := range segments {
+		found = nil
+		for _, n := range idx.children(parentID) {
+			if n.slug == seg {
+				found = n
+				break
+			}
+		}
+		if found == nil {
+			respondError(w, http.StatusNotFound, "wiki page not found", "not_found")
+			return
+		}
+		id := found.id
+		parentID = &id
+	}
+
+	page, err := s.db.Client.WikiPage.Query().
+		Where(wikipage.ID(found.id)).
+		WithCreator().
+		WithUpdater().
+		Only(ctx)
+	if err != nil {
+		if ent.IsNotFound(err) {
+			respondError(w, http.StatusNotFound, "wiki page not found", "not_found")
+			return
+		}
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki page", "internal_error")
+		return
+	}
+
+	response := WikiPageResponse{
+		ID:        page.ID,
+		ProjectID: page.ProjectID,
+		Title:     page.Title,
+		Slug:      page.Slug,
+		CreatedBy: page.CreatedBy,
+		UpdatedBy: page.UpdatedBy,
+		Content:   &page.Content,
+		CreatedAt: page.CreatedAt,
+		UpdatedAt: page.UpdatedAt,
+	}
+	if page.Edges.Creator != nil && page.Edges.Creator.Name != nil {
+		response.CreatorName = page.Edges.Creator.Name
+	}
+	if page.Edges.Updater != nil && page.Edges.Updater.Name != nil {
+		response.UpdaterName = page.Edges.Updater.Name
+	}
+	idx.applyWikiHierarchy(&response)
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// HandleMoveWikiPage re-parents and/or reorders a wiki page
+// POST /api/wiki/pages/{pageId}/move
+func (s *Server) HandleMoveWikiPage(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	userID := r.Context().Value(UserIDKey).(int64)
+	pageID, err := strconv.ParseInt(chi.URLParam(r, "pageId"), 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "invalid page ID", "invalid_input")
+		return
+	}
+
+	var projectID int64
+	err = s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT project_id FROM wiki_pages WHERE id = ?`), pageID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "wiki page not found", "not_found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki page", "internal_error")
+		return
+	}
+
+	hasAccess, err := s.authorizeProject(ctx, userID, projectID, PermWikiEdit)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to verify project access", "internal_error")
+		return
+	}
+	if !hasAccess {
+		respondError(w, http.StatusForbidden, "access denied", "forbidden")
+		return
+	}
+
+	var req MoveWikiPageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, errInvalidRequestBody, "invalid_input")
+		return
+	}
+
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to fetch wiki pages", "internal_error")
+		return
+	}
+	page := idx[pageID]
+
+	if req.ParentID != nil {
+		if _, ok := idx[*req.ParentID]; !ok {
+			respondError(w, http.StatusBadRequest, "parent page not found in this project", "invalid_input")
+			return
+		}
+		if *req.ParentID == pageID || idx.isDescendant(*req.ParentID, pageID) {
+			respondError(w, http.StatusBadRequest, "a page cannot be moved under itself or its descendants", "invalid_input")
+			return
+		}
+	}
+
+	oldSiblings := withoutWikiPage(idx.children(page.parentID), pageID)
+	newSiblings := withoutWikiPage(idx.children(req.ParentID), pageID)
+	position := len(newSiblings)
+	if req.Position != nil {
+		if *req.Position < 0 {
+			respondError(w, http.StatusBadRequest, "position must be non-negative", "invalid_input")
+			return
+		}
+		if *req.Position < position {
+			position = *req.Position
+		}
+	}
+	newSiblings = append(newSiblings[:position], append([]*wikiPageNode{page}, newSiblings[position:]...)...)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to move wiki page", "internal_error")
+		return
+	}
+	defer tx.Rollback()
+
+	if err := writeWikiSiblings(ctx, tx, s.db.Rebind, page.parentID, oldSiblings); err != nil {
+		s.logger.Error("Failed to reorder wiki pages", zap.Int64("page_id", pageID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to move wiki page", "internal_error")
+		return
+	}
+	if err := writeWikiSiblings(ctx, tx, s.db.Rebind, req.ParentID, newSiblings); err != nil {
+		s.logger.Error("Failed to reorder wiki pages", zap.Int64("page_id", pageID), zap.Error(err))
+		respondError(w, http.StatusInternalServerError, "failed to move wiki page", "internal_error")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondError(w, http.StatusInternalServerError, "failed to move wiki page", "internal_error")
+		return
+	}
+
+	// Reflect the new placement in the in-memory index for the response
+	for i, n := range oldSiblings {
+		n.position = i
+	}
+	for i, n := range newSiblings {
+		n.parentID = req.ParentID
+		n.position = i
+	}
+
+	response := WikiTreeNode{
+		ID:       page.id,
+		ParentID: page.parentID,
+		Title:    page.title,
+		Slug:     page.slug,
+		Path:     idx.path(page.id),
+		Position: page.position,
+		Children: []*WikiTreeNode{},
+	}
+	respondJSON(w, http.StatusOK, response)
+	go s.emitWebhookEvent(projectID, "wiki_page.updated", response)
+}
+
+// deleteWikiPageTree deletes a page, then either deletes its descendants
+// (cascade) or splices its children into its place under its own parent.
+// Returns the IDs of all deleted pages.
+func (s *Server) deleteWikiPageTree(ctx context.Context, projectID, pageID int64, cascade bool) ([]int64, error) {
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	page, ok := idx[pageID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := []int64{pageID}
+	if cascade {
+		for id := range idx {
+			if idx.isDescendant(id, pageID) {
+				deleted = append(deleted, id)
+			}
+		}
+	} else {
+		var siblings []*wikiPageNode
+		for _, n := range idx.children(page.parentID) {
+			if n.id == pageID {
+				siblings = append(siblings, idx.children(&page.id)...)
+				continue
+			}
+			siblings = append(siblings, n)
+		}
+		if err := writeWikiSiblings(ctx, tx, s.db.Rebind, page.parentID, siblings); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range deleted {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM wiki_pages WHERE id = ?`), id); err != nil {
+			return nil, err
+		}
+	}
+	return deleted, tx.Commit()
+}
+
+// cachedWikiPageIndex loads a project's page index once per request. A failed
+// load yields an empty index, so callers simply omit breadcrumbs.
+func (s *Server) cachedWikiPageIndex(ctx context.Context, cache map[int64]wikiPageIndex, projectID int64) wikiPageIndex {
+	if idx, ok := cache[projectID]; ok {
+		return idx
+	}
+	idx, err := s.loadWikiPageIndex(ctx, projectID)
+	if err != nil {
+		s.logger.Warn("Failed to load wiki page index", zap.Int64("project_id", projectID), zap.Error(err))
+		idx = wikiPageIndex{}
+	}
+	cache[projectID] = idx
+	return idx
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// createTestWikiChild creates a page under parentID through the create handler.
+func (ts *TestServer) createTestWikiChild(t *testing.T, projectID, userID int64, title string, parentID *int64) WikiPageResponse {
+	t.Helper()
+
+	body := map[string]interface{}{"title": title}
+	if parentID != nil {
+		body["parent_id"] = *parentID
+	}
+	rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+		fmt.Sprintf("/api/projects/%d/wiki/pages", projectID), body, userID,
+		map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+	ts.HandleCreateWikiPage(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusCreated)
+
+	var page WikiPageResponse
+	DecodeJSON(t, rec, &page)
+	return page
+}
+
+func (ts *TestServer) getTestWikiTree(t *testing.T, projectID, userID int64) []*WikiTreeNode {
+	t.Helper()
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodGet,
+		fmt.Sprintf("/api/projects/%d/wiki/tree", projectID), nil, userID,
+		map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+	ts.HandleGetWikiTree(rec, req)
+	AssertStatusCode(t, rec.Code, http.StatusOK)
+
+	var tree []*WikiTreeNode
+	DecodeJSON(t, rec, &tree)
+	return tree
+}
+
+func TestWikiPageHierarchy(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+
+	eng := ts.createTestWikiChild(t, projectID, userID, "Engineering", nil)
+	runbooks := ts.createTestWikiChild(t, projectID, userID, "Runbooks", &eng.ID)
+	db := ts.createTestWikiChild(t, projectID, userID, "DB", &runbooks.ID)
+	onboarding := ts.createTestWikiChild(t, projectID, userID, "Onboarding", &eng.ID)
+
+	if db.Path != "engineering/runbooks/db" {
+		t.Errorf("Expected nested path, got %q", db.Path)
+	}
+	if len(db.Breadcrumbs) != 2 || db.Breadcrumbs[0].ID != eng.ID || db.Breadcrumbs[1].ID != runbooks.ID {
+		t.Errorf("Unexpected breadcrumbs: %+v", db.Breadcrumbs)
+	}
+	if onboarding.Position != 1 {
+		t.Errorf("Expected second child at position 1, got %d", onboarding.Position)
+	}
+
+	t.Run("tree is nested and ordered", func(t *testing.T) {
+		tree := ts.getTestWikiTree(t, projectID, userID)
+		if len(tree) != 1 || tree[0].ID != eng.ID {
+			t.Fatalf("Expected single root 'Engineering', got %+v", tree)
+		}
+		children := tree[0].Children
+		if len(children) != 2 || children[0].ID != runbooks.ID || children[1].ID != onboarding.ID {
+			t.Fatalf("Unexpected children order: %+v", children)
+		}
+		if len(children[0].Children) != 1 || children[0].Children[0].Path != "engineering/runbooks/db" {
+			t.Errorf("Expected DB under Runbooks, got %+v", children[0].Children)
+		}
+	})
+
+	t.Run("resolve by path", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodGet,
+			fmt.Sprintf("/api/projects/%d/wiki/by-path?path=engineering/runbooks/db", projectID), nil, userID,
+			map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+		ts.HandleGetWikiPageByPath(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusOK)
+
+		var page WikiPageResponse
+		DecodeJSON(t, rec, &page)
+		if page.ID != db.ID {
+			t.Errorf("Expected page %d, got %d", db.ID, page.ID)
+		}
+
+		// A slug that exists elsewhere in the tree does not match the wrong parent
+		rec, req = ts.MakeAuthRequest(t, http.MethodGet,
+			fmt.Sprintf("/api/projects/%d/wiki/by-path?path=engineering/db", projectID), nil, userID,
+			map[string]string{"projectId": fmt.Sprintf("%d", projectID)})
+		ts.HandleGetWikiPageByPath(rec, req)
+		AssertError(t, rec, http.StatusNotFound, "", "not_found")
+	})
+
+	move := func(pageID int64, body map[string]interface{}) *WikiTreeNode {
+		t.Helper()
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+			fmt.Sprintf("/api/wiki/pages/%d/move", pageID), body, userID,
+			map[string]string{"pageId": fmt.Sprintf("%d", pageID)})
+		ts.HandleMoveWikiPage(rec, req)
+		if rec.Code != http.StatusOK {
+			return nil
+		}
+		var node WikiTreeNode
+		DecodeJSON(t, rec, &node)
+		return &node
+	}
+
+	t.Run("move rejects cycles", func(t *testing.T) {
+		rec, req := ts.MakeAuthRequest(t, http.MethodPost,
+			fmt.Sprintf("/api/wiki/pages/%d/move", eng.ID), map[string]interface{}{"parent_id": db.ID}, userID,
+			map[string]string{"pageId": fmt.Sprintf("%d", eng.ID)})
+		ts.HandleMoveWikiPage(rec, req)
+		AssertError(t, rec, http.StatusBadRequest, "under itself", "invalid_input")
+	})
+
+	t.Run("reorder and re-parent", func(t *testing.T) {
+		if node := move(onboarding.ID, map[string]interface{}{"parent_id": eng.ID, "position": 0}); node == nil || node.Position != 0 {
+			t.Fatalf("Expected Onboarding moved to position 0, got %+v", node)
+		}
+		node := move(db.ID, map[string]interface{}{"parent_id": nil})
+		if node == nil || node.Path != "db" || node.ParentID != nil {
+			t.Fatalf("Expected DB at top level, got %+v", node)
+		}
+
+		tree := ts.getTestWikiTree(t, projectID, userID)
+		if len(tree) != 2 || tree[0].ID != eng.ID || tree[1].ID != db.ID {
+			t.Fatalf("Unexpected roots: %+v", tree)
+		}
+		if tree[0].Children[0].ID != onboarding.ID || len(tree[0].Children[1].Children) != 0 {
+			t.Errorf("Unexpected children after move: %+v", tree[0].Children)
+		}
+	})
+}
+
+func TestDeleteWikiPageWithChildren(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	userID := ts.CreateTestUser(t, "test@example.com", "password123")
+	projectID := ts.CreateTestProject(t, userID, "Test Project")
+
+	first := ts.createTestWikiChild(t, projectID, userID, "First", nil)
+	parent := ts.createTestWikiChild(t, projectID, userID, "Parent", nil)
+	childA := ts.createTestWikiChild(t, projectID, userID, "Child A", &parent.ID)
+	childB := ts.createTestWikiChild(t, projectID, userID, "Child B", &parent.ID)
+	grandchild := ts.createTestWikiChild(t, projectID, userID, "Grandchild", &childB.ID)
+	last := ts.createTestWikiChild(t, projectID, userID, "Last", nil)
+
+	del := func(pageID int64, query string) {
+		t.Helper()
+		rec, req := ts.MakeAuthRequest(t, http.MethodDelete,
+			fmt.Sprintf("/api/wiki/pages/%d%s", pageID, query), nil, userID,
+			map[string]string{"pageId": fmt.Sprintf("%d", pageID)})
+		ts.HandleDeleteWikiPage(rec, req)
+		AssertStatusCode(t, rec.Code, http.StatusNoContent)
+	}
+
+	// Default: children take the deleted page's place under its parent
+	del(parent.ID, "")
+	tree := ts.getTestWikiTree(t, projectID, userID)
+	want := []int64{first.ID, childA.ID, childB.ID, last.ID}
+	if len(tree) != len(want) {
+		t.Fatalf("Expected %d roots after re-parenting, got %d", len(want), len(tree))
+	}
+	for i, id := range want {
+		if tree[i].ID != id {
+			t.Errorf("Root %d: expected page %d, got %d", i, id, tree[i].ID)
+		}
+	}
+
+	// Cascade removes the whole subtree
+	del(childB.ID, "?children=cascade")
+	var count int
+	if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM wiki_pages WHERE id IN (?, ?)`, childB.ID, grandchild.ID).Scan(&count); err != nil {
+		t.Fatalf("Failed to count pages: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected subtree to be deleted, %d pages remain", count)
+	}
+
+	rec, req := ts.MakeAuthRequest(t, http.MethodDelete,
+		fmt.Sprintf("/api/wiki/pages/%d?children=orphan", first.ID), nil, userID,
+		map[string]string{"pageId": fmt.Sprintf("%d", first.ID)})
+	ts.HandleDeleteWikiPage(rec, req)
+	AssertError(t, rec, http.StatusBadRequest, "", "invalid_input")
+}
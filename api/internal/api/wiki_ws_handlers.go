@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"go.uber.org/zap"
 
 	"taskai/ent"
+	"taskai/ent/pageversion"
 	"taskai/ent/wikipage"
 	"taskai/ent/yjsupdate"
 	"taskai/internal/collab"
@@ -38,7 +40,8 @@ type SyncRequestPayload struct {
 	PageID int64 `json:"page_id"`
 }
 
-// SyncResponsePayload represents a sync response with all updates
+// SyncResponsePayload represents a sync response: the latest snapshot (if any)
+// followed by the updates logged after it
 type SyncResponsePayload struct {
 	Updates []string `json:"updates"` // Array of base64-encoded Yjs updates
 }
@@ -49,6 +52,51 @@ type ErrorPayload struct {
 	Code    string `json:"code"`
 }
 
+// CollabSessionPayload tells a client its role in the page's project. Sent on
+// connect and again whenever the user's membership changes mid-session.
+type CollabSessionPayload struct {
+	Role     string `json:"role"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// wikiRoleCanEdit reports whether a project role may persist document updates.
+// Viewers get sync and awareness only.
+func wikiRoleCanEdit(role string) bool {
+	return role != "" && role != "viewer"
+}
+
+// projectMemberRole returns the user's role in a project, or "" if they are not a member.
+func (s *Server) projectMemberRole(ctx context.Context, userID, projectID int64) (string, error) {
+	if !apiKeyAllowsProject(ctx, projectID) {
+		return "", nil
+	}
+	var role string
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(
+		`SELECT role FROM project_members WHERE project_id = ? AND user_id = ?`), projectID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// wikiCollabRole returns the role a user collaborates on a project's pages
+// with: their project role, or viewer when it doesn't allow editing the wiki.
+// It is "" for non-members.
+func (s *Server) wikiCollabRole(ctx context.Context, userID, projectID int64) (string, error) {
+	role, err := s.projectMemberRole(ctx, userID, projectID)
+	if err != nil || role == "" {
+		return role, err
+	}
+	canEdit, err := s.authorizeProject(ctx, userID, projectID, PermWikiEdit)
+	if err != nil {
+		return "", err
+	}
+	if !canEdit {
+		return RoleViewer, nil
+	}
+	return role, nil
+}
+
 // HandleWikiWebSocket handles WebSocket connections for wiki collaboration
 func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -64,7 +112,7 @@ func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Validate JWT token from query param
-		claims, err := s.auth.ValidateToken(token)
+		claims, err := s.authenticateToken(ctx, token)
 		if err != nil {
 			s.logger.Warn("Invalid WebSocket token",
 				zap.Error(err),
@@ -102,8 +150,8 @@ func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user has access to the project
-	hasAccess, err := s.checkProjectAccess(ctx, userID, page.ProjectID)
+	// Check if user has access to the project; the role decides whether they may edit
+	role, err := s.wikiCollabRole(ctx, userID, page.ProjectID)
 	if err != nil {
 		s.logger.Error("Failed to check project access",
 			zap.Int64("user_id", userID),
@@ -113,7 +161,7 @@ func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to verify access", http.StatusInternalServerError)
 		return
 	}
-	if !hasAccess {
+	if role == "" {
 		http.Error(w, "access denied", http.StatusForbidden)
 		return
 	}
@@ -129,12 +177,19 @@ func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// Create client
 	client := &collab.Client{
-		ID:      uuid.New().String(),
-		UserID:  userID,
-		PageID:  pageID,
-		Conn:    conn,
-		Send:    make(chan []byte, 256),
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		PageID:    pageID,
+		ProjectID: page.ProjectID,
+		Conn:      conn,
+		Send:      make(chan []byte, 256),
 	}
+	// API keys without wiki:write join read-only
+	if key := apiKeyFromContext(ctx); key != nil && !apiKeyHasScope(key.Scopes, ScopeWikiWrite) {
+		client.ViewOnly = true
+		role = "viewer"
+	}
+	client.SetRole(role)
 
 	// Store reference to manager and room ID in client
 	// We need to modify the collab.Client struct to store these
@@ -142,11 +197,13 @@ func (s *Server) HandleWikiWebSocket(w http.ResponseWriter, r *http.Request) {
 
 	// Register client with the collaboration manager
 	s.registerWebSocketClient(client, roomID)
+	s.sendCollabSession(client)
 
 	s.logger.Info("WebSocket connection established",
 		zap.String("client_id", client.ID),
 		zap.Int64("user_id", userID),
 		zap.Int64("page_id", pageID),
+		zap.String("role", role),
 	)
 }
 
@@ -191,6 +248,10 @@ func (s *Server) handleWikiMessage(client *collab.Client, message []byte, roomID
 		s.handleSyncRequest(ctx, client, msg.Payload)
 
 	case "update":
+		if !wikiRoleCanEdit(client.Role()) {
+			s.sendError(client, "Your role does not allow editing this page", "read_only")
+			return
+		}
 		s.handleYjsUpdate(ctx, client, roomID, msg.Payload)
 
 	case "awareness":
@@ -207,7 +268,8 @@ func (s *Server) handleWikiMessage(client *collab.Client, message []byte, roomID
 	}
 }
 
-// handleSyncRequest sends all Yjs updates for a page to a client
+// handleSyncRequest sends a page's latest snapshot followed by the updates
+// logged since it was compacted
 func (s *Server) handleSyncRequest(ctx context.Context, client *collab.Client, payload json.RawMessage) {
 	var req SyncRequestPayload
 	if err := json.Unmarshal(payload, &req); err != nil {
@@ -215,10 +277,12 @@ func (s *Server) handleSyncRequest(ctx context.Context, client *collab.Client, p
 		return
 	}
 
-	// Fetch all Yjs updates for the page from database
+	// Read the tail before the snapshot: if compaction runs in between, the
+	// tail is already part of the newer snapshot and applying it twice is a
+	// no-op, whereas the other order could miss the pruned updates entirely.
 	yjsUpdates, err := s.db.Client.YjsUpdate.Query().
 		Where(yjsupdate.PageID(client.PageID)).
-		Order(ent.Asc(yjsupdate.FieldCreatedAt)).
+		Order(ent.Asc(yjsupdate.FieldID)).
 		All(ctx)
 	if err != nil {
 		s.logger.Error("Failed to fetch Yjs updates",
@@ -229,10 +293,26 @@ func (s *Server) handleSyncRequest(ctx context.Context, client *collab.Client, p
 		return
 	}
 
-	// Convert binary updates to base64 strings
-	updates := make([]string, len(yjsUpdates))
-	for i, update := range yjsUpdates {
-		updates[i] = base64.StdEncoding.EncodeToString(update.UpdateData)
+	snapshot, err := s.db.Client.PageVersion.Query().
+		Where(pageversion.PageID(client.PageID)).
+		Order(ent.Desc(pageversion.FieldVersionNumber)).
+		First(ctx)
+	if err != nil && !ent.IsNotFound(err) {
+		s.logger.Error("Failed to fetch page snapshot",
+			zap.Int64("page_id", client.PageID),
+			zap.Error(err),
+		)
+		s.sendError(client, "Failed to fetch document history", "internal_error")
+		return
+	}
+
+	// Convert binary updates to base64 strings, snapshot first
+	updates := make([]string, 0, len(yjsUpdates)+1)
+	if snapshot != nil {
+		updates = append(updates, base64.StdEncoding.EncodeToString(snapshot.YjsState))
+	}
+	for _, update := range yjsUpdates {
+		updates = append(updates, base64.StdEncoding.EncodeToString(update.UpdateData))
 	}
 
 	// Send sync response
@@ -301,6 +381,65 @@ func (s *Server) handleYjsUpdate(ctx context.Context, client *collab.Client, roo
 	)
 }
 
+// sendCollabSession sends the client its current role and edit permission
+func (s *Server) sendCollabSession(client *collab.Client) {
+	role := client.Role()
+	msg := collab.Message{
+		Type: "session",
+		Payload: json.RawMessage(mustMarshal(CollabSessionPayload{
+			Role:     role,
+			ReadOnly: !wikiRoleCanEdit(role),
+		})),
+	}
+
+	select {
+	case client.Send <- mustMarshal(msg):
+	default:
+		s.logger.Warn("Failed to send session message, buffer full",
+			zap.String("client_id", client.ID),
+		)
+	}
+}
+
+// syncWikiCollabMembership re-checks a user's project role after a membership
+// change and applies it to their open wiki sessions in that project: removed
+// members are disconnected, everyone else gets the new role pushed.
+func (s *Server) syncWikiCollabMembership(projectID, userID int64) {
+	if s.collabManager == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	role, err := s.wikiCollabRole(ctx, userID, projectID)
+	if err != nil {
+		s.logger.Warn("syncWikiCollabMembership: role lookup failed",
+			zap.Int64("project_id", projectID),
+			zap.Int64("user_id", userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, client := range s.collabManager.ClientsForUser(userID) {
+		if client.ProjectID != projectID {
+			continue
+		}
+		if role == "" {
+			s.collabManager.Disconnect(client, websocket.ClosePolicyViolation, "project membership revoked")
+			continue
+		}
+		clientRole := role
+		if client.ViewOnly {
+			clientRole = "viewer"
+		}
+		if client.Role() != clientRole {
+			client.SetRole(clientRole)
+			s.sendCollabSession(client)
+		}
+	}
+}
+
 // sendError sends an error message to a client
 func (s *Server) sendError(client *collab.Client, message, code string) {
 	errorMsg := collab.Message{
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/collab"
+)
+
+// newTestCollabClient returns an unconnected wiki client with the given role.
+func newTestCollabClient(userID, projectID, pageID int64, role string) *collab.Client {
+	client := &collab.Client{
+		ID:        fmt.Sprintf("test-%d", userID),
+		UserID:    userID,
+		PageID:    pageID,
+		ProjectID: projectID,
+		Send:      make(chan []byte, 8),
+	}
+	client.SetRole(role)
+	return client
+}
+
+func TestHandleWikiMessageRoleEnforcement(t *testing.T) {
+	ts := NewTestServer(t)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The manager's goroutine logs on shutdown, after the test has finished.
+	ts.SetCollabManager(collab.NewManager(ctx, zap.NewNop()))
+
+	ownerID := ts.CreateTestUser(t, "owner@example.com", "password123")
+	viewerID := ts.CreateTestUser(t, "viewer@example.com", "password123")
+	projectID := ts.CreateTestProject(t, ownerID, "Test Project")
+	ts.AddProjectMember(t, projectID, viewerID, ownerID, "viewer")
+	pageID := ts.createTestWikiPage(t, projectID, ownerID, "Collab Page")
+	roomID := fmt.Sprintf("page:%d", pageID)
+
+	update := mustMarshal(collab.Message{
+		Type:    "update",
+		Payload: json.RawMessage(mustMarshal(YjsUpdatePayload{Update: base64.StdEncoding.EncodeToString([]byte{1, 2, 3})})),
+	})
+	countUpdates := func() int {
+		var n int
+		if err := ts.DB.QueryRow(`SELECT COUNT(*) FROM yjs_updates WHERE page_id = ?`, pageID).Scan(&n); err != nil {
+			t.Fatalf("Failed to count updates: %v", err)
+		}
+		return n
+	}
+
+	t.Run("viewer update is rejected", func(t *testing.T) {
+		client := newTestCollabClient(viewerID, projectID, pageID, "viewer")
+		ts.handleWikiMessage(client, update, roomID)
+
+		if n := countUpdates(); n != 0 {
+			t.Fatalf("Expected no persisted updates, got %d", n)
+		}
+		select {
+		case raw := <-client.Send:
+			var msg collab.Message
+			var payload ErrorPayload
+			if err := json.Unmarshal(raw, &msg); err != nil || msg.Type != "error" {
+				t.Fatalf("Expected error message, got %s", raw)
+			}
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.Code != "read_only" {
+				t.Errorf("Expected read_only error, got %s", msg.Payload)
+			}
+		default:
+			t.Fatal("Expected an error message to be sent")
+		}
+	})
+
+	t.Run("promoted viewer can edit", func(t *testing.T) {
+		client := newTestCollabClient(viewerID, projectID, pageID, "viewer")
+		if _, err := ts.DB.Exec(`UPDATE project_members SET role = 'editor' WHERE project_id = ? AND user_id = ?`,
+			projectID, viewerID); err != nil {
+			t.Fatalf("Failed to promote member: %v", err)
+		}
+		role, err := ts.projectMemberRole(context.Background(), viewerID, projectID)
+		if err != nil || role != "editor" {
+			t.Fatalf("Expected editor role, got %q (%v)", role, err)
+		}
+		client.SetRole(role)
+
+		ts.handleWikiMessage(client, update, roomID)
+		if n := countUpdates(); n != 1 {
+			t.Errorf("Expected 1 persisted update, got %d", n)
+		}
+	})
+
+	t.Run("non-member has no role", func(t *testing.T) {
+		strangerID := ts.CreateTestUser(t, "stranger@example.com", "password123")
+		role, err := ts.projectMemberRole(context.Background(), strangerID, projectID)
+		if err != nil || role != "" {
+			t.Errorf("Expected empty role for non-member, got %q (%v)", role, err)
+		}
+		if wikiRoleCanEdit(role) {
+			t.Error("Expected non-member to be unable to edit")
+		}
+	})
+}
@@ -9,17 +9,25 @@ import (
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    int64  `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID int64  `json:"sid,omitempty"` // 0 for tokens issued without a session
 	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token for a user
 func GenerateToken(userID int64, email, secret string, expiry time.Duration) (string, error) {
+	return GenerateSessionToken(userID, email, 0, secret, expiry)
+}
+
+// GenerateSessionToken creates a JWT access token bound to a login session,
+// so revoking the session invalidates the token before it expires
+func GenerateSessionToken(userID int64, email string, sessionID int64, secret string, expiry time.Duration) (string, error) {
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(now),
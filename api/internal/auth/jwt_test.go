@@ -235,6 +235,47 @@ func TestTokenClaims(t *testing.T) {
 	}
 }
 
+func TestSessionTokenClaims(t *testing.T) {
+	secret := "test-secret"
+
+	token, err := GenerateSessionToken(42, "test@example.com", 7, secret, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, err := ValidateToken(token, secret)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.SessionID != 7 {
+		t.Errorf("SessionID mismatch: got %d, want 7", claims.SessionID)
+	}
+
+	// Tokens without a session carry no sid
+	token, _ = GenerateToken(42, "test@example.com", secret, time.Hour)
+	claims, _ = ValidateToken(token, secret)
+	if claims.SessionID != 0 {
+		t.Errorf("Expected no session ID, got %d", claims.SessionID)
+	}
+}
+
+func TestGenerateRefreshToken(t *testing.T) {
+	token, hash, err := GenerateRefreshToken()
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	if token == "" || hash == token {
+		t.Errorf("Expected a token and a distinct hash, got %q and %q", token, hash)
+	}
+	if HashRefreshToken(token) != hash {
+		t.Error("Expected HashRefreshToken to reproduce the stored hash")
+	}
+
+	other, _, _ := GenerateRefreshToken()
+	if other == token {
+		t.Error("Expected refresh tokens to be unique")
+	}
+}
+
 // splitString splits a string by a delimiter
 func splitString(s string, delim rune) []string {
 	var parts []string
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateRefreshToken creates a random refresh token and the hash to store
+// for it. Only the hash is persisted; the token is handed to the client once.
+func GenerateRefreshToken() (token, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+// HashRefreshToken returns the stored form of a refresh token
+func HashRefreshToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+// GenerateEmailToken creates a random token for a link sent by email, such as
+// an account unlock link, and the hash to store for it.
+func GenerateEmailToken() (token, hash string, err error) {
+	return generateOpaqueToken()
+}
+
+// HashEmailToken returns the stored form of an email link token
+func HashEmailToken(token string) string {
+	return hashOpaqueToken(token)
+}
+
+func generateOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashOpaqueToken(token), nil
+}
+
+func hashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -15,15 +15,33 @@ type Client struct {
 	ID            string
 	UserID        int64
 	PageID        int64
+	ProjectID     int64 // Project the page belongs to (0 for non-wiki rooms)
+	ViewOnly      bool  // Stays a viewer whatever the project role (API keys without wiki:write)
 	Conn          *websocket.Conn
 	Send          chan []byte
 	manager       *Manager
 	roomID        string
 	closedMu      sync.Mutex
 	closed        bool
+	roleMu        sync.RWMutex
+	role          string
 	HandleMessage func([]byte) // Custom message handler
 }
 
+// Role returns the client's current project role
+func (c *Client) Role() string {
+	c.roleMu.RLock()
+	defer c.roleMu.RUnlock()
+	return c.role
+}
+
+// SetRole updates the client's project role (e.g. after a membership change)
+func (c *Client) SetRole(role string) {
+	c.roleMu.Lock()
+	defer c.roleMu.Unlock()
+	c.role = role
+}
+
 // Message represents a WebSocket message
 type Message struct {
 	Type    string          `json:"type"`
@@ -249,6 +267,37 @@ func (m *Manager) closeAllConnections() {
 	}
 }
 
+// ClientsForUser returns the connected clients of a user across all rooms
+func (m *Manager) ClientsForUser(userID int64) []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []*Client
+	for _, clients := range m.rooms {
+		for client := range clients {
+			if client.UserID == userID {
+				out = append(out, client)
+			}
+		}
+	}
+	return out
+}
+
+// Disconnect sends a close frame with the given reason and closes the
+// connection. The read pump then unregisters the client as usual.
+func (m *Manager) Disconnect(client *Client, code int, reason string) {
+	_ = client.Conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait))
+	client.Conn.Close()
+
+	m.logger.Info("Client disconnected by server",
+		zap.String("client_id", client.ID),
+		zap.Int64("user_id", client.UserID),
+		zap.String("room_id", client.roomID),
+		zap.String("reason", reason),
+	)
+}
+
 // GetRoomSize returns the number of clients in a room
 func (m *Manager) GetRoomSize(roomID string) int {
 	m.mu.RLock()
@@ -21,7 +21,11 @@ type Config struct {
 
 	// JWT
 	JWTSecret      string
-	JWTExpiryHours int
+	JWTExpiryHours int // tokens issued without a session (OAuth login)
+
+	// Sessions
+	AccessTokenMinutes int
+	RefreshTokenDays   int
 
 	// CORS
 	CORSAllowedOrigins []string
@@ -29,6 +33,9 @@ type Config struct {
 	// Rate Limiting
 	RateLimitRequests       int
 	RateLimitWindowMinutes  int
+	RateLimitStore          string   // "memory" (per process) or "postgres" (shared)
+	RateLimitMaxKeys        int      // clients tracked by the memory store
+	TrustedProxies          []string // CIDRs allowed to set X-Forwarded-For / X-Real-IP
 
 	// Logging
 	LogLevel       string
@@ -57,8 +64,25 @@ type Config struct {
 	OAuthSuccessURL         string
 	OAuthErrorURL           string
 
+	// WebAuthn (passkeys and security keys). The relying party ID defaults
+	// to the host of the first origin, and origins default to the frontend URL.
+	WebAuthnRPID    string
+	WebAuthnOrigins []string
+
 	// Backup (Google Drive) — reuses GOOGLE_CLIENT_ID/SECRET from OAuth login
 	BackupEncryptionKey string // 64-char hex-encoded 32-byte AES key
+
+	// Master key for third-party secrets stored in the database, and the
+	// keys it replaced, which are only used to read values during rotation
+	SecretsEncryptionKey string   // 64-char hex-encoded 32-byte AES key
+	SecretsPreviousKeys  []string // same format, comma-separated in the environment
+}
+
+// DefaultTrustedProxies are the loopback and private networks a reverse proxy
+// in front of the API usually connects from
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8", "::1/128",
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7",
 }
 
 // Load reads configuration from environment variables
@@ -74,9 +98,14 @@ func Load() *Config {
 		MigrationsPath:          getEnv("MIGRATIONS_PATH", "./internal/db/migrations"),
 		JWTSecret:               getEnv("JWT_SECRET", "change-this-to-a-secure-random-string-in-production"),
 		JWTExpiryHours:          getEnvAsInt("JWT_EXPIRY_HOURS", 24),
+		AccessTokenMinutes:      getEnvAsInt("ACCESS_TOKEN_MINUTES", 15),
+		RefreshTokenDays:        getEnvAsInt("REFRESH_TOKEN_DAYS", 30),
 		CORSAllowedOrigins:      getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173", "http://localhost:3000"}),
 		RateLimitRequests:       getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
 		RateLimitWindowMinutes:  getEnvAsInt("RATE_LIMIT_WINDOW_MINUTES", 15),
+		RateLimitStore:          getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitMaxKeys:        getEnvAsInt("RATE_LIMIT_MAX_KEYS", 100000),
+		TrustedProxies:          getEnvAsSlice("TRUSTED_PROXIES", DefaultTrustedProxies),
 		LogLevel:                getEnv("LOG_LEVEL", "info"),
 		EnableSQLLog:            getEnv("ENV", "development") == "development" || getEnv("ENABLE_SQL_LOG", "false") == "true",
 		EnablePprof:             getEnv("ENABLE_PPROF", "false") == "true",
@@ -92,8 +121,13 @@ func Load() *Config {
 		OAuthStateSecret:        getEnv("OAUTH_STATE_SECRET", ""),
 		OAuthSuccessURL:         getEnv("OAUTH_SUCCESS_URL", "http://localhost:5173/oauth/callback"),
 		OAuthErrorURL:           getEnv("OAUTH_ERROR_URL", "http://localhost:5173/login"),
+		WebAuthnRPID:            getEnv("WEBAUTHN_RP_ID", ""),
+		WebAuthnOrigins:         getEnvAsSlice("WEBAUTHN_ORIGINS", nil),
 
 		BackupEncryptionKey: getEnv("BACKUP_ENCRYPTION_KEY", ""),
+
+		SecretsEncryptionKey: getEnv("SECRETS_ENCRYPTION_KEY", ""),
+		SecretsPreviousKeys:  getEnvAsSlice("SECRETS_PREVIOUS_KEYS", nil),
 	}
 
 	// Validate critical configuration
@@ -110,6 +144,22 @@ func (c *Config) JWTExpiry() time.Duration {
 	return time.Duration(c.JWTExpiryHours) * time.Hour
 }
 
+// AccessTokenExpiry returns the lifetime of session access tokens
+func (c *Config) AccessTokenExpiry() time.Duration {
+	if c.AccessTokenMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.AccessTokenMinutes) * time.Minute
+}
+
+// RefreshTokenExpiry returns how long a session lasts without being refreshed
+func (c *Config) RefreshTokenExpiry() time.Duration {
+	if c.RefreshTokenDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(c.RefreshTokenDays) * 24 * time.Hour
+}
+
 // getEnv reads an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -242,6 +242,25 @@ func TestJWTExpiry(t *testing.T) {
 	}
 }
 
+func TestSessionExpiry(t *testing.T) {
+	cfg := &Config{AccessTokenMinutes: 5, RefreshTokenDays: 7}
+	if got := cfg.AccessTokenExpiry(); got != 5*time.Minute {
+		t.Errorf("AccessTokenExpiry() = %v, want 5m", got)
+	}
+	if got := cfg.RefreshTokenExpiry(); got != 7*24*time.Hour {
+		t.Errorf("RefreshTokenExpiry() = %v, want 168h", got)
+	}
+
+	// Unset values fall back to the defaults
+	cfg = &Config{}
+	if got := cfg.AccessTokenExpiry(); got != 15*time.Minute {
+		t.Errorf("Default AccessTokenExpiry() = %v, want 15m", got)
+	}
+	if got := cfg.RefreshTokenExpiry(); got != 30*24*time.Hour {
+		t.Errorf("Default RefreshTokenExpiry() = %v, want 720h", got)
+	}
+}
+
 func TestLoad(t *testing.T) {
 	t.Run("default values in development", func(t *testing.T) {
 		// Ensure ENV is not set to production (to avoid Fatal)
@@ -270,6 +289,15 @@ func TestLoad(t *testing.T) {
 		if cfg.RateLimitWindowMinutes != 15 {
 			t.Errorf("Default RateLimitWindowMinutes = %d, want 15", cfg.RateLimitWindowMinutes)
 		}
+		if cfg.RateLimitStore != "memory" {
+			t.Errorf("Default RateLimitStore = %q, want %q", cfg.RateLimitStore, "memory")
+		}
+		if len(cfg.TrustedProxies) != len(DefaultTrustedProxies) {
+			t.Errorf("Default TrustedProxies = %v, want %v", cfg.TrustedProxies, DefaultTrustedProxies)
+		}
+		if cfg.WebAuthnRPID != "" || len(cfg.WebAuthnOrigins) != 0 {
+			t.Errorf("Expected WebAuthn to default to the frontend URL, got %q %v", cfg.WebAuthnRPID, cfg.WebAuthnOrigins)
+		}
 		if cfg.LogLevel != "info" {
 			t.Errorf("Default LogLevel = %q, want %q", cfg.LogLevel, "info")
 		}
@@ -294,6 +322,10 @@ func TestLoad(t *testing.T) {
 		t.Setenv("CORS_ALLOWED_ORIGINS", "https://example.com,https://api.example.com")
 		t.Setenv("RATE_LIMIT_REQUESTS", "50")
 		t.Setenv("RATE_LIMIT_WINDOW_MINUTES", "10")
+		t.Setenv("RATE_LIMIT_STORE", "postgres")
+		t.Setenv("TRUSTED_PROXIES", "10.1.0.0/16")
+		t.Setenv("WEBAUTHN_RP_ID", "example.com")
+		t.Setenv("WEBAUTHN_ORIGINS", "https://app.example.com, https://example.com")
 		t.Setenv("LOG_LEVEL", "debug")
 		t.Setenv("DB_QUERY_TIMEOUT_SECONDS", "10")
 
@@ -323,6 +355,15 @@ func TestLoad(t *testing.T) {
 		if cfg.RateLimitWindowMinutes != 10 {
 			t.Errorf("RateLimitWindowMinutes = %d, want 10", cfg.RateLimitWindowMinutes)
 		}
+		if cfg.RateLimitStore != "postgres" {
+			t.Errorf("RateLimitStore = %q, want %q", cfg.RateLimitStore, "postgres")
+		}
+		if len(cfg.TrustedProxies) != 1 || cfg.TrustedProxies[0] != "10.1.0.0/16" {
+			t.Errorf("TrustedProxies = %v, want [10.1.0.0/16]", cfg.TrustedProxies)
+		}
+		if cfg.WebAuthnRPID != "example.com" || len(cfg.WebAuthnOrigins) != 2 || cfg.WebAuthnOrigins[1] != "https://example.com" {
+			t.Errorf("WebAuthn = %q %v, want example.com and two origins", cfg.WebAuthnRPID, cfg.WebAuthnOrigins)
+		}
 		if cfg.LogLevel != "debug" {
 			t.Errorf("LogLevel = %q, want %q", cfg.LogLevel, "debug")
 		}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -23,6 +26,9 @@ type APIKey struct {
 	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ProjectIDs []int64    `json:"project_ids,omitempty"` // empty means all of the user's projects
+	LastUsedIP *string    `json:"last_used_ip,omitempty"`
 }
 
 // APIKeyWithSecret includes the full key (only returned on creation)
@@ -58,8 +64,15 @@ func HashAPIKey(key string) string {
 	return base64.URLEncoding.EncodeToString(hash[:])
 }
 
-// CreateAPIKey creates a new API key for a user
+// CreateAPIKey creates a new API key for a user with full access
 func (db *DB) CreateAPIKey(ctx context.Context, userID int64, name string, expiresAt *time.Time) (*APIKeyWithSecret, error) {
+	return db.CreateScopedAPIKey(ctx, userID, name, expiresAt, nil, nil)
+}
+
+// CreateScopedAPIKey creates a new API key limited to scopes and, when
+// projectIDs is not empty, to those projects. Nil scopes keep the column
+// default, which grants full access.
+func (db *DB) CreateScopedAPIKey(ctx context.Context, userID int64, name string, expiresAt *time.Time, scopes []string, projectIDs []int64) (*APIKeyWithSecret, error) {
 	// Generate API key
 	key, keyHash, prefix, err := GenerateAPIKey()
 	if err != nil {
@@ -82,16 +95,33 @@ func (db *DB) CreateAPIKey(ctx context.Context, userID int64, name string, expir
 		return nil, fmt.Errorf("failed to insert API key: %w", err)
 	}
 
+	// Scopes are not in the ent schema; never leave a key with more access than asked for
+	if scopes != nil || len(projectIDs) > 0 {
+		_, err := db.ExecContext(ctx, db.Rebind(`UPDATE api_keys SET scopes = COALESCE(?, scopes), project_ids = ? WHERE id = ?`),
+			nullableList(scopes), joinIDs(projectIDs), newAPIKey.ID)
+		if err != nil {
+			_ = db.Client.APIKey.DeleteOneID(newAPIKey.ID).Exec(ctx)
+			return nil, fmt.Errorf("failed to set API key scopes: %w", err)
+		}
+	}
+
+	grants, err := db.apiKeyGrants(ctx, newAPIKey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	created := APIKey{
+		ID:        newAPIKey.ID,
+		UserID:    newAPIKey.UserID,
+		Name:      newAPIKey.Name,
+		KeyPrefix: newAPIKey.KeyPrefix,
+		CreatedAt: newAPIKey.CreatedAt,
+		ExpiresAt: newAPIKey.ExpiresAt,
+	}
+	grants[newAPIKey.ID].apply(&created)
+
 	return &APIKeyWithSecret{
-		APIKey: APIKey{
-			ID:        newAPIKey.ID,
-			UserID:    newAPIKey.UserID,
-			Name:      newAPIKey.Name,
-			KeyPrefix: newAPIKey.KeyPrefix,
-			CreatedAt: newAPIKey.CreatedAt,
-			ExpiresAt: newAPIKey.ExpiresAt,
-		},
-		Key: key,
+		APIKey: created,
+		Key:    key,
 	}, nil
 }
 
@@ -105,9 +135,14 @@ func (db *DB) GetAPIKeysByUserID(ctx context.Context, userID int64) ([]APIKey, e
 		return nil, fmt.Errorf("failed to query API keys: %w", err)
 	}
 
+	grants, err := db.apiKeyGrants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	keys := make([]APIKey, 0, len(entKeys))
 	for _, ek := range entKeys {
-		keys = append(keys, APIKey{
+		key := APIKey{
 			ID:         ek.ID,
 			UserID:     ek.UserID,
 			Name:       ek.Name,
@@ -115,12 +150,84 @@ func (db *DB) GetAPIKeysByUserID(ctx context.Context, userID int64) ([]APIKey, e
 			LastUsedAt: ek.LastUsedAt,
 			CreatedAt:  ek.CreatedAt,
 			ExpiresAt:  ek.ExpiresAt,
-		})
+		}
+		grants[ek.ID].apply(&key)
+		keys = append(keys, key)
 	}
 
 	return keys, nil
 }
 
+// apiKeyGrant holds the API key columns that are not in the ent schema
+type apiKeyGrant struct {
+	scopes     string
+	projectIDs string
+	lastUsedIP sql.NullString
+}
+
+func (g apiKeyGrant) apply(key *APIKey) {
+	key.Scopes = splitList(g.scopes)
+	key.ProjectIDs = nil
+	for _, part := range splitList(g.projectIDs) {
+		if id, err := strconv.ParseInt(part, 10, 64); err == nil {
+			key.ProjectIDs = append(key.ProjectIDs, id)
+		}
+	}
+	if g.lastUsedIP.Valid {
+		key.LastUsedIP = &g.lastUsedIP.String
+	}
+}
+
+// apiKeyGrants loads the scopes, project allow-list and last used IP of a
+// user's API keys, by key ID.
+func (db *DB) apiKeyGrants(ctx context.Context, userID int64) (map[int64]apiKeyGrant, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, scopes, project_ids, last_used_ip FROM api_keys WHERE user_id = ?`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API key scopes: %w", err)
+	}
+	defer rows.Close()
+
+	grants := make(map[int64]apiKeyGrant)
+	for rows.Next() {
+		var id int64
+		var g apiKeyGrant
+		if err := rows.Scan(&id, &g.scopes, &g.projectIDs, &g.lastUsedIP); err != nil {
+			return nil, fmt.Errorf("failed to scan API key scopes: %w", err)
+		}
+		grants[id] = g
+	}
+	return grants, rows.Err()
+}
+
+// splitList splits a stored comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	out := []string{}
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// nullableList joins a list for storage; nil stays NULL.
+func nullableList(items []string) *string {
+	if items == nil {
+		return nil
+	}
+	joined := strings.Join(items, ",")
+	return &joined
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
 // ValidateAPIKey checks if an API key is valid and returns the user ID
 func (db *DB) ValidateAPIKey(ctx context.Context, key string) (int64, error) {
 	keyHash := HashAPIKey(key)
@@ -179,19 +286,63 @@ func (db *DB) DeleteAPIKey(ctx context.Context, keyID, userID int64) error {
 
 // GetUserByAPIKey retrieves user info using an API key
 func (db *DB) GetUserByAPIKey(ctx context.Context, key string) (int64, string, error) {
-	userID, err := db.ValidateAPIKey(ctx, key)
+	apiKey, email, err := db.AuthenticateAPIKey(ctx, key, "")
 	if err != nil {
 		return 0, "", err
 	}
+	return apiKey.UserID, email, nil
+}
 
-	// Get user email
-	userEntity, err := db.Client.User.Get(ctx, userID)
+// AuthenticateAPIKey validates an API key and records its use from ip. It
+// returns the key with its scopes and project allow-list, and the owner's email.
+func (db *DB) AuthenticateAPIKey(ctx context.Context, key, ip string) (*APIKey, string, error) {
+	apiKeyEntity, err := db.Client.APIKey.Query().
+		Where(apikey.KeyHash(HashAPIKey(key))).
+		WithUser().
+		Only(ctx)
 	if err != nil {
 		if ent.IsNotFound(err) {
-			return 0, "", fmt.Errorf("user not found")
+			return nil, "", fmt.Errorf("invalid API key")
 		}
-		return 0, "", fmt.Errorf("failed to get user: %w", err)
+		return nil, "", fmt.Errorf("failed to validate API key: %w", err)
 	}
 
-	return userID, userEntity.Email, nil
+	// Check expiration
+	if apiKeyEntity.ExpiresAt != nil && apiKeyEntity.ExpiresAt.Before(time.Now()) {
+		return nil, "", fmt.Errorf("API key expired")
+	}
+	if apiKeyEntity.Edges.User == nil {
+		return nil, "", fmt.Errorf("user not found")
+	}
+
+	var g apiKeyGrant
+	err = db.QueryRowContext(ctx, db.Rebind(`SELECT scopes, project_ids, last_used_ip FROM api_keys WHERE id = ?`),
+		apiKeyEntity.ID).Scan(&g.scopes, &g.projectIDs, &g.lastUsedIP)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load API key scopes: %w", err)
+	}
+
+	// Update last used timestamp and address
+	now := time.Now()
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE api_keys SET last_used_at = ?, last_used_ip = COALESCE(NULLIF(?, ''), last_used_ip) WHERE id = ?`),
+		now, ip, apiKeyEntity.ID); err != nil {
+		// Log but don't fail on update error
+		db.logger.Warn("Failed to update API key last use", zap.Error(err))
+	}
+
+	result := &APIKey{
+		ID:         apiKeyEntity.ID,
+		UserID:     apiKeyEntity.UserID,
+		Name:       apiKeyEntity.Name,
+		KeyPrefix:  apiKeyEntity.KeyPrefix,
+		LastUsedAt: &now,
+		CreatedAt:  apiKeyEntity.CreatedAt,
+		ExpiresAt:  apiKeyEntity.ExpiresAt,
+	}
+	g.apply(result)
+	if ip != "" {
+		result.LastUsedIP = &ip
+	}
+	return result, apiKeyEntity.Edges.User.Email, nil
 }
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Audit channels: how an action reached the API
+const (
+	AuditChannelWeb    = "web"
+	AuditChannelAPIKey = "api_key"
+	AuditChannelSCIM   = "scim"
+	AuditChannelGitHub = "github" // GitHub webhooks and syncs
+)
+
+// AuditChange is the value of a field before and after an action. Before is
+// nil for created resources and After for deleted ones.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry records one mutating action
+type AuditEntry struct {
+	ID           int64                  `json:"id"`
+	CreatedAt    time.Time              `json:"created_at"`
+	ActorID      *int64                 `json:"actor_id,omitempty"`
+	ActorEmail   string                 `json:"actor_email"`
+	Channel      string                 `json:"channel"`
+	APIKeyPrefix string                 `json:"api_key_prefix,omitempty"`
+	AgentName    string                 `json:"agent_name,omitempty"`
+	IPAddress    string                 `json:"ip_address,omitempty"`
+	UserAgent    string                 `json:"user_agent,omitempty"`
+	Action       string                 `json:"action"`
+	ResourceType string                 `json:"resource_type,omitempty"`
+	ResourceID   *int64                 `json:"resource_id,omitempty"`
+	ProjectID    *int64                 `json:"project_id,omitempty"`
+	TeamID       *int64                 `json:"team_id,omitempty"`
+	Method       string                 `json:"method"`
+	Path         string                 `json:"path"`
+	Status       int                    `json:"status"`
+	Changes      map[string]AuditChange `json:"changes,omitempty"`
+}
+
+// CreateAuditEntry stores an audit entry.
+func (db *DB) CreateAuditEntry(ctx context.Context, e *AuditEntry) error {
+	changes := ""
+	if len(e.Changes) > 0 {
+		data, err := json.Marshal(e.Changes)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit changes: %w", err)
+		}
+		changes = string(data)
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO audit_log (created_at, actor_id, actor_email, channel, api_key_prefix, agent_name, ip_address,
+			user_agent, action, resource_type, resource_id, project_id, team_id, method, path, status, changes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		e.CreatedAt, e.ActorID, e.ActorEmail, e.Channel, e.APIKeyPrefix, e.AgentName, e.IPAddress,
+		e.UserAgent, e.Action, e.ResourceType, e.ResourceID, e.ProjectID, e.TeamID, e.Method, e.Path, e.Status, changes,
+	).Scan(&e.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save audit entry: %w", err)
+	}
+	return nil
+}
+
+// AuditQuery filters the audit log. Zero values match everything.
+type AuditQuery struct {
+	// ProjectIDs restricts entries to these projects; nil for no restriction.
+	// An empty, non-nil slice matches nothing.
+	ProjectIDs []int64
+	ProjectID  int64
+	ActorID    int64
+	// Action matches the action exactly, or every action of a resource type
+	// when it ends in a dot, like "task."
+	Action       string
+	ResourceType string
+	ResourceID   int64
+	Channel      string
+	Since        *time.Time
+	Until        *time.Time
+	Offset       int
+	// Limit is the page size; negative for no limit
+	Limit int
+}
+
+func (q AuditQuery) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if q.ProjectIDs != nil {
+		if len(q.ProjectIDs) == 0 {
+			return " WHERE 1 = 0", nil
+		}
+		conds = append(conds, `project_id IN (?`+strings.Repeat(`, ?`, len(q.ProjectIDs)-1)+`)`)
+		for _, id := range q.ProjectIDs {
+			args = append(args, id)
+		}
+	}
+	if q.ProjectID != 0 {
+		conds = append(conds, `project_id = ?`)
+		args = append(args, q.ProjectID)
+	}
+	if q.ActorID != 0 {
+		conds = append(conds, `actor_id = ?`)
+		args = append(args, q.ActorID)
+	}
+	if strings.HasSuffix(q.Action, ".") {
+		conds = append(conds, `action LIKE ?`)
+		args = append(args, strings.NewReplacer(`%`, ``, `_`, ``).Replace(q.Action)+`%`)
+	} else if q.Action != "" {
+		conds = append(conds, `action = ?`)
+		args = append(args, q.Action)
+	}
+	if q.ResourceType != "" {
+		conds = append(conds, `resource_type = ?`)
+		args = append(args, q.ResourceType)
+	}
+	if q.ResourceID != 0 {
+		conds = append(conds, `resource_id = ?`)
+		args = append(args, q.ResourceID)
+	}
+	if q.Channel != "" {
+		conds = append(conds, `channel = ?`)
+		args = append(args, q.Channel)
+	}
+	if q.Since != nil {
+		conds = append(conds, `created_at >= ?`)
+		args = append(args, q.Since.UTC())
+	}
+	if q.Until != nil {
+		conds = append(conds, `created_at < ?`)
+		args = append(args, q.Until.UTC())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ListAuditEntries returns a page of audit entries, newest first, and the
+// total number matching the query.
+func (db *DB) ListAuditEntries(ctx context.Context, q AuditQuery) ([]AuditEntry, int, error) {
+	where, args := q.where()
+
+	var total int
+	if err := db.QueryRowContext(ctx, db.Rebind(`SELECT COUNT(*) FROM audit_log`+where), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := `SELECT id, created_at, actor_id, actor_email, channel, api_key_prefix, agent_name, ip_address, user_agent,
+		action, resource_type, resource_id, project_id, team_id, method, path, status, changes
+		FROM audit_log` + where + ` ORDER BY created_at DESC, id DESC`
+	if q.Limit >= 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, q.Limit, q.Offset)
+	}
+	rows, err := db.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var changes string
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.ActorID, &e.ActorEmail, &e.Channel, &e.APIKeyPrefix, &e.AgentName,
+			&e.IPAddress, &e.UserAgent, &e.Action, &e.ResourceType, &e.ResourceID, &e.ProjectID, &e.TeamID,
+			&e.Method, &e.Path, &e.Status, &changes); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+				return nil, 0, fmt.Errorf("failed to decode audit changes: %w", err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+// AuditProjectsOwnedBy returns the projects whose audit log a user may read:
+// those they own.
+func (db *DB) AuditProjectsOwnedBy(ctx context.Context, userID int64) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id FROM projects WHERE owner_id = ?
+		 UNION SELECT project_id FROM project_members WHERE user_id = ? AND role = 'owner'`), userID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owned projects: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,249 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Custom field types
+const (
+	FieldText        = "text"
+	FieldNumber      = "number"
+	FieldSelect      = "select"
+	FieldMultiSelect = "multi_select"
+	FieldDate        = "date"
+	FieldUser        = "user"
+	FieldURL         = "url"
+	FieldCheckbox    = "checkbox"
+)
+
+// ErrCustomFieldNotFound is returned for custom fields that don't exist
+var ErrCustomFieldNotFound = errors.New("custom field not found")
+
+// CustomField is a task field a project defines. Options are the choices of
+// select and multi_select fields.
+type CustomField struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Name      string    `json:"name"`
+	FieldType string    `json:"field_type"`
+	Options   []string  `json:"options"`
+	Required  bool      `json:"required"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HasOptions reports whether the field's values are picked from its options
+func (f *CustomField) HasOptions() bool {
+	return f.FieldType == FieldSelect || f.FieldType == FieldMultiSelect
+}
+
+const customFieldColumns = `id, project_id, name, field_type, options, required, position, created_at, updated_at`
+
+func scanCustomField(row rowScanner, f *CustomField) error {
+	var options string
+	if err := row.Scan(&f.ID, &f.ProjectID, &f.Name, &f.FieldType, &options, &f.Required, &f.Position,
+		&f.CreatedAt, &f.UpdatedAt); err != nil {
+		return err
+	}
+	f.Options = []string{}
+	if err := json.Unmarshal([]byte(options), &f.Options); err != nil {
+		return fmt.Errorf("invalid options of custom field %d: %w", f.ID, err)
+	}
+	return nil
+}
+
+func encodeOptions(options []string) string {
+	if options == nil {
+		options = []string{}
+	}
+	b, _ := json.Marshal(options)
+	return string(b)
+}
+
+// ListCustomFields returns the custom fields of a project in display order.
+func (db *DB) ListCustomFields(ctx context.Context, projectID int64) ([]CustomField, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT `+customFieldColumns+` FROM custom_fields WHERE project_id = ? ORDER BY position, id`), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := []CustomField{}
+	for rows.Next() {
+		var f CustomField
+		if err := scanCustomField(rows, &f); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field: %w", err)
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// GetCustomField returns a custom field by ID.
+func (db *DB) GetCustomField(ctx context.Context, id int64) (*CustomField, error) {
+	var f CustomField
+	err := scanCustomField(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+customFieldColumns+` FROM custom_fields WHERE id = ?`), id), &f)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCustomFieldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom field: %w", err)
+	}
+	return &f, nil
+}
+
+// CreateCustomField stores a new custom field.
+func (db *DB) CreateCustomField(ctx context.Context, f *CustomField) error {
+	now := time.Now().UTC()
+	f.CreatedAt, f.UpdatedAt = now, now
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO custom_fields (project_id, name, field_type, options, required, position, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		f.ProjectID, f.Name, f.FieldType, encodeOptions(f.Options), f.Required, f.Position, f.CreatedAt, f.UpdatedAt).Scan(&f.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save custom field: %w", err)
+	}
+	return nil
+}
+
+// UpdateCustomField saves changes to a custom field. Values of select fields
+// that are no longer among its options are removed from tasks.
+func (db *DB) UpdateCustomField(ctx context.Context, f *CustomField) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	f.UpdatedAt = time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`UPDATE custom_fields SET name = ?, options = ?, required = ?, position = ?, updated_at = ? WHERE id = ?`),
+		f.Name, encodeOptions(f.Options), f.Required, f.Position, f.UpdatedAt, f.ID); err != nil {
+		return fmt.Errorf("failed to update custom field: %w", err)
+	}
+	if f.HasOptions() {
+		query := `DELETE FROM task_custom_field_values WHERE field_id = ?`
+		args := []interface{}{f.ID}
+		if len(f.Options) > 0 {
+			query += ` AND value NOT IN (?` + strings.Repeat(`, ?`, len(f.Options)-1) + `)`
+			for _, o := range f.Options {
+				args = append(args, o)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, db.Rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to remove old options: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteCustomField deletes a custom field and its values.
+func (db *DB) DeleteCustomField(ctx context.Context, id int64) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM custom_fields WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to delete custom field: %w", err)
+	}
+	return nil
+}
+
+// GetTaskCustomFieldValues returns the custom field values of tasks, by task
+// and then field ID.
+func (db *DB) GetTaskCustomFieldValues(ctx context.Context, taskIDs []int64) (map[int64]map[int64][]string, error) {
+	values := map[int64]map[int64][]string{}
+	if len(taskIDs) == 0 {
+		return values, nil
+	}
+	args := make([]interface{}, len(taskIDs))
+	for i, id := range taskIDs {
+		args[i] = id
+	}
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT task_id, field_id, value FROM task_custom_field_values
+		 WHERE task_id IN (?`+strings.Repeat(`, ?`, len(taskIDs)-1)+`) ORDER BY task_id, field_id, value`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query custom field values: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, fieldID int64
+		var value string
+		if err := rows.Scan(&taskID, &fieldID, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan custom field value: %w", err)
+		}
+		if values[taskID] == nil {
+			values[taskID] = map[int64][]string{}
+		}
+		values[taskID][fieldID] = append(values[taskID][fieldID], value)
+	}
+	return values, rows.Err()
+}
+
+// SetTaskCustomFieldValues replaces the values of the given fields on a
+// task; fields without values are cleared. Other fields are left alone.
+func (db *DB) SetTaskCustomFieldValues(ctx context.Context, taskID int64, values map[int64][]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for fieldID, vs := range values {
+		if _, err := tx.ExecContext(ctx, db.Rebind(
+			`DELETE FROM task_custom_field_values WHERE task_id = ? AND field_id = ?`), taskID, fieldID); err != nil {
+			return fmt.Errorf("failed to clear custom field value: %w", err)
+		}
+		for _, v := range vs {
+			if _, err := tx.ExecContext(ctx, db.Rebind(
+				`INSERT INTO task_custom_field_values (task_id, field_id, value) VALUES (?, ?, ?)`), taskID, fieldID, v); err != nil {
+				return fmt.Errorf("failed to save custom field value: %w", err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchCustomFieldTasks returns the IDs of tasks in the given projects with
+// a text, URL or select value containing query, case-insensitively.
+func (db *DB) SearchCustomFieldTasks(ctx context.Context, query string, projectIDs []int64, limit int) ([]int64, error) {
+	if len(projectIDs) == 0 || strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(query))
+	args := []interface{}{FieldText, FieldURL, FieldSelect, FieldMultiSelect, "%" + escaped + "%"}
+	for _, id := range projectIDs {
+		args = append(args, id)
+	}
+	args = append(args, limit)
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT DISTINCT v.task_id FROM task_custom_field_values v
+		 JOIN custom_fields f ON f.id = v.field_id
+		 WHERE f.field_type IN (?, ?, ?, ?) AND LOWER(v.value) LIKE ? ESCAPE '\'
+		   AND f.project_id IN (?`+strings.Repeat(`, ?`, len(projectIDs)-1)+`)
+		 LIMIT ?`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan task id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -18,6 +18,7 @@ import (
 	_ "modernc.org/sqlite"
 
 	"taskai/ent"
+	"taskai/internal/secrets"
 	"entgo.io/ent/dialect"
 	entsql "entgo.io/ent/dialect/sql"
 )
@@ -28,6 +29,7 @@ type DB struct {
 	Client *ent.Client  // Ent ORM client for queries
 	Driver string      // "sqlite" or "postgres"
 	logger *zap.Logger
+	secrets *secrets.Keyring // encrypts stored third-party secrets; nil stores them as they are
 }
 
 // Rebind converts SQLite-style ? placeholders to Postgres-style $1, $2, ...
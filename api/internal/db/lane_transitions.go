@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WIP limit enforcement modes
+const (
+	WIPSoft = "soft" // tasks moved into a full lane are flagged
+	WIPHard = "hard" // moves into a full lane are rejected
+)
+
+// WIPLimit is the work-in-progress limit of a swim lane. Limit is nil for
+// lanes without one.
+type WIPLimit struct {
+	Limit       *int
+	Enforcement string
+}
+
+// LaneTransition records a task entering a swim lane. From values are nil
+// when the task was created.
+type LaneTransition struct {
+	ID         int64
+	TaskID     int64
+	ProjectID  int64
+	FromLaneID *int64
+	ToLaneID   *int64
+	FromStatus *string
+	ToStatus   string
+	CreatedAt  time.Time
+
+	WIPLimitExceeded bool // the move took the lane over its WIP limit
+}
+
+// GetWIPLimits returns the WIP limits of a project's swim lanes, by lane ID.
+func (db *DB) GetWIPLimits(ctx context.Context, projectID int64) (map[int64]WIPLimit, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, wip_limit, wip_enforcement FROM swim_lanes WHERE project_id = ?`), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wip limits: %w", err)
+	}
+	defer rows.Close()
+
+	limits := map[int64]WIPLimit{}
+	for rows.Next() {
+		var id int64
+		var l WIPLimit
+		var limit sql.NullInt64
+		if err := rows.Scan(&id, &limit, &l.Enforcement); err != nil {
+			return nil, fmt.Errorf("failed to scan wip limit: %w", err)
+		}
+		if limit.Valid {
+			n := int(limit.Int64)
+			l.Limit = &n
+		}
+		limits[id] = l
+	}
+	return limits, rows.Err()
+}
+
+// SetWIPLimit sets the WIP limit of a swim lane; a nil limit removes it.
+func (db *DB) SetWIPLimit(ctx context.Context, laneID int64, l WIPLimit) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE swim_lanes SET wip_limit = ?, wip_enforcement = ? WHERE id = ?`), l.Limit, l.Enforcement, laneID); err != nil {
+		return fmt.Errorf("failed to set wip limit: %w", err)
+	}
+	return nil
+}
+
+// CountLaneTasks counts the tasks in a swim lane other than excludeTaskID.
+func (db *DB) CountLaneTasks(ctx context.Context, laneID, excludeTaskID int64) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*) FROM tasks WHERE swim_lane_id = ? AND id <> ?`), laneID, excludeTaskID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count lane tasks: %w", err)
+	}
+	return n, nil
+}
+
+// CreateLaneTransition records a task entering a swim lane.
+func (db *DB) CreateLaneTransition(ctx context.Context, t *LaneTransition) error {
+	t.CreatedAt = time.Now().UTC()
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO task_lane_transitions (task_id, project_id, from_lane_id, to_lane_id, from_status, to_status,
+		   wip_limit_exceeded, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		t.TaskID, t.ProjectID, t.FromLaneID, t.ToLaneID, t.FromStatus, t.ToStatus, t.WIPLimitExceeded, t.CreatedAt).Scan(&t.ID)
+	if err != nil {
+		return fmt.Errorf("failed to save lane transition: %w", err)
+	}
+	return nil
+}
+
+// ListLaneTransitions returns the lane transitions of a project's tasks, by
+// task and then oldest first.
+func (db *DB) ListLaneTransitions(ctx context.Context, projectID int64) ([]LaneTransition, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, task_id, project_id, from_lane_id, to_lane_id, from_status, to_status, wip_limit_exceeded, created_at
+		 FROM task_lane_transitions WHERE project_id = ? ORDER BY task_id, created_at, id`), projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lane transitions: %w", err)
+	}
+	defer rows.Close()
+
+	transitions := []LaneTransition{}
+	for rows.Next() {
+		var t LaneTransition
+		if err := rows.Scan(&t.ID, &t.TaskID, &t.ProjectID, &t.FromLaneID, &t.ToLaneID, &t.FromStatus,
+			&t.ToStatus, &t.WIPLimitExceeded, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lane transition: %w", err)
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
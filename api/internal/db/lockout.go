@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnlockTokenInvalid is returned for unknown, used or expired unlock tokens
+var ErrUnlockTokenInvalid = errors.New("invalid unlock token")
+
+// Lockout is the failed-login state of an account
+type Lockout struct {
+	UserID       int64      `json:"user_id"`
+	Email        string     `json:"email"`
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// Locked reports whether the account is locked at now.
+func (l *Lockout) Locked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
+
+func scanLockout(row interface{ Scan(...interface{}) error }) (*Lockout, error) {
+	var l Lockout
+	var lockedUntil sql.NullTime
+	if err := row.Scan(&l.UserID, &l.Email, &l.FailedLogins, &lockedUntil); err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		l.LockedUntil = &lockedUntil.Time
+	}
+	return &l, nil
+}
+
+// GetLockout returns the failed-login state of a user.
+func (db *DB) GetLockout(ctx context.Context, userID int64) (*Lockout, error) {
+	l, err := scanLockout(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT id, email, failed_login_count, locked_until FROM users WHERE id = ?`), userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query lockout: %w", err)
+	}
+	return l, nil
+}
+
+// RecordFailedLogin counts a failed login for a user and returns the number
+// of consecutive failures.
+func (db *DB) RecordFailedLogin(ctx context.Context, userID int64) (int, error) {
+	var failures int
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`UPDATE users SET failed_login_count = failed_login_count + 1 WHERE id = ? RETURNING failed_login_count`),
+		userID).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	return failures, nil
+}
+
+// LockAccount stops a user from logging in until the given time.
+func (db *DB) LockAccount(ctx context.Context, userID int64, until time.Time) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(`UPDATE users SET locked_until = ? WHERE id = ?`), until.UTC(), userID); err != nil {
+		return fmt.Errorf("failed to lock account: %w", err)
+	}
+	return nil
+}
+
+// ClearLockout unlocks an account and resets its failed login count. It
+// reports whether the user exists.
+func (db *DB) ClearLockout(ctx context.Context, userID int64) (bool, error) {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE users SET failed_login_count = 0, locked_until = NULL WHERE id = ? AND deleted_at IS NULL`), userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear lockout: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ListLockedAccounts returns the accounts locked at now, those locked longest first.
+func (db *DB) ListLockedAccounts(ctx context.Context, now time.Time) ([]Lockout, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, email, failed_login_count, locked_until FROM users
+		 WHERE locked_until > ? AND deleted_at IS NULL
+		 ORDER BY locked_until DESC, id`), now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query locked accounts: %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := []Lockout{}
+	for rows.Next() {
+		l, err := scanLockout(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lockout: %w", err)
+		}
+		lockouts = append(lockouts, *l)
+	}
+	return lockouts, rows.Err()
+}
+
+// CreateUnlockToken stores the hash of an account unlock token.
+func (db *DB) CreateUnlockToken(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`INSERT INTO account_unlock_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)`),
+		userID, tokenHash, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to create unlock token: %w", err)
+	}
+	return nil
+}
+
+// UseUnlockToken marks an unlock token used and returns its user. The
+// account itself is unlocked by the caller.
+func (db *DB) UseUnlockToken(ctx context.Context, tokenHash string, now time.Time) (int64, error) {
+	var userID int64
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`UPDATE account_unlock_tokens SET used_at = ?
+		 WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		 RETURNING user_id`),
+		now.UTC(), tokenHash, now.UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrUnlockTokenInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to use unlock token: %w", err)
+	}
+	return userID, nil
+}
+
+// LoginSource is where a past login came from
+type LoginSource struct {
+	IPAddress string
+	UserAgent string
+}
+
+// RecentLoginSources returns the IP addresses and user agents of a user's
+// latest successful logins, newest first.
+func (db *DB) RecentLoginSources(ctx context.Context, userID int64, limit int) ([]LoginSource, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT COALESCE(ip_address, ''), COALESCE(user_agent, '') FROM user_activity
+		 WHERE user_id = ? AND activity_type = 'login'
+		 ORDER BY created_at DESC, id DESC LIMIT ?`), userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query login history: %w", err)
+	}
+	defer rows.Close()
+
+	sources := []LoginSource{}
+	for rows.Next() {
+		var src LoginSource
+		if err := rows.Scan(&src.IPAddress, &src.UserAgent); err != nil {
+			return nil, fmt.Errorf("failed to scan login history: %w", err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
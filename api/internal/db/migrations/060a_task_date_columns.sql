@@ -0,0 +1,13 @@
+-- tasks.due_date and tasks.start_date were added as TEXT columns, which the
+-- SQLite driver returns as strings, so Ent can't read tasks that have dates.
+-- Re-declare them as DATETIME, keeping their values. Postgres already stores
+-- them as timestamps.
+ALTER TABLE tasks RENAME COLUMN due_date TO due_date_text;
+ALTER TABLE tasks ADD COLUMN due_date DATETIME;
+UPDATE tasks SET due_date = due_date_text;
+ALTER TABLE tasks DROP COLUMN due_date_text;
+
+ALTER TABLE tasks RENAME COLUMN start_date TO start_date_text;
+ALTER TABLE tasks ADD COLUMN start_date DATETIME;
+UPDATE tasks SET start_date = start_date_text;
+ALTER TABLE tasks DROP COLUMN start_date_text;
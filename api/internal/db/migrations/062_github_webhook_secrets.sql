@@ -0,0 +1,10 @@
+-- Inbound GitHub webhooks are verified with a secret per project. Delivery
+-- IDs are remembered for a week so redelivered events are applied once.
+ALTER TABLE projects ADD COLUMN github_webhook_secret TEXT;
+
+CREATE TABLE IF NOT EXISTS github_webhook_deliveries (
+    delivery_id TEXT PRIMARY KEY,
+    received_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_github_webhook_deliveries_received ON github_webhook_deliveries(received_at);
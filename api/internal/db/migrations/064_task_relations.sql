@@ -0,0 +1,38 @@
+-- Typed task-to-task relations (blocks, duplicates, relates_to).
+-- "Is blocked by" / "is duplicated by" are the same rows read from the target side.
+
+CREATE TABLE IF NOT EXISTS task_relations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    source_task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    target_task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL CHECK(relation_type IN ('blocks', 'duplicates', 'relates_to')),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(source_task_id, target_task_id, relation_type),
+    CHECK(source_task_id <> target_task_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_relations_project ON task_relations(project_id, relation_type);
+CREATE INDEX IF NOT EXISTS idx_task_relations_target ON task_relations(target_task_id);
+
+-- Graph edges between the same nodes may now differ by relation type, so a
+-- task can both reference and block another. SQLite can't alter a UNIQUE
+-- constraint, so the table is rebuilt.
+CREATE TABLE graph_edges_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source_node_id INTEGER NOT NULL REFERENCES graph_nodes(id) ON DELETE CASCADE,
+    target_node_id INTEGER NOT NULL REFERENCES graph_nodes(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL DEFAULT 'reference',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(source_node_id, target_node_id, relation_type)
+);
+
+INSERT INTO graph_edges_new (id, source_node_id, target_node_id, relation_type, created_at)
+SELECT id, source_node_id, target_node_id, relation_type, created_at FROM graph_edges;
+
+DROP TABLE graph_edges;
+ALTER TABLE graph_edges_new RENAME TO graph_edges;
+
+CREATE INDEX IF NOT EXISTS idx_graph_edges_source ON graph_edges(source_node_id);
+CREATE INDEX IF NOT EXISTS idx_graph_edges_target ON graph_edges(target_node_id);
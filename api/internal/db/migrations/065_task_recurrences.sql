@@ -0,0 +1,37 @@
+-- Recurring task series. Each series stores its schedule and the fields copied
+-- into every occurrence; occurrences are ordinary tasks linked by recurrence_id.
+CREATE TABLE IF NOT EXISTS task_recurrences (
+    id               INTEGER PRIMARY KEY AUTOINCREMENT,
+    project_id       INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    frequency        TEXT NOT NULL CHECK(frequency IN ('daily', 'weekly', 'monthly', 'cron')),
+    interval_count   INTEGER NOT NULL DEFAULT 1,
+    weekdays         TEXT NOT NULL DEFAULT '',
+    cron_expr        TEXT NOT NULL DEFAULT '',
+    starts_at        DATETIME NOT NULL,
+    ends_at          DATETIME,
+    max_occurrences  INTEGER,
+    occurrence_count INTEGER NOT NULL DEFAULT 0,
+    next_run_at      DATETIME,
+    active           BOOLEAN NOT NULL DEFAULT 1,
+    title            TEXT NOT NULL,
+    description      TEXT,
+    priority         TEXT NOT NULL DEFAULT 'medium',
+    swim_lane_id     INTEGER REFERENCES swim_lanes(id) ON DELETE SET NULL,
+    estimated_hours  REAL,
+    due_offset_days  INTEGER,
+    tag_ids          TEXT NOT NULL DEFAULT '',
+    assignee_ids     TEXT NOT NULL DEFAULT '',
+    created_by       INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at       DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at       DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_recurrences_project ON task_recurrences(project_id);
+CREATE INDEX IF NOT EXISTS idx_task_recurrences_due ON task_recurrences(active, next_run_at);
+
+ALTER TABLE tasks ADD COLUMN recurrence_id INTEGER REFERENCES task_recurrences(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN occurrence_at DATETIME;
+
+-- One task per scheduled occurrence, even with several API instances running the worker
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurrence_occurrence ON tasks(recurrence_id, occurrence_at)
+    WHERE recurrence_id IS NOT NULL;
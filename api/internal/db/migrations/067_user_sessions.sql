@@ -0,0 +1,21 @@
+-- Login sessions. Each session holds the hash of its current refresh token and
+-- of the one it replaced, so a reused (stolen) refresh token revokes the session.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id                  INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id             INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT NOT NULL UNIQUE,
+    previous_token_hash TEXT,
+    user_agent          TEXT NOT NULL DEFAULT '',
+    ip_address          TEXT NOT NULL DEFAULT '',
+    created_at          DATETIME NOT NULL DEFAULT (datetime('now')),
+    last_used_at        DATETIME NOT NULL DEFAULT (datetime('now')),
+    expires_at          DATETIME NOT NULL,
+    revoked_at          DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous ON user_sessions(previous_token_hash);
+
+-- Tokens issued without a session (OAuth login, older clients) before this
+-- time are rejected
+ALTER TABLE users ADD COLUMN tokens_revoked_at DATETIME;
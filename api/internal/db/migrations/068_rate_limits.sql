@@ -0,0 +1,10 @@
+-- Rate limit state shared between API replicas. Limits use GCRA: each key
+-- stores its theoretical arrival time (unix nanoseconds), the moment the
+-- client's allowance is fully restored.
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket_key TEXT PRIMARY KEY,
+    tat        INTEGER NOT NULL,
+    allowed    INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_tat ON rate_limits(tat);
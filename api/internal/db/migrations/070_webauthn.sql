@@ -0,0 +1,30 @@
+-- WebAuthn credentials (passkeys and security keys). A credential is a second
+-- factor after the password, and discoverable ones also log in on their own.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id   TEXT NOT NULL UNIQUE,
+    public_key      BLOB NOT NULL,
+    sign_count      INTEGER NOT NULL DEFAULT 0,
+    transports      TEXT NOT NULL DEFAULT '',
+    name            TEXT NOT NULL,
+    backup_eligible BOOLEAN NOT NULL DEFAULT 0,
+    backed_up       BOOLEAN NOT NULL DEFAULT 0,
+    created_at      DATETIME NOT NULL DEFAULT (datetime('now')),
+    last_used_at    DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);
+
+-- Outstanding challenges: WebAuthn registrations and logins, and logins
+-- waiting for a second factor. user_id is NULL for passwordless logins, where
+-- the user isn't known until the credential is presented.
+CREATE TABLE IF NOT EXISTS auth_challenges (
+    challenge  TEXT PRIMARY KEY,
+    purpose    TEXT NOT NULL,
+    user_id    INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_challenges_expires ON auth_challenges(expires_at);
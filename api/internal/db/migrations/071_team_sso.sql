@@ -0,0 +1,30 @@
+-- Single sign-on for a team through an OpenID Connect issuer or a SAML
+-- identity provider. Users in the team's verified email domains are
+-- provisioned into the team on their first login. When enforced, members who
+-- signed in through it, other than the owner, can only log in through it.
+CREATE TABLE IF NOT EXISTS team_sso_configs (
+    team_id            INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    protocol           TEXT NOT NULL,             -- 'oidc' or 'saml'
+    enabled            BOOLEAN NOT NULL DEFAULT 0,
+    enforced           BOOLEAN NOT NULL DEFAULT 0,
+    oidc_issuer        TEXT NOT NULL DEFAULT '',
+    oidc_client_id     TEXT NOT NULL DEFAULT '',
+    oidc_client_secret TEXT NOT NULL DEFAULT '',
+    saml_metadata      TEXT NOT NULL DEFAULT '',
+    created_at         DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at         DATETIME NOT NULL DEFAULT (datetime('now'))
+);
+
+-- Email domains a team's single sign-on claims. A claim only counts once the
+-- team has proved it controls the domain by publishing the token in a DNS
+-- TXT record, and only one team can verify a domain.
+CREATE TABLE IF NOT EXISTS team_sso_domains (
+    team_id     INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    domain      TEXT NOT NULL,
+    token       TEXT NOT NULL,
+    verified_at DATETIME,
+    created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (team_id, domain)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_sso_domains_verified ON team_sso_domains(domain) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Custom project roles a team defines on top of the built-in viewer, member,
+-- editor and owner. project_members.role and project_invitations.role hold
+-- the role's name, which is unique within the team and never a built-in name.
+CREATE TABLE IF NOT EXISTS team_roles (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    team_id     INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    permissions TEXT NOT NULL DEFAULT '',  -- comma separated, e.g. 'task.create,wiki.edit'
+    created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(team_id, name)
+);
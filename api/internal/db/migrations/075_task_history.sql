@@ -0,0 +1,18 @@
+-- Field-level history of task changes: who changed which field, through which
+-- channel, and its value before and after. Values are stored as shown to
+-- users, e.g. swim lane and sprint names rather than IDs.
+CREATE TABLE IF NOT EXISTS task_history (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id    INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id    INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    agent_name TEXT NOT NULL DEFAULT '',
+    source     TEXT NOT NULL,  -- 'web' or 'api_key'
+    field      TEXT NOT NULL,  -- e.g. 'swim_lane', 'priority', 'assignees'
+    old_value  TEXT,
+    new_value  TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_history_task ON task_history(task_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_task_history_user ON task_history(user_id, created_at);
@@ -0,0 +1,30 @@
+-- Optional work-in-progress limits per swim lane. A NULL limit means none;
+-- 'soft' limits only flag tasks moved into a full lane, 'hard' ones reject
+-- the move.
+ALTER TABLE swim_lanes ADD COLUMN wip_limit INTEGER;
+ALTER TABLE swim_lanes ADD COLUMN wip_enforcement TEXT NOT NULL DEFAULT 'soft';
+
+-- Every move of a task between swim lanes, for flow metrics (cycle time,
+-- lead time, throughput, cumulative flow). Lane IDs aren't foreign keys so
+-- the flow through deleted lanes stays on record. wip_limit_exceeded flags
+-- moves that took a lane over its WIP limit, including ones GitHub sync
+-- makes regardless of the limit.
+CREATE TABLE IF NOT EXISTS task_lane_transitions (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id      INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    project_id   INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    from_lane_id INTEGER,  -- NULL when the task was created
+    to_lane_id   INTEGER,
+    from_status  TEXT,
+    to_status    TEXT NOT NULL,
+    wip_limit_exceeded BOOLEAN NOT NULL DEFAULT 0,
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_lane_transitions_project ON task_lane_transitions(project_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_task_lane_transitions_task ON task_lane_transitions(task_id, created_at);
+
+-- Existing tasks are taken to have entered their current lane when they
+-- were last updated
+INSERT INTO task_lane_transitions (task_id, project_id, to_lane_id, to_status, created_at)
+SELECT id, project_id, swim_lane_id, status, COALESCE(updated_at, created_at, CURRENT_TIMESTAMP) FROM tasks;
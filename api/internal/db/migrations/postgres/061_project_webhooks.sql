@@ -0,0 +1,34 @@
+-- Outgoing webhook subscriptions and their persistent delivery queue
+CREATE TABLE IF NOT EXISTS project_webhooks (
+    id          BIGSERIAL PRIMARY KEY,
+    project_id  BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    url         TEXT NOT NULL,
+    secret      TEXT NOT NULL,
+    events      TEXT NOT NULL DEFAULT '*',   -- comma-separated event names, or * for all
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_webhooks_project ON project_webhooks(project_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    webhook_id       BIGINT NOT NULL REFERENCES project_webhooks(id) ON DELETE CASCADE,
+    event            VARCHAR(50) NOT NULL,
+    payload          TEXT NOT NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, succeeded, failed
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ,
+    last_attempt_at  TIMESTAMPTZ,
+    response_status  INTEGER,
+    response_body    TEXT,
+    error_message    TEXT,
+    duration_ms      INTEGER,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(status, next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+-- Optional parent page and sibling ordering for hierarchical wikis.
+-- Slugs stay unique per project; the nested URL path is built from ancestor slugs.
+
+ALTER TABLE wiki_pages ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES wiki_pages(id) ON DELETE SET NULL;
+ALTER TABLE wiki_pages ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_wiki_pages_parent ON wiki_pages(project_id, parent_id, position);
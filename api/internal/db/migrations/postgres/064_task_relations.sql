@@ -0,0 +1,23 @@
+-- Typed task-to-task relations (blocks, duplicates, relates_to).
+-- "Is blocked by" / "is duplicated by" are the same rows read from the target side.
+
+CREATE TABLE IF NOT EXISTS task_relations (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    source_task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    target_task_id BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    relation_type TEXT NOT NULL CHECK(relation_type IN ('blocks', 'duplicates', 'relates_to')),
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(source_task_id, target_task_id, relation_type),
+    CHECK(source_task_id <> target_task_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_relations_project ON task_relations(project_id, relation_type);
+CREATE INDEX IF NOT EXISTS idx_task_relations_target ON task_relations(target_task_id);
+
+-- Graph edges between the same nodes may now differ by relation type, so a
+-- task can both reference and block another.
+ALTER TABLE graph_edges DROP CONSTRAINT IF EXISTS graph_edges_source_node_id_target_node_id_key;
+ALTER TABLE graph_edges ADD CONSTRAINT graph_edges_source_target_relation_key
+    UNIQUE (source_node_id, target_node_id, relation_type);
@@ -0,0 +1,37 @@
+-- Recurring task series. Each series stores its schedule and the fields copied
+-- into every occurrence; occurrences are ordinary tasks linked by recurrence_id.
+CREATE TABLE IF NOT EXISTS task_recurrences (
+    id               BIGSERIAL PRIMARY KEY,
+    project_id       BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    frequency        TEXT NOT NULL CHECK(frequency IN ('daily', 'weekly', 'monthly', 'cron')),
+    interval_count   INTEGER NOT NULL DEFAULT 1,
+    weekdays         TEXT NOT NULL DEFAULT '',
+    cron_expr        TEXT NOT NULL DEFAULT '',
+    starts_at        TIMESTAMPTZ NOT NULL,
+    ends_at          TIMESTAMPTZ,
+    max_occurrences  INTEGER,
+    occurrence_count INTEGER NOT NULL DEFAULT 0,
+    next_run_at      TIMESTAMPTZ,
+    active           BOOLEAN NOT NULL DEFAULT TRUE,
+    title            TEXT NOT NULL,
+    description      TEXT,
+    priority         TEXT NOT NULL DEFAULT 'medium',
+    swim_lane_id     BIGINT REFERENCES swim_lanes(id) ON DELETE SET NULL,
+    estimated_hours  REAL,
+    due_offset_days  INTEGER,
+    tag_ids          TEXT NOT NULL DEFAULT '',
+    assignee_ids     TEXT NOT NULL DEFAULT '',
+    created_by       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_recurrences_project ON task_recurrences(project_id);
+CREATE INDEX IF NOT EXISTS idx_task_recurrences_due ON task_recurrences(active, next_run_at);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence_id BIGINT REFERENCES task_recurrences(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMPTZ;
+
+-- One task per scheduled occurrence, even with several API instances running the worker
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_recurrence_occurrence ON tasks(recurrence_id, occurrence_at)
+    WHERE recurrence_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Scoped API keys. Scopes and the project allow-list are comma-separated;
+-- existing keys keep full access through the admin scope.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT NOT NULL DEFAULT 'admin';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS project_ids TEXT NOT NULL DEFAULT '';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_ip TEXT;
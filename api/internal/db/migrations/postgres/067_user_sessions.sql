@@ -0,0 +1,21 @@
+-- Login sessions. Each session holds the hash of its current refresh token and
+-- of the one it replaced, so a reused (stolen) refresh token revokes the session.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT NOT NULL UNIQUE,
+    previous_token_hash TEXT,
+    user_agent          TEXT NOT NULL DEFAULT '',
+    ip_address          TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous ON user_sessions(previous_token_hash);
+
+-- Tokens issued without a session (OAuth login, older clients) before this
+-- time are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMPTZ;
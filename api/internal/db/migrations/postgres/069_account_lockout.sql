@@ -0,0 +1,17 @@
+-- Progressive account lockout. failed_login_count counts consecutive failed
+-- logins and is reset by a successful one; locked_until is set each time the
+-- count crosses the lockout threshold.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+-- One-time links emailed to a locked-out user to unlock their account
+CREATE TABLE IF NOT EXISTS account_unlock_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_unlock_tokens_user ON account_unlock_tokens(user_id);
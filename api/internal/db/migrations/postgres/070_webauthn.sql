@@ -0,0 +1,30 @@
+-- WebAuthn credentials (passkeys and security keys). A credential is a second
+-- factor after the password, and discoverable ones also log in on their own.
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id              BIGSERIAL PRIMARY KEY,
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id   TEXT NOT NULL UNIQUE,
+    public_key      BYTEA NOT NULL,
+    sign_count      BIGINT NOT NULL DEFAULT 0,
+    transports      TEXT NOT NULL DEFAULT '',
+    name            TEXT NOT NULL,
+    backup_eligible BOOLEAN NOT NULL DEFAULT FALSE,
+    backed_up       BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user ON webauthn_credentials(user_id);
+
+-- Outstanding challenges: WebAuthn registrations and logins, and logins
+-- waiting for a second factor. user_id is NULL for passwordless logins, where
+-- the user isn't known until the credential is presented.
+CREATE TABLE IF NOT EXISTS auth_challenges (
+    challenge  TEXT PRIMARY KEY,
+    purpose    TEXT NOT NULL,
+    user_id    BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_challenges_expires ON auth_challenges(expires_at);
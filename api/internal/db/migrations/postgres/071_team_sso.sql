@@ -0,0 +1,30 @@
+-- Single sign-on for a team through an OpenID Connect issuer or a SAML
+-- identity provider. Users in the team's verified email domains are
+-- provisioned into the team on their first login. When enforced, members who
+-- signed in through it, other than the owner, can only log in through it.
+CREATE TABLE IF NOT EXISTS team_sso_configs (
+    team_id            BIGINT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    protocol           TEXT NOT NULL,             -- 'oidc' or 'saml'
+    enabled            BOOLEAN NOT NULL DEFAULT FALSE,
+    enforced           BOOLEAN NOT NULL DEFAULT FALSE,
+    oidc_issuer        TEXT NOT NULL DEFAULT '',
+    oidc_client_id     TEXT NOT NULL DEFAULT '',
+    oidc_client_secret TEXT NOT NULL DEFAULT '',
+    saml_metadata      TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Email domains a team's single sign-on claims. A claim only counts once the
+-- team has proved it controls the domain by publishing the token in a DNS
+-- TXT record, and only one team can verify a domain.
+CREATE TABLE IF NOT EXISTS team_sso_domains (
+    team_id     BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    domain      TEXT NOT NULL,
+    token       TEXT NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, domain)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_sso_domains_verified ON team_sso_domains(domain) WHERE verified_at IS NOT NULL;
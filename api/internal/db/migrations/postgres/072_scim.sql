@@ -0,0 +1,54 @@
+-- SCIM 2.0 provisioning. A team's identity provider authenticates with the
+-- team's bearer token to create, update and deactivate its members, and to
+-- manage groups that map to team roles and project access.
+CREATE TABLE IF NOT EXISTS team_scim_tokens (
+    team_id      BIGINT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+    token_hash   TEXT NOT NULL UNIQUE,
+    token_prefix TEXT NOT NULL,
+    created_by   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+-- Accounts created by a team's identity provider belong to it: it can change
+-- their profile, and deactivating them blocks every way of logging in. The
+-- team ID is not a foreign key: teams already reference users through
+-- owner_id, and backups need the two tables in a fixed order.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS managed_by_team_id BIGINT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+-- The identity provider's own ID for a team member
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS scim_external_id TEXT NOT NULL DEFAULT '';
+-- Set while a member's team role comes from their SCIM groups
+ALTER TABLE team_members ADD COLUMN IF NOT EXISTS scim_role BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Project access granted through a SCIM group, which is taken back when the
+-- user leaves the group. Access granted by hand is never touched.
+ALTER TABLE project_members ADD COLUMN IF NOT EXISTS scim_managed BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS scim_groups (
+    id           BIGSERIAL PRIMARY KEY,
+    team_id      BIGINT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL,
+    external_id  TEXT NOT NULL DEFAULT '',
+    team_role    TEXT NOT NULL DEFAULT 'member',  -- 'member' or 'admin'
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(team_id, display_name)
+);
+
+CREATE TABLE IF NOT EXISTS scim_group_members (
+    group_id BIGINT NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id  BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_group_members_user ON scim_group_members(user_id);
+
+-- Projects a group's members get access to, and with which role
+CREATE TABLE IF NOT EXISTS scim_group_projects (
+    group_id   BIGINT NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    role       TEXT NOT NULL,  -- 'viewer', 'member' or 'editor'
+    PRIMARY KEY (group_id, project_id)
+);
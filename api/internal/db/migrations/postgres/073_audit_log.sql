@@ -0,0 +1,28 @@
+-- Audit trail of every mutating action: who changed what, when and through
+-- which channel. Entries outlive the users and projects they mention, so
+-- there are no foreign keys.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor_id       BIGINT,
+    actor_email    TEXT NOT NULL DEFAULT '',
+    channel        TEXT NOT NULL,  -- 'web', 'api_key' or 'scim'
+    api_key_prefix TEXT NOT NULL DEFAULT '',
+    agent_name     TEXT NOT NULL DEFAULT '',
+    ip_address     TEXT NOT NULL DEFAULT '',
+    user_agent     TEXT NOT NULL DEFAULT '',
+    action         TEXT NOT NULL,  -- e.g. 'task.updated'
+    resource_type  TEXT NOT NULL DEFAULT '',
+    resource_id    BIGINT,
+    project_id     BIGINT,
+    team_id        BIGINT,
+    method         TEXT NOT NULL,
+    path           TEXT NOT NULL,
+    status         INTEGER NOT NULL,
+    changes        TEXT NOT NULL DEFAULT ''  -- JSON object of field: {before, after}
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_project ON audit_log(project_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_resource ON audit_log(resource_type, resource_id);
@@ -0,0 +1,12 @@
+-- Daily snapshots of the tasks in each running sprint, for burndown, burnup,
+-- velocity and scope-change reporting. A day's rows are replaced whenever it
+-- is snapshotted again, so they hold the sprint's last known state that day.
+-- task_id has no foreign key: deleted tasks stay in the days they were part of.
+CREATE TABLE IF NOT EXISTS sprint_task_snapshots (
+    sprint_id       BIGINT NOT NULL REFERENCES sprints(id) ON DELETE CASCADE,
+    day             TEXT NOT NULL,  -- 'YYYY-MM-DD', UTC
+    task_id         BIGINT NOT NULL,
+    status          TEXT NOT NULL,
+    estimated_hours REAL,
+    PRIMARY KEY (sprint_id, day, task_id)
+);
@@ -0,0 +1,23 @@
+-- Time logged on tasks, by timer or as a manual duration. A task's
+-- actual_hours is the sum of its entries once it has any. Running timers
+-- have no duration yet; each user has at most one.
+CREATE TABLE IF NOT EXISTS time_entries (
+    id               BIGSERIAL PRIMARY KEY,
+    task_id          BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    project_id       BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    user_id          BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    agent_name       TEXT NOT NULL DEFAULT '',
+    work_date        TEXT NOT NULL,  -- 'YYYY-MM-DD' the work was done
+    started_at       TIMESTAMPTZ,    -- NULL for manual entries
+    ended_at         TIMESTAMPTZ,
+    duration_seconds BIGINT NOT NULL DEFAULT 0,
+    running          BOOLEAN NOT NULL DEFAULT FALSE,
+    note             TEXT NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries(task_id);
+CREATE INDEX IF NOT EXISTS idx_time_entries_project_date ON time_entries(project_id, work_date);
+CREATE INDEX IF NOT EXISTS idx_time_entries_user_date ON time_entries(user_id, work_date);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries(user_id) WHERE running;
@@ -0,0 +1,27 @@
+-- Project-defined task fields. options holds the JSON array of choices of
+-- select and multi_select fields.
+CREATE TABLE IF NOT EXISTS custom_fields (
+    id          BIGSERIAL PRIMARY KEY,
+    project_id  BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name        TEXT NOT NULL,
+    field_type  TEXT NOT NULL,  -- text, number, select, multi_select, date, user, url, checkbox
+    options     TEXT NOT NULL DEFAULT '[]',
+    required    BOOLEAN NOT NULL DEFAULT FALSE,
+    position    INTEGER NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_fields_project_name ON custom_fields(project_id, name);
+
+-- The values of custom fields on tasks, as text: one row per value, so
+-- multi_select fields have a row for each chosen option. Unset fields have
+-- no rows.
+CREATE TABLE IF NOT EXISTS task_custom_field_values (
+    task_id   BIGINT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    field_id  BIGINT NOT NULL REFERENCES custom_fields(id) ON DELETE CASCADE,
+    value     TEXT NOT NULL,
+    PRIMARY KEY (task_id, field_id, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_custom_field_values_field ON task_custom_field_values(field_id, value);
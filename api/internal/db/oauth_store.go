@@ -55,6 +55,9 @@ func (s *OAuthStore) FindUserByProviderID(ctx context.Context, provider, provide
 		}
 		return nil, fmt.Errorf("oauth_store: FindUserByProviderID: fetch user: %w", err)
 	}
+	if err := s.checkLoginAllowed(ctx, entUser.ID, provider); err != nil {
+		return nil, err
+	}
 	return &gologin.User{ID: entUser.ID, Email: entUser.Email}, nil
 }
 
@@ -315,6 +318,10 @@ func (s *OAuthStore) ValidateInviteCode(ctx context.Context, code string) (*golo
 // and returns the user. This enables users to sign in with any provider whose
 // email matches their account.
 func (s *OAuthStore) LinkOAuthProvider(ctx context.Context, userID int64, provider, providerUserID string) (*gologin.User, error) {
+	if err := s.checkLoginAllowed(ctx, userID, provider); err != nil {
+		return nil, err
+	}
+
 	// Upsert: insert or update so the provider_user_id is always current.
 	_, err := s.db.ExecContext(ctx,
 		s.db.Rebind(`INSERT INTO oauth_providers (user_id, provider, provider_user_id)
@@ -339,6 +346,76 @@ func (s *OAuthStore) LinkOAuthProvider(ctx context.Context, userID int64, provid
 	return &gologin.User{ID: entUser.ID, Email: entUser.Email}, nil
 }
 
+// ProvisionSSOUser creates the account of someone logging in through a
+// team's single sign-on for the first time. It goes through an invite from
+// the team owner, so the user gets a personal team and joins the SSO team
+// the same way as users invited by email.
+func (s *OAuthStore) ProvisionSSOUser(ctx context.Context, info gologin.ProviderUserInfo, teamID int64) (*gologin.User, error) {
+	team, err := s.db.Client.Team.Get(ctx, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("oauth_store: ProvisionSSOUser: team lookup: %w", err)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("oauth_store: ProvisionSSOUser: %w", err)
+	}
+	code := hex.EncodeToString(b)
+	expires := time.Now().Add(time.Hour)
+	inv, err := s.db.Client.Invite.Create().
+		SetCode(code).
+		SetInviterID(team.OwnerID).
+		SetExpiresAt(expires).
+		Save(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("oauth_store: ProvisionSSOUser: create invite: %w", err)
+	}
+	teamInv, err := s.db.Client.TeamInvitation.Create().
+		SetTeamID(teamID).
+		SetInviterID(team.OwnerID).
+		SetInviteeEmail(info.Email).
+		SetStatus("pending").
+		SetInviteCode(code).
+		Save(ctx)
+	if err != nil {
+		s.db.Client.Invite.DeleteOneID(inv.ID).Exec(ctx)
+		return nil, fmt.Errorf("oauth_store: ProvisionSSOUser: create team invitation: %w", err)
+	}
+
+	user, err := s.CreateOAuthUser(ctx, info, SSOProvider(teamID), code)
+	if err != nil {
+		s.db.Client.TeamInvitation.DeleteOneID(teamInv.ID).Exec(ctx)
+		s.db.Client.Invite.DeleteOneID(inv.ID).Exec(ctx)
+		return nil, err
+	}
+	return user, nil
+}
+
+// checkLoginAllowed refuses logins from accounts deactivated by their
+// identity provider, and logins with Google or GitHub from members of teams
+// that require their own single sign-on.
+func (s *OAuthStore) checkLoginAllowed(ctx context.Context, userID int64, provider string) error {
+	deactivated, err := s.db.IsUserDeactivated(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("oauth_store: %w", err)
+	}
+	if deactivated {
+		return ErrUserDeactivated
+	}
+
+	if strings.HasPrefix(provider, "sso:") {
+		return nil
+	}
+	teamID, err := s.db.SSOEnforcedTeam(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("oauth_store: %w", err)
+	}
+	if teamID != 0 {
+		return ErrSSORequired
+	}
+	return nil
+}
+
 // randomPlaceholderHash generates a random bcrypt hash to use as a placeholder
 // password for OAuth-only users (satisfies NOT NULL constraint; never usable).
 func randomPlaceholderHash() (string, error) {
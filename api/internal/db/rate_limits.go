@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TakeRateLimit spends one request of a GCRA rate limit for key. interval is
+// the time one request uses up and tolerance how far ahead of now the
+// theoretical arrival time (TAT) may run, i.e. the burst allowance. It returns
+// the key's TAT after the request and whether the request was allowed; a
+// rejected request leaves the TAT unchanged.
+func (db *DB) TakeRateLimit(ctx context.Context, key string, now time.Time, interval, tolerance time.Duration) (time.Time, bool, error) {
+	nowNanos := now.UnixNano()
+	limit := nowNanos + int64(tolerance)
+
+	// A TAT at or before limit is allowed. Checking the stored TAT is enough:
+	// now never exceeds limit, so max(tat, now) <= limit iff tat <= limit.
+	var tat int64
+	var allowed int
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO rate_limits (bucket_key, tat, allowed) VALUES (?, ?, 1)
+		 ON CONFLICT (bucket_key) DO UPDATE SET
+		     tat = CASE WHEN rate_limits.tat <= ?
+		                THEN (CASE WHEN rate_limits.tat > ? THEN rate_limits.tat ELSE ? END) + ?
+		                ELSE rate_limits.tat END,
+		     allowed = CASE WHEN rate_limits.tat <= ? THEN 1 ELSE 0 END
+		 RETURNING tat, allowed`),
+		key, nowNanos+int64(interval),
+		limit, nowNanos, nowNanos, int64(interval),
+		limit,
+	).Scan(&tat, &allowed)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to take rate limit: %w", err)
+	}
+	return time.Unix(0, tat), allowed == 1, nil
+}
+
+// PruneRateLimits deletes keys whose allowance was fully restored before now
+// and returns how many were removed.
+func (db *DB) PruneRateLimits(ctx context.Context, now time.Time) (int64, error) {
+	res, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM rate_limits WHERE tat < ?`), now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune rate limits: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTeamRoleNotFound is returned for roles that don't exist in the team
+	ErrTeamRoleNotFound = errors.New("role not found")
+	// ErrTeamRoleExists is returned for a role name already in use
+	ErrTeamRoleExists = errors.New("role already exists")
+	// ErrTeamRoleInUse is returned when deleting a role that project members
+	// or pending invitations still have
+	ErrTeamRoleInUse = errors.New("role is still assigned")
+)
+
+// TeamRole is a custom project role defined by a team. Members who have it
+// get exactly its permissions.
+type TeamRole struct {
+	ID          int64     `json:"id"`
+	TeamID      int64     `json:"team_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+const teamRoleSelectCols = `id, team_id, name, description, permissions, created_at, updated_at`
+
+func scanTeamRole(row interface{ Scan(...interface{}) error }) (*TeamRole, error) {
+	var r TeamRole
+	var permissions string
+	if err := row.Scan(&r.ID, &r.TeamID, &r.Name, &r.Description, &permissions, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	r.Permissions = []string{}
+	if permissions != "" {
+		r.Permissions = strings.Split(permissions, ",")
+	}
+	return &r, nil
+}
+
+// ListTeamRoles returns a team's custom roles by name.
+func (db *DB) ListTeamRoles(ctx context.Context, teamID int64) ([]TeamRole, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT `+teamRoleSelectCols+` FROM team_roles WHERE team_id = ? ORDER BY name`), teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []TeamRole{}
+	for rows.Next() {
+		r, err := scanTeamRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan team role: %w", err)
+		}
+		roles = append(roles, *r)
+	}
+	return roles, rows.Err()
+}
+
+// GetTeamRole returns one of a team's custom roles.
+func (db *DB) GetTeamRole(ctx context.Context, teamID, roleID int64) (*TeamRole, error) {
+	r, err := scanTeamRole(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+teamRoleSelectCols+` FROM team_roles WHERE team_id = ? AND id = ?`), teamID, roleID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTeamRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team role: %w", err)
+	}
+	return r, nil
+}
+
+// GetProjectTeamRole returns the custom role with a name in the team that
+// owns a project.
+func (db *DB) GetProjectTeamRole(ctx context.Context, projectID int64, name string) (*TeamRole, error) {
+	r, err := scanTeamRole(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT r.id, r.team_id, r.name, r.description, r.permissions, r.created_at, r.updated_at
+		 FROM team_roles r JOIN projects p ON p.team_id = r.team_id
+		 WHERE p.id = ? AND r.name = ?`), projectID, name))
+	if err == sql.ErrNoRows {
+		return nil, ErrTeamRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team role: %w", err)
+	}
+	return r, nil
+}
+
+// CreateTeamRole stores a new custom role.
+func (db *DB) CreateTeamRole(ctx context.Context, r *TeamRole) error {
+	if err := db.checkTeamRoleName(ctx, r.TeamID, 0, r.Name); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	r.CreatedAt, r.UpdatedAt = now, now
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO team_roles (team_id, name, description, permissions, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?) RETURNING id`),
+		r.TeamID, r.Name, r.Description, strings.Join(r.Permissions, ","), r.CreatedAt, r.UpdatedAt,
+	).Scan(&r.ID); err != nil {
+		return fmt.Errorf("failed to create team role: %w", err)
+	}
+	return nil
+}
+
+// UpdateTeamRole saves a custom role. Renaming it renames it for the members
+// and pending invitations of the team's projects too.
+func (db *DB) UpdateTeamRole(ctx context.Context, r *TeamRole) error {
+	existing, err := db.GetTeamRole(ctx, r.TeamID, r.ID)
+	if err != nil {
+		return err
+	}
+	if err := db.checkTeamRoleName(ctx, r.TeamID, r.ID, r.Name); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`UPDATE team_roles SET name = ?, description = ?, permissions = ?, updated_at = ? WHERE id = ?`),
+		r.Name, r.Description, strings.Join(r.Permissions, ","), r.UpdatedAt, r.ID); err != nil {
+		return fmt.Errorf("failed to update team role: %w", err)
+	}
+	if r.Name != existing.Name {
+		for _, table := range []string{"project_members", "project_invitations"} {
+			if _, err := tx.ExecContext(ctx, db.Rebind(
+				`UPDATE `+table+` SET role = ?
+				 WHERE role = ? AND project_id IN (SELECT id FROM projects WHERE team_id = ?)`),
+				r.Name, existing.Name, r.TeamID); err != nil {
+				return fmt.Errorf("failed to rename role in %s: %w", table, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteTeamRole removes a custom role nobody has.
+func (db *DB) DeleteTeamRole(ctx context.Context, teamID, roleID int64) error {
+	r, err := db.GetTeamRole(ctx, teamID, roleID)
+	if err != nil {
+		return err
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT (SELECT COUNT(*) FROM project_members m JOIN projects p ON p.id = m.project_id
+		         WHERE p.team_id = ? AND m.role = ?)
+		      + (SELECT COUNT(*) FROM project_invitations i JOIN projects p ON p.id = i.project_id
+		         WHERE p.team_id = ? AND i.role = ? AND i.status = 'pending')`),
+		teamID, r.Name, teamID, r.Name).Scan(&n); err != nil {
+		return fmt.Errorf("failed to count role assignments: %w", err)
+	}
+	if n > 0 {
+		return ErrTeamRoleInUse
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM team_roles WHERE id = ?`), roleID); err != nil {
+		return fmt.Errorf("failed to delete team role: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) checkTeamRoleName(ctx context.Context, teamID, roleID int64, name string) error {
+	var n int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*) FROM team_roles WHERE team_id = ? AND LOWER(name) = LOWER(?) AND id <> ?`),
+		teamID, name, roleID).Scan(&n); err != nil {
+		return fmt.Errorf("failed to query team roles: %w", err)
+	}
+	if n > 0 {
+		return ErrTeamRoleExists
+	}
+	return nil
+}
@@ -0,0 +1,1078 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSCIMTokenNotFound is returned for teams without a SCIM token and
+	// for tokens that don't match any team
+	ErrSCIMTokenNotFound = errors.New("scim token not found")
+	// ErrSCIMUserNotFound is returned for users who aren't team members
+	ErrSCIMUserNotFound = errors.New("scim user not found")
+	// ErrSCIMUserExists is returned when provisioning a user who is
+	// already a team member
+	ErrSCIMUserExists = errors.New("user is already a team member")
+	// ErrSCIMEmailTaken is returned when renaming a user to an email
+	// address another account has
+	ErrSCIMEmailTaken = errors.New("email address is already in use")
+	// ErrSCIMAccountOutsideDomains is returned when provisioning the email
+	// address of an existing account outside the team's verified domains
+	ErrSCIMAccountOutsideDomains = errors.New("an account outside the team's verified domains uses this email address")
+	// ErrSCIMTeamOwner is returned for changes to the team owner, who is
+	// outside the identity provider's control
+	ErrSCIMTeamOwner = errors.New("the team owner can't be changed through scim")
+	// ErrSCIMGroupNotFound is returned for groups that don't exist in the team
+	ErrSCIMGroupNotFound = errors.New("scim group not found")
+	// ErrSCIMGroupExists is returned for a group name already in use
+	ErrSCIMGroupExists = errors.New("scim group already exists")
+	// ErrSCIMInvalidMember is returned for group members who aren't team members
+	ErrSCIMInvalidMember = errors.New("group member is not a team member")
+	// ErrUserDeactivated is returned when a deactivated user tries to log in
+	ErrUserDeactivated = errors.New("user account is deactivated")
+)
+
+// scimTokenPrefix marks SCIM tokens, so they can't be mistaken for API keys
+const scimTokenPrefix = "scim_"
+
+// AuthProviderSCIM is the auth_provider of accounts created through SCIM
+const AuthProviderSCIM = "scim"
+
+// projectRoleRank orders the project roles a SCIM group can grant
+var projectRoleRank = map[string]int{"viewer": 1, "member": 2, "editor": 3}
+
+// ValidSCIMProjectRole reports whether a SCIM group can grant a project role
+func ValidSCIMProjectRole(role string) bool {
+	return projectRoleRank[role] > 0
+}
+
+// SCIMToken is a team's SCIM bearer token, without the secret
+type SCIMToken struct {
+	TeamID      int64      `json:"team_id"`
+	TokenPrefix string     `json:"token_prefix"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
+
+// SCIMTokenWithSecret includes the token itself (only returned on creation)
+type SCIMTokenWithSecret struct {
+	SCIMToken
+	Token string `json:"token"`
+}
+
+// CreateSCIMToken generates a team's SCIM token, replacing the old one.
+func (db *DB) CreateSCIMToken(ctx context.Context, teamID, createdBy int64) (*SCIMTokenWithSecret, error) {
+	key, _, _, err := GenerateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate scim token: %w", err)
+	}
+	token := scimTokenPrefix + key
+	t := SCIMTokenWithSecret{
+		SCIMToken: SCIMToken{TeamID: teamID, TokenPrefix: token[:12], CreatedAt: time.Now().UTC()},
+		Token:     token,
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`INSERT INTO team_scim_tokens (team_id, token_hash, token_prefix, created_by, created_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT (team_id) DO UPDATE SET token_hash = excluded.token_hash, token_prefix = excluded.token_prefix,
+			created_by = excluded.created_by, created_at = excluded.created_at, last_used_at = NULL`),
+		teamID, HashAPIKey(token), t.TokenPrefix, createdBy, t.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to save scim token: %w", err)
+	}
+	return &t, nil
+}
+
+// GetSCIMToken returns a team's SCIM token.
+func (db *DB) GetSCIMToken(ctx context.Context, teamID int64) (*SCIMToken, error) {
+	t := SCIMToken{TeamID: teamID}
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT token_prefix, created_at, last_used_at FROM team_scim_tokens WHERE team_id = ?`), teamID,
+	).Scan(&t.TokenPrefix, &t.CreatedAt, &t.LastUsedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrSCIMTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim token: %w", err)
+	}
+	return &t, nil
+}
+
+// DeleteSCIMToken revokes a team's SCIM token. Provisioned users and groups
+// are kept.
+func (db *DB) DeleteSCIMToken(ctx context.Context, teamID int64) error {
+	res, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM team_scim_tokens WHERE team_id = ?`), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scim token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSCIMTokenNotFound
+	}
+	return nil
+}
+
+// AuthenticateSCIMToken returns the team a SCIM token belongs to.
+func (db *DB) AuthenticateSCIMToken(ctx context.Context, token string) (int64, error) {
+	if !strings.HasPrefix(token, scimTokenPrefix) {
+		return 0, ErrSCIMTokenNotFound
+	}
+	var teamID int64
+	err := db.QueryRowContext(ctx, db.Rebind(`SELECT team_id FROM team_scim_tokens WHERE token_hash = ?`),
+		HashAPIKey(token)).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, ErrSCIMTokenNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to validate scim token: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`UPDATE team_scim_tokens SET last_used_at = ? WHERE team_id = ?`),
+		time.Now().UTC(), teamID); err != nil {
+		return 0, fmt.Errorf("failed to update scim token: %w", err)
+	}
+	return teamID, nil
+}
+
+// IsUserDeactivated reports whether a user's account was deactivated by the
+// identity provider that manages it.
+func (db *DB) IsUserDeactivated(ctx context.Context, userID int64) (bool, error) {
+	var deactivatedAt *time.Time
+	err := db.QueryRowContext(ctx, db.Rebind(`SELECT deactivated_at FROM users WHERE id = ? LIMIT 1`), userID).Scan(&deactivatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query user status: %w", err)
+	}
+	return deactivatedAt != nil, nil
+}
+
+// SCIMUser is a team member as the team's identity provider sees them
+type SCIMUser struct {
+	ID         int64
+	Email      string
+	FirstName  string
+	LastName   string
+	ExternalID string
+	Role       string
+	Active     bool
+	// Managed is set for accounts the identity provider created, whose
+	// profile it controls. Other accounts only have their membership
+	// managed.
+	Managed   bool
+	Groups    []SCIMGroupRef
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SCIMGroupRef names a group a user is in
+type SCIMGroupRef struct {
+	ID          int64
+	DisplayName string
+}
+
+// SCIMUserInput is what an identity provider sets on a user
+type SCIMUserInput struct {
+	Email      string
+	FirstName  string
+	LastName   string
+	ExternalID string
+	Active     bool
+}
+
+// SCIMQuery selects a page of users or groups. Email and DisplayName match
+// case-insensitively, ExternalID exactly.
+type SCIMQuery struct {
+	Email       string
+	DisplayName string
+	ExternalID  string
+	Offset      int
+	// Limit is the page size; negative for no limit
+	Limit int
+}
+
+func (q SCIMQuery) page(query string, args []interface{}) (string, []interface{}) {
+	if q.Limit < 0 {
+		return query, args
+	}
+	return query + ` LIMIT ? OFFSET ?`, append(args, q.Limit, q.Offset)
+}
+
+const scimUserSelect = `SELECT u.id, u.email, COALESCE(u.first_name, ''), COALESCE(u.last_name, ''), tm.scim_external_id,
+	tm.role, tm.status, COALESCE(u.managed_by_team_id, 0), u.created_at, u.updated_at
+	FROM team_members tm JOIN users u ON u.id = tm.user_id
+	WHERE tm.team_id = ? AND u.deleted_at IS NULL`
+
+func scanSCIMUser(row interface{ Scan(...interface{}) error }, teamID int64) (*SCIMUser, error) {
+	var u SCIMUser
+	var status string
+	var managedBy int64
+	if err := row.Scan(&u.ID, &u.Email, &u.FirstName, &u.LastName, &u.ExternalID,
+		&u.Role, &status, &managedBy, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	u.Active = status == "active"
+	u.Managed = managedBy == teamID
+	u.Groups = []SCIMGroupRef{}
+	return &u, nil
+}
+
+// ListSCIMUsers returns a page of a team's members and the total number
+// matching the query.
+func (db *DB) ListSCIMUsers(ctx context.Context, teamID int64, q SCIMQuery) ([]SCIMUser, int, error) {
+	where := ""
+	args := []interface{}{teamID}
+	if q.Email != "" {
+		where += ` AND LOWER(u.email) = LOWER(?)`
+		args = append(args, q.Email)
+	}
+	if q.ExternalID != "" {
+		where += ` AND tm.scim_external_id = ?`
+		args = append(args, q.ExternalID)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*) FROM team_members tm JOIN users u ON u.id = tm.user_id
+		 WHERE tm.team_id = ? AND u.deleted_at IS NULL`+where), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim users: %w", err)
+	}
+
+	query, args := q.page(scimUserSelect+where+` ORDER BY u.id`, args)
+	rows, err := db.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query scim users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []SCIMUser{}
+	for rows.Next() {
+		u, err := scanSCIMUser(rows, teamID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scim user: %w", err)
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	groups, err := db.scimUserGroups(ctx, teamID)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range users {
+		if g, ok := groups[users[i].ID]; ok {
+			users[i].Groups = g
+		}
+	}
+	return users, total, nil
+}
+
+// GetSCIMUser returns a team member.
+func (db *DB) GetSCIMUser(ctx context.Context, teamID, userID int64) (*SCIMUser, error) {
+	u, err := scanSCIMUser(db.QueryRowContext(ctx, db.Rebind(scimUserSelect+` AND u.id = ?`), teamID, userID), teamID)
+	if err == sql.ErrNoRows {
+		return nil, ErrSCIMUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim user: %w", err)
+	}
+	groups, err := db.scimUserGroups(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if g, ok := groups[u.ID]; ok {
+		u.Groups = g
+	}
+	return u, nil
+}
+
+// scimUserGroups returns the groups of each of a team's users
+func (db *DB) scimUserGroups(ctx context.Context, teamID int64) (map[int64][]SCIMGroupRef, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT m.user_id, g.id, g.display_name FROM scim_group_members m
+		 JOIN scim_groups g ON g.id = m.group_id
+		 WHERE g.team_id = ? ORDER BY g.display_name`), teamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim user groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := map[int64][]SCIMGroupRef{}
+	for rows.Next() {
+		var userID int64
+		var g SCIMGroupRef
+		if err := rows.Scan(&userID, &g.ID, &g.DisplayName); err != nil {
+			return nil, fmt.Errorf("failed to scan scim user group: %w", err)
+		}
+		groups[userID] = append(groups[userID], g)
+	}
+	return groups, rows.Err()
+}
+
+// CreateSCIMUser provisions a user into a team. Someone without an account
+// gets one that the identity provider manages, along with a personal team
+// like every user. An existing account joins the team but stays its
+// owner's: the identity provider only controls its membership. Only
+// accounts in one of the team's verified single sign-on domains can be
+// adopted like that; others fail with ErrSCIMAccountOutsideDomains.
+func (db *DB) CreateSCIMUser(ctx context.Context, teamID int64, in SCIMUserInput) (*SCIMUser, error) {
+	var userID int64
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT id FROM users WHERE LOWER(email) = LOWER(?) AND deleted_at IS NULL LIMIT 1`), in.Email).Scan(&userID)
+	switch {
+	case err == sql.ErrNoRows:
+		if userID, err = db.createSCIMAccount(ctx, teamID, in); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, fmt.Errorf("failed to query user: %w", err)
+	default:
+		if _, err := db.GetSCIMUser(ctx, teamID, userID); err == nil {
+			return nil, ErrSCIMUserExists
+		} else if err != ErrSCIMUserNotFound {
+			return nil, err
+		}
+		inDomain, err := db.teamVerifiedEmail(ctx, teamID, in.Email)
+		if err != nil {
+			return nil, err
+		}
+		if !inDomain {
+			return nil, ErrSCIMAccountOutsideDomains
+		}
+		if _, err := db.Client.TeamMember.Create().
+			SetTeamID(teamID).
+			SetUserID(userID).
+			SetRole("member").
+			SetStatus("active").
+			Save(ctx); err != nil {
+			return nil, fmt.Errorf("failed to add team member: %w", err)
+		}
+		// A managed account removed from the team earlier was left deactivated
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE users SET deactivated_at = NULL WHERE id = ? AND managed_by_team_id = ?`), userID, teamID); err != nil {
+			return nil, fmt.Errorf("failed to reactivate user: %w", err)
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE team_members SET scim_external_id = ? WHERE team_id = ? AND user_id = ?`),
+		in.ExternalID, teamID, userID); err != nil {
+		return nil, fmt.Errorf("failed to set external id: %w", err)
+	}
+
+	u, err := db.GetSCIMUser(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !in.Active {
+		if err := db.setSCIMUserActive(ctx, teamID, u, false); err != nil {
+			return nil, err
+		}
+		return db.GetSCIMUser(ctx, teamID, userID)
+	}
+	return u, nil
+}
+
+// createSCIMAccount creates an account managed by a team's identity
+// provider, with a personal team, as a member of the provider's team
+func (db *DB) createSCIMAccount(ctx context.Context, teamID int64, in SCIMUserInput) (int64, error) {
+	// The account has no usable password; its user logs in through the
+	// team's single sign-on or sets one with a password reset
+	fakeHash, err := randomPlaceholderHash()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Client.Tx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	userCreate := tx.User.Create().
+		SetEmail(in.Email).
+		SetPasswordHash(fakeHash)
+	if in.FirstName != "" {
+		userCreate.SetFirstName(in.FirstName)
+	}
+	if in.LastName != "" {
+		userCreate.SetLastName(in.LastName)
+	}
+	displayName := strings.TrimSpace(in.FirstName + " " + in.LastName)
+	if displayName != "" {
+		userCreate.SetName(displayName)
+	} else {
+		displayName = in.Email
+	}
+	newUser, err := userCreate.Save(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	personal, err := tx.Team.Create().
+		SetName(displayName + "'s Team").
+		SetOwnerID(newUser.ID).
+		Save(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create team: %w", err)
+	}
+	if _, err := tx.TeamMember.Create().
+		SetTeamID(personal.ID).
+		SetUserID(newUser.ID).
+		SetRole("owner").
+		SetStatus("active").
+		Save(ctx); err != nil {
+		return 0, fmt.Errorf("failed to add personal team member: %w", err)
+	}
+	if _, err := tx.TeamMember.Create().
+		SetTeamID(teamID).
+		SetUserID(newUser.ID).
+		SetRole("member").
+		SetStatus("active").
+		Save(ctx); err != nil {
+		return 0, fmt.Errorf("failed to add team member: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit user: %w", err)
+	}
+
+	// Not in the ent schema
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE users SET auth_provider = ?, managed_by_team_id = ? WHERE id = ?`),
+		AuthProviderSCIM, teamID, newUser.ID); err != nil {
+		return 0, fmt.Errorf("failed to mark user as provisioned: %w", err)
+	}
+	return newUser.ID, nil
+}
+
+// UpdateSCIMUser replaces what the identity provider controls of a team
+// member: the external ID and whether they are active, and for accounts it
+// manages, the email address and name.
+func (db *DB) UpdateSCIMUser(ctx context.Context, teamID, userID int64, in SCIMUserInput) (*SCIMUser, error) {
+	u, err := db.GetSCIMUser(ctx, teamID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == "owner" && !in.Active {
+		return nil, ErrSCIMTeamOwner
+	}
+
+	if in.ExternalID != u.ExternalID {
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE team_members SET scim_external_id = ? WHERE team_id = ? AND user_id = ?`),
+			in.ExternalID, teamID, userID); err != nil {
+			return nil, fmt.Errorf("failed to set external id: %w", err)
+		}
+	}
+
+	if u.Managed && (!strings.EqualFold(in.Email, u.Email) || in.FirstName != u.FirstName || in.LastName != u.LastName) {
+		if !strings.EqualFold(in.Email, u.Email) {
+			var n int
+			if err := db.QueryRowContext(ctx, db.Rebind(
+				`SELECT COUNT(*) FROM users WHERE LOWER(email) = LOWER(?) AND id <> ?`), in.Email, userID).Scan(&n); err != nil {
+				return nil, fmt.Errorf("failed to query user: %w", err)
+			}
+			if n > 0 {
+				return nil, ErrSCIMEmailTaken
+			}
+		}
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE users SET email = ?, first_name = ?, last_name = ?, name = ?, updated_at = ? WHERE id = ?`),
+			in.Email, in.FirstName, in.LastName, strings.TrimSpace(in.FirstName+" "+in.LastName), time.Now().UTC(), userID); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	if in.Active != u.Active {
+		if err := db.setSCIMUserActive(ctx, teamID, u, in.Active); err != nil {
+			return nil, err
+		}
+	}
+	return db.GetSCIMUser(ctx, teamID, userID)
+}
+
+// DeleteSCIMUser removes a user from the team and its groups. An account
+// the identity provider manages is deactivated rather than deleted, so its
+// work in the team keeps an author.
+func (db *DB) DeleteSCIMUser(ctx context.Context, teamID, userID int64) error {
+	u, err := db.GetSCIMUser(ctx, teamID, userID)
+	if err != nil {
+		return err
+	}
+	if u.Role == "owner" {
+		return ErrSCIMTeamOwner
+	}
+	if err := db.setSCIMUserActive(ctx, teamID, u, false); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`DELETE FROM scim_group_members WHERE user_id = ? AND group_id IN (SELECT id FROM scim_groups WHERE team_id = ?)`),
+		userID, teamID); err != nil {
+		return fmt.Errorf("failed to remove group memberships: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM team_members WHERE team_id = ? AND user_id = ?`),
+		teamID, userID); err != nil {
+		return fmt.Errorf("failed to remove team member: %w", err)
+	}
+	return nil
+}
+
+// setSCIMUserActive deactivates or reactivates a team member.
+//
+// Deactivating takes away the team and its projects at once: the member's
+// sessions end and API keys limited to the team's projects are deleted. An
+// account the identity provider manages is locked as well: all its API
+// keys are deleted and it can no longer log in. Reactivating restores the
+// team and the project access the user's groups grant; access that was
+// granted by hand has to be granted again.
+func (db *DB) setSCIMUserActive(ctx context.Context, teamID int64, u *SCIMUser, active bool) error {
+	if u.Role == "owner" {
+		return ErrSCIMTeamOwner
+	}
+
+	if active {
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE team_members SET status = 'active' WHERE team_id = ? AND user_id = ?`), teamID, u.ID); err != nil {
+			return fmt.Errorf("failed to reactivate team member: %w", err)
+		}
+		if u.Managed {
+			if _, err := db.ExecContext(ctx, db.Rebind(`UPDATE users SET deactivated_at = NULL WHERE id = ?`), u.ID); err != nil {
+				return fmt.Errorf("failed to reactivate user: %w", err)
+			}
+		}
+		return db.syncSCIMAccess(ctx, teamID, u.ID)
+	}
+
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE team_members SET status = 'inactive' WHERE team_id = ? AND user_id = ?`), teamID, u.ID); err != nil {
+		return fmt.Errorf("failed to deactivate team member: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`DELETE FROM project_members WHERE user_id = ? AND role <> 'owner'
+		 AND project_id IN (SELECT id FROM projects WHERE team_id = ?)`), u.ID, teamID); err != nil {
+		return fmt.Errorf("failed to remove project access: %w", err)
+	}
+	if _, err := db.RevokeUserSessions(ctx, u.ID, 0); err != nil {
+		return err
+	}
+	if !u.Managed {
+		return db.deleteTeamAPIKeys(ctx, teamID, u.ID)
+	}
+
+	if _, err := db.ExecContext(ctx, db.Rebind(`UPDATE users SET deactivated_at = ? WHERE id = ?`),
+		time.Now().UTC(), u.ID); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM api_keys WHERE user_id = ?`), u.ID); err != nil {
+		return fmt.Errorf("failed to delete api keys: %w", err)
+	}
+	return nil
+}
+
+// deleteTeamAPIKeys deletes a user's API keys that are limited to projects
+// including one of the team's. Keys for all of the user's projects stay;
+// they lose the team's projects along with the membership.
+func (db *DB) deleteTeamAPIKeys(ctx context.Context, teamID, userID int64) error {
+	rows, err := db.QueryContext(ctx, db.Rebind(`SELECT id FROM projects WHERE team_id = ?`), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to query team projects: %w", err)
+	}
+	teamProjects := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan team project: %w", err)
+		}
+		teamProjects[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	grants, err := db.apiKeyGrants(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for keyID, g := range grants {
+		var key APIKey
+		g.apply(&key)
+		if !slices.ContainsFunc(key.ProjectIDs, func(id int64) bool { return teamProjects[id] }) {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM api_keys WHERE id = ?`), keyID); err != nil {
+			return fmt.Errorf("failed to delete api key: %w", err)
+		}
+	}
+	return nil
+}
+
+// SCIMGroup is a group pushed by a team's identity provider, and what its
+// members get in the team
+type SCIMGroup struct {
+	ID          int64              `json:"id"`
+	TeamID      int64              `json:"team_id"`
+	DisplayName string             `json:"display_name"`
+	ExternalID  string             `json:"external_id"`
+	TeamRole    string             `json:"team_role"`
+	Members     []SCIMGroupMember  `json:"members"`
+	Projects    []SCIMGroupProject `json:"projects"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// SCIMGroupMember is a user in a SCIM group
+type SCIMGroupMember struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// SCIMGroupProject is a project a SCIM group's members get access to
+type SCIMGroupProject struct {
+	ProjectID   int64  `json:"project_id"`
+	ProjectName string `json:"project_name,omitempty"`
+	Role        string `json:"role"`
+}
+
+const scimGroupSelect = `SELECT id, team_id, display_name, external_id, team_role, created_at, updated_at
+	FROM scim_groups WHERE team_id = ?`
+
+func scanSCIMGroup(row interface{ Scan(...interface{}) error }) (*SCIMGroup, error) {
+	g := SCIMGroup{Members: []SCIMGroupMember{}, Projects: []SCIMGroupProject{}}
+	if err := row.Scan(&g.ID, &g.TeamID, &g.DisplayName, &g.ExternalID, &g.TeamRole, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// ListSCIMGroups returns a page of a team's SCIM groups and the total
+// number matching the query.
+func (db *DB) ListSCIMGroups(ctx context.Context, teamID int64, q SCIMQuery) ([]SCIMGroup, int, error) {
+	where := ""
+	args := []interface{}{teamID}
+	if q.DisplayName != "" {
+		where += ` AND LOWER(display_name) = LOWER(?)`
+		args = append(args, q.DisplayName)
+	}
+	if q.ExternalID != "" {
+		where += ` AND external_id = ?`
+		args = append(args, q.ExternalID)
+	}
+
+	var total int
+	if err := db.QueryRowContext(ctx, db.Rebind(`SELECT COUNT(*) FROM scim_groups WHERE team_id = ?`+where),
+		args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scim groups: %w", err)
+	}
+
+	query, args := q.page(scimGroupSelect+where+` ORDER BY id`, args)
+	rows, err := db.QueryContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query scim groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []SCIMGroup{}
+	for rows.Next() {
+		g, err := scanSCIMGroup(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan scim group: %w", err)
+		}
+		groups = append(groups, *g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := db.loadSCIMGroupDetails(ctx, teamID, groups); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+// GetSCIMGroup returns one of a team's SCIM groups.
+func (db *DB) GetSCIMGroup(ctx context.Context, teamID, groupID int64) (*SCIMGroup, error) {
+	g, err := scanSCIMGroup(db.QueryRowContext(ctx, db.Rebind(scimGroupSelect+` AND id = ?`), teamID, groupID))
+	if err == sql.ErrNoRows {
+		return nil, ErrSCIMGroupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scim group: %w", err)
+	}
+	groups := []SCIMGroup{*g}
+	if err := db.loadSCIMGroupDetails(ctx, teamID, groups); err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+// loadSCIMGroupDetails fills in the members and projects of groups
+func (db *DB) loadSCIMGroupDetails(ctx context.Context, teamID int64, groups []SCIMGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	index := make(map[int64]*SCIMGroup, len(groups))
+	for i := range groups {
+		index[groups[i].ID] = &groups[i]
+	}
+
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT m.group_id, u.id, u.email FROM scim_group_members m
+		 JOIN scim_groups g ON g.id = m.group_id
+		 JOIN users u ON u.id = m.user_id
+		 WHERE g.team_id = ? ORDER BY u.id`), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to query scim group members: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupID int64
+		var m SCIMGroupMember
+		if err := rows.Scan(&groupID, &m.UserID, &m.Email); err != nil {
+			return fmt.Errorf("failed to scan scim group member: %w", err)
+		}
+		if g, ok := index[groupID]; ok {
+			g.Members = append(g.Members, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	projRows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT gp.group_id, p.id, p.name, gp.role FROM scim_group_projects gp
+		 JOIN scim_groups g ON g.id = gp.group_id
+		 JOIN projects p ON p.id = gp.project_id
+		 WHERE g.team_id = ? ORDER BY p.name`), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to query scim group projects: %w", err)
+	}
+	defer projRows.Close()
+	for projRows.Next() {
+		var groupID int64
+		var p SCIMGroupProject
+		if err := projRows.Scan(&groupID, &p.ProjectID, &p.ProjectName, &p.Role); err != nil {
+			return fmt.Errorf("failed to scan scim group project: %w", err)
+		}
+		if g, ok := index[groupID]; ok {
+			g.Projects = append(g.Projects, p)
+		}
+	}
+	return projRows.Err()
+}
+
+// CreateSCIMGroup creates a group of team members. New groups grant the
+// member role and no projects until the team owner maps them.
+func (db *DB) CreateSCIMGroup(ctx context.Context, teamID int64, displayName, externalID string, memberIDs []int64) (*SCIMGroup, error) {
+	if err := db.checkSCIMGroupName(ctx, teamID, 0, displayName); err != nil {
+		return nil, err
+	}
+	if err := db.checkSCIMGroupMembers(ctx, teamID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var groupID int64
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO scim_groups (team_id, display_name, external_id, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?) RETURNING id`),
+		teamID, displayName, externalID, now, now).Scan(&groupID); err != nil {
+		return nil, fmt.Errorf("failed to create scim group: %w", err)
+	}
+	if err := db.setSCIMGroupMembers(ctx, teamID, groupID, memberIDs); err != nil {
+		return nil, err
+	}
+	return db.GetSCIMGroup(ctx, teamID, groupID)
+}
+
+// UpdateSCIMGroup renames a group and replaces its members, then brings
+// the access of everyone who joined or left it up to date.
+func (db *DB) UpdateSCIMGroup(ctx context.Context, teamID, groupID int64, displayName, externalID string, memberIDs []int64) (*SCIMGroup, error) {
+	if _, err := db.GetSCIMGroup(ctx, teamID, groupID); err != nil {
+		return nil, err
+	}
+	if err := db.checkSCIMGroupName(ctx, teamID, groupID, displayName); err != nil {
+		return nil, err
+	}
+	if err := db.checkSCIMGroupMembers(ctx, teamID, memberIDs); err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE scim_groups SET display_name = ?, external_id = ?, updated_at = ? WHERE id = ?`),
+		displayName, externalID, time.Now().UTC(), groupID); err != nil {
+		return nil, fmt.Errorf("failed to update scim group: %w", err)
+	}
+	if err := db.setSCIMGroupMembers(ctx, teamID, groupID, memberIDs); err != nil {
+		return nil, err
+	}
+	return db.GetSCIMGroup(ctx, teamID, groupID)
+}
+
+// DeleteSCIMGroup deletes a group and takes back what it granted.
+func (db *DB) DeleteSCIMGroup(ctx context.Context, teamID, groupID int64) error {
+	g, err := db.GetSCIMGroup(ctx, teamID, groupID)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM scim_group_members WHERE group_id = ?`), groupID); err != nil {
+		return fmt.Errorf("failed to delete scim group members: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM scim_group_projects WHERE group_id = ?`), groupID); err != nil {
+		return fmt.Errorf("failed to delete scim group projects: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM scim_groups WHERE id = ?`), groupID); err != nil {
+		return fmt.Errorf("failed to delete scim group: %w", err)
+	}
+	for _, m := range g.Members {
+		if err := db.syncSCIMAccess(ctx, teamID, m.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetSCIMGroupMapping sets the team role and project access a group's
+// members get, and applies it to them.
+func (db *DB) SetSCIMGroupMapping(ctx context.Context, teamID, groupID int64, teamRole string, projects []SCIMGroupProject) (*SCIMGroup, error) {
+	g, err := db.GetSCIMGroup(ctx, teamID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE scim_groups SET team_role = ?, updated_at = ? WHERE id = ?`), teamRole, time.Now().UTC(), groupID); err != nil {
+		return nil, fmt.Errorf("failed to update scim group: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM scim_group_projects WHERE group_id = ?`), groupID); err != nil {
+		return nil, fmt.Errorf("failed to update scim group projects: %w", err)
+	}
+	for _, p := range projects {
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`INSERT INTO scim_group_projects (group_id, project_id, role) VALUES (?, ?, ?)`),
+			groupID, p.ProjectID, p.Role); err != nil {
+			return nil, fmt.Errorf("failed to update scim group projects: %w", err)
+		}
+	}
+	for _, m := range g.Members {
+		if err := db.syncSCIMAccess(ctx, teamID, m.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return db.GetSCIMGroup(ctx, teamID, groupID)
+}
+
+func (db *DB) checkSCIMGroupName(ctx context.Context, teamID, groupID int64, displayName string) error {
+	var n int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*) FROM scim_groups WHERE team_id = ? AND LOWER(display_name) = LOWER(?) AND id <> ?`),
+		teamID, displayName, groupID).Scan(&n); err != nil {
+		return fmt.Errorf("failed to query scim groups: %w", err)
+	}
+	if n > 0 {
+		return ErrSCIMGroupExists
+	}
+	return nil
+}
+
+func (db *DB) checkSCIMGroupMembers(ctx context.Context, teamID int64, memberIDs []int64) error {
+	for _, userID := range memberIDs {
+		var n int
+		if err := db.QueryRowContext(ctx, db.Rebind(
+			`SELECT COUNT(*) FROM team_members WHERE team_id = ? AND user_id = ?`), teamID, userID).Scan(&n); err != nil {
+			return fmt.Errorf("failed to query team member: %w", err)
+		}
+		if n == 0 {
+			return ErrSCIMInvalidMember
+		}
+	}
+	return nil
+}
+
+// setSCIMGroupMembers replaces a group's members and syncs the access of
+// everyone who joined or left
+func (db *DB) setSCIMGroupMembers(ctx context.Context, teamID, groupID int64, memberIDs []int64) error {
+	rows, err := db.QueryContext(ctx, db.Rebind(`SELECT user_id FROM scim_group_members WHERE group_id = ?`), groupID)
+	if err != nil {
+		return fmt.Errorf("failed to query scim group members: %w", err)
+	}
+	current := map[int64]bool{}
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan scim group member: %w", err)
+		}
+		current[userID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	wanted := map[int64]bool{}
+	var changed []int64
+	for _, userID := range memberIDs {
+		if wanted[userID] {
+			continue
+		}
+		wanted[userID] = true
+		if !current[userID] {
+			if _, err := db.ExecContext(ctx, db.Rebind(
+				`INSERT INTO scim_group_members (group_id, user_id) VALUES (?, ?)`), groupID, userID); err != nil {
+				return fmt.Errorf("failed to add scim group member: %w", err)
+			}
+			changed = append(changed, userID)
+		}
+	}
+	for userID := range current {
+		if !wanted[userID] {
+			if _, err := db.ExecContext(ctx, db.Rebind(
+				`DELETE FROM scim_group_members WHERE group_id = ? AND user_id = ?`), groupID, userID); err != nil {
+				return fmt.Errorf("failed to remove scim group member: %w", err)
+			}
+			changed = append(changed, userID)
+		}
+	}
+
+	for _, userID := range changed {
+		if err := db.syncSCIMAccess(ctx, teamID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncSCIMAccess brings a team member's role and project access in line
+// with their SCIM groups. Members of any group are admins if one of their
+// groups grants admin, and members otherwise. Leaving the last group makes
+// them a member; users who were never in a group keep the role they were
+// given by hand. Project access from groups is added and taken back as
+// groups change, without touching access granted by hand. The team owner
+// and deactivated members are left alone.
+func (db *DB) syncSCIMAccess(ctx context.Context, teamID, userID int64) error {
+	var role, status string
+	var scimRole bool
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT role, status, scim_role FROM team_members WHERE team_id = ? AND user_id = ?`), teamID, userID).Scan(&role, &status, &scimRole)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query team member: %w", err)
+	}
+	if role == "owner" || status != "active" {
+		return nil
+	}
+
+	var groups, admin int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*), COALESCE(SUM(CASE WHEN g.team_role = 'admin' THEN 1 ELSE 0 END), 0)
+		 FROM scim_group_members m JOIN scim_groups g ON g.id = m.group_id
+		 WHERE g.team_id = ? AND m.user_id = ?`), teamID, userID).Scan(&groups, &admin); err != nil {
+		return fmt.Errorf("failed to query scim groups: %w", err)
+	}
+	newRole, fromGroups := role, groups > 0
+	switch {
+	case fromGroups && admin > 0:
+		newRole = "admin"
+	case fromGroups || scimRole:
+		newRole = "member"
+	}
+	if newRole != role || fromGroups != scimRole {
+		if _, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE team_members SET role = ?, scim_role = ? WHERE team_id = ? AND user_id = ?`),
+			newRole, fromGroups, teamID, userID); err != nil {
+			return fmt.Errorf("failed to update team role: %w", err)
+		}
+	}
+
+	// The highest role any of the user's groups grants on each project
+	wanted := map[int64]string{}
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT gp.project_id, gp.role FROM scim_group_projects gp
+		 JOIN scim_group_members m ON m.group_id = gp.group_id
+		 JOIN scim_groups g ON g.id = gp.group_id
+		 WHERE g.team_id = ? AND m.user_id = ?`), teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to query scim group projects: %w", err)
+	}
+	for rows.Next() {
+		var projectID int64
+		var projectRole string
+		if err := rows.Scan(&projectID, &projectRole); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan scim group project: %w", err)
+		}
+		if projectRoleRank[projectRole] > projectRoleRank[wanted[projectID]] {
+			wanted[projectID] = projectRole
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	type grant struct {
+		role    string
+		managed bool
+	}
+	existing := map[int64]grant{}
+	rows, err = db.QueryContext(ctx, db.Rebind(
+		`SELECT pm.project_id, pm.role, pm.scim_managed FROM project_members pm
+		 JOIN projects p ON p.id = pm.project_id
+		 WHERE p.team_id = ? AND pm.user_id = ?`), teamID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to query project members: %w", err)
+	}
+	for rows.Next() {
+		var projectID int64
+		var g grant
+		if err := rows.Scan(&projectID, &g.role, &g.managed); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan project member: %w", err)
+		}
+		existing[projectID] = g
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Access is granted in the owner's name, as the identity provider has no user
+	var ownerID int64
+	if err := db.QueryRowContext(ctx, db.Rebind(`SELECT owner_id FROM teams WHERE id = ?`), teamID).Scan(&ownerID); err != nil {
+		return fmt.Errorf("failed to query team owner: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for projectID, projectRole := range wanted {
+		g, ok := existing[projectID]
+		switch {
+		case !ok:
+			_, err = db.ExecContext(ctx, db.Rebind(
+				`INSERT INTO project_members (project_id, user_id, role, granted_by, granted_at, scim_managed)
+				 VALUES (?, ?, ?, ?, ?, ?)`), projectID, userID, projectRole, ownerID, now, true)
+		case g.managed && g.role != projectRole:
+			_, err = db.ExecContext(ctx, db.Rebind(
+				`UPDATE project_members SET role = ? WHERE project_id = ? AND user_id = ?`), projectRole, projectID, userID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to grant project access: %w", err)
+		}
+	}
+	for projectID, g := range existing {
+		if _, ok := wanted[projectID]; g.managed && !ok {
+			if _, err := db.ExecContext(ctx, db.Rebind(
+				`DELETE FROM project_members WHERE project_id = ? AND user_id = ? AND scim_managed`), projectID, userID); err != nil {
+				return fmt.Errorf("failed to remove project access: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"taskai/internal/secrets"
+)
+
+// SecretColumn is a column holding a third-party secret. Values are stored
+// encrypted when a keyring is configured.
+type SecretColumn struct {
+	Table  string
+	Key    string // primary key column
+	Column string
+}
+
+// SecretColumns lists every stored third-party secret
+var SecretColumns = []SecretColumn{
+	{"projects", "id", "github_token"},
+	{"projects", "id", "github_webhook_secret"},
+	{"cloudinary_credentials", "id", "api_secret"},
+	{"figma_credentials", "id", "access_token"},
+	{"email_provider", "id", "api_key"},
+	{"project_webhooks", "id", "secret"},
+	{"team_sso_configs", "team_id", "oidc_client_secret"},
+}
+
+// SetSecretKeyring sets the keyring secrets are encrypted with. Without one
+// secrets are stored as they are.
+func (db *DB) SetSecretKeyring(k *secrets.Keyring) {
+	db.secrets = k
+}
+
+// EncryptSecret prepares a secret for storage.
+func (db *DB) EncryptSecret(value string) (string, error) {
+	return db.secrets.Encrypt(value)
+}
+
+// DecryptSecret reads a stored secret. Secrets stored before encryption was
+// enabled are returned as they are.
+func (db *DB) DecryptSecret(value string) (string, error) {
+	return db.secrets.Decrypt(value)
+}
+
+// SealSecret returns a stored secret in encrypted form, encrypting it if it
+// was stored before encryption was enabled. It returns secrets.ErrNoKey for
+// unencrypted values when no keyring is configured.
+func (db *DB) SealSecret(value string) (string, error) {
+	if value == "" || secrets.IsEncrypted(value) {
+		return value, nil
+	}
+	return db.secrets.Rotate(value)
+}
+
+// EncryptStoredSecrets encrypts the secrets stored before encryption was
+// enabled. With rotate set it also re-seals every secret encrypted with a
+// previous master key, so the previous keys can be retired. It returns how
+// many values were rewritten.
+func (db *DB) EncryptStoredSecrets(ctx context.Context, rotate bool) (int, error) {
+	if db.secrets == nil {
+		return 0, secrets.ErrNoKey
+	}
+	total := 0
+	for _, col := range SecretColumns {
+		n, err := db.encryptSecretColumn(ctx, col, rotate)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("failed to encrypt %s.%s: %w", col.Table, col.Column, err)
+		}
+		if n > 0 && db.logger != nil {
+			db.logger.Info("Encrypted stored secrets",
+				zap.String("table", col.Table), zap.String("column", col.Column), zap.Int("rows", n))
+		}
+	}
+	return total, nil
+}
+
+func (db *DB) encryptSecretColumn(ctx context.Context, col SecretColumn, rotate bool) (int, error) {
+	type secretRow struct {
+		key   interface{}
+		value string
+	}
+
+	// Read the whole column first: SQLite has a single connection
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s <> ''`,
+		col.Key, col.Column, col.Table, col.Column, col.Column))
+	if err != nil {
+		return 0, err
+	}
+	var pending []secretRow
+	for rows.Next() {
+		var r secretRow
+		if err := rows.Scan(&r.key, &r.value); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if secrets.IsEncrypted(r.value) && (!rotate || !db.secrets.NeedsRotation(r.value)) {
+			continue
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, r := range pending {
+		sealed, err := db.secrets.Rotate(r.value)
+		if err != nil {
+			return n, err
+		}
+		// Skip rows changed since they were read
+		res, err := db.ExecContext(ctx, db.Rebind(fmt.Sprintf(
+			`UPDATE %s SET %s = ? WHERE %s = ? AND %s = ?`, col.Table, col.Column, col.Key, col.Column)),
+			sealed, r.key, r.value)
+		if err != nil {
+			return n, err
+		}
+		if affected, _ := res.RowsAffected(); affected > 0 {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"taskai/internal/secrets"
+)
+
+func TestEncryptStoredSecrets(t *testing.T) {
+	database := newTestDB(t)
+	defer database.Close()
+	ctx := context.Background()
+
+	if _, err := database.EncryptStoredSecrets(ctx, false); !errors.Is(err, secrets.ErrNoKey) {
+		t.Fatalf("Expected ErrNoKey without a keyring, got %v", err)
+	}
+
+	userID := createTestUser(t, database, "figma@example.com")
+	if _, err := database.ExecContext(ctx,
+		`INSERT INTO figma_credentials (user_id, access_token) VALUES (?, ?)`, userID, "figd_legacy"); err != nil {
+		t.Fatalf("Failed to store token: %v", err)
+	}
+	stored := func() string {
+		var token string
+		if err := database.QueryRowContext(ctx,
+			`SELECT access_token FROM figma_credentials WHERE user_id = ?`, userID).Scan(&token); err != nil {
+			t.Fatalf("Failed to load token: %v", err)
+		}
+		return token
+	}
+
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	old, _ := secrets.NewKeyring(oldKey)
+	database.SetSecretKeyring(old)
+	if n, err := database.EncryptStoredSecrets(ctx, false); err != nil || n != 1 {
+		t.Fatalf("Expected the legacy token to be encrypted, got %d, %v", n, err)
+	}
+	sealed := stored()
+	if !secrets.IsEncrypted(sealed) {
+		t.Fatalf("Expected an encrypted token, got %q", sealed)
+	}
+	if n, _ := database.EncryptStoredSecrets(ctx, false); n != 0 {
+		t.Errorf("Expected encrypted values to be left alone, rewrote %d", n)
+	}
+
+	rotated, _ := secrets.NewKeyring(bytes.Repeat([]byte{2}, 32), oldKey)
+	database.SetSecretKeyring(rotated)
+	if n, _ := database.EncryptStoredSecrets(ctx, false); n != 0 {
+		t.Errorf("Expected startup encryption not to rotate, rewrote %d", n)
+	}
+	if n, err := database.EncryptStoredSecrets(ctx, true); err != nil || n != 1 {
+		t.Fatalf("Expected the token to be rotated, got %d, %v", n, err)
+	}
+	if rotated.NeedsRotation(stored()) {
+		t.Error("Expected the token to be sealed with the new key")
+	}
+
+	current, _ := secrets.NewKeyring(bytes.Repeat([]byte{2}, 32))
+	database.SetSecretKeyring(current)
+	if token, err := database.DecryptSecret(stored()); err != nil || token != "figd_legacy" {
+		t.Errorf("Expected the token to open without the old key, got %q, %v", token, err)
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound is returned for unknown, expired or revoked sessions
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrRefreshTokenReused is returned when a refresh token that was already
+	// rotated out is presented again. The session is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 512
+
+// Session is a login session. Access tokens carry its ID and the refresh
+// token presented to rotate it is stored only as a hash.
+type Session struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+const sessionSelectCols = `id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	var s Session
+	var revokedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	return &s, nil
+}
+
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+// CreateSession starts a login session for a user. refreshHash is the hash of
+// the session's first refresh token; the session lasts ttl unless refreshed.
+func (db *DB) CreateSession(ctx context.Context, userID int64, refreshHash, userAgent, ip string, ttl time.Duration) (*Session, error) {
+	now := time.Now().UTC()
+
+	// Drop sessions that can no longer be used so the table doesn't grow forever
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`DELETE FROM user_sessions WHERE user_id = ? AND (expires_at < ? OR revoked_at IS NOT NULL)`),
+		userID, now); err != nil {
+		return nil, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+
+	var id int64
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO user_sessions (user_id, refresh_token_hash, user_agent, ip_address, created_at, last_used_at, expires_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		userID, refreshHash, truncateUserAgent(userAgent), ip, now, now, now.Add(ttl),
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  truncateUserAgent(userAgent),
+		IPAddress:  ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}, nil
+}
+
+// RotateSession exchanges a refresh token for a new one. The session is
+// extended by ttl and records the client it was refreshed from. Presenting
+// the refresh token the session had before its last rotation revokes it.
+func (db *DB) RotateSession(ctx context.Context, refreshHash, newHash, userAgent, ip string, ttl time.Duration) (*Session, error) {
+	now := time.Now().UTC()
+
+	session, err := scanSession(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+sessionSelectCols+` FROM user_sessions WHERE refresh_token_hash = ?`), refreshHash))
+	if err == sql.ErrNoRows {
+		// A rotated-out token being replayed means it leaked; end the session
+		res, err := db.ExecContext(ctx, db.Rebind(
+			`UPDATE user_sessions SET revoked_at = ? WHERE previous_token_hash = ? AND revoked_at IS NULL`),
+			now, refreshHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session: %w", err)
+	}
+	if !session.Active(now) {
+		return nil, ErrSessionNotFound
+	}
+
+	userAgent = truncateUserAgent(userAgent)
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE user_sessions
+		 SET previous_token_hash = refresh_token_hash, refresh_token_hash = ?,
+		     user_agent = ?, ip_address = ?, last_used_at = ?, expires_at = ?
+		 WHERE id = ? AND refresh_token_hash = ?`),
+		newHash, userAgent, ip, now, now.Add(ttl), session.ID, refreshHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Another request rotated the token first
+		return nil, ErrSessionNotFound
+	}
+
+	session.UserAgent = userAgent
+	session.IPAddress = ip
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(ttl)
+	return session, nil
+}
+
+// ListActiveSessions returns a user's usable sessions, most recently used first.
+func (db *DB) ListActiveSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT `+sessionSelectCols+` FROM user_sessions
+		 WHERE user_id = ? AND revoked_at IS NULL
+		 ORDER BY last_used_at DESC, id DESC`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	sessions := []Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if session.Active(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession ends one of a user's sessions.
+func (db *DB) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE user_sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL`),
+		time.Now().UTC(), sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeSessionByRefreshToken ends the session a refresh token belongs to.
+func (db *DB) RevokeSessionByRefreshToken(ctx context.Context, refreshHash string) error {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE user_sessions SET revoked_at = ? WHERE refresh_token_hash = ? AND revoked_at IS NULL`),
+		time.Now().UTC(), refreshHash)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions ends all of a user's sessions except keepSessionID
+// (0 keeps none) and invalidates tokens issued without a session. It returns
+// the number of sessions revoked.
+func (db *DB) RevokeUserSessions(ctx context.Context, userID, keepSessionID int64) (int64, error) {
+	now := time.Now().UTC()
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL`),
+		now, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(`UPDATE users SET tokens_revoked_at = ? WHERE id = ?`), now, userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// IsTokenRevoked reports whether an access token issued at issuedAt may no
+// longer be used. Tokens with a session follow that session; tokens without
+// one are revoked by RevokeUserSessions.
+func (db *DB) IsTokenRevoked(ctx context.Context, userID, sessionID int64, issuedAt time.Time) (bool, error) {
+	if sessionID != 0 {
+		session, err := scanSession(db.QueryRowContext(ctx, db.Rebind(
+			`SELECT `+sessionSelectCols+` FROM user_sessions WHERE id = ?`), sessionID))
+		if err == sql.ErrNoRows {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return session.UserID != userID || !session.Active(time.Now()), nil
+	}
+
+	var revokedAt *time.Time
+	err := db.QueryRowContext(ctx, db.Rebind(`SELECT tokens_revoked_at FROM users WHERE id = ? LIMIT 1`), userID).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return false, nil // user doesn't exist — let the handler return 404
+	}
+	if err != nil {
+		return false, err
+	}
+	// Token timestamps have second precision; a token from the same second as
+	// the revocation is treated as revoked
+	return revokedAt != nil && !issuedAt.After(*revokedAt), nil
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrSprintCompleted is returned when completing a sprint that already is
+var ErrSprintCompleted = errors.New("sprint is already completed")
+
+// SprintTaskSnapshot is the state of one task of a sprint at the end of a day
+type SprintTaskSnapshot struct {
+	Day            string // 'YYYY-MM-DD', UTC
+	TaskID         int64
+	Status         string
+	EstimatedHours *float64
+}
+
+// SprintTask is a task left unfinished when its sprint was completed
+type SprintTask struct {
+	ID             int64    `json:"id"`
+	ProjectID      int64    `json:"project_id"`
+	TaskNumber     *int64   `json:"task_number,omitempty"`
+	Title          string   `json:"title"`
+	Status         string   `json:"status"`
+	EstimatedHours *float64 `json:"estimated_hours,omitempty"`
+}
+
+// SnapshotDay is the day a snapshot taken at t is recorded under
+func SnapshotDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// snapshotSprint replaces a sprint's snapshot of day with its current tasks
+func (db *DB) snapshotSprint(ctx context.Context, tx *sql.Tx, sprintID int64, day string) error {
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`DELETE FROM sprint_task_snapshots WHERE sprint_id = ? AND day = ?`), sprintID, day); err != nil {
+		return fmt.Errorf("failed to clear sprint snapshot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`INSERT INTO sprint_task_snapshots (sprint_id, day, task_id, status, estimated_hours)
+		 SELECT sprint_id, CAST(? AS TEXT), id, status, estimated_hours FROM tasks WHERE sprint_id = ?`),
+		day, sprintID); err != nil {
+		return fmt.Errorf("failed to save sprint snapshot: %w", err)
+	}
+	return nil
+}
+
+// SnapshotSprint records the current tasks of a sprint as its state at now.
+func (db *DB) SnapshotSprint(ctx context.Context, sprintID int64, now time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.snapshotSprint(ctx, tx, sprintID, SnapshotDay(now)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SnapshotRunningSprints snapshots every running sprint: active ones, and
+// planned ones whose start date has come. It returns how many it recorded.
+func (db *DB) SnapshotRunningSprints(ctx context.Context, now time.Time) (int, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, status, start_date FROM sprints WHERE status <> ?`), "completed")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query sprints: %w", err)
+	}
+	var running []int64
+	for rows.Next() {
+		var id int64
+		var status string
+		var startDate *time.Time
+		if err := rows.Scan(&id, &status, &startDate); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan sprint: %w", err)
+		}
+		if status == "active" || (startDate != nil && SnapshotDay(*startDate) <= SnapshotDay(now)) {
+			running = append(running, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, id := range running {
+		if err := db.SnapshotSprint(ctx, id, now); err != nil {
+			return n, fmt.Errorf("sprint %d: %w", id, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// ListSprintSnapshots returns the recorded task states of a sprint, by day.
+func (db *DB) ListSprintSnapshots(ctx context.Context, sprintID int64) ([]SprintTaskSnapshot, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT day, task_id, status, estimated_hours FROM sprint_task_snapshots
+		 WHERE sprint_id = ? ORDER BY day, task_id`), sprintID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sprint snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []SprintTaskSnapshot{}
+	for rows.Next() {
+		var s SprintTaskSnapshot
+		if err := rows.Scan(&s.Day, &s.TaskID, &s.Status, &s.EstimatedHours); err != nil {
+			return nil, fmt.Errorf("failed to scan sprint snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+// CompleteSprint marks a sprint completed and moves its unfinished tasks to
+// nextSprintID, or out of any sprint when it's nil. The sprint's final state
+// is snapshotted first, so its reports still show the unfinished work. It
+// returns the tasks that were moved.
+func (db *DB) CompleteSprint(ctx context.Context, sprintID int64, nextSprintID *int64, now time.Time) ([]SprintTask, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, db.Rebind(
+		`UPDATE sprints SET status = ? WHERE id = ? AND status <> ?`), "completed", sprintID, "completed")
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete sprint: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrSprintCompleted
+	}
+
+	if err := db.snapshotSprint(ctx, tx, sprintID, SnapshotDay(now)); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, db.Rebind(
+		`SELECT id, project_id, task_number, title, status, estimated_hours FROM tasks
+		 WHERE sprint_id = ? AND status <> ? ORDER BY project_id, task_number, id`), sprintID, "done")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unfinished tasks: %w", err)
+	}
+	unfinished := []SprintTask{}
+	for rows.Next() {
+		var t SprintTask
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.TaskNumber, &t.Title, &t.Status, &t.EstimatedHours); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan unfinished task: %w", err)
+		}
+		unfinished = append(unfinished, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`UPDATE tasks SET sprint_id = ?, updated_at = ? WHERE sprint_id = ? AND status <> ?`),
+		nextSprintID, now.UTC(), sprintID, "done"); err != nil {
+		return nil, fmt.Errorf("failed to move unfinished tasks: %w", err)
+	}
+	return unfinished, tx.Commit()
+}
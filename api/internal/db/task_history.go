@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TaskHistoryEntry records a change to one field of a task. Values are nil
+// for fields that were unset.
+type TaskHistoryEntry struct {
+	ID        int64     `json:"id"`
+	TaskID    int64     `json:"task_id"`
+	ProjectID int64     `json:"project_id"`
+	UserID    *int64    `json:"user_id,omitempty"`
+	UserName  string    `json:"user_name,omitempty"`
+	AgentName string    `json:"agent_name,omitempty"`
+	Source    string    `json:"source"` // an audit channel, e.g. "web" or "api_key"
+	Field     string    `json:"field"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateTaskHistory stores the field changes of one task update. They share
+// a timestamp, which groups them back into the update.
+func (db *DB) CreateTaskHistory(ctx context.Context, entries []TaskHistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt := db.Rebind(`INSERT INTO task_history (task_id, project_id, user_id, agent_name, source, field, old_value, new_value, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for i := range entries {
+		e := &entries[i]
+		e.CreatedAt = now
+		if _, err := tx.ExecContext(ctx, stmt, e.TaskID, e.ProjectID, e.UserID, e.AgentName, e.Source,
+			e.Field, e.OldValue, e.NewValue, e.CreatedAt); err != nil {
+			return fmt.Errorf("failed to save task history: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListTaskHistory returns the field changes of a task, oldest first.
+func (db *DB) ListTaskHistory(ctx context.Context, taskID int64) ([]TaskHistoryEntry, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT id, task_id, project_id, user_id, agent_name, source, field, old_value, new_value, created_at
+		 FROM task_history WHERE task_id = ? ORDER BY created_at, id`), taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []TaskHistoryEntry{}
+	for rows.Next() {
+		var e TaskHistoryEntry
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.ProjectID, &e.UserID, &e.AgentName, &e.Source,
+			&e.Field, &e.OldValue, &e.NewValue, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task history: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
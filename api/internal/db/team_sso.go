@@ -0,0 +1,368 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTeamSSONotFound is returned for teams without single sign-on
+	ErrTeamSSONotFound = errors.New("team sso not configured")
+	// ErrSSORequired is returned when a user tries another way to log in
+	// while their team enforces single sign-on
+	ErrSSORequired = errors.New("team requires single sign-on")
+	// ErrSAMLAssertionReplayed is returned for SAML assertions seen before
+	ErrSAMLAssertionReplayed = errors.New("saml assertion already used")
+	// ErrSSOLinkUsed is returned for account link confirmations used before
+	ErrSSOLinkUsed = errors.New("sso link already used")
+	// ErrSSODomainNotFound is returned for domains a team hasn't claimed
+	ErrSSODomainNotFound = errors.New("sso domain not found")
+	// ErrSSODomainTaken is returned when another team verified a domain first
+	ErrSSODomainTaken = errors.New("sso domain verified by another team")
+)
+
+// Auth challenge purposes that remember used one-time tokens until they
+// expire
+const (
+	ChallengeSAMLAssertion = "saml_assertion"
+	ChallengeSSOLink       = "sso_link"
+)
+
+// SSODomainVerificationPrefix starts the DNS TXT record that proves a team
+// controls an email domain
+const SSODomainVerificationPrefix = "taskai-domain-verification="
+
+// TeamSSODomain is an email domain claimed by a team's single sign-on. Only
+// verified domains are used to match users.
+type TeamSSODomain struct {
+	Domain string `json:"domain"`
+	// VerificationRecord is the TXT record to publish on the domain
+	VerificationRecord string     `json:"verification_record"`
+	VerifiedAt         *time.Time `json:"verified_at"`
+
+	token string
+}
+
+// Verified reports whether the team proved it controls the domain
+func (d *TeamSSODomain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// TeamSSOConfig is a team's single sign-on identity provider
+type TeamSSOConfig struct {
+	TeamID           int64           `json:"team_id"`
+	Protocol         string          `json:"protocol"`
+	Enabled          bool            `json:"enabled"`
+	Enforced         bool            `json:"enforced"`
+	EmailDomains     []TeamSSODomain `json:"email_domains"`
+	OIDCIssuer       string          `json:"oidc_issuer"`
+	OIDCClientID     string          `json:"oidc_client_id"`
+	OIDCClientSecret string          `json:"-"`
+	SAMLMetadata     string          `json:"saml_metadata"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+// SSOProvider is the oauth_providers name of a team's identity provider
+func SSOProvider(teamID int64) string {
+	return fmt.Sprintf("sso:%d", teamID)
+}
+
+// MatchesEmail reports whether an email address is in one of the team's
+// verified domains. No address matches before a domain is verified.
+func (c *TeamSSOConfig) MatchesEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range c.EmailDomains {
+		if d.Verified() && d.Domain == domain {
+			return true
+		}
+	}
+	return false
+}
+
+const teamSSOSelectCols = `team_id, protocol, enabled, enforced, oidc_issuer, oidc_client_id,
+	oidc_client_secret, saml_metadata, created_at, updated_at`
+
+func (db *DB) scanTeamSSOConfig(row interface{ Scan(...interface{}) error }) (*TeamSSOConfig, error) {
+	var c TeamSSOConfig
+	var clientSecret string
+	if err := row.Scan(&c.TeamID, &c.Protocol, &c.Enabled, &c.Enforced, &c.OIDCIssuer, &c.OIDCClientID,
+		&clientSecret, &c.SAMLMetadata, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	secret, err := db.DecryptSecret(clientSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt client secret: %w", err)
+	}
+	c.OIDCClientSecret = secret
+	c.EmailDomains = []TeamSSODomain{}
+	return &c, nil
+}
+
+// loadTeamSSODomains fills in the email domains of a team's configuration
+func (db *DB) loadTeamSSODomains(ctx context.Context, c *TeamSSOConfig) error {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT domain, token, verified_at FROM team_sso_domains WHERE team_id = ? ORDER BY domain`), c.TeamID)
+	if err != nil {
+		return fmt.Errorf("failed to query team sso domains: %w", err)
+	}
+	defer rows.Close()
+
+	c.EmailDomains = []TeamSSODomain{}
+	for rows.Next() {
+		var d TeamSSODomain
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&d.Domain, &d.token, &verifiedAt); err != nil {
+			return fmt.Errorf("failed to scan team sso domain: %w", err)
+		}
+		d.VerificationRecord = SSODomainVerificationPrefix + d.token
+		if verifiedAt.Valid {
+			d.VerifiedAt = &verifiedAt.Time
+		}
+		c.EmailDomains = append(c.EmailDomains, d)
+	}
+	return rows.Err()
+}
+
+// GetTeamSSOConfig returns a team's single sign-on configuration.
+func (db *DB) GetTeamSSOConfig(ctx context.Context, teamID int64) (*TeamSSOConfig, error) {
+	c, err := db.scanTeamSSOConfig(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+teamSSOSelectCols+` FROM team_sso_configs WHERE team_id = ?`), teamID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTeamSSONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team sso config: %w", err)
+	}
+	if err := db.loadTeamSSODomains(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SaveTeamSSOConfig creates or replaces a team's single sign-on
+// configuration. Domains the team already claimed keep their verification
+// token and state; new ones start unverified with a fresh token.
+func (db *DB) SaveTeamSSOConfig(ctx context.Context, c *TeamSSOConfig) error {
+	now := time.Now().UTC()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+	clientSecret, err := db.EncryptSecret(c.OIDCClientSecret)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt client secret: %w", err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`INSERT INTO team_sso_configs (team_id, protocol, enabled, enforced, oidc_issuer, oidc_client_id,
+			oidc_client_secret, saml_metadata, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (team_id) DO UPDATE SET protocol = excluded.protocol, enabled = excluded.enabled,
+			enforced = excluded.enforced, oidc_issuer = excluded.oidc_issuer,
+			oidc_client_id = excluded.oidc_client_id, oidc_client_secret = excluded.oidc_client_secret,
+			saml_metadata = excluded.saml_metadata, updated_at = excluded.updated_at`),
+		c.TeamID, c.Protocol, c.Enabled, c.Enforced, c.OIDCIssuer, c.OIDCClientID,
+		clientSecret, c.SAMLMetadata, c.CreatedAt, c.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save team sso config: %w", err)
+	}
+
+	keep := make([]interface{}, 0, len(c.EmailDomains)+1)
+	keep = append(keep, c.TeamID)
+	placeholders := make([]string, 0, len(c.EmailDomains))
+	for _, d := range c.EmailDomains {
+		token := make([]byte, 16)
+		if _, err := rand.Read(token); err != nil {
+			return fmt.Errorf("failed to generate verification token: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, db.Rebind(
+			`INSERT INTO team_sso_domains (team_id, domain, token, created_at) VALUES (?, ?, ?, ?)
+			 ON CONFLICT (team_id, domain) DO NOTHING`),
+			c.TeamID, d.Domain, hex.EncodeToString(token), now); err != nil {
+			return fmt.Errorf("failed to save team sso domain: %w", err)
+		}
+		keep = append(keep, d.Domain)
+		placeholders = append(placeholders, "?")
+	}
+	query := `DELETE FROM team_sso_domains WHERE team_id = ?`
+	if len(placeholders) > 0 {
+		query += ` AND domain NOT IN (` + strings.Join(placeholders, ", ") + `)`
+	}
+	if _, err := tx.ExecContext(ctx, db.Rebind(query), keep...); err != nil {
+		return fmt.Errorf("failed to remove team sso domains: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit team sso config: %w", err)
+	}
+	return db.loadTeamSSODomains(ctx, c)
+}
+
+// VerifyTeamSSODomain marks a team's email domain verified. It fails with
+// ErrSSODomainTaken if another team verified the domain first.
+func (db *DB) VerifyTeamSSODomain(ctx context.Context, teamID int64, domain string) error {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE team_sso_domains SET verified_at = ? WHERE team_id = ? AND domain = ? AND verified_at IS NULL`),
+		time.Now().UTC(), teamID, domain)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrSSODomainTaken
+		}
+		return fmt.Errorf("failed to verify team sso domain: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := db.QueryRowContext(ctx, db.Rebind(
+			`SELECT EXISTS (SELECT 1 FROM team_sso_domains WHERE team_id = ? AND domain = ?)`),
+			teamID, domain).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to query team sso domain: %w", err)
+		}
+		if !exists {
+			return ErrSSODomainNotFound
+		}
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint failure, on
+// either database
+func isUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint") || strings.Contains(msg, "unique constraint") ||
+		strings.Contains(msg, "duplicate key")
+}
+
+// DeleteTeamSSOConfig removes a team's single sign-on. Users keep their
+// accounts and can set a password through the password reset flow.
+func (db *DB) DeleteTeamSSOConfig(ctx context.Context, teamID int64) error {
+	res, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM team_sso_configs WHERE team_id = ?`), teamID)
+	if err != nil {
+		return fmt.Errorf("failed to delete team sso config: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTeamSSONotFound
+	}
+	return nil
+}
+
+// FindTeamSSOByEmailDomain returns the enabled single sign-on that verified
+// an email domain.
+func (db *DB) FindTeamSSOByEmailDomain(ctx context.Context, domain string) (*TeamSSOConfig, error) {
+	c, err := db.scanTeamSSOConfig(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+teamSSOSelectCols+` FROM team_sso_configs
+		 WHERE enabled AND team_id = (SELECT team_id FROM team_sso_domains WHERE domain = ? AND verified_at IS NOT NULL)`),
+		strings.ToLower(domain)))
+	if err == sql.ErrNoRows {
+		return nil, ErrTeamSSONotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query team sso config: %w", err)
+	}
+	if err := db.loadTeamSSODomains(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// teamVerifiedEmail reports whether an email address is in one of a team's
+// verified single sign-on domains
+func (db *DB) teamVerifiedEmail(ctx context.Context, teamID int64, email string) (bool, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false, nil
+	}
+	var ok bool
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT EXISTS (SELECT 1 FROM team_sso_domains WHERE team_id = ? AND domain = ? AND verified_at IS NOT NULL)`),
+		teamID, strings.ToLower(email[at+1:])).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to query team sso domains: %w", err)
+	}
+	return ok, nil
+}
+
+// SSOEnforcedTeam returns the team that requires a user to log in through
+// single sign-on, or 0 if none does. It only applies to members who signed
+// in through the team's identity provider with an address in one of its
+// verified domains, so a team can't lock out accounts it merely added.
+// Team owners are exempt, so a broken identity provider can't lock
+// everyone out.
+func (db *DB) SSOEnforcedTeam(ctx context.Context, userID int64) (int64, error) {
+	var teamID int64
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT c.team_id FROM team_sso_configs c
+		 JOIN team_members tm ON tm.team_id = c.team_id
+		 JOIN users u ON u.id = tm.user_id
+		 WHERE tm.user_id = ? AND tm.status = 'active' AND tm.role <> 'owner' AND c.enabled AND c.enforced
+		   AND EXISTS (SELECT 1 FROM oauth_providers op
+		               WHERE op.user_id = u.id AND op.provider = 'sso:' || CAST(c.team_id AS TEXT))
+		   AND EXISTS (SELECT 1 FROM team_sso_domains d
+		               WHERE d.team_id = c.team_id AND d.verified_at IS NOT NULL AND LOWER(u.email) LIKE '%@' || d.domain)
+		 ORDER BY c.team_id LIMIT 1`), userID).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query enforced sso: %w", err)
+	}
+	return teamID, nil
+}
+
+// UseSAMLAssertion records that a SAML assertion was used to log in. It
+// fails with ErrSAMLAssertionReplayed if the assertion was used before.
+func (db *DB) UseSAMLAssertion(ctx context.Context, teamID int64, assertionID string, expiresAt time.Time) error {
+	used, err := db.useOnce(ctx, fmt.Sprintf("saml:%d:%s", teamID, assertionID), ChallengeSAMLAssertion, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record saml assertion: %w", err)
+	}
+	if used {
+		return ErrSAMLAssertionReplayed
+	}
+	return nil
+}
+
+// UseSSOLink records that an account link confirmation was used. It fails
+// with ErrSSOLinkUsed if the confirmation was used before.
+func (db *DB) UseSSOLink(ctx context.Context, linkID string, expiresAt time.Time) error {
+	used, err := db.useOnce(ctx, "sso_link:"+linkID, ChallengeSSOLink, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record sso link: %w", err)
+	}
+	if used {
+		return ErrSSOLinkUsed
+	}
+	return nil
+}
+
+// useOnce remembers a one-time token until it expires, and reports whether
+// it was already used
+func (db *DB) useOnce(ctx context.Context, challenge, purpose string, expiresAt time.Time) (bool, error) {
+	now := time.Now().UTC()
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM auth_challenges WHERE expires_at < ?`), now); err != nil {
+		return false, err
+	}
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`INSERT INTO auth_challenges (challenge, purpose, expires_at, created_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT (challenge) DO NOTHING`),
+		challenge, purpose, expiresAt.UTC(), now)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 0, nil
+}
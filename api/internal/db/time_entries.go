@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrTimeEntryNotFound is returned for time entries that don't exist
+	ErrTimeEntryNotFound = errors.New("time entry not found")
+	// ErrTimerRunning is returned when starting a timer while the user
+	// already has one running
+	ErrTimerRunning = errors.New("a timer is already running")
+)
+
+// TimeEntry is time a user logged on a task, with a timer or as a manual
+// duration. Running timers have no duration until they are stopped.
+type TimeEntry struct {
+	ID              int64      `json:"id"`
+	TaskID          int64      `json:"task_id"`
+	ProjectID       int64      `json:"project_id"`
+	UserID          *int64     `json:"user_id,omitempty"` // nil once the user is deleted
+	UserName        string     `json:"user_name,omitempty"`
+	AgentName       string     `json:"agent_name,omitempty"`
+	Date            string     `json:"date"` // 'YYYY-MM-DD' the work was done
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Running         bool       `json:"running"`
+	Note            string     `json:"note"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+const timeEntryColumns = `id, task_id, project_id, user_id, agent_name, work_date, started_at, ended_at,
+	duration_seconds, running, note, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTimeEntry(row rowScanner, e *TimeEntry) error {
+	return row.Scan(&e.ID, &e.TaskID, &e.ProjectID, &e.UserID, &e.AgentName, &e.Date, &e.StartedAt, &e.EndedAt,
+		&e.DurationSeconds, &e.Running, &e.Note, &e.CreatedAt, &e.UpdatedAt)
+}
+
+// CreateTimeEntry stores a time entry. Starting a timer fails with
+// ErrTimerRunning while the user has another one running.
+func (db *DB) CreateTimeEntry(ctx context.Context, e *TimeEntry) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if e.Running {
+		var id int64
+		err := tx.QueryRowContext(ctx, db.Rebind(
+			`SELECT id FROM time_entries WHERE user_id = ? AND running = ?`), e.UserID, true).Scan(&id)
+		if err == nil {
+			return ErrTimerRunning
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to check running timers: %w", err)
+		}
+	}
+
+	now := time.Now().UTC()
+	e.CreatedAt, e.UpdatedAt = now, now
+	if err := tx.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO time_entries (task_id, project_id, user_id, agent_name, work_date, started_at, ended_at,
+			duration_seconds, running, note, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		e.TaskID, e.ProjectID, e.UserID, e.AgentName, e.Date, e.StartedAt, e.EndedAt,
+		e.DurationSeconds, e.Running, e.Note, e.CreatedAt, e.UpdatedAt).Scan(&e.ID); err != nil {
+		return fmt.Errorf("failed to save time entry: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetTimeEntry returns a time entry by ID.
+func (db *DB) GetTimeEntry(ctx context.Context, id int64) (*TimeEntry, error) {
+	var e TimeEntry
+	err := scanTimeEntry(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+timeEntryColumns+` FROM time_entries WHERE id = ?`), id), &e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTimeEntryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entry: %w", err)
+	}
+	return &e, nil
+}
+
+// RunningTimeEntry returns the timer a user has running, or nil.
+func (db *DB) RunningTimeEntry(ctx context.Context, userID int64) (*TimeEntry, error) {
+	var e TimeEntry
+	err := scanTimeEntry(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+timeEntryColumns+` FROM time_entries WHERE user_id = ? AND running = ?`), userID, true), &e)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get running timer: %w", err)
+	}
+	return &e, nil
+}
+
+// ListTaskTimeEntries returns the time logged on a task, newest first.
+func (db *DB) ListTaskTimeEntries(ctx context.Context, taskID int64) ([]TimeEntry, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT `+timeEntryColumns+` FROM time_entries WHERE task_id = ? ORDER BY work_date DESC, id DESC`), taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []TimeEntry{}
+	for rows.Next() {
+		var e TimeEntry
+		if err := scanTimeEntry(rows, &e); err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// HasTimeEntries reports whether any time has been logged on a task.
+func (db *DB) HasTimeEntries(ctx context.Context, taskID int64) (bool, error) {
+	var n int
+	if err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*) FROM time_entries WHERE task_id = ?`), taskID).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to count time entries: %w", err)
+	}
+	return n > 0, nil
+}
+
+// UpdateTimeEntry saves the date, times, duration, running state and note of
+// a time entry.
+func (db *DB) UpdateTimeEntry(ctx context.Context, e *TimeEntry) error {
+	e.UpdatedAt = time.Now().UTC()
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE time_entries SET work_date = ?, started_at = ?, ended_at = ?, duration_seconds = ?, running = ?,
+			note = ?, updated_at = ?
+		 WHERE id = ?`),
+		e.Date, e.StartedAt, e.EndedAt, e.DurationSeconds, e.Running, e.Note, e.UpdatedAt, e.ID); err != nil {
+		return fmt.Errorf("failed to update time entry: %w", err)
+	}
+	return nil
+}
+
+// DeleteTimeEntry deletes a time entry.
+func (db *DB) DeleteTimeEntry(ctx context.Context, id int64) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM time_entries WHERE id = ?`), id); err != nil {
+		return fmt.Errorf("failed to delete time entry: %w", err)
+	}
+	return nil
+}
+
+// SyncTaskActualHours sets a task's actual_hours to the sum of its finished
+// time entries, in hours rounded to two decimals, or NULL when it has none.
+// It returns the task's hours before and after.
+func (db *DB) SyncTaskActualHours(ctx context.Context, taskID int64) (before, after *float64, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, db.Rebind(
+		`SELECT actual_hours FROM tasks WHERE id = ?`), taskID).Scan(&before); err != nil {
+		return nil, nil, fmt.Errorf("failed to get task hours: %w", err)
+	}
+	var n int
+	var seconds sql.NullInt64
+	if err := tx.QueryRowContext(ctx, db.Rebind(
+		`SELECT COUNT(*), SUM(duration_seconds) FROM time_entries WHERE task_id = ? AND running = ?`),
+		taskID, false).Scan(&n, &seconds); err != nil {
+		return nil, nil, fmt.Errorf("failed to sum time entries: %w", err)
+	}
+	if n > 0 {
+		hours := math.Round(float64(seconds.Int64)/3600*100) / 100
+		after = &hours
+	}
+	if _, err := tx.ExecContext(ctx, db.Rebind(
+		`UPDATE tasks SET actual_hours = ?, updated_at = ? WHERE id = ?`), after, time.Now().UTC(), taskID); err != nil {
+		return nil, nil, fmt.Errorf("failed to update task hours: %w", err)
+	}
+	return before, after, tx.Commit()
+}
+
+// TimesheetQuery filters the finished time entries of a timesheet. Zero
+// values match everything.
+type TimesheetQuery struct {
+	From, To string // 'YYYY-MM-DD', inclusive
+	// ProjectIDs restricts entries to these projects; an empty slice matches
+	// nothing.
+	ProjectIDs []int64
+	ProjectID  int64
+	UserID     int64
+	SprintID   int64 // the sprint the task is in now
+	TagID      int64
+}
+
+// TimesheetTag is a tag of a timesheet entry's task
+type TimesheetTag struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// TimesheetEntry is a finished time entry with what it was logged on
+type TimesheetEntry struct {
+	TimeEntry
+	TaskNumber  *int64         `json:"task_number,omitempty"`
+	TaskTitle   string         `json:"task_title"`
+	ProjectName string         `json:"project_name"`
+	SprintID    *int64         `json:"sprint_id,omitempty"`
+	SprintName  *string        `json:"sprint_name,omitempty"`
+	Tags        []TimesheetTag `json:"tags"`
+}
+
+// ListTimesheetEntries returns the finished time entries matching a query,
+// by date.
+func (db *DB) ListTimesheetEntries(ctx context.Context, q TimesheetQuery) ([]TimesheetEntry, error) {
+	entries := []TimesheetEntry{}
+	if len(q.ProjectIDs) == 0 {
+		return entries, nil
+	}
+
+	conds := []string{`e.running = ?`, `e.project_id IN (?` + strings.Repeat(`, ?`, len(q.ProjectIDs)-1) + `)`}
+	args := []interface{}{false}
+	for _, id := range q.ProjectIDs {
+		args = append(args, id)
+	}
+	if q.From != "" {
+		conds = append(conds, `e.work_date >= ?`)
+		args = append(args, q.From)
+	}
+	if q.To != "" {
+		conds = append(conds, `e.work_date <= ?`)
+		args = append(args, q.To)
+	}
+	if q.ProjectID != 0 {
+		conds = append(conds, `e.project_id = ?`)
+		args = append(args, q.ProjectID)
+	}
+	if q.UserID != 0 {
+		conds = append(conds, `e.user_id = ?`)
+		args = append(args, q.UserID)
+	}
+	if q.SprintID != 0 {
+		conds = append(conds, `t.sprint_id = ?`)
+		args = append(args, q.SprintID)
+	}
+	if q.TagID != 0 {
+		conds = append(conds, `EXISTS (SELECT 1 FROM task_tags tt WHERE tt.task_id = e.task_id AND tt.tag_id = ?)`)
+		args = append(args, q.TagID)
+	}
+
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT e.id, e.task_id, e.project_id, e.user_id, e.agent_name, e.work_date, e.started_at, e.ended_at,
+			e.duration_seconds, e.running, e.note, e.created_at, e.updated_at,
+			t.task_number, t.title, p.name, t.sprint_id, s.name
+		 FROM time_entries e
+		 JOIN tasks t ON t.id = e.task_id
+		 JOIN projects p ON p.id = e.project_id
+		 LEFT JOIN sprints s ON s.id = t.sprint_id
+		 WHERE `+strings.Join(conds, " AND ")+`
+		 ORDER BY e.work_date, e.id`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query timesheet: %w", err)
+	}
+	defer rows.Close()
+
+	byTask := map[int64][]int{}
+	for rows.Next() {
+		var e TimesheetEntry
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.ProjectID, &e.UserID, &e.AgentName, &e.Date, &e.StartedAt, &e.EndedAt,
+			&e.DurationSeconds, &e.Running, &e.Note, &e.CreatedAt, &e.UpdatedAt,
+			&e.TaskNumber, &e.TaskTitle, &e.ProjectName, &e.SprintID, &e.SprintName); err != nil {
+			return nil, fmt.Errorf("failed to scan timesheet entry: %w", err)
+		}
+		e.Tags = []TimesheetTag{}
+		byTask[e.TaskID] = append(byTask[e.TaskID], len(entries))
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(byTask) == 0 {
+		return entries, nil
+	}
+
+	taskIDs := make([]interface{}, 0, len(byTask))
+	for id := range byTask {
+		taskIDs = append(taskIDs, id)
+	}
+	tagRows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT tt.task_id, tg.id, tg.name FROM task_tags tt JOIN tags tg ON tg.id = tt.tag_id
+		 WHERE tt.task_id IN (?`+strings.Repeat(`, ?`, len(taskIDs)-1)+`) ORDER BY tg.name`), taskIDs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query task tags: %w", err)
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var taskID int64
+		var tag TimesheetTag
+		if err := tagRows.Scan(&taskID, &tag.ID, &tag.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan task tag: %w", err)
+		}
+		for _, i := range byTask[taskID] {
+			entries[i].Tags = append(entries[i].Tags, tag)
+		}
+	}
+	return entries, tagRows.Err()
+}
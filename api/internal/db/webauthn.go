@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrWebAuthnCredentialNotFound is returned for unknown credentials
+	ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+	// ErrAuthChallengeInvalid is returned for unknown, expired or already used challenges
+	ErrAuthChallengeInvalid = errors.New("invalid auth challenge")
+)
+
+// Purposes of auth challenges
+const (
+	ChallengeWebAuthnRegister = "webauthn_register"
+	ChallengeWebAuthnLogin    = "webauthn_login"
+	ChallengeSecondFactor     = "second_factor"
+)
+
+// WebAuthnCredential is a registered passkey or security key
+type WebAuthnCredential struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"user_id"`
+	CredentialID   string     `json:"credential_id"`
+	PublicKey      []byte     `json:"-"`
+	SignCount      uint32     `json:"-"`
+	Transports     []string   `json:"transports"`
+	Name           string     `json:"name"`
+	BackupEligible bool       `json:"backup_eligible"`
+	BackedUp       bool       `json:"backed_up"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty"`
+}
+
+const webAuthnCredentialSelectCols = `id, user_id, credential_id, public_key, sign_count, transports, name,
+	backup_eligible, backed_up, created_at, last_used_at`
+
+func scanWebAuthnCredential(row interface{ Scan(...interface{}) error }) (*WebAuthnCredential, error) {
+	var c WebAuthnCredential
+	var signCount int64
+	var transports string
+	var lastUsedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.UserID, &c.CredentialID, &c.PublicKey, &signCount, &transports, &c.Name,
+		&c.BackupEligible, &c.BackedUp, &c.CreatedAt, &lastUsedAt); err != nil {
+		return nil, err
+	}
+	c.SignCount = uint32(signCount)
+	c.Transports = []string{}
+	if transports != "" {
+		c.Transports = strings.Split(transports, ",")
+	}
+	if lastUsedAt.Valid {
+		c.LastUsedAt = &lastUsedAt.Time
+	}
+	return &c, nil
+}
+
+// CreateWebAuthnCredential stores a newly registered credential and fills in
+// its ID and creation time.
+func (db *DB) CreateWebAuthnCredential(ctx context.Context, c *WebAuthnCredential) error {
+	c.CreatedAt = time.Now().UTC()
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, transports, name, backup_eligible, backed_up, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		c.UserID, c.CredentialID, c.PublicKey, int64(c.SignCount), strings.Join(c.Transports, ","), c.Name,
+		c.BackupEligible, c.BackedUp, c.CreatedAt,
+	).Scan(&c.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// ListWebAuthnCredentials returns a user's credentials, oldest first.
+func (db *DB) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]WebAuthnCredential, error) {
+	rows, err := db.QueryContext(ctx, db.Rebind(
+		`SELECT `+webAuthnCredentialSelectCols+` FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at, id`), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webauthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	creds := []WebAuthnCredential{}
+	for rows.Next() {
+		c, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webauthn credential: %w", err)
+		}
+		creds = append(creds, *c)
+	}
+	return creds, rows.Err()
+}
+
+// GetWebAuthnCredential looks up a credential by its base64url credential ID.
+func (db *DB) GetWebAuthnCredential(ctx context.Context, credentialID string) (*WebAuthnCredential, error) {
+	c, err := scanWebAuthnCredential(db.QueryRowContext(ctx, db.Rebind(
+		`SELECT `+webAuthnCredentialSelectCols+` FROM webauthn_credentials WHERE credential_id = ?`), credentialID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebAuthnCredentialNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webauthn credential: %w", err)
+	}
+	return c, nil
+}
+
+// RecordWebAuthnCredentialUse stores the signature counter and backup state
+// reported by a successful authentication.
+func (db *DB) RecordWebAuthnCredentialUse(ctx context.Context, id int64, signCount uint32, backedUp bool) error {
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE webauthn_credentials SET sign_count = ?, backed_up = ?, last_used_at = ? WHERE id = ?`),
+		int64(signCount), backedUp, time.Now().UTC(), id); err != nil {
+		return fmt.Errorf("failed to update webauthn credential: %w", err)
+	}
+	return nil
+}
+
+// RenameWebAuthnCredential renames one of a user's credentials.
+func (db *DB) RenameWebAuthnCredential(ctx context.Context, userID, id int64, name string) error {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`UPDATE webauthn_credentials SET name = ? WHERE id = ? AND user_id = ?`), name, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to rename webauthn credential: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
+
+// DeleteWebAuthnCredential revokes one of a user's credentials.
+func (db *DB) DeleteWebAuthnCredential(ctx context.Context, userID, id int64) error {
+	res, err := db.ExecContext(ctx, db.Rebind(
+		`DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?`), id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webauthn credential: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrWebAuthnCredentialNotFound
+	}
+	return nil
+}
+
+// StrongAuth is which second factors a user has set up
+type StrongAuth struct {
+	TOTPEnabled         bool `json:"totp_enabled"`
+	WebAuthnCredentials int  `json:"webauthn_credentials"`
+}
+
+// Enabled reports whether the user has any second factor.
+func (s StrongAuth) Enabled() bool {
+	return s.TOTPEnabled || s.WebAuthnCredentials > 0
+}
+
+// GetStrongAuth returns the second factors a user has set up.
+func (db *DB) GetStrongAuth(ctx context.Context, userID int64) (StrongAuth, error) {
+	var s StrongAuth
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT totp_enabled, (SELECT COUNT(*) FROM webauthn_credentials WHERE user_id = users.id)
+		 FROM users WHERE id = ?`), userID).Scan(&s.TOTPEnabled, &s.WebAuthnCredentials)
+	if err != nil {
+		return s, fmt.Errorf("failed to query second factors: %w", err)
+	}
+	return s, nil
+}
+
+// ListStrongAuth returns the second factors of every user who has one, by user ID.
+func (db *DB) ListStrongAuth(ctx context.Context) (map[int64]StrongAuth, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT u.id, u.totp_enabled, COUNT(c.id) FROM users u
+		 LEFT JOIN webauthn_credentials c ON c.user_id = u.id
+		 GROUP BY u.id, u.totp_enabled
+		 HAVING u.totp_enabled OR COUNT(c.id) > 0`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query second factors: %w", err)
+	}
+	defer rows.Close()
+
+	result := map[int64]StrongAuth{}
+	for rows.Next() {
+		var userID int64
+		var s StrongAuth
+		if err := rows.Scan(&userID, &s.TOTPEnabled, &s.WebAuthnCredentials); err != nil {
+			return nil, fmt.Errorf("failed to scan second factors: %w", err)
+		}
+		result[userID] = s
+	}
+	return result, rows.Err()
+}
+
+// CreateAuthChallenge stores a challenge for a user, or for no user yet when
+// userID is 0, until expiresAt. Expired challenges are dropped.
+func (db *DB) CreateAuthChallenge(ctx context.Context, challenge, purpose string, userID int64, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	if _, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM auth_challenges WHERE expires_at < ?`), now); err != nil {
+		return fmt.Errorf("failed to prune auth challenges: %w", err)
+	}
+
+	var user interface{}
+	if userID != 0 {
+		user = userID
+	}
+	if _, err := db.ExecContext(ctx, db.Rebind(
+		`INSERT INTO auth_challenges (challenge, purpose, user_id, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`),
+		challenge, purpose, user, expiresAt.UTC(), now); err != nil {
+		return fmt.Errorf("failed to create auth challenge: %w", err)
+	}
+	return nil
+}
+
+// GetAuthChallenge returns the user of an outstanding challenge, or 0 if it
+// was issued before the user was known. The challenge stays valid so a
+// mistyped code can be retried; ConsumeAuthChallenge ends it.
+func (db *DB) GetAuthChallenge(ctx context.Context, challenge, purpose string, now time.Time) (int64, error) {
+	var userID sql.NullInt64
+	err := db.QueryRowContext(ctx, db.Rebind(
+		`SELECT user_id FROM auth_challenges WHERE challenge = ? AND purpose = ? AND expires_at > ?`),
+		challenge, purpose, now.UTC()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrAuthChallengeInvalid
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to query auth challenge: %w", err)
+	}
+	return userID.Int64, nil
+}
+
+// ConsumeAuthChallenge ends a challenge once it has been answered. Of
+// concurrent requests answering the same challenge only one succeeds.
+func (db *DB) ConsumeAuthChallenge(ctx context.Context, challenge string) error {
+	res, err := db.ExecContext(ctx, db.Rebind(`DELETE FROM auth_challenges WHERE challenge = ?`), challenge)
+	if err != nil {
+		return fmt.Errorf("failed to consume auth challenge: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAuthChallengeInvalid
+	}
+	return nil
+}
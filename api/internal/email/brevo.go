@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"time"
@@ -193,6 +194,59 @@ func (s *BrevoService) SendPasswordReset(ctx context.Context, toEmail, token, ap
 	return s.SendEmail(ctx, toEmail, subject, html)
 }
 
+// SendSSOLinkConfirmation asks the owner of an existing account to confirm
+// connecting it to a team's single sign-on
+func (s *BrevoService) SendSSOLinkConfirmation(ctx context.Context, toEmail, teamName, linkURL string) error {
+	subject := "Connect your TaskAI account to single sign-on"
+
+	html := buildEmailTemplate(
+		"Confirm Single Sign-On",
+		fmt.Sprintf("Someone signed in through <strong>%s</strong>'s single sign-on with your email address. Click the button below to connect your existing TaskAI account to it and join the team. This link expires in 1 hour.",
+			html.EscapeString(teamName)),
+		linkURL,
+		"Connect Account",
+		"If this wasn't you, ignore this email — your account stays as it is.",
+	)
+
+	return s.SendEmail(ctx, toEmail, subject, html)
+}
+
+// SendAccountLocked tells a user their account was locked after repeated
+// failed logins, with a one-time link to unlock it
+func (s *BrevoService) SendAccountLocked(ctx context.Context, toEmail, token, appURL string, lockedUntil time.Time) error {
+	unlockURL := fmt.Sprintf("%s/unlock-account?token=%s", appURL, token)
+	subject := "Your TaskAI account has been locked"
+
+	html := buildEmailTemplate(
+		"Account Locked",
+		fmt.Sprintf("We locked your TaskAI account after several failed sign-in attempts. It unlocks automatically at <strong>%s</strong>, or you can unlock it now with the button below. This link expires in 1 hour.",
+			lockedUntil.UTC().Format("Jan 2, 2006 15:04 MST")),
+		unlockURL,
+		"Unlock Account",
+		"If these attempts weren't you, someone may be guessing your password — consider changing it after you sign in.",
+	)
+
+	return s.SendEmail(ctx, toEmail, subject, html)
+}
+
+// SendNewLoginAlert tells a user about a sign-in from a device or network
+// their account hasn't been used from before
+func (s *BrevoService) SendNewLoginAlert(ctx context.Context, toEmail, device, ipAddress string, at time.Time, appURL string) error {
+	settingsURL := appURL + "/app/settings"
+	subject := "New sign-in to your TaskAI account"
+
+	content := buildEmailTemplate(
+		"New Sign-in Detected",
+		fmt.Sprintf("Your TaskAI account was just signed in to from <strong>%s</strong> (IP address %s) at %s.",
+			html.EscapeString(device), html.EscapeString(ipAddress), at.UTC().Format("Jan 2, 2006 15:04 MST")),
+		settingsURL,
+		"Review Sessions",
+		"If this was you, there's nothing to do. If not, change your password and log out your other sessions.",
+	)
+
+	return s.SendEmail(ctx, toEmail, subject, content)
+}
+
 // buildEmailTemplate generates a responsive HTML email with TaskAI branding
 func buildEmailTemplate(heading, bodyText, ctaURL, ctaLabel, footerNote string) string {
 	return fmt.Sprintf(`<!DOCTYPE html>
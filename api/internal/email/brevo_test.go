@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"go.uber.org/zap/zaptest"
 )
@@ -245,6 +246,57 @@ func TestSendProjectInvitationNewUser(t *testing.T) {
 	}
 }
 
+func TestSendAccountLocked(t *testing.T) {
+	var receivedBody brevoEmailRequest
+
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &receivedBody)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	lockedUntil := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+	err := svc.SendAccountLocked(context.Background(), "user@test.com", "unlock123", "https://app.taskai.cc", lockedUntil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if receivedBody.To[0].Email != "user@test.com" {
+		t.Errorf("Expected to 'user@test.com', got '%s'", receivedBody.To[0].Email)
+	}
+	if !strings.Contains(receivedBody.HTMLContent, "https://app.taskai.cc/unlock-account?token=unlock123") {
+		t.Error("Expected HTML to contain unlock URL with token")
+	}
+	if !strings.Contains(receivedBody.HTMLContent, "Mar 4, 2026 15:30 UTC") {
+		t.Error("Expected HTML to contain the time the lock ends")
+	}
+}
+
+func TestSendNewLoginAlert(t *testing.T) {
+	var receivedBody brevoEmailRequest
+
+	svc := newTestService(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &receivedBody)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	err := svc.SendNewLoginAlert(context.Background(), "user@test.com", "<script>", "203.0.113.7", time.Now(), "https://app.taskai.cc")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !strings.Contains(receivedBody.HTMLContent, "203.0.113.7") {
+		t.Error("Expected HTML to contain the IP address")
+	}
+	if strings.Contains(receivedBody.HTMLContent, "<script>") || !strings.Contains(receivedBody.HTMLContent, "&lt;script&gt;") {
+		t.Error("Expected the device to be HTML-escaped")
+	}
+	if !strings.Contains(receivedBody.HTMLContent, "https://app.taskai.cc/app/settings") {
+		t.Error("Expected HTML to link to the settings page")
+	}
+}
+
 func TestBuildEmailTemplate(t *testing.T) {
 	html := buildEmailTemplate("Test Heading", "Test body text", "https://example.com", "Click Me", "Footer note")
 
@@ -0,0 +1,94 @@
+// Package netguard keeps outbound requests to user-supplied URLs, such as
+// webhook targets, away from internal addresses: loopback, private networks
+// and cloud metadata services.
+//
+// Addresses are checked when each connection is dialed, after DNS
+// resolution, so a hostname that re-resolves to an internal address is
+// still refused.
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// blockedNets are internal ranges not covered by the net.IP helpers: "this
+// network", carrier-grade NAT (which hosts some cloud metadata services) and
+// the NAT64 prefixes, which embed an IPv4 address a gateway connects to.
+var blockedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("64:ff9b::/96"),
+	mustParseCIDR("64:ff9b:1::/48"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// PublicIP reports whether ip is a public address. Loopback, private,
+// link-local (including the 169.254.169.254 metadata endpoint), unspecified
+// and multicast addresses are not. An IPv4-mapped IPv6 address is judged by
+// the IPv4 address it carries.
+func PublicIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control returns a net.Dialer Control hook that refuses connections to
+// addresses that aren't public. allowPrivate lifts the check while it
+// returns true; tests use it to reach local servers.
+func Control(allowPrivate func() bool) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		if allowPrivate != nil && allowPrivate() {
+			return nil
+		}
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+			return fmt.Errorf("%s is not a public address", host)
+		}
+		return nil
+	}
+}
+
+// NewClient returns an HTTP client that only connects to public addresses.
+// It ignores proxy settings so every connection goes through the check, and
+// never follows redirects: callers get the 3xx response itself.
+func NewClient(timeout time.Duration, allowPrivate func() bool) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: timeout,
+				Control: Control(allowPrivate),
+			}).DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
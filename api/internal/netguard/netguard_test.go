@@ -0,0 +1,67 @@
+package netguard
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"0.0.0.0", false},
+		{"fd00:ec2::254", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:93.184.216.34", true},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::5db8:d822", false},
+		{"64:ff9b:1::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := PublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("PublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	t.Run("private addresses are refused at connect time", func(t *testing.T) {
+		resp, err := NewClient(time.Second, nil).Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+			t.Fatal("Expected the connection to a loopback server to be refused")
+		}
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		resp, err := NewClient(time.Second, func() bool { return true }).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Errorf("Expected the redirect response itself, got %d", resp.StatusCode)
+		}
+	})
+}
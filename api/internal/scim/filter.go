@@ -0,0 +1,59 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Filter is an equality filter on a single attribute, which is all that
+// identity providers use to look up users and groups before creating them.
+type Filter struct {
+	// Attribute is the lower-cased attribute path, without a schema URN
+	// prefix, e.g. "username" or "emails.value"
+	Attribute string
+	Value     string
+}
+
+// ParseFilter parses a filter of the form `attribute eq "value"`. Other
+// operators and logical expressions are rejected with invalidFilter.
+func ParseFilter(s string) (*Filter, error) {
+	s = strings.TrimSpace(s)
+	attr, rest, ok := strings.Cut(s, " ")
+	if !ok {
+		return nil, BadRequest(ErrInvalidFilter, "Filter must be of the form: attribute eq \"value\"")
+	}
+	op, value, ok := strings.Cut(strings.TrimLeft(rest, " "), " ")
+	if !ok || !strings.EqualFold(op, "eq") {
+		return nil, BadRequest(ErrInvalidFilter, "Only the eq filter operator is supported")
+	}
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, `"`) {
+		var str string
+		if err := json.Unmarshal([]byte(value), &str); err != nil {
+			return nil, BadRequest(ErrInvalidFilter, "Filter value is not a valid string")
+		}
+		value = str
+	} else if strings.ContainsAny(value, " ()[]") {
+		return nil, BadRequest(ErrInvalidFilter, "Only single attribute filters are supported")
+	}
+
+	return &Filter{Attribute: attributePath(attr), Value: value}, nil
+}
+
+// attributePath lower-cases an attribute path and strips a core schema
+// URN prefix from it
+func attributePath(path string) string {
+	return strings.ToLower(stripSchema(path))
+}
+
+// stripSchema strips a core schema URN prefix from an attribute path
+func stripSchema(path string) string {
+	path = strings.TrimSpace(path)
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			return path[len(schema)+1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,471 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Patch operations
+const (
+	OpAdd     = "add"
+	OpReplace = "replace"
+	OpRemove  = "remove"
+)
+
+// PatchOperation is one operation of a PATCH request
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// PatchRequest is the body of a PATCH request
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// ParsePatch decodes a PATCH request and checks its operations. Operation
+// names are lower-cased, as some identity providers capitalize them.
+func ParsePatch(data []byte) (*PatchRequest, error) {
+	var req PatchRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, BadRequest(ErrInvalidSyntax, "Request body is not a valid PatchOp")
+	}
+	if len(req.Operations) == 0 {
+		return nil, BadRequest(ErrInvalidSyntax, "Operations are required")
+	}
+	for i := range req.Operations {
+		op := &req.Operations[i]
+		op.Op = strings.ToLower(op.Op)
+		switch op.Op {
+		case OpAdd, OpReplace:
+			if len(op.Value) == 0 {
+				return nil, BadRequest(ErrInvalidValue, "A value is required for "+op.Op+" operations")
+			}
+		case OpRemove:
+			if op.Path == "" {
+				return nil, BadRequest(ErrNoTarget, "A path is required for remove operations")
+			}
+		default:
+			return nil, BadRequest(ErrInvalidSyntax, "Unsupported patch operation: "+op.Op)
+		}
+	}
+	return &req, nil
+}
+
+// patchPath is a parsed attribute path such as `name.givenName` or
+// `emails[type eq "work"].value`
+type patchPath struct {
+	attr   string
+	filter *Filter
+	sub    string
+}
+
+func parsePath(path string) (patchPath, error) {
+	raw := stripSchema(path)
+	// Extension attributes are namespaced by a URN, which has dots of its own
+	if strings.HasPrefix(strings.ToLower(raw), "urn:") {
+		return patchPath{attr: strings.ToLower(raw)}, nil
+	}
+
+	var p patchPath
+	open := strings.Index(raw, "[")
+	if open < 0 {
+		p.attr, p.sub, _ = strings.Cut(strings.ToLower(raw), ".")
+		return p, nil
+	}
+	end := strings.LastIndex(raw, "]")
+	if end < open {
+		return p, BadRequest(ErrInvalidPath, "Invalid path: "+path)
+	}
+	f, err := ParseFilter(raw[open+1 : end])
+	if err != nil {
+		return p, BadRequest(ErrInvalidPath, "Invalid path filter: "+path)
+	}
+	p.attr, p.filter = strings.ToLower(raw[:open]), f
+	if rest := strings.ToLower(raw[end+1:]); rest != "" {
+		if !strings.HasPrefix(rest, ".") {
+			return p, BadRequest(ErrInvalidPath, "Invalid path: "+path)
+		}
+		p.sub = rest[1:]
+	}
+	return p, nil
+}
+
+// applyOperation resolves an operation to attribute paths and hands them to
+// apply. An add or replace without a path carries an object of attributes,
+// each of which is treated as a path of its own. Attributes of extension
+// schemas are ignored, since nothing is stored for them.
+func applyOperation(op PatchOperation, apply func(op string, p patchPath, value json.RawMessage) error) error {
+	if op.Path != "" {
+		p, err := parsePath(op.Path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(p.attr, "urn:") {
+			return nil
+		}
+		return apply(op.Op, p, op.Value)
+	}
+
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &attrs); err != nil {
+		return BadRequest(ErrInvalidValue, "The value of an operation without a path must be an object")
+	}
+	for key, value := range attrs {
+		p, err := parsePath(key)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(p.attr, "urn:") || p.attr == "schemas" || p.attr == "id" || p.attr == "meta" {
+			continue
+		}
+		if err := apply(op.Op, p, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeString(attr string, value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", BadRequest(ErrInvalidValue, attr+" must be a string")
+	}
+	return s, nil
+}
+
+// decodeBool accepts "True" and "False" strings as well as booleans, as
+// some identity providers send those
+func decodeBool(attr string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, BadRequest(ErrInvalidValue, attr+" must be a boolean")
+}
+
+// decodeList decodes a multi-valued attribute, accepting a single value in
+// place of a list of one
+func decodeList[T any](attr string, value json.RawMessage) ([]T, error) {
+	var list []T
+	if err := json.Unmarshal(value, &list); err == nil {
+		return list, nil
+	}
+	var one T
+	if err := json.Unmarshal(value, &one); err != nil {
+		return nil, BadRequest(ErrInvalidValue, attr+" has an invalid value")
+	}
+	return []T{one}, nil
+}
+
+// ApplyPatch applies PATCH operations to the user. Operations before a
+// failing one are applied, so callers should discard the user on error.
+func (u *User) ApplyPatch(ops []PatchOperation) error {
+	for _, op := range ops {
+		if err := applyOperation(op, u.applyPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *User) applyPath(op string, p patchPath, value json.RawMessage) error {
+	if p.filter != nil && p.attr != "emails" {
+		return BadRequest(ErrInvalidPath, "Filters are only supported on emails")
+	}
+	remove := op == OpRemove
+
+	switch p.attr {
+	case "active":
+		if remove {
+			return BadRequest(ErrMutability, "active can't be removed")
+		}
+		active, err := decodeBool("active", value)
+		if err != nil {
+			return err
+		}
+		u.Active = active
+	case "username":
+		if remove {
+			return BadRequest(ErrMutability, "userName can't be removed")
+		}
+		s, err := decodeString("userName", value)
+		if err != nil {
+			return err
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return BadRequest(ErrInvalidValue, "userName is required")
+		}
+		u.UserName = s
+	case "externalid", "displayname":
+		var s string
+		if !remove {
+			var err error
+			if s, err = decodeString(p.attr, value); err != nil {
+				return err
+			}
+		}
+		if p.attr == "externalid" {
+			u.ExternalID = s
+		} else {
+			u.DisplayName = s
+		}
+	case "name":
+		return u.applyName(remove, p.sub, value)
+	case "emails":
+		return u.applyEmails(op, p, value)
+	case "groups":
+		return BadRequest(ErrMutability, "groups is read-only; patch the group instead")
+	default:
+		return BadRequest(ErrInvalidPath, "Unsupported attribute: "+p.attr)
+	}
+	return nil
+}
+
+func (u *User) applyName(remove bool, sub string, value json.RawMessage) error {
+	if u.Name == nil {
+		u.Name = &Name{}
+	}
+	if sub == "" {
+		if remove {
+			u.Name = nil
+			return nil
+		}
+		// Only the sub-attributes given are changed
+		var n struct {
+			Formatted  *string `json:"formatted"`
+			GivenName  *string `json:"givenName"`
+			FamilyName *string `json:"familyName"`
+		}
+		if err := json.Unmarshal(value, &n); err != nil {
+			return BadRequest(ErrInvalidValue, "name must be an object")
+		}
+		for _, f := range []struct {
+			src *string
+			dst *string
+		}{{n.Formatted, &u.Name.Formatted}, {n.GivenName, &u.Name.GivenName}, {n.FamilyName, &u.Name.FamilyName}} {
+			if f.src != nil {
+				*f.dst = *f.src
+			}
+		}
+		return nil
+	}
+
+	var dst *string
+	switch sub {
+	case "formatted":
+		dst = &u.Name.Formatted
+	case "givenname":
+		dst = &u.Name.GivenName
+	case "familyname":
+		dst = &u.Name.FamilyName
+	default:
+		return BadRequest(ErrInvalidPath, "Unsupported attribute: name."+sub)
+	}
+	if remove {
+		*dst = ""
+		return nil
+	}
+	s, err := decodeString("name."+sub, value)
+	if err != nil {
+		return err
+	}
+	*dst = s
+	return nil
+}
+
+func (u *User) applyEmails(op string, p patchPath, value json.RawMessage) error {
+	if p.filter == nil {
+		if p.sub != "" {
+			return BadRequest(ErrInvalidPath, "Unsupported attribute: emails."+p.sub)
+		}
+		if op == OpRemove {
+			u.Emails = nil
+			return nil
+		}
+		emails, err := decodeList[Email]("emails", value)
+		if err != nil {
+			return err
+		}
+		if op == OpAdd {
+			u.Emails = append(u.Emails, emails...)
+		} else {
+			u.Emails = emails
+		}
+		return nil
+	}
+
+	match := func(e Email) bool {
+		switch p.filter.Attribute {
+		case "type":
+			return strings.EqualFold(e.Type, p.filter.Value)
+		case "value":
+			return strings.EqualFold(e.Value, p.filter.Value)
+		case "primary":
+			return e.Primary == strings.EqualFold(p.filter.Value, "true")
+		}
+		return false
+	}
+	if p.filter.Attribute != "type" && p.filter.Attribute != "value" && p.filter.Attribute != "primary" {
+		return BadRequest(ErrInvalidPath, "Unsupported emails filter: "+p.filter.Attribute)
+	}
+	if p.sub != "" && p.sub != "value" {
+		return BadRequest(ErrInvalidPath, "Unsupported attribute: emails."+p.sub)
+	}
+
+	if op == OpRemove {
+		kept := u.Emails[:0]
+		for _, e := range u.Emails {
+			if !match(e) {
+				kept = append(kept, e)
+			}
+		}
+		u.Emails = kept
+		return nil
+	}
+
+	var email Email
+	if p.sub == "value" {
+		s, err := decodeString("emails.value", value)
+		if err != nil {
+			return err
+		}
+		email.Value = s
+	} else if err := json.Unmarshal(value, &email); err != nil {
+		return BadRequest(ErrInvalidValue, "emails has an invalid value")
+	}
+	for i, e := range u.Emails {
+		if match(e) {
+			if p.sub == "value" {
+				u.Emails[i].Value = email.Value
+			} else {
+				u.Emails[i] = email
+			}
+			return nil
+		}
+	}
+	// Nothing matched; add the address, keeping the type it was looked up by
+	if p.filter.Attribute == "type" && email.Type == "" {
+		email.Type = p.filter.Value
+	}
+	u.Emails = append(u.Emails, email)
+	return nil
+}
+
+// ApplyPatch applies PATCH operations to the group
+func (g *Group) ApplyPatch(ops []PatchOperation) error {
+	for _, op := range ops {
+		if err := applyOperation(op, g.applyPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Group) applyPath(op string, p patchPath, value json.RawMessage) error {
+	switch p.attr {
+	case "displayname":
+		if op == OpRemove {
+			return BadRequest(ErrMutability, "displayName can't be removed")
+		}
+		s, err := decodeString("displayName", value)
+		if err != nil {
+			return err
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			return BadRequest(ErrInvalidValue, "displayName is required")
+		}
+		g.DisplayName = s
+	case "externalid":
+		var s string
+		if op != OpRemove {
+			var err error
+			if s, err = decodeString("externalId", value); err != nil {
+				return err
+			}
+		}
+		g.ExternalID = s
+	case "members":
+		return g.applyMembers(op, p, value)
+	default:
+		return BadRequest(ErrInvalidPath, "Unsupported attribute: "+p.attr)
+	}
+	return nil
+}
+
+func (g *Group) applyMembers(op string, p patchPath, value json.RawMessage) error {
+	if p.sub != "" {
+		return BadRequest(ErrInvalidPath, "Unsupported attribute: members."+p.sub)
+	}
+
+	// members[value eq "id"] names a single member, which can only be removed
+	if p.filter != nil {
+		if p.filter.Attribute != "value" || op != OpRemove {
+			return BadRequest(ErrInvalidPath, "Only removing members by value is supported")
+		}
+		g.removeMembers(map[string]bool{p.filter.Value: true})
+		return nil
+	}
+
+	var members []Reference
+	if len(value) > 0 && string(value) != "null" {
+		var err error
+		if members, err = decodeList[Reference]("members", value); err != nil {
+			return err
+		}
+	}
+
+	switch op {
+	case OpRemove:
+		// Without a value every member is removed
+		if len(members) == 0 {
+			g.Members = []Reference{}
+			return nil
+		}
+		ids := make(map[string]bool, len(members))
+		for _, m := range members {
+			ids[m.Value] = true
+		}
+		g.removeMembers(ids)
+	case OpReplace:
+		g.Members = []Reference{}
+		g.addMembers(members)
+	default:
+		g.addMembers(members)
+	}
+	return nil
+}
+
+func (g *Group) addMembers(members []Reference) {
+	seen := make(map[string]bool, len(g.Members))
+	for _, m := range g.Members {
+		seen[m.Value] = true
+	}
+	for _, m := range members {
+		if m.Value != "" && !seen[m.Value] {
+			seen[m.Value] = true
+			g.Members = append(g.Members, m)
+		}
+	}
+}
+
+func (g *Group) removeMembers(ids map[string]bool) {
+	kept := []Reference{}
+	for _, m := range g.Members {
+		if !ids[m.Value] {
+			kept = append(kept, m)
+		}
+	}
+	g.Members = kept
+}
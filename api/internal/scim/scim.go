@@ -0,0 +1,307 @@
+// Package scim implements the parts of SCIM 2.0 (RFC 7643 and RFC 7644)
+// that identity providers use to provision accounts: the core User and
+// Group resources, list responses, equality filters and PATCH operations.
+//
+// It only deals with the protocol. Which users and groups exist, and what
+// they grant, is left to the caller.
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaEnterpriseUser        = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// Error types from RFC 7644 section 3.12
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrNoTarget      = "noTarget"
+	ErrMutability    = "mutability"
+	ErrUniqueness    = "uniqueness"
+)
+
+// Paging limits for list requests
+const (
+	DefaultCount = 100
+	MaxCount     = 200
+)
+
+// Error is a SCIM error response
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+
+	code int
+}
+
+// NewError returns an error response with an HTTP status and, for 400 and
+// 409 responses, a SCIM error type.
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+		code:     status,
+	}
+}
+
+// BadRequest returns a 400 error of a SCIM error type
+func BadRequest(scimType, detail string) *Error {
+	return NewError(http.StatusBadRequest, scimType, detail)
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// StatusCode is the HTTP status to respond with
+func (e *Error) StatusCode() int {
+	return e.code
+}
+
+// Meta is the metadata common to all resources
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created,omitzero"`
+	LastModified time.Time `json:"lastModified,omitzero"`
+	Location     string    `json:"location,omitempty"`
+}
+
+// Name is a user's name
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is one of a user's email addresses
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Reference points at another resource, like a group member
+type Reference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is the core User resource
+type User struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *Name       `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []Email     `json:"emails,omitempty"`
+	Active      bool        `json:"active"`
+	Groups      []Reference `json:"groups,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// ParseUser decodes a User from a create or replace request. Users are
+// active unless the request says otherwise.
+func ParseUser(data []byte) (*User, error) {
+	u := User{Active: true}
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, BadRequest(ErrInvalidSyntax, "Request body is not a valid User")
+	}
+	u.UserName = strings.TrimSpace(u.UserName)
+	if u.UserName == "" {
+		return nil, BadRequest(ErrInvalidValue, "userName is required")
+	}
+	return &u, nil
+}
+
+// Email returns the user's primary email address, falling back to the
+// first one listed and then to the userName.
+func (u *User) Email() string {
+	for _, e := range u.Emails {
+		if e.Primary && e.Value != "" {
+			return e.Value
+		}
+	}
+	for _, e := range u.Emails {
+		if e.Value != "" {
+			return e.Value
+		}
+	}
+	return u.UserName
+}
+
+// Group is the core Group resource
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []Reference `json:"members,omitempty"`
+	Meta        *Meta       `json:"meta,omitempty"`
+}
+
+// ParseGroup decodes a Group from a create or replace request
+func ParseGroup(data []byte) (*Group, error) {
+	var g Group
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, BadRequest(ErrInvalidSyntax, "Request body is not a valid Group")
+	}
+	g.DisplayName = strings.TrimSpace(g.DisplayName)
+	if g.DisplayName == "" {
+		return nil, BadRequest(ErrInvalidValue, "displayName is required")
+	}
+	return &g, nil
+}
+
+// Supported is a feature of the service provider that is on or off
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+// FilterSupport describes filtering support
+type FilterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+// BulkSupport describes bulk operation support
+type BulkSupport struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+// AuthenticationScheme is a way clients can authenticate
+type AuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary,omitempty"`
+}
+
+// ServiceProviderConfig describes the SCIM features a service provider
+// supports (RFC 7643 section 5)
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 Supported              `json:"patch"`
+	Bulk                  BulkSupport            `json:"bulk"`
+	Filter                FilterSupport          `json:"filter"`
+	ChangePassword        Supported              `json:"changePassword"`
+	Sort                  Supported              `json:"sort"`
+	ETag                  Supported              `json:"etag"`
+	AuthenticationSchemes []AuthenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
+
+// NewServiceProviderConfig describes what this package implements: PATCH
+// and equality filters, authenticated by a bearer token
+func NewServiceProviderConfig() *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas: []string{SchemaServiceProviderConfig},
+		Patch:   Supported{Supported: true},
+		Filter:  FilterSupport{Supported: true, MaxResults: MaxCount},
+		AuthenticationSchemes: []AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer Token",
+			Description: "Authentication with a bearer token",
+			Primary:     true,
+		}},
+	}
+}
+
+// ResourceType describes a resource endpoint (RFC 7643 section 6)
+type ResourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description,omitempty"`
+	Schema      string   `json:"schema"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// ResourceTypes returns the User and Group resource types, with their
+// locations under baseURL
+func ResourceTypes(baseURL string) []ResourceType {
+	return []ResourceType{
+		{
+			Schemas: []string{SchemaResourceType}, ID: "User", Name: "User", Endpoint: "/Users",
+			Schema: SchemaUser, Meta: &Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/User"},
+		},
+		{
+			Schemas: []string{SchemaResourceType}, ID: "Group", Name: "Group", Endpoint: "/Groups",
+			Schema: SchemaGroup, Meta: &Meta{ResourceType: "ResourceType", Location: baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+// ListResponse is the response to a list or filter request
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// NewListResponse returns one page of resources out of total
+func NewListResponse(resources []interface{}, total, startIndex int) *ListResponse {
+	if resources == nil {
+		resources = []interface{}{}
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// ParsePagination reads the 1-based startIndex and count query parameters
+func ParsePagination(q url.Values) (startIndex, count int, err error) {
+	startIndex, count = 1, DefaultCount
+	if s := q.Get("startIndex"); s != "" {
+		n, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			return 0, 0, BadRequest(ErrInvalidValue, "startIndex must be a number")
+		}
+		if n > 1 {
+			startIndex = n
+		}
+	}
+	if s := q.Get("count"); s != "" {
+		n, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			return 0, 0, BadRequest(ErrInvalidValue, "count must be a number")
+		}
+		count = min(max(n, 0), MaxCount)
+	}
+	return startIndex, count, nil
+}
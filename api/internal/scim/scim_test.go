@@ -0,0 +1,239 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	for filter, want := range map[string]Filter{
+		`userName eq "jane@example.com"`:                                      {"username", "jane@example.com"},
+		`externalId EQ "a \"quoted\" id"`:                                     {"externalid", `a "quoted" id`},
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "Jane"`:       {"username", "Jane"},
+		`displayName eq "Engineering Team"`:                                   {"displayname", "Engineering Team"},
+		`emails[type eq "work"]`:                                              {}, // rejected below
+		`active eq true`:                                                      {"active", "true"},
+		`  urn:ietf:params:scim:schemas:core:2.0:Group:displayName eq "Ops" `: {"displayname", "Ops"},
+	} {
+		f, err := ParseFilter(filter)
+		if want == (Filter{}) {
+			if err == nil {
+				t.Errorf("ParseFilter(%q): expected an error", filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", filter, err)
+			continue
+		}
+		if *f != want {
+			t.Errorf("ParseFilter(%q) = %+v, want %+v", filter, *f, want)
+		}
+	}
+
+	for _, filter := range []string{
+		`userName`,
+		`userName sw "jane"`,
+		`userName eq "a" or userName eq "b"`,
+		`userName eq "unterminated`,
+	} {
+		_, err := ParseFilter(filter)
+		var scimErr *Error
+		if !errors.As(err, &scimErr) || scimErr.ScimType != ErrInvalidFilter {
+			t.Errorf("ParseFilter(%q) = %v, want an invalidFilter error", filter, err)
+		}
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	for query, want := range map[string][2]int{
+		"":                       {1, DefaultCount},
+		"startIndex=0&count=-5":  {1, 0},
+		"startIndex=11&count=10": {11, 10},
+		"count=100000":           {1, MaxCount},
+	} {
+		q, _ := url.ParseQuery(query)
+		start, count, err := ParsePagination(q)
+		if err != nil || start != want[0] || count != want[1] {
+			t.Errorf("ParsePagination(%q) = %d, %d, %v", query, start, count, err)
+		}
+	}
+	if _, _, err := ParsePagination(url.Values{"count": {"ten"}}); err == nil {
+		t.Error("expected an error for a non-numeric count")
+	}
+}
+
+func TestParseUser(t *testing.T) {
+	u, err := ParseUser([]byte(`{"schemas":["` + SchemaUser + `"],"userName":" jane@example.com ",
+		"name":{"givenName":"Jane","familyName":"Doe"},
+		"emails":[{"value":"jane.doe@example.com","type":"home"},{"value":"jane@example.com","type":"work","primary":true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Active {
+		t.Error("users should be active unless the request says otherwise")
+	}
+	if u.UserName != "jane@example.com" || u.Email() != "jane@example.com" {
+		t.Errorf("userName = %q, email = %q", u.UserName, u.Email())
+	}
+
+	u, err = ParseUser([]byte(`{"userName":"bob","active":false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Active || u.Email() != "bob" {
+		t.Errorf("active = %v, email = %q", u.Active, u.Email())
+	}
+
+	_, err = ParseUser([]byte(`{"name":{"givenName":"Nobody"}}`))
+	var scimErr *Error
+	if !errors.As(err, &scimErr) || scimErr.StatusCode() != http.StatusBadRequest || scimErr.ScimType != ErrInvalidValue {
+		t.Errorf("missing userName: got %v", err)
+	}
+}
+
+func TestParsePatch(t *testing.T) {
+	req, err := ParsePatch([]byte(`{"schemas":["` + SchemaPatchOp + `"],
+		"Operations":[{"op":"Replace","path":"active","value":"False"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Operations[0].Op != OpReplace {
+		t.Errorf("op = %q, want it lower-cased", req.Operations[0].Op)
+	}
+
+	for name, body := range map[string]string{
+		"no operations":         `{"Operations":[]}`,
+		"unknown op":            `{"Operations":[{"op":"move","path":"userName","value":"x"}]}`,
+		"add without value":     `{"Operations":[{"op":"add","path":"userName"}]}`,
+		"remove without path":   `{"Operations":[{"op":"remove"}]}`,
+		"not json":              `Operations`,
+		"operations not a list": `{"Operations":{"op":"add"}}`,
+	} {
+		if _, err := ParsePatch([]byte(body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func patchUser(t *testing.T, u *User, body string) error {
+	t.Helper()
+	req, err := ParsePatch([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.ApplyPatch(req.Operations)
+}
+
+func TestUserApplyPatch(t *testing.T) {
+	u := &User{
+		UserName: "jane@example.com",
+		Name:     &Name{GivenName: "Jane", FamilyName: "Doe"},
+		Emails:   []Email{{Value: "jane@example.com", Type: "work", Primary: true}},
+		Active:   true,
+	}
+
+	// Azure AD style: one operation per path, with string booleans
+	if err := patchUser(t, u, `{"Operations":[
+		{"op":"Replace","path":"active","value":"False"},
+		{"op":"Replace","path":"name.familyName","value":"Smith"},
+		{"op":"Replace","path":"emails[type eq \"work\"].value","value":"jane.smith@example.com"},
+		{"op":"Add","path":"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department","value":"Sales"}
+	]}`); err != nil {
+		t.Fatal(err)
+	}
+	if u.Active || u.Name.FamilyName != "Smith" || u.Name.GivenName != "Jane" {
+		t.Errorf("got active = %v, name = %+v", u.Active, *u.Name)
+	}
+	if len(u.Emails) != 1 || u.Emails[0].Value != "jane.smith@example.com" || !u.Emails[0].Primary {
+		t.Errorf("emails = %+v", u.Emails)
+	}
+
+	// Okta style: no path, an object of attributes
+	if err := patchUser(t, u, `{"Operations":[{"op":"replace","value":{
+		"active":true,"userName":"jane.smith@example.com","name":{"givenName":"Janet"},
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User":{"department":"Sales"}
+	}}]}`); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Active || u.UserName != "jane.smith@example.com" || *u.Name != (Name{GivenName: "Janet", FamilyName: "Smith"}) {
+		t.Errorf("got active = %v, userName = %q, name = %+v", u.Active, u.UserName, *u.Name)
+	}
+
+	if err := patchUser(t, u, `{"Operations":[
+		{"op":"add","path":"emails[type eq \"home\"].value","value":"jane@home.example"},
+		{"op":"remove","path":"emails[type eq \"work\"]"},
+		{"op":"add","path":"externalId","value":"00u1"}
+	]}`); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Email{{Value: "jane@home.example", Type: "home"}}; !reflect.DeepEqual(u.Emails, want) {
+		t.Errorf("emails = %+v, want %+v", u.Emails, want)
+	}
+	if u.ExternalID != "00u1" {
+		t.Errorf("externalId = %q", u.ExternalID)
+	}
+
+	for name, body := range map[string]string{
+		"unknown attribute": `{"Operations":[{"op":"replace","path":"nickName","value":"JJ"}]}`,
+		"remove userName":   `{"Operations":[{"op":"remove","path":"userName"}]}`,
+		"active not a bool": `{"Operations":[{"op":"replace","path":"active","value":"maybe"}]}`,
+		"groups":            `{"Operations":[{"op":"add","path":"groups","value":[{"value":"1"}]}]}`,
+		"filter on name":    `{"Operations":[{"op":"replace","path":"name[givenName eq \"x\"]","value":"y"}]}`,
+	} {
+		var scimErr *Error
+		if err := patchUser(t, u, body); !errors.As(err, &scimErr) || scimErr.StatusCode() != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want a 400 error", name, err)
+		}
+	}
+}
+
+func TestGroupApplyPatch(t *testing.T) {
+	g := &Group{DisplayName: "Engineering", Members: []Reference{{Value: "1"}, {Value: "2"}}}
+	apply := func(body string) {
+		t.Helper()
+		req, err := ParsePatch([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.ApplyPatch(req.Operations); err != nil {
+			t.Fatal(err)
+		}
+	}
+	members := func() []string {
+		ids := []string{}
+		for _, m := range g.Members {
+			ids = append(ids, m.Value)
+		}
+		return ids
+	}
+
+	apply(`{"Operations":[{"op":"add","path":"members","value":[{"value":"2"},{"value":"3"}]}]}`)
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(members(), want) {
+		t.Errorf("after add: members = %v, want %v", members(), want)
+	}
+
+	apply(`{"Operations":[{"op":"remove","path":"members[value eq \"1\"]"},
+		{"op":"Remove","path":"members","value":[{"value":"3"}]}]}`)
+	if want := []string{"2"}; !reflect.DeepEqual(members(), want) {
+		t.Errorf("after remove: members = %v, want %v", members(), want)
+	}
+
+	apply(`{"Operations":[{"op":"replace","value":{"displayName":"Platform","members":[{"value":"4"}]}}]}`)
+	if want := []string{"4"}; g.DisplayName != "Platform" || !reflect.DeepEqual(members(), want) {
+		t.Errorf("after replace: displayName = %q, members = %v", g.DisplayName, members())
+	}
+
+	apply(`{"Operations":[{"op":"remove","path":"members"}]}`)
+	if len(members()) != 0 {
+		t.Errorf("after removing all: members = %v", members())
+	}
+
+	req, _ := ParsePatch([]byte(`{"Operations":[{"op":"add","path":"members[value eq \"5\"]","value":{"value":"5"}}]}`))
+	if err := g.ApplyPatch(req.Operations); err == nil {
+		t.Error("expected an error adding through a filter")
+	}
+}
@@ -0,0 +1,262 @@
+// Package secrets encrypts the third-party credentials TaskAI stores, such as
+// GitHub tokens and provider API keys, with envelope encryption. Every value
+// is sealed with its own random data key, and the data key is sealed with a
+// master key from the configuration. Rotating the master key only re-seals
+// the data keys.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks encrypted values: enc:v1:<key id>:<sealed data key>:<sealed value>
+const prefix = "enc:v1:"
+
+var (
+	// ErrNoKey is returned when a value can't be encrypted or decrypted
+	// because no master key is configured
+	ErrNoKey = errors.New("secrets: no encryption key configured")
+	// ErrUnknownKey is returned for values sealed with a master key the
+	// keyring doesn't have
+	ErrUnknownKey = errors.New("secrets: value was encrypted with an unknown key")
+	// ErrMalformed is returned for encrypted values that can't be parsed or
+	// fail authentication
+	ErrMalformed = errors.New("secrets: malformed encrypted value")
+)
+
+type masterKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring holds the current master key, which seals new values, and the
+// previous keys, which can still open values sealed before a rotation. A nil
+// Keyring stores values unencrypted.
+type Keyring struct {
+	current *masterKey
+	keys    map[string]*masterKey
+}
+
+// NewKeyring creates a keyring from 32-byte master keys.
+func NewKeyring(current []byte, previous ...[]byte) (*Keyring, error) {
+	k := &Keyring{keys: map[string]*masterKey{}}
+	for i, raw := range append([][]byte{current}, previous...) {
+		mk, err := newMasterKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			k.current = mk
+		}
+		k.keys[mk.id] = mk
+	}
+	return k, nil
+}
+
+// ParseKeyring creates a keyring from 64-character hex-encoded master keys.
+func ParseKeyring(current string, previous []string) (*Keyring, error) {
+	decode := func(s string) ([]byte, error) {
+		b, err := hex.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(b) != 32 {
+			return nil, errors.New("secrets: keys must be 64-char hex strings (32 bytes)")
+		}
+		return b, nil
+	}
+	cur, err := decode(current)
+	if err != nil {
+		return nil, err
+	}
+	var prev [][]byte
+	for _, p := range previous {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		b, err := decode(p)
+		if err != nil {
+			return nil, err
+		}
+		prev = append(prev, b)
+	}
+	return NewKeyring(cur, prev...)
+}
+
+func newMasterKey(raw []byte) (*masterKey, error) {
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("secrets: master key must be 32 bytes, got %d", len(raw))
+	}
+	aead, err := newGCM(raw)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return &masterKey{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyID identifies the current master key, or is empty for a nil keyring.
+func (k *Keyring) KeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.current.id
+}
+
+// IsEncrypted reports whether a stored value is sealed.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt seals a value with a new data key. Empty values stay empty, so
+// "not configured" needs no key. A nil keyring returns the value unchanged.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if k == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return k.current.wrap(dataKey, sealedValue)
+}
+
+// Decrypt opens a sealed value. Values stored before encryption was enabled
+// are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	dataKey, sealedValue, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, sealedValue, nil)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation reports whether a stored value is unencrypted or sealed with
+// a key other than the current one.
+func (k *Keyring) NeedsRotation(value string) bool {
+	if value == "" || k == nil {
+		return false
+	}
+	id, _, _, ok := parse(value)
+	return !ok || id != k.current.id
+}
+
+// Rotate re-seals a value's data key with the current master key, encrypting
+// unencrypted values. Values already sealed with the current key are returned
+// unchanged.
+func (k *Keyring) Rotate(value string) (string, error) {
+	if k == nil {
+		return "", ErrNoKey
+	}
+	if !k.NeedsRotation(value) {
+		return value, nil
+	}
+	if !IsEncrypted(value) {
+		return k.Encrypt(value)
+	}
+	dataKey, sealedValue, err := k.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+	return k.current.wrap(dataKey, sealedValue)
+}
+
+// wrap seals a data key with the master key and formats the stored value.
+// The key ID is authenticated with the data key.
+func (mk *masterKey) wrap(dataKey, sealedValue []byte) (string, error) {
+	sealedKey, err := seal(mk.aead, dataKey, []byte(mk.id))
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return prefix + mk.id + ":" + enc.EncodeToString(sealedKey) + ":" + enc.EncodeToString(sealedValue), nil
+}
+
+// unwrap opens the data key of a sealed value.
+func (k *Keyring) unwrap(value string) (dataKey, sealedValue []byte, err error) {
+	id, sealedKey, sealedValue, ok := parse(value)
+	if !ok {
+		return nil, nil, ErrMalformed
+	}
+	if k == nil {
+		return nil, nil, ErrNoKey
+	}
+	mk, ok := k.keys[id]
+	if !ok {
+		return nil, nil, ErrUnknownKey
+	}
+	dataKey, err = open(mk.aead, sealedKey, []byte(id))
+	if err != nil {
+		return nil, nil, ErrMalformed
+	}
+	return dataKey, sealedValue, nil
+}
+
+func parse(value string) (id string, sealedKey, sealedValue []byte, ok bool) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, false
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, false
+	}
+	enc := base64.RawURLEncoding
+	sealedKey, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, false
+	}
+	sealedValue, err = enc.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, false
+	}
+	return parts[0], sealedKey, sealedValue, true
+}
+
+// seal encrypts with a random nonce, which prefixes the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	sealed, err := k.Encrypt("ghp_secret")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if !IsEncrypted(sealed) || strings.Contains(sealed, "ghp_secret") || !strings.Contains(sealed, k.KeyID()) {
+		t.Errorf("Expected a sealed value with the key ID, got %q", sealed)
+	}
+	again, _ := k.Encrypt("ghp_secret")
+	if again == sealed {
+		t.Error("Expected a new data key and nonce for every value")
+	}
+
+	got, err := k.Decrypt(sealed)
+	if err != nil || got != "ghp_secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if got, err := k.Decrypt("legacy-plaintext"); err != nil || got != "legacy-plaintext" {
+		t.Errorf("Expected unencrypted values to pass through, got %q, %v", got, err)
+	}
+	if got, _ := k.Encrypt(""); got != "" {
+		t.Errorf("Expected empty values to stay empty, got %q", got)
+	}
+
+	tampered := []byte(sealed)
+	i := len(tampered) - 5
+	if tampered[i] == 'A' {
+		tampered[i] = 'B'
+	} else {
+		tampered[i] = 'A'
+	}
+	if _, err := k.Decrypt(string(tampered)); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Expected tampering to be detected, got %v", err)
+	}
+	other, _ := NewKeyring(testKey(2))
+	if _, err := other.Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected an unknown key error, got %v", err)
+	}
+}
+
+func TestNilKeyring(t *testing.T) {
+	var k *Keyring
+	if got, err := k.Encrypt("token"); err != nil || got != "token" {
+		t.Errorf("Expected a nil keyring to store values as they are, got %q, %v", got, err)
+	}
+	if got, err := k.Decrypt("token"); err != nil || got != "token" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	sealer, _ := NewKeyring(testKey(1))
+	sealed, _ := sealer.Encrypt("token")
+	if _, err := k.Decrypt(sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+	if _, err := k.Rotate("token"); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	old, _ := NewKeyring(testKey(1))
+	sealed, _ := old.Encrypt("figd_token")
+
+	k, err := NewKeyring(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	if got, err := k.Decrypt(sealed); err != nil || got != "figd_token" {
+		t.Fatalf("Expected previous keys to open old values, got %q, %v", got, err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"sealed with a previous key", sealed, true},
+		{"unencrypted", "figd_token", true},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k.NeedsRotation(tt.value); got != tt.want {
+				t.Fatalf("NeedsRotation = %v, want %v", got, tt.want)
+			}
+			rotated, err := k.Rotate(tt.value)
+			if err != nil {
+				t.Fatalf("Rotate: %v", err)
+			}
+			if k.NeedsRotation(rotated) {
+				t.Errorf("Expected %q to be sealed with the current key", rotated)
+			}
+			if got, _ := k.Decrypt(rotated); tt.value != "" && got != "figd_token" {
+				t.Errorf("Decrypt after rotation = %q", got)
+			}
+		})
+	}
+
+	rotated, _ := k.Rotate(sealed)
+	_, _, before, _ := parse(sealed)
+	_, _, after, _ := parse(rotated)
+	if !bytes.Equal(before, after) {
+		t.Error("Expected rotation to re-seal only the data key")
+	}
+	current, _ := NewKeyring(testKey(2))
+	if got, err := current.Decrypt(rotated); err != nil || got != "figd_token" {
+		t.Errorf("Expected the rotated value to open without the previous key, got %q, %v", got, err)
+	}
+	if same, _ := k.Rotate(rotated); same != rotated {
+		t.Error("Expected values sealed with the current key to be left alone")
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	if _, err := ParseKeyring(hexKey, []string{"", strings.Repeat("cd", 32)}); err != nil {
+		t.Errorf("ParseKeyring: %v", err)
+	}
+	for _, bad := range []string{"", "not-hex", strings.Repeat("ab", 16)} {
+		if _, err := ParseKeyring(bad, nil); err == nil {
+			t.Errorf("ParseKeyring(%q): expected an error", bad)
+		}
+	}
+	if _, err := ParseKeyring(hexKey, []string{"short"}); err == nil {
+		t.Error("Expected invalid previous keys to be rejected")
+	}
+}
@@ -0,0 +1,353 @@
+package sso
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// discoveryTTL is how long discovered issuer metadata is reused
+	discoveryTTL = time.Hour
+	// keyRefreshInterval limits how often keys are refetched for an
+	// unknown key ID
+	keyRefreshInterval = time.Minute
+	// maxResponseSize caps documents fetched from an issuer
+	maxResponseSize = 1 << 20
+)
+
+// OIDCClient is this application as registered with an OpenID Connect issuer
+type OIDCClient struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// OIDCProvider is an OpenID Connect issuer, as found by discovery
+type OIDCProvider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+
+	fetched       time.Time
+	mu            sync.Mutex
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+var providerCache = struct {
+	sync.Mutex
+	m map[string]*OIDCProvider
+}{m: map[string]*OIDCProvider{}}
+
+// ValidateIssuerURL checks an issuer or endpoint URL. HTTPS is required,
+// except on loopback addresses for local identity providers while
+// AllowPrivateIssuers is set.
+func ValidateIssuerURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must be an absolute URL without query or fragment")
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); AllowPrivateIssuers && u.Scheme == "http" && (host == "localhost" || ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return errors.New("must use https")
+}
+
+// DiscoverOIDC fetches an issuer's metadata from its well-known
+// configuration document. Results are cached for an hour.
+func DiscoverOIDC(ctx context.Context, issuer string) (*OIDCProvider, error) {
+	providerCache.Lock()
+	p, ok := providerCache.m[issuer]
+	providerCache.Unlock()
+	if ok && time.Since(p.fetched) < discoveryTTL {
+		return p, nil
+	}
+
+	if err := ValidateIssuerURL(issuer); err != nil {
+		return nil, fmt.Errorf("issuer %w", err)
+	}
+	p = &OIDCProvider{}
+	if err := getJSON(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", p); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("discovery returned issuer %q, expected %q", p.Issuer, issuer)
+	}
+	for _, endpoint := range []string{p.AuthorizationEndpoint, p.TokenEndpoint, p.JWKSURI} {
+		if err := ValidateIssuerURL(endpoint); err != nil {
+			return nil, fmt.Errorf("discovered endpoint %q %w", endpoint, err)
+		}
+	}
+	p.fetched = time.Now()
+
+	providerCache.Lock()
+	providerCache.m[issuer] = p
+	providerCache.Unlock()
+	return p, nil
+}
+
+// CodeChallenge returns the PKCE S256 challenge for a code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the URL that sends the user to the issuer to log in
+func (p *OIDCProvider) AuthCodeURL(client OIDCClient, state, nonce, codeVerifier string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ClientID},
+		"redirect_uri":          {client.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(p.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.AuthorizationEndpoint + sep + q.Encode()
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string      `json:"nonce"`
+	AuthorizedBy  string      `json:"azp"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+}
+
+// Exchange redeems an authorization code and verifies the ID token that
+// comes back, including that it was issued for nonce
+func (p *OIDCProvider) Exchange(ctx context.Context, client OIDCClient, code, codeVerifier, nonce string) (*Identity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {client.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	basic := len(p.TokenAuthMethods) == 0 || slices.Contains(p.TokenAuthMethods, "client_secret_basic")
+	if !basic {
+		form.Set("client_id", client.ClientID)
+		form.Set("client_secret", client.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(client.ClientID), url.QueryEscape(client.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return nil, fmt.Errorf("invalid token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, verificationError("token request rejected: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, verificationError("token response has no ID token")
+	}
+
+	claims := &idTokenClaims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(client.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if _, err := parser.ParseWithClaims(token.IDToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}); err != nil {
+		return nil, verificationError("invalid ID token: %v", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, verificationError("ID token nonce mismatch")
+	}
+	if claims.AuthorizedBy != "" && claims.AuthorizedBy != client.ClientID {
+		return nil, verificationError("ID token was issued to another client")
+	}
+	if claims.Subject == "" {
+		return nil, verificationError("ID token has no subject")
+	}
+	if claims.Email == "" {
+		return nil, verificationError("ID token has no email address")
+	}
+	// Providers that don't verify emails say so; ones that always do, like
+	// Azure AD, leave the claim out
+	if v := claims.EmailVerified; v == false || v == "false" {
+		return nil, verificationError("email address is not verified")
+	}
+
+	return &Identity{
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		FirstName: claims.GivenName,
+		LastName:  claims.FamilyName,
+		Name:      claims.Name,
+	}, nil
+}
+
+// key returns the issuer's signing key with the given ID, refetching the
+// key set when the ID is new, since issuers rotate keys
+func (p *OIDCProvider) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	find := func() crypto.PublicKey {
+		if kid == "" && len(p.keys) == 1 {
+			for _, k := range p.keys {
+				return k
+			}
+		}
+		return p.keys[kid]
+	}
+	if k := find(); k != nil {
+		return k, nil
+	}
+	if time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetching signing keys: %w", err)
+	}
+	p.keys = map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if k, err := jwk.publicKey(); err == nil {
+			p.keys[jwk.Kid] = k
+		}
+	}
+	p.keysFetchedAt = time.Now()
+
+	if k := find(); k != nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	field := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := field(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := field(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := field(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := field(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		point := append([]byte{4}, x.FillBytes(make([]byte, size))...)
+		point = append(point, y.FillBytes(make([]byte, size))...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func getJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", rawURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v)
+}
@@ -0,0 +1,345 @@
+package sso
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SAML namespaces, bindings and formats
+const (
+	nsSAML   = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsSAMLP  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsSAMLMD = "urn:oasis:names:tc:SAML:2.0:metadata"
+
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer    = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	nameIDEmail     = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+)
+
+// IdPMetadata is what's used from an identity provider's SAML metadata
+type IdPMetadata struct {
+	EntityID string
+	// SSOURL receives authentication requests with the HTTP-Redirect binding
+	SSOURL string
+	// Certificates are trusted to sign responses
+	Certificates []*x509.Certificate
+}
+
+type metadataXML struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID string   `xml:"entityID,attr"`
+	IDP      []struct {
+		Keys []struct {
+			Use   string   `xml:"use,attr"`
+			Certs []string `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo>X509Data>X509Certificate"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+		SSO []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SingleSignOnService"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+}
+
+// ParseIdPMetadata reads the entity ID, redirect login URL and signing
+// certificates from an identity provider's metadata document
+func ParseIdPMetadata(data []byte) (*IdPMetadata, error) {
+	if bytes.Contains(data, []byte("<!DOCTYPE")) {
+		return nil, errors.New("metadata must not contain a DTD")
+	}
+	var md metadataXML
+	if err := xml.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	if md.EntityID == "" || len(md.IDP) == 0 {
+		return nil, errors.New("metadata does not describe an identity provider")
+	}
+
+	meta := &IdPMetadata{EntityID: md.EntityID}
+	for _, idp := range md.IDP {
+		for _, sso := range idp.SSO {
+			if sso.Binding == bindingRedirect && meta.SSOURL == "" {
+				meta.SSOURL = sso.Location
+			}
+		}
+		for _, key := range idp.Keys {
+			if key.Use != "" && key.Use != "signing" {
+				continue
+			}
+			for _, c := range key.Certs {
+				der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(c), ""))
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate: %w", err)
+				}
+				cert, err := x509.ParseCertificate(der)
+				if err != nil {
+					return nil, fmt.Errorf("invalid certificate: %w", err)
+				}
+				meta.Certificates = append(meta.Certificates, cert)
+			}
+		}
+	}
+	if meta.SSOURL == "" {
+		return nil, errors.New("metadata has no HTTP-Redirect single sign-on service")
+	}
+	if len(meta.Certificates) == 0 {
+		return nil, errors.New("metadata has no signing certificate")
+	}
+	return meta, nil
+}
+
+// ServiceProvider is this application as registered with an identity provider
+type ServiceProvider struct {
+	// EntityID identifies the service provider, by convention the URL of
+	// its metadata
+	EntityID string
+	// ACSURL is the assertion consumer service responses are posted to
+	ACSURL string
+}
+
+// Metadata returns the service provider's metadata document
+func (sp *ServiceProvider) Metadata() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<md:EntityDescriptor xmlns:md="` + nsSAMLMD + `" entityID="`)
+	xml.EscapeText(&b, []byte(sp.EntityID))
+	b.WriteString(`">` + "\n")
+	b.WriteString(`  <md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="` + nsSAMLP + `">` + "\n")
+	b.WriteString(`    <md:NameIDFormat>` + nameIDEmail + `</md:NameIDFormat>` + "\n")
+	b.WriteString(`    <md:AssertionConsumerService Binding="` + bindingPOST + `" Location="`)
+	xml.EscapeText(&b, []byte(sp.ACSURL))
+	b.WriteString(`" index="0" isDefault="true"/>` + "\n")
+	b.WriteString("  </md:SPSSODescriptor>\n</md:EntityDescriptor>\n")
+	return b.Bytes()
+}
+
+// NewRequestID returns a random ID for an AuthnRequest
+func NewRequestID() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "_" + hex.EncodeToString(raw), nil
+}
+
+// AuthnRequestURL returns the URL that sends the user to the identity
+// provider to log in. The response must answer id, which comes from
+// NewRequestID.
+func (sp *ServiceProvider) AuthnRequestURL(idp *IdPMetadata, id, relayState string, now time.Time) (string, error) {
+	var req bytes.Buffer
+	req.WriteString(`<samlp:AuthnRequest xmlns:samlp="` + nsSAMLP + `" xmlns:saml="` + nsSAML + `" ID="` + id +
+		`" Version="2.0" IssueInstant="` + now.UTC().Format(time.RFC3339) + `" Destination="`)
+	xml.EscapeText(&req, []byte(idp.SSOURL))
+	req.WriteString(`" AssertionConsumerServiceURL="`)
+	xml.EscapeText(&req, []byte(sp.ACSURL))
+	req.WriteString(`" ProtocolBinding="` + bindingPOST + `"><saml:Issuer>`)
+	xml.EscapeText(&req, []byte(sp.EntityID))
+	req.WriteString(`</saml:Issuer><samlp:NameIDPolicy Format="` + nameIDEmail + `" AllowCreate="true"/></samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	w, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	w.Write(req.Bytes())
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(idp.SSOURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid single sign-on URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Assertion is a verified SAML assertion
+type Assertion struct {
+	Identity
+	// ID is unique per assertion; remember it until Expires to refuse
+	// replays
+	ID      string
+	Expires time.Time
+}
+
+// Attribute names identity providers commonly use, by claim
+var (
+	emailAttributes     = []string{"email", "mail", "emailaddress", "urn:oid:0.9.2342.19200300.100.1.3", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress"}
+	firstNameAttributes = []string{"givenName", "firstName", "first_name", "urn:oid:2.5.4.42", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname"}
+	lastNameAttributes  = []string{"sn", "surname", "lastName", "last_name", "urn:oid:2.5.4.4", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname"}
+	nameAttributes      = []string{"displayName", "name", "urn:oid:2.16.840.1.113730.3.1.241", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name"}
+)
+
+// ParseResponse verifies the base64 SAMLResponse posted to the assertion
+// consumer service. It must answer requestID; unsolicited, IdP-initiated
+// responses are refused. Either the response or its assertion has to be
+// signed by one of the identity provider's certificates. Encrypted
+// assertions are not supported.
+func (sp *ServiceProvider) ParseResponse(samlResponse string, idp *IdPMetadata, requestID string, now time.Time) (*Assertion, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(samlResponse), ""))
+	if err != nil {
+		return nil, verificationError("response is not base64")
+	}
+	resp, err := parseXML(data)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+	if !resp.is(nsSAMLP, "Response") {
+		return nil, verificationError("not a SAML response")
+	}
+
+	responseSigned := false
+	switch err := verifySignature(resp, idp.Certificates); err {
+	case nil:
+		responseSigned = true
+	case errNotSigned:
+	default:
+		return nil, err
+	}
+
+	if status := resp.child(nsSAMLP, "Status"); status == nil || status.child(nsSAMLP, "StatusCode") == nil {
+		return nil, verificationError("response has no status")
+	} else if code := status.child(nsSAMLP, "StatusCode").attr("Value"); code != statusSuccess {
+		return nil, verificationError("identity provider returned %s", code)
+	}
+	if dest := resp.attr("Destination"); dest != "" && dest != sp.ACSURL {
+		return nil, verificationError("response is for %s", dest)
+	}
+	if requestID == "" || resp.attr("InResponseTo") != requestID {
+		return nil, verificationError("response does not answer this login")
+	}
+	if issuer := resp.child(nsSAML, "Issuer"); issuer != nil && issuer.text() != idp.EntityID {
+		return nil, verificationError("response is from %s", issuer.text())
+	}
+	if resp.child(nsSAML, "EncryptedAssertion") != nil {
+		return nil, verificationError("encrypted assertions are not supported")
+	}
+
+	assertions := resp.all(nsSAML, "Assertion")
+	if len(assertions) != 1 {
+		return nil, verificationError("response must contain exactly one assertion")
+	}
+	a := assertions[0]
+	if err := verifySignature(a, idp.Certificates); err == errNotSigned {
+		if !responseSigned {
+			return nil, verificationError("neither the response nor the assertion is signed")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if issuer := a.child(nsSAML, "Issuer"); issuer == nil || issuer.text() != idp.EntityID {
+		return nil, verificationError("assertion is not from the identity provider")
+	}
+	result := &Assertion{ID: a.attr("ID")}
+	if result.ID == "" {
+		return nil, verificationError("assertion has no ID")
+	}
+
+	subject := a.child(nsSAML, "Subject")
+	if subject == nil || subject.child(nsSAML, "NameID") == nil || subject.child(nsSAML, "NameID").text() == "" {
+		return nil, verificationError("assertion has no subject")
+	}
+	nameID := subject.child(nsSAML, "NameID")
+	result.Subject = nameID.text()
+
+	for _, sc := range subject.all(nsSAML, "SubjectConfirmation") {
+		data := sc.child(nsSAML, "SubjectConfirmationData")
+		if sc.attr("Method") != methodBearer || data == nil {
+			continue
+		}
+		if data.attr("Recipient") != sp.ACSURL {
+			continue
+		}
+		if irt := data.attr("InResponseTo"); irt != "" && irt != requestID {
+			continue
+		}
+		expires, err := time.Parse(time.RFC3339, data.attr("NotOnOrAfter"))
+		if err != nil || !now.Before(expires.Add(clockSkew)) {
+			continue
+		}
+		result.Expires = expires
+		break
+	}
+	if result.Expires.IsZero() {
+		return nil, verificationError("assertion has no valid bearer confirmation")
+	}
+
+	conditions := a.child(nsSAML, "Conditions")
+	if conditions == nil {
+		return nil, verificationError("assertion has no conditions")
+	}
+	if v := conditions.attr("NotBefore"); v != "" {
+		notBefore, err := time.Parse(time.RFC3339, v)
+		if err != nil || now.Add(clockSkew).Before(notBefore) {
+			return nil, verificationError("assertion is not valid yet")
+		}
+	}
+	if v := conditions.attr("NotOnOrAfter"); v != "" {
+		notOnOrAfter, err := time.Parse(time.RFC3339, v)
+		if err != nil || !now.Before(notOnOrAfter.Add(clockSkew)) {
+			return nil, verificationError("assertion has expired")
+		}
+	}
+	restrictions := conditions.all(nsSAML, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, verificationError("assertion has no audience")
+	}
+	for _, r := range restrictions {
+		if !slices.ContainsFunc(r.all(nsSAML, "Audience"), func(aud *element) bool { return aud.text() == sp.EntityID }) {
+			return nil, verificationError("assertion is for another service provider")
+		}
+	}
+
+	attrs := map[string]string{}
+	if stmt := a.child(nsSAML, "AttributeStatement"); stmt != nil {
+		for _, at := range stmt.all(nsSAML, "Attribute") {
+			value := at.child(nsSAML, "AttributeValue")
+			if value == nil {
+				continue
+			}
+			for _, name := range []string{at.attr("Name"), at.attr("FriendlyName")} {
+				if _, ok := attrs[name]; name != "" && !ok {
+					attrs[name] = value.text()
+				}
+			}
+		}
+	}
+	first := func(names []string) string {
+		for _, n := range names {
+			if v := attrs[n]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	result.Email = first(emailAttributes)
+	if result.Email == "" && (nameID.attr("Format") == nameIDEmail || strings.Contains(result.Subject, "@")) {
+		result.Email = result.Subject
+	}
+	result.FirstName = first(firstNameAttributes)
+	result.LastName = first(lastNameAttributes)
+	result.Name = first(nameAttributes)
+	if result.Email == "" {
+		return nil, verificationError("assertion has no email address")
+	}
+	return result, nil
+}
@@ -0,0 +1,53 @@
+// Package sso implements the service provider side of enterprise single
+// sign-on: OpenID Connect with a generic issuer, and SAML 2.0 with the
+// HTTP-Redirect binding for requests and HTTP-POST for responses.
+//
+// Both protocols end in an Identity. Deciding which account it belongs to,
+// and whether it may log in at all, is left to the caller.
+package sso
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"taskai/internal/netguard"
+)
+
+// Protocols a team can configure
+const (
+	ProtocolOIDC = "oidc"
+	ProtocolSAML = "saml"
+)
+
+// clockSkew is how far the identity provider's clock may be off from ours
+const clockSkew = 2 * time.Minute
+
+var (
+	// ErrVerification is wrapped by every error about a response that
+	// doesn't check out, as opposed to network or configuration problems
+	ErrVerification = errors.New("sso verification failed")
+)
+
+func verificationError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrVerification, fmt.Sprintf(format, args...))
+}
+
+// Identity is the user an identity provider vouched for
+type Identity struct {
+	// Subject is the provider's stable ID for the user: the OIDC "sub"
+	// claim or the SAML NameID
+	Subject   string
+	Email     string
+	FirstName string
+	LastName  string
+	Name      string
+}
+
+// AllowPrivateIssuers lets identity providers run on loopback and private
+// addresses, for development and tests. Otherwise an issuer configured by a
+// team owner could be used to reach internal services.
+var AllowPrivateIssuers = false
+
+// httpClient is used for discovery, token and key requests
+var httpClient = netguard.NewClient(10*time.Second, func() bool { return AllowPrivateIssuers })
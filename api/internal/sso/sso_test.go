@@ -0,0 +1,346 @@
+package sso
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"taskai/internal/sso/ssotest"
+)
+
+func TestCanonicalize(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<!-- dropped -->
+<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns="urn:default"><a:child z="1" b:y="2" a="3 &amp; &quot;x&quot;&#9;" ><b:leaf/>text &amp; &lt;more&gt;<!-- dropped --><plain xmlns=""/></a:child><x xmlns="urn:d"><y/></x></a:root>`
+	root, err := parseXML([]byte(doc))
+	if err != nil {
+		t.Fatalf("parseXML failed: %v", err)
+	}
+	child := root.child("urn:a", "child")
+
+	tests := []struct {
+		name      string
+		el        *element
+		skip      *element
+		inclusive []string
+		want      string
+	}{
+		{
+			name: "subtree declares what it uses",
+			el:   child,
+			want: `<a:child xmlns:a="urn:a" xmlns:b="urn:b" a="3 &amp; &quot;x&quot;&#x9;" z="1" b:y="2"><b:leaf></b:leaf>text &amp; &lt;more&gt;<plain></plain></a:child>`,
+		},
+		{
+			name: "unused namespaces are left out",
+			el:   root,
+			want: `<a:root xmlns:a="urn:a"><a:child xmlns:b="urn:b" a="3 &amp; &quot;x&quot;&#x9;" z="1" b:y="2"><b:leaf></b:leaf>text &amp; &lt;more&gt;<plain></plain></a:child>` +
+				`<x xmlns="urn:d"><y></y></x></a:root>`,
+		},
+		{
+			name: "skipped element",
+			el:   child,
+			skip: child.child("urn:b", "leaf"),
+			want: `<a:child xmlns:a="urn:a" xmlns:b="urn:b" a="3 &amp; &quot;x&quot;&#x9;" z="1" b:y="2">text &amp; &lt;more&gt;<plain></plain></a:child>`,
+		},
+		{
+			name:      "inclusive default namespace",
+			el:        child,
+			inclusive: []string{"#default"},
+			want:      `<a:child xmlns="urn:default" xmlns:a="urn:a" xmlns:b="urn:b" a="3 &amp; &quot;x&quot;&#x9;" z="1" b:y="2"><b:leaf></b:leaf>text &amp; &lt;more&gt;<plain xmlns=""></plain></a:child>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(canonicalize(tt.el, tt.skip, tt.inclusive)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseXMLRejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"DTD":                `<!DOCTYPE r [<!ENTITY x "y">]><r>&x;</r>`,
+		"undeclared prefix":  `<p:r/>`,
+		"mismatched end tag": `<a><b></a></b>`,
+		"two roots":          `<a/><b/>`,
+	} {
+		if _, err := parseXML([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func testServiceProvider() *ServiceProvider {
+	return &ServiceProvider{
+		EntityID: "http://localhost:8080/api/auth/sso/1/saml/metadata",
+		ACSURL:   "http://localhost:8080/api/auth/sso/1/saml/acs",
+	}
+}
+
+func TestSAMLLogin(t *testing.T) {
+	idp := ssotest.New()
+	defer idp.Close()
+	meta, err := ParseIdPMetadata(idp.Metadata())
+	if err != nil {
+		t.Fatalf("ParseIdPMetadata failed: %v", err)
+	}
+	if meta.EntityID != idp.EntityID() || meta.SSOURL != idp.SSOURL() || len(meta.Certificates) != 1 {
+		t.Fatalf("Unexpected metadata: %+v", meta)
+	}
+	sp := testServiceProvider()
+	user := ssotest.User{Subject: "jane@corp.example", Email: "jane@corp.example", FirstName: "Jane", LastName: "Doe"}
+
+	login := func(t *testing.T) (*ssotest.Response, string) {
+		t.Helper()
+		requestID, err := NewRequestID()
+		if err != nil {
+			t.Fatalf("NewRequestID failed: %v", err)
+		}
+		redirect, err := sp.AuthnRequestURL(meta, requestID, "relay", time.Now())
+		if err != nil {
+			t.Fatalf("AuthnRequestURL failed: %v", err)
+		}
+		resp, relayState, err := idp.Login(redirect, user)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		if relayState != "relay" || resp.Destination != sp.ACSURL || resp.Audience != sp.EntityID {
+			t.Fatalf("Unexpected request: %+v, relay state %q", resp, relayState)
+		}
+		return resp, requestID
+	}
+	reject := func(t *testing.T, samlResponse, requestID string, now time.Time, want string) {
+		t.Helper()
+		_, err := sp.ParseResponse(samlResponse, meta, requestID, now)
+		if !errors.Is(err, ErrVerification) || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected a verification error about %q, got %v", want, err)
+		}
+	}
+
+	resp, requestID := login(t)
+	a, err := sp.ParseResponse(idp.Encode(resp), meta, requestID, time.Now())
+	if err != nil {
+		t.Fatalf("ParseResponse failed: %v", err)
+	}
+	if a.Subject != user.Subject || a.Email != user.Email || a.FirstName != "Jane" || a.LastName != "Doe" || a.ID != resp.AssertionID {
+		t.Errorf("Unexpected assertion: %+v", a)
+	}
+	if time.Until(a.Expires) < 4*time.Minute {
+		t.Errorf("Expected the assertion to expire in 5 minutes, got %v", a.Expires)
+	}
+
+	t.Run("signed response", func(t *testing.T) {
+		resp, requestID := login(t)
+		resp.SignResponse = true
+		if _, err := sp.ParseResponse(idp.Encode(resp), meta, requestID, time.Now()); err != nil {
+			t.Errorf("ParseResponse failed: %v", err)
+		}
+	})
+
+	t.Run("another request", func(t *testing.T) {
+		resp, _ := login(t)
+		_, requestID := login(t)
+		reject(t, idp.Encode(resp), requestID, time.Now(), "does not answer")
+		reject(t, idp.Encode(resp), "", time.Now(), "does not answer")
+	})
+
+	t.Run("another service provider", func(t *testing.T) {
+		resp, requestID := login(t)
+		resp.Audience = "https://other.example"
+		reject(t, idp.Encode(resp), requestID, time.Now(), "another service provider")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		resp, requestID := login(t)
+		reject(t, idp.Encode(resp), requestID, time.Now().Add(10*time.Minute), "bearer")
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		resp, requestID := login(t)
+		doc := strings.Replace(idp.XML(resp), "jane@corp.example</saml:AttributeValue>", "ceo@corp.example</saml:AttributeValue>", 1)
+		reject(t, base64.StdEncoding.EncodeToString([]byte(doc)), requestID, time.Now(), "digest mismatch")
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		resp, requestID := login(t)
+		doc := idp.XML(resp)
+		start, end := strings.Index(doc, "<ds:Signature"), strings.Index(doc, "</ds:Signature>")+len("</ds:Signature>")
+		reject(t, base64.StdEncoding.EncodeToString([]byte(doc[:start]+doc[end:])), requestID, time.Now(), "neither")
+	})
+
+	t.Run("signature wrapping", func(t *testing.T) {
+		// A genuine signed assertion is moved aside and a forged one takes
+		// its place
+		resp, requestID := login(t)
+		genuine := idp.XML(resp)
+		resp.User = ssotest.User{Subject: "ceo@corp.example", Email: "ceo@corp.example"}
+		forged := idp.XML(resp)
+
+		assertion := func(doc string) string {
+			return doc[strings.Index(doc, "<saml:Assertion") : strings.Index(doc, "</saml:Assertion>")+len("</saml:Assertion>")]
+		}
+		withoutSig := func(s string) string {
+			start, end := strings.Index(s, "<ds:Signature"), strings.Index(s, "</ds:Signature>")+len("</ds:Signature>")
+			return s[:start] + s[end:]
+		}
+		wrapped := strings.Replace(genuine, assertion(genuine),
+			`<samlp:Extensions>`+assertion(genuine)+`</samlp:Extensions>`+withoutSig(assertion(forged)), 1)
+		reject(t, base64.StdEncoding.EncodeToString([]byte(wrapped)), requestID, time.Now(), "neither")
+	})
+
+	t.Run("another identity provider", func(t *testing.T) {
+		other := ssotest.New()
+		defer other.Close()
+		otherMeta, err := ParseIdPMetadata(other.Metadata())
+		if err != nil {
+			t.Fatalf("ParseIdPMetadata failed: %v", err)
+		}
+		otherMeta.EntityID = meta.EntityID
+
+		resp, requestID := login(t)
+		if _, err := sp.ParseResponse(idp.Encode(resp), otherMeta, requestID, time.Now()); err == nil || !strings.Contains(err.Error(), "trusted certificate") {
+			t.Errorf("Expected an untrusted signature, got %v", err)
+		}
+	})
+}
+
+func TestParseIdPMetadataRejects(t *testing.T) {
+	idp := ssotest.New()
+	defer idp.Close()
+
+	for name, doc := range map[string]string{
+		"not metadata":   `<html></html>`,
+		"no certificate": strings.Replace(string(idp.Metadata()), `use="signing"`, `use="encryption"`, 1),
+		"no redirect":    strings.Replace(string(idp.Metadata()), "bindings:HTTP-Redirect", "bindings:SOAP", 1),
+		"DTD":            `<!DOCTYPE x>` + string(idp.Metadata()),
+		"invalid base64": strings.Replace(string(idp.Metadata()), "<ds:X509Certificate>", "<ds:X509Certificate>!", 1),
+	} {
+		if _, err := ParseIdPMetadata([]byte(doc)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// allowPrivateIssuers lets a test reach identity providers on loopback
+func allowPrivateIssuers(t *testing.T) {
+	t.Helper()
+	AllowPrivateIssuers = true
+	t.Cleanup(func() { AllowPrivateIssuers = false })
+}
+
+func TestOIDCLogin(t *testing.T) {
+	allowPrivateIssuers(t)
+	idp := ssotest.New()
+	defer idp.Close()
+	ctx := context.Background()
+
+	p, err := DiscoverOIDC(ctx, idp.Issuer())
+	if err != nil {
+		t.Fatalf("DiscoverOIDC failed: %v", err)
+	}
+	client := OIDCClient{ClientID: idp.ClientID, ClientSecret: idp.ClientSecret, RedirectURL: "http://localhost:8080/api/auth/sso/1/oidc/callback"}
+	user := ssotest.User{Subject: "00u1", Email: "jane@corp.example", FirstName: "Jane", LastName: "Doe"}
+
+	authorize := func(t *testing.T, user ssotest.User) string {
+		t.Helper()
+		authURL := p.AuthCodeURL(client, "state", "nonce", "verifier")
+		callback, err := idp.Authorize(authURL, user)
+		if err != nil {
+			t.Fatalf("Authorize failed: %v", err)
+		}
+		u, _ := url.Parse(callback)
+		if !strings.HasPrefix(callback, client.RedirectURL+"?") || u.Query().Get("state") != "state" {
+			t.Fatalf("Unexpected callback: %s", callback)
+		}
+		return u.Query().Get("code")
+	}
+
+	identity, err := p.Exchange(ctx, client, authorize(t, user), "verifier", "nonce")
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	if identity.Subject != "00u1" || identity.Email != user.Email || identity.FirstName != "Jane" || identity.Name != "Jane Doe" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		_, err := p.Exchange(ctx, client, authorize(t, user), "verifier", "other")
+		if !errors.Is(err, ErrVerification) {
+			t.Errorf("Expected a verification error, got %v", err)
+		}
+	})
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		_, err := p.Exchange(ctx, client, authorize(t, user), "other", "nonce")
+		if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+			t.Errorf("Expected the code to be refused, got %v", err)
+		}
+	})
+
+	t.Run("wrong client secret", func(t *testing.T) {
+		bad := client
+		bad.ClientSecret = "guess"
+		_, err := p.Exchange(ctx, bad, authorize(t, user), "verifier", "nonce")
+		if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+			t.Errorf("Expected the client to be refused, got %v", err)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		unverified := user
+		unverified.Unverified = true
+		_, err := p.Exchange(ctx, client, authorize(t, unverified), "verifier", "nonce")
+		if err == nil || !strings.Contains(err.Error(), "not verified") {
+			t.Errorf("Expected the email to be refused, got %v", err)
+		}
+	})
+
+	t.Run("issuer mismatch", func(t *testing.T) {
+		if _, err := DiscoverOIDC(ctx, idp.Issuer()+"/"); err == nil {
+			t.Error("Expected discovery to check the issuer")
+		}
+	})
+}
+
+func TestValidateIssuerURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://login.example.com":         true,
+		"https://login.example.com/tenant":  true,
+		"http://localhost:9000":             false,
+		"http://127.0.0.1:9000":             false,
+		"http://login.example.com":          false,
+		"https://login.example.com/?tenant": false,
+		"login.example.com":                 false,
+		"file:///etc/passwd":                false,
+	} {
+		if err := ValidateIssuerURL(raw); (err == nil) != ok {
+			t.Errorf("ValidateIssuerURL(%q) = %v", raw, err)
+		}
+	}
+
+	allowPrivateIssuers(t)
+	for _, raw := range []string{"http://localhost:9000", "http://127.0.0.1:9000"} {
+		if err := ValidateIssuerURL(raw); err != nil {
+			t.Errorf("ValidateIssuerURL(%q) with private issuers allowed = %v", raw, err)
+		}
+	}
+}
+
+func TestDiscoverOIDCRefusesPrivateAddresses(t *testing.T) {
+	idp := ssotest.New()
+	defer idp.Close()
+
+	// An https issuer that resolves to a private address is refused when
+	// connecting, not just by its URL
+	if _, err := DiscoverOIDC(context.Background(), "https://127.0.0.1:1"); err == nil || !strings.Contains(err.Error(), "not a public address") {
+		t.Errorf("Expected a private https issuer to be refused, got %v", err)
+	}
+	if _, err := DiscoverOIDC(context.Background(), idp.Issuer()); err == nil {
+		t.Error("Expected a loopback http issuer to be refused")
+	}
+}
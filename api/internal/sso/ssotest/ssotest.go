@@ -0,0 +1,390 @@
+// Package ssotest provides a stand-in identity provider for testing OpenID
+// Connect and SAML logins without a real one.
+package ssotest
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// User is who logs in at the identity provider
+type User struct {
+	Subject   string
+	Email     string
+	FirstName string
+	LastName  string
+	// Unverified marks the email as unverified in OIDC ID tokens
+	Unverified bool
+}
+
+// IdP is an OpenID Connect issuer and SAML identity provider. Logins are
+// approved without asking: Authorize and Login stand in for the browser
+// round trip through the provider's login page.
+type IdP struct {
+	// Server serves OIDC discovery, token and key set endpoints. Its URL
+	// is the issuer.
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key  *rsa.PrivateKey
+	cert []byte
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+type authorization struct {
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+	user          User
+}
+
+// New starts an identity provider; Close it when done
+func New() *IdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ssotest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+
+	p := &IdP{ClientID: "taskai", ClientSecret: "client-secret", key: key, cert: cert, codes: map[string]authorization{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("POST /token", p.handleToken)
+	mux.HandleFunc("GET /keys", p.handleKeys)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Close shuts the provider's server down
+func (p *IdP) Close() {
+	p.Server.Close()
+}
+
+// Issuer is the OIDC issuer URL
+func (p *IdP) Issuer() string {
+	return p.Server.URL
+}
+
+// Authorize approves an OIDC authorization request for user and returns
+// the redirect back to the client, with the code and state
+func (p *IdP) Authorize(authURL string, user User) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" || !strings.Contains(q.Get("scope"), "openid") {
+		return "", fmt.Errorf("invalid authorization request: %s", u.RawQuery)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		return "", fmt.Errorf("authorization request without PKCE")
+	}
+
+	code := randomID()
+	p.mu.Lock()
+	p.codes[code] = authorization{
+		redirectURI:   q.Get("redirect_uri"),
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		user:          user,
+	}
+	p.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		return "", err
+	}
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	return redirect.String(), nil
+}
+
+func (p *IdP) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic"},
+	})
+}
+
+func (p *IdP) handleKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *IdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	id, _ = url.QueryUnescape(id)
+	secret, _ = url.QueryUnescape(secret)
+	if !ok || id != p.ClientID || subtle.ConstantTimeCompare([]byte(secret), []byte(p.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostFormValue("code")
+	p.mu.Lock()
+	auth, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != auth.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer(),
+		"sub":            auth.user.Subject,
+		"aud":            p.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.user.Email,
+		"email_verified": !auth.user.Unverified,
+		"given_name":     auth.user.FirstName,
+		"family_name":    auth.user.LastName,
+		"name":           strings.TrimSpace(auth.user.FirstName + " " + auth.user.LastName),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomID(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// SAML
+
+const (
+	nsSAML  = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsSAMLP = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsDSig  = "http://www.w3.org/2000/09/xmldsig#"
+)
+
+// EntityID is the SAML identity provider's entity ID
+func (p *IdP) EntityID() string {
+	return p.Issuer() + "/saml"
+}
+
+// SSOURL is where SAML authentication requests are sent
+func (p *IdP) SSOURL() string {
+	return p.Issuer() + "/saml/sso"
+}
+
+// Metadata returns the SAML identity provider metadata
+func (p *IdP) Metadata() []byte {
+	return []byte(`<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" entityID="` + p.EntityID() + `">
+  <md:IDPSSODescriptor WantAuthnRequestsSigned="false" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(p.cert) + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress</md:NameIDFormat>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="` + p.SSOURL() + `"/>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="` + p.SSOURL() + `"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>
+`)
+}
+
+// Response describes a SAML response; Login fills it in from the request
+// and tests can tamper with it before signing
+type Response struct {
+	InResponseTo string
+	Destination  string
+	Audience     string
+	AssertionID  string
+	IssueInstant time.Time
+	User         User
+	// SignResponse signs the whole response instead of the assertion
+	SignResponse bool
+}
+
+// Login approves a SAML authentication request sent with the HTTP-Redirect
+// binding, returning the response to post and the relay state to echo
+func (p *IdP) Login(authnRequestURL string, user User) (*Response, string, error) {
+	u, err := url.Parse(authnRequestURL)
+	if err != nil {
+		return nil, "", err
+	}
+	if !strings.HasPrefix(authnRequestURL, p.SSOURL()+"?") {
+		return nil, "", fmt.Errorf("request sent to %s", u.Path)
+	}
+	deflated, err := base64.StdEncoding.DecodeString(u.Query().Get("SAMLRequest"))
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	var req struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+		ID      string   `xml:"ID,attr"`
+		ACSURL  string   `xml:"AssertionConsumerServiceURL,attr"`
+		Issuer  string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	}
+	if err := xml.Unmarshal(data, &req); err != nil {
+		return nil, "", fmt.Errorf("invalid AuthnRequest: %w", err)
+	}
+	return &Response{
+		InResponseTo: req.ID,
+		Destination:  req.ACSURL,
+		Audience:     req.Issuer,
+		AssertionID:  "_" + randomID(),
+		IssueInstant: time.Now().UTC(),
+		User:         user,
+	}, u.Query().Get("RelayState"), nil
+}
+
+// Encode signs the response and returns it base64 encoded, as posted in
+// the SAMLResponse form field
+func (p *IdP) Encode(r *Response) string {
+	return base64.StdEncoding.EncodeToString([]byte(p.XML(r)))
+}
+
+// XML signs the response and returns the document
+func (p *IdP) XML(r *Response) string {
+	now := r.IssueInstant.Format(time.RFC3339)
+	expires := r.IssueInstant.Add(5 * time.Minute).Format(time.RFC3339)
+
+	// Elements are written in canonical form, each declaring the
+	// namespaces it uses, so they can be signed as they are
+	var a strings.Builder
+	a.WriteString(`<saml:Assertion xmlns:saml="` + nsSAML + `" ID="` + r.AssertionID + `" IssueInstant="` + now + `" Version="2.0">`)
+	a.WriteString(`<saml:Issuer>` + text(p.EntityID()) + `</saml:Issuer>`)
+	a.WriteString(`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">` + text(r.User.Subject) + `</saml:NameID>`)
+	a.WriteString(`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer"><saml:SubjectConfirmationData InResponseTo="` + attr(r.InResponseTo) +
+		`" NotOnOrAfter="` + expires + `" Recipient="` + attr(r.Destination) + `"></saml:SubjectConfirmationData></saml:SubjectConfirmation></saml:Subject>`)
+	a.WriteString(`<saml:Conditions NotBefore="` + now + `" NotOnOrAfter="` + expires + `"><saml:AudienceRestriction><saml:Audience>` + text(r.Audience) +
+		`</saml:Audience></saml:AudienceRestriction></saml:Conditions>`)
+	a.WriteString(`<saml:AuthnStatement AuthnInstant="` + now + `" SessionIndex="` + r.AssertionID + `"><saml:AuthnContext><saml:AuthnContextClassRef>` +
+		`urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport</saml:AuthnContextClassRef></saml:AuthnContext></saml:AuthnStatement>`)
+	a.WriteString(`<saml:AttributeStatement>`)
+	for _, at := range [][2]string{{"email", r.User.Email}, {"givenName", r.User.FirstName}, {"sn", r.User.LastName}} {
+		a.WriteString(`<saml:Attribute Name="` + at[0] + `"><saml:AttributeValue>` + text(at[1]) + `</saml:AttributeValue></saml:Attribute>`)
+	}
+	a.WriteString(`</saml:AttributeStatement></saml:Assertion>`)
+	assertion := a.String()
+	if !r.SignResponse {
+		assertion = p.sign(assertion, r.AssertionID, "</saml:Issuer>")
+	}
+
+	responseID := "_" + randomID()
+	response := `<samlp:Response xmlns:samlp="` + nsSAMLP + `" Destination="` + attr(r.Destination) + `" ID="` + responseID +
+		`" InResponseTo="` + attr(r.InResponseTo) + `" IssueInstant="` + now + `" Version="2.0">` +
+		`<saml:Issuer xmlns:saml="` + nsSAML + `">` + text(p.EntityID()) + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"></samlp:StatusCode></samlp:Status>` +
+		assertion + `</samlp:Response>`
+	if r.SignResponse {
+		response = p.sign(response, responseID, "</saml:Issuer>")
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + response
+}
+
+// sign inserts an enveloped signature over the canonical element el after
+// the first occurrence of after
+func (p *IdP) sign(el, id, after string) string {
+	digest := sha256.Sum256([]byte(el))
+	signedInfo := `<ds:SignedInfo xmlns:ds="` + nsDSig + `">` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:CanonicalizationMethod>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"></ds:SignatureMethod>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"></ds:Transform>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"></ds:Transform></ds:Transforms>` +
+		`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"></ds:DigestMethod>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue></ds:Reference></ds:SignedInfo>`
+
+	hashed := sha256.Sum256([]byte(signedInfo))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		panic(err)
+	}
+	signature := `<ds:Signature xmlns:ds="` + nsDSig + `">` + signedInfo +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sig) + `</ds:SignatureValue>` +
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(p.cert) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo></ds:Signature>`
+
+	i := strings.Index(el, after) + len(after)
+	return el[:i] + signature + el[i:]
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func text(s string) string { return textEscaper.Replace(s) }
+func attr(s string) string { return attrEscaper.Replace(s) }
+
+func randomID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
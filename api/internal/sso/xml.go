@@ -0,0 +1,337 @@
+package sso
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// SAML messages are signed over their exclusive canonical form, which
+// depends on namespace prefixes and declarations that encoding/xml throws
+// away. This is a small DOM that keeps them.
+
+const nsXML = "http://www.w3.org/XML/1998/namespace"
+
+type element struct {
+	prefix, local string
+	space         string // resolved namespace URI
+	attrs         []attr
+	ns            []nsDecl // declared on this element
+	children      []interface{}
+	parent        *element
+}
+
+type attr struct {
+	prefix, local, space, value string
+}
+
+type nsDecl struct {
+	prefix, uri string
+}
+
+type charData string
+
+// parseXML parses a document into its root element. DTDs, and with them
+// entity expansion tricks, are rejected.
+func parseXML(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+
+	var root, cur *element
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if cur == nil && root != nil {
+				return nil, errors.New("xml: more than one root element")
+			}
+			e := &element{prefix: t.Name.Space, local: t.Name.Local, parent: cur}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					e.ns = append(e.ns, nsDecl{prefix: a.Name.Local, uri: a.Value})
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					e.ns = append(e.ns, nsDecl{uri: a.Value})
+				default:
+					e.attrs = append(e.attrs, attr{prefix: a.Name.Space, local: a.Name.Local, value: a.Value})
+				}
+			}
+			if cur == nil {
+				root = e
+			} else {
+				cur.children = append(cur.children, e)
+			}
+			cur = e
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, fmt.Errorf("xml: unexpected end element </%s>", t.Name.Local)
+			}
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, charData(t))
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("xml: text outside the root element")
+			}
+		case xml.Directive:
+			return nil, errors.New("xml: DTDs are not allowed")
+		case xml.ProcInst:
+			if cur != nil {
+				return nil, errors.New("xml: processing instructions are not allowed")
+			}
+		case xml.Comment:
+			// Dropped, as by canonicalization without comments
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, errors.New("xml: incomplete document")
+	}
+	if err := root.resolve(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// lookupNS returns the namespace URI prefix is bound to at e
+func (e *element) lookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for n := e; n != nil; n = n.parent {
+		for _, d := range n.ns {
+			if d.prefix == prefix {
+				return d.uri, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+func (e *element) resolve() error {
+	space, ok := e.lookupNS(e.prefix)
+	if !ok {
+		return fmt.Errorf("xml: undeclared namespace prefix %q", e.prefix)
+	}
+	e.space = space
+	for i := range e.attrs {
+		if e.attrs[i].prefix == "" {
+			continue
+		}
+		space, ok := e.lookupNS(e.attrs[i].prefix)
+		if !ok {
+			return fmt.Errorf("xml: undeclared namespace prefix %q", e.attrs[i].prefix)
+		}
+		e.attrs[i].space = space
+	}
+	for _, c := range e.children {
+		if c, ok := c.(*element); ok {
+			if err := c.resolve(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *element) is(space, local string) bool {
+	return e.space == space && e.local == local
+}
+
+// child returns the first child element with the given name
+func (e *element) child(space, local string) *element {
+	for _, c := range e.children {
+		if c, ok := c.(*element); ok && c.is(space, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// all returns every child element with the given name
+func (e *element) all(space, local string) []*element {
+	var found []*element
+	for _, c := range e.children {
+		if c, ok := c.(*element); ok && c.is(space, local) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// attr returns the value of an attribute without a namespace
+func (e *element) attr(local string) string {
+	for _, a := range e.attrs {
+		if a.space == "" && a.local == local {
+			return a.value
+		}
+	}
+	return ""
+}
+
+// text returns the element's own text, trimmed
+func (e *element) text() string {
+	var b strings.Builder
+	for _, c := range e.children {
+		if c, ok := c.(charData); ok {
+			b.WriteString(string(c))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// canonicalize serializes the subtree at e with Exclusive XML
+// Canonicalization (without comments), leaving out skip if it's a
+// descendant. Prefixes in inclusive are treated as in inclusive
+// canonicalization, "#default" standing for the default namespace.
+func canonicalize(e, skip *element, inclusive []string) []byte {
+	c := &canonicalizer{skip: skip, inclusive: inclusive}
+	c.element(e, map[string]string{})
+	return c.buf.Bytes()
+}
+
+type canonicalizer struct {
+	buf       bytes.Buffer
+	skip      *element
+	inclusive []string
+}
+
+func (c *canonicalizer) element(e *element, rendered map[string]string) {
+	// Namespaces are rendered where they are visibly used, unless an output
+	// ancestor already rendered the same declaration
+	used := []string{e.prefix}
+	for _, a := range e.attrs {
+		if a.prefix != "" {
+			used = append(used, a.prefix)
+		}
+	}
+	for _, p := range c.inclusive {
+		if p == "#default" {
+			p = ""
+		}
+		if _, ok := e.lookupNS(p); ok {
+			used = append(used, p)
+		}
+	}
+
+	var decls []nsDecl
+	scope := rendered
+	for _, p := range used {
+		if p == "xml" || slices.ContainsFunc(decls, func(d nsDecl) bool { return d.prefix == p }) {
+			continue
+		}
+		uri, _ := e.lookupNS(p)
+		if prev, ok := rendered[p]; ok && prev == uri || !ok && p == "" && uri == "" {
+			continue
+		}
+		decls = append(decls, nsDecl{prefix: p, uri: uri})
+	}
+	if len(decls) > 0 {
+		scope = make(map[string]string, len(rendered)+len(decls))
+		for p, uri := range rendered {
+			scope[p] = uri
+		}
+		for _, d := range decls {
+			scope[d.prefix] = d.uri
+		}
+	}
+	slices.SortFunc(decls, func(a, b nsDecl) int { return strings.Compare(a.prefix, b.prefix) })
+
+	attrs := slices.Clone(e.attrs)
+	slices.SortFunc(attrs, func(a, b attr) int {
+		if a.space != b.space {
+			return strings.Compare(a.space, b.space)
+		}
+		return strings.Compare(a.local, b.local)
+	})
+
+	c.buf.WriteByte('<')
+	c.name(e.prefix, e.local)
+	for _, d := range decls {
+		if d.prefix == "" {
+			c.buf.WriteString(` xmlns="`)
+		} else {
+			c.buf.WriteString(` xmlns:` + d.prefix + `="`)
+		}
+		c.attrValue(d.uri)
+		c.buf.WriteByte('"')
+	}
+	for _, a := range attrs {
+		c.buf.WriteByte(' ')
+		c.name(a.prefix, a.local)
+		c.buf.WriteString(`="`)
+		c.attrValue(a.value)
+		c.buf.WriteByte('"')
+	}
+	c.buf.WriteByte('>')
+
+	for _, child := range e.children {
+		switch child := child.(type) {
+		case *element:
+			if child != c.skip {
+				c.element(child, scope)
+			}
+		case charData:
+			c.text(string(child))
+		}
+	}
+
+	c.buf.WriteString("</")
+	c.name(e.prefix, e.local)
+	c.buf.WriteByte('>')
+}
+
+func (c *canonicalizer) name(prefix, local string) {
+	if prefix != "" {
+		c.buf.WriteString(prefix + ":")
+	}
+	c.buf.WriteString(local)
+}
+
+func (c *canonicalizer) text(s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			c.buf.WriteString("&amp;")
+		case '<':
+			c.buf.WriteString("&lt;")
+		case '>':
+			c.buf.WriteString("&gt;")
+		case '\r':
+			c.buf.WriteString("&#xD;")
+		default:
+			c.buf.WriteRune(r)
+		}
+	}
+}
+
+func (c *canonicalizer) attrValue(s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			c.buf.WriteString("&amp;")
+		case '<':
+			c.buf.WriteString("&lt;")
+		case '"':
+			c.buf.WriteString("&quot;")
+		case '\t':
+			c.buf.WriteString("&#x9;")
+		case '\n':
+			c.buf.WriteString("&#xA;")
+		case '\r':
+			c.buf.WriteString("&#xD;")
+		default:
+			c.buf.WriteRune(r)
+		}
+	}
+}
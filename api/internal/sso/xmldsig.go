@@ -0,0 +1,171 @@
+package sso
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// XML signature algorithms. Only what current identity providers use is
+// supported; SHA-1 in particular is rejected.
+const (
+	nsDSig   = "http://www.w3.org/2000/09/xmldsig#"
+	nsExcC14 = "http://www.w3.org/2001/10/xml-exc-c14n#"
+
+	algExcC14N     = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algSHA256      = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA512      = "http://www.w3.org/2001/04/xmlenc#sha512"
+	algRSASHA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algECDSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+)
+
+var errNotSigned = errors.New("not signed")
+
+// verifySignature checks the enveloped signature that is a direct child of
+// e and references e itself, against any of the trusted certificates.
+// Referencing e, rather than looking its ID up anywhere in the document,
+// is what keeps signature wrapping attacks out: callers only read from the
+// element they verified.
+func verifySignature(e *element, certs []*x509.Certificate) error {
+	sigs := e.all(nsDSig, "Signature")
+	if len(sigs) == 0 {
+		return errNotSigned
+	}
+	if len(sigs) > 1 {
+		return verificationError("more than one signature on %s", e.local)
+	}
+	sig := sigs[0]
+
+	signedInfo := sig.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return verificationError("signature has no SignedInfo")
+	}
+	inclusive, err := c14nMethod(signedInfo.child(nsDSig, "CanonicalizationMethod"))
+	if err != nil {
+		return err
+	}
+
+	refs := signedInfo.all(nsDSig, "Reference")
+	if len(refs) != 1 {
+		return verificationError("signature must have exactly one reference")
+	}
+	ref := refs[0]
+	if id := e.attr("ID"); id == "" || ref.attr("URI") != "#"+id {
+		return verificationError("signature does not reference the signed element")
+	}
+
+	var refInclusive []string
+	enveloped, canonical := false, false
+	if transforms := ref.child(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.all(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnveloped:
+				enveloped = true
+			case algExcC14N:
+				if refInclusive, err = c14nMethod(t); err != nil {
+					return err
+				}
+				canonical = true
+			default:
+				return verificationError("unsupported transform %q", t.attr("Algorithm"))
+			}
+		}
+	}
+	if !enveloped || !canonical {
+		return verificationError("signature must be enveloped and use exclusive canonicalization")
+	}
+
+	digestHash, err := hashFor(ref.child(nsDSig, "DigestMethod"), map[string]crypto.Hash{
+		algSHA256: crypto.SHA256,
+		algSHA512: crypto.SHA512,
+	})
+	if err != nil {
+		return err
+	}
+	want, err := decodeBase64(ref.child(nsDSig, "DigestValue"))
+	if err != nil {
+		return verificationError("invalid digest value")
+	}
+	h := digestHash.New()
+	h.Write(canonicalize(e, sig, refInclusive))
+	if subtle.ConstantTimeCompare(h.Sum(nil), want) != 1 {
+		return verificationError("digest mismatch")
+	}
+
+	method := signedInfo.child(nsDSig, "SignatureMethod")
+	sigHash, err := hashFor(method, map[string]crypto.Hash{
+		algRSASHA256:   crypto.SHA256,
+		algRSASHA512:   crypto.SHA512,
+		algECDSASHA256: crypto.SHA256,
+	})
+	if err != nil {
+		return err
+	}
+	signature, err := decodeBase64(sig.child(nsDSig, "SignatureValue"))
+	if err != nil {
+		return verificationError("invalid signature value")
+	}
+	h = sigHash.New()
+	h.Write(canonicalize(signedInfo, nil, inclusive))
+	digest := h.Sum(nil)
+
+	for _, cert := range certs {
+		switch key := cert.PublicKey.(type) {
+		case *rsa.PublicKey:
+			if method.attr("Algorithm") != algECDSASHA256 && rsa.VerifyPKCS1v15(key, sigHash, digest, signature) == nil {
+				return nil
+			}
+		case *ecdsa.PublicKey:
+			// XML signatures use the raw r || s encoding
+			if method.attr("Algorithm") == algECDSASHA256 && len(signature)%2 == 0 {
+				half := len(signature) / 2
+				r := new(big.Int).SetBytes(signature[:half])
+				s := new(big.Int).SetBytes(signature[half:])
+				if ecdsa.Verify(key, digest, r, s) {
+					return nil
+				}
+			}
+		}
+	}
+	return verificationError("signature is not from a trusted certificate")
+}
+
+// c14nMethod checks a canonicalization algorithm and returns its
+// inclusive namespace prefixes
+func c14nMethod(method *element) ([]string, error) {
+	if method == nil || method.attr("Algorithm") != algExcC14N {
+		return nil, verificationError("unsupported canonicalization")
+	}
+	if ns := method.child(nsExcC14, "InclusiveNamespaces"); ns != nil {
+		return strings.Fields(ns.attr("PrefixList")), nil
+	}
+	return nil, nil
+}
+
+func hashFor(method *element, supported map[string]crypto.Hash) (crypto.Hash, error) {
+	if method == nil {
+		return 0, verificationError("missing algorithm")
+	}
+	h, ok := supported[method.attr("Algorithm")]
+	if !ok {
+		return 0, verificationError("unsupported algorithm %q", method.attr("Algorithm"))
+	}
+	return h, nil
+}
+
+func decodeBase64(e *element) ([]byte, error) {
+	if e == nil {
+		return nil, errors.New("missing value")
+	}
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.text()), ""))
+}
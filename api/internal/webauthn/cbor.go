@@ -0,0 +1,138 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// maxCBORDepth bounds nesting so hostile input can't exhaust the stack
+const maxCBORDepth = 16
+
+var errCBORTruncated = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the first CBOR item in data and returns it with the
+// bytes that follow it. Only what authenticators send is supported: integers
+// (as int64), byte and text strings, arrays, maps, booleans and null.
+// Indefinite lengths, tags and floats are rejected.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.New("cbor: nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	data = data[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		}
+		return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+	}
+
+	arg, data, err := cborArgument(info, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return int64(arg), data, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflows int64")
+		}
+		return -1 - int64(arg), data, nil
+	case 2, 3:
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBORTruncated
+		}
+		b := data[:arg]
+		if major == 3 {
+			return string(b), data[arg:], nil
+		}
+		return append([]byte(nil), b...), data[arg:], nil
+	case 4:
+		// Every item takes at least one byte
+		if arg > uint64(len(data)) {
+			return nil, nil, errCBORTruncated
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item interface{}
+			if item, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	case 5:
+		if arg > uint64(len(data))/2 {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value interface{}
+			if key, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key %T", key)
+			}
+			if value, data, err = decodeCBORItem(data, depth+1); err != nil {
+				return nil, nil, err
+			}
+			if _, dup := m[key]; dup {
+				return nil, nil, fmt.Errorf("cbor: duplicate map key %v", key)
+			}
+			m[key] = value
+		}
+		return m, data, nil
+	}
+	return nil, nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
+
+// cborArgument reads the argument that follows an initial byte
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		if len(data) < 1 {
+			return 0, nil, errCBORTruncated
+		}
+		return uint64(data[0]), data[1:], nil
+	case info == 25:
+		if len(data) < 2 {
+			return 0, nil, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26:
+		if len(data) < 4 {
+			return 0, nil, errCBORTruncated
+		}
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27:
+		if len(data) < 8 {
+			return 0, nil, errCBORTruncated
+		}
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	}
+	return 0, nil, fmt.Errorf("cbor: unsupported additional info %d", info)
+}
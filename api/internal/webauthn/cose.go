@@ -0,0 +1,113 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the signature schemes accepted for
+// credentials, in order of preference
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators when registering
+var SupportedAlgorithms = []int{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKty = 1
+	coseAlg = 3
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// publicKey is a credential public key decoded from its COSE form
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parsePublicKey decodes a COSE_Key as stored for a credential
+func parsePublicKey(cose []byte) (*publicKey, error) {
+	v, rest, err := decodeCBOR(cose)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("public key is not a map")
+	}
+
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+	crv, _ := m[int64(-1)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("invalid P-256 public key")
+		}
+		// Points that aren't on the curve are rejected here
+		point := append(append([]byte{4}, x...), y...)
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, fmt.Errorf("invalid P-256 public key: %w", err)
+		}
+		return &publicKey{alg: alg, key: key}, nil
+
+	case kty == coseKtyOKP && alg == AlgEdDSA:
+		x, _ := m[int64(-2)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		return &publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA public key")
+		}
+		exp := 0
+		for _, b := range e {
+			exp = exp<<8 | int(b)
+		}
+		return &publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %d with algorithm %d", kty, alg)
+}
+
+// verify checks a signature over message
+func (k *publicKey) verify(message, sig []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, message, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
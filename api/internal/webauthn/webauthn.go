@@ -0,0 +1,491 @@
+// Package webauthn implements the relying party side of WebAuthn: the
+// options sent to the browser to register a passkey or security key, and
+// verification of what the authenticator sends back.
+//
+// Attestation isn't requested or verified. Every authenticator model is
+// trusted the same, so credentials are registered with "none" attestation.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Timeout is how long the browser waits for the user, and how long a
+// challenge stays valid
+const Timeout = 5 * time.Minute
+
+// Authenticator data flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackedUp       = 0x10
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+)
+
+var (
+	// ErrVerification is wrapped by every error about a response that
+	// doesn't check out, as opposed to malformed JSON or server problems
+	ErrVerification = errors.New("webauthn verification failed")
+	// ErrSignCount means the authenticator's signature counter went
+	// backwards, which suggests the credential was cloned
+	ErrSignCount = fmt.Errorf("%w: signature counter did not increase", ErrVerification)
+)
+
+func verificationError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrVerification, fmt.Sprintf(format, args...))
+}
+
+// Config identifies the relying party
+type Config struct {
+	// RPID is the domain credentials are scoped to, e.g. "example.com".
+	// It defaults to the host of the first origin.
+	RPID string
+	// RPName is shown by some browsers while registering
+	RPName string
+	// Origins are the web origins allowed to use credentials, e.g.
+	// "https://app.example.com"
+	Origins []string
+}
+
+// RelyingParty creates WebAuthn options and verifies responses
+type RelyingParty struct {
+	id      string
+	name    string
+	origins []string
+}
+
+// New returns a relying party for cfg
+func New(cfg Config) (*RelyingParty, error) {
+	if len(cfg.Origins) == 0 {
+		return nil, errors.New("webauthn: at least one origin is required")
+	}
+	rp := &RelyingParty{id: cfg.RPID, name: cfg.RPName}
+	for _, origin := range cfg.Origins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("webauthn: invalid origin %q", origin)
+		}
+		if rp.id == "" {
+			rp.id = u.Hostname()
+		}
+		rp.origins = append(rp.origins, u.Scheme+"://"+u.Host)
+	}
+	if rp.name == "" {
+		rp.name = rp.id
+	}
+	return rp, nil
+}
+
+// ID returns the relying party ID
+func (rp *RelyingParty) ID() string {
+	return rp.id
+}
+
+// NewChallenge returns a random challenge, base64url encoded as it appears in
+// options and client data
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// User is the account a credential is registered for
+type User struct {
+	// ID is the user handle: opaque bytes returned by the authenticator
+	// during passwordless login, base64url encoded
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialDescriptor refers to an existing credential
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor refers to a credential by its base64url ID
+func NewCredentialDescriptor(id string, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{Type: "public-key", ID: id, Transports: transports}
+}
+
+// PublicKeyCredentialParameters offers a signature algorithm
+type PublicKeyCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// AuthenticatorSelection states what kind of authenticator is wanted
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// RelyingPartyEntity names the relying party
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreationOptions are passed to navigator.credentials.create(), in the JSON
+// form accepted by PublicKeyCredential.parseCreationOptionsFromJSON()
+type CreationOptions struct {
+	Challenge              string                          `json:"challenge"`
+	RP                     RelyingPartyEntity              `json:"rp"`
+	User                   User                            `json:"user"`
+	PubKeyCredParams       []PublicKeyCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                           `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor          `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection          `json:"authenticatorSelection"`
+	Attestation            string                          `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get(), in the JSON form
+// accepted by PublicKeyCredential.parseRequestOptionsFromJSON()
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions returns the options to register a new credential for user.
+// Credentials the user already has are excluded so an authenticator isn't
+// registered twice. Discoverable credentials (passkeys) are preferred so the
+// credential can also be used to log in without a password.
+func (rp *RelyingParty) CreationOptions(user User, exclude []CredentialDescriptor) (*CreationOptions, error) {
+	challenge, err := NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	params := make([]PublicKeyCredentialParameters, 0, len(SupportedAlgorithms))
+	for _, alg := range SupportedAlgorithms {
+		params = append(params, PublicKeyCredentialParameters{Type: "public-key", Alg: alg})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+	return &CreationOptions{
+		Challenge:          challenge,
+		RP:                 RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// RequestOptions returns the options to authenticate with one of allow, or
+// with any discoverable credential for the site when allow is empty.
+// userVerification is "required" for passwordless login and "preferred" when
+// the credential is a second factor.
+func (rp *RelyingParty) RequestOptions(allow []CredentialDescriptor, userVerification string) (*RequestOptions, error) {
+	challenge, err := NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          Timeout.Milliseconds(),
+		RPID:             rp.id,
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}, nil
+}
+
+// RegistrationResponse is the JSON form of the PublicKeyCredential returned
+// by navigator.credentials.create(), as produced by its toJSON() method
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the JSON form of the PublicKeyCredential returned by
+// navigator.credentials.get()
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// Credential is a newly registered credential
+type Credential struct {
+	// ID is the base64url credential ID
+	ID string
+	// PublicKey is the COSE encoded public key
+	PublicKey      []byte
+	SignCount      uint32
+	Transports     []string
+	UserVerified   bool
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// Assertion is the result of a successful authentication
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+	BackedUp     bool
+}
+
+// collectedClientData is the clientDataJSON the browser signs over
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// authenticatorData is the parsed authenticator data
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// VerifyRegistration checks a registration response against the challenge
+// from its creation options and returns the new credential.
+func (rp *RelyingParty) VerifyRegistration(resp *RegistrationResponse, challenge string) (*Credential, error) {
+	if resp.Type != "public-key" {
+		return nil, verificationError("unexpected credential type %q", resp.Type)
+	}
+	clientData, err := decodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, verificationError("invalid clientDataJSON")
+	}
+	if err := rp.verifyClientData(clientData, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, verificationError("invalid attestationObject")
+	}
+	v, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, verificationError("invalid attestationObject: %v", err)
+	}
+	attestation, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, verificationError("attestationObject is not a map")
+	}
+	if _, ok := attestation["fmt"].(string); !ok {
+		return nil, verificationError("attestationObject has no format")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, verificationError("attestationObject has no authData")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData, false); err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttestedData == 0 {
+		return nil, verificationError("no attested credential data")
+	}
+	if _, err := parsePublicKey(authData.publicKey); err != nil {
+		return nil, verificationError("%v", err)
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(authData.credentialID)
+	if resp.ID != id {
+		return nil, verificationError("credential ID does not match authenticator data")
+	}
+
+	return &Credential{
+		ID:             id,
+		PublicKey:      authData.publicKey,
+		SignCount:      authData.signCount,
+		Transports:     resp.Response.Transports,
+		UserVerified:   authData.flags&flagUserVerified != 0,
+		BackupEligible: authData.flags&flagBackupEligible != 0,
+		BackedUp:       authData.flags&flagBackedUp != 0,
+	}, nil
+}
+
+// VerifyAssertion checks an authentication response against the challenge
+// from its request options and the stored public key and signature counter
+// of the credential it names. requireUserVerification is set for
+// passwordless login, where the authenticator's PIN or biometric check
+// stands in for the password.
+func (rp *RelyingParty) VerifyAssertion(resp *AssertionResponse, challenge string, publicKeyCOSE []byte, signCount uint32, requireUserVerification bool) (*Assertion, error) {
+	if resp.Type != "public-key" {
+		return nil, verificationError("unexpected credential type %q", resp.Type)
+	}
+	clientData, err := decodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, verificationError("invalid clientDataJSON")
+	}
+	if err := rp.verifyClientData(clientData, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := decodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, verificationError("invalid authenticatorData")
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.verifyAuthenticatorData(authData, requireUserVerification); err != nil {
+		return nil, err
+	}
+
+	key, err := parsePublicKey(publicKeyCOSE)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored public key: %w", err)
+	}
+	sig, err := decodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return nil, verificationError("invalid signature encoding")
+	}
+	clientDataHash := sha256.Sum256(clientData)
+	if !key.verify(append(slices.Clone(rawAuthData), clientDataHash[:]...), sig) {
+		return nil, verificationError("invalid signature")
+	}
+
+	// Authenticators without a counter always report zero
+	if (authData.signCount != 0 || signCount != 0) && authData.signCount <= signCount {
+		return nil, ErrSignCount
+	}
+
+	return &Assertion{
+		SignCount:    authData.signCount,
+		UserVerified: authData.flags&flagUserVerified != 0,
+		BackedUp:     authData.flags&flagBackedUp != 0,
+	}, nil
+}
+
+func (rp *RelyingParty) verifyClientData(raw []byte, ceremony, challenge string) error {
+	var cd collectedClientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return verificationError("invalid clientDataJSON")
+	}
+	if cd.Type != ceremony {
+		return verificationError("unexpected ceremony %q", cd.Type)
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return verificationError("challenge mismatch")
+	}
+	if !slices.Contains(rp.origins, cd.Origin) {
+		return verificationError("origin %q not allowed", cd.Origin)
+	}
+	if cd.CrossOrigin {
+		return verificationError("cross-origin requests are not allowed")
+	}
+	return nil
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData, requireUserVerification bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.id))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return verificationError("credential is for another site")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return verificationError("user not present")
+	}
+	if requireUserVerification && authData.flags&flagUserVerified == 0 {
+		return verificationError("user not verified")
+	}
+	return nil
+}
+
+// parseAuthenticatorData decodes authenticator data: the RP ID hash, flags
+// and signature counter, followed by the new credential when registering
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, verificationError("authenticator data too short")
+	}
+	ad := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if ad.flags&flagAttestedData != 0 {
+		// AAGUID, then a length-prefixed credential ID and a COSE key
+		if len(rest) < 18 {
+			return nil, verificationError("attested credential data too short")
+		}
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, verificationError("invalid credential ID length")
+		}
+		ad.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, verificationError("invalid credential public key: %v", err)
+		}
+		ad.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+		rest = after
+	}
+	if ad.flags&flagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, verificationError("invalid extension data: %v", err)
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, verificationError("trailing authenticator data")
+	}
+	return ad, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers and
+// libraries differ
+func decodeBase64URL(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("empty value")
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
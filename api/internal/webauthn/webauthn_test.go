@@ -0,0 +1,434 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testOrigin = "https://app.example.com"
+
+// encodeCBOR encodes the subset of CBOR decodeCBOR reads, with map keys in
+// a stable order
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		default:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+	}
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case []interface{}:
+		out := head(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		encoded := map[string][]byte{}
+		for k, val := range v {
+			ek := encodeCBOR(k)
+			keys = append(keys, ek)
+			encoded[string(ek)] = encodeCBOR(val)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+		out := head(5, uint64(len(v)))
+		for _, k := range keys {
+			out = append(append(out, k...), encoded[string(k)]...)
+		}
+		return out
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+// authenticator is a software authenticator holding one credential
+type authenticator struct {
+	credentialID []byte
+	cose         []byte
+	sign         func(message []byte) []byte
+	signCount    uint32
+	counterless  bool
+	flags        byte
+}
+
+func newES256Authenticator(t *testing.T) *authenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{
+		credentialID: []byte("es256-credential"),
+		cose: encodeCBOR(map[interface{}]interface{}{
+			1: coseKtyEC2, 3: AlgES256, -1: coseCrvP256, -2: point[1:33], -3: point[33:],
+		}),
+		sign: func(message []byte) []byte {
+			digest := sha256.Sum256(message)
+			sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+		flags: flagUserPresent | flagUserVerified | flagBackupEligible | flagBackedUp,
+	}
+}
+
+func newEd25519Authenticator(t *testing.T) *authenticator {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{
+		credentialID: []byte("ed25519-credential"),
+		cose:         encodeCBOR(map[interface{}]interface{}{1: coseKtyOKP, 3: AlgEdDSA, -1: coseCrvEd25519, -2: []byte(pub)}),
+		sign:         func(message []byte) []byte { return ed25519.Sign(priv, message) },
+		flags:        flagUserPresent,
+	}
+}
+
+func newRS256Authenticator(t *testing.T) *authenticator {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &authenticator{
+		credentialID: []byte("rs256-credential"),
+		cose: encodeCBOR(map[interface{}]interface{}{
+			1: coseKtyRSA, 3: AlgRS256, -1: key.N.Bytes(), -2: big.NewInt(int64(key.E)).Bytes(),
+		}),
+		sign: func(message []byte) []byte {
+			digest := sha256.Sum256(message)
+			sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return sig
+		},
+		flags: flagUserPresent | flagUserVerified,
+	}
+}
+
+func (a *authenticator) id() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialID)
+}
+
+func clientDataJSON(ceremony, challenge, origin string) []byte {
+	b, _ := json.Marshal(collectedClientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	return b
+}
+
+func (a *authenticator) authData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedData
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.cose...)
+	}
+	return data
+}
+
+func (a *authenticator) register(rpID, challenge, origin string) *RegistrationResponse {
+	resp := &RegistrationResponse{ID: a.id(), RawID: a.id(), Type: "public-key"}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON("webauthn.create", challenge, origin))
+	resp.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(encodeCBOR(map[interface{}]interface{}{
+		"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": a.authData(rpID, true),
+	}))
+	resp.Response.Transports = []string{"internal", "hybrid"}
+	return resp
+}
+
+func (a *authenticator) assert(rpID, challenge, origin string) *AssertionResponse {
+	if !a.counterless {
+		a.signCount++
+	}
+	clientData := clientDataJSON("webauthn.get", challenge, origin)
+	authData := a.authData(rpID, false)
+	clientDataHash := sha256.Sum256(clientData)
+
+	resp := &AssertionResponse{ID: a.id(), RawID: a.id(), Type: "public-key"}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	resp.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	resp.Response.Signature = base64.RawURLEncoding.EncodeToString(a.sign(append(authData, clientDataHash[:]...)))
+	return resp
+}
+
+func newTestRelyingParty(t *testing.T) *RelyingParty {
+	t.Helper()
+	rp, err := New(Config{RPID: "example.com", RPName: "TaskAI", Origins: []string{testOrigin}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return rp
+}
+
+func TestNew(t *testing.T) {
+	rp, err := New(Config{Origins: []string{"http://localhost:5173/"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if rp.ID() != "localhost" || rp.name != "localhost" || rp.origins[0] != "http://localhost:5173" {
+		t.Errorf("Expected defaults from the origin, got %+v", rp)
+	}
+
+	if _, err := New(Config{}); err == nil {
+		t.Error("Expected an error without origins")
+	}
+	if _, err := New(Config{Origins: []string{"app.example.com"}}); err == nil {
+		t.Error("Expected an error for an origin without a scheme")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	rp := newTestRelyingParty(t)
+
+	creation, err := rp.CreationOptions(User{ID: "AQ", Name: "user@example.com"}, nil)
+	if err != nil {
+		t.Fatalf("CreationOptions failed: %v", err)
+	}
+	raw, _ := json.Marshal(creation)
+	var decoded map[string]interface{}
+	json.Unmarshal(raw, &decoded)
+	if decoded["excludeCredentials"] == nil || decoded["attestation"] != "none" || creation.RP.ID != "example.com" {
+		t.Errorf("Unexpected creation options: %s", raw)
+	}
+	if len(creation.PubKeyCredParams) != len(SupportedAlgorithms) || creation.PubKeyCredParams[0].Alg != AlgES256 {
+		t.Errorf("Expected ES256 to be preferred, got %+v", creation.PubKeyCredParams)
+	}
+
+	request, err := rp.RequestOptions(nil, "required")
+	if err != nil {
+		t.Fatalf("RequestOptions failed: %v", err)
+	}
+	if request.Challenge == creation.Challenge || len(request.Challenge) != 43 {
+		t.Errorf("Expected a fresh 32-byte challenge, got %q", request.Challenge)
+	}
+	if request.AllowCredentials == nil || request.RPID != "example.com" {
+		t.Errorf("Unexpected request options: %+v", request)
+	}
+}
+
+func TestRegisterAndAuthenticate(t *testing.T) {
+	rp := newTestRelyingParty(t)
+
+	for _, tt := range []struct {
+		name string
+		new  func(*testing.T) *authenticator
+	}{
+		{"ES256", newES256Authenticator},
+		{"EdDSA", newEd25519Authenticator},
+		{"RS256", newRS256Authenticator},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.new(t)
+			cred, err := rp.VerifyRegistration(a.register("example.com", "reg-challenge", testOrigin), "reg-challenge")
+			if err != nil {
+				t.Fatalf("VerifyRegistration failed: %v", err)
+			}
+			if cred.ID != a.id() || string(cred.PublicKey) != string(a.cose) || len(cred.Transports) != 2 {
+				t.Errorf("Unexpected credential: %+v", cred)
+			}
+
+			assertion, err := rp.VerifyAssertion(a.assert("example.com", "login-challenge", testOrigin), "login-challenge", cred.PublicKey, cred.SignCount, false)
+			if err != nil {
+				t.Fatalf("VerifyAssertion failed: %v", err)
+			}
+			if assertion.SignCount != 1 {
+				t.Errorf("Expected sign count 1, got %d", assertion.SignCount)
+			}
+		})
+	}
+
+	t.Run("flags", func(t *testing.T) {
+		a := newES256Authenticator(t)
+		cred, err := rp.VerifyRegistration(a.register("example.com", "c", testOrigin), "c")
+		if err != nil {
+			t.Fatalf("VerifyRegistration failed: %v", err)
+		}
+		if !cred.UserVerified || !cred.BackupEligible || !cred.BackedUp {
+			t.Errorf("Expected a verified, synced passkey, got %+v", cred)
+		}
+	})
+}
+
+func TestVerifyRegistrationRejects(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	a := newES256Authenticator(t)
+
+	tests := []struct {
+		name   string
+		resp   func() *RegistrationResponse
+		expect string
+	}{
+		{"wrong challenge", func() *RegistrationResponse { return a.register("example.com", "other", testOrigin) }, "challenge"},
+		{"wrong origin", func() *RegistrationResponse { return a.register("example.com", "c", "https://evil.example") }, "origin"},
+		{"wrong RP ID", func() *RegistrationResponse { return a.register("evil.example", "c", testOrigin) }, "site"},
+		{"assertion instead of registration", func() *RegistrationResponse {
+			resp := a.register("example.com", "c", testOrigin)
+			resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientDataJSON("webauthn.get", "c", testOrigin))
+			return resp
+		}, "ceremony"},
+		{"mismatched ID", func() *RegistrationResponse {
+			resp := a.register("example.com", "c", testOrigin)
+			resp.ID = "b3RoZXI"
+			return resp
+		}, "credential ID"},
+		{"garbage attestation", func() *RegistrationResponse {
+			resp := a.register("example.com", "c", testOrigin)
+			resp.Response.AttestationObject = "AAAA"
+			return resp
+		}, "attestationObject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rp.VerifyRegistration(tt.resp(), "c")
+			if !errors.Is(err, ErrVerification) {
+				t.Fatalf("Expected a verification error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.expect) {
+				t.Errorf("Expected error about %s, got %v", tt.expect, err)
+			}
+		})
+	}
+
+	t.Run("user not present", func(t *testing.T) {
+		a := newES256Authenticator(t)
+		a.flags = 0
+		if _, err := rp.VerifyRegistration(a.register("example.com", "c", testOrigin), "c"); !errors.Is(err, ErrVerification) {
+			t.Errorf("Expected a verification error, got %v", err)
+		}
+	})
+}
+
+func TestVerifyAssertionRejects(t *testing.T) {
+	rp := newTestRelyingParty(t)
+	a := newES256Authenticator(t)
+	cred, err := rp.VerifyRegistration(a.register("example.com", "c", testOrigin), "c")
+	if err != nil {
+		t.Fatalf("VerifyRegistration failed: %v", err)
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		resp := a.assert("example.com", "c", testOrigin)
+		resp.Response.Signature = base64.RawURLEncoding.EncodeToString([]byte("not a signature"))
+		if _, err := rp.VerifyAssertion(resp, "c", cred.PublicKey, 0, false); !errors.Is(err, ErrVerification) {
+			t.Errorf("Expected a verification error, got %v", err)
+		}
+	})
+
+	t.Run("another credential's key", func(t *testing.T) {
+		other := newES256Authenticator(t)
+		if _, err := rp.VerifyAssertion(a.assert("example.com", "c", testOrigin), "c", other.cose, 0, false); !errors.Is(err, ErrVerification) {
+			t.Errorf("Expected a verification error, got %v", err)
+		}
+	})
+
+	t.Run("replayed counter", func(t *testing.T) {
+		resp := a.assert("example.com", "c", testOrigin)
+		if _, err := rp.VerifyAssertion(resp, "c", cred.PublicKey, a.signCount, false); !errors.Is(err, ErrSignCount) {
+			t.Errorf("Expected ErrSignCount, got %v", err)
+		}
+	})
+
+	t.Run("counterless authenticator", func(t *testing.T) {
+		a := newES256Authenticator(t)
+		a.counterless = true
+		for i := 0; i < 2; i++ {
+			if _, err := rp.VerifyAssertion(a.assert("example.com", "c", testOrigin), "c", a.cose, 0, false); err != nil {
+				t.Errorf("Expected a zero counter to be accepted, got %v", err)
+			}
+		}
+	})
+
+	t.Run("user verification required", func(t *testing.T) {
+		a := newEd25519Authenticator(t)
+		resp := a.assert("example.com", "c", testOrigin)
+		if _, err := rp.VerifyAssertion(resp, "c", a.cose, 0, true); err == nil || !strings.Contains(err.Error(), "not verified") {
+			t.Errorf("Expected a user verification error, got %v", err)
+		}
+		if _, err := rp.VerifyAssertion(resp, "c", a.cose, 0, false); err != nil {
+			t.Errorf("Expected the assertion to pass as a second factor, got %v", err)
+		}
+	})
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		if _, err := rp.VerifyAssertion(a.assert("example.com", "c", testOrigin), "other", cred.PublicKey, 0, false); !errors.Is(err, ErrVerification) {
+			t.Errorf("Expected a verification error, got %v", err)
+		}
+	})
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	v, rest, err := decodeCBOR(append(encodeCBOR(map[interface{}]interface{}{"a": []interface{}{1, -300, true}, 3: []byte{1, 2}}), 0xff))
+	if err != nil {
+		t.Fatalf("decodeCBOR failed: %v", err)
+	}
+	m := v.(map[interface{}]interface{})
+	items := m["a"].([]interface{})
+	if items[0] != int64(1) || items[1] != int64(-300) || items[2] != true || len(m[int64(3)].([]byte)) != 2 {
+		t.Errorf("Unexpected value: %#v", v)
+	}
+	if len(rest) != 1 {
+		t.Errorf("Expected the trailing byte to be returned, got %x", rest)
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated":      {0x5a, 0, 0, 0, 10, 1},
+		"huge array":     {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite":     {0x5f},
+		"float":          {0xfa, 0, 0, 0, 0},
+		"duplicate keys": {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"deep nesting":   append(bytes.Repeat([]byte{0x81}, maxCBORDepth+2), 0x00),
+	} {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
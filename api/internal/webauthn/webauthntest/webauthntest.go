@@ -0,0 +1,142 @@
+// Package webauthntest provides a software authenticator for testing WebAuthn
+// registration and login without a browser.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+
+	"taskai/internal/webauthn"
+)
+
+// Authenticator holds one ES256 credential, like a security key or passkey
+type Authenticator struct {
+	// CredentialID is the raw credential ID
+	CredentialID []byte
+	// SignCount is incremented by every assertion
+	SignCount uint32
+	// UserVerified reports a PIN or biometric check; without it the
+	// authenticator only proves user presence
+	UserVerified bool
+	// Synced marks the credential as a backed up, multi-device passkey
+	Synced bool
+	// UserHandle is returned with assertions, as registered
+	UserHandle string
+
+	key *ecdsa.PrivateKey
+}
+
+// New returns an authenticator with a new credential that verifies the user
+func New() *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return &Authenticator{CredentialID: id, UserVerified: true, key: key}
+}
+
+// ID returns the base64url credential ID
+func (a *Authenticator) ID() string {
+	return base64.RawURLEncoding.EncodeToString(a.CredentialID)
+}
+
+// Register answers creation options as a browser at origin would
+func (a *Authenticator) Register(options *webauthn.CreationOptions, origin string) *webauthn.RegistrationResponse {
+	a.UserHandle = options.User.ID
+	clientData := clientDataJSON("webauthn.create", options.Challenge, origin)
+
+	resp := &webauthn.RegistrationResponse{ID: a.ID(), RawID: a.ID(), Type: "public-key"}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	resp.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(a.attestationObject(options.RP.ID))
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+// Assert answers request options as a browser at origin would
+func (a *Authenticator) Assert(options *webauthn.RequestOptions, origin string) *webauthn.AssertionResponse {
+	a.SignCount++
+	clientData := clientDataJSON("webauthn.get", options.Challenge, origin)
+	authData := a.authData(options.RPID, false)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	resp := &webauthn.AssertionResponse{ID: a.ID(), RawID: a.ID(), Type: "public-key"}
+	resp.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	resp.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	resp.Response.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	resp.Response.UserHandle = a.UserHandle
+	return resp
+}
+
+func clientDataJSON(ceremony, challenge, origin string) []byte {
+	b, _ := json.Marshal(map[string]interface{}{"type": ceremony, "challenge": challenge, "origin": origin, "crossOrigin": false})
+	return b
+}
+
+func (a *Authenticator) authData(rpID string, attested bool) []byte {
+	flags := byte(0x01) // user present
+	if a.UserVerified {
+		flags |= 0x04
+	}
+	if a.Synced {
+		flags |= 0x08 | 0x10
+	}
+	if attested {
+		flags |= 0x40
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.CredentialID)))
+		data = append(data, a.CredentialID...)
+		data = append(data, a.publicKey()...)
+	}
+	return data
+}
+
+// publicKey encodes the credential's public key as a COSE_Key:
+// {1: 2 (EC2), 3: -7 (ES256), -1: 1 (P-256), -2: x, -3: y}
+func (a *Authenticator) publicKey() []byte {
+	point, err := a.key.PublicKey.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	key := []byte{0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21, 0x58, 0x20}
+	key = append(key, point[1:33]...)
+	key = append(key, 0x22, 0x58, 0x20)
+	return append(key, point[33:]...)
+}
+
+// attestationObject encodes {"fmt": "none", "attStmt": {}, "authData": ...}
+func (a *Authenticator) attestationObject(rpID string) []byte {
+	authData := a.authData(rpID, true)
+	obj := []byte{0xa3}
+	obj = append(obj, cborText("fmt")...)
+	obj = append(obj, cborText("none")...)
+	obj = append(obj, cborText("attStmt")...)
+	obj = append(obj, 0xa0)
+	obj = append(obj, cborText("authData")...)
+	obj = append(obj, 0x59) // byte string, two-byte length
+	obj = binary.BigEndian.AppendUint16(obj, uint16(len(authData)))
+	return append(obj, authData...)
+}
+
+func cborText(s string) []byte {
+	return append([]byte{0x60 | byte(len(s))}, s...)
+}
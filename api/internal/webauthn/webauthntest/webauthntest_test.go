@@ -0,0 +1,40 @@
+package webauthntest
+
+import (
+	"testing"
+
+	"taskai/internal/webauthn"
+)
+
+func TestAuthenticator(t *testing.T) {
+	rp, err := webauthn.New(webauthn.Config{Origins: []string{"http://localhost:5173"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	a := New()
+	a.Synced = true
+
+	creation, err := rp.CreationOptions(webauthn.User{ID: "AQ", Name: "user@example.com"}, nil)
+	if err != nil {
+		t.Fatalf("CreationOptions failed: %v", err)
+	}
+	cred, err := rp.VerifyRegistration(a.Register(creation, "http://localhost:5173"), creation.Challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration failed: %v", err)
+	}
+	if !cred.BackupEligible || cred.ID != a.ID() {
+		t.Errorf("Unexpected credential: %+v", cred)
+	}
+
+	request, err := rp.RequestOptions(nil, "required")
+	if err != nil {
+		t.Fatalf("RequestOptions failed: %v", err)
+	}
+	assertion := a.Assert(request, "http://localhost:5173")
+	if assertion.Response.UserHandle != "AQ" {
+		t.Errorf("Expected the registered user handle, got %q", assertion.Response.UserHandle)
+	}
+	if _, err := rp.VerifyAssertion(assertion, request.Challenge, cred.PublicKey, cred.SignCount, true); err != nil {
+		t.Errorf("VerifyAssertion failed: %v", err)
+	}
+}
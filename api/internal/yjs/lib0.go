@@ -0,0 +1,175 @@
+package yjs
+
+import "errors"
+
+// ErrUnexpectedEOF is returned when an update ends in the middle of a value.
+var ErrUnexpectedEOF = errors.New("yjs: unexpected end of update")
+
+// decoder reads lib0-encoded values (the binary format used by Yjs).
+type decoder struct {
+	buf []byte
+	pos int
+}
+
+func newDecoder(buf []byte) *decoder {
+	return &decoder{buf: buf}
+}
+
+func (d *decoder) readUint8() (byte, error) {
+	if d.pos >= len(d.buf) {
+		return 0, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos]
+	d.pos++
+	return b, nil
+}
+
+// readVarUint reads an unsigned integer stored 7 bits per byte, low bits first.
+func (d *decoder) readVarUint() (uint64, error) {
+	var num uint64
+	var shift uint
+	for {
+		b, err := d.readUint8()
+		if err != nil {
+			return 0, err
+		}
+		num |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return num, nil
+		}
+		shift += 7
+		if shift > 63 {
+			return 0, errors.New("yjs: varuint overflow")
+		}
+	}
+}
+
+// readVarInt reads a signed integer: the first byte holds a continuation bit,
+// a sign bit and 6 value bits.
+func (d *decoder) readVarInt() (int64, error) {
+	b, err := d.readUint8()
+	if err != nil {
+		return 0, err
+	}
+	num := uint64(b & 0x3f)
+	negative := b&0x40 != 0
+	shift := uint(6)
+	for b >= 0x80 {
+		if b, err = d.readUint8(); err != nil {
+			return 0, err
+		}
+		num |= uint64(b&0x7f) << shift
+		shift += 7
+		if shift > 70 {
+			return 0, errors.New("yjs: varint overflow")
+		}
+	}
+	if negative {
+		return -int64(num), nil
+	}
+	return int64(num), nil
+}
+
+func (d *decoder) readBytes(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.buf) {
+		return nil, ErrUnexpectedEOF
+	}
+	b := d.buf[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *decoder) readVarUint8Array() ([]byte, error) {
+	n, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)) {
+		return nil, ErrUnexpectedEOF
+	}
+	return d.readBytes(int(n))
+}
+
+func (d *decoder) readVarString() (string, error) {
+	b, err := d.readVarUint8Array()
+	return string(b), err
+}
+
+// skipAny advances past one value written with lib0's writeAny.
+func (d *decoder) skipAny() error {
+	tag, err := d.readUint8()
+	if err != nil {
+		return err
+	}
+	switch tag {
+	case 127, 126, 121, 120: // undefined, null, false, true
+		return nil
+	case 125: // integer
+		_, err = d.readVarInt()
+	case 124: // float32
+		_, err = d.readBytes(4)
+	case 123, 122: // float64, bigint
+		_, err = d.readBytes(8)
+	case 119: // string
+		_, err = d.readVarString()
+	case 118: // object
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if _, err = d.readVarString(); err != nil {
+				return err
+			}
+			if err = d.skipAny(); err != nil {
+				return err
+			}
+		}
+	case 117: // array
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return err
+		}
+		for i := uint64(0); i < n; i++ {
+			if err = d.skipAny(); err != nil {
+				return err
+			}
+		}
+	case 116: // Uint8Array
+		_, err = d.readVarUint8Array()
+	default:
+		return errors.New("yjs: unknown any type")
+	}
+	return err
+}
+
+// encoder writes lib0-encoded values.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) writeUint8(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *encoder) writeVarUint(num uint64) {
+	for num > 0x7f {
+		e.buf = append(e.buf, byte(0x80|(num&0x7f)))
+		num >>= 7
+	}
+	e.buf = append(e.buf, byte(num))
+}
+
+func (e *encoder) writeBytes(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeVarUint8Array(b []byte) {
+	e.writeVarUint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) writeVarString(s string) {
+	e.writeVarUint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
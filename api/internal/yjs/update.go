@@ -0,0 +1,639 @@
+package yjs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// This file is a native port of the parts of Yjs needed to merge document
+// updates (Y.mergeUpdates) in the v1 update format. Merging works on the
+// encoded structs alone, without integrating them into a document, so the
+// result is exactly what the JS implementation produces and can be applied
+// by any Yjs client.
+
+// Struct kinds (the low 5 bits of a struct's info byte)
+const (
+	refGC          = 0
+	refDeleted     = 1
+	refJSON        = 2
+	refBinary      = 3
+	refString      = 4
+	refEmbed       = 5
+	refFormat      = 6
+	refType        = 7
+	refAny         = 8
+	refDoc         = 9
+	refSkip        = 10
+	infoHasOrigin  = 0x80
+	infoHasRightOr = 0x40
+	infoHasSub     = 0x20
+	typeRefXMLElem = 3
+	typeRefXMLHook = 5
+)
+
+// id identifies a struct by client and Lamport clock.
+type id struct {
+	client uint64
+	clock  uint64
+}
+
+// content is the payload of an Item. Multi-unit contents (strings, JSON and
+// any arrays, deleted runs) can be written from an offset; everything else is
+// a single unit kept as raw bytes.
+type content struct {
+	ref     byte
+	raw     []byte   // single-unit contents, encoded as-is
+	str     []uint16 // refString, in UTF-16 code units like JS strings
+	elems   [][]byte // refJSON / refAny, one encoded element each
+	deleted uint64   // refDeleted run length
+}
+
+func (c *content) length() uint64 {
+	switch c.ref {
+	case refDeleted:
+		return c.deleted
+	case refString:
+		return uint64(len(c.str))
+	case refJSON, refAny:
+		return uint64(len(c.elems))
+	default:
+		return 1
+	}
+}
+
+// splice returns the part of the content from offset on.
+func (c *content) splice(offset uint64) *content {
+	switch c.ref {
+	case refDeleted:
+		return &content{ref: refDeleted, deleted: c.deleted - offset}
+	case refString:
+		right := append([]uint16(nil), c.str[offset:]...)
+		// Never start with half of a surrogate pair (mirrors ContentString.splice)
+		if offset > 0 && utf16.IsSurrogate(rune(c.str[offset-1])) && c.str[offset-1] < 0xdc00 {
+			right[0] = 0xfffd
+		}
+		return &content{ref: refString, str: right}
+	case refJSON, refAny:
+		return &content{ref: c.ref, elems: c.elems[offset:]}
+	default:
+		return c
+	}
+}
+
+func (c *content) write(e *encoder, offset uint64) {
+	switch c.ref {
+	case refDeleted:
+		e.writeVarUint(c.deleted - offset)
+	case refString:
+		e.writeVarString(string(utf16.Decode(c.str[offset:])))
+	case refJSON, refAny:
+		e.writeVarUint(uint64(len(c.elems)) - offset)
+		for _, el := range c.elems[offset:] {
+			e.writeBytes(el)
+		}
+	default:
+		e.writeBytes(c.raw)
+	}
+}
+
+// structRef is a decoded GC, Skip or Item.
+type structRef struct {
+	kind        byte // refGC, refSkip, or the content ref of an Item
+	id          id
+	len         uint64 // GC/Skip length; Items use content.length()
+	origin      *id
+	rightOrigin *id
+	parentKey   *string // root type name
+	parentID    *id     // parent item
+	parentSub   *string
+	content     *content
+}
+
+func (s *structRef) isItem() bool {
+	return s.kind != refGC && s.kind != refSkip
+}
+
+func (s *structRef) length() uint64 {
+	if s.isItem() {
+		return s.content.length()
+	}
+	return s.len
+}
+
+func (s *structRef) end() uint64 {
+	return s.id.clock + s.length()
+}
+
+// slice returns the struct from diff units on (mirrors sliceStruct in Yjs).
+func (s *structRef) slice(diff uint64) *structRef {
+	if !s.isItem() {
+		return &structRef{kind: s.kind, id: id{s.id.client, s.id.clock + diff}, len: s.len - diff}
+	}
+	return &structRef{
+		kind:        s.kind,
+		id:          id{s.id.client, s.id.clock + diff},
+		origin:      &id{s.id.client, s.id.clock + diff - 1},
+		rightOrigin: s.rightOrigin,
+		parentKey:   s.parentKey,
+		parentID:    s.parentID,
+		parentSub:   s.parentSub,
+		content:     s.content.splice(diff),
+	}
+}
+
+// mergeWith appends right to s when both are GC or both are Skip. Decoded
+// Items are never merged, matching Item.mergeWith for unintegrated items.
+func (s *structRef) mergeWith(right *structRef) bool {
+	if s.isItem() || s.kind != right.kind {
+		return false
+	}
+	s.len += right.len
+	return true
+}
+
+func (s *structRef) write(e *encoder, offset uint64) {
+	switch s.kind {
+	case refGC:
+		e.writeUint8(refGC)
+		e.writeVarUint(s.len - offset)
+		return
+	case refSkip:
+		e.writeUint8(refSkip)
+		e.writeVarUint(s.len - offset)
+		return
+	}
+
+	origin := s.origin
+	if offset > 0 {
+		origin = &id{s.id.client, s.id.clock + offset - 1}
+	}
+	info := s.kind
+	if origin != nil {
+		info |= infoHasOrigin
+	}
+	if s.rightOrigin != nil {
+		info |= infoHasRightOr
+	}
+	if s.parentSub != nil {
+		info |= infoHasSub
+	}
+	e.writeUint8(info)
+	if origin != nil {
+		e.writeVarUint(origin.client)
+		e.writeVarUint(origin.clock)
+	}
+	if s.rightOrigin != nil {
+		e.writeVarUint(s.rightOrigin.client)
+		e.writeVarUint(s.rightOrigin.clock)
+	}
+	if origin == nil && s.rightOrigin == nil {
+		if s.parentKey != nil {
+			e.writeVarUint(1)
+			e.writeVarString(*s.parentKey)
+		} else {
+			e.writeVarUint(0)
+			e.writeVarUint(s.parentID.client)
+			e.writeVarUint(s.parentID.clock)
+		}
+		if s.parentSub != nil {
+			e.writeVarString(*s.parentSub)
+		}
+	}
+	s.content.write(e, offset)
+}
+
+// deleteRange is a run of deleted clocks of one client.
+type deleteRange struct {
+	clock uint64
+	len   uint64
+}
+
+// update is a fully decoded v1 update.
+type update struct {
+	structs []*structRef // in encoded order: clients descending, clocks ascending
+	deletes map[uint64][]deleteRange
+}
+
+func readID(d *decoder) (*id, error) {
+	client, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	clock, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	return &id{client, clock}, nil
+}
+
+func readContent(d *decoder, ref byte) (*content, error) {
+	c := &content{ref: ref}
+	start := d.pos
+	var err error
+	switch ref {
+	case refDeleted:
+		c.deleted, err = d.readVarUint()
+	case refString:
+		var s string
+		if s, err = d.readVarString(); err == nil {
+			c.str = utf16.Encode([]rune(s))
+		}
+	case refJSON, refAny:
+		var n uint64
+		if n, err = d.readVarUint(); err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.buf)) {
+			return nil, ErrUnexpectedEOF
+		}
+		c.elems = make([][]byte, 0, n)
+		for i := uint64(0); i < n; i++ {
+			elStart := d.pos
+			if ref == refJSON {
+				_, err = d.readVarString()
+			} else {
+				err = d.skipAny()
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.elems = append(c.elems, d.buf[elStart:d.pos])
+		}
+	case refBinary, refEmbed:
+		_, err = d.readVarUint8Array()
+	case refFormat:
+		if _, err = d.readVarString(); err == nil {
+			_, err = d.readVarString()
+		}
+	case refType:
+		var typeRef uint64
+		if typeRef, err = d.readVarUint(); err == nil && (typeRef == typeRefXMLElem || typeRef == typeRefXMLHook) {
+			_, err = d.readVarString()
+		}
+	case refDoc:
+		if _, err = d.readVarString(); err == nil {
+			err = d.skipAny()
+		}
+	default:
+		return nil, fmt.Errorf("yjs: unknown content type %d", ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.raw = d.buf[start:d.pos]
+	return c, nil
+}
+
+func readStruct(d *decoder, client, clock uint64) (*structRef, error) {
+	info, err := d.readUint8()
+	if err != nil {
+		return nil, err
+	}
+	kind := info & 0x1f
+	switch kind {
+	case refGC, refSkip:
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		return &structRef{kind: kind, id: id{client, clock}, len: n}, nil
+	}
+
+	s := &structRef{kind: kind, id: id{client, clock}}
+	if info&infoHasOrigin != 0 {
+		if s.origin, err = readID(d); err != nil {
+			return nil, err
+		}
+	}
+	if info&infoHasRightOr != 0 {
+		if s.rightOrigin, err = readID(d); err != nil {
+			return nil, err
+		}
+	}
+	if info&(infoHasOrigin|infoHasRightOr) == 0 {
+		parentInfo, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		if parentInfo == 1 {
+			key, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			s.parentKey = &key
+		} else if s.parentID, err = readID(d); err != nil {
+			return nil, err
+		}
+		if info&infoHasSub != 0 {
+			sub, err := d.readVarString()
+			if err != nil {
+				return nil, err
+			}
+			s.parentSub = &sub
+		}
+	}
+	if s.content, err = readContent(d, kind); err != nil {
+		return nil, err
+	}
+	if s.length() == 0 {
+		return nil, errors.New("yjs: empty struct")
+	}
+	return s, nil
+}
+
+// decodeUpdate parses a v1 update.
+func decodeUpdate(buf []byte) (*update, error) {
+	d := newDecoder(buf)
+	u := &update{deletes: map[uint64][]deleteRange{}}
+
+	numClients, err := d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numClients; i++ {
+		numStructs, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		clock, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < numStructs; j++ {
+			s, err := readStruct(d, client, clock)
+			if err != nil {
+				return nil, err
+			}
+			u.structs = append(u.structs, s)
+			clock += s.length()
+		}
+	}
+
+	numClients, err = d.readVarUint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numClients; i++ {
+		client, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		n, err := d.readVarUint()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < n; j++ {
+			clock, err := d.readVarUint()
+			if err != nil {
+				return nil, err
+			}
+			length, err := d.readVarUint()
+			if err != nil {
+				return nil, err
+			}
+			u.deletes[client] = append(u.deletes[client], deleteRange{clock, length})
+		}
+	}
+	return u, nil
+}
+
+// structReader iterates an update's structs, skipping Skip placeholders.
+type structReader struct {
+	structs []*structRef
+	pos     int
+	curr    *structRef
+}
+
+func newStructReader(structs []*structRef) *structReader {
+	r := &structReader{structs: structs, pos: -1}
+	r.next()
+	return r
+}
+
+func (r *structReader) next() *structRef {
+	r.curr = nil
+	for r.pos+1 < len(r.structs) {
+		r.pos++
+		if s := r.structs[r.pos]; s.kind != refSkip {
+			r.curr = s
+			break
+		}
+	}
+	return r.curr
+}
+
+// structWriter buffers structs per client and prefixes each client's run
+// with its struct count (mirrors LazyStructWriter).
+type structWriter struct {
+	written    uint64
+	currClient uint64
+	rest       *encoder
+	parts      []writtenPart
+}
+
+type writtenPart struct {
+	written uint64
+	data    []byte
+}
+
+func (w *structWriter) write(s *structRef, offset uint64) {
+	if w.written > 0 && w.currClient != s.id.client {
+		w.flush()
+	}
+	if w.written == 0 {
+		w.currClient = s.id.client
+		w.rest.writeVarUint(s.id.client)
+		w.rest.writeVarUint(s.id.clock + offset)
+	}
+	s.write(w.rest, offset)
+	w.written++
+}
+
+func (w *structWriter) flush() {
+	if w.written > 0 {
+		w.parts = append(w.parts, writtenPart{w.written, w.rest.buf})
+		w.rest = &encoder{}
+		w.written = 0
+	}
+}
+
+func (w *structWriter) finish(e *encoder) {
+	w.flush()
+	e.writeVarUint(uint64(len(w.parts)))
+	for _, p := range w.parts {
+		e.writeVarUint(p.written)
+		e.writeBytes(p.data)
+	}
+}
+
+// pendingWrite is the struct currently being extended before it is written.
+type pendingWrite struct {
+	s      *structRef
+	offset uint64
+}
+
+// MergeUpdates merges v1 Yjs updates into a single update, dropping
+// duplicate structs and combining delete sets. It is a port of
+// Y.mergeUpdates: the result applies to a document exactly like applying
+// every input update in turn.
+//
+// Like Y.mergeUpdates it doesn't garbage-collect: deleted content stays in
+// the result along with its delete set. Only a document that integrates the
+// update and encodes its state (Y.encodeStateAsUpdate with gc on) drops it.
+func MergeUpdates(updates [][]byte) ([]byte, error) {
+	if len(updates) == 1 {
+		return updates[0], nil
+	}
+
+	decoded := make([]*update, len(updates))
+	readers := make([]*structReader, len(updates))
+	for i, buf := range updates {
+		u, err := decodeUpdate(buf)
+		if err != nil {
+			return nil, fmt.Errorf("update %d: %w", i, err)
+		}
+		decoded[i] = u
+		readers[i] = newStructReader(u.structs)
+	}
+
+	writer := &structWriter{rest: &encoder{}}
+	var currWrite *pendingWrite
+
+	for {
+		// Write higher clients first: sort by client desc, clock asc and drop drained readers
+		active := readers[:0]
+		for _, r := range readers {
+			if r.curr != nil {
+				active = append(active, r)
+			}
+		}
+		readers = active
+		sort.SliceStable(readers, func(i, j int) bool {
+			a, b := readers[i].curr, readers[j].curr
+			if a.id.client != b.id.client {
+				return a.id.client > b.id.client
+			}
+			return a.id.clock < b.id.clock
+		})
+		if len(readers) == 0 {
+			break
+		}
+
+		currReader := readers[0]
+		firstClient := currReader.curr.id.client
+
+		if currWrite != nil {
+			curr := currReader.curr
+			iterated := false
+			// Skip everything this reader has that was already written
+			for curr != nil && curr.end() <= currWrite.s.end() && curr.id.client >= currWrite.s.id.client {
+				curr = currReader.next()
+				iterated = true
+			}
+			if curr == nil || curr.id.client != firstClient ||
+				(iterated && curr.id.clock > currWrite.s.end()) {
+				continue
+			}
+
+			if firstClient != currWrite.s.id.client {
+				writer.write(currWrite.s, currWrite.offset)
+				currWrite = &pendingWrite{s: curr}
+				currReader.next()
+			} else if currWrite.s.end() < curr.id.clock {
+				// Gap in the clock: fill it with a Skip
+				if currWrite.s.kind == refSkip {
+					currWrite.s.len = curr.end() - currWrite.s.id.clock
+				} else {
+					writer.write(currWrite.s, currWrite.offset)
+					diff := curr.id.clock - currWrite.s.end()
+					currWrite = &pendingWrite{s: &structRef{kind: refSkip, id: id{firstClient, currWrite.s.end()}, len: diff}}
+				}
+			} else {
+				diff := currWrite.s.end() - curr.id.clock
+				if diff > 0 {
+					if currWrite.s.kind == refSkip {
+						// Prefer slicing the Skip; the other struct carries more information
+						currWrite.s.len -= diff
+					} else {
+						curr = curr.slice(diff)
+					}
+				}
+				if !currWrite.s.mergeWith(curr) {
+					writer.write(currWrite.s, currWrite.offset)
+					currWrite = &pendingWrite{s: curr}
+					currReader.next()
+				}
+			}
+		} else {
+			currWrite = &pendingWrite{s: currReader.curr}
+			currReader.next()
+		}
+
+		for next := currReader.curr; next != nil && next.id.client == firstClient &&
+			next.id.clock == currWrite.s.end() && next.kind != refSkip; next = currReader.next() {
+			writer.write(currWrite.s, currWrite.offset)
+			currWrite = &pendingWrite{s: next}
+		}
+	}
+	if currWrite != nil {
+		writer.write(currWrite.s, currWrite.offset)
+	}
+
+	out := &encoder{}
+	writer.finish(out)
+	writeDeleteSet(out, mergeDeleteSets(decoded))
+	return out.buf, nil
+}
+
+// mergeDeleteSets combines, sorts and coalesces the delete sets of all updates.
+func mergeDeleteSets(updates []*update) map[uint64][]deleteRange {
+	merged := map[uint64][]deleteRange{}
+	for _, u := range updates {
+		for client, dels := range u.deletes {
+			merged[client] = append(merged[client], dels...)
+		}
+	}
+	for client, dels := range merged {
+		sort.SliceStable(dels, func(i, j int) bool { return dels[i].clock < dels[j].clock })
+		j := 1
+		for i := 1; i < len(dels); i++ {
+			left, right := &dels[j-1], dels[i]
+			if left.clock+left.len >= right.clock {
+				if end := right.clock + right.len; end > left.clock+left.len {
+					left.len = end - left.clock
+				}
+			} else {
+				dels[j] = right
+				j++
+			}
+		}
+		if len(dels) > 0 {
+			merged[client] = dels[:j]
+		}
+	}
+	return merged
+}
+
+func writeDeleteSet(e *encoder, ds map[uint64][]deleteRange) {
+	clients := make([]uint64, 0, len(ds))
+	for client := range ds {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i] > clients[j] })
+
+	e.writeVarUint(uint64(len(clients)))
+	for _, client := range clients {
+		e.writeVarUint(client)
+		e.writeVarUint(uint64(len(ds[client])))
+		for _, r := range ds[client] {
+			e.writeVarUint(r.clock)
+			e.writeVarUint(r.len)
+		}
+	}
+}
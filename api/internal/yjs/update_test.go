@@ -0,0 +1,199 @@
+package yjs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// testStruct describes a string insertion or a skip in a hand-built update.
+type testStruct struct {
+	text   string // inserted into root type "t" unless skip > 0
+	skip   uint64
+	origin *id
+}
+
+type testClient struct {
+	client  uint64
+	clock   uint64
+	structs []testStruct
+}
+
+type testDelete struct {
+	client uint64
+	ranges []deleteRange
+}
+
+// buildUpdate encodes a v1 update the way Y.encodeStateAsUpdate would.
+func buildUpdate(clients []testClient, deletes []testDelete) []byte {
+	e := &encoder{}
+	e.writeVarUint(uint64(len(clients)))
+	for _, c := range clients {
+		e.writeVarUint(uint64(len(c.structs)))
+		e.writeVarUint(c.client)
+		e.writeVarUint(c.clock)
+		for _, s := range c.structs {
+			if s.skip > 0 {
+				e.writeUint8(refSkip)
+				e.writeVarUint(s.skip)
+				continue
+			}
+			if s.origin != nil {
+				e.writeUint8(refString | infoHasOrigin)
+				e.writeVarUint(s.origin.client)
+				e.writeVarUint(s.origin.clock)
+			} else {
+				e.writeUint8(refString)
+				e.writeVarUint(1)
+				e.writeVarString("t")
+			}
+			e.writeVarString(s.text)
+		}
+	}
+	e.writeVarUint(uint64(len(deletes)))
+	for _, d := range deletes {
+		e.writeVarUint(d.client)
+		e.writeVarUint(uint64(len(d.ranges)))
+		for _, r := range d.ranges {
+			e.writeVarUint(r.clock)
+			e.writeVarUint(r.len)
+		}
+	}
+	return e.buf
+}
+
+func mustMerge(t *testing.T, updates ...[]byte) []byte {
+	t.Helper()
+	merged, err := MergeUpdates(updates)
+	if err != nil {
+		t.Fatalf("MergeUpdates failed: %v", err)
+	}
+	return merged
+}
+
+func TestMergeUpdatesSequential(t *testing.T) {
+	first := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: "abc"}}}}, nil)
+	second := buildUpdate([]testClient{{client: 1, clock: 3, structs: []testStruct{{text: "de", origin: &id{1, 2}}}}}, nil)
+	want := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{
+		{text: "abc"},
+		{text: "de", origin: &id{1, 2}},
+	}}}, nil)
+
+	merged := mustMerge(t, first, second)
+	if !bytes.Equal(merged, want) {
+		t.Fatalf("Unexpected merge result:\n got %v\nwant %v", merged, want)
+	}
+
+	t.Run("order does not matter", func(t *testing.T) {
+		if got := mustMerge(t, second, first); !bytes.Equal(got, want) {
+			t.Errorf("Expected same result in reverse order, got %v", got)
+		}
+	})
+
+	t.Run("duplicates are dropped", func(t *testing.T) {
+		if got := mustMerge(t, merged, second, first); !bytes.Equal(got, want) {
+			t.Errorf("Expected idempotent merge, got %v", got)
+		}
+	})
+
+	t.Run("single update is returned as-is", func(t *testing.T) {
+		if got := mustMerge(t, first); !bytes.Equal(got, first) {
+			t.Errorf("Expected input back, got %v", got)
+		}
+	})
+}
+
+func TestMergeUpdatesMultipleClients(t *testing.T) {
+	a := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: "ab"}}}}, nil)
+	b := buildUpdate([]testClient{{client: 7, clock: 0, structs: []testStruct{{text: "x", origin: &id{1, 1}}}}}, nil)
+	want := buildUpdate([]testClient{
+		{client: 7, clock: 0, structs: []testStruct{{text: "x", origin: &id{1, 1}}}},
+		{client: 1, clock: 0, structs: []testStruct{{text: "ab"}}},
+	}, nil)
+
+	if got := mustMerge(t, a, b); !bytes.Equal(got, want) {
+		t.Errorf("Expected clients in descending order:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMergeUpdatesOverlap(t *testing.T) {
+	tests := []struct {
+		name  string
+		short string
+		long  string
+		tail  string
+	}{
+		{"ascii", "abc", "abcde", "de"},
+		{"multibyte", "ñan", "ñandú", "dú"},
+		{"surrogate pair", "a😀", "a😀b", "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			short := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: tt.short}}}}, nil)
+			long := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: tt.long}}}}, nil)
+			shortLen := uint64(len(utf16.Encode([]rune(tt.short))))
+			want := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{
+				{text: tt.short},
+				{text: tt.tail, origin: &id{1, shortLen - 1}},
+			}}}, nil)
+
+			if got := mustMerge(t, short, long); !bytes.Equal(got, want) {
+				t.Errorf("Expected overlapping struct to be sliced:\n got %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergeUpdatesGap(t *testing.T) {
+	a := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: "ab"}}}}, nil)
+	b := buildUpdate([]testClient{{client: 1, clock: 5, structs: []testStruct{{text: "x", origin: &id{1, 4}}}}}, nil)
+	want := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{
+		{text: "ab"},
+		{skip: 3},
+		{text: "x", origin: &id{1, 4}},
+	}}}, nil)
+
+	merged := mustMerge(t, a, b)
+	if !bytes.Equal(merged, want) {
+		t.Fatalf("Expected a skip over the missing clocks:\n got %v\nwant %v", merged, want)
+	}
+
+	// Filling the gap later replaces the skip
+	fill := buildUpdate([]testClient{{client: 1, clock: 2, structs: []testStruct{{text: "cde", origin: &id{1, 1}}}}}, nil)
+	want = buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{
+		{text: "ab"},
+		{text: "cde", origin: &id{1, 1}},
+		{text: "x", origin: &id{1, 4}},
+	}}}, nil)
+	if got := mustMerge(t, merged, fill); !bytes.Equal(got, want) {
+		t.Errorf("Expected gap to be filled:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMergeUpdatesDeleteSets(t *testing.T) {
+	a := buildUpdate(nil, []testDelete{{client: 1, ranges: []deleteRange{{0, 2}}}})
+	b := buildUpdate(nil, []testDelete{
+		{client: 1, ranges: []deleteRange{{1, 3}, {8, 1}}},
+		{client: 2, ranges: []deleteRange{{0, 1}}},
+	})
+	want := buildUpdate(nil, []testDelete{
+		{client: 2, ranges: []deleteRange{{0, 1}}},
+		{client: 1, ranges: []deleteRange{{0, 4}, {8, 1}}},
+	})
+
+	if got := mustMerge(t, a, b); !bytes.Equal(got, want) {
+		t.Errorf("Expected merged delete set:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMergeUpdatesInvalid(t *testing.T) {
+	valid := buildUpdate([]testClient{{client: 1, clock: 0, structs: []testStruct{{text: "abc"}}}}, nil)
+
+	if _, err := MergeUpdates([][]byte{valid, valid[:len(valid)-2]}); !errors.Is(err, ErrUnexpectedEOF) {
+		t.Errorf("Expected ErrUnexpectedEOF for truncated update, got %v", err)
+	}
+	if _, err := MergeUpdates([][]byte{valid, {1, 1, 1, 0, 31}}); err == nil {
+		t.Error("Expected error for unknown content type")
+	}
+}
@@ -9,6 +9,7 @@ import Login from './routes/Login'
 import Signup from './routes/Signup'
 import ForgotPassword from './routes/ForgotPassword'
 import ResetPassword from './routes/ResetPassword'
+import UnlockAccount from './routes/UnlockAccount'
 import Dashboard from './routes/Dashboard'
 import OAuthCallback from './routes/OAuthCallback'
 
@@ -73,6 +74,7 @@ function AppRoutes() {
         <Route path="/signup" element={<Signup />} />
         <Route path="/forgot-password" element={<ForgotPassword />} />
         <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/unlock-account" element={<UnlockAccount />} />
         <Route path="/oauth/callback" element={<OAuthCallback />} />
         <Route path="/accept-invite" element={<AcceptTeamInvite />} />
 
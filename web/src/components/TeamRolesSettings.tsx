@@ -0,0 +1,179 @@
+import { useState, useEffect, FormEvent } from 'react'
+import Card from './ui/Card'
+import Button from './ui/Button'
+import TextInput from './ui/TextInput'
+import FormError from './ui/FormError'
+import { apiClient, type TeamRoles, type ProjectRole, type Permission } from '../lib/api'
+
+interface RoleForm {
+  id?: number
+  name: string
+  description: string
+  permissions: Permission[]
+}
+
+const emptyForm: RoleForm = { name: '', description: '', permissions: [] }
+
+// TeamRolesSettings lists the roles project members can be given and lets
+// team owners and admins define custom ones.
+export default function TeamRolesSettings() {
+  const [roles, setRoles] = useState<TeamRoles | null>(null)
+  const [form, setForm] = useState<RoleForm | null>(null)
+  const [error, setError] = useState('')
+  const [success, setSuccess] = useState('')
+  const [isWorking, setIsWorking] = useState(false)
+
+  useEffect(() => {
+    apiClient.getTeamRoles().then(setRoles).catch(() => setRoles(null))
+  }, [])
+
+  if (!roles) {
+    return null
+  }
+
+  const run = async (action: () => Promise<void>, failure: string) => {
+    setError('')
+    setSuccess('')
+    setIsWorking(true)
+    try {
+      await action()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : failure)
+    } finally {
+      setIsWorking(false)
+    }
+  }
+
+  const describe = (name?: Permission) =>
+    (roles.permissions || []).find((p) => p.name === name)?.description || name
+
+  const togglePermission = (name: Permission) => {
+    if (!form) return
+    const permissions = form.permissions.includes(name)
+      ? form.permissions.filter((p) => p !== name)
+      : [...form.permissions, name]
+    setForm({ ...form, permissions })
+  }
+
+  const handleSave = (e: FormEvent) => {
+    e.preventDefault()
+    if (!form) return
+    const data = { name: form.name.trim(), description: form.description.trim(), permissions: form.permissions }
+    run(async () => {
+      const saved = form.id ? await apiClient.updateTeamRole(form.id, data) : await apiClient.createTeamRole(data)
+      setRoles(await apiClient.getTeamRoles())
+      setForm(null)
+      setSuccess(`Saved ${saved.name}`)
+    }, 'Failed to save role')
+  }
+
+  const handleDelete = (role: ProjectRole) => {
+    if (!confirm(`Delete the ${role.name} role?`)) {
+      return
+    }
+    run(async () => {
+      await apiClient.deleteTeamRole(role.id!)
+      setRoles(await apiClient.getTeamRoles())
+      setSuccess(`Deleted ${role.name}`)
+    }, 'Failed to delete role')
+  }
+
+  const editRole = (role: ProjectRole) =>
+    setForm({
+      id: role.id,
+      name: role.name || '',
+      description: role.description || '',
+      permissions: role.permissions || [],
+    })
+
+  return (
+    <Card className="shadow-md">
+      <div className="p-6 sm:p-8">
+        <h2 className="text-xl font-semibold text-dark-text-primary mb-1">Project Roles</h2>
+        <p className="text-sm text-dark-text-secondary mb-6">
+          Roles decide what project members can do. Every role can view its projects.
+        </p>
+
+        {success && (
+          <div className="mb-4 p-4 bg-success-500/10 border-l-4 border-success-400 rounded-r-lg">
+            <span className="text-success-300 font-medium">{success}</span>
+          </div>
+        )}
+        {error && <FormError message={error} className="mb-4" />}
+
+        <div className="space-y-3 mb-6">
+          {(roles.roles || []).map((role) => (
+            <div key={role.id || role.name} className="p-4 border border-dark-border-subtle rounded-lg">
+              <div className="flex items-center justify-between gap-3">
+                <div>
+                  <p className="text-sm font-medium text-dark-text-primary">
+                    {role.name}
+                    {role.built_in && <span className="ml-2 text-xs text-dark-text-tertiary">Built-in</span>}
+                  </p>
+                  {role.description && <p className="text-xs text-dark-text-tertiary">{role.description}</p>}
+                </div>
+                {!role.built_in && (
+                  <div className="flex gap-2">
+                    <Button type="button" size="sm" variant="secondary" onClick={() => editRole(role)} disabled={isWorking}>
+                      Edit
+                    </Button>
+                    <Button type="button" size="sm" variant="danger" onClick={() => handleDelete(role)} disabled={isWorking}>
+                      Delete
+                    </Button>
+                  </div>
+                )}
+              </div>
+              <p className="text-xs text-dark-text-secondary mt-2">
+                {(role.permissions || []).length === 0
+                  ? 'View only'
+                  : (role.permissions || []).map((p) => describe(p)).join(' · ')}
+              </p>
+            </div>
+          ))}
+        </div>
+
+        {form ? (
+          <form onSubmit={handleSave} className="p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg space-y-4">
+            <TextInput
+              label="Name"
+              value={form.name}
+              onChange={(e) => setForm({ ...form, name: e.target.value })}
+              maxLength={50}
+              required
+            />
+            <TextInput
+              label="Description"
+              value={form.description}
+              onChange={(e) => setForm({ ...form, description: e.target.value })}
+            />
+            <fieldset className="space-y-2">
+              <legend className="text-xs font-medium text-dark-text-secondary mb-1">Permissions</legend>
+              {(roles.permissions || []).map((p) => (
+                <label key={p.name} className="flex items-center gap-2 text-sm text-dark-text-primary">
+                  <input
+                    type="checkbox"
+                    checked={form.permissions.includes(p.name!)}
+                    onChange={() => togglePermission(p.name!)}
+                  />
+                  {p.description}
+                </label>
+              ))}
+            </fieldset>
+            <div className="flex gap-3">
+              <Button type="submit" disabled={isWorking || !form.name.trim()}>
+                {form.id ? 'Save role' : 'Create role'}
+              </Button>
+              <Button type="button" variant="secondary" onClick={() => setForm(null)} disabled={isWorking}>
+                Cancel
+              </Button>
+            </div>
+          </form>
+        ) : (
+          <Button type="button" onClick={() => setForm(emptyForm)} disabled={isWorking}>
+            New role
+          </Button>
+        )}
+      </div>
+    </Card>
+  )
+}
@@ -0,0 +1,215 @@
+import { useState, useEffect } from 'react'
+import Card from './ui/Card'
+import Button from './ui/Button'
+import FormError from './ui/FormError'
+import { apiClient, type TeamSCIM, type SCIMGroupMapping, type SCIMGroupMappingRequest, type Project } from '../lib/api'
+
+type ProjectRole = 'viewer' | 'member' | 'editor'
+
+const selectClass =
+  'px-2 py-1.5 border border-dark-border-subtle rounded-md bg-dark-bg-secondary text-dark-text-primary text-xs focus:outline-none focus:ring-1 focus:ring-dark-border-strong'
+
+// TeamSCIMSettings lets the team owner connect an identity provider's SCIM
+// provisioning and decide what its groups grant. It renders nothing for
+// other members, who can't read the settings.
+export default function TeamSCIMSettings() {
+  const [setup, setSetup] = useState<TeamSCIM | null>(null)
+  const [projects, setProjects] = useState<Project[]>([])
+  const [newToken, setNewToken] = useState('')
+  const [error, setError] = useState('')
+  const [success, setSuccess] = useState('')
+  const [isWorking, setIsWorking] = useState(false)
+
+  useEffect(() => {
+    apiClient.getTeamSCIM().then(setSetup).catch(() => setSetup(null))
+    apiClient.getProjects().then(setProjects).catch(() => setProjects([]))
+  }, [])
+
+  if (!setup) {
+    return null
+  }
+
+  const run = async (action: () => Promise<void>, failure: string) => {
+    setError('')
+    setSuccess('')
+    setIsWorking(true)
+    try {
+      await action()
+    } catch (err) {
+      setError(err instanceof Error ? err.message : failure)
+    } finally {
+      setIsWorking(false)
+    }
+  }
+
+  const handleCreateToken = () => {
+    if (setup.token && !confirm('Generate a new token? The identity provider stops syncing until you give it the new one.')) {
+      return
+    }
+    run(async () => {
+      const created = await apiClient.createTeamSCIMToken()
+      setNewToken(created.token || '')
+      setSetup(await apiClient.getTeamSCIM())
+    }, 'Failed to create provisioning token')
+  }
+
+  const handleRevokeToken = () => {
+    if (!confirm('Revoke the provisioning token? Provisioned users and groups are kept.')) {
+      return
+    }
+    run(async () => {
+      await apiClient.deleteTeamSCIMToken()
+      setNewToken('')
+      setSetup(await apiClient.getTeamSCIM())
+      setSuccess('Provisioning token revoked')
+    }, 'Failed to revoke provisioning token')
+  }
+
+  const saveMapping = (group: SCIMGroupMapping, data: SCIMGroupMappingRequest) => {
+    run(async () => {
+      const saved = await apiClient.updateSCIMGroupMapping(group.id!, data)
+      setSetup({ ...setup, groups: (setup.groups || []).map((g) => (g.id === saved.id ? saved : g)) })
+      setSuccess(`Updated ${saved.display_name}`)
+    }, 'Failed to update group')
+  }
+
+  const mappingOf = (group: SCIMGroupMapping): SCIMGroupMappingRequest => ({
+    team_role: group.team_role || 'member',
+    projects: (group.projects || []).map((p) => ({ project_id: p.project_id, role: p.role })),
+  })
+
+  return (
+    <Card className="shadow-md">
+      <div className="p-6 sm:p-8">
+        <h2 className="text-xl font-semibold text-dark-text-primary mb-1">User Provisioning (SCIM)</h2>
+        <p className="text-sm text-dark-text-secondary mb-6">
+          Let your identity provider add, update and deactivate team members, and grant access through its groups.
+        </p>
+
+        {success && (
+          <div className="mb-4 p-4 bg-success-500/10 border-l-4 border-success-400 rounded-r-lg">
+            <span className="text-success-300 font-medium">{success}</span>
+          </div>
+        )}
+        {error && <FormError message={error} className="mb-4" />}
+
+        <div className="p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg space-y-2 mb-4">
+          <p className="text-xs font-medium text-dark-text-secondary">SCIM base URL</p>
+          <code className="text-xs text-dark-text-primary break-all">{setup.base_url}</code>
+          {newToken && (
+            <>
+              <p className="text-xs font-medium text-dark-text-secondary pt-2">Token (shown only once)</p>
+              <code className="text-xs text-dark-text-primary break-all">{newToken}</code>
+            </>
+          )}
+          {!newToken && setup.token && (
+            <p className="text-xs text-dark-text-tertiary pt-2">
+              Token {setup.token.token_prefix}… created {new Date(setup.token.created_at!).toLocaleDateString()}
+              {setup.token.last_used_at ? `, last used ${new Date(setup.token.last_used_at).toLocaleString()}` : ', never used'}
+            </p>
+          )}
+        </div>
+
+        <div className="flex gap-3 mb-6">
+          <Button type="button" onClick={handleCreateToken} disabled={isWorking}>
+            {setup.token ? 'Regenerate token' : 'Generate token'}
+          </Button>
+          {setup.token && (
+            <Button type="button" variant="danger" onClick={handleRevokeToken} disabled={isWorking}>
+              Revoke
+            </Button>
+          )}
+        </div>
+
+        <h3 className="text-sm font-semibold text-dark-text-primary mb-2">Groups</h3>
+        {(setup.groups || []).length === 0 ? (
+          <p className="text-sm text-dark-text-tertiary">
+            No groups yet. Groups your identity provider pushes show up here.
+          </p>
+        ) : (
+          <div className="space-y-3">
+            {(setup.groups || []).map((group) => {
+              const mapping = mappingOf(group)
+              const unmapped = projects.filter((p) => !mapping.projects!.some((m) => m.project_id === p.id))
+              return (
+                <div key={group.id} className="p-4 border border-dark-border-subtle rounded-lg space-y-3">
+                  <div className="flex items-center justify-between gap-3">
+                    <div>
+                      <p className="text-sm font-medium text-dark-text-primary">{group.display_name}</p>
+                      <p className="text-xs text-dark-text-tertiary">
+                        {(group.members || []).length} member{(group.members || []).length === 1 ? '' : 's'}
+                      </p>
+                    </div>
+                    <select
+                      aria-label={`Team role for ${group.display_name}`}
+                      className={selectClass}
+                      value={mapping.team_role}
+                      disabled={isWorking}
+                      onChange={(e) => saveMapping(group, { ...mapping, team_role: e.target.value as 'member' | 'admin' })}
+                    >
+                      <option value="member">Team member</option>
+                      <option value="admin">Team admin</option>
+                    </select>
+                  </div>
+
+                  {(group.projects || []).map((p) => (
+                    <div key={p.project_id} className="flex items-center justify-between gap-3">
+                      <span className="text-xs text-dark-text-secondary">{p.project_name}</span>
+                      <div className="flex items-center gap-2">
+                        <select
+                          aria-label={`Role in ${p.project_name}`}
+                          className={selectClass}
+                          value={p.role}
+                          disabled={isWorking}
+                          onChange={(e) => saveMapping(group, {
+                            ...mapping,
+                            projects: mapping.projects!.map((m) =>
+                              m.project_id === p.project_id ? { ...m, role: e.target.value as ProjectRole } : m),
+                          })}
+                        >
+                          <option value="viewer">Viewer</option>
+                          <option value="member">Member</option>
+                          <option value="editor">Editor</option>
+                        </select>
+                        <Button
+                          type="button"
+                          size="sm"
+                          variant="secondary"
+                          disabled={isWorking}
+                          onClick={() => saveMapping(group, {
+                            ...mapping,
+                            projects: mapping.projects!.filter((m) => m.project_id !== p.project_id),
+                          })}
+                        >
+                          Remove
+                        </Button>
+                      </div>
+                    </div>
+                  ))}
+
+                  {unmapped.length > 0 && (
+                    <select
+                      aria-label={`Add a project to ${group.display_name}`}
+                      className={selectClass}
+                      value=""
+                      disabled={isWorking}
+                      onChange={(e) => saveMapping(group, {
+                        ...mapping,
+                        projects: [...mapping.projects!, { project_id: Number(e.target.value), role: 'member' }],
+                      })}
+                    >
+                      <option value="">Grant access to a project…</option>
+                      {unmapped.map((p) => (
+                        <option key={p.id} value={p.id}>{p.name}</option>
+                      ))}
+                    </select>
+                  )}
+                </div>
+              )
+            })}
+          </div>
+        )}
+      </div>
+    </Card>
+  )
+}
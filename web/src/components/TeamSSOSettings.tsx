@@ -0,0 +1,247 @@
+import { useState, useEffect, FormEvent } from 'react'
+import Card from './ui/Card'
+import Button from './ui/Button'
+import TextInput from './ui/TextInput'
+import FormError from './ui/FormError'
+import { apiClient, type TeamSSOConfig } from '../lib/api'
+
+type Protocol = 'oidc' | 'saml'
+
+// TeamSSOSettings lets the team owner connect an identity provider. It
+// renders nothing for other members, who can't read the settings.
+export default function TeamSSOSettings() {
+  const [config, setConfig] = useState<TeamSSOConfig | null>(null)
+  const [protocol, setProtocol] = useState<Protocol>('oidc')
+  const [enabled, setEnabled] = useState(false)
+  const [enforced, setEnforced] = useState(false)
+  const [domains, setDomains] = useState('')
+  const [issuer, setIssuer] = useState('')
+  const [clientID, setClientID] = useState('')
+  const [clientSecret, setClientSecret] = useState('')
+  const [metadata, setMetadata] = useState('')
+  const [error, setError] = useState('')
+  const [success, setSuccess] = useState('')
+  const [isSaving, setIsSaving] = useState(false)
+  const [isRemoving, setIsRemoving] = useState(false)
+  const [verifying, setVerifying] = useState('')
+
+  const applyConfig = (c: TeamSSOConfig) => {
+    setConfig(c)
+    setProtocol(c.protocol === 'saml' ? 'saml' : 'oidc')
+    setEnabled(!!c.enabled)
+    setEnforced(!!c.enforced)
+    setDomains((c.email_domains || []).map((d) => d.domain).join(', '))
+    setIssuer(c.oidc_issuer || '')
+    setClientID(c.oidc_client_id || '')
+    setClientSecret('')
+    setMetadata(c.saml_metadata || '')
+  }
+
+  useEffect(() => {
+    apiClient.getTeamSSO().then(applyConfig).catch(() => setConfig(null))
+  }, [])
+
+  if (!config) {
+    return null
+  }
+
+  const handleSave = async (e: FormEvent) => {
+    e.preventDefault()
+    setError('')
+    setSuccess('')
+    setIsSaving(true)
+    try {
+      const saved = await apiClient.updateTeamSSO({
+        protocol,
+        enabled,
+        enforced: enabled && enforced,
+        email_domains: domains.split(/[\s,]+/).filter(Boolean),
+        oidc_issuer: issuer.trim(),
+        oidc_client_id: clientID.trim(),
+        oidc_client_secret: clientSecret,
+        saml_metadata: metadata,
+      })
+      applyConfig(saved)
+      setSuccess('Single sign-on settings saved')
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to save single sign-on settings')
+    } finally {
+      setIsSaving(false)
+    }
+  }
+
+  const handleRemove = async () => {
+    if (!confirm('Remove single sign-on? Members will need to reset their password to sign in.')) {
+      return
+    }
+    setError('')
+    setSuccess('')
+    setIsRemoving(true)
+    try {
+      await apiClient.deleteTeamSSO()
+      applyConfig(await apiClient.getTeamSSO())
+      setSuccess('Single sign-on removed')
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to remove single sign-on')
+    } finally {
+      setIsRemoving(false)
+    }
+  }
+
+  const handleVerify = async (domain: string) => {
+    setError('')
+    setSuccess('')
+    setVerifying(domain)
+    try {
+      applyConfig(await apiClient.verifyTeamSSODomain(domain))
+      setSuccess(`${domain} verified`)
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to verify domain')
+    } finally {
+      setVerifying('')
+    }
+  }
+
+  const urls: [string, string | undefined][] = protocol === 'oidc'
+    ? [['Redirect URI', config.oidc_redirect_url]]
+    : [['Entity ID / metadata URL', config.saml_entity_id], ['Assertion consumer service URL', config.saml_acs_url]]
+
+  return (
+    <Card className="shadow-md">
+      <div className="p-6 sm:p-8">
+        <h2 className="text-xl font-semibold text-dark-text-primary mb-1">Single Sign-On</h2>
+        <p className="text-sm text-dark-text-secondary mb-6">
+          Let members sign in with your identity provider. New users from your verified email domains join the team automatically.
+        </p>
+
+        {success && (
+          <div className="mb-4 p-4 bg-success-500/10 border-l-4 border-success-400 rounded-r-lg">
+            <span className="text-success-300 font-medium">{success}</span>
+          </div>
+        )}
+        {error && <FormError message={error} className="mb-4" />}
+
+        <form onSubmit={handleSave} className="space-y-4">
+          <div className="flex gap-2">
+            {(['oidc', 'saml'] as Protocol[]).map((p) => (
+              <Button
+                key={p}
+                type="button"
+                size="sm"
+                variant={protocol === p ? 'primary' : 'secondary'}
+                onClick={() => setProtocol(p)}
+              >
+                {p === 'oidc' ? 'OpenID Connect' : 'SAML 2.0'}
+              </Button>
+            ))}
+          </div>
+
+          <div className="p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg space-y-2">
+            <p className="text-xs text-dark-text-tertiary">Register these with your identity provider:</p>
+            {urls.map(([label, url]) => (
+              <div key={label}>
+                <p className="text-xs font-medium text-dark-text-secondary">{label}</p>
+                <code className="text-xs text-dark-text-primary break-all">{url}</code>
+              </div>
+            ))}
+          </div>
+
+          <TextInput
+            id="sso-domains"
+            label="Email domains"
+            value={domains}
+            onChange={(e) => setDomains(e.target.value)}
+            placeholder="example.com"
+            helpText="Comma separated. Only addresses in verified domains can sign in, and the login page finds your identity provider by them."
+          />
+
+          {(config.email_domains || []).length > 0 && (
+            <div className="space-y-2">
+              {(config.email_domains || []).map((d) => (
+                <div key={d.domain} className="flex items-center justify-between gap-3 p-3 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg">
+                  <div className="min-w-0">
+                    <p className="text-sm text-dark-text-primary">{d.domain}</p>
+                    {d.verified_at ? (
+                      <p className="text-xs text-success-300">Verified</p>
+                    ) : (
+                      <p className="text-xs text-dark-text-tertiary">
+                        Add this TXT record to the domain's DNS, then verify:{' '}
+                        <code className="text-dark-text-primary break-all">{d.verification_record}</code>
+                      </p>
+                    )}
+                  </div>
+                  {!d.verified_at && d.domain && (
+                    <Button type="button" size="sm" variant="secondary" onClick={() => handleVerify(d.domain!)} disabled={verifying !== ''}>
+                      {verifying === d.domain ? 'Verifying...' : 'Verify'}
+                    </Button>
+                  )}
+                </div>
+              ))}
+            </div>
+          )}
+
+          {protocol === 'oidc' ? (
+            <>
+              <TextInput
+                id="sso-issuer"
+                label="Issuer URL"
+                value={issuer}
+                onChange={(e) => setIssuer(e.target.value)}
+                placeholder="https://accounts.example.com"
+              />
+              <TextInput
+                id="sso-client-id"
+                label="Client ID"
+                value={clientID}
+                onChange={(e) => setClientID(e.target.value)}
+              />
+              <TextInput
+                id="sso-client-secret"
+                label="Client secret"
+                type="password"
+                autoComplete="off"
+                value={clientSecret}
+                onChange={(e) => setClientSecret(e.target.value)}
+                placeholder={config.has_client_secret ? '••••••••' : ''}
+                helpText={config.has_client_secret ? 'Leave empty to keep the current secret' : undefined}
+              />
+            </>
+          ) : (
+            <div>
+              <label htmlFor="sso-metadata" className="block text-xs font-medium text-dark-text-tertiary mb-1.5">
+                Identity provider metadata XML
+              </label>
+              <textarea
+                id="sso-metadata"
+                value={metadata}
+                onChange={(e) => setMetadata(e.target.value)}
+                rows={6}
+                className="block w-full px-3 py-2 border border-dark-border-subtle rounded-md bg-dark-bg-secondary text-dark-text-primary text-xs font-mono focus:outline-none focus:ring-1 focus:ring-dark-border-strong"
+              />
+            </div>
+          )}
+
+          <label className="flex items-center gap-2 text-sm text-dark-text-secondary">
+            <input type="checkbox" checked={enabled} onChange={(e) => setEnabled(e.target.checked)} />
+            Enable single sign-on
+          </label>
+          <label className="flex items-center gap-2 text-sm text-dark-text-secondary">
+            <input type="checkbox" checked={enforced} disabled={!enabled} onChange={(e) => setEnforced(e.target.checked)} />
+            Require single sign-on for members who sign in with it, except the owner
+          </label>
+
+          <div className="flex gap-3">
+            <Button type="submit" disabled={isSaving}>
+              {isSaving ? 'Saving...' : 'Save'}
+            </Button>
+            {config.protocol && (
+              <Button type="button" variant="danger" onClick={handleRemove} disabled={isRemoving}>
+                {isRemoving ? 'Removing...' : 'Remove'}
+              </Button>
+            )}
+          </div>
+        </form>
+      </div>
+    </Card>
+  )
+}
@@ -874,6 +874,19 @@ describe('ApiClient', () => {
       await expect(apiClient.getAssets(1)).rejects.toThrow('Not authorized')
     })
 
+    it('refreshes the session and retries once on 401', async () => {
+      mockErrorResponse('invalid or expired token', 401)
+      mockResponse({ token: 'fresh-token', refresh_token: 'next-refresh', expires_in: 900 })
+      mockResponse([])
+
+      await apiClient.getAssets(1)
+
+      expect(mockFetch.mock.calls[1][0]).toContain('/api/auth/refresh')
+      const retry = mockFetch.mock.calls[2][1] as RequestInit
+      expect((retry.headers as Record<string, string>)['Authorization']).toBe('Bearer fresh-token')
+      expect(localStorageMock.setItem).toHaveBeenCalledWith('refresh_token', 'next-refresh')
+    })
+
     it('throws generic error for 500 errors', async () => {
       mockFetch.mockResolvedValueOnce({
         ok: false,
@@ -9,6 +9,74 @@ export type LoginRequest = components['schemas']['LoginRequest']
 export type Project = components['schemas']['Project']
 export type Task = components['schemas']['Task'] & { task_number?: number; github_issue_number?: number | null; github_repo?: string; start_date?: string | null; github_reactions?: GitHubReaction[]; agent_name?: string | null }
 export type ApiError = components['schemas']['Error']
+export type Session = components['schemas']['Session']
+export type RevokeSessionsResponse = components['schemas']['RevokeSessionsResponse']
+export type PasswordChangedResponse = components['schemas']['PasswordChangedResponse']
+export type AccountLockout = components['schemas']['AccountLockout']
+export type SecondFactorChallenge = components['schemas']['SecondFactorChallengeResponse']
+export type SecondFactorRequest = components['schemas']['SecondFactorRequest']
+export type WebAuthnCredential = components['schemas']['WebAuthnCredential']
+export type WebAuthnCreationOptions = components['schemas']['WebAuthnCreationOptions']
+export type WebAuthnRequestOptions = components['schemas']['WebAuthnRequestOptions']
+export type WebAuthnRegistration = components['schemas']['WebAuthnRegistration']
+export type WebAuthnAssertion = components['schemas']['WebAuthnAssertion']
+export type TeamSSOConfig = components['schemas']['TeamSSOConfig']
+export type TeamSSORequest = components['schemas']['TeamSSORequest']
+export type SSODiscovery = components['schemas']['SSODiscovery']
+export type TeamSCIM = components['schemas']['TeamSCIM']
+export type SCIMTokenWithSecret = components['schemas']['SCIMTokenWithSecret']
+export type SCIMGroupMapping = components['schemas']['SCIMGroupMapping']
+export type SCIMGroupMappingRequest = components['schemas']['SCIMGroupMappingRequest']
+export type AuditEntry = components['schemas']['AuditEntry']
+export type AuditLogPage = components['schemas']['AuditLogPage']
+export type Permission = components['schemas']['Permission']
+export type PermissionInfo = components['schemas']['PermissionInfo']
+export type ProjectRole = components['schemas']['ProjectRole']
+export type TeamRoles = components['schemas']['TeamRoles']
+export type TaskHistoryEntry = components['schemas']['TaskHistoryEntry']
+export type SprintAnalytics = components['schemas']['SprintAnalytics']
+export type VelocityReport = components['schemas']['VelocityReport']
+export type CompleteSprintResponse = components['schemas']['CompleteSprintResponse']
+export type FlowMetrics = components['schemas']['FlowMetrics']
+export type TimeEntry = components['schemas']['TimeEntry']
+export type CreateTimeEntryRequest = components['schemas']['CreateTimeEntryRequest']
+export type UpdateTimeEntryRequest = components['schemas']['UpdateTimeEntryRequest']
+export type Timesheet = components['schemas']['Timesheet']
+export type CustomField = components['schemas']['CustomField']
+export type CreateCustomFieldRequest = components['schemas']['CreateCustomFieldRequest']
+export type UpdateCustomFieldRequest = components['schemas']['UpdateCustomFieldRequest']
+export type TeamRoleRequest = components['schemas']['TeamRoleRequest']
+export type ProjectPermissions = components['schemas']['ProjectPermissions']
+
+export type AuditLogFilters = {
+  project_id?: number
+  actor_id?: number
+  action?: string
+  resource_type?: string
+  resource_id?: number
+  channel?: 'web' | 'api_key' | 'scim'
+  since?: string
+  until?: string
+}
+
+export type TimesheetFilters = {
+  from?: string
+  to?: string
+  group_by?: 'user' | 'project' | 'sprint' | 'tag'
+  project_id?: number
+  user_id?: number | 'me'
+  sprint_id?: number
+  tag_id?: number
+}
+
+function auditLogQuery(params?: Record<string, string | number | undefined>): string {
+  const q = new URLSearchParams()
+  for (const [key, value] of Object.entries(params ?? {})) {
+    if (value !== undefined && value !== '') q.set(key, String(value))
+  }
+  const qs = q.toString()
+  return qs ? `?${qs}` : ''
+}
 
 export interface GitHubReaction {
   reaction: string
@@ -542,6 +610,8 @@ export interface SwimLane {
   color: string
   position: number
   status_category: 'todo' | 'in_progress' | 'done'
+  wip_limit?: number
+  wip_enforcement: 'soft' | 'hard'
   created_at: string
   updated_at: string
 }
@@ -551,6 +621,8 @@ export interface CreateSwimLaneRequest {
   color: string
   position: number
   status_category: 'todo' | 'in_progress' | 'done'
+  wip_limit?: number
+  wip_enforcement?: 'soft' | 'hard'
 }
 
 export interface UpdateSwimLaneRequest {
@@ -558,6 +630,8 @@ export interface UpdateSwimLaneRequest {
   color?: string
   position?: number
   status_category?: 'todo' | 'in_progress' | 'done'
+  wip_limit?: number
+  wip_enforcement?: 'soft' | 'hard'
 }
 
 // Helper types for API responses (using available operations)
@@ -706,11 +780,14 @@ const API_BASE_URL = import.meta.env.VITE_API_URL || (
 class ApiClient {
   private baseURL: string
   private token: string | null = null
+  private refreshToken: string | null = null
+  private refreshing: Promise<boolean> | null = null
 
   constructor(baseURL: string) {
     this.baseURL = baseURL
-    // Load token from localStorage on initialization
+    // Load tokens from localStorage on initialization
     this.token = localStorage.getItem('auth_token')
+    this.refreshToken = localStorage.getItem('refresh_token')
   }
 
   setToken(token: string | null) {
@@ -726,9 +803,59 @@ class ApiClient {
     return this.token
   }
 
+  setRefreshToken(token: string | null) {
+    this.refreshToken = token
+    if (token) {
+      localStorage.setItem('refresh_token', token)
+    } else {
+      localStorage.removeItem('refresh_token')
+    }
+  }
+
+  private storeSession(response: { token?: string; refresh_token?: string }) {
+    if (response.token) {
+      this.setToken(response.token)
+    }
+    if (response.refresh_token) {
+      this.setRefreshToken(response.refresh_token)
+    }
+  }
+
+  // Exchange the refresh token for new tokens. Each refresh token works once,
+  // so concurrent callers share a single refresh request.
+  private refreshSession(): Promise<boolean> {
+    if (!this.refreshToken) {
+      return Promise.resolve(false)
+    }
+    if (!this.refreshing) {
+      const refreshToken = this.refreshToken
+      this.refreshing = (async () => {
+        try {
+          const response = await fetch(`${this.baseURL}/api/auth/refresh`, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+          })
+          if (!response.ok) {
+            this.setRefreshToken(null)
+            return false
+          }
+          this.storeSession(await response.json())
+          return true
+        } catch {
+          return false
+        } finally {
+          this.refreshing = null
+        }
+      })()
+    }
+    return this.refreshing
+  }
+
   private async request<T>(
     endpoint: string,
-    options: RequestInit = {}
+    options: RequestInit = {},
+    retry = true
   ): Promise<T> {
     const url = `${this.baseURL}${endpoint}`
 
@@ -754,6 +881,11 @@ class ApiClient {
     try {
       const response = await fetch(url, config)
 
+      // The access token expired or was revoked; refresh the session once
+      if (response.status === 401 && retry && !endpoint.startsWith('/api/auth/') && await this.refreshSession()) {
+        return this.request<T>(endpoint, options, false)
+      }
+
       // Handle non-JSON responses (like 204 No Content)
       if (response.status === 204) {
         return {} as T
@@ -796,25 +928,68 @@ class ApiClient {
       method: 'POST',
       body: JSON.stringify(data),
     })
-    if (response.token) {
-      this.setToken(response.token)
-    }
+    this.storeSession(response)
     return response
   }
 
+  // Users with a second factor get a challenge instead of tokens, answered
+  // with verifySecondFactor()
   async login(data: LoginRequest): Promise<LoginResponse> {
     const response = await this.request<LoginResponse>('/api/auth/login', {
       method: 'POST',
       body: JSON.stringify(data),
     })
-    if (response.token) {
-      this.setToken(response.token)
+    if (!('two_factor_required' in response)) {
+      this.storeSession(response)
     }
     return response
   }
 
+  async verifySecondFactor(data: SecondFactorRequest): Promise<AuthResponse> {
+    const response = await this.request<AuthResponse>('/api/auth/2fa/verify', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+    this.storeSession(response)
+    return response
+  }
+
+  async beginPasskeyLogin(): Promise<WebAuthnRequestOptions> {
+    return this.request<WebAuthnRequestOptions>('/api/auth/webauthn/login/begin', { method: 'POST' })
+  }
+
+  async finishPasskeyLogin(challenge: string, credential: WebAuthnAssertion): Promise<AuthResponse> {
+    const response = await this.request<AuthResponse>('/api/auth/webauthn/login/finish', {
+      method: 'POST',
+      body: JSON.stringify({ challenge, credential }),
+    })
+    this.storeSession(response)
+    return response
+  }
+
+  async discoverSSO(email: string): Promise<SSODiscovery> {
+    return this.request<SSODiscovery>(`/api/auth/sso/discover?email=${encodeURIComponent(email)}`)
+  }
+
   logout(): void {
+    if (this.refreshToken) {
+      void this.endSession(this.refreshToken)
+    }
     this.setToken(null)
+    this.setRefreshToken(null)
+  }
+
+  // Best effort: an abandoned session still expires on its own
+  private async endSession(refreshToken: string): Promise<void> {
+    try {
+      await fetch(`${this.baseURL}/api/auth/logout`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      })
+    } catch {
+      // ignore
+    }
   }
 
   async getCurrentUser(): Promise<GetCurrentUserResponse> {
@@ -896,6 +1071,10 @@ class ApiClient {
     return this.request<TaskComment[]>(`/api/tasks/${taskId}/comments`)
   }
 
+  async getTaskHistory(taskId: number): Promise<TaskHistoryEntry[]> {
+    return this.request<TaskHistoryEntry[]>(`/api/tasks/${taskId}/history`)
+  }
+
   async createTaskComment(taskId: number, comment: string): Promise<TaskComment> {
     return this.request<TaskComment>(`/api/tasks/${taskId}/comments`, {
       method: 'POST',
@@ -903,6 +1082,66 @@ class ApiClient {
     })
   }
 
+  // Time tracking endpoints
+  async listTimeEntries(taskId: number): Promise<TimeEntry[]> {
+    return this.request<TimeEntry[]>(`/api/tasks/${taskId}/time-entries`)
+  }
+
+  async createTimeEntry(taskId: number, data: CreateTimeEntryRequest): Promise<TimeEntry> {
+    return this.request<TimeEntry>(`/api/tasks/${taskId}/time-entries`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async updateTimeEntry(entryId: number, data: UpdateTimeEntryRequest): Promise<TimeEntry> {
+    return this.request<TimeEntry>(`/api/time-entries/${entryId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteTimeEntry(entryId: number): Promise<void> {
+    return this.request<void>(`/api/time-entries/${entryId}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async startTimer(taskId: number, note?: string): Promise<TimeEntry> {
+    return this.request<TimeEntry>(`/api/tasks/${taskId}/timer/start`, {
+      method: 'POST',
+      body: JSON.stringify({ note: note ?? '' }),
+    })
+  }
+
+  async getTimer(): Promise<TimeEntry | null> {
+    return this.request<TimeEntry | null>('/api/me/timer')
+  }
+
+  async stopTimer(): Promise<TimeEntry> {
+    return this.request<TimeEntry>('/api/me/timer/stop', {
+      method: 'POST',
+    })
+  }
+
+  async getTimesheet(params?: TimesheetFilters): Promise<Timesheet> {
+    return this.request<Timesheet>(`/api/timesheets${auditLogQuery(params)}`)
+  }
+
+  async exportTimesheet(params?: TimesheetFilters): Promise<void> {
+    const url = `${this.baseURL}/api/timesheets${auditLogQuery({ ...params, format: 'csv' })}`
+    const resp = await fetch(url, {
+      headers: this.token ? { Authorization: `Bearer ${this.token}` } : {},
+    })
+    if (!resp.ok) throw new Error(`Export failed: ${resp.status}`)
+    const blob = await resp.blob()
+    const a = document.createElement('a')
+    a.href = URL.createObjectURL(blob)
+    a.download = 'taskai-timesheet.csv'
+    a.click()
+    URL.revokeObjectURL(a.href)
+  }
+
   async toggleReaction(taskId: number, reaction: string, commentId?: number): Promise<GitHubReaction> {
     return this.request<GitHubReaction>(`/api/tasks/${taskId}/reactions`, {
       method: 'POST',
@@ -915,6 +1154,10 @@ class ApiClient {
     return this.request<ProjectMember[]>(`/api/projects/${projectId}/members`)
   }
 
+  async getProjectPermissions(projectId: number): Promise<ProjectPermissions> {
+    return this.request<ProjectPermissions>(`/api/projects/${projectId}/permissions`)
+  }
+
   async addProjectMember(projectId: number, data: { email: string; role: string }): Promise<MessageResponse> {
     return this.request<MessageResponse>(`/api/projects/${projectId}/members`, {
       method: 'POST',
@@ -1152,6 +1395,16 @@ class ApiClient {
     })
   }
 
+  async getLockedAccounts(): Promise<AccountLockout[]> {
+    return this.request<AccountLockout[]>('/api/admin/users/locked')
+  }
+
+  async adminUnlockUser(userId: number): Promise<{ id: number; unlocked: boolean }> {
+    return this.request<{ id: number; unlocked: boolean }>(`/api/admin/users/${userId}/unlock`, {
+      method: 'POST',
+    })
+  }
+
   async adminGetInvitations(params?: { status?: string; type?: string }): Promise<AdminInvitation[]> {
     const q = new URLSearchParams()
     if (params?.status) q.set('status', params.status)
@@ -1188,12 +1441,21 @@ class ApiClient {
     })
   }
 
+  async unlockAccount(token: string): Promise<{ message: string }> {
+    return this.request<{ message: string }>('/api/auth/unlock', {
+      method: 'POST',
+      body: JSON.stringify({ token }),
+    })
+  }
+
   // Security/Settings endpoints
-  async changePassword(data: { current_password: string; new_password: string }): Promise<{ message: string }> {
-    return this.request<{ message: string }>('/api/settings/password', {
+  async changePassword(data: { current_password: string; new_password: string }): Promise<PasswordChangedResponse> {
+    const response = await this.request<PasswordChangedResponse>('/api/settings/password', {
       method: 'POST',
       body: JSON.stringify(data),
     })
+    this.storeSession(response)
+    return response
   }
 
   async get2FAStatus(): Promise<{ enabled: boolean }> {
@@ -1213,11 +1475,53 @@ class ApiClient {
     })
   }
 
-  async disable2FA(data: { password: string }): Promise<{ message: string }> {
-    return this.request<{ message: string }>('/api/settings/2fa/disable', {
+  async disable2FA(data: { password: string }): Promise<PasswordChangedResponse> {
+    const response = await this.request<PasswordChangedResponse>('/api/settings/2fa/disable', {
       method: 'POST',
       body: JSON.stringify(data),
     })
+    this.storeSession(response)
+    return response
+  }
+
+  async getSessions(): Promise<Session[]> {
+    return this.request<Session[]>('/api/settings/sessions')
+  }
+
+  async revokeSession(id: number): Promise<void> {
+    await this.request<void>(`/api/settings/sessions/${id}`, { method: 'DELETE' })
+  }
+
+  async beginWebAuthnRegistration(): Promise<WebAuthnCreationOptions> {
+    return this.request<WebAuthnCreationOptions>('/api/settings/webauthn/register/begin', { method: 'POST' })
+  }
+
+  async finishWebAuthnRegistration(challenge: string, credential: WebAuthnRegistration, name?: string): Promise<WebAuthnCredential> {
+    return this.request<WebAuthnCredential>('/api/settings/webauthn/register/finish', {
+      method: 'POST',
+      body: JSON.stringify({ challenge, credential, name }),
+    })
+  }
+
+  async getWebAuthnCredentials(): Promise<WebAuthnCredential[]> {
+    return this.request<WebAuthnCredential[]>('/api/settings/webauthn/credentials')
+  }
+
+  async renameWebAuthnCredential(id: number, name: string): Promise<{ id: number; name: string }> {
+    return this.request<{ id: number; name: string }>(`/api/settings/webauthn/credentials/${id}`, {
+      method: 'PATCH',
+      body: JSON.stringify({ name }),
+    })
+  }
+
+  async deleteWebAuthnCredential(id: number): Promise<void> {
+    await this.request<void>(`/api/settings/webauthn/credentials/${id}`, { method: 'DELETE' })
+  }
+
+  async revokeOtherSessions(): Promise<RevokeSessionsResponse> {
+    const response = await this.request<RevokeSessionsResponse>('/api/settings/sessions', { method: 'DELETE' })
+    this.storeSession(response)
+    return response
   }
 
   // Sprint endpoints (project-scoped)
@@ -1245,6 +1549,22 @@ class ApiClient {
     })
   }
 
+  async getSprintAnalytics(id: number): Promise<SprintAnalytics> {
+    return this.request<SprintAnalytics>(`/api/sprints/${id}/analytics`)
+  }
+
+  async getSprintVelocity(projectId: number, count?: number): Promise<VelocityReport> {
+    const query = count ? `?count=${count}` : ''
+    return this.request<VelocityReport>(`/api/projects/${projectId}/sprints/velocity${query}`)
+  }
+
+  async completeSprint(id: number, nextSprintId?: number): Promise<CompleteSprintResponse> {
+    return this.request<CompleteSprintResponse>(`/api/sprints/${id}/complete`, {
+      method: 'POST',
+      body: JSON.stringify(nextSprintId ? { next_sprint_id: nextSprintId } : {}),
+    })
+  }
+
   // Tag endpoints (project-scoped)
   async getTags(projectId: number): Promise<Tag[]> {
     return this.request<Tag[]>(`/api/projects/${projectId}/tags`)
@@ -1388,6 +1708,94 @@ class ApiClient {
     })
   }
 
+  async getTeamSSO(): Promise<TeamSSOConfig> {
+    return this.request<TeamSSOConfig>('/api/team/sso')
+  }
+
+  async updateTeamSSO(data: TeamSSORequest): Promise<TeamSSOConfig> {
+    return this.request<TeamSSOConfig>('/api/team/sso', {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async verifyTeamSSODomain(domain: string): Promise<TeamSSOConfig> {
+    return this.request<TeamSSOConfig>(`/api/team/sso/domains/${encodeURIComponent(domain)}/verify`, {
+      method: 'POST',
+    })
+  }
+
+  async deleteTeamSSO(): Promise<void> {
+    return this.request<void>('/api/team/sso', {
+      method: 'DELETE',
+    })
+  }
+
+  async getTeamSCIM(): Promise<TeamSCIM> {
+    return this.request<TeamSCIM>('/api/team/scim')
+  }
+
+  async createTeamSCIMToken(): Promise<SCIMTokenWithSecret> {
+    return this.request<SCIMTokenWithSecret>('/api/team/scim/token', {
+      method: 'POST',
+    })
+  }
+
+  async deleteTeamSCIMToken(): Promise<void> {
+    return this.request<void>('/api/team/scim/token', {
+      method: 'DELETE',
+    })
+  }
+
+  async updateSCIMGroupMapping(groupId: number, data: SCIMGroupMappingRequest): Promise<SCIMGroupMapping> {
+    return this.request<SCIMGroupMapping>(`/api/team/scim/groups/${groupId}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async getTeamRoles(): Promise<TeamRoles> {
+    return this.request<TeamRoles>('/api/team/roles')
+  }
+
+  async createTeamRole(data: TeamRoleRequest): Promise<ProjectRole> {
+    return this.request<ProjectRole>('/api/team/roles', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async updateTeamRole(roleId: number, data: TeamRoleRequest): Promise<ProjectRole> {
+    return this.request<ProjectRole>(`/api/team/roles/${roleId}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteTeamRole(roleId: number): Promise<void> {
+    return this.request<void>(`/api/team/roles/${roleId}`, {
+      method: 'DELETE',
+    })
+  }
+
+  async listAuditLog(params?: AuditLogFilters & { limit?: number; offset?: number }): Promise<AuditLogPage> {
+    return this.request<AuditLogPage>(`/api/audit-log${auditLogQuery(params)}`)
+  }
+
+  async exportAuditLog(format: 'csv' | 'json', params?: AuditLogFilters): Promise<void> {
+    const url = `${this.baseURL}/api/audit-log${auditLogQuery({ ...params, format })}`
+    const resp = await fetch(url, {
+      headers: this.token ? { Authorization: `Bearer ${this.token}` } : {},
+    })
+    if (!resp.ok) throw new Error(`Export failed: ${resp.status}`)
+    const blob = await resp.blob()
+    const a = document.createElement('a')
+    a.href = URL.createObjectURL(blob)
+    a.download = `taskai-audit-log.${format}`
+    a.click()
+    URL.revokeObjectURL(a.href)
+  }
+
   async getTeamSentInvitations(): Promise<SentInvitation[]> {
     return this.request<SentInvitation[]>('/api/team/invitations/sent')
   }
@@ -1683,12 +2091,45 @@ class ApiClient {
     })
   }
 
+  async getFlowMetrics(projectId: number, from?: string, to?: string): Promise<FlowMetrics> {
+    const params = new URLSearchParams()
+    if (from) params.set('from', from)
+    if (to) params.set('to', to)
+    const query = params.toString()
+    return this.request<FlowMetrics>(`/api/projects/${projectId}/flow-metrics${query ? `?${query}` : ''}`)
+  }
+
   async deleteSwimLane(swimLaneId: number): Promise<void> {
     return this.request<void>(`/api/swim-lanes/${swimLaneId}`, {
       method: 'DELETE',
     })
   }
 
+  // Custom field endpoints
+  async listCustomFields(projectId: number): Promise<CustomField[]> {
+    return this.request<CustomField[]>(`/api/projects/${projectId}/custom-fields`)
+  }
+
+  async createCustomField(projectId: number, data: CreateCustomFieldRequest): Promise<CustomField> {
+    return this.request<CustomField>(`/api/projects/${projectId}/custom-fields`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async updateCustomField(fieldId: number, data: UpdateCustomFieldRequest): Promise<CustomField> {
+    return this.request<CustomField>(`/api/custom-fields/${fieldId}`, {
+      method: 'PATCH',
+      body: JSON.stringify(data),
+    })
+  }
+
+  async deleteCustomField(fieldId: number): Promise<void> {
+    return this.request<void>(`/api/custom-fields/${fieldId}`, {
+      method: 'DELETE',
+    })
+  }
+
   // Wiki endpoints
   async getWikiPages(projectId: number): Promise<WikiPage[]> {
     return this.request<WikiPage[]>(`/api/projects/${projectId}/wiki/pages`)
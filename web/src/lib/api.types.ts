@@ -264,6 +264,28 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/api/projects/{projectId}/tasks/{taskNumber}/time-entries": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Log Time by Task Number
+         * @description Log time on a task named by its project-scoped number, so agents can log
+         *     their work without looking up task IDs. Send the X-Agent-Name header to
+         *     record which agent did the work.
+         */
+        post: operations["createTimeEntryByNumber"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/api/tasks/{id}": {
         parameters: {
             query?: never;
@@ -336,6 +358,80 @@ export interface paths {
         patch: operations["updateSwimLane"];
         trace?: never;
     };
+    "/api/projects/{projectId}/flow-metrics": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Flow Metrics
+         * @description Cycle time, lead time, throughput and cumulative flow of a project over
+         *     a period, from when its tasks moved between swim lanes. Lead time runs
+         *     from a task's creation to its completion, cycle time from when it was
+         *     first started.
+         */
+        get: operations["getFlowMetrics"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/projects/{projectId}/custom-fields": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List Custom Fields
+         * @description List the custom task fields of a project in display order
+         */
+        get: operations["listCustomFields"];
+        put?: never;
+        /**
+         * Create Custom Field
+         * @description Add a custom task field to a project (max 50 per project). Requires
+         *     project edit permission. Select and multi-select fields need options.
+         */
+        post: operations["createCustomField"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/custom-fields/{id}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        post?: never;
+        /**
+         * Delete Custom Field
+         * @description Delete a custom field and its values on all tasks
+         */
+        delete: operations["deleteCustomField"];
+        options?: never;
+        head?: never;
+        /**
+         * Update Custom Field
+         * @description Rename, reorder or change the options of a custom field. The type of a
+         *     field can't be changed. Values no longer among the options of a select
+         *     field are removed from tasks.
+         */
+        patch: operations["updateCustomField"];
+        trace?: never;
+    };
     "/api/tasks/{taskId}/comments": {
         parameters: {
             query?: never;
@@ -360,6 +456,165 @@ export interface paths {
         patch?: never;
         trace?: never;
     };
+    "/api/tasks/{taskId}/history": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Task History
+         * @description List the field changes of a task, oldest first: who changed which field,
+         *     through which channel, and the values before and after. Values are shown
+         *     as in the UI, e.g. swim lane and sprint names rather than IDs.
+         */
+        get: operations["getTaskHistory"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/tasks/{taskId}/time-entries": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * List Time Entries
+         * @description List the time logged on a task, newest first.
+         */
+        get: operations["listTimeEntries"];
+        put?: never;
+        /**
+         * Log Time
+         * @description Log time on a task for the current user, either as minutes or as the
+         *     start and end of the work. The task's actual_hours becomes the sum of its
+         *     time entries.
+         */
+        post: operations["createTimeEntry"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/tasks/{taskId}/timer/start": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Start Timer
+         * @description Start a timer on a task for the current user. Users can only have one
+         *     timer running; stopping it logs the time since it started.
+         */
+        post: operations["startTimer"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/time-entries/{id}": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        post?: never;
+        /**
+         * Delete Time Entry
+         * @description Delete a time entry, or discard a running timer.
+         */
+        delete: operations["deleteTimeEntry"];
+        options?: never;
+        head?: never;
+        /**
+         * Update Time Entry
+         * @description Change the duration, date or note of a time entry. Users can change their
+         *     own entries with task edit permission, and other people's with project
+         *     edit permission. The duration of a running timer can't be changed.
+         */
+        patch: operations["updateTimeEntry"];
+        trace?: never;
+    };
+    "/api/me/timer": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Running Timer
+         * @description Get the current user's running timer, or null when none is running.
+         */
+        get: operations["getTimer"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/me/timer/stop": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Stop Timer
+         * @description Stop the current user's running timer, logging the time since it started.
+         *     Timers are capped at 24 hours.
+         */
+        post: operations["stopTimer"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/timesheets": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Timesheet
+         * @description Time logged over a period on the projects the user can see, grouped by
+         *     user, project, sprint or tag. Entries on tasks with several tags count
+         *     towards each of them. With `format=csv` the entries are downloaded as a
+         *     CSV file instead, one row per entry and group.
+         */
+        get: operations["getTimesheet"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/api/sprints": {
         parameters: {
             query?: never;
@@ -408,6 +663,71 @@ export interface paths {
         patch: operations["updateSprint"];
         trace?: never;
     };
+    "/api/sprints/{id}/analytics": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Sprint Analytics
+         * @description Daily burndown and burnup of a sprint, by task count and by estimated
+         *     hours, and the tasks added or removed after its first day. The series
+         *     comes from daily snapshots of the sprint's tasks; days before the
+         *     first snapshot are left out.
+         */
+        get: operations["getSprintAnalytics"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/sprints/{id}/complete": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        get?: never;
+        put?: never;
+        /**
+         * Complete Sprint
+         * @description Mark a sprint completed and move its unfinished tasks to the chosen
+         *     next sprint, which must be an open sprint of the same project. Without
+         *     a next sprint they're taken out of any sprint.
+         */
+        post: operations["completeSprint"];
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
+    "/api/projects/{id}/sprints/velocity": {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        /**
+         * Get Sprint Velocity
+         * @description What the last completed sprints of a project got done, oldest first
+         */
+        get: operations["getSprintVelocity"];
+        put?: never;
+        post?: never;
+        delete?: never;
+        options?: never;
+        head?: never;
+        patch?: never;
+        trace?: never;
+    };
     "/api/tags": {
         parameters: {
             query?: never;
@@ -971,6 +1291,13 @@ export interface components {
             /** Format: float */
             actual_hours?: number;
             tag_ids?: number[];
+            /**
+             * @description Custom field values keyed by field ID or name. Multi-select fields
+             *     take an array; `null` clears a field. Required fields must be given.
+             */
+            custom_fields?: {
+                [key: string]: unknown;
+            };
         };
         UpdateTaskRequest: {
             title?: string;
@@ -992,6 +1319,57 @@ export interface components {
             /** Format: float */
             actual_hours?: number;
             tag_ids?: number[];
+            /**
+             * @description Custom field values keyed by field ID or name. Multi-select fields
+             *     take an array; `null` clears a field. Fields not given are left unchanged.
+             */
+            custom_fields?: {
+                [key: string]: unknown;
+            };
+        };
+        CustomField: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            project_id?: number;
+            /** @example Size */
+            name?: string;
+            /** @enum {string} */
+            field_type?: "text" | "number" | "select" | "multi_select" | "date" | "user" | "url" | "checkbox";
+            /**
+             * @description Choices of select and multi_select fields
+             * @example [
+             *       "S",
+             *       "M",
+             *       "L"
+             *     ]
+             */
+            options?: string[];
+            /** @description Whether tasks must have a value */
+            required?: boolean;
+            position?: number;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            updated_at?: string;
+        };
+        CreateCustomFieldRequest: {
+            /** @example Size */
+            name: string;
+            /** @enum {string} */
+            field_type: "text" | "number" | "select" | "multi_select" | "date" | "user" | "url" | "checkbox";
+            /** @description Required for select and multi_select fields (max 100) */
+            options?: string[];
+            /** @default false */
+            required?: boolean;
+            /** @description Defaults to after the last field */
+            position?: number;
+        };
+        UpdateCustomFieldRequest: {
+            name?: string;
+            options?: string[];
+            required?: boolean;
+            position?: number;
         };
         CreateSwimLaneRequest: {
             /** @example Testing */
@@ -1003,11 +1381,29 @@ export interface components {
             color: string;
             /** @default 0 */
             position: number;
-        };
+            /** @enum {string} */
+            status_category?: "todo" | "in_progress" | "done";
+            /** @description Most tasks the lane should hold; 0 removes the limit */
+            wip_limit?: number;
+            /**
+             * @description Whether moves into a full lane are flagged (soft) or rejected (hard)
+             * @enum {string}
+             */
+            wip_enforcement?: "soft" | "hard";
+        };
         UpdateSwimLaneRequest: {
             name?: string;
             color?: string;
             position?: number;
+            /** @enum {string} */
+            status_category?: "todo" | "in_progress" | "done";
+            /** @description Most tasks the lane should hold; 0 removes the limit */
+            wip_limit?: number;
+            /**
+             * @description Whether moves into a full lane are flagged (soft) or rejected (hard)
+             * @enum {string}
+             */
+            wip_enforcement?: "soft" | "hard";
         };
         CreateCommentRequest: {
             /** @example This looks good, but needs testing. */
@@ -1058,17 +1454,17 @@ export interface components {
              */
             email: string;
             /**
+             * @description viewer, member, editor, owner or one of the team's custom roles
              * @example member
-             * @enum {string}
              */
-            role: "viewer" | "member" | "editor" | "owner";
+            role: string;
         };
         UpdateProjectMemberRequest: {
             /**
+             * @description viewer, member, editor, owner or one of the team's custom roles
              * @example editor
-             * @enum {string}
              */
-            role: "viewer" | "member" | "editor" | "owner";
+            role: string;
         };
         UpdateGitHubSettingsRequest: {
             /** @example https://github.com/user/repo */
@@ -1116,12 +1512,168 @@ export interface components {
         };
         AuthResponse: {
             /**
-             * @description JWT authentication token
+             * @description JWT access token
              * @example eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
              */
             token?: string;
+            /** @description Single-use token for /api/auth/refresh */
+            refresh_token?: string;
+            /**
+             * @description Access token lifetime in seconds
+             * @example 900
+             */
+            expires_in?: number;
             user?: components["schemas"]["User"];
         };
+        RefreshTokenRequest: {
+            refresh_token: string;
+        };
+        Session: {
+            /** Format: int64 */
+            id?: number;
+            /**
+             * @description Browser and OS derived from the user agent
+             * @example Chrome on macOS
+             */
+            device?: string;
+            user_agent?: string;
+            /** @example 203.0.113.7 */
+            ip_address?: string;
+            /** Format: date-time */
+            created_at?: string;
+            /**
+             * Format: date-time
+             * @description When the session was started or last refreshed
+             */
+            last_used_at?: string;
+            /** Format: date-time */
+            expires_at?: string;
+            /** @description Whether this is the session making the request */
+            current?: boolean;
+        };
+        SecondFactorChallengeResponse: {
+            /** @example true */
+            two_factor_required?: boolean;
+            /** @description Answered at /api/auth/2fa/verify within 5 minutes */
+            challenge?: string;
+            methods?: ("totp" | "backup_code" | "webauthn")[];
+            webauthn?: components["schemas"]["WebAuthnRequestOptions"];
+        };
+        /** @description Exactly one of code and credential */
+        SecondFactorRequest: {
+            challenge: string;
+            /** @description TOTP or backup code */
+            code?: string;
+            credential?: components["schemas"]["WebAuthnAssertion"];
+        };
+        WebAuthnCredentialDescriptor: {
+            /** @example public-key */
+            type?: string;
+            /** @description Base64url credential ID */
+            id?: string;
+            transports?: string[];
+        };
+        /** @description Options for PublicKeyCredential.parseCreationOptionsFromJSON() */
+        WebAuthnCreationOptions: {
+            challenge?: string;
+            rp?: {
+                id?: string;
+                name?: string;
+            };
+            user?: {
+                id?: string;
+                name?: string;
+                displayName?: string;
+            };
+            pubKeyCredParams?: {
+                type?: string;
+                alg?: number;
+            }[];
+            timeout?: number;
+            excludeCredentials?: components["schemas"]["WebAuthnCredentialDescriptor"][];
+            authenticatorSelection?: {
+                residentKey?: string;
+                userVerification?: string;
+            };
+            attestation?: string;
+        };
+        /** @description Options for PublicKeyCredential.parseRequestOptionsFromJSON() */
+        WebAuthnRequestOptions: {
+            challenge?: string;
+            timeout?: number;
+            rpId?: string;
+            allowCredentials?: components["schemas"]["WebAuthnCredentialDescriptor"][];
+            userVerification?: string;
+        };
+        /** @description PublicKeyCredential.toJSON() of a new credential */
+        WebAuthnRegistration: {
+            id: string;
+            rawId?: string;
+            type?: string;
+            response: {
+                clientDataJSON?: string;
+                attestationObject?: string;
+                transports?: string[];
+            };
+        };
+        /** @description PublicKeyCredential.toJSON() of an assertion */
+        WebAuthnAssertion: {
+            id: string;
+            rawId?: string;
+            type?: string;
+            response: {
+                clientDataJSON?: string;
+                authenticatorData?: string;
+                signature?: string;
+                userHandle?: string;
+            };
+        };
+        WebAuthnRegisterRequest: {
+            challenge: string;
+            /** @description Defaults to "Passkey" or "Security key" */
+            name?: string;
+            credential: components["schemas"]["WebAuthnRegistration"];
+        };
+        WebAuthnLoginRequest: {
+            challenge: string;
+            credential: components["schemas"]["WebAuthnAssertion"];
+        };
+        RenameWebAuthnCredentialRequest: {
+            name: string;
+        };
+        WebAuthnCredential: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            user_id?: number;
+            credential_id?: string;
+            transports?: string[];
+            /** @example YubiKey */
+            name?: string;
+            /** @description Whether this is a synced passkey rather than a device-bound key */
+            backup_eligible?: boolean;
+            backed_up?: boolean;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            last_used_at?: string;
+        };
+        RevokeSessionsResponse: {
+            /** @description Number of sessions logged out */
+            revoked?: number;
+            /** @description Access token of a new session, only when the caller's token had none */
+            token?: string;
+            refresh_token?: string;
+            expires_in?: number;
+        };
+        PasswordChangedResponse: {
+            /** @example Password changed successfully */
+            message?: string;
+            /** @description Access token of a new session, only when the caller's token had none */
+            token?: string;
+            refresh_token?: string;
+            expires_in?: number;
+        };
         User: {
             /**
              * Format: int64
@@ -1229,11 +1781,73 @@ export interface components {
             /** Format: float */
             actual_hours?: number | null;
             tags?: components["schemas"]["Tag"][];
+            /** @description Set on create and update responses when the task went into a swim lane over its soft WIP limit */
+            wip_limit_exceeded?: boolean;
+            /**
+             * @description Values of the project's custom fields, keyed by field ID. Multi-select
+             *     values are arrays, numbers are numbers, checkboxes are booleans and
+             *     user fields hold a user ID; other types are strings. Unset fields are
+             *     omitted.
+             * @example {
+             *       "12": "M",
+             *       "13": 3.5,
+             *       "14": [
+             *         "api",
+             *         "ui"
+             *       ]
+             *     }
+             */
+            custom_fields?: {
+                [key: string]: unknown;
+            };
             /** Format: date-time */
             created_at?: string;
             /** Format: date-time */
             updated_at?: string;
         };
+        /** @description How long completed tasks took, in hours */
+        FlowDuration: {
+            count?: number;
+            average_hours?: number;
+            median_hours?: number;
+            /** @description 85th percentile */
+            p85_hours?: number;
+        };
+        ThroughputDay: {
+            /** Format: date */
+            date?: string;
+            completed?: number;
+        };
+        FlowLane: {
+            /** Format: int64 */
+            id?: number;
+            name?: string;
+            /** @enum {string} */
+            status_category?: "todo" | "in_progress" | "done";
+        };
+        CumulativeFlowDay: {
+            /** Format: date */
+            date?: string;
+            /** @description Tasks in each swim lane at the end of the day, by lane ID */
+            lanes?: {
+                [key: string]: number;
+            };
+        };
+        FlowMetrics: {
+            /** Format: date */
+            from?: string;
+            /** Format: date */
+            to?: string;
+            /** @description Tasks done as of `to` that were completed within the period */
+            completed_tasks?: number;
+            lead_time?: components["schemas"]["FlowDuration"];
+            cycle_time?: components["schemas"]["FlowDuration"];
+            throughput?: components["schemas"]["ThroughputDay"][];
+            lanes?: components["schemas"]["FlowLane"][];
+            cumulative_flow?: components["schemas"]["CumulativeFlowDay"][];
+            /** @description Moves within the period that took a swim lane over its WIP limit, including GitHub sync moves, which ignore limits */
+            wip_limit_exceeded?: number;
+        };
         SwimLane: {
             /**
              * Format: int64
@@ -1257,6 +1871,10 @@ export interface components {
              * @enum {string}
              */
             status_category?: "todo" | "in_progress" | "done";
+            /** @description Most tasks the lane should hold; absent when it has no limit */
+            wip_limit?: number;
+            /** @enum {string} */
+            wip_enforcement?: "soft" | "hard";
             /** Format: date-time */
             created_at?: string;
             /** Format: date-time */
@@ -1287,6 +1905,156 @@ export interface components {
             /** Format: date-time */
             updated_at?: string;
         };
+        TaskHistoryEntry: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            task_id?: number;
+            /** Format: int64 */
+            project_id?: number;
+            /**
+             * Format: int64
+             * @description Omitted once the user is deleted
+             */
+            user_id?: number;
+            /** @example Anshuman Biswas */
+            user_name?: string;
+            /**
+             * @description AI agent that made the change, from the X-Agent-Name header
+             * @example triage-bot
+             */
+            agent_name?: string;
+            /** @enum {string} */
+            source?: "web" | "api_key";
+            /** @enum {string} */
+            field?: "title" | "description" | "status" | "swim_lane" | "priority" | "assignees" | "sprint" | "start_date" | "due_date" | "estimated_hours" | "actual_hours" | "tags";
+            /** @example To Do */
+            old_value?: string | null;
+            /** @example In Progress */
+            new_value?: string | null;
+            /** Format: date-time */
+            created_at?: string;
+        };
+        TimeEntry: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            task_id?: number;
+            /** Format: int64 */
+            project_id?: number;
+            /**
+             * Format: int64
+             * @description Omitted once the user is deleted
+             */
+            user_id?: number;
+            /** @example Anshuman Biswas */
+            user_name?: string;
+            /**
+             * @description AI agent that logged the time, from the X-Agent-Name header
+             * @example triage-bot
+             */
+            agent_name?: string;
+            /**
+             * Format: date
+             * @description Day the work was done
+             */
+            date?: string;
+            /**
+             * Format: date-time
+             * @description Omitted for time logged as minutes
+             */
+            started_at?: string;
+            /**
+             * Format: date-time
+             * @description Omitted for time logged as minutes and running timers
+             */
+            ended_at?: string;
+            /**
+             * Format: int64
+             * @description 0 while the timer is running
+             */
+            duration_seconds?: number;
+            running?: boolean;
+            /** @example Reproduced the bug */
+            note?: string;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            updated_at?: string;
+        };
+        /** @description Give either minutes, or started_at and ended_at */
+        CreateTimeEntryRequest: {
+            /** @example 45 */
+            minutes?: number;
+            /** Format: date-time */
+            started_at?: string;
+            /**
+             * Format: date-time
+             * @description At most 24 hours after started_at
+             */
+            ended_at?: string;
+            /**
+             * Format: date
+             * @description Day the work was done; defaults to the day it started, or today
+             */
+            date?: string;
+            note?: string;
+        };
+        UpdateTimeEntryRequest: {
+            minutes?: number;
+            /** Format: date */
+            date?: string;
+            note?: string;
+        };
+        StartTimerRequest: {
+            note?: string;
+        };
+        TimesheetTag: {
+            /** Format: int64 */
+            id?: number;
+            name?: string;
+        };
+        TimesheetEntry: components["schemas"]["TimeEntry"] & {
+            /** Format: int64 */
+            task_number?: number;
+            task_title?: string;
+            project_name?: string;
+            /**
+             * Format: int64
+             * @description The task's current sprint
+             */
+            sprint_id?: number;
+            sprint_name?: string;
+            tags?: components["schemas"]["TimesheetTag"][];
+        };
+        TimesheetGroup: {
+            /**
+             * Format: int64
+             * @description Omitted for entries without a sprint or tag, or by deleted users
+             */
+            id?: number;
+            name?: string;
+            /** Format: int64 */
+            seconds?: number;
+            /** Format: double */
+            hours?: number;
+            entries?: number;
+        };
+        Timesheet: {
+            /** Format: date */
+            from?: string;
+            /** Format: date */
+            to?: string;
+            /** @enum {string} */
+            group_by?: "user" | "project" | "sprint" | "tag";
+            /** Format: int64 */
+            total_seconds?: number;
+            /** Format: double */
+            total_hours?: number;
+            /** @description Totals by group, most time first. Tag groups can add up to more than the total. */
+            groups?: components["schemas"]["TimesheetGroup"][];
+            entries?: components["schemas"]["TimesheetEntry"][];
+        };
         Sprint: {
             /**
              * Format: int64
@@ -1316,6 +2084,93 @@ export interface components {
             /** Format: date-time */
             updated_at?: string;
         };
+        /** @description The state of a sprint at the end of one day. Remaining values trace
+         *     the burndown, completed and total values the burnup. */
+        SprintDay: {
+            /**
+             * Format: date
+             * @example 2026-03-02
+             */
+            date?: string;
+            total_tasks?: number;
+            completed_tasks?: number;
+            remaining_tasks?: number;
+            total_hours?: number;
+            completed_hours?: number;
+            remaining_hours?: number;
+            /** @description Linear burndown of the committed scope; omitted for sprints without an end date */
+            ideal_remaining_tasks?: number;
+            /** @description Linear burndown of the committed hours; omitted for sprints without an end date */
+            ideal_remaining_hours?: number;
+        };
+        SprintScopeChange: {
+            /** Format: date */
+            date?: string;
+            /** Format: int64 */
+            task_id?: number;
+            task_number?: number;
+            /** @description Empty for tasks that have since been deleted */
+            title?: string;
+            /** @enum {string} */
+            change?: "added" | "removed";
+            estimated_hours?: number;
+        };
+        SprintAnalytics: {
+            sprint?: components["schemas"]["Sprint"];
+            /** @description Tasks in the sprint at the end of its first recorded day */
+            committed_tasks?: number;
+            committed_hours?: number;
+            completed_tasks?: number;
+            completed_hours?: number;
+            added_tasks?: number;
+            removed_tasks?: number;
+            days?: components["schemas"]["SprintDay"][];
+            scope_changes?: components["schemas"]["SprintScopeChange"][];
+        };
+        SprintVelocity: {
+            /** Format: int64 */
+            sprint_id?: number;
+            name?: string;
+            /** Format: date */
+            start_date?: string;
+            /** Format: date */
+            end_date?: string;
+            /** @description Null for sprints completed before snapshots were recorded */
+            committed_tasks?: number | null;
+            committed_hours?: number | null;
+            completed_tasks?: number;
+            completed_hours?: number;
+        };
+        VelocityReport: {
+            sprints?: components["schemas"]["SprintVelocity"][];
+            average_completed_tasks?: number;
+            average_completed_hours?: number;
+        };
+        CompleteSprintRequest: {
+            /**
+             * Format: int64
+             * @description Sprint to move unfinished tasks to
+             */
+            next_sprint_id?: number;
+        };
+        SprintTask: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            project_id?: number;
+            task_number?: number;
+            title?: string;
+            status?: string;
+            estimated_hours?: number;
+        };
+        CompleteSprintResponse: {
+            sprint?: components["schemas"]["Sprint"];
+            completed_tasks?: number;
+            completed_hours?: number;
+            unfinished_tasks?: components["schemas"]["SprintTask"][];
+            /** Format: int64 */
+            next_sprint_id?: number;
+        };
         Tag: {
             /**
              * Format: int64
@@ -1358,10 +2213,10 @@ export interface components {
             /** @example Team Member */
             name?: string | null;
             /**
+             * @description viewer, member, editor, owner or one of the team's custom roles
              * @example member
-             * @enum {string}
              */
-            role?: "viewer" | "member" | "editor" | "owner";
+            role?: string;
             /**
              * Format: int64
              * @example 1
@@ -1433,6 +2288,171 @@ export interface components {
             /** Format: date-time */
             expires_at?: string | null;
         };
+        TeamSSORequest: {
+            /** @enum {string} */
+            protocol: "oidc" | "saml";
+            enabled?: boolean;
+            /** @description Block password and passkey logins for members who signed in through single sign-on, other than the owner; requires enabled */
+            enforced?: boolean;
+            /**
+             * @description Email domains that sign in with this identity provider once verified; no other team may have verified them
+             * @example [
+             *       "example.com"
+             *     ]
+             */
+            email_domains?: string[];
+            /** @example https://accounts.example.com */
+            oidc_issuer?: string;
+            oidc_client_id?: string;
+            /** @description Leave empty to keep the current secret */
+            oidc_client_secret?: string;
+            /** @description The identity provider's SAML metadata XML */
+            saml_metadata?: string;
+        };
+        TeamSSODomain: {
+            /** @example example.com */
+            domain?: string;
+            /**
+             * @description TXT record to publish on the domain before verifying it
+             * @example taskai-domain-verification=4f6c2a9e0b1d8e7f3a5c6b2d9e0f1a3b
+             */
+            verification_record?: string;
+            /** Format: date-time */
+            verified_at?: string | null;
+        };
+        TeamSSOConfig: {
+            /** Format: int64 */
+            team_id?: number;
+            /** @enum {string} */
+            protocol?: "" | "oidc" | "saml";
+            enabled?: boolean;
+            enforced?: boolean;
+            email_domains?: components["schemas"]["TeamSSODomain"][];
+            oidc_issuer?: string;
+            oidc_client_id?: string;
+            has_client_secret?: boolean;
+            saml_metadata?: string;
+            /** @description Starts a login with the team's identity provider */
+            login_url?: string;
+            /** @description Redirect URI to register with the OpenID Connect provider */
+            oidc_redirect_url?: string;
+            /** @description Service provider entity ID, which also serves its metadata */
+            saml_entity_id?: string;
+            /** @description Assertion consumer service URL to register with the SAML identity provider */
+            saml_acs_url?: string;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            updated_at?: string;
+        };
+        SCIMToken: {
+            /** Format: int64 */
+            team_id?: number;
+            /** @example scim_a1b2c3d */
+            token_prefix?: string;
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            last_used_at?: string;
+        };
+        SCIMTokenWithSecret: components["schemas"]["SCIMToken"] & {
+            /** @description Only returned when the token is created */
+            token?: string;
+        };
+        SCIMGroupMapping: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: int64 */
+            team_id?: number;
+            display_name?: string;
+            external_id?: string;
+            /** @enum {string} */
+            team_role?: "member" | "admin";
+            members?: {
+                /** Format: int64 */
+                user_id?: number;
+                email?: string;
+            }[];
+            projects?: components["schemas"]["SCIMGroupProject"][];
+            /** Format: date-time */
+            created_at?: string;
+            /** Format: date-time */
+            updated_at?: string;
+        };
+        SCIMGroupProject: {
+            /** Format: int64 */
+            project_id: number;
+            readonly project_name?: string;
+            /** @enum {string} */
+            role: "viewer" | "member" | "editor";
+        };
+        SCIMGroupMappingRequest: {
+            /** @enum {string} */
+            team_role: "member" | "admin";
+            projects?: components["schemas"]["SCIMGroupProject"][];
+        };
+        /** @enum {string} */
+        Permission: "project.view" | "project.edit" | "project.delete" | "members.manage" | "task.create" | "task.edit" | "task.delete" | "task.comment" | "wiki.edit" | "wiki.delete" | "wiki.comment" | "github.configure" | "webhooks.manage";
+        PermissionInfo: {
+            name?: components["schemas"]["Permission"];
+            /** @example Create tasks */
+            description?: string;
+        };
+        ProjectRole: {
+            /**
+             * Format: int64
+             * @description Omitted for built-in roles
+             */
+            id?: number;
+            /** @example Triager */
+            name?: string;
+            description?: string;
+            /** @description What the role allows besides viewing the project */
+            permissions?: components["schemas"]["Permission"][];
+            built_in?: boolean;
+        };
+        TeamRoles: {
+            permissions?: components["schemas"]["PermissionInfo"][];
+            roles?: components["schemas"]["ProjectRole"][];
+        };
+        TeamRoleRequest: {
+            /**
+             * @description Unique within the team and not the name of a built-in role
+             * @example Triager
+             */
+            name: string;
+            description?: string;
+            /**
+             * @example [
+             *       "task.create",
+             *       "task.edit",
+             *       "task.comment"
+             *     ]
+             */
+            permissions: components["schemas"]["Permission"][];
+        };
+        ProjectPermissions: {
+            /** @example member */
+            role?: string;
+            permissions?: components["schemas"]["Permission"][];
+        };
+        TeamSCIM: {
+            /**
+             * @description SCIM base URL to register with the identity provider
+             * @example https://taskai.example.com/api/scim/v2
+             */
+            base_url?: string;
+            token?: components["schemas"]["SCIMToken"] | null;
+            groups?: components["schemas"]["SCIMGroupMapping"][];
+        };
+        SSODiscovery: {
+            /** Format: int64 */
+            team_id?: number;
+            /** @enum {string} */
+            protocol?: "oidc" | "saml";
+            enforced?: boolean;
+            login_url?: string;
+        };
         Team: {
             /**
              * Format: int64
@@ -1543,6 +2563,9 @@ export interface components {
             last_login_ip?: string | null;
             /** @example 0 */
             failed_attempts?: number;
+            totp_enabled?: boolean;
+            /** @description Number of registered passkeys and security keys */
+            webauthn_credentials?: number;
         };
         UserActivity: {
             /**
@@ -1559,7 +2582,7 @@ export interface components {
              * @example login
              * @enum {string}
              */
-            activity_type?: "login" | "logout" | "failed_login";
+            activity_type?: "login" | "logout" | "failed_login" | "login_alert" | "account_locked" | "account_unlocked";
             /** @example 192.168.1.1 */
             ip_address?: string | null;
             /** @example Mozilla/5.0... */
@@ -1567,6 +2590,69 @@ export interface components {
             /** Format: date-time */
             created_at?: string;
         };
+        UnlockAccountRequest: {
+            /** @description Token from the account locked email */
+            token: string;
+        };
+        AccountLockout: {
+            /** Format: int64 */
+            user_id?: number;
+            /** Format: email */
+            email?: string;
+            /** @description Consecutive failed logins since the last successful one */
+            failed_logins?: number;
+            /** Format: date-time */
+            locked_until?: string;
+        };
+        AuditChange: {
+            /** @description Value before the action; null for created resources */
+            before?: unknown;
+            /** @description Value after the action; null for deleted resources */
+            after?: unknown;
+        };
+        AuditEntry: {
+            /** Format: int64 */
+            id?: number;
+            /** Format: date-time */
+            created_at?: string;
+            /**
+             * Format: int64
+             * @description Absent for SCIM requests
+             */
+            actor_id?: number;
+            actor_email?: string;
+            /** @enum {string} */
+            channel?: "web" | "api_key" | "scim";
+            api_key_prefix?: string;
+            /** @description X-Agent-Name of the request */
+            agent_name?: string;
+            ip_address?: string;
+            user_agent?: string;
+            /**
+             * @description What was done, e.g. task.updated, or the method and route for actions without a name
+             * @example task.updated
+             */
+            action?: string;
+            resource_type?: string;
+            /** Format: int64 */
+            resource_id?: number;
+            /** Format: int64 */
+            project_id?: number;
+            /** Format: int64 */
+            team_id?: number;
+            method?: string;
+            path?: string;
+            status?: number;
+            /** @description Changed fields; secrets are shown as [redacted] */
+            changes?: {
+                [key: string]: components["schemas"]["AuditChange"];
+            };
+        };
+        AuditLogPage: {
+            entries?: components["schemas"]["AuditEntry"][];
+            /** @description Number of entries matching the filters */
+            total?: number;
+        };
         MessageResponse: {
             /** @example Operation successful */
             message?: string;
@@ -1639,6 +2725,8 @@ export interface components {
         TagId: number;
         /** @description Member ID */
         MemberId: number;
+        /** @description Custom role ID */
+        RoleId: number;
         /** @description Team member ID */
         TeamMemberId: number;
         /** @description Invitation ID */
@@ -1832,13 +2920,16 @@ export interface operations {
             };
         };
         responses: {
-            /** @description Login successful */
+            /** @description Login successful. Users with TOTP or a security key get a
+             *     SecondFactorChallengeResponse instead of tokens and finish the
+             *     login at /api/auth/2fa/verify.
+             *      */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["AuthResponse"];
+                    "application/json": components["schemas"]["AuthResponse"] | components["schemas"]["SecondFactorChallengeResponse"];
                 };
             };
             /** @description Invalid request */
@@ -2206,6 +3297,48 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
+    createTimeEntryByNumber: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Project ID */
+                projectId: components["parameters"]["ProjectIdPath"];
+                /** @description Project-scoped task number */
+                taskNumber: number;
+            };
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateTimeEntryRequest"];
+            };
+        };
+        responses: {
+            /** @description Time logged */
+            201: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"];
+                };
+            };
+            /** @description Invalid parameters or request body */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
     deleteTask: {
         parameters: {
             query?: never;
@@ -2428,28 +3561,68 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
-    listTaskComments: {
+    getFlowMetrics: {
+        parameters: {
+            query?: {
+                /** @description First day (YYYY-MM-DD, UTC); defaults to 29 days before `to` */
+                from?: string;
+                /** @description Last day (YYYY-MM-DD, UTC); defaults to today. At most 366 days after `from`. */
+                to?: string;
+            };
+            header?: never;
+            path: {
+                /** @description Project ID */
+                projectId: components["parameters"]["ProjectIdPath"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Flow metrics */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["FlowMetrics"];
+                };
+            };
+            /** @description Invalid project ID or period */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listCustomFields: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Task ID */
-                taskId: components["parameters"]["TaskIdPath"];
+                /** @description Project ID */
+                projectId: components["parameters"]["ProjectIdPath"];
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of comments */
+            /** @description List of custom fields */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["TaskComment"][];
+                    "application/json": components["schemas"]["CustomField"][];
                 };
             };
-            /** @description Invalid task ID */
+            /** @description Invalid project ID */
             400: {
                 headers: {
                     [name: string]: unknown;
@@ -2460,33 +3633,80 @@ export interface operations {
             };
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
-            404: components["responses"]["NotFound"];
             500: components["responses"]["InternalError"];
         };
     };
-    createTaskComment: {
+    createCustomField: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Task ID */
-                taskId: components["parameters"]["TaskIdPath"];
+                /** @description Project ID */
+                projectId: components["parameters"]["ProjectIdPath"];
             };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["CreateCommentRequest"];
+                "application/json": components["schemas"]["CreateCustomFieldRequest"];
             };
         };
         responses: {
-            /** @description Comment created */
+            /** @description Custom field created */
             201: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["TaskComment"];
+                    "application/json": components["schemas"]["CustomField"];
+                };
+            };
+            /** @description Invalid request or max custom fields reached */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            /** @description A field with this name already exists */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    updateCustomField: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Custom field ID */
+                id: number;
+            };
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["UpdateCustomFieldRequest"];
+            };
+        };
+        responses: {
+            /** @description Updated custom field */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["CustomField"];
                 };
             };
             /** @description Invalid request */
@@ -2501,51 +3721,111 @@ export interface operations {
             401: components["responses"]["Unauthorized"];
             403: components["responses"]["Forbidden"];
             404: components["responses"]["NotFound"];
+            /** @description A field with this name already exists */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    deleteCustomField: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Custom field ID */
+                id: number;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Custom field deleted */
+            204: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+            /** @description Invalid ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             500: components["responses"]["InternalError"];
         };
     };
-    listSprints: {
+    listTaskComments: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
+            };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description List of sprints */
+            /** @description List of comments */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Sprint"][];
+                    "application/json": components["schemas"]["TaskComment"][];
+                };
+            };
+            /** @description Invalid task ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
                 };
             };
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             500: components["responses"]["InternalError"];
         };
     };
-    createSprint: {
+    createTaskComment: {
         parameters: {
             query?: never;
             header?: never;
-            path?: never;
+            path: {
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
+            };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["CreateSprintRequest"];
+                "application/json": components["schemas"]["CreateCommentRequest"];
             };
         };
         responses: {
-            /** @description Sprint created */
+            /** @description Comment created */
             201: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Sprint"];
+                    "application/json": components["schemas"]["TaskComment"];
                 };
             };
             /** @description Invalid request */
@@ -2558,31 +3838,33 @@ export interface operations {
                 };
             };
             401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
             500: components["responses"]["InternalError"];
         };
     };
-    deleteSprint: {
+    getTaskHistory: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Sprint ID */
-                id: components["parameters"]["SprintId"];
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
             };
             cookie?: never;
         };
         requestBody?: never;
         responses: {
-            /** @description Sprint deleted */
+            /** @description Field changes of the task */
             200: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["MessageResponse"];
+                    "application/json": components["schemas"]["TaskHistoryEntry"][];
                 };
             };
-            /** @description Invalid sprint ID */
+            /** @description Invalid task ID */
             400: {
                 headers: {
                     [name: string]: unknown;
@@ -2597,32 +3879,68 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
-    updateSprint: {
+    listTimeEntries: {
         parameters: {
             query?: never;
             header?: never;
             path: {
-                /** @description Sprint ID */
-                id: components["parameters"]["SprintId"];
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Time entries of the task */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"][];
+                };
+            };
+            /** @description Invalid task ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    createTimeEntry: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
             };
             cookie?: never;
         };
         requestBody: {
             content: {
-                "application/json": components["schemas"]["UpdateSprintRequest"];
+                "application/json": components["schemas"]["CreateTimeEntryRequest"];
             };
         };
         responses: {
-            /** @description Updated sprint */
-            200: {
+            /** @description Time logged */
+            201: {
                 headers: {
                     [name: string]: unknown;
                 };
                 content: {
-                    "application/json": components["schemas"]["Sprint"];
+                    "application/json": components["schemas"]["TimeEntry"];
                 };
             };
-            /** @description Invalid request */
+            /** @description Invalid task ID or request body */
             400: {
                 headers: {
                     [name: string]: unknown;
@@ -2637,6 +3955,494 @@ export interface operations {
             500: components["responses"]["InternalError"];
         };
     };
+    startTimer: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Task ID */
+                taskId: components["parameters"]["TaskIdPath"];
+            };
+            cookie?: never;
+        };
+        requestBody?: {
+            content: {
+                "application/json": components["schemas"]["StartTimerRequest"];
+            };
+        };
+        responses: {
+            /** @description Timer started */
+            201: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"];
+                };
+            };
+            /** @description Invalid task ID or request body */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            /** @description The user already has a timer running */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    updateTimeEntry: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Time entry ID */
+                id: number;
+            };
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["UpdateTimeEntryRequest"];
+            };
+        };
+        responses: {
+            /** @description Time entry updated */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"];
+                };
+            };
+            /** @description Invalid time entry ID or request body */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            /** @description The time entry is a running timer */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    deleteTimeEntry: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Time entry ID */
+                id: number;
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Time entry deleted */
+            204: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content?: never;
+            };
+            /** @description Invalid time entry ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getTimer: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description The running timer */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"] | null;
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    stopTimer: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Timer stopped */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["TimeEntry"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            /** @description No timer is running */
+            404: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getTimesheet: {
+        parameters: {
+            query?: {
+                /** @description First day (YYYY-MM-DD); defaults to 6 days before `to` */
+                from?: string;
+                /** @description Last day (YYYY-MM-DD); defaults to today. At most 366 days after `from`. */
+                to?: string;
+                group_by?: "user" | "project" | "sprint" | "tag";
+                project_id?: number;
+                /** @description A user ID, or `me` */
+                user_id?: string;
+                sprint_id?: number;
+                tag_id?: number;
+                /** @description Download the timesheet as a file */
+                format?: "csv" | "json";
+            };
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Timesheet */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Timesheet"];
+                    "text/csv": string;
+                };
+            };
+            /** @description Invalid period or filters */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    listSprints: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description List of sprints */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Sprint"][];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    createSprint: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path?: never;
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["CreateSprintRequest"];
+            };
+        };
+        responses: {
+            /** @description Sprint created */
+            201: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Sprint"];
+                };
+            };
+            /** @description Invalid request */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    deleteSprint: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Sprint ID */
+                id: components["parameters"]["SprintId"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Sprint deleted */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["MessageResponse"];
+                };
+            };
+            /** @description Invalid sprint ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    updateSprint: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Sprint ID */
+                id: components["parameters"]["SprintId"];
+            };
+            cookie?: never;
+        };
+        requestBody: {
+            content: {
+                "application/json": components["schemas"]["UpdateSprintRequest"];
+            };
+        };
+        responses: {
+            /** @description Updated sprint */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Sprint"];
+                };
+            };
+            /** @description Invalid request */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getSprintAnalytics: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Sprint ID */
+                id: components["parameters"]["SprintId"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Sprint analytics */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["SprintAnalytics"];
+                };
+            };
+            /** @description Invalid sprint ID */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            500: components["responses"]["InternalError"];
+        };
+    };
+    completeSprint: {
+        parameters: {
+            query?: never;
+            header?: never;
+            path: {
+                /** @description Sprint ID */
+                id: components["parameters"]["SprintId"];
+            };
+            cookie?: never;
+        };
+        requestBody?: {
+            content: {
+                "application/json": components["schemas"]["CompleteSprintRequest"];
+            };
+        };
+        responses: {
+            /** @description Sprint completed */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["CompleteSprintResponse"];
+                };
+            };
+            /** @description Invalid request or next sprint */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            404: components["responses"]["NotFound"];
+            /** @description Sprint is already completed */
+            409: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            500: components["responses"]["InternalError"];
+        };
+    };
+    getSprintVelocity: {
+        parameters: {
+            query?: {
+                /** @description Number of completed sprints to cover */
+                count?: number;
+            };
+            header?: never;
+            path: {
+                /** @description Project ID */
+                id: components["parameters"]["ProjectId"];
+            };
+            cookie?: never;
+        };
+        requestBody?: never;
+        responses: {
+            /** @description Velocity report */
+            200: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["VelocityReport"];
+                };
+            };
+            /** @description Invalid project ID or count */
+            400: {
+                headers: {
+                    [name: string]: unknown;
+                };
+                content: {
+                    "application/json": components["schemas"]["Error"];
+                };
+            };
+            401: components["responses"]["Unauthorized"];
+            403: components["responses"]["Forbidden"];
+            500: components["responses"]["InternalError"];
+        };
+    };
     listTags: {
         parameters: {
             query?: never;
@@ -0,0 +1,107 @@
+// Browser side of WebAuthn: turns the JSON options from the API into
+// navigator.credentials calls and the resulting credentials back into JSON.
+import type { WebAuthnCreationOptions, WebAuthnRequestOptions, WebAuthnRegistration, WebAuthnAssertion } from './api'
+
+export function isWebAuthnSupported(): boolean {
+  return typeof window !== 'undefined' && !!window.PublicKeyCredential && !!navigator.credentials
+}
+
+function base64urlToBuffer(value: string): ArrayBuffer {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+  const binary = atob(base64.padEnd(base64.length + ((4 - (base64.length % 4)) % 4), '='))
+  const bytes = new Uint8Array(binary.length)
+  for (let i = 0; i < binary.length; i++) {
+    bytes[i] = binary.charCodeAt(i)
+  }
+  return bytes.buffer
+}
+
+function bufferToBase64url(buffer: ArrayBuffer): string {
+  let binary = ''
+  for (const byte of new Uint8Array(buffer)) {
+    binary += String.fromCharCode(byte)
+  }
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '')
+}
+
+// Older browsers lack PublicKeyCredential.parse*FromJSON() and toJSON()
+type PublicKeyCredentialStatics = typeof PublicKeyCredential & {
+  parseCreationOptionsFromJSON?: (options: unknown) => PublicKeyCredentialCreationOptions
+  parseRequestOptionsFromJSON?: (options: unknown) => PublicKeyCredentialRequestOptions
+}
+
+function descriptors(list?: { type?: string; id?: string; transports?: string[] }[]): PublicKeyCredentialDescriptor[] {
+  return (list || []).map((c) => ({
+    type: 'public-key',
+    id: base64urlToBuffer(c.id || ''),
+    transports: c.transports as AuthenticatorTransport[] | undefined,
+  }))
+}
+
+// createCredential registers a new passkey or security key
+export async function createCredential(options: WebAuthnCreationOptions): Promise<WebAuthnRegistration> {
+  const statics = window.PublicKeyCredential as PublicKeyCredentialStatics
+  const publicKey = statics.parseCreationOptionsFromJSON
+    ? statics.parseCreationOptionsFromJSON(options)
+    : {
+        challenge: base64urlToBuffer(options.challenge || ''),
+        rp: { id: options.rp?.id, name: options.rp?.name || '' },
+        user: {
+          id: base64urlToBuffer(options.user?.id || ''),
+          name: options.user?.name || '',
+          displayName: options.user?.displayName || '',
+        },
+        pubKeyCredParams: (options.pubKeyCredParams || []).map((p) => ({ type: 'public-key' as const, alg: p.alg || 0 })),
+        timeout: options.timeout,
+        excludeCredentials: descriptors(options.excludeCredentials),
+        authenticatorSelection: options.authenticatorSelection as AuthenticatorSelectionCriteria,
+        attestation: options.attestation as AttestationConveyancePreference,
+      }
+
+  const credential = (await navigator.credentials.create({ publicKey })) as PublicKeyCredential | null
+  if (!credential) {
+    throw new Error('No credential was created')
+  }
+  const response = credential.response as AuthenticatorAttestationResponse
+  return {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: bufferToBase64url(response.clientDataJSON),
+      attestationObject: bufferToBase64url(response.attestationObject),
+      transports: response.getTransports ? response.getTransports() : [],
+    },
+  }
+}
+
+// getAssertion signs a login challenge with a passkey or security key
+export async function getAssertion(options: WebAuthnRequestOptions): Promise<WebAuthnAssertion> {
+  const statics = window.PublicKeyCredential as PublicKeyCredentialStatics
+  const publicKey = statics.parseRequestOptionsFromJSON
+    ? statics.parseRequestOptionsFromJSON(options)
+    : {
+        challenge: base64urlToBuffer(options.challenge || ''),
+        timeout: options.timeout,
+        rpId: options.rpId,
+        allowCredentials: descriptors(options.allowCredentials),
+        userVerification: options.userVerification as UserVerificationRequirement,
+      }
+
+  const credential = (await navigator.credentials.get({ publicKey })) as PublicKeyCredential | null
+  if (!credential) {
+    throw new Error('No credential was selected')
+  }
+  const response = credential.response as AuthenticatorAssertionResponse
+  return {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    response: {
+      clientDataJSON: bufferToBase64url(response.clientDataJSON),
+      authenticatorData: bufferToBase64url(response.authenticatorData),
+      signature: bufferToBase64url(response.signature),
+      userHandle: response.userHandle ? bufferToBase64url(response.userHandle) : undefined,
+    },
+  }
+}
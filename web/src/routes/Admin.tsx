@@ -107,6 +107,8 @@ interface UserWithStats {
   last_login_at?: string | null
   last_login_ip?: string | null
   failed_attempts: number
+  totp_enabled?: boolean
+  webauthn_credentials?: number
   invite_count: number
   linked_providers: string[]
 }
@@ -918,6 +920,15 @@ export default function Admin() {
                       <span className="text-xs text-dark-text-tertiary">Joined {formatShortDate(u.created_at)}</span>
                     </div>
 
+                    {(u.totp_enabled || (u.webauthn_credentials ?? 0) > 0) && (
+                      <span
+                        className="hidden sm:inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-success-500/10 text-success-400"
+                        title="Two-factor authentication enabled"
+                      >
+                        2FA
+                      </span>
+                    )}
+
                     <div className="hidden sm:flex items-center gap-1.5 text-xs text-dark-text-secondary" title="Invites remaining">
                       <svg className="w-3.5 h-3.5" fill="none" viewBox="0 0 24 24" stroke="currentColor">
                         <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M3 8l7.89 5.26a2 2 0 002.22 0L21 8M5 19h14a2 2 0 002-2V7a2 2 0 00-2-2H5a2 2 0 00-2 2v10a2 2 0 002 2z" />
@@ -997,6 +1008,25 @@ export default function Admin() {
                             </span>
                           ))}
                         </div>
+                        <p className="text-xs text-dark-text-tertiary mt-3 mb-2">Second Factors</p>
+                        <div className="flex flex-wrap gap-2">
+                          {!u.totp_enabled && !u.webauthn_credentials ? (
+                            <span className="text-xs text-dark-text-tertiary">None</span>
+                          ) : (
+                            <>
+                              {u.totp_enabled && (
+                                <span className="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-success-500/20 text-success-300 border border-success-500/30">
+                                  Authenticator app
+                                </span>
+                              )}
+                              {(u.webauthn_credentials ?? 0) > 0 && (
+                                <span className="inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-success-500/20 text-success-300 border border-success-500/30">
+                                  {u.webauthn_credentials} security {u.webauthn_credentials === 1 ? 'key' : 'keys'}
+                                </span>
+                              )}
+                            </>
+                          )}
+                        </div>
                       </div>
 
                       <div className="flex items-center gap-3 bg-dark-bg-secondary rounded-lg p-3 border border-dark-border-subtle">
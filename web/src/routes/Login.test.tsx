@@ -14,12 +14,15 @@ vi.mock('react-router-dom', () => ({
 
 // Mock useAuth
 const mockLogin = vi.fn()
+const mockVerifySecondFactor = vi.fn()
 const mockClearError = vi.fn()
 let mockAuthState = {
   user: null as { id: number; email: string; is_admin: boolean; created_at: string } | null,
   error: null as string | null,
   loading: false,
   login: mockLogin,
+  verifySecondFactor: mockVerifySecondFactor,
+  loginWithPasskey: vi.fn(),
   clearError: mockClearError,
   signup: vi.fn(),
   logout: vi.fn(),
@@ -29,6 +32,11 @@ vi.mock('../state/AuthContext', () => ({
   useAuth: () => mockAuthState,
 }))
 
+const mockDiscoverSSO = vi.fn()
+vi.mock('../lib/api', () => ({
+  apiClient: { discoverSSO: (email: string) => mockDiscoverSSO(email) },
+}))
+
 describe('Login', () => {
   beforeEach(() => {
     vi.clearAllMocks()
@@ -37,6 +45,8 @@ describe('Login', () => {
       error: null,
       loading: false,
       login: mockLogin,
+      verifySecondFactor: mockVerifySecondFactor,
+      loginWithPasskey: vi.fn(),
       clearError: mockClearError,
       signup: vi.fn(),
       logout: vi.fn(),
@@ -86,6 +96,25 @@ describe('Login', () => {
     expect(mockClearError).toHaveBeenCalled()
   })
 
+  it('asks for a second factor when the account has one', async () => {
+    const user = userEvent.setup()
+    mockLogin.mockResolvedValue({ two_factor_required: true, challenge: 'abc', methods: ['totp', 'backup_code'] })
+    mockVerifySecondFactor.mockResolvedValue(undefined)
+    render(<Login />)
+
+    await user.type(screen.getByLabelText(/email address/i), 'test@example.com')
+    await user.type(screen.getByLabelText(/password/i), 'password123')
+    await user.click(screen.getByRole('button', { name: /sign in/i }))
+
+    await user.type(await screen.findByLabelText(/authentication code/i), ' 123456 ')
+    await user.click(screen.getByRole('button', { name: /verify/i }))
+
+    await waitFor(() => {
+      expect(mockVerifySecondFactor).toHaveBeenCalledWith({ challenge: 'abc', code: '123456' })
+    })
+    expect(screen.queryByRole('button', { name: /security key/i })).not.toBeInTheDocument()
+  })
+
   it('shows server error from auth context', () => {
     mockAuthState.error = 'Invalid credentials'
     render(<Login />)
@@ -93,6 +122,20 @@ describe('Login', () => {
     expect(screen.getByRole('alert')).toHaveTextContent('Invalid credentials')
   })
 
+  it('looks up single sign-on by the email domain', async () => {
+    const user = userEvent.setup()
+    mockDiscoverSSO.mockRejectedValue(new Error('not found'))
+    render(<Login />)
+
+    await user.type(screen.getByLabelText(/email address/i), 'jane@example.com')
+    await user.click(screen.getByRole('button', { name: /single sign-on/i }))
+
+    await waitFor(() => {
+      expect(screen.getByRole('alert')).toHaveTextContent(/isn't set up for this email/i)
+    })
+    expect(mockDiscoverSSO).toHaveBeenCalledWith('jane@example.com')
+  })
+
   it('redirects when user is already logged in', async () => {
     mockAuthState.user = {
       id: 1,
@@ -2,6 +2,8 @@ import { useState, FormEvent, useEffect } from 'react'
 import { Link, useNavigate, useSearchParams } from 'react-router-dom'
 import { useAuth } from '../state/AuthContext'
 import { validateLoginForm } from '../lib/validation'
+import { apiClient, SecondFactorChallenge } from '../lib/api'
+import { getAssertion, isWebAuthnSupported } from '../lib/webauthn'
 import Card, { CardHeader, CardBody } from '../components/ui/Card'
 import TextInput from '../components/ui/TextInput'
 import Button from '../components/ui/Button'
@@ -12,7 +14,11 @@ export default function Login() {
   const [password, setPassword] = useState('')
   const [fieldErrors, setFieldErrors] = useState<Record<string, string>>({})
   const [touched, setTouched] = useState<Record<string, boolean>>({})
-  const { login, error, loading, clearError, user } = useAuth()
+  const [challenge, setChallenge] = useState<SecondFactorChallenge | null>(null)
+  const [code, setCode] = useState('')
+  const [ssoError, setSsoError] = useState('')
+  const [ssoLoading, setSsoLoading] = useState(false)
+  const { login, verifySecondFactor, loginWithPasskey, error, loading, clearError, user } = useAuth()
   const navigate = useNavigate()
   const [searchParams] = useSearchParams()
   const redirectTo = searchParams.get('redirect')
@@ -49,13 +55,87 @@ export default function Login() {
     }
 
     try {
-      await login({ email, password })
-      // AuthContext will update user, useEffect will redirect
+      const secondFactor = await login({ email, password })
+      // Otherwise AuthContext will update user, useEffect will redirect
+      if (secondFactor) {
+        setChallenge(secondFactor)
+      }
     } catch (err) {
       // Error is handled by AuthContext
     }
   }
 
+  const handleCodeSubmit = async (e: FormEvent) => {
+    e.preventDefault()
+    if (!challenge?.challenge || !code.trim()) {
+      return
+    }
+    clearError()
+    try {
+      await verifySecondFactor({ challenge: challenge.challenge, code: code.trim() })
+    } catch (err) {
+      // Error is handled by AuthContext; a wrong code can be retried
+      setCode('')
+    }
+  }
+
+  const handleSecurityKey = async () => {
+    if (!challenge?.challenge || !challenge.webauthn) {
+      return
+    }
+    clearError()
+    try {
+      const credential = await getAssertion(challenge.webauthn)
+      await verifySecondFactor({ challenge: challenge.challenge, credential })
+    } catch (err) {
+      // Error is handled by AuthContext
+    }
+  }
+
+  const handlePasskey = async () => {
+    clearError()
+    try {
+      await loginWithPasskey()
+    } catch (err) {
+      // Error is handled by AuthContext
+    }
+  }
+
+  // Single sign-on is found by the email domain; the identity provider
+  // sends the user back through /oauth/callback like Google and GitHub
+  const handleSSO = async () => {
+    clearError()
+    setSsoError('')
+    if (!email.includes('@')) {
+      setTouched({ ...touched, email: true })
+      setSsoError('Enter your work email to sign in with single sign-on')
+      return
+    }
+    setSsoLoading(true)
+    try {
+      const sso = await apiClient.discoverSSO(email.trim())
+      if (sso.login_url) {
+        window.location.href = sso.login_url
+        return
+      }
+    } catch (err) {
+      // Handled below
+    }
+    setSsoError("Single sign-on isn't set up for this email address")
+    setSsoLoading(false)
+  }
+
+  const cancelSecondFactor = () => {
+    setChallenge(null)
+    setCode('')
+    setPassword('')
+    clearError()
+  }
+
+  const methods = challenge?.methods || []
+  const canUseCode = methods.includes('totp') || methods.includes('backup_code')
+  const canUseKey = methods.includes('webauthn') && isWebAuthnSupported()
+
   return (
     <div className="min-h-screen flex items-center justify-center bg-gradient-to-br from-dark-bg-base to-dark-bg-primary px-4 relative">
       {/* Back to home */}
@@ -98,100 +178,176 @@ export default function Login() {
             </div>
           )}
 
-          <form className="space-y-6" onSubmit={handleSubmit}>
-            <FormError message={error || ''} />
-
-            <div className="space-y-4">
-              <TextInput
-                id="email"
-                name="email"
-                type="email"
-                label="Email address"
-                autoComplete="email"
-                required
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                onBlur={() => handleBlur('email')}
-                error={touched.email ? fieldErrors.email : undefined}
-                placeholder="you@example.com"
-                disabled={loading}
-              />
-
-              <div>
-                <TextInput
-                  id="password"
-                  name="password"
-                  type="password"
-                  label="Password"
-                  autoComplete="current-password"
-                  required
-                  value={password}
-                  onChange={(e) => setPassword(e.target.value)}
-                  onBlur={() => handleBlur('password')}
-                  error={touched.password ? fieldErrors.password : undefined}
-                  placeholder="••••••••"
-                  disabled={loading}
-                />
-                <div className="mt-1.5 text-right">
-                  <Link to="/forgot-password" className="text-xs text-dark-text-tertiary hover:text-primary-400 transition-colors">
-                    Forgot password?
-                  </Link>
-                </div>
-              </div>
-            </div>
+          {challenge ? (
+            <div className="space-y-6">
+              <FormError message={error || ''} />
 
-            <Button
-              type="submit"
-              variant="primary"
-              fullWidth
-              loading={loading}
-            >
-              Sign in
-            </Button>
-
-            <div className="text-sm text-center">
-              <span className="text-dark-text-quaternary">Don't have an account? </span>
-              <Link to="/signup" className="font-medium text-primary-400 hover:text-primary-300 transition-colors">
-                Sign up
-              </Link>
-            </div>
-          </form>
+              <p className="text-sm text-dark-text-secondary">
+                Two-factor authentication is enabled for this account.
+              </p>
 
-          <div className="mt-6">
-            <div className="relative">
-              <div className="absolute inset-0 flex items-center">
-                <div className="w-full border-t border-dark-border-subtle" />
-              </div>
-              <div className="relative flex justify-center text-xs">
-                <span className="px-2 bg-dark-bg-secondary text-dark-text-quaternary">or continue with</span>
+              {canUseKey && (
+                <Button type="button" variant="primary" fullWidth loading={loading} onClick={handleSecurityKey}>
+                  Use security key or passkey
+                </Button>
+              )}
+
+              {canUseCode && (
+                <form className="space-y-4" onSubmit={handleCodeSubmit}>
+                  <TextInput
+                    id="code"
+                    name="code"
+                    type="text"
+                    label="Authentication code"
+                    autoComplete="one-time-code"
+                    inputMode="text"
+                    required
+                    autoFocus={!canUseKey}
+                    value={code}
+                    onChange={(e) => setCode(e.target.value)}
+                    placeholder="123456 or a backup code"
+                    disabled={loading}
+                  />
+                  <Button type="submit" variant={canUseKey ? 'secondary' : 'primary'} fullWidth loading={loading}>
+                    Verify
+                  </Button>
+                </form>
+              )}
+
+              <div className="text-sm text-center">
+                <button
+                  type="button"
+                  onClick={cancelSecondFactor}
+                  className="font-medium text-primary-400 hover:text-primary-300 transition-colors"
+                >
+                  Back to sign in
+                </button>
               </div>
             </div>
+          ) : (
+            <>
+              <form className="space-y-6" onSubmit={handleSubmit}>
+                <FormError message={error || ssoError} />
 
-            <div className="mt-4 flex flex-col gap-3">
-              <a
-                href="/api/auth/google"
-                className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary"
-              >
-                <svg className="w-4 h-4" viewBox="0 0 24 24" aria-hidden="true">
-                  <path d="M22.56 12.25c0-.78-.07-1.53-.2-2.25H12v4.26h5.92c-.26 1.37-1.04 2.53-2.21 3.31v2.77h3.57c2.08-1.92 3.28-4.74 3.28-8.09z" fill="#4285F4"/>
-                  <path d="M12 23c2.97 0 5.46-.98 7.28-2.66l-3.57-2.77c-.98.66-2.23 1.06-3.71 1.06-2.86 0-5.29-1.93-6.16-4.53H2.18v2.84C3.99 20.53 7.7 23 12 23z" fill="#34A853"/>
-                  <path d="M5.84 14.09c-.22-.66-.35-1.36-.35-2.09s.13-1.43.35-2.09V7.07H2.18C1.43 8.55 1 10.22 1 12s.43 3.45 1.18 4.93l2.85-2.22.81-.62z" fill="#FBBC05"/>
-                  <path d="M12 5.38c1.62 0 3.06.56 4.21 1.64l3.15-3.15C17.45 2.09 14.97 1 12 1 7.7 1 3.99 3.47 2.18 7.07l3.66 2.84c.87-2.6 3.3-4.53 6.16-4.53z" fill="#EA4335"/>
-                </svg>
-                Sign in with Google
-              </a>
-
-              <a
-                href="/api/auth/github/login"
-                className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary"
-              >
-                <svg className="w-4 h-4" viewBox="0 0 24 24" aria-hidden="true" fill="currentColor">
-                  <path d="M12 2C6.477 2 2 6.484 2 12.017c0 4.425 2.865 8.18 6.839 9.504.5.092.682-.217.682-.483 0-.237-.008-.868-.013-1.703-2.782.605-3.369-1.343-3.369-1.343-.454-1.158-1.11-1.466-1.11-1.466-.908-.62.069-.608.069-.608 1.003.07 1.531 1.032 1.531 1.032.892 1.53 2.341 1.088 2.91.832.092-.647.35-1.088.636-1.338-2.22-.253-4.555-1.113-4.555-4.951 0-1.093.39-1.988 1.029-2.688-.103-.253-.446-1.272.098-2.65 0 0 .84-.27 2.75 1.026A9.564 9.564 0 0 1 12 6.844a9.59 9.59 0 0 1 2.504.337c1.909-1.296 2.747-1.027 2.747-1.027.546 1.379.202 2.398.1 2.651.64.7 1.028 1.595 1.028 2.688 0 3.848-2.339 4.695-4.566 4.943.359.309.678.92.678 1.855 0 1.338-.012 2.419-.012 2.747 0 .268.18.58.688.482A10.02 10.02 0 0 0 22 12.017C22 6.484 17.522 2 12 2z" />
-                </svg>
-                Sign in with GitHub
-              </a>
-            </div>
-          </div>
+                <div className="space-y-4">
+                  <TextInput
+                    id="email"
+                    name="email"
+                    type="email"
+                    label="Email address"
+                    autoComplete="email"
+                    required
+                    value={email}
+                    onChange={(e) => setEmail(e.target.value)}
+                    onBlur={() => handleBlur('email')}
+                    error={touched.email ? fieldErrors.email : undefined}
+                    placeholder="you@example.com"
+                    disabled={loading}
+                  />
+
+                  <div>
+                    <TextInput
+                      id="password"
+                      name="password"
+                      type="password"
+                      label="Password"
+                      autoComplete="current-password"
+                      required
+                      value={password}
+                      onChange={(e) => setPassword(e.target.value)}
+                      onBlur={() => handleBlur('password')}
+                      error={touched.password ? fieldErrors.password : undefined}
+                      placeholder="••••••••"
+                      disabled={loading}
+                    />
+                    <div className="mt-1.5 text-right">
+                      <Link to="/forgot-password" className="text-xs text-dark-text-tertiary hover:text-primary-400 transition-colors">
+                        Forgot password?
+                      </Link>
+                    </div>
+                  </div>
+                </div>
+
+                <Button
+                  type="submit"
+                  variant="primary"
+                  fullWidth
+                  loading={loading}
+                >
+                  Sign in
+                </Button>
+
+                <div className="text-sm text-center">
+                  <span className="text-dark-text-quaternary">Don't have an account? </span>
+                  <Link to="/signup" className="font-medium text-primary-400 hover:text-primary-300 transition-colors">
+                    Sign up
+                  </Link>
+                </div>
+              </form>
+
+              <div className="mt-6">
+                <div className="relative">
+                  <div className="absolute inset-0 flex items-center">
+                    <div className="w-full border-t border-dark-border-subtle" />
+                  </div>
+                  <div className="relative flex justify-center text-xs">
+                    <span className="px-2 bg-dark-bg-secondary text-dark-text-quaternary">or continue with</span>
+                  </div>
+                </div>
+
+                <div className="mt-4 flex flex-col gap-3">
+                  {isWebAuthnSupported() && (
+                    <button
+                      type="button"
+                      onClick={handlePasskey}
+                      disabled={loading}
+                      className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary disabled:opacity-50"
+                    >
+                      <svg className="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24" aria-hidden="true">
+                        <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M15 7a2 2 0 012 2m4 0a6 6 0 01-7.743 5.743L11 17H9v2H7v2H4a1 1 0 01-1-1v-2.586a1 1 0 01.293-.707l5.964-5.964A6 6 0 1121 9z" />
+                      </svg>
+                      Sign in with a passkey
+                    </button>
+                  )}
+
+                  <button
+                    type="button"
+                    onClick={handleSSO}
+                    disabled={loading || ssoLoading}
+                    className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary disabled:opacity-50"
+                  >
+                    <svg className="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24" aria-hidden="true">
+                      <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 21V5a2 2 0 00-2-2H7a2 2 0 00-2 2v16m14 0h2m-2 0h-5m-9 0H3m2 0h5M9 7h1m-1 4h1m4-4h1m-1 4h1m-5 10v-5a1 1 0 011-1h2a1 1 0 011 1v5m-4 0h4" />
+                    </svg>
+                    Single sign-on (SSO)
+                  </button>
+
+                  <a
+                    href="/api/auth/google"
+                    className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary"
+                  >
+                    <svg className="w-4 h-4" viewBox="0 0 24 24" aria-hidden="true">
+                      <path d="M22.56 12.25c0-.78-.07-1.53-.2-2.25H12v4.26h5.92c-.26 1.37-1.04 2.53-2.21 3.31v2.77h3.57c2.08-1.92 3.28-4.74 3.28-8.09z" fill="#4285F4"/>
+                      <path d="M12 23c2.97 0 5.46-.98 7.28-2.66l-3.57-2.77c-.98.66-2.23 1.06-3.71 1.06-2.86 0-5.29-1.93-6.16-4.53H2.18v2.84C3.99 20.53 7.7 23 12 23z" fill="#34A853"/>
+                      <path d="M5.84 14.09c-.22-.66-.35-1.36-.35-2.09s.13-1.43.35-2.09V7.07H2.18C1.43 8.55 1 10.22 1 12s.43 3.45 1.18 4.93l2.85-2.22.81-.62z" fill="#FBBC05"/>
+                      <path d="M12 5.38c1.62 0 3.06.56 4.21 1.64l3.15-3.15C17.45 2.09 14.97 1 12 1 7.7 1 3.99 3.47 2.18 7.07l3.66 2.84c.87-2.6 3.3-4.53 6.16-4.53z" fill="#EA4335"/>
+                    </svg>
+                    Sign in with Google
+                  </a>
+
+                  <a
+                    href="/api/auth/github/login"
+                    className="flex items-center justify-center gap-3 px-4 py-2.5 rounded-lg border border-dark-border-subtle bg-dark-bg-primary hover:bg-dark-bg-tertiary transition-colors text-sm text-dark-text-secondary"
+                  >
+                    <svg className="w-4 h-4" viewBox="0 0 24 24" aria-hidden="true" fill="currentColor">
+                      <path d="M12 2C6.477 2 2 6.484 2 12.017c0 4.425 2.865 8.18 6.839 9.504.5.092.682-.217.682-.483 0-.237-.008-.868-.013-1.703-2.782.605-3.369-1.343-3.369-1.343-.454-1.158-1.11-1.466-1.11-1.466-.908-.62.069-.608.069-.608 1.003.07 1.531 1.032 1.531 1.032.892 1.53 2.341 1.088 2.91.832.092-.647.35-1.088.636-1.338-2.22-.253-4.555-1.113-4.555-4.951 0-1.093.39-1.988 1.029-2.688-.103-.253-.446-1.272.098-2.65 0 0 .84-.27 2.75 1.026A9.564 9.564 0 0 1 12 6.844a9.59 9.59 0 0 1 2.504.337c1.909-1.296 2.747-1.027 2.747-1.027.546 1.379.202 2.398.1 2.651.64.7 1.028 1.595 1.028 2.688 0 3.848-2.339 4.695-4.566 4.943.359.309.678.92.678 1.855 0 1.338-.012 2.419-.012 2.747 0 .268.18.58.688.482A10.02 10.02 0 0 0 22 12.017C22 6.484 17.522 2 12 2z" />
+                    </svg>
+                    Sign in with GitHub
+                  </a>
+                </div>
+              </div>
+            </>
+          )}
         </CardBody>
       </Card>
     </div>
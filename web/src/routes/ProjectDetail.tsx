@@ -711,6 +711,7 @@ export default function ProjectDetail() {
                             id={lane.id.toString()}
                             title={lane.name}
                             count={tasksBySwimLane[lane.id]?.length || 0}
+                            wipLimit={lane.wip_limit}
                             tasks={tasksBySwimLane[lane.id] || []}
                             color={lane.color}
                             projectId={projectId || ''}
@@ -727,6 +728,7 @@ export default function ProjectDetail() {
                         id={lane.id.toString()}
                         title={lane.name}
                         count={tasksBySwimLane[lane.id]?.length || 0}
+                        wipLimit={lane.wip_limit}
                         tasks={tasksBySwimLane[lane.id] || []}
                         color={lane.color}
                         projectId={projectId || ''}
@@ -850,10 +852,11 @@ export default function ProjectDetail() {
 import { useDroppable } from '@dnd-kit/core'
 import { useDraggable } from '@dnd-kit/core'
 
-function TaskColumn({ id, title, count, tasks, color, projectId }: {
+function TaskColumn({ id, title, count, wipLimit, tasks, color, projectId }: {
   id: string
   title: string
   count: number
+  wipLimit?: number
   tasks: Task[]
   color: string
   projectId: string
@@ -867,7 +870,10 @@ function TaskColumn({ id, title, count, tasks, color, projectId }: {
           className="w-1.5 h-1.5 rounded-full"
           style={{ backgroundColor: color }}
         ></div>
-        {title} ({count})
+        {title} ({count}{wipLimit != null && `/${wipLimit}`})
+        {wipLimit != null && count > wipLimit && (
+          <span className="normal-case tracking-normal text-danger-400" title="This lane is over its WIP limit">over WIP limit</span>
+        )}
       </h3>
       <div className="space-y-2">
         {tasks.map((task) => (
@@ -26,6 +26,10 @@ const mocks = vi.hoisted(() => ({
   createSwimLane: vi.fn(),
   updateSwimLane: vi.fn(),
   deleteSwimLane: vi.fn(),
+  listCustomFields: vi.fn(),
+  createCustomField: vi.fn(),
+  updateCustomField: vi.fn(),
+  deleteCustomField: vi.fn(),
   getStorageUsage: vi.fn(),
   getProjectInvitations: vi.fn(),
   githubGetMappings: vi.fn(),
@@ -88,6 +92,7 @@ describe('ProjectSettings', () => {
       github_project_url: '',
     })
     mocks.getSwimLanes.mockResolvedValue(swimLanes)
+    mocks.listCustomFields.mockResolvedValue([])
     mocks.getStorageUsage.mockResolvedValue([])
     mocks.getProjectInvitations.mockResolvedValue([])
     mocks.githubGetMappings.mockResolvedValue({ status_mappings: {}, user_mappings: {} })
@@ -130,6 +135,42 @@ describe('ProjectSettings', () => {
     })
   })
 
+  describe('Custom Fields', () => {
+    it('displays current fields', async () => {
+      mocks.listCustomFields.mockResolvedValue([
+        { id: 1, project_id: 42, name: 'Size', field_type: 'select', options: ['S', 'M', 'L'], required: true, position: 0 },
+      ])
+      render(<ProjectSettings />)
+      await waitFor(() => {
+        expect(screen.getByText('Current Fields (1)')).toBeInTheDocument()
+        expect(screen.getByText('Size')).toBeInTheDocument()
+        expect(screen.getByText('S, M, L')).toBeInTheDocument()
+      })
+    })
+
+    it('creates a new field', async () => {
+      mocks.createCustomField.mockResolvedValue(undefined)
+      const user = userEvent.setup()
+      render(<ProjectSettings />)
+
+      await waitFor(() => {
+        expect(screen.getByText('Add New Field')).toBeInTheDocument()
+      })
+
+      await user.type(screen.getByPlaceholderText('e.g., Size, Customer, Spec link'), 'Customer')
+      await user.click(screen.getByText('Add Field'))
+
+      await waitFor(() => {
+        expect(mocks.createCustomField).toHaveBeenCalledWith(42, {
+          name: 'Customer',
+          field_type: 'text',
+          options: undefined,
+          required: false,
+        })
+      })
+    })
+  })
+
   describe('Swim Lanes', () => {
     it('displays current swim lanes', async () => {
       render(<ProjectSettings />)
@@ -1,12 +1,11 @@
-import { useState, useEffect, useMemo } from 'react'
+import { useState, useEffect } from 'react'
 import { useNavigate, useParams } from 'react-router-dom'
-import { useAuth } from '../state/AuthContext'
 import Card from '../components/ui/Card'
 import Button from '../components/ui/Button'
 import TextInput from '../components/ui/TextInput'
 import FormError from '../components/ui/FormError'
 import SearchSelect from '../components/ui/SearchSelect'
-import { apiClient, type SwimLane, type Project, type ProjectInvitation, type GitHubRepo, type GitHubProgressEvent } from '../lib/api'
+import { apiClient, type SwimLane, type CustomField, type Project, type ProjectInvitation, type GitHubRepo, type GitHubProgressEvent, type Permission } from '../lib/api'
 
 interface ProjectMember {
   id: number
@@ -53,17 +52,20 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
   const navigate = useNavigate()
   const { projectId: projectIdParam } = useParams<{ projectId: string }>()
   const projectId = projectIdOverride || parseInt(projectIdParam || '0')
-  const { user } = useAuth()
 
   // Project state
   const [project, setProject] = useState<Project | null>(null)
 
   // Members state
   const [members, setMembers] = useState<ProjectMember[]>([])
-  const isOwnerOrAdmin = useMemo(
-    () => members.some(m => m.user_id === user?.id && (m.role === 'owner' || m.role === 'admin')),
-    [members, user]
-  )
+  const [permissions, setPermissions] = useState<Permission[]>([])
+  const canConfigureGitHub = permissions.includes('github.configure')
+  const [roleOptions, setRoleOptions] = useState([
+    { value: 'viewer', label: 'Viewer' },
+    { value: 'member', label: 'Member' },
+    { value: 'editor', label: 'Editor' },
+    { value: 'owner', label: 'Owner' },
+  ])
   const [invitations, setInvitations] = useState<ProjectInvitation[]>([])
   const [teamMembers, setTeamMembers] = useState<TeamMember[]>([])
   const [selectedUserId, setSelectedUserId] = useState('')
@@ -122,20 +124,33 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
   const [editingLane, setEditingLane] = useState<number | null>(null)
   const [editLaneName, setEditLaneName] = useState('')
   const [editLaneColor, setEditLaneColor] = useState('')
+  const [editLaneWipLimit, setEditLaneWipLimit] = useState('')
+  const [editLaneWipEnforcement, setEditLaneWipEnforcement] = useState<'soft' | 'hard'>('soft')
   const [newLaneName, setNewLaneName] = useState('')
   const [newLaneColor, setNewLaneColor] = useState('#6B7280')
   const [newLaneStatusCategory, setNewLaneStatusCategory] = useState<'todo' | 'in_progress' | 'done'>('todo')
   const [swimLaneError, setSwimLaneError] = useState('')
   const [swimLaneSuccess, setSwimLaneSuccess] = useState('')
 
+  // Custom field state
+  const [customFields, setCustomFields] = useState<CustomField[]>([])
+  const [newFieldName, setNewFieldName] = useState('')
+  const [newFieldType, setNewFieldType] = useState<NonNullable<CustomField['field_type']>>('text')
+  const [newFieldOptions, setNewFieldOptions] = useState('')
+  const [newFieldRequired, setNewFieldRequired] = useState(false)
+  const [customFieldError, setCustomFieldError] = useState('')
+  const [customFieldSuccess, setCustomFieldSuccess] = useState('')
+
   useEffect(() => {
     loadProject()
     loadMembers()
+    loadPermissions()
     loadInvitations()
     loadTeamMembers()
     loadGitHubSettings()
     loadSyncLogs()
     loadSwimLanes()
+    loadCustomFields()
     loadStorageUsage()
 
     // Detect ?github=connected from OAuth callback
@@ -181,6 +196,15 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
     }
   }
 
+  const loadPermissions = async () => {
+    try {
+      const data = await apiClient.getProjectPermissions(projectId)
+      setPermissions(data.permissions || [])
+    } catch (error: unknown) {
+      console.error('Failed to load data:', error)
+    }
+  }
+
   const loadInvitations = async () => {
     try {
       const data = await apiClient.getProjectInvitations(projectId)
@@ -197,6 +221,15 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
     } catch (error: unknown) {
       console.error('Failed to load data:', error)
     }
+    try {
+      const { roles } = await apiClient.getTeamRoles()
+      setRoleOptions((roles || []).map((r) => ({
+        value: r.name!,
+        label: r.built_in ? r.name!.charAt(0).toUpperCase() + r.name!.slice(1) : r.name!,
+      })))
+    } catch {
+      // Keep the built-in roles
+    }
   }
 
   const loadGitHubSettings = async () => {
@@ -396,6 +429,15 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
     }
   }
 
+  const loadCustomFields = async () => {
+    try {
+      const data = await apiClient.listCustomFields(projectId)
+      setCustomFields(data)
+    } catch (error: unknown) {
+      console.error('Failed to load custom fields:', error)
+    }
+  }
+
   const loadStorageUsage = async () => {
     try {
       setLoadingStorage(true)
@@ -459,6 +501,8 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
       await apiClient.updateSwimLane(laneId, {
         name: editLaneName.trim(),
         color: editLaneColor,
+        wip_limit: editLaneWipLimit ? Number(editLaneWipLimit) : 0,
+        wip_enforcement: editLaneWipEnforcement,
       })
       setSwimLaneSuccess('Swim lane updated successfully')
       setEditingLane(null)
@@ -487,6 +531,65 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
     }
   }
 
+  const handleAddCustomField = async () => {
+    setCustomFieldError('')
+    setCustomFieldSuccess('')
+
+    if (!newFieldName.trim()) {
+      setCustomFieldError('Field name is required')
+      return
+    }
+
+    const hasOptions = newFieldType === 'select' || newFieldType === 'multi_select'
+    const options = newFieldOptions.split(',').map(o => o.trim()).filter(Boolean)
+    if (hasOptions && options.length === 0) {
+      setCustomFieldError('Select fields need at least one option')
+      return
+    }
+
+    try {
+      await apiClient.createCustomField(projectId, {
+        name: newFieldName.trim(),
+        field_type: newFieldType,
+        options: hasOptions ? options : undefined,
+        required: newFieldRequired,
+      })
+      setCustomFieldSuccess('Custom field created successfully')
+      setNewFieldName('')
+      setNewFieldType('text')
+      setNewFieldOptions('')
+      setNewFieldRequired(false)
+      loadCustomFields()
+    } catch (error: unknown) {
+      setCustomFieldError(error instanceof Error ? error.message : 'Failed to create custom field')
+    }
+  }
+
+  const handleToggleCustomFieldRequired = async (field: CustomField) => {
+    setCustomFieldError('')
+    setCustomFieldSuccess('')
+    try {
+      await apiClient.updateCustomField(field.id!, { required: !field.required })
+      loadCustomFields()
+    } catch (error: unknown) {
+      setCustomFieldError(error instanceof Error ? error.message : 'Failed to update custom field')
+    }
+  }
+
+  const handleDeleteCustomField = async (field: CustomField) => {
+    if (!confirm(`Delete the field "${field.name}"? Its values will be removed from all tasks.`)) {
+      return
+    }
+
+    try {
+      await apiClient.deleteCustomField(field.id!)
+      setCustomFieldSuccess('Custom field deleted successfully')
+      loadCustomFields()
+    } catch (error: unknown) {
+      setCustomFieldError(error instanceof Error ? error.message : 'Failed to delete custom field')
+    }
+  }
+
   const handleMoveSwimLane = async (laneId: number, direction: 'up' | 'down') => {
     const currentIndex = swimLanes.findIndex(l => l.id === laneId)
     if (currentIndex === -1) return
@@ -728,12 +831,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                     <SearchSelect
                       value={newMemberRole}
                       onChange={setNewMemberRole}
-                      options={[
-                        { value: 'viewer', label: 'Viewer' },
-                        { value: 'member', label: 'Member' },
-                        { value: 'editor', label: 'Editor' },
-                        { value: 'owner', label: 'Owner' },
-                      ]}
+                      options={roleOptions}
                     />
                   </div>
                 </div>
@@ -779,12 +877,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                             variant="inline"
                             value={member.role}
                             onChange={(v) => handleUpdateMemberRole(member.id, v)}
-                            options={[
-                              { value: 'viewer', label: 'Viewer' },
-                              { value: 'member', label: 'Member' },
-                              { value: 'editor', label: 'Editor' },
-                              { value: 'owner', label: 'Owner' },
-                            ]}
+                            options={roleOptions}
                           />
                           <button
                             onClick={() => handleRemoveMember(member.id)}
@@ -973,6 +1066,24 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                             onChange={(e) => setEditLaneColor(e.target.value)}
                             className="w-12 h-8 px-1 bg-dark-bg-primary border border-dark-border-subtle rounded-md cursor-pointer"
                           />
+                          <input
+                            type="number"
+                            min={0}
+                            value={editLaneWipLimit}
+                            onChange={(e) => setEditLaneWipLimit(e.target.value)}
+                            placeholder="WIP limit"
+                            title="Most tasks this lane should hold (empty for no limit)"
+                            className="w-24 px-3 py-1 bg-dark-bg-primary border border-dark-border-subtle text-dark-text-primary rounded-md focus:ring-1 focus:ring-primary-500 focus:border-primary-500 outline-none text-sm"
+                          />
+                          <select
+                            value={editLaneWipEnforcement}
+                            onChange={(e) => setEditLaneWipEnforcement(e.target.value as 'soft' | 'hard')}
+                            title="Soft limits warn when a task goes over them; hard limits reject the move"
+                            className="px-2 py-1 bg-dark-bg-primary border border-dark-border-subtle text-dark-text-primary rounded-md outline-none text-sm"
+                          >
+                            <option value="soft">Warn</option>
+                            <option value="hard">Block</option>
+                          </select>
                           <button
                             onClick={() => handleUpdateSwimLane(lane.id)}
                             className="px-3 py-1 bg-primary-500 hover:bg-primary-600 text-white text-sm rounded-md transition-colors"
@@ -991,6 +1102,11 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                           <div className="flex-1">
                             <span className="font-medium text-dark-text-primary">{lane.name}</span>
                             <span className="ml-2 text-xs text-dark-text-tertiary">Position: {lane.position + 1}</span>
+                            {lane.wip_limit != null && (
+                              <span className="ml-2 text-xs text-dark-text-tertiary">
+                                WIP limit: {lane.wip_limit} ({lane.wip_enforcement === 'hard' ? 'blocks' : 'warns'})
+                              </span>
+                            )}
                           </div>
                           <div className="flex items-center gap-1">
                             {/* Move up */}
@@ -1021,6 +1137,8 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                                 setEditingLane(lane.id)
                                 setEditLaneName(lane.name)
                                 setEditLaneColor(lane.color)
+                                setEditLaneWipLimit(lane.wip_limit != null ? String(lane.wip_limit) : '')
+                                setEditLaneWipEnforcement(lane.wip_enforcement === 'hard' ? 'hard' : 'soft')
                               }}
                               className="p-1.5 text-primary-400 hover:text-primary-300 hover:bg-primary-500/10 rounded transition-colors"
                               title="Edit"
@@ -1050,6 +1168,139 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
             </div>
           </Card>
 
+          {/* Custom Fields Section */}
+          <Card className="shadow-md">
+            <div className="p-6 sm:p-8">
+              <div className="flex items-start gap-4 mb-6">
+                <div className="flex-shrink-0 w-10 h-10 bg-primary-500/10 rounded-lg flex items-center justify-center">
+                  <svg className="w-6 h-6 text-primary-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                    <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M4 6h16M4 10h16M4 14h10M4 18h6" />
+                  </svg>
+                </div>
+                <div className="flex-1">
+                  <h2 className="text-xl font-semibold text-dark-text-primary mb-1">Custom Fields</h2>
+                  <p className="text-sm text-dark-text-secondary">Extra fields to track on every task in this project</p>
+                </div>
+              </div>
+
+              {customFieldSuccess && (
+                <div className="mb-4 p-4 bg-success-500/10 border-l-4 border-success-500/30 rounded-r-lg">
+                  <span className="text-success-300 font-medium">{customFieldSuccess}</span>
+                </div>
+              )}
+
+              {customFieldError && <FormError message={customFieldError} className="mb-4" />}
+
+              {/* Add Custom Field Form */}
+              <div className="mb-6 p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg">
+                <h3 className="font-semibold text-dark-text-primary mb-4">Add New Field</h3>
+                <div className="grid grid-cols-1 md:grid-cols-3 gap-3">
+                  <div className="md:col-span-2">
+                    <label className="block text-sm font-medium text-dark-text-primary mb-1">
+                      Name <span className="text-danger-400">*</span>
+                    </label>
+                    <input
+                      type="text"
+                      value={newFieldName}
+                      onChange={(e) => setNewFieldName(e.target.value)}
+                      placeholder="e.g., Size, Customer, Spec link"
+                      className="w-full px-3 py-2 bg-dark-bg-secondary border border-dark-border-subtle text-dark-text-primary rounded-lg focus:ring-2 focus:ring-primary-500 focus:border-primary-500 outline-none transition-colors"
+                      maxLength={100}
+                    />
+                  </div>
+                  <div>
+                    <label className="block text-sm font-medium text-dark-text-primary mb-1">
+                      Type
+                    </label>
+                    <SearchSelect
+                      value={newFieldType}
+                      onChange={(v) => setNewFieldType(v as NonNullable<CustomField['field_type']>)}
+                      options={[
+                        { value: 'text', label: 'Text' },
+                        { value: 'number', label: 'Number' },
+                        { value: 'select', label: 'Select' },
+                        { value: 'multi_select', label: 'Multi-select' },
+                        { value: 'date', label: 'Date' },
+                        { value: 'user', label: 'User' },
+                        { value: 'url', label: 'URL' },
+                        { value: 'checkbox', label: 'Checkbox' },
+                      ]}
+                    />
+                  </div>
+                </div>
+                {(newFieldType === 'select' || newFieldType === 'multi_select') && (
+                  <div className="mt-3">
+                    <label className="block text-sm font-medium text-dark-text-primary mb-1">
+                      Options <span className="text-danger-400">*</span>
+                    </label>
+                    <input
+                      type="text"
+                      value={newFieldOptions}
+                      onChange={(e) => setNewFieldOptions(e.target.value)}
+                      placeholder="Comma-separated, e.g., S, M, L"
+                      className="w-full px-3 py-2 bg-dark-bg-secondary border border-dark-border-subtle text-dark-text-primary rounded-lg focus:ring-2 focus:ring-primary-500 focus:border-primary-500 outline-none transition-colors"
+                    />
+                  </div>
+                )}
+                <label className="mt-3 flex items-center gap-2 text-sm text-dark-text-secondary">
+                  <input
+                    type="checkbox"
+                    checked={newFieldRequired}
+                    onChange={(e) => setNewFieldRequired(e.target.checked)}
+                  />
+                  Required on new tasks
+                </label>
+                <div className="mt-4">
+                  <Button onClick={handleAddCustomField} disabled={!newFieldName.trim()} size="sm">
+                    Add Field
+                  </Button>
+                </div>
+              </div>
+
+              {/* Custom Fields List */}
+              <div>
+                <h3 className="font-semibold text-dark-text-primary mb-3">Current Fields ({customFields.length})</h3>
+                {customFields.length === 0 ? (
+                  <p className="text-sm text-dark-text-tertiary">No custom fields yet</p>
+                ) : (
+                  <div className="space-y-2">
+                    {customFields.map((field) => (
+                      <div
+                        key={field.id}
+                        className="flex items-center gap-3 p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg"
+                      >
+                        <div className="flex-1">
+                          <span className="font-medium text-dark-text-primary">{field.name}</span>
+                          <span className="ml-2 text-xs text-dark-text-tertiary">{field.field_type?.replace('_', '-')}</span>
+                          {field.options && field.options.length > 0 && (
+                            <span className="ml-2 text-xs text-dark-text-tertiary">{field.options.join(', ')}</span>
+                          )}
+                        </div>
+                        <label className="flex items-center gap-1 text-xs text-dark-text-secondary">
+                          <input
+                            type="checkbox"
+                            checked={!!field.required}
+                            onChange={() => handleToggleCustomFieldRequired(field)}
+                          />
+                          Required
+                        </label>
+                        <button
+                          onClick={() => handleDeleteCustomField(field)}
+                          className="p-1.5 text-danger-400 hover:text-danger-300 hover:bg-danger-500/10 rounded transition-colors"
+                          title="Delete"
+                        >
+                          <svg className="w-4 h-4" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                            <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M19 7l-.867 12.142A2 2 0 0116.138 21H7.862a2 2 0 01-1.995-1.858L5 7m5 4v6m4-6v6m1-10V4a1 1 0 00-1-1h-4a1 1 0 00-1 1v3M4 7h16" />
+                          </svg>
+                        </button>
+                      </div>
+                    ))}
+                  </div>
+                )}
+              </div>
+            </div>
+          </Card>
+
           {/* Storage Usage Section */}
           <Card className="shadow-md">
             <div className="p-6 sm:p-8">
@@ -1172,7 +1423,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
               {!githubSettings.github_token_set ? (
                 /* --- Not connected --- */
                 <div className="py-4">
-                  {isOwnerOrAdmin ? (
+                  {canConfigureGitHub ? (
                     <>
                       <p className="text-sm text-dark-text-secondary mb-4">Connect this project to your GitHub account to pick a repository.</p>
                       <Button onClick={handleConnectGitHub} disabled={isConnectingGitHub}>
@@ -1196,7 +1447,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                         Connected{githubSettings.github_login ? ` as @${githubSettings.github_login}` : ''}
                       </span>
                     </div>
-                    {isOwnerOrAdmin && (
+                    {canConfigureGitHub && (
                       <button
                         type="button"
                         onClick={handleDisconnectGitHub}
@@ -1273,7 +1524,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                     </label>
                   </div>
 
-                  {isOwnerOrAdmin && (
+                  {canConfigureGitHub && (
                     <div className="flex items-center gap-3 p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg">
                       <div className="flex-1">
                         <span className="font-medium text-dark-text-primary">Auto-sync Interval</span>
@@ -1292,7 +1543,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                     </div>
                   )}
 
-                  {isOwnerOrAdmin && githubSettings.github_sync_interval && (
+                  {canConfigureGitHub && githubSettings.github_sync_interval && (
                     <div className="flex items-center gap-3 p-4 bg-dark-bg-secondary border border-dark-border-subtle rounded-lg">
                       <div className="flex-1">
                         <span className="font-medium text-dark-text-primary">Sync Time (UTC)</span>
@@ -1352,7 +1603,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
                     </label>
                   </div>
 
-                  {isOwnerOrAdmin && (
+                  {canConfigureGitHub && (
                     <Button type="submit" disabled={isSavingGitHub}>
                       {isSavingGitHub ? 'Saving...' : 'Save Settings'}
                     </Button>
@@ -1361,7 +1612,7 @@ export default function ProjectSettings({ embedded, projectIdOverride }: Project
               )}
 
               {/* GitHub Sync Section — owners/admins only */}
-              {isOwnerOrAdmin && githubSettings.github_owner && githubSettings.github_repo_name && (
+              {canConfigureGitHub && githubSettings.github_owner && githubSettings.github_repo_name && (
                 <div className="mt-8 pt-6 border-t border-dark-border-subtle">
                   <h3 className="text-lg font-semibold text-dark-text-primary mb-1">GitHub Sync</h3>
                   <p className="text-sm text-dark-text-secondary mb-4">
@@ -18,6 +18,9 @@ const mocks = vi.hoisted(() => ({
   setup2FA: vi.fn(),
   enable2FA: vi.fn(),
   disable2FA: vi.fn(),
+  getWebAuthnCredentials: vi.fn(),
+  renameWebAuthnCredential: vi.fn(),
+  deleteWebAuthnCredential: vi.fn(),
   changePassword: vi.fn(),
   getAPIKeys: vi.fn(),
   createAPIKey: vi.fn(),
@@ -45,6 +48,7 @@ describe('Settings', () => {
   beforeEach(() => {
     vi.clearAllMocks()
     mocks.get2FAStatus.mockResolvedValue({ enabled: false })
+    mocks.getWebAuthnCredentials.mockResolvedValue([])
     mocks.getAPIKeys.mockResolvedValue([])
     mocks.getCloudinaryCredential.mockRejectedValue(new Error('not found'))
     mocks.getMyTeam.mockResolvedValue(null)
@@ -191,6 +195,42 @@ describe('Settings', () => {
     })
   })
 
+  describe('Passkeys', () => {
+    it('lists registered security keys', async () => {
+      mocks.getWebAuthnCredentials.mockResolvedValue([
+        { id: 1, name: 'YubiKey', backup_eligible: false, created_at: '2024-01-01T00:00:00Z' },
+        { id: 2, name: 'MacBook', backup_eligible: true, created_at: '2024-02-01T00:00:00Z', last_used_at: '2024-03-01T00:00:00Z' },
+      ])
+
+      render(<Settings />)
+      await waitFor(() => {
+        expect(screen.getByText('YubiKey')).toBeInTheDocument()
+      })
+      expect(screen.getByText('MacBook')).toBeInTheDocument()
+      expect(screen.getAllByText('Synced')).toHaveLength(1)
+    })
+
+    it('renames a security key', async () => {
+      const user = userEvent.setup()
+      mocks.getWebAuthnCredentials.mockResolvedValue([
+        { id: 1, name: 'YubiKey', backup_eligible: false, created_at: '2024-01-01T00:00:00Z' },
+      ])
+      mocks.renameWebAuthnCredential.mockResolvedValue({ id: 1, name: 'Work key' })
+
+      render(<Settings />)
+      await user.click(await screen.findByRole('button', { name: 'Rename' }))
+      const input = screen.getByLabelText('Security key name')
+      await user.clear(input)
+      await user.type(input, 'Work key')
+      await user.click(screen.getByRole('button', { name: 'Save' }))
+
+      await waitFor(() => {
+        expect(screen.getByText('Work key')).toBeInTheDocument()
+      })
+      expect(mocks.renameWebAuthnCredential).toHaveBeenCalledWith(1, 'Work key')
+    })
+  })
+
   describe('API Keys', () => {
     it('renders API keys section', async () => {
       render(<Settings />)
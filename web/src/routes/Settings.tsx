@@ -5,8 +5,12 @@ import Button from '../components/ui/Button'
 import TextInput from '../components/ui/TextInput'
 import FormError from '../components/ui/FormError'
 import SearchSelect from '../components/ui/SearchSelect'
-import { apiClient, type CloudinaryCredentialResponse, type APIKey, type Team, type TeamMember, type TeamInvitation, type TeamMembership, type SentInvitation, type UserSearchResult, type Invite, type ProjectInvitation } from '../lib/api'
+import TeamSSOSettings from '../components/TeamSSOSettings'
+import TeamSCIMSettings from '../components/TeamSCIMSettings'
+import TeamRolesSettings from '../components/TeamRolesSettings'
+import { apiClient, type WebAuthnCredential, type CloudinaryCredentialResponse, type APIKey, type Team, type TeamMember, type TeamInvitation, type TeamMembership, type SentInvitation, type UserSearchResult, type Invite, type ProjectInvitation } from '../lib/api'
 import type { FigmaCredentialsStatus } from '../lib/api'
+import { createCredential, isWebAuthnSupported } from '../lib/webauthn'
 
 export default function Settings() {
   const navigate = useNavigate()
@@ -44,6 +48,15 @@ export default function Settings() {
   const [disablePassword, setDisablePassword] = useState('')
   const [isDisabling2FA, setIsDisabling2FA] = useState(false)
 
+  // Passkeys and security keys state
+  const [passkeys, setPasskeys] = useState<WebAuthnCredential[]>([])
+  const [newPasskeyName, setNewPasskeyName] = useState('')
+  const [passkeyError, setPasskeyError] = useState('')
+  const [passkeySuccess, setPasskeySuccess] = useState('')
+  const [isRegisteringPasskey, setIsRegisteringPasskey] = useState(false)
+  const [editingPasskey, setEditingPasskey] = useState<{ id: number; name: string } | null>(null)
+  const [isDeletingPasskey, setIsDeletingPasskey] = useState<number | null>(null)
+
   // API Keys state
   const [apiKeys, setApiKeys] = useState<APIKey[]>([])
   const [newKeyName, setNewKeyName] = useState('')
@@ -118,6 +131,7 @@ export default function Settings() {
   useEffect(() => {
     loadProfile()
     load2FAStatus()
+    loadPasskeys()
     loadAPIKeys()
     loadTeamData()
     loadCloudinaryCredentials()
@@ -162,6 +176,72 @@ export default function Settings() {
     }
   }
 
+  const loadPasskeys = async () => {
+    try {
+      const creds = await apiClient.getWebAuthnCredentials()
+      setPasskeys(creds)
+    } catch (error) {
+      console.error('Failed to load data:', error)
+    }
+  }
+
+  const handleRegisterPasskey = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setPasskeyError('')
+    setPasskeySuccess('')
+    setIsRegisteringPasskey(true)
+
+    try {
+      const options = await apiClient.beginWebAuthnRegistration()
+      const credential = await createCredential(options)
+      const created = await apiClient.finishWebAuthnRegistration(options.challenge || '', credential, newPasskeyName.trim() || undefined)
+      setPasskeys([...passkeys, created])
+      setNewPasskeyName('')
+      setPasskeySuccess(`"${created.name}" added`)
+    } catch (error: unknown) {
+      // The browser rejects with NotAllowedError when the prompt is cancelled
+      if (error instanceof DOMException && error.name === 'NotAllowedError') {
+        setPasskeyError('Registration was cancelled or timed out')
+      } else {
+        setPasskeyError(error instanceof Error ? error.message : 'Failed to add security key')
+      }
+    } finally {
+      setIsRegisteringPasskey(false)
+    }
+  }
+
+  const handleRenamePasskey = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!editingPasskey || !editingPasskey.name.trim()) return
+    setPasskeyError('')
+    setPasskeySuccess('')
+
+    try {
+      const renamed = await apiClient.renameWebAuthnCredential(editingPasskey.id, editingPasskey.name.trim())
+      setPasskeys(passkeys.map((p) => (p.id === renamed.id ? { ...p, name: renamed.name } : p)))
+      setEditingPasskey(null)
+    } catch (error: unknown) {
+      setPasskeyError(error instanceof Error ? error.message : 'Failed to rename security key')
+    }
+  }
+
+  const handleDeletePasskey = async (passkey: WebAuthnCredential) => {
+    if (!passkey.id || !confirm(`Remove "${passkey.name}"? It will no longer be able to sign in to your account.`)) return
+    setPasskeyError('')
+    setPasskeySuccess('')
+    setIsDeletingPasskey(passkey.id)
+
+    try {
+      await apiClient.deleteWebAuthnCredential(passkey.id)
+      setPasskeys(passkeys.filter((p) => p.id !== passkey.id))
+      setPasskeySuccess(`"${passkey.name}" removed`)
+    } catch (error: unknown) {
+      setPasskeyError(error instanceof Error ? error.message : 'Failed to remove security key')
+    } finally {
+      setIsDeletingPasskey(null)
+    }
+  }
+
   const handlePasswordChange = async (e: React.FormEvent) => {
     e.preventDefault()
     setPasswordError('')
@@ -1153,6 +1233,108 @@ export default function Settings() {
             </div>
           </Card>
 
+          {/* Passkeys Section */}
+          <Card className="shadow-md">
+            <div className="p-6 sm:p-8 flex items-start gap-4">
+              <div className="flex-shrink-0 w-10 h-10 bg-purple-500/10 rounded-lg flex items-center justify-center">
+                <svg className="w-6 h-6 text-purple-400" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                  <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M15 7a2 2 0 012 2m4 0a6 6 0 01-7.743 5.743L11 17H9v2H7v2H4a1 1 0 01-1-1v-2.586a1 1 0 01.293-.707l5.964-5.964A6 6 0 1121 9z" />
+                </svg>
+              </div>
+              <div className="flex-1">
+                <h2 className="text-xl font-semibold text-dark-text-primary mb-1">Passkeys &amp; Security Keys</h2>
+                <p className="text-sm text-dark-text-secondary mb-6">
+                  Use a security key as a second factor, or a passkey to sign in without a password
+                </p>
+
+                {passkeySuccess && (
+                  <div className="mb-4 p-4 bg-success-500/10 border-l-4 border-success-400 rounded-r-lg">
+                    <span className="text-success-300 font-medium">{passkeySuccess}</span>
+                  </div>
+                )}
+
+                {passkeyError && <FormError message={passkeyError} className="mb-4" />}
+
+                {passkeys.length > 0 && (
+                  <ul className="mb-6 divide-y divide-dark-border-subtle border border-dark-border-subtle rounded-lg">
+                    {passkeys.map((passkey) => (
+                      <li key={passkey.id} className="p-4 flex items-center justify-between gap-4">
+                        {editingPasskey?.id === passkey.id ? (
+                          <form onSubmit={handleRenamePasskey} className="flex-1 flex items-center gap-2">
+                            <TextInput
+                              id={`passkey-name-${passkey.id}`}
+                              label="Security key name"
+                              value={editingPasskey.name}
+                              onChange={(e) => setEditingPasskey({ id: editingPasskey.id, name: e.target.value })}
+                              maxLength={100}
+                              autoFocus
+                            />
+                            <Button type="submit" size="sm">Save</Button>
+                            <Button type="button" size="sm" variant="ghost" onClick={() => setEditingPasskey(null)}>Cancel</Button>
+                          </form>
+                        ) : (
+                          <>
+                            <div>
+                              <p className="font-medium text-dark-text-primary">
+                                {passkey.name}
+                                {passkey.backup_eligible && (
+                                  <span className="ml-2 px-2 py-0.5 rounded-full text-xs bg-primary-500/10 text-primary-400">Synced</span>
+                                )}
+                              </p>
+                              <p className="text-xs text-dark-text-tertiary">
+                                Added {passkey.created_at ? new Date(passkey.created_at).toLocaleDateString() : ''}
+                                {passkey.last_used_at ? ` · Last used ${new Date(passkey.last_used_at).toLocaleDateString()}` : ' · Never used'}
+                              </p>
+                            </div>
+                            <div className="flex items-center gap-2">
+                              <Button
+                                type="button"
+                                size="sm"
+                                variant="ghost"
+                                onClick={() => setEditingPasskey({ id: passkey.id || 0, name: passkey.name || '' })}
+                              >
+                                Rename
+                              </Button>
+                              <Button
+                                type="button"
+                                size="sm"
+                                variant="danger"
+                                disabled={isDeletingPasskey === passkey.id}
+                                onClick={() => handleDeletePasskey(passkey)}
+                              >
+                                {isDeletingPasskey === passkey.id ? 'Removing...' : 'Remove'}
+                              </Button>
+                            </div>
+                          </>
+                        )}
+                      </li>
+                    ))}
+                  </ul>
+                )}
+
+                {isWebAuthnSupported() ? (
+                  <form onSubmit={handleRegisterPasskey} className="flex flex-col sm:flex-row gap-3 sm:items-end">
+                    <div className="flex-1">
+                      <TextInput
+                        id="new-passkey-name"
+                        label="Name (optional)"
+                        value={newPasskeyName}
+                        onChange={(e) => setNewPasskeyName(e.target.value)}
+                        placeholder="e.g. YubiKey, MacBook"
+                        maxLength={100}
+                      />
+                    </div>
+                    <Button type="submit" disabled={isRegisteringPasskey}>
+                      {isRegisteringPasskey ? 'Waiting for key...' : 'Add security key'}
+                    </Button>
+                  </form>
+                ) : (
+                  <p className="text-sm text-dark-text-tertiary">This browser doesn't support passkeys or security keys.</p>
+                )}
+              </div>
+            </div>
+          </Card>
+
           {/* Cloudinary Section */}
           <Card className="shadow-md">
             <div className="p-6 sm:p-8 flex items-start gap-4">
@@ -2325,6 +2507,15 @@ print(response.json())`}
               </div>
             </div>
           </Card>
+
+          {/* Project Roles Section */}
+          {team && <TeamRolesSettings />}
+
+          {/* Single Sign-On Section (team owners only) */}
+          {team && <TeamSSOSettings />}
+
+          {/* User Provisioning Section (team owners only) */}
+          {team && <TeamSCIMSettings />}
         </div>
       </div>
     </div>
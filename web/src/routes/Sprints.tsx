@@ -5,7 +5,7 @@ import Button from '../components/ui/Button'
 import TextInput from '../components/ui/TextInput'
 import FormError from '../components/ui/FormError'
 import SearchSelect from '../components/ui/SearchSelect'
-import { apiClient, type Project } from '../lib/api'
+import { apiClient, type Project, type SprintAnalytics, type VelocityReport } from '../lib/api'
 
 interface Sprint {
   id: number
@@ -18,6 +18,29 @@ interface Sprint {
   created_at: string
 }
 
+// Burndown draws a sprint's remaining tasks against its ideal line
+function Burndown({ days }: { days: NonNullable<SprintAnalytics['days']> }) {
+  const width = 320
+  const height = 96
+  const top = Math.max(1, ...days.map(d => Math.max(d.total_tasks ?? 0, d.ideal_remaining_tasks ?? 0)))
+  const x = (i: number) => (days.length > 1 ? (i / (days.length - 1)) * width : width / 2)
+  const y = (v: number) => height - (v / top) * height
+  const line = (value: (d: (typeof days)[number]) => number | undefined) =>
+    days
+      .map((d, i) => [i, value(d)] as const)
+      .filter(([, v]) => v !== undefined)
+      .map(([i, v]) => `${x(i)},${y(v!)}`)
+      .join(' ')
+
+  return (
+    <svg viewBox={`0 0 ${width} ${height}`} className="w-full h-24" preserveAspectRatio="none" aria-label="Burndown chart">
+      <polyline points={line(d => d.ideal_remaining_tasks)} fill="none" stroke="currentColor" strokeDasharray="4 4" className="text-dark-text-tertiary" />
+      <polyline points={line(d => d.total_tasks)} fill="none" stroke="currentColor" className="text-purple-400/50" />
+      <polyline points={line(d => d.remaining_tasks)} fill="none" stroke="currentColor" strokeWidth={2} className="text-primary-400" />
+    </svg>
+  )
+}
+
 export default function Sprints() {
   const navigate = useNavigate()
   const { projectId } = useParams<{ projectId: string }>()
@@ -43,6 +66,11 @@ export default function Sprints() {
   const [shareMenuId, setShareMenuId] = useState<number | null>(null)
   const [otherProjects, setOtherProjects] = useState<Project[]>([])
   const shareMenuRef = useRef<HTMLDivElement>(null)
+  const [reportId, setReportId] = useState<number | null>(null)
+  const [analytics, setAnalytics] = useState<SprintAnalytics | null>(null)
+  const [velocity, setVelocity] = useState<VelocityReport | null>(null)
+  const [completingId, setCompletingId] = useState<number | null>(null)
+  const [nextSprintId, setNextSprintId] = useState('')
 
   useEffect(() => {
     if (projectIdNum) loadSprints()
@@ -69,6 +97,7 @@ export default function Sprints() {
     try {
       const data = await apiClient.getSprints(projectIdNum)
       setSprints(data)
+      apiClient.getSprintVelocity(projectIdNum).then(setVelocity).catch(() => {})
     } catch (error: unknown) {
       console.error('Failed to load sprints:', error)
     }
@@ -148,6 +177,39 @@ export default function Sprints() {
     }
   }
 
+  const toggleReport = async (sprintId: number) => {
+    if (reportId === sprintId) {
+      setReportId(null)
+      return
+    }
+    setReportId(sprintId)
+    setAnalytics(null)
+    try {
+      setAnalytics(await apiClient.getSprintAnalytics(sprintId))
+    } catch (error: unknown) {
+      setError(error instanceof Error ? error.message : 'Failed to load sprint report')
+    }
+  }
+
+  const handleComplete = async (sprintId: number) => {
+    setError('')
+    setSuccess('')
+    try {
+      const result = await apiClient.completeSprint(sprintId, nextSprintId ? Number(nextSprintId) : undefined)
+      const unfinished = result.unfinished_tasks?.length ?? 0
+      const next = sprints.find(sp => sp.id === result.next_sprint_id)
+      setSuccess(
+        `Sprint completed with ${result.completed_tasks ?? 0} tasks done` +
+          (unfinished > 0 ? `; ${unfinished} unfinished moved ${next ? `to ${next.name}` : 'out of the sprint'}` : '')
+      )
+      setCompletingId(null)
+      setNextSprintId('')
+      loadSprints()
+    } catch (error: unknown) {
+      setError(error instanceof Error ? error.message : 'Failed to complete sprint')
+    }
+  }
+
   const getStatusColor = (status: string) => {
     switch (status) {
       case 'active':
@@ -288,6 +350,16 @@ export default function Sprints() {
               </form>
             )}
 
+            {velocity && (velocity.sprints?.length ?? 0) > 0 && (
+              <div className="mb-4 p-3 bg-dark-bg-primary border border-dark-border-subtle rounded-lg text-sm text-dark-text-secondary">
+                Velocity over the last {velocity.sprints!.length} completed sprints:{' '}
+                <span className="font-semibold text-dark-text-primary">
+                  {(velocity.average_completed_tasks ?? 0).toFixed(1)} tasks
+                </span>{' '}
+                ({(velocity.average_completed_hours ?? 0).toFixed(1)}h estimated) per sprint
+              </div>
+            )}
+
             <div className="space-y-3">
               {sprints.length === 0 ? (
                 <div className="text-center py-8 text-dark-text-tertiary">
@@ -324,6 +396,19 @@ export default function Sprints() {
                             {sprint.end_date && new Date(sprint.end_date).toLocaleDateString()}
                           </p>
                         )}
+                        <div className="flex gap-3 mt-2 text-xs">
+                          <button onClick={() => toggleReport(sprint.id)} className="text-primary-400 hover:underline">
+                            {reportId === sprint.id ? 'Hide report' : 'Report'}
+                          </button>
+                          {!sprint.is_shared && sprint.status !== 'completed' && (
+                            <button
+                              onClick={() => setCompletingId(completingId === sprint.id ? null : sprint.id)}
+                              className="text-success-400 hover:underline"
+                            >
+                              Complete sprint
+                            </button>
+                          )}
+                        </div>
                       </div>
                       <div className="flex gap-1 relative" ref={shareMenuId === sprint.id ? shareMenuRef : undefined}>
                         {sprint.is_shared ? (
@@ -386,6 +471,61 @@ export default function Sprints() {
                         )}
                       </div>
                     </div>
+
+                    {completingId === sprint.id && (
+                      <div className="mt-3 p-3 bg-success-500/5 border border-success-500/30 rounded-lg space-y-2">
+                        <label className="block text-sm font-medium text-dark-text-primary">Move unfinished tasks to</label>
+                        <SearchSelect
+                          value={nextSprintId}
+                          onChange={setNextSprintId}
+                          options={[
+                            { value: '', label: 'No sprint' },
+                            ...sprints
+                              .filter(sp => sp.id !== sprint.id && sp.status !== 'completed' && !sp.is_shared)
+                              .map(sp => ({ value: String(sp.id), label: sp.name })),
+                          ]}
+                        />
+                        <div className="flex gap-2">
+                          <Button size="sm" onClick={() => handleComplete(sprint.id)}>
+                            Complete
+                          </Button>
+                          <Button size="sm" variant="secondary" onClick={() => setCompletingId(null)}>
+                            Cancel
+                          </Button>
+                        </div>
+                      </div>
+                    )}
+
+                    {reportId === sprint.id && (
+                      <div className="mt-3 pt-3 border-t border-dark-border-subtle text-sm text-dark-text-secondary">
+                        {!analytics ? (
+                          <p className="text-dark-text-tertiary">Loading…</p>
+                        ) : (analytics.days?.length ?? 0) === 0 ? (
+                          <p className="text-dark-text-tertiary">No snapshots recorded yet</p>
+                        ) : (
+                          <>
+                            <Burndown days={analytics.days!} />
+                            <p className="mt-2">
+                              {analytics.completed_tasks} of {analytics.committed_tasks} committed tasks done
+                              ({(analytics.completed_hours ?? 0).toFixed(1)}h of {(analytics.committed_hours ?? 0).toFixed(1)}h)
+                            </p>
+                            {(analytics.scope_changes?.length ?? 0) > 0 && (
+                              <ul className="mt-2 space-y-1 text-xs">
+                                {analytics.scope_changes!.map(change => (
+                                  <li key={`${change.date}-${change.task_id}-${change.change}`}>
+                                    <span className={change.change === 'added' ? 'text-amber-400' : 'text-dark-text-tertiary'}>
+                                      {change.change === 'added' ? '+ Added' : '− Removed'}
+                                    </span>{' '}
+                                    {change.task_number ? `#${change.task_number} ` : ''}
+                                    {change.title || 'Deleted task'} on {change.date}
+                                  </li>
+                                ))}
+                              </ul>
+                            )}
+                          </>
+                        )}
+                      </div>
+                    )}
                   </div>
                 ))
               )}
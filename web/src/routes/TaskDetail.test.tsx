@@ -26,6 +26,10 @@ const mocks = vi.hoisted(() => ({
   getSprints: vi.fn(),
   getSwimLanes: vi.fn(),
   getTaskComments: vi.fn(),
+  getTaskHistory: vi.fn(),
+  listTimeEntries: vi.fn(),
+  getTimer: vi.fn(),
+  listCustomFields: vi.fn(),
   createTaskComment: vi.fn(),
   getProjectMembers: vi.fn(),
   getTaskAttachments: vi.fn(),
@@ -67,6 +71,10 @@ describe('TaskDetail', () => {
       { id: 2, project_id: 7, name: 'In Progress', color: '#3B82F6', position: 1, status_category: 'in_progress' },
     ])
     mocks.getTaskComments.mockResolvedValue([])
+    mocks.getTaskHistory.mockResolvedValue([])
+    mocks.listTimeEntries.mockResolvedValue([])
+    mocks.getTimer.mockResolvedValue(null)
+    mocks.listCustomFields.mockResolvedValue([])
     mocks.getProjectMembers.mockResolvedValue([])
     mocks.getTaskAttachments.mockResolvedValue([])
     mocks.getProjectGitHub.mockResolvedValue({ github_owner: '', github_repo_name: '', github_token_set: false, github_branch: 'main', github_sync_enabled: false, github_last_sync: null, github_login: null })
@@ -147,6 +155,31 @@ describe('TaskDetail', () => {
     })
   })
 
+  it('merges field changes into the timeline', async () => {
+    mocks.getTaskComments.mockResolvedValue([
+      {
+        id: 1,
+        task_id: 1,
+        user_id: 1,
+        user_name: 'Alice',
+        comment: 'Working on this now',
+        created_at: '2024-01-10T00:00:00Z',
+        updated_at: '2024-01-10T00:00:00Z',
+      },
+    ])
+    mocks.getTaskHistory.mockResolvedValue([
+      { id: 1, task_id: 1, user_id: 2, user_name: 'Bob', agent_name: 'triage-bot', source: 'api_key', field: 'priority', old_value: 'low', new_value: 'high', created_at: '2024-01-11T00:00:00Z' },
+      { id: 2, task_id: 1, user_id: 2, user_name: 'Bob', agent_name: 'triage-bot', source: 'api_key', field: 'due_date', old_value: null, new_value: '2024-02-01', created_at: '2024-01-11T00:00:00Z' },
+    ])
+
+    render(<TaskDetail />)
+    await waitFor(() => {
+      expect(screen.getByText('triage-bot for Bob')).toBeInTheDocument()
+    })
+    expect(screen.getByText(/changed the priority from low to high, set the due date to 2024-02-01/)).toBeInTheDocument()
+    expect(mocks.getTaskHistory).toHaveBeenCalledWith(1)
+  })
+
   it('renders attachments section', async () => {
     mocks.getTaskAttachments.mockResolvedValue([
       {
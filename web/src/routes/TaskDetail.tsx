@@ -7,7 +7,7 @@ import Button from '../components/ui/Button'
 import SearchSelect from '../components/ui/SearchSelect'
 import MultiSelectDropdown from '../components/ui/MultiSelectDropdown'
 import ImagePickerModal from '../components/ImagePickerModal'
-import { apiClient, Task, type UpdateTaskRequest, type SwimLane, type Sprint, type ProjectMember, type Attachment, type TaskComment, type GitHubPushTaskResponse, type Tag, type GitHubReaction } from '../lib/api'
+import { apiClient, Task, type UpdateTaskRequest, type SwimLane, type Sprint, type ProjectMember, type Attachment, type TaskComment, type TaskHistoryEntry, type TimeEntry, type CustomField, type GitHubPushTaskResponse, type Tag, type GitHubReaction } from '../lib/api'
 import { preprocessGraphLinks, parseGraphLinkUrl } from '../lib/graphLinks'
 import FigmaEmbed from '../components/FigmaEmbed'
 import { REACTION_EMOJI, REACTION_ORDER } from '../lib/reactionUtils'
@@ -19,6 +19,62 @@ function preprocessFigmaUrls(content: string): string {
   return content.replace(FIGMA_URL_RE, (_, prefix, url) => `${prefix}[${url}](${url})`)
 }
 
+const HISTORY_FIELD_LABELS: Record<string, string> = {
+  title: 'the title',
+  status: 'the status',
+  swim_lane: 'the swim lane',
+  priority: 'the priority',
+  assignees: 'the assignees',
+  sprint: 'the sprint',
+  start_date: 'the start date',
+  due_date: 'the due date',
+  estimated_hours: 'the estimate',
+  actual_hours: 'the actual hours',
+  tags: 'the tags',
+}
+
+function describeChange(change: TaskHistoryEntry): string {
+  if (change.field === 'description') return 'updated the description'
+  const label = HISTORY_FIELD_LABELS[change.field || ''] || change.field
+  if (change.old_value == null) return `set ${label} to ${change.new_value}`
+  if (change.new_value == null) return `cleared ${label}`
+  return `changed ${label} from ${change.old_value} to ${change.new_value}`
+}
+
+type TimelineItem =
+  | { kind: 'comment'; at: string; comment: TaskComment }
+  | { kind: 'changes'; at: string; changes: TaskHistoryEntry[] }
+
+// buildTimeline merges comments and field changes in time order. The changes
+// of one update share a timestamp and are shown together.
+function buildTimeline(comments: TaskComment[], history: TaskHistoryEntry[]): TimelineItem[] {
+  const items = comments.map((comment): TimelineItem => ({ kind: 'comment', at: comment.created_at, comment }))
+  let last: TimelineItem | undefined
+  for (const change of history) {
+    if (last?.kind === 'changes' && last.at === change.created_at && last.changes[0].user_id === change.user_id) {
+      last.changes.push(change)
+      continue
+    }
+    last = { kind: 'changes', at: change.created_at || '', changes: [change] }
+    items.push(last)
+  }
+  return items.sort((a, b) => new Date(a.at).getTime() - new Date(b.at).getTime())
+}
+
+function TaskChanges({ changes }: { changes: TaskHistoryEntry[] }) {
+  const first = changes[0]
+  const actor = first.user_name || (first.user_id ? `User ${first.user_id}` : 'Deleted user')
+  return (
+    <div className="border-t border-dark-border-subtle pt-4 first:border-t-0 first:pt-0 text-xs text-dark-text-tertiary">
+      <span className="font-medium text-dark-text-secondary">
+        {first.agent_name ? `${first.agent_name} for ${actor}` : actor}
+      </span>{' '}
+      {changes.map(describeChange).join(', ')}
+      <span className="ml-2">{new Date(first.created_at || '').toLocaleString()}</span>
+    </div>
+  )
+}
+
 function ReactionBar({
   reactions,
   onToggle,
@@ -115,6 +171,7 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
   const [swimLanes, setSwimLanes] = useState<SwimLane[]>([])
   const [members, setMembers] = useState<ProjectMember[]>([])
   const [projectTags, setProjectTags] = useState<Tag[]>([])
+  const [customFields, setCustomFields] = useState<CustomField[]>([])
 
   // Attachments
   const [attachments, setAttachments] = useState<Attachment[]>([])
@@ -126,6 +183,9 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
 
   // Comments
   const [comments, setComments] = useState<TaskComment[]>([])
+  const [history, setHistory] = useState<TaskHistoryEntry[]>([])
+  const [timeEntries, setTimeEntries] = useState<TimeEntry[]>([])
+  const timeline = buildTimeline(comments, history)
   const [newComment, setNewComment] = useState('')
   const [postingComment, setPostingComment] = useState(false)
 
@@ -228,6 +288,7 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
     loadSwimLanes()
     loadMembers()
     loadProjectTags()
+    loadCustomFields()
     if (projectId) {
       apiClient.getProjectGitHub(Number(projectId))
         .then(s => setGithubRepo({ github_owner: s.github_owner, github_repo_name: s.github_repo_name }))
@@ -238,6 +299,8 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
   useEffect(() => {
     if (task?.id) {
       loadComments(task.id)
+      loadHistory(task.id)
+      loadTimeEntries(task.id)
       loadAttachments(task.id)
     }
   }, [task?.id]) // eslint-disable-line react-hooks/exhaustive-deps
@@ -299,6 +362,25 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
     try { setComments(await apiClient.getTaskComments(taskId)) } catch { /* ignore */ }
   }
 
+  const loadHistory = async (id?: number) => {
+    const taskId = id ?? task?.id
+    if (!taskId) return
+    try { setHistory(await apiClient.getTaskHistory(taskId)) } catch { /* ignore */ }
+  }
+
+  const loadTimeEntries = async (id?: number) => {
+    const taskId = id ?? task?.id
+    if (!taskId) return
+    try { setTimeEntries(await apiClient.listTimeEntries(taskId)) } catch { /* ignore */ }
+  }
+
+  // Logging time changes the task's actual hours and history
+  const refreshTimeTracking = async () => {
+    loadTimeEntries()
+    loadHistory()
+    try { setTask(await apiClient.getTaskByNumber(Number(projectId), Number(taskNumber))) } catch { /* ignore */ }
+  }
+
   const loadMembers = async () => {
     try { setMembers(await apiClient.getProjectMembers(Number(projectId))) } catch { /* ignore */ }
   }
@@ -307,6 +389,21 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
     try { setProjectTags(await apiClient.getTags(Number(projectId))) } catch { /* ignore */ }
   }
 
+  const loadCustomFields = async () => {
+    try { setCustomFields(await apiClient.listCustomFields(Number(projectId))) } catch { /* ignore */ }
+  }
+
+  const saveCustomField = async (fieldId: number, value: unknown) => {
+    if (!task?.id) return
+    try {
+      const updated = await apiClient.updateTask(task.id, { custom_fields: { [fieldId]: value } })
+      setTask(prev => prev ? { ...prev, custom_fields: updated.custom_fields } : prev)
+      loadHistory()
+    } catch (err: unknown) {
+      setError(err instanceof Error ? err.message : 'Failed to update field')
+    }
+  }
+
   const saveTagIds = async (tagIds: number[]) => {
     if (!task?.id) return
     try {
@@ -492,6 +589,7 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
 
       await apiClient.updateTask(task.id!, update)
       await loadTask()
+      loadHistory()
     } catch (err: unknown) {
       setError(err instanceof Error ? err.message : 'Failed to update')
     } finally {
@@ -919,7 +1017,7 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
             {/* Comments Section */}
             <div className="bg-dark-bg-secondary border border-dark-border-subtle rounded-lg p-6">
               <h2 className="text-sm font-semibold text-dark-text-primary mb-4">
-                Comments {comments.length > 0 && `(${comments.length})`}
+                Activity {comments.length > 0 && `(${comments.length} comment${comments.length !== 1 ? 's' : ''})`}
               </h2>
 
               <div className="mb-4">
@@ -966,56 +1064,62 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
               </div>
 
               <div className="space-y-4">
-                {comments.length === 0 ? (
+                {timeline.length === 0 ? (
                   <p className="text-sm text-dark-text-tertiary italic">No comments yet</p>
                 ) : (
-                  comments.map((comment) => (
-                    <div key={comment.id} className="border-t border-dark-border-subtle pt-4 first:border-t-0 first:pt-0">
-                      <div className="flex items-start gap-3">
-                        <div className={`w-8 h-8 rounded-full flex items-center justify-center flex-shrink-0 ${comment.agent_name ? 'bg-violet-500/10' : 'bg-primary-500/10'}`}>
-                          <span className={`text-xs font-medium ${comment.agent_name ? 'text-violet-400' : 'text-primary-400'}`}>
-                            {comment.agent_name ? 'AI' : (comment.user_name || 'U').charAt(0).toUpperCase()}
-                          </span>
-                        </div>
-                        <div className="flex-1 min-w-0">
-                          <div className="flex items-center gap-2 mb-1">
-                            <Link
-                              to={`/app/users/${comment.user_id}`}
-                              className="text-sm font-medium text-dark-text-primary hover:text-primary-400 transition-colors"
-                            >
-                              {comment.agent_name
-                                ? `${comment.agent_name} for ${comment.user_name || `User ${comment.user_id}`}`
-                                : comment.user_name || `User ${comment.user_id}`}
-                            </Link>
-                            {comment.agent_name && (
-                              <span className="inline-flex items-center px-1.5 py-0.5 rounded text-[10px] font-medium bg-violet-500/10 text-violet-400 border border-violet-500/20">
-                                AI
-                              </span>
-                            )}
-                            <span className="text-xs text-dark-text-tertiary">
-                              {new Date(comment.created_at).toLocaleString()}
+                  timeline.map((item) => {
+                    if (item.kind === 'changes') {
+                      return <TaskChanges key={`changes-${item.changes[0].id}`} changes={item.changes} />
+                    }
+                    const comment = item.comment
+                    return (
+                      <div key={comment.id} className="border-t border-dark-border-subtle pt-4 first:border-t-0 first:pt-0">
+                        <div className="flex items-start gap-3">
+                          <div className={`w-8 h-8 rounded-full flex items-center justify-center flex-shrink-0 ${comment.agent_name ? 'bg-violet-500/10' : 'bg-primary-500/10'}`}>
+                            <span className={`text-xs font-medium ${comment.agent_name ? 'text-violet-400' : 'text-primary-400'}`}>
+                              {comment.agent_name ? 'AI' : (comment.user_name || 'U').charAt(0).toUpperCase()}
                             </span>
                           </div>
-                          <div className="text-sm text-dark-text-secondary prose prose-sm max-w-none prose-headings:text-dark-text-primary prose-p:text-dark-text-secondary prose-a:text-primary-400 prose-code:text-primary-400 prose-code:bg-primary-500/10 prose-code:px-1 prose-code:py-0.5 prose-code:rounded prose-pre:bg-dark-bg-primary prose-pre:border prose-pre:border-dark-border-subtle prose-strong:text-dark-text-primary prose-li:text-dark-text-secondary prose-img:rounded-lg prose-img:max-h-64 prose-img:border prose-img:border-dark-border-subtle">
-                            <ReactMarkdown
-                              remarkPlugins={[remarkGfm, remarkEmoji]}
-                              components={{
-                                a: ({ href, children }) => {
-                                  if (href && /figma\.com\/(file|design|proto)\//.test(href)) {
-                                    return <FigmaEmbed url={href} size="m" />
-                                  }
-                                  return <a href={href}>{children}</a>
-                                },
-                              }}
-                            >
-                              {preprocessFigmaUrls(comment.comment)}
-                            </ReactMarkdown>
-                            <ReactionBar reactions={comment.github_reactions} onToggle={(r) => handleToggleReaction(r, comment.id)} />
+                          <div className="flex-1 min-w-0">
+                            <div className="flex items-center gap-2 mb-1">
+                              <Link
+                                to={`/app/users/${comment.user_id}`}
+                                className="text-sm font-medium text-dark-text-primary hover:text-primary-400 transition-colors"
+                              >
+                                {comment.agent_name
+                                  ? `${comment.agent_name} for ${comment.user_name || `User ${comment.user_id}`}`
+                                  : comment.user_name || `User ${comment.user_id}`}
+                              </Link>
+                              {comment.agent_name && (
+                                <span className="inline-flex items-center px-1.5 py-0.5 rounded text-[10px] font-medium bg-violet-500/10 text-violet-400 border border-violet-500/20">
+                                  AI
+                                </span>
+                              )}
+                              <span className="text-xs text-dark-text-tertiary">
+                                {new Date(comment.created_at).toLocaleString()}
+                              </span>
+                            </div>
+                            <div className="text-sm text-dark-text-secondary prose prose-sm max-w-none prose-headings:text-dark-text-primary prose-p:text-dark-text-secondary prose-a:text-primary-400 prose-code:text-primary-400 prose-code:bg-primary-500/10 prose-code:px-1 prose-code:py-0.5 prose-code:rounded prose-pre:bg-dark-bg-primary prose-pre:border prose-pre:border-dark-border-subtle prose-strong:text-dark-text-primary prose-li:text-dark-text-secondary prose-img:rounded-lg prose-img:max-h-64 prose-img:border prose-img:border-dark-border-subtle">
+                              <ReactMarkdown
+                                remarkPlugins={[remarkGfm, remarkEmoji]}
+                                components={{
+                                  a: ({ href, children }) => {
+                                    if (href && /figma\.com\/(file|design|proto)\//.test(href)) {
+                                      return <FigmaEmbed url={href} size="m" />
+                                    }
+                                    return <a href={href}>{children}</a>
+                                  },
+                                }}
+                              >
+                                {preprocessFigmaUrls(comment.comment)}
+                              </ReactMarkdown>
+                              <ReactionBar reactions={comment.github_reactions} onToggle={(r) => handleToggleReaction(r, comment.id)} />
+                            </div>
                           </div>
                         </div>
                       </div>
-                    </div>
-                  ))
+                    )
+                  })
                 )}
               </div>
             </div>
@@ -1158,6 +1262,10 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
                     className="w-full text-sm bg-dark-bg-primary border border-dark-border-subtle text-dark-text-primary rounded-md px-3 py-1.5 focus:ring-1 focus:ring-primary-500 focus:border-primary-500 outline-none"
                     autoFocus
                   />
+                ) : timeEntries.length > 0 ? (
+                  <p className="text-sm text-dark-text-primary px-3 py-1.5" title="Sum of the logged time">
+                    {task.actual_hours ?? 0}h
+                  </p>
                 ) : (
                   <button
                     onClick={() => startEdit('actual_hours', String(task.actual_hours ?? 0))}
@@ -1168,6 +1276,11 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
                 )}
               </SidebarField>
 
+              {/* Time Tracking */}
+              <SidebarField label="Time Logged">
+                <TimeTracking taskId={task.id!} entries={timeEntries} onChange={refreshTimeTracking} />
+              </SidebarField>
+
               {/* Tags */}
               {projectTags.length > 0 && (
                 <SidebarField label="Tags">
@@ -1181,6 +1294,18 @@ export default function TaskDetail({ isModal, onClose }: TaskDetailProps) {
                   />
                 </SidebarField>
               )}
+
+              {/* Custom Fields */}
+              {customFields.map((field) => (
+                <SidebarField key={field.id} label={field.required ? `${field.name} *` : field.name!}>
+                  <CustomFieldInput
+                    field={field}
+                    value={task.custom_fields?.[String(field.id)]}
+                    members={members}
+                    onSave={(value) => saveCustomField(field.id!, value)}
+                  />
+                </SidebarField>
+              ))}
             </div>
 
             {/* GitHub */}
@@ -1438,6 +1563,202 @@ function ConfirmModal({ message, onConfirm, onCancel }: {
 
 /* Sidebar helper components */
 
+function formatDuration(seconds: number): string {
+  const h = Math.floor(seconds / 3600)
+  const m = Math.round((seconds % 3600) / 60)
+  return h > 0 ? `${h}h ${m}m` : `${m}m`
+}
+
+function TimeTracking({ taskId, entries, onChange }: {
+  taskId: number
+  entries: TimeEntry[]
+  onChange: () => void
+}) {
+  const [timer, setTimer] = useState<TimeEntry | null>(null)
+  const [minutes, setMinutes] = useState('')
+  const [note, setNote] = useState('')
+  const [busy, setBusy] = useState(false)
+  const [error, setError] = useState('')
+
+  useEffect(() => {
+    apiClient.getTimer().then(setTimer).catch(() => {})
+  }, [taskId])
+
+  const run = async (action: () => Promise<void>) => {
+    setBusy(true)
+    setError('')
+    try {
+      await action()
+      onChange()
+    } catch (err: unknown) {
+      setError(err instanceof Error ? err.message : 'Failed to log time')
+    } finally {
+      setBusy(false)
+    }
+  }
+
+  const logTime = (e: React.FormEvent) => {
+    e.preventDefault()
+    const value = parseInt(minutes, 10)
+    if (!value || value <= 0) return
+    run(async () => {
+      await apiClient.createTimeEntry(taskId, { minutes: value, note })
+      setMinutes('')
+      setNote('')
+    })
+  }
+
+  const runningHere = timer?.task_id === taskId
+  const inputClass = 'text-sm bg-dark-bg-primary border border-dark-border-subtle text-dark-text-primary rounded-md px-2 py-1 focus:ring-1 focus:ring-primary-500 focus:border-primary-500 outline-none'
+
+  return (
+    <div className="space-y-2">
+      {runningHere ? (
+        <Button size="sm" variant="secondary" disabled={busy} onClick={() => run(async () => { await apiClient.stopTimer(); setTimer(null) })}>
+          Stop timer
+        </Button>
+      ) : (
+        <Button
+          size="sm"
+          variant="secondary"
+          disabled={busy || timer !== null}
+          title={timer ? 'You have a timer running on another task' : undefined}
+          onClick={() => run(async () => { setTimer(await apiClient.startTimer(taskId)) })}
+        >
+          Start timer
+        </Button>
+      )}
+
+      <form onSubmit={logTime} className="flex gap-1">
+        <input
+          type="number"
+          min="1"
+          max="1440"
+          placeholder="min"
+          value={minutes}
+          onChange={(e) => setMinutes(e.target.value)}
+          className={`${inputClass} w-16`}
+        />
+        <input
+          type="text"
+          placeholder="Note"
+          value={note}
+          onChange={(e) => setNote(e.target.value)}
+          className={`${inputClass} flex-1 min-w-0`}
+        />
+        <Button size="sm" type="submit" disabled={busy || !minutes}>Log</Button>
+      </form>
+      {error && <p className="text-xs text-danger-400">{error}</p>}
+
+      {entries.length > 0 && (
+        <ul className="space-y-1">
+          {entries.map((entry) => (
+            <li key={entry.id} className="flex items-center gap-2 text-xs text-dark-text-secondary">
+              <span className="text-dark-text-primary">
+                {entry.running ? 'running' : formatDuration(entry.duration_seconds ?? 0)}
+              </span>
+              <span>{entry.date}</span>
+              <span className="truncate flex-1" title={entry.note}>
+                {entry.agent_name || entry.user_name}{entry.note ? ` · ${entry.note}` : ''}
+              </span>
+              <button
+                onClick={() => run(async () => {
+                  await apiClient.deleteTimeEntry(entry.id!)
+                  if (entry.running) setTimer(null)
+                })}
+                className="text-dark-text-tertiary hover:text-danger-400"
+                aria-label="Delete time entry"
+              >
+                ×
+              </button>
+            </li>
+          ))}
+        </ul>
+      )}
+    </div>
+  )
+}
+
+// Edits the value of a custom field; empty values clear it
+function CustomFieldInput({ field, value, members, onSave }: {
+  field: CustomField
+  value: unknown
+  members: ProjectMember[]
+  onSave: (value: unknown) => void
+}) {
+  const current = value == null ? '' : String(value)
+  const [draft, setDraft] = useState(current)
+
+  useEffect(() => { setDraft(current) }, [current])
+
+  const inputClass = 'w-full text-sm bg-dark-bg-primary border border-dark-border-subtle text-dark-text-primary rounded-md px-3 py-1.5 focus:ring-1 focus:ring-primary-500 focus:border-primary-500 outline-none'
+  const commit = () => {
+    if (draft.trim() !== current) onSave(draft.trim() === '' ? null : draft.trim())
+  }
+
+  switch (field.field_type) {
+    case 'checkbox':
+      return (
+        <input
+          type="checkbox"
+          checked={value === true}
+          onChange={(e) => onSave(e.target.checked)}
+          aria-label={field.name}
+        />
+      )
+    case 'multi_select':
+      return (
+        <MultiSelectDropdown
+          values={Array.isArray(value) ? value.map(String) : []}
+          onChange={(vals) => onSave(vals.length > 0 ? vals : null)}
+          options={(field.options ?? []).map((o) => ({ value: o, label: o }))}
+          title={`Select ${field.name}`}
+          placeholder="None"
+          filterPlaceholder="Filter options…"
+        />
+      )
+    case 'select':
+    case 'user':
+      return (
+        <select
+          value={current}
+          onChange={(e) => onSave(e.target.value === '' ? null : e.target.value)}
+          className={inputClass}
+          aria-label={field.name}
+        >
+          <option value="">None</option>
+          {field.field_type === 'select'
+            ? (field.options ?? []).map((o) => <option key={o} value={o}>{o}</option>)
+            : members.map((m) => (
+              <option key={m.user_id || m.id} value={String(m.user_id || m.id)}>
+                {m.user_name || m.name || m.email || `User ${m.user_id || m.id}`}
+              </option>
+            ))}
+        </select>
+      )
+    default:
+      return (
+        <div className="flex items-center gap-1">
+          <input
+            type={field.field_type === 'number' ? 'number' : field.field_type === 'date' ? 'date' : field.field_type === 'url' ? 'url' : 'text'}
+            value={draft}
+            onChange={(e) => setDraft(e.target.value)}
+            onBlur={commit}
+            onKeyDown={(e) => { if (e.key === 'Enter') e.currentTarget.blur() }}
+            placeholder="None"
+            className={inputClass}
+            aria-label={field.name}
+          />
+          {field.field_type === 'url' && current && (
+            <a href={current} target="_blank" rel="noopener noreferrer" className="text-xs text-primary-400 hover:text-primary-300">
+              Open
+            </a>
+          )}
+        </div>
+      )
+  }
+}
+
 function SidebarField({ label, children }: { label: string; children: React.ReactNode }) {
   return (
     <div className="px-4 py-3">
@@ -0,0 +1,67 @@
+import { useEffect, useRef, useState } from 'react'
+import { Link, useSearchParams } from 'react-router-dom'
+import { api } from '../lib/api'
+import Card, { CardHeader, CardBody } from '../components/ui/Card'
+
+export default function UnlockAccount() {
+  const [searchParams] = useSearchParams()
+  const token = searchParams.get('token') ?? ''
+
+  const [status, setStatus] = useState<'loading' | 'done' | 'error'>(token ? 'loading' : 'error')
+  const [error, setError] = useState(token ? '' : 'Invalid or missing unlock token.')
+  const submitted = useRef(false)
+
+  useEffect(() => {
+    // The token is single use, so don't send it twice in strict mode
+    if (!token || submitted.current) return
+    submitted.current = true
+
+    api.unlockAccount(token)
+      .then(() => setStatus('done'))
+      .catch((err) => {
+        setError(err instanceof Error ? err.message : 'Failed to unlock account')
+        setStatus('error')
+      })
+  }, [token])
+
+  return (
+    <div className="min-h-screen flex items-center justify-center bg-gradient-to-br from-dark-bg-base to-dark-bg-primary px-4">
+      <Card className="max-w-md w-full">
+        <CardHeader>
+          <div className="text-center">
+            <img src="/logo.svg" alt="TaskAI" className="mx-auto h-16 w-16 mb-4" />
+            <h2 className="text-xl font-semibold text-dark-text-primary tracking-tight">
+              Unlock account
+            </h2>
+          </div>
+        </CardHeader>
+
+        <CardBody>
+          {status === 'loading' ? (
+            <p className="text-sm text-dark-text-tertiary text-center">Unlocking your account…</p>
+          ) : status === 'done' ? (
+            <div className="p-4 bg-green-500/10 border border-green-500/30 rounded-lg text-center">
+              <p className="text-sm text-green-300 font-medium">Your account is unlocked</p>
+              <p className="text-xs text-dark-text-tertiary mt-1">
+                If you didn't try to sign in, reset your password.
+              </p>
+              <Link to="/login" className="inline-block mt-3 text-sm text-primary-400 hover:text-primary-300">
+                Sign in
+              </Link>
+            </div>
+          ) : (
+            <div className="text-center space-y-4">
+              <p className="text-sm text-danger-300">{error}</p>
+              <p className="text-xs text-dark-text-tertiary">
+                Locks expire on their own, or you can reset your password instead.
+              </p>
+              <Link to="/forgot-password" className="text-sm text-primary-400 hover:text-primary-300">
+                Reset your password
+              </Link>
+            </div>
+          )}
+        </CardBody>
+      </Card>
+    </div>
+  )
+}
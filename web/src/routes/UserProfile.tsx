@@ -7,6 +7,7 @@ const activityIcon: Record<string, string> = {
   wiki_page: '📄',
   annotation_comment: '💭',
   task_created: '✅',
+  task_updated: '🔄',
   annotation_created: '📌',
   wiki_edit: '✏️',
 }
@@ -16,16 +17,18 @@ const activityLabel: Record<string, string> = {
   wiki_page: 'Created wiki page',
   annotation_comment: 'Commented on annotation',
   task_created: 'Created task',
+  task_updated: 'Updated task',
   annotation_created: 'Created annotation',
   wiki_edit: 'Edited wiki page',
 }
 
-type FilterType = 'all' | 'task_comment' | 'task_created' | 'wiki_page' | 'wiki_edit' | 'annotation_created' | 'annotation_comment'
+type FilterType = 'all' | 'task_comment' | 'task_created' | 'task_updated' | 'wiki_page' | 'wiki_edit' | 'annotation_created' | 'annotation_comment'
 
 const TYPE_FILTERS: { value: FilterType; label: string }[] = [
   { value: 'all', label: 'All' },
   { value: 'task_comment', label: 'Comments' },
   { value: 'task_created', label: 'Tasks' },
+  { value: 'task_updated', label: 'Task updates' },
   { value: 'wiki_page', label: 'Wiki pages' },
   { value: 'wiki_edit', label: 'Wiki edits' },
   { value: 'annotation_created', label: 'Annotations' },
@@ -163,6 +166,7 @@ export default function UserProfile() {
     const parts: string[] = []
     if (counts.task_comment) parts.push(`${counts.task_comment} comment${counts.task_comment !== 1 ? 's' : ''}`)
     if (counts.task_created) parts.push(`${counts.task_created} task${counts.task_created !== 1 ? 's' : ''} created`)
+    if (counts.task_updated) parts.push(`${counts.task_updated} task update${counts.task_updated !== 1 ? 's' : ''}`)
     if (counts.wiki_page) parts.push(`${counts.wiki_page} wiki page${counts.wiki_page !== 1 ? 's' : ''}`)
     if (counts.annotation_created) parts.push(`${counts.annotation_created} annotation${counts.annotation_created !== 1 ? 's' : ''}`)
     if (counts.annotation_comment) parts.push(`${counts.annotation_comment} ann. comment${counts.annotation_comment !== 1 ? 's' : ''}`)
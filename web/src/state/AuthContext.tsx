@@ -1,11 +1,15 @@
 import { createContext, useContext, useState, useEffect, ReactNode } from 'react'
-import { api, User, SignupRequest, LoginRequest } from '../lib/api'
+import { api, User, SignupRequest, LoginRequest, SecondFactorChallenge, SecondFactorRequest } from '../lib/api'
+import { getAssertion } from '../lib/webauthn'
 
 interface AuthContextType {
   user: User | null
   loading: boolean
   error: string | null
-  login: (data: LoginRequest) => Promise<void>
+  // Resolves to a challenge when the user must also pass a second factor
+  login: (data: LoginRequest) => Promise<SecondFactorChallenge | null>
+  verifySecondFactor: (data: SecondFactorRequest) => Promise<void>
+  loginWithPasskey: () => Promise<void>
   signup: (data: SignupRequest & { invite_code?: string }) => Promise<void>
   logout: () => void
   clearError: () => void
@@ -43,7 +47,11 @@ export function AuthProvider({ children }: { children: ReactNode }) {
       setError(null)
       setLoading(true)
       const response = await api.login(data)
+      if ('two_factor_required' in response) {
+        return response
+      }
       setUser(response.user || null)
+      return null
     } catch (err) {
       const message = err instanceof Error ? err.message : 'Login failed'
       setError(message)
@@ -53,6 +61,38 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     }
   }
 
+  const verifySecondFactor = async (data: SecondFactorRequest) => {
+    try {
+      setError(null)
+      setLoading(true)
+      const response = await api.verifySecondFactor(data)
+      setUser(response.user || null)
+    } catch (err) {
+      const message = err instanceof Error ? err.message : 'Verification failed'
+      setError(message)
+      throw err
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  const loginWithPasskey = async () => {
+    try {
+      setError(null)
+      setLoading(true)
+      const options = await api.beginPasskeyLogin()
+      const credential = await getAssertion(options)
+      const response = await api.finishPasskeyLogin(options.challenge || '', credential)
+      setUser(response.user || null)
+    } catch (err) {
+      const message = err instanceof Error ? err.message : 'Passkey login failed'
+      setError(message)
+      throw err
+    } finally {
+      setLoading(false)
+    }
+  }
+
   const signup = async (data: SignupRequest & { invite_code?: string }) => {
     try {
       setError(null)
@@ -89,6 +129,8 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     loading,
     error,
     login,
+    verifySecondFactor,
+    loginWithPasskey,
     signup,
     logout,
     clearError,